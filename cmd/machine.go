@@ -4,13 +4,17 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"path/filepath"
 	"strconv"
 
 	"github.com/codegangsta/cli"
 	"github.com/docker/machine/commands"
 	"github.com/docker/machine/commands/mcndirs"
+	"github.com/docker/machine/libmachine/cert"
+	"github.com/docker/machine/libmachine/hook"
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/mcnutils"
+	"github.com/docker/machine/libmachine/persist"
 	"github.com/docker/machine/libmachine/ssh"
 	"github.com/docker/machine/version"
 )
@@ -77,11 +81,37 @@ func main() {
 	app.Before = func(c *cli.Context) error {
 		// TODO: Need better handling of config, everything is too
 		// complected together right now.
+		if err := log.SetFormat(c.GlobalString("log-format")); err != nil {
+			return err
+		}
 		if c.GlobalBool("native-ssh") {
 			ssh.SetDefaultClient(ssh.Native)
 		}
 		mcnutils.GithubAPIToken = c.GlobalString("github-api-token")
+		hook.Global = hook.Options{
+			Scripts:  c.GlobalStringSlice("hook-script"),
+			Webhooks: c.GlobalStringSlice("hook-url"),
+		}
 		mcndirs.BaseDir = c.GlobalString("storage-path")
+		ssh.SetControlDir(filepath.Join(mcndirs.GetBaseDir(), "ssh"))
+		if err := cert.SetSigner(cert.SignerOptions{
+			Command:      c.GlobalString("tls-signing-command"),
+			VaultAddr:    c.GlobalString("tls-vault-addr"),
+			VaultToken:   c.GlobalString("tls-vault-token"),
+			VaultPKIPath: c.GlobalString("tls-vault-pki-path"),
+			VaultRole:    c.GlobalString("tls-vault-role"),
+		}); err != nil {
+			return err
+		}
+		if _, err := persist.NewStore(persist.StoreOptions{
+			Driver:     c.GlobalString("storage-driver"),
+			URL:        c.GlobalString("storage-url"),
+			EtcdCACert: c.GlobalString("storage-tls-ca-cert"),
+			EtcdCert:   c.GlobalString("storage-tls-cert"),
+			EtcdKey:    c.GlobalString("storage-tls-key"),
+		}); err != nil {
+			return err
+		}
 		return nil
 	}
 
@@ -103,6 +133,32 @@ func main() {
 			Value:  mcndirs.GetBaseDir(),
 			Usage:  "Configures storage path",
 		},
+		cli.StringFlag{
+			EnvVar: "MACHINE_STORAGE_DRIVER",
+			Name:   "storage-driver",
+			Value:  persist.FilestoreDriver,
+			Usage:  "Where to keep the machines: filestore or etcd",
+		},
+		cli.StringFlag{
+			EnvVar: "MACHINE_STORAGE_URL",
+			Name:   "storage-url",
+			Usage:  "URL of the etcd store, whose path prefixes the keys",
+		},
+		cli.StringFlag{
+			EnvVar: "MACHINE_STORAGE_TLS_CA_CERT",
+			Name:   "storage-tls-ca-cert",
+			Usage:  "CA verifying the certificate of the etcd store",
+		},
+		cli.StringFlag{
+			EnvVar: "MACHINE_STORAGE_TLS_CERT",
+			Name:   "storage-tls-cert",
+			Usage:  "Client certificate authenticating with the etcd store",
+		},
+		cli.StringFlag{
+			EnvVar: "MACHINE_STORAGE_TLS_KEY",
+			Name:   "storage-tls-key",
+			Usage:  "Private key of the client certificate of the etcd store",
+		},
 		cli.StringFlag{
 			EnvVar: "MACHINE_TLS_CA_CERT",
 			Name:   "tls-ca-cert",
@@ -127,12 +183,60 @@ func main() {
 			Usage:  "Private key used in client TLS auth",
 			Value:  "",
 		},
+		cli.StringFlag{
+			EnvVar: "MACHINE_TLS_SIGNING_COMMAND",
+			Name:   "tls-signing-command",
+			Usage:  "Command signing the certificates with an external CA, given the request on its input",
+			Value:  "",
+		},
+		cli.StringFlag{
+			EnvVar: "MACHINE_TLS_VAULT_ADDR,VAULT_ADDR",
+			Name:   "tls-vault-addr",
+			Usage:  "Address of the Vault server whose PKI secrets engine signs the certificates",
+			Value:  "",
+		},
+		cli.StringFlag{
+			EnvVar: "MACHINE_TLS_VAULT_TOKEN,VAULT_TOKEN",
+			Name:   "tls-vault-token",
+			Usage:  "Token to authenticate to Vault with",
+			Value:  "",
+		},
+		cli.StringFlag{
+			EnvVar: "MACHINE_TLS_VAULT_PKI_PATH",
+			Name:   "tls-vault-pki-path",
+			Usage:  "Path the PKI secrets engine is mounted at in Vault",
+			Value:  "pki",
+		},
+		cli.StringFlag{
+			EnvVar: "MACHINE_TLS_VAULT_ROLE",
+			Name:   "tls-vault-role",
+			Usage:  "Vault role signing the certificates",
+			Value:  "",
+		},
 		cli.StringFlag{
 			EnvVar: "MACHINE_GITHUB_API_TOKEN",
 			Name:   "github-api-token",
 			Usage:  "Token to use for requests to the Github API",
 			Value:  "",
 		},
+		cli.StringSliceFlag{
+			EnvVar: "MACHINE_HOOK_SCRIPT",
+			Name:   "hook-script",
+			Usage:  "Script to run at the lifecycle events of every machine",
+			Value:  &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			EnvVar: "MACHINE_HOOK_URL",
+			Name:   "hook-url",
+			Usage:  "Webhook URL to POST the lifecycle events of every machine to",
+			Value:  &cli.StringSlice{},
+		},
+		cli.StringFlag{
+			EnvVar: "MACHINE_LOG_FORMAT",
+			Name:   "log-format",
+			Usage:  "Format of the logs: text, or json to write them to the standard error as a JSON object per line",
+			Value:  log.FormatText,
+		},
 		cli.BoolFlag{
 			EnvVar: "MACHINE_NATIVE_SSH",
 			Name:   "native-ssh",
@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+// autostartEnabled reports whether the driver of h recorded that the machine
+// starts when the host boots. It is read from the saved driver config, so
+// that listing doesn't start every driver plugin.
+func autostartEnabled(h *host.Host) bool {
+	var driver struct {
+		Autostart bool
+	}
+
+	if err := json.Unmarshal(h.RawDriver, &driver); err != nil {
+		return false
+	}
+
+	return driver.Autostart
+}
+
+// writeAutostart lists the machines which start when the host boots.
+func writeAutostart(out io.Writer, hosts []*host.Host) error {
+	w := tabwriter.NewWriter(out, 5, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDRIVER")
+
+	for _, h := range hosts {
+		if autostartEnabled(h) {
+			fmt.Fprintf(w, "%s\t%s\n", h.Name, h.DriverName)
+		}
+	}
+
+	return w.Flush()
+}
+
+func cmdAutostart(c CommandLine) error {
+	if len(c.Args()) != 0 {
+		return errTooManyArguments
+	}
+
+	hosts, err := getStore(c).List()
+	if err != nil {
+		return fmt.Errorf("Error attempting to list hosts from store: %s", err)
+	}
+
+	return writeAutostart(os.Stdout, hosts)
+}
@@ -0,0 +1,26 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteAutostart(t *testing.T) {
+	hosts := []*host.Host{
+		{Name: "web", DriverName: "virtualbox", RawDriver: []byte(`{"MachineName":"web","Autostart":true}`)},
+		{Name: "dev", DriverName: "virtualbox", RawDriver: []byte(`{"MachineName":"dev"}`)},
+		{Name: "cloud", DriverName: "amazonec2", RawDriver: []byte(`{"MachineName":"cloud"}`)},
+		{Name: "broken", DriverName: "virtualbox", RawDriver: []byte(`{`)},
+	}
+
+	out := &bytes.Buffer{}
+	err := writeAutostart(out, hosts)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `NAME   DRIVER
+web    virtualbox
+`, out.String())
+}
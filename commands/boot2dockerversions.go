@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/docker/machine/libmachine/mcnutils"
+)
+
+// writeBoot2DockerReleases lists the releases with the URL of their ISO, to
+// use with --virtualbox-boot2docker-url.
+func writeBoot2DockerReleases(out io.Writer, releases []mcnutils.Boot2DockerRelease) error {
+	w := tabwriter.NewWriter(out, 5, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tPRERELEASE\tURL")
+
+	for _, release := range releases {
+		preRelease := "no"
+		if release.PreRelease {
+			preRelease = "yes"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\n", release.Tag, preRelease, release.ISOURL)
+	}
+
+	return w.Flush()
+}
+
+func cmdBoot2DockerVersions(c CommandLine) error {
+	if len(c.Args()) != 0 {
+		return errTooManyArguments
+	}
+
+	b2dutils := mcnutils.NewB2dUtils(c.GlobalString("storage-path"))
+
+	releases, err := b2dutils.ListBoot2DockerReleases(c.String("api-url"), c.Bool("refresh"))
+	if err != nil {
+		return err
+	}
+
+	return writeBoot2DockerReleases(os.Stdout, releases)
+}
@@ -0,0 +1,25 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/docker/machine/libmachine/mcnutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteBoot2DockerReleases(t *testing.T) {
+	releases := []mcnutils.Boot2DockerRelease{
+		{Tag: "v1.10.0-rc1", PreRelease: true, ISOURL: "https://github.com/boot2docker/boot2docker/releases/download/v1.10.0-rc1/boot2docker.iso"},
+		{Tag: "v1.9.1", ISOURL: "https://github.com/boot2docker/boot2docker/releases/download/v1.9.1/boot2docker.iso"},
+	}
+
+	out := &bytes.Buffer{}
+	err := writeBoot2DockerReleases(out, releases)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `VERSION       PRERELEASE   URL
+v1.10.0-rc1   yes          https://github.com/boot2docker/boot2docker/releases/download/v1.10.0-rc1/boot2docker.iso
+v1.9.1        no           https://github.com/boot2docker/boot2docker/releases/download/v1.9.1/boot2docker.iso
+`, out.String())
+}
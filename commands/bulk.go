@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/codegangsta/cli"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/skarademir/naturalsort"
+)
+
+// bulkConcurrency is how many machines the commands run on at a time, for
+// the cloud providers not to rate limit them.
+const bulkConcurrency = 10
+
+var (
+	errBulkAndNames  = errors.New("Error: --all and --filter select the machines, they can't be used with machine names")
+	errAllAndFilters = errors.New("Error: --all and --filter can't be used together")
+
+	// bulkFlags select the machines the lifecycle commands run on, rather
+	// than their names.
+	bulkFlags = []cli.Flag{
+		cli.BoolFlag{
+			Name:  "all, a",
+			Usage: "Run on all the machines",
+		},
+		cli.StringSliceFlag{
+			Name:  "filter",
+			Usage: "Run on the machines matching the filter, as with ls --filter (may be repeated)",
+			Value: &cli.StringSlice{},
+		},
+	}
+
+	// bulkActionVerbs report the actions done on the machines.
+	bulkActionVerbs = map[string]string{
+		"start":   "Started",
+		"stop":    "Stopped",
+		"restart": "Restarted",
+		"kill":    "Killed",
+	}
+)
+
+// isBulk tells whether the machines are selected by --all or --filter.
+func isBulk(c CommandLine) bool {
+	return c.Bool("all") || len(c.StringSlice("filter")) > 0
+}
+
+// getTargetHostsFromContext returns the machines selected by --all or
+// --filter, else the machines named by the arguments.
+func getTargetHostsFromContext(c CommandLine) ([]*host.Host, error) {
+	if !isBulk(c) {
+		return getHostsFromContext(c)
+	}
+
+	if len(c.Args()) > 0 {
+		return nil, errBulkAndNames
+	}
+
+	filters := c.StringSlice("filter")
+	if c.Bool("all") && len(filters) > 0 {
+		return nil, errAllAndFilters
+	}
+
+	options, err := parseFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts, err := listHosts(getStore(c))
+	if err != nil {
+		return nil, err
+	}
+
+	return filterHosts(hosts, options), nil
+}
+
+// runBulkAction runs the action on the machines, bulkConcurrency at a time,
+// reporting whether it succeeded on each of them. The error returned sums
+// up those it failed on.
+func runBulkAction(hosts []*host.Host, verb string, action func(h *host.Host) error) error {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		failed  = []string{}
+		workers = make(chan struct{}, bulkConcurrency)
+	)
+
+	for _, h := range hosts {
+		wg.Add(1)
+		go func(h *host.Host) {
+			defer wg.Done()
+
+			workers <- struct{}{}
+			err := action(h)
+			<-workers
+
+			if err != nil {
+				log.Errorf("%s: %s", h.Name, err)
+
+				mu.Lock()
+				failed = append(failed, h.Name)
+				mu.Unlock()
+				return
+			}
+
+			log.Infof("%s %s", verb, h.Name)
+		}(h)
+	}
+	wg.Wait()
+
+	if len(failed) > 0 {
+		sort.Sort(naturalsort.NaturalSort(failed))
+		return fmt.Errorf("Failed on %d of %d machines: %s", len(failed), len(hosts), strings.Join(failed, ", "))
+	}
+
+	return nil
+}
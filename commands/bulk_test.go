@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/stretchr/testify/assert"
+)
+
+func bulkHosts(names ...string) []*host.Host {
+	hosts := []*host.Host{}
+	for _, name := range names {
+		hosts = append(hosts, &host.Host{Name: name})
+	}
+
+	return hosts
+}
+
+func TestRunBulkAction(t *testing.T) {
+	var mu sync.Mutex
+	done := []string{}
+
+	err := runBulkAction(bulkHosts("dev-1", "dev-2", "dev-3"), "Stopped", func(h *host.Host) error {
+		mu.Lock()
+		defer mu.Unlock()
+		done = append(done, h.Name)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	sort.Strings(done)
+	assert.Equal(t, []string{"dev-1", "dev-2", "dev-3"}, done)
+}
+
+func TestRunBulkActionSumsUpFailures(t *testing.T) {
+	err := runBulkAction(bulkHosts("dev-10", "dev-1", "dev-2", "dev-3"), "Stopped", func(h *host.Host) error {
+		if h.Name == "dev-2" {
+			return nil
+		}
+		return errors.New("unreachable")
+	})
+
+	assert.EqualError(t, err, "Failed on 3 of 4 machines: dev-1, dev-3, dev-10")
+}
@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/docker/machine/drivers/errdriver"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/drivers/rpc"
+)
+
+var errExpectedOneDriver = errors.New("Error: Expected one driver name as an argument")
+
+// writeCapabilities writes whether the driver supports each known capability.
+func writeCapabilities(out io.Writer, d drivers.Driver) error {
+	w := tabwriter.NewWriter(out, 5, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "CAPABILITY\tSUPPORTED")
+
+	for _, capability := range drivers.AllCapabilities {
+		supported := "no"
+		if drivers.HasCapability(d, capability) {
+			supported = "yes"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\n", capability, supported)
+	}
+
+	return w.Flush()
+}
+
+func cmdCapabilities(c CommandLine) error {
+	if len(c.Args()) != 1 {
+		return errExpectedOneDriver
+	}
+
+	driverName := c.Args().First()
+
+	bareDriverData, err := json.Marshal(&drivers.BaseDriver{
+		StorePath: c.GlobalString("storage-path"),
+	})
+	if err != nil {
+		return err
+	}
+
+	driver, err := newPluginDriver(driverName, bareDriverData)
+	if err != nil {
+		return fmt.Errorf("Error loading driver %q: %s", driverName, err)
+	}
+
+	if _, ok := driver.(*errdriver.Driver); ok {
+		return errdriver.NotLoadable{Name: driverName}
+	}
+
+	if err := writeCapabilities(os.Stdout, driver); err != nil {
+		return err
+	}
+
+	if serialDriver, ok := driver.(*drivers.SerialDriver); ok {
+		driver = serialDriver.Driver
+	}
+
+	if rpcd, ok := driver.(*rpcdriver.RPCClientDriver); ok {
+		return rpcd.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteCapabilities(t *testing.T) {
+	d := &fakedriver.Driver{
+		MockCapabilities: []drivers.Capability{drivers.CapabilityStart, drivers.CapabilitySnapshot},
+	}
+
+	out := &bytes.Buffer{}
+	err := writeCapabilities(out, d)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `CAPABILITY       SUPPORTED
+start            yes
+stop             no
+kill             no
+snapshot         yes
+resize           no
+suspend          no
+port-forward     no
+shared-folders   no
+`, out.String())
+}
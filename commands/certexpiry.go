@@ -0,0 +1,229 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/cert"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// defaultCertExpiryDays is how many days before they expire the certificates
+// are reported as expiring.
+const defaultCertExpiryDays = 30
+
+// The kinds of certificates whose expiry is checked.
+const (
+	serverCertKind = "server"
+	clientCertKind = "client"
+	caCertKind     = "CA"
+)
+
+// expiringCert is a certificate of a machine which expires soon, or already
+// has.
+type expiringCert struct {
+	Machine  string
+	Kind     string
+	Path     string
+	NotAfter time.Time
+}
+
+// describe tells when the certificate expires, or expired, relative to now.
+func (e expiringCert) describe(now time.Time) string {
+	date := e.NotAfter.Format("2006-01-02")
+	if !e.NotAfter.After(now) {
+		return fmt.Sprintf("The %s certificate of %s expired on %s", e.Kind, e.Machine, date)
+	}
+
+	days := int(e.NotAfter.Sub(now).Hours() / 24)
+	return fmt.Sprintf("The %s certificate of %s expires on %s, in %d day(s)", e.Kind, e.Machine, date, days)
+}
+
+// certExpiryWindow returns how long before they expire the certificates are
+// reported as expiring, given in days by --cert-expiry-days.
+func certExpiryWindow(c CommandLine) (time.Duration, error) {
+	days := c.Int("cert-expiry-days")
+	if days < 0 {
+		return 0, fmt.Errorf("--cert-expiry-days must be a positive number of days, not %d", days)
+	}
+
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
+// expiringCerts returns the server, client and CA certificates of the
+// machine which expire within the window from now.
+func expiringCerts(machineName string, authOptions *auth.Options, window time.Duration, now time.Time) ([]expiringCert, error) {
+	expiring := []expiringCert{}
+	if authOptions == nil {
+		return expiring, nil
+	}
+
+	certs := []struct {
+		kind string
+		path string
+	}{
+		{serverCertKind, authOptions.ServerCertPath},
+		{clientCertKind, authOptions.ClientCertPath},
+		{caCertKind, authOptions.CaCertPath},
+	}
+
+	for _, c := range certs {
+		if c.path == "" {
+			continue
+		}
+
+		notAfter, err := cert.CertificateExpiry(c.path)
+		if err != nil {
+			return expiring, fmt.Errorf("Error reading the %s certificate of %s: %s", c.kind, machineName, err)
+		}
+
+		if notAfter.Sub(now) < window {
+			expiring = append(expiring, expiringCert{
+				Machine:  machineName,
+				Kind:     c.kind,
+				Path:     c.path,
+				NotAfter: notAfter,
+			})
+		}
+	}
+
+	return expiring, nil
+}
+
+func hostAuthOptions(h *host.Host) *auth.Options {
+	if h.HostOptions == nil {
+		return nil
+	}
+
+	return h.HostOptions.AuthOptions
+}
+
+// certExpiryWarnings returns a warning per certificate of the machines
+// expiring within the window. The client certificate and the CA shared by
+// machines are only reported once.
+func certExpiryWarnings(hosts []*host.Host, window time.Duration, now time.Time) []string {
+	warnings := []string{}
+	seen := map[string]bool{}
+
+	for _, h := range hosts {
+		expiring, err := expiringCerts(h.Name, hostAuthOptions(h), window, now)
+		if err != nil {
+			log.Debug(err)
+		}
+
+		for _, e := range expiring {
+			if seen[e.Path] {
+				continue
+			}
+			seen[e.Path] = true
+
+			warnings = append(warnings, e.describe(now)+renewalHint(h, e))
+		}
+	}
+
+	return warnings
+}
+
+// renewalHint tells how to renew the expiring certificate of the machine.
+// regenerate-certs --expiring renews the CA of a machine which has its own,
+// but not the CA of the workstation, which signs the certificates of all the
+// machines.
+func renewalHint(h *host.Host, e expiringCert) string {
+	switch {
+	case e.Kind == clientCertKind:
+		return ", run 'docker-machine regenerate-certs --expiring --all' to renew it"
+	case e.Kind == caCertKind && !hasOwnCA(hostAuthOptions(h)):
+		return ", run 'docker-machine regenerate-certs --own-ca' on the machines using it to give them a new CA of their own"
+	}
+
+	return fmt.Sprintf(", run 'docker-machine regenerate-certs --expiring %s' to renew it", h.Name)
+}
+
+// printCertExpiryWarnings logs the warnings about expiring certificates.
+func printCertExpiryWarnings(hosts []*host.Host, window time.Duration) {
+	for _, warning := range certExpiryWarnings(hosts, window, time.Now()) {
+		log.Warn(warning)
+	}
+}
+
+// regenerateClientCert replaces the client certificate by a new one signed by
+// the CA. The engines trust the CA, so they don't need to be restarted.
+func regenerateClientCert(authOptions *auth.Options) error {
+	for _, path := range []string{authOptions.ClientCertPath, authOptions.ClientKeyPath} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return cert.BootstrapCertificates(authOptions)
+}
+
+// selectExpiringCerts returns the machines whose server certificate, or own
+// CA, expires within the window, the auth options of the client certificates
+// which do, once each, and the names of the machines whose own CA does. An
+// expiring CA of the workstation is only reported, as renewing it would
+// revoke the certificates of all the machines.
+func selectExpiringCerts(hosts []*host.Host, window time.Duration, now time.Time) ([]*host.Host, []*auth.Options, map[string]bool) {
+	expiringHosts := []*host.Host{}
+	clientCerts := []*auth.Options{}
+	renewedCAs := map[string]bool{}
+	seen := map[string]bool{}
+
+	for _, h := range hosts {
+		expiring, err := expiringCerts(h.Name, hostAuthOptions(h), window, now)
+		if err != nil {
+			log.Warn(err)
+			continue
+		}
+
+		selected := false
+		for _, e := range expiring {
+			if seen[e.Path] {
+				continue
+			}
+			seen[e.Path] = true
+
+			switch {
+			case e.Kind == clientCertKind:
+				log.Info(e.describe(now))
+				clientCerts = append(clientCerts, hostAuthOptions(h))
+			case e.Kind == caCertKind && !hasOwnCA(hostAuthOptions(h)):
+				log.Warn(e.describe(now) + renewalHint(h, e))
+			default:
+				log.Info(e.describe(now))
+				if e.Kind == caCertKind {
+					renewedCAs[h.Name] = true
+				}
+				if !selected {
+					expiringHosts = append(expiringHosts, h)
+					selected = true
+				}
+			}
+		}
+	}
+
+	// A new CA of its own comes with a new client certificate.
+	renewedClientCerts := []*auth.Options{}
+	for _, authOptions := range clientCerts {
+		if !renewsClientCert(expiringHosts, renewedCAs, authOptions) {
+			renewedClientCerts = append(renewedClientCerts, authOptions)
+		}
+	}
+
+	return expiringHosts, renewedClientCerts, renewedCAs
+}
+
+// renewsClientCert tells whether renewing the CAs of the machines renews the
+// client certificate of the auth options.
+func renewsClientCert(hosts []*host.Host, renewedCAs map[string]bool, authOptions *auth.Options) bool {
+	for _, h := range hosts {
+		if renewedCAs[h.Name] && hostAuthOptions(h).ClientCertPath == authOptions.ClientCertPath {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/cert"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/stretchr/testify/assert"
+)
+
+// newCertExpiryHost returns a machine whose server certificate is valid for
+// serverValidity, sharing the client certificate of dir.
+func newCertExpiryHost(t *testing.T, dir, name string, serverValidity time.Duration) *host.Host {
+	authOptions := &auth.Options{
+		CertDir:          dir,
+		CaCertPath:       filepath.Join(dir, "ca.pem"),
+		CaPrivateKeyPath: filepath.Join(dir, "ca-key.pem"),
+		ClientCertPath:   filepath.Join(dir, "cert.pem"),
+		ClientKeyPath:    filepath.Join(dir, "key.pem"),
+		ServerCertPath:   filepath.Join(dir, name+"-server.pem"),
+		ServerKeyPath:    filepath.Join(dir, name+"-server-key.pem"),
+	}
+
+	if err := cert.BootstrapCertificates(authOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := cert.Options{Org: "test-org", KeyType: cert.KeyTypeECDSA, Validity: serverValidity}
+	if err := cert.GenerateCertWithOptions([]string{"localhost"}, authOptions.ServerCertPath, authOptions.ServerKeyPath, authOptions.CaCertPath, authOptions.CaPrivateKeyPath, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	return &host.Host{
+		Name:        name,
+		HostOptions: &host.Options{AuthOptions: authOptions},
+	}
+}
+
+func TestCertExpiry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	soon := newCertExpiryHost(t, dir, "soon", 10*24*time.Hour)
+	later := newCertExpiryHost(t, dir, "later", 100*24*time.Hour)
+	hosts := []*host.Host{soon, later}
+	now := time.Now()
+
+	expiring, err := expiringCerts(soon.Name, soon.HostOptions.AuthOptions, 30*24*time.Hour, now)
+	assert.NoError(t, err)
+	assert.Len(t, expiring, 1)
+	assert.Equal(t, serverCertKind, expiring[0].Kind)
+	assert.Contains(t, expiring[0].describe(now), "The server certificate of soon expires on")
+	assert.Contains(t, expiring[0].describe(now.Add(11*24*time.Hour)), "The server certificate of soon expired on")
+
+	warnings := certExpiryWarnings(hosts, 30*24*time.Hour, now)
+	assert.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "regenerate-certs --expiring soon")
+
+	// The client certificate and the CA, valid for years, are only reported
+	// once.
+	warnings = certExpiryWarnings(hosts, 10000*24*time.Hour, now)
+	assert.Len(t, warnings, 4)
+	assert.Contains(t, warnings[1], "The client certificate of soon")
+	assert.Contains(t, warnings[2], "The CA certificate of soon")
+	assert.Contains(t, warnings[2], "regenerate-certs --own-ca")
+
+	expiringHosts, clientCerts, renewedCAs := selectExpiringCerts(hosts, 30*24*time.Hour, now)
+	assert.Equal(t, []*host.Host{soon}, expiringHosts)
+	assert.Empty(t, clientCerts)
+	assert.Empty(t, renewedCAs)
+
+	expiringHosts, clientCerts, renewedCAs = selectExpiringCerts(hosts, 0, now)
+	assert.Empty(t, expiringHosts)
+	assert.Empty(t, clientCerts)
+	assert.Empty(t, renewedCAs)
+
+	// The CA of the workstation isn't renewed.
+	expiringHosts, clientCerts, renewedCAs = selectExpiringCerts(hosts, 10000*24*time.Hour, now)
+	assert.Equal(t, hosts, expiringHosts)
+	assert.Equal(t, []*auth.Options{soon.HostOptions.AuthOptions}, clientCerts)
+	assert.Empty(t, renewedCAs)
+}
+
+func TestCertExpiryOfOwnCA(t *testing.T) {
+	storePath, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(storePath)
+
+	h := newCertExpiryHost(t, filepath.Join(storePath, "certs"), "dev", 100*24*time.Hour)
+	h.HostOptions.AuthOptions.StorePath = storePath
+	now := time.Now()
+
+	warnings := certExpiryWarnings([]*host.Host{h}, 10000*24*time.Hour, now)
+	assert.Len(t, warnings, 3)
+	assert.Contains(t, warnings[2], "The CA certificate of dev")
+	assert.Contains(t, warnings[2], "regenerate-certs --expiring dev")
+
+	// The new CA comes with a new client certificate.
+	expiringHosts, clientCerts, renewedCAs := selectExpiringCerts([]*host.Host{h}, 10000*24*time.Hour, now)
+	assert.Equal(t, []*host.Host{h}, expiringHosts)
+	assert.Empty(t, clientCerts)
+	assert.Equal(t, map[string]bool{"dev": true}, renewedCAs)
+}
+
+func TestRegenerateClientCert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	authOptions := newCertExpiryHost(t, dir, "default", time.Hour).HostOptions.AuthOptions
+
+	before, err := ioutil.ReadFile(authOptions.ClientCertPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, regenerateClientCert(authOptions))
+
+	after, err := ioutil.ReadFile(authOptions.ClientCertPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotEqual(t, before, after)
+}
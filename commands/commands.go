@@ -10,6 +10,7 @@ import (
 	"github.com/codegangsta/cli"
 	"github.com/docker/machine/commands/mcndirs"
 	"github.com/docker/machine/drivers/errdriver"
+	"github.com/docker/machine/libmachine"
 	"github.com/docker/machine/libmachine/cert"
 	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/drivers/plugin/localbinary"
@@ -41,6 +42,8 @@ type CommandLine interface {
 
 	StringSlice(name string) []string
 
+	Int(name string) int
+
 	GlobalString(name string) string
 
 	FlagNames() (names []string)
@@ -81,9 +84,33 @@ func newPluginDriver(driverName string, rawContent []byte) (drivers.Driver, erro
 	return d, nil
 }
 
+func init() {
+	// The provisioning of a machine reaches the other machines of the store
+	// through the plugins of their drivers.
+	libmachine.SetDriverLoader(func(h *host.Host) error {
+		d, err := newPluginDriver(h.DriverName, h.RawDriver)
+		if err != nil {
+			return err
+		}
+
+		h.Driver = d
+		return nil
+	})
+}
+
+// exitCoder is implemented by the errors which make a command exit with a
+// specific status rather than 1.
+type exitCoder interface {
+	ExitCode() int
+}
+
 func fatalOnError(command func(commandLine CommandLine) error) func(context *cli.Context) {
 	return func(context *cli.Context) {
 		if err := command(&contextCommandLine{context}); err != nil {
+			if e, ok := err.(exitCoder); ok {
+				log.Error(err)
+				os.Exit(e.ExitCode())
+			}
 			log.Fatal(err)
 		}
 	}
@@ -104,11 +131,22 @@ func confirmInput(msg string) (bool, error) {
 
 func getStore(c CommandLine) persist.Store {
 	certInfo := getCertPathInfoFromContext(c)
-	return &persist.Filestore{
+	store, err := persist.NewStore(persist.StoreOptions{
+		Driver:           c.GlobalString("storage-driver"),
+		URL:              c.GlobalString("storage-url"),
+		EtcdCACert:       c.GlobalString("storage-tls-ca-cert"),
+		EtcdCert:         c.GlobalString("storage-tls-cert"),
+		EtcdKey:          c.GlobalString("storage-tls-key"),
 		Path:             c.GlobalString("storage-path"),
 		CaCertPath:       certInfo.CaCertPath,
 		CaPrivateKeyPath: certInfo.CaPrivateKeyPath,
+	})
+	if err != nil {
+		// The storage options are checked before any command runs.
+		log.Fatal(err)
 	}
+
+	return store
 }
 
 func listHosts(store persist.Store) ([]*host.Host, error) {
@@ -190,6 +228,33 @@ var Commands = []cli.Command{
 		Usage:  "Print which machine is active",
 		Action: fatalOnError(cmdActive),
 	},
+	{
+		Name:   "autostart",
+		Usage:  "List the machines which start when the host boots",
+		Action: fatalOnError(cmdAutostart),
+	},
+	{
+		Name:   "boot2docker-versions",
+		Usage:  "List the available boot2docker ISO versions",
+		Action: fatalOnError(cmdBoot2DockerVersions),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "api-url",
+				Usage: "GitHub (Enterprise) releases API URL to list the releases of",
+				Value: "https://api.github.com/repos/boot2docker/boot2docker/releases",
+			},
+			cli.BoolFlag{
+				Name:  "refresh",
+				Usage: "Query the release source even if a recent listing is cached",
+			},
+		},
+	},
+	{
+		Name:        "capabilities",
+		Usage:       "Print the optional operations a driver supports",
+		Description: "Argument is a driver name.",
+		Action:      fatalOnError(cmdCapabilities),
+	},
 	{
 		Name:        "config",
 		Usage:       "Print the connection config for machine",
@@ -201,6 +266,22 @@ var Commands = []cli.Command{
 				Usage: "Display the Swarm config instead of the Docker daemon",
 			},
 		},
+		Subcommands: []cli.Command{
+			{
+				Name:        "set-engine-opt",
+				Usage:       "Add engine options to a machine and configure its engine with them",
+				Description: "Argument is a machine name.",
+				Action:      fatalOnError(cmdConfigSetEngineOpt),
+				Flags:       engineOptionFlags,
+			},
+			{
+				Name:        "unset-engine-opt",
+				Usage:       "Remove engine options from a machine and configure its engine without them",
+				Description: "Argument is a machine name.",
+				Action:      fatalOnError(cmdConfigUnsetEngineOpt),
+				Flags:       engineOptionFlags,
+			},
+		},
 	},
 	{
 		Flags:           sharedCreateFlags,
@@ -210,6 +291,65 @@ var Commands = []cli.Command{
 		Action:          fatalOnError(cmdCreateOuter),
 		SkipFlagParsing: true,
 	},
+	{
+		Name:        "compose-env",
+		Usage:       "Display the connection settings of a machine as a Docker Compose .env file",
+		Description: "Argument is a machine name.",
+		Action:      fatalOnError(cmdComposeEnv),
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "swarm",
+				Usage: "Display the Swarm config instead of the Docker daemon",
+			},
+		},
+	},
+	{
+		Name:  "credential",
+		Usage: "Manage the credentials of the keychain which the flags of the drivers can name",
+		Subcommands: []cli.Command{
+			{
+				Name:        "add",
+				Usage:       "Add a credential to the keychain, read from stdin",
+				Description: "Argument is a credential name.",
+				Action:      fatalOnError(cmdCredentialAdd),
+			},
+			{
+				Name:   "ls",
+				Usage:  "List the credentials of the keychain",
+				Action: fatalOnError(cmdCredentialLs),
+			},
+			{
+				Name:        "rm",
+				Usage:       "Remove credentials from the keychain",
+				Description: "Argument(s) are one or more credential names.",
+				Action:      fatalOnError(cmdCredentialRm),
+			},
+		},
+	},
+	{
+		Name:        "daemon-config",
+		Usage:       "Fetch the Docker daemon configuration files of a machine",
+		Description: "Argument is a machine name.",
+		Action:      fatalOnError(cmdDaemonConfig),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "output-dir, o",
+				Usage: "Save the files under this directory instead of printing them",
+			},
+		},
+	},
+	{
+		Name:        "engine-version",
+		Usage:       "Print the Docker engine version of a machine",
+		Description: "Argument is a machine name.",
+		Action:      fatalOnError(cmdEngineVersion),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "min",
+				Usage: "Fail with exit status 3 if the engine is older than this version",
+			},
+		},
+	},
 	{
 		Name:        "env",
 		Usage:       "Display the commands to set up the environment for the Docker client",
@@ -222,7 +362,7 @@ var Commands = []cli.Command{
 			},
 			cli.StringFlag{
 				Name:  "shell",
-				Usage: "Force environment to be configured for specified shell",
+				Usage: "Force environment to be configured for specified shell: bash, zsh, fish, powershell, pwsh, cmd, nu or direnv",
 			},
 			cli.BoolFlag{
 				Name:  "unset, u",
@@ -232,6 +372,60 @@ var Commands = []cli.Command{
 				Name:  "no-proxy",
 				Usage: "Add machine IP to NO_PROXY environment variable",
 			},
+			cli.BoolFlag{
+				Name:  "no-proxy-subnet",
+				Usage: "Add the network of the machine to NO_PROXY along with its IP, implies --no-proxy",
+			},
+			cli.StringSliceFlag{
+				Name:  "no-proxy-extra",
+				Usage: "Host or network to add to NO_PROXY along with the machine IP, implies --no-proxy (may be repeated)",
+				Value: &cli.StringSlice{},
+			},
+			cli.BoolFlag{
+				Name:  "json",
+				Usage: "Print the variables as a JSON object instead of shell commands",
+			},
+		},
+	},
+	{
+		Name:        "export",
+		Usage:       "Export a machine into an archive to import on another workstation",
+		Description: "Argument is a machine name.",
+		Action:      fatalOnError(cmdExport),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "output, o",
+				Usage: "File to write the archive to, NAME.tar.gz by default",
+			},
+		},
+	},
+	{
+		Name:        "healthcheck",
+		Usage:       "Check the health of machines and optionally repair them",
+		Description: "Argument(s) are zero or more machine names, all the machines are checked by default.",
+		Action:      fatalOnError(cmdHealthcheck),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "interval",
+				Usage: "Keep checking the machines at this interval, e.g. 30s or 5m, instead of checking them once",
+			},
+			cli.StringSliceFlag{
+				Name:  "repair",
+				Usage: "Repair to run on unhealthy machines: restart or regenerate-certs (may be repeated)",
+				Value: &cli.StringSlice{},
+			},
+		},
+	},
+	{
+		Name:        "import",
+		Usage:       "Import a machine from an archive written by export",
+		Description: "Argument is the path of the archive.",
+		Action:      fatalOnError(cmdImport),
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "regenerate-certs",
+				Usage: "Connect to the machine with the certificates of this workstation, regenerating its server certificate",
+			},
 		},
 	},
 	{
@@ -247,6 +441,21 @@ var Commands = []cli.Command{
 			},
 		},
 	},
+	{
+		Name:   "inventory",
+		Usage:  "Print the machines as an Ansible dynamic inventory",
+		Action: fatalOnError(cmdInventory),
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "list",
+				Usage: "Print the whole inventory, the default",
+			},
+			cli.StringFlag{
+				Name:  "host",
+				Usage: "Print the variables of this machine",
+			},
+		},
+	},
 	{
 		Name:        "ip",
 		Usage:       "Get the IP address of a machine",
@@ -258,6 +467,13 @@ var Commands = []cli.Command{
 		Usage:       "Kill a machine",
 		Description: "Argument(s) are one or more machine names.",
 		Action:      fatalOnError(cmdKill),
+		Flags:       bulkFlags,
+	},
+	{
+		Name:        "label",
+		Usage:       "Print or edit the labels of a machine",
+		Description: "Arguments are a machine name, followed by key=value labels to set or key- labels to remove.",
+		Action:      fatalOnError(cmdLabel),
 	},
 	{
 		Flags: []cli.Flag{
@@ -267,14 +483,72 @@ var Commands = []cli.Command{
 			},
 			cli.StringSliceFlag{
 				Name:  "filter",
-				Usage: "Filter output based on conditions provided",
+				Usage: "Filter output based on conditions provided, key=value or key!=value with key name, driver, state, swarm or label (may be repeated)",
 				Value: &cli.StringSlice{},
 			},
+			cli.StringFlag{
+				Name:  "format",
+				Usage: "Print each machine using the given go template",
+			},
+			cli.StringFlag{
+				Name:  "output, o",
+				Usage: "Output format: table or json",
+			},
+			cli.IntFlag{
+				Name:  "cert-expiry-days",
+				Usage: "Warn about the certificates which expire within this number of days",
+				Value: defaultCertExpiryDays,
+			},
 		},
 		Name:   "ls",
 		Usage:  "List machines",
 		Action: fatalOnError(cmdLs),
 	},
+	{
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "dry-run, n",
+				Usage: "Only print what would be removed",
+			},
+			cli.BoolFlag{
+				Name:  "force, f",
+				Usage: "Remove without prompting for confirmation",
+			},
+		},
+		Name:   "prune",
+		Usage:  "Remove storage left behind by machines that can no longer be loaded",
+		Action: fatalOnError(cmdPrune),
+	},
+	{
+		Name:  "port",
+		Usage: "Add, remove and list the ports of the host forwarded to a machine",
+		Subcommands: []cli.Command{
+			{
+				Name:        "add",
+				Usage:       "Forward a port of the host to a machine",
+				Description: "Arguments are a machine name and a rule [name=][host-ip:]host-port:guest-port[/protocol].",
+				Action:      fatalOnError(cmdPortAdd),
+			},
+			{
+				Name:        "rm",
+				Usage:       "Remove a port forwarding rule of a machine",
+				Description: "Arguments are a machine name and a rule name.",
+				Action:      fatalOnError(cmdPortRm),
+			},
+			{
+				Name:        "ls",
+				Usage:       "List the port forwarding rules of a machine",
+				Description: "Argument is a machine name.",
+				Action:      fatalOnError(cmdPortLs),
+			},
+		},
+	},
+	{
+		Name:        "provision",
+		Usage:       "Provision machines again, applying their engine, certificate and swarm options",
+		Description: "Argument(s) are one or more machine names.",
+		Action:      fatalOnError(cmdProvision),
+	},
 	{
 		Name:        "regenerate-certs",
 		Usage:       "Regenerate TLS Certificates for a machine",
@@ -285,6 +559,40 @@ var Commands = []cli.Command{
 				Name:  "force, f",
 				Usage: "Force rebuild and do not prompt",
 			},
+			cli.StringSliceFlag{
+				Name:  "san",
+				Usage: "Add a SAN, a DNS name or an IP address, to the server certificate of the machines",
+				Value: &cli.StringSlice{},
+			},
+			cli.StringFlag{
+				Name:  "tls-key-type",
+				Usage: "Type of the key of the server certificate, rsa or ecdsa (P-256)",
+			},
+			cli.IntFlag{
+				Name:  "tls-key-bits",
+				Usage: "Size of the RSA key of the server certificate",
+			},
+			cli.StringFlag{
+				Name:  "tls-cert-validity",
+				Usage: "Validity of the server certificate, a number of days such as 365d or a duration such as 8760h",
+			},
+			cli.BoolFlag{
+				Name:  "expiring",
+				Usage: "Only regenerate the certificates which expire within --cert-expiry-days",
+			},
+			cli.BoolFlag{
+				Name:  "all",
+				Usage: "Regenerate the certificates of all the machines",
+			},
+			cli.BoolFlag{
+				Name:  "own-ca",
+				Usage: "Give the machines a new CA of their own instead of the CA shared by all the machines, for share",
+			},
+			cli.IntFlag{
+				Name:  "cert-expiry-days",
+				Usage: "Number of days before they expire the certificates are regenerated by --expiring",
+				Value: defaultCertExpiryDays,
+			},
 		},
 	},
 	{
@@ -292,19 +600,115 @@ var Commands = []cli.Command{
 		Usage:       "Restart a machine",
 		Description: "Argument(s) are one or more machine names.",
 		Action:      fatalOnError(cmdRestart),
+		Flags:       bulkFlags,
 	},
 	{
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			cli.BoolFlag{
 				Name:  "force, f",
 				Usage: "Remove local configuration even if machine cannot be removed",
 			},
-		},
+			cli.BoolFlag{
+				Name:  "yes, y",
+				Usage: "Remove the machines selected by --all or --filter without prompting for confirmation",
+			},
+		}, bulkFlags...),
 		Name:        "rm",
 		Usage:       "Remove a machine",
 		Description: "Argument(s) are one or more machine names.",
 		Action:      fatalOnError(cmdRm),
 	},
+	{
+		Name:  "secrets",
+		Usage: "Encrypt the secrets of the drivers, such as the access keys of the cloud providers",
+		Subcommands: []cli.Command{
+			{
+				Name:   "init",
+				Usage:  "Generate the key encrypting the secrets and store it in the keychain",
+				Action: fatalOnError(cmdSecretsInit),
+				Flags: []cli.Flag{
+					cli.BoolFlag{
+						Name:  "print",
+						Usage: "Print the key, to be given by MACHINE_SECRETS_KEY, rather than storing it",
+					},
+				},
+			},
+			{
+				Name:   "encrypt",
+				Usage:  "Encrypt the secrets of the existing machines",
+				Action: fatalOnError(cmdSecretsEncrypt),
+			},
+		},
+	},
+	{
+		Name:        "serve",
+		Usage:       "Serve the machines over an HTTPS API",
+		Description: "The clients authenticate with a certificate signed by the CA of the machines.",
+		Action:      fatalOnError(cmdServe),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:   "addr",
+				Usage:  "Address to listen on",
+				Value:  defaultServeAddr,
+				EnvVar: "MACHINE_SERVE_ADDR",
+			},
+			cli.StringSliceFlag{
+				Name:  "san",
+				Usage: "Extra DNS name or IP address for the certificate of the server to be valid for (may be repeated)",
+				Value: &cli.StringSlice{},
+			},
+		},
+	},
+	{
+		Name:        "share",
+		Usage:       "Write a new client certificate of a machine for a colleague to connect to its engine",
+		Description: "Argument is a machine name.",
+		Action:      fatalOnError(cmdShare),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "client",
+				Usage: "Name of the colleague the machine is shared with, the organization of the certificate",
+			},
+			cli.StringFlag{
+				Name:  "output, o",
+				Usage: "File to write the bundle to, NAME-CLIENT.tar.gz by default",
+			},
+			cli.StringFlag{
+				Name:  "validity",
+				Usage: "Validity of the certificate, a number of days such as 30d or a duration such as 720h",
+			},
+		},
+	},
+	{
+		Name:  "snapshot",
+		Usage: "Take, list, restore and delete snapshots of a machine",
+		Subcommands: []cli.Command{
+			{
+				Name:        "create",
+				Usage:       "Take a snapshot of a machine",
+				Description: "Arguments are a machine name and a snapshot name.",
+				Action:      fatalOnError(cmdSnapshotCreate),
+			},
+			{
+				Name:        "list",
+				Usage:       "List the snapshots of a machine",
+				Description: "Argument is a machine name.",
+				Action:      fatalOnError(cmdSnapshotList),
+			},
+			{
+				Name:        "restore",
+				Usage:       "Restore a machine to a snapshot",
+				Description: "Arguments are a machine name and a snapshot name.",
+				Action:      fatalOnError(cmdSnapshotRestore),
+			},
+			{
+				Name:        "delete",
+				Usage:       "Delete a snapshot of a machine",
+				Description: "Arguments are a machine name and a snapshot name.",
+				Action:      fatalOnError(cmdSnapshotDelete),
+			},
+		},
+	},
 	{
 		Name:            "ssh",
 		Usage:           "Log into or run a command on a machine with SSH.",
@@ -322,6 +726,10 @@ var Commands = []cli.Command{
 				Name:  "recursive, r",
 				Usage: "Copy files recursively (required to copy directories)",
 			},
+			cli.BoolFlag{
+				Name:  "rsync",
+				Usage: "Sync directories with rsync, only sending what changed",
+			},
 		},
 	},
 	{
@@ -329,24 +737,65 @@ var Commands = []cli.Command{
 		Usage:       "Start a machine",
 		Description: "Argument(s) are one or more machine names.",
 		Action:      fatalOnError(cmdStart),
+		Flags: append([]cli.Flag{
+			cli.BoolFlag{
+				Name:  "provision",
+				Usage: "Provision the machines which were created with --no-provision",
+			},
+		}, bulkFlags...),
+	},
+	{
+		Name:        "stats",
+		Usage:       "Display the resource usage of the containers of a machine",
+		Description: "Argument is a machine name.",
+		Action:      fatalOnError(cmdStats),
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "no-stream",
+				Usage: "Print a single sample instead of refreshing the table",
+			},
+		},
 	},
 	{
 		Name:        "status",
 		Usage:       "Get the status of a machine",
 		Description: "Argument is a machine name.",
 		Action:      fatalOnError(cmdStatus),
+		Flags: []cli.Flag{
+			cli.IntFlag{
+				Name:  "cert-expiry-days",
+				Usage: "Warn about the certificates which expire within this number of days",
+				Value: defaultCertExpiryDays,
+			},
+		},
 	},
 	{
 		Name:        "stop",
 		Usage:       "Stop a machine",
 		Description: "Argument(s) are one or more machine names.",
 		Action:      fatalOnError(cmdStop),
+		Flags:       bulkFlags,
 	},
 	{
 		Name:        "upgrade",
 		Usage:       "Upgrade a machine to the latest version of Docker",
 		Description: "Argument(s) are one or more machine names.",
 		Action:      fatalOnError(cmdUpgrade),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "engine-version",
+				Usage: "Install this version of Docker, such as 20.10.24, and pin the machine to it",
+				Value: "",
+			},
+			cli.BoolFlag{
+				Name:  "allow-downgrade",
+				Usage: "Allow --engine-version to be older than the running version",
+			},
+			cli.BoolFlag{
+				Name:  "check",
+				Usage: "Report the running and newer available versions of Docker instead of upgrading",
+			},
+		},
 	},
 	{
 		Name:        "url",
@@ -354,11 +803,32 @@ var Commands = []cli.Command{
 		Description: "Argument is a machine name.",
 		Action:      fatalOnError(cmdURL),
 	},
+	{
+		Name:   "validate",
+		Usage:  "Check that every stored machine can be loaded",
+		Action: fatalOnError(cmdValidate),
+	},
 	{
 		Name:   "version",
 		Usage:  "Show the Docker Machine version information",
 		Action: fatalOnError(cmdVersion),
 	},
+	{
+		Name:  "wireguard",
+		Usage: "List and update the WireGuard overlay between the machines",
+		Subcommands: []cli.Command{
+			{
+				Name:   "ls",
+				Usage:  "List the machines of the WireGuard overlay",
+				Action: fatalOnError(cmdWireGuardLs),
+			},
+			{
+				Name:   "sync",
+				Usage:  "Configure every running machine of the overlay with all the others as peers",
+				Action: fatalOnError(cmdWireGuardSync),
+			},
+		},
+	},
 }
 
 func printIP(h *host.Host) func() error {
@@ -372,9 +842,8 @@ func printIP(h *host.Host) func() error {
 	}
 }
 
-// machineCommand maps the command name to the corresponding machine command.
-// We run commands concurrently and communicate back an error if there was one.
-func machineCommand(actionName string, host *host.Host, errorChan chan<- error) {
+// machineAction maps the command name to the corresponding machine command.
+func machineAction(actionName string, host *host.Host) func() error {
 	// TODO: These actions should have their own type.
 	commands := map[string](func() error){
 		"configureAuth": host.ConfigureAuth,
@@ -386,9 +855,15 @@ func machineCommand(actionName string, host *host.Host, errorChan chan<- error)
 		"ip":            printIP(host),
 	}
 
+	return commands[actionName]
+}
+
+// machineCommand runs the machine command of the command name.
+// We run commands concurrently and communicate back an error if there was one.
+func machineCommand(actionName string, host *host.Host, errorChan chan<- error) {
 	log.Debugf("command=%s machine=%s", actionName, host.Name)
 
-	errorChan <- commands[actionName]()
+	errorChan <- machineAction(actionName, host)()
 }
 
 // runActionForeachMachine will run the command across multiple machines
@@ -428,13 +903,44 @@ func consolidateErrs(errs []error) error {
 }
 
 func runActionWithContext(actionName string, c CommandLine) error {
-	store := getStore(c)
-
-	hosts, err := getHostsFromContext(c)
+	hosts, err := getTargetHostsFromContext(c)
 	if err != nil {
 		return err
 	}
 
+	return runActionOnHosts(actionName, c, hosts, nil)
+}
+
+// runActionOnHosts runs the action on the machines and saves them, then runs
+// after, if given, on the machines the action succeeded on. The machines
+// selected by --all or --filter are reported on one by one, the failures on
+// some of them not keeping the others from going on with after.
+func runActionOnHosts(actionName string, c CommandLine, hosts []*host.Host, after func(h *host.Host) error) error {
+	store := getStore(c)
+
+	if isBulk(c) {
+		if len(hosts) == 0 {
+			log.Info("No machine matches")
+			return nil
+		}
+
+		return runBulkAction(hosts, bulkActionVerbs[actionName], func(h *host.Host) error {
+			if err := machineAction(actionName, h)(); err != nil {
+				return err
+			}
+
+			if err := saveHost(store, h); err != nil {
+				return err
+			}
+
+			if after != nil {
+				return after(h)
+			}
+
+			return nil
+		})
+	}
+
 	if len(hosts) == 0 {
 		return ErrNoMachineSpecified
 	}
@@ -449,6 +955,14 @@ func runActionWithContext(actionName string, c CommandLine) error {
 		}
 	}
 
+	if after != nil {
+		for _, h := range hosts {
+			if err := after(h); err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
 
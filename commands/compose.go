@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/machine/commands/mcndirs"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// composeCertFiles are the files Compose expects to find in DOCKER_CERT_PATH.
+var composeCertFiles = []string{"ca.pem", "cert.pem", "key.pem"}
+
+func cmdComposeEnv(c CommandLine) error {
+	// Ensure that log messages always go to stderr when this command is
+	// being run (its output is intended to be redirected to a file)
+	log.SetOutWriter(os.Stderr)
+
+	if len(c.Args()) != 1 {
+		return ErrExpectedOneMachine
+	}
+
+	host, err := getFirstArgHost(c)
+	if err != nil {
+		return err
+	}
+
+	dockerHost, _, err := runConnectionBoilerplate(host, c)
+	if err != nil {
+		return fmt.Errorf("Error running connection boilerplate: %s", err)
+	}
+
+	env, err := composeEnv(dockerHost, filepath.Join(mcndirs.GetMachineDir(), host.Name), host.Name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(env)
+
+	return nil
+}
+
+// composeEnv renders the connection settings of a machine as the KEY=VALUE
+// lines of a Compose .env file. Compose resolves relative paths against the
+// project directory, so the cert path is made absolute and checked to hold
+// the TLS files Compose needs.
+func composeEnv(dockerHost, certPath, machineName string) (string, error) {
+	certPath, err := filepath.Abs(certPath)
+	if err != nil {
+		return "", err
+	}
+
+	for _, name := range composeCertFiles {
+		if _, err := os.Stat(filepath.Join(certPath, name)); err != nil {
+			return "", fmt.Errorf("Error checking the TLS certificates of %s: %s", machineName, err)
+		}
+	}
+
+	return fmt.Sprintf("DOCKER_TLS_VERIFY=1\nDOCKER_HOST=%s\nDOCKER_CERT_PATH=%s\nDOCKER_MACHINE_NAME=%s\n",
+		dockerHost, certPath, machineName), nil
+}
@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComposeEnv(t *testing.T) {
+	certPath, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(certPath)
+
+	for _, name := range composeCertFiles {
+		if err := ioutil.WriteFile(filepath.Join(certPath, name), []byte{}, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	env, err := composeEnv("tcp://192.168.99.100:2376", certPath, "dev")
+
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("DOCKER_TLS_VERIFY=1\nDOCKER_HOST=tcp://192.168.99.100:2376\nDOCKER_CERT_PATH=%s\nDOCKER_MACHINE_NAME=dev\n", certPath), env)
+}
+
+func TestComposeEnvErrorsGivenMissingCerts(t *testing.T) {
+	certPath, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(certPath)
+
+	_, err = composeEnv("tcp://192.168.99.100:2376", certPath, "dev")
+
+	assert.Error(t, err)
+}
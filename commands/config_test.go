@@ -26,6 +26,10 @@ func (fcg FakeCertGenerator) GenerateCert(hosts []string, certFile, keyFile, caF
 	return nil
 }
 
+func (fcg FakeCertGenerator) GenerateCertWithOptions(hosts []string, certFile, keyFile, caFile, caKeyFile string, opts cert.Options) error {
+	return nil
+}
+
 func (fcg FakeCertGenerator) ValidateCertificate(addr string, authOptions *auth.Options) (bool, error) {
 	return fcg.fakeValidateCertificate.IsValid, fcg.fakeValidateCertificate.Err
 }
@@ -4,11 +4,16 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"errors"
 
@@ -17,19 +22,32 @@ import (
 	"github.com/docker/machine/drivers/errdriver"
 	"github.com/docker/machine/libmachine"
 	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/cert"
 	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/drivers/rpc"
 	"github.com/docker/machine/libmachine/engine"
+	"github.com/docker/machine/libmachine/hook"
 	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/mcnerror"
 	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/docker/machine/libmachine/nfs"
 	"github.com/docker/machine/libmachine/persist"
+	"github.com/docker/machine/libmachine/ssh"
 	"github.com/docker/machine/libmachine/swarm"
+	"github.com/docker/machine/libmachine/wireguard"
 )
 
 var (
 	errNoMachineName = errors.New("Error: No machine name specified")
+
+	// reImageReference matches a Docker image reference: an optional
+	// registry host, a repository path, an optional tag and digest.
+	reImageReference = regexp.MustCompile(`^(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)*(?::[0-9]+)?/)?` +
+		`[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*` +
+		`(?::[\w][\w.-]{0,127})?(?:@[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9a-fA-F]{32,})?$`)
+
+	reSHA256 = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
 )
 
 var (
@@ -47,6 +65,24 @@ var (
 			Value:  "https://get.docker.com",
 			EnvVar: "MACHINE_DOCKER_INSTALL_URL",
 		},
+		cli.StringFlag{
+			Name:   "engine-install-url-sha256",
+			Usage:  "SHA256 checksum the engine install script must have to be run",
+			Value:  "",
+			EnvVar: "MACHINE_DOCKER_INSTALL_URL_SHA256",
+		},
+		cli.StringFlag{
+			Name:   "engine-install-method",
+			Usage:  "How to install the engine: package, with the install script, static, with the static binaries of --engine-install-version, or none",
+			Value:  engine.InstallMethodPackage,
+			EnvVar: "MACHINE_DOCKER_INSTALL_METHOD",
+		},
+		cli.StringFlag{
+			Name:   "engine-install-version",
+			Usage:  "Version of the engine installed with the static install method, such as 17.03.2-ce",
+			Value:  "",
+			EnvVar: "MACHINE_DOCKER_INSTALL_VERSION",
+		},
 		cli.StringSliceFlag{
 			Name:  "engine-opt",
 			Usage: "Specify arbitrary flags to include with the created engine in the form flag=value",
@@ -76,6 +112,41 @@ var (
 			Usage: "Specify environment variables to set in the engine",
 			Value: &cli.StringSlice{},
 		},
+		cli.StringSliceFlag{
+			Name:  "engine-ca-cert",
+			Usage: "Specify PEM files of extra CA certificates for the machine to trust",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringFlag{
+			Name:   "engine-timezone",
+			Usage:  "Specify the time zone of the machine, e.g. America/New_York",
+			EnvVar: "MACHINE_TIMEZONE",
+		},
+		cli.BoolFlag{
+			Name:  "engine-inotify-preset",
+			Usage: "Raise the inotify watch and instance limits of the machine",
+		},
+		cli.StringFlag{
+			Name:  "http-proxy",
+			Usage: "Proxy for the engine and the provisioning of the machine to use for HTTP",
+		},
+		cli.StringFlag{
+			Name:  "https-proxy",
+			Usage: "Proxy for the engine and the provisioning of the machine to use for HTTPS",
+		},
+		cli.StringFlag{
+			Name:  "no-proxy",
+			Usage: "Comma separated hosts for the engine and the provisioning of the machine to reach without proxy",
+		},
+		cli.BoolFlag{
+			Name:  "engine-env-proxy",
+			Usage: "Use the HTTP_PROXY, HTTPS_PROXY and NO_PROXY of the environment for the proxy flags not given",
+		},
+		cli.StringFlag{
+			Name:   drivers.SSHProxyJumpFlag,
+			Usage:  "Reach the machine with SSH through a bastion, given as [user@]host[:port]",
+			EnvVar: "MACHINE_SSH_PROXY_JUMP",
+		},
 		cli.BoolFlag{
 			Name:  "swarm",
 			Usage: "Configure Machine with Swarm",
@@ -83,7 +154,7 @@ var (
 		cli.StringFlag{
 			Name:   "swarm-image",
 			Usage:  "Specify Docker image to use for Swarm",
-			Value:  "swarm:latest",
+			Value:  swarm.DockerImage,
 			EnvVar: "MACHINE_SWARM_IMAGE",
 		},
 		cli.BoolFlag{
@@ -95,6 +166,11 @@ var (
 			Usage: "Discovery service to use with Swarm",
 			Value: "",
 		},
+		cli.StringSliceFlag{
+			Name:  "swarm-discovery-opt",
+			Usage: "Define options for the Swarm discovery backend",
+			Value: &cli.StringSlice{},
+		},
 		cli.StringFlag{
 			Name:  "swarm-strategy",
 			Usage: "Define a default scheduling strategy for Swarm",
@@ -115,75 +191,502 @@ var (
 			Usage: "addr to advertise for Swarm (default: detect and use the machine IP)",
 			Value: "",
 		},
+		cli.BoolFlag{
+			Name:  "no-provision",
+			Usage: "Create the machine without provisioning it, run 'start --provision' to provision it later",
+		},
+		cli.BoolFlag{
+			Name:  "nfs-share",
+			Usage: "Mount the shared folders of the driver over NFS, exported by the host (macOS and Linux hosts only)",
+		},
+		cli.BoolFlag{
+			Name:  "wireguard",
+			Usage: "Join the machine to a WireGuard overlay with the other machines created with --wireguard",
+		},
+		cli.StringFlag{
+			Name:   "wireguard-subnet",
+			Usage:  "Overlay network of the WireGuard machines to get an address in",
+			Value:  wireguard.DefaultSubnet,
+			EnvVar: "MACHINE_WIREGUARD_SUBNET",
+		},
+		cli.IntFlag{
+			Name:  "wireguard-port",
+			Usage: "UDP port for the machine to listen to the other WireGuard machines on",
+			Value: wireguard.DefaultListenPort,
+		},
+		cli.StringSliceFlag{
+			Name:  "env-no-proxy",
+			Usage: "Host or network for 'env --no-proxy' to add to NO_PROXY along with the machine IP (may be repeated)",
+			Value: &cli.StringSlice{},
+		},
+		cli.BoolFlag{
+			Name:  "env-no-proxy-subnet",
+			Usage: "Make 'env --no-proxy' add the network of the machine to NO_PROXY along with its IP",
+		},
+		cli.StringSliceFlag{
+			Name:  "hook-script",
+			Usage: "Script to run at the pre-create, post-provision, pre-stop and post-remove events of the machine",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "hook-url",
+			Usage: "Webhook URL to POST the pre-create, post-provision, pre-stop and post-remove events of the machine to",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "label",
+			Usage: "Label of the machine as key=value, for ls to filter on (may be repeated)",
+			Value: &cli.StringSlice{},
+		},
 		cli.StringSliceFlag{
 			Name:  "tls-san",
 			Usage: "Support extra SANs for TLS certs",
 			Value: &cli.StringSlice{},
 		},
+		cli.StringFlag{
+			Name:  "tls-key-type",
+			Usage: "Type of the key of the server certificate, rsa or ecdsa (P-256)",
+			Value: cert.KeyTypeRSA,
+		},
+		cli.IntFlag{
+			Name:  "tls-key-bits",
+			Usage: "Size of the RSA key of the server certificate",
+			Value: 2048,
+		},
+		cli.StringFlag{
+			Name:  "tls-cert-validity",
+			Usage: "Validity of the server certificate, a number of days such as 365d or a duration such as 8760h",
+			Value: "1080d",
+		},
+		cli.IntFlag{
+			Name:  "count",
+			Usage: "Create this many machines, named after the given one with a -1, -2... suffix",
+		},
+		cli.BoolFlag{
+			Name:  "resume",
+			Usage: "Resume the creation of the given machines from the step it failed at",
+		},
+		cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Check the configuration and the driver prerequisites, and print the machines which would be created as JSON, without creating them",
+		},
+		cli.IntFlag{
+			Name:  "parallel",
+			Usage: "Maximum number of machines created at the same time",
+			Value: libmachine.DefaultCreateParallelism,
+		},
 	}
 )
 
 func cmdCreateInner(c CommandLine) error {
-	if len(c.Args()) > 1 {
-		return fmt.Errorf("Invalid command line. Found extra arguments %v", c.Args()[1:])
+	if c.Bool("resume") {
+		return resumeCreate(c)
+	}
+
+	names, err := createMachineNames(c.Args(), c.Int("count"))
+	if err != nil {
+		return err
+	}
+
+	if len(names) == 0 {
+		c.ShowHelp()
+		return errNoMachineName
+	}
+
+	for _, name := range names {
+		if !host.ValidateHostName(name) {
+			return fmt.Errorf("Error creating machine: %s", mcnerror.ErrInvalidHostname)
+		}
 	}
 
-	name := c.Args().First()
-	driverName := c.String("driver")
 	certInfo := getCertPathInfoFromContext(c)
+	store := getStore(c)
+
+	if err := validateSwarmDiscovery(c.String("swarm-discovery")); err != nil {
+		return fmt.Errorf("Error parsing swarm discovery: %s", err)
+	}
+
+	if err := validateSwarmImage(c.String("swarm-image")); err != nil {
+		return fmt.Errorf("Error parsing swarm image: %s", err)
+	}
+
+	if err := validateTimezone(c.String("engine-timezone")); err != nil {
+		return fmt.Errorf("Error parsing time zone: %s", err)
+	}
+
+	if err := validateInstallURL(c.String("engine-install-url"), c.String("engine-install-url-sha256")); err != nil {
+		return fmt.Errorf("Error parsing engine install URL: %s", err)
+	}
+
+	if err := validateInstallMethod(c.String("engine-install-method"), c.String("engine-install-version")); err != nil {
+		return fmt.Errorf("Error parsing engine install method: %s", err)
+	}
+
+	if _, err := serverCertOptionsFromContext(c); err != nil {
+		return fmt.Errorf("Error parsing TLS options: %s", err)
+	}
+
+	if c.Bool("wireguard") {
+		if err := wireguard.ValidateSubnet(c.String("wireguard-subnet")); err != nil {
+			return fmt.Errorf("Error parsing WireGuard options: %s", err)
+		}
+		if _, shared := store.(*persist.Etcdstore); shared {
+			return fmt.Errorf("Error parsing WireGuard options: %s", libmachine.ErrWireGuardSharedStore)
+		}
+	}
+
+	if jump := c.String(drivers.SSHProxyJumpFlag); jump != "" {
+		if _, err := ssh.ParseProxyJump(jump); err != nil {
+			return fmt.Errorf("Error parsing SSH bastion: %s", err)
+		}
+	}
+
+	httpProxy, httpsProxy, _ := proxyFromContext(c)
+	for _, proxy := range []string{httpProxy, httpsProxy} {
+		if err := validateProxy(proxy); err != nil {
+			return fmt.Errorf("Error parsing proxy: %s", err)
+		}
+	}
+
+	// The certificates are installed again whenever the machine gets
+	// provisioned, so keep paths which don't depend on the working directory.
+	caCerts := []string{}
+	for _, caCert := range c.StringSlice("engine-ca-cert") {
+		if _, err := cert.ReadPEMCertificates(caCert); err != nil {
+			return fmt.Errorf("Error reading CA certificate: %s", err)
+		}
+
+		absPath, err := filepath.Abs(caCert)
+		if err != nil {
+			return fmt.Errorf("Error reading CA certificate: %s", err)
+		}
+		caCerts = append(caCerts, absPath)
+	}
+
+	if c.Bool("dry-run") {
+		return planCreate(c, store, names, certInfo, caCerts)
+	}
+
+	if len(names) == 1 {
+		h, err := newCreateHost(c, store, names[0], certInfo, caCerts)
+		if err != nil {
+			return err
+		}
+
+		if err := libmachine.Create(store, h); err != nil {
+			return fmt.Errorf("Error creating machine: %s", err)
+		}
+
+		if err := saveHost(store, h); err != nil {
+			return fmt.Errorf("Error attempting to save store: %s", err)
+		}
+
+		log.Infof("To see how to connect Docker to this machine, run: %s", fmt.Sprintf("%s env %s", os.Args[0], h.Name))
+
+		return nil
+	}
+
+	// Every machine is set up before any gets created, so that a typo in a
+	// flag doesn't leave half of them behind.
+	hosts := []*host.Host{}
+	for _, name := range names {
+		h, err := newCreateHost(c, store, name, certInfo, caCerts)
+		if err != nil {
+			return err
+		}
+		hosts = append(hosts, h)
+	}
+
+	createErrs := libmachine.CreateAll(store, hosts, c.Int("parallel"))
 
-	storePath := c.GlobalString("storage-path")
+	errs := []error{}
+	for _, h := range hosts {
+		if err, failed := createErrs[h.Name]; failed {
+			errs = append(errs, fmt.Errorf("Error creating machine %s: %s", h.Name, err))
+			continue
+		}
 
-	store := &persist.Filestore{
-		Path:             storePath,
-		CaCertPath:       certInfo.CaCertPath,
-		CaPrivateKeyPath: certInfo.CaPrivateKeyPath,
+		if err := saveHost(store, h); err != nil {
+			errs = append(errs, fmt.Errorf("Error attempting to save %s to the store: %s", h.Name, err))
+		}
 	}
 
-	if name == "" {
+	log.Infof("Created %d of %d machines", len(hosts)-len(errs), len(hosts))
+
+	if len(errs) > 0 {
+		return consolidateErrs(errs)
+	}
+
+	log.Infof("To see how to connect Docker to a machine, run: %s", fmt.Sprintf("%s env <name>", os.Args[0]))
+
+	return nil
+}
+
+// resumeCreate resumes the creation of the machines given, which are
+// configured already.
+func resumeCreate(c CommandLine) error {
+	if len(c.Args()) == 0 {
 		c.ShowHelp()
 		return errNoMachineName
 	}
 
-	validName := host.ValidateHostName(name)
-	if !validName {
-		return fmt.Errorf("Error creating machine: %s", mcnerror.ErrInvalidHostname)
+	store := getStore(c)
+	for _, name := range c.Args() {
+		h, err := loadHost(store, name)
+		if err != nil {
+			return err
+		}
+
+		if err := libmachine.Resume(store, h); err != nil {
+			return fmt.Errorf("Error resuming the creation of %s: %s", name, err)
+		}
+
+		if err := saveHost(store, h); err != nil {
+			return err
+		}
+
+		log.Infof("%s created", name)
 	}
 
-	if err := validateSwarmDiscovery(c.String("swarm-discovery")); err != nil {
-		return fmt.Errorf("Error parsing swarm discovery: %s", err)
+	return nil
+}
+
+// createPlan is a machine which create --dry-run would create.
+type createPlan struct {
+	Name       string
+	DriverName string
+	// Resources are what creating the machine would make, on the provider
+	// and on this computer.
+	Resources   []string
+	Driver      map[string]interface{}
+	HostOptions *host.Options
+}
+
+// reSecretField matches the names of the fields of the drivers which hold
+// credentials, e.g. AccessToken, SecretKey or Password.
+var reSecretField = regexp.MustCompile(`(?i)(token|secret|password|key)$`)
+
+// planCreate sets up the machines and runs the pre-create checks of their
+// drivers, then prints what would be created instead of creating anything.
+func planCreate(c CommandLine, store persist.Store, names []string, certInfo cert.PathInfo, caCerts []string) error {
+	plans := []createPlan{}
+	for _, name := range names {
+		h, err := newCreateHost(c, store, name, certInfo, caCerts)
+		if err != nil {
+			return err
+		}
+
+		log.Infof("Running pre-create checks for %s...", name)
+		if err := h.Driver.PreCreateCheck(); err != nil {
+			return fmt.Errorf("Error with pre-create check of %s: %s", name, err)
+		}
+
+		plan, err := newCreatePlan(h)
+		if err != nil {
+			return err
+		}
+		plans = append(plans, plan)
+	}
+
+	return writeCreatePlans(os.Stdout, plans)
+}
+
+// newCreatePlan returns the plan of a machine, without the credentials of
+// its driver.
+func newCreatePlan(h *host.Host) (createPlan, error) {
+	data, err := persist.DriverConfig(h.Driver)
+	if err != nil {
+		return createPlan{}, fmt.Errorf("Error getting the configuration of %s: %s", h.Name, err)
+	}
+
+	config := map[string]interface{}{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return createPlan{}, fmt.Errorf("Error getting the configuration of %s: %s", h.Name, err)
+	}
+	redactSecrets(config)
+
+	hostOptions := h.HostOptions
+	if h.UsesWireGuard() {
+		options := *h.HostOptions
+		wireGuardOptions := *options.WireGuardOptions
+		wireGuardOptions.PrivateKey = "<redacted>"
+		options.WireGuardOptions = &wireGuardOptions
+		hostOptions = &options
+	}
+
+	return createPlan{
+		Name:        h.Name,
+		DriverName:  h.DriverName,
+		Resources:   plannedResources(h),
+		Driver:      config,
+		HostOptions: hostOptions,
+	}, nil
+}
+
+// plannedResources returns what creating the machine would make.
+func plannedResources(h *host.Host) []string {
+	resources := []string{fmt.Sprintf("%s machine %s", h.DriverName, h.Name)}
+	// TODO: Not really a fan of just checking "none" here.
+	provisioned := h.IsProvisioned() && h.DriverName != "none"
+
+	if keyPath := h.Driver.GetSSHKeyPath(); keyPath != "" {
+		resources = append(resources, "SSH key "+keyPath)
+	}
+
+	if authOptions := h.HostOptions.AuthOptions; authOptions != nil {
+		if _, err := os.Stat(authOptions.CaCertPath); os.IsNotExist(err) {
+			resources = append(resources, "CA certificate "+authOptions.CaCertPath)
+		}
+		if provisioned {
+			resources = append(resources, "server certificate "+authOptions.ServerCertPath)
+		}
+	}
+
+	if engineOptions := h.HostOptions.EngineOptions; engineOptions != nil && provisioned {
+		switch engineOptions.InstallMethod {
+		case engine.InstallMethodStatic:
+			resources = append(resources, "Docker engine "+engineOptions.InstallVersion+" from the static binaries")
+		case engine.InstallMethodNone:
+		default:
+			resources = append(resources, "Docker engine from "+engineOptions.InstallURL)
+		}
+	}
+
+	if h.HostOptions.NFSShare {
+		resources = append(resources, "NFS exports of the shared folders in /etc/exports")
+	}
+
+	if h.UsesWireGuard() {
+		resources = append(resources, "WireGuard address in "+h.HostOptions.WireGuardOptions.Subnet)
+	}
+
+	return resources
+}
+
+// redactSecrets hides the values of the credential fields of a driver
+// configuration, recursively.
+func redactSecrets(config map[string]interface{}) {
+	for name, value := range config {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			redactSecrets(v)
+		case string:
+			if v != "" && reSecretField.MatchString(name) {
+				config[name] = "<redacted>"
+			}
+		}
+	}
+}
+
+func writeCreatePlans(out io.Writer, plans []createPlan) error {
+	data, err := json.MarshalIndent(plans, "", "    ")
+	if err != nil {
+		return err
 	}
 
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
+// createMachineNames returns the names of the machines to create: those
+// given, or, with a count, that many names derived from the single one given.
+func createMachineNames(args []string, count int) ([]string, error) {
+	if count < 0 {
+		return nil, fmt.Errorf("Invalid count %d, it must be positive", count)
+	}
+
+	if count == 0 {
+		seen := map[string]bool{}
+		for _, name := range args {
+			if seen[name] {
+				return nil, fmt.Errorf("Invalid command line. Machine %s is given more than once", name)
+			}
+			seen[name] = true
+		}
+
+		return args, nil
+	}
+
+	if len(args) > 1 {
+		return nil, fmt.Errorf("Invalid command line. A single machine name is expected with --count, found %v", args)
+	}
+
+	if len(args) == 0 {
+		return []string{}, nil
+	}
+
+	names := []string{}
+	for i := 1; i <= count; i++ {
+		names = append(names, fmt.Sprintf("%s-%d", args[0], i))
+	}
+
+	return names, nil
+}
+
+// newCreateHost returns the host to create for name, configured from the
+// command line.
+func newCreateHost(c CommandLine, store persist.Store, name string, certInfo cert.PathInfo, caCerts []string) (*host.Host, error) {
+	driverName := c.String("driver")
+
 	// TODO: Fix hacky JSON solution
 	bareDriverData, err := json.Marshal(&drivers.BaseDriver{
 		MachineName: name,
 		StorePath:   c.GlobalString("storage-path"),
 	})
 	if err != nil {
-		return fmt.Errorf("Error attempting to marshal bare driver data: %s", err)
+		return nil, fmt.Errorf("Error attempting to marshal bare driver data: %s", err)
 	}
 
 	driver, err := newPluginDriver(driverName, bareDriverData)
 	if err != nil {
-		return fmt.Errorf("Error loading driver %q: %s", driverName, err)
+		return nil, fmt.Errorf("Error loading driver %q: %s", driverName, err)
 	}
 
 	h, err := store.NewHost(driver)
 	if err != nil {
-		return fmt.Errorf("Error getting new host: %s", err)
+		return nil, fmt.Errorf("Error getting new host: %s", err)
+	}
+
+	sysctls := []string{}
+	if c.Bool("engine-inotify-preset") {
+		sysctls = append(sysctls, engine.InotifySysctls...)
+	}
+
+	httpProxy, httpsProxy, noProxy := proxyFromContext(c)
+
+	serverCertOpts, err := serverCertOptionsFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+
+	wireGuardOptions, err := wireGuardOptionsFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := parseLabels(c.StringSlice("label"))
+	if err != nil {
+		return nil, err
+	}
+	if len(labels) == 0 {
+		labels = nil
 	}
 
 	h.HostOptions = &host.Options{
 		AuthOptions: &auth.Options{
-			CertDir:          mcndirs.GetMachineCertDir(),
-			CaCertPath:       certInfo.CaCertPath,
-			CaPrivateKeyPath: certInfo.CaPrivateKeyPath,
-			ClientCertPath:   certInfo.ClientCertPath,
-			ClientKeyPath:    certInfo.ClientKeyPath,
-			ServerCertPath:   filepath.Join(mcndirs.GetMachineDir(), name, "server.pem"),
-			ServerKeyPath:    filepath.Join(mcndirs.GetMachineDir(), name, "server-key.pem"),
-			StorePath:        filepath.Join(mcndirs.GetMachineDir(), name),
-			ServerCertSANs:   c.StringSlice("tls-san"),
+			CertDir:            mcndirs.GetMachineCertDir(),
+			CaCertPath:         certInfo.CaCertPath,
+			CaPrivateKeyPath:   certInfo.CaPrivateKeyPath,
+			ClientCertPath:     certInfo.ClientCertPath,
+			ClientKeyPath:      certInfo.ClientKeyPath,
+			ServerCertPath:     filepath.Join(mcndirs.GetMachineDir(), name, "server.pem"),
+			ServerKeyPath:      filepath.Join(mcndirs.GetMachineDir(), name, "server-key.pem"),
+			StorePath:          filepath.Join(mcndirs.GetMachineDir(), name),
+			ServerCertSANs:     c.StringSlice("tls-san"),
+			ServerKeyType:      serverCertOpts.KeyType,
+			ServerKeyBits:      serverCertOpts.Bits,
+			ServerCertValidity: serverCertOpts.Validity,
+			Signer:             cert.GlobalSigner(),
 		},
 		EngineOptions: &engine.Options{
 			ArbitraryFlags:   c.StringSlice("engine-opt"),
@@ -194,25 +697,45 @@ func cmdCreateInner(c CommandLine) error {
 			StorageDriver:    c.String("engine-storage-driver"),
 			TLSVerify:        true,
 			InstallURL:       c.String("engine-install-url"),
+			InstallURLSHA256: c.String("engine-install-url-sha256"),
+			InstallMethod:    c.String("engine-install-method"),
+			InstallVersion:   c.String("engine-install-version"),
+			CACerts:          caCerts,
+			Timezone:         c.String("engine-timezone"),
+			Sysctls:          sysctls,
+			HTTPProxy:        httpProxy,
+			HTTPSProxy:       httpsProxy,
+			NoProxy:          noProxy,
 		},
 		SwarmOptions: &swarm.Options{
 			IsSwarm:        c.Bool("swarm"),
 			Image:          c.String("swarm-image"),
 			Master:         c.Bool("swarm-master"),
 			Discovery:      c.String("swarm-discovery"),
+			DiscoveryOpts:  c.StringSlice("swarm-discovery-opt"),
 			Address:        c.String("swarm-addr"),
 			Host:           c.String("swarm-host"),
 			Strategy:       c.String("swarm-strategy"),
 			ArbitraryFlags: c.StringSlice("swarm-opt"),
 		},
+		HookOptions: &hook.Options{
+			Scripts:  c.StringSlice("hook-script"),
+			Webhooks: c.StringSlice("hook-url"),
+		},
+		Unprovisioned:    c.Bool("no-provision"),
+		NFSShare:         c.Bool("nfs-share"),
+		WireGuardOptions: wireGuardOptions,
+		NoProxy:          splitNoProxy(c.StringSlice("env-no-proxy")),
+		NoProxySubnet:    c.Bool("env-no-proxy-subnet"),
+		Labels:           labels,
 	}
 
 	exists, err := store.Exists(h.Name)
 	if err != nil {
-		return fmt.Errorf("Error checking if host exists: %s", err)
+		return nil, fmt.Errorf("Error checking if host exists: %s", err)
 	}
 	if exists {
-		return mcnerror.ErrHostAlreadyExists{
+		return nil, mcnerror.ErrHostAlreadyExists{
 			Name: h.Name,
 		}
 	}
@@ -221,23 +744,25 @@ func cmdCreateInner(c CommandLine) error {
 	// driver parameters (an interface fulfilling drivers.DriverOptions,
 	// concrete type rpcdriver.RpcFlags).
 	mcnFlags := driver.GetCreateFlags()
-	driverOpts := getDriverOpts(c, mcnFlags)
-
-	if err := h.Driver.SetConfigFromFlags(driverOpts); err != nil {
-		return fmt.Errorf("Error setting machine configuration from flags provided: %s", err)
+	driverOpts, err := getDriverOpts(c, mcnFlags)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := libmachine.Create(store, h); err != nil {
-		return fmt.Errorf("Error creating machine: %s", err)
+	if err := h.Driver.SetConfigFromFlags(driverOpts); err != nil {
+		return nil, fmt.Errorf("Error setting machine configuration from flags provided: %s", err)
 	}
 
-	if err := saveHost(store, h); err != nil {
-		return fmt.Errorf("Error attempting to save store: %s", err)
+	if h.HostOptions.NFSShare {
+		if err := nfs.Supported(runtime.GOOS); err != nil {
+			return nil, err
+		}
+		if _, err := drivers.AsSharedFolderer(h.Driver); err != nil {
+			return nil, fmt.Errorf("Error using --nfs-share: %s", err)
+		}
 	}
 
-	log.Infof("To see how to connect Docker to this machine, run: %s", fmt.Sprintf("%s env %s", os.Args[0], name))
-
-	return nil
+	return h, nil
 }
 
 // The following function is needed because the CLI acrobatics that we're doing
@@ -276,10 +801,43 @@ func flagHackLookup(flagName string) string {
 	return ""
 }
 
+// isResumeFlag tells whether the arguments resume the creation of machines,
+// with --resume or --resume=true.
+func isResumeFlag(args []string) bool {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+
+		arg = "-" + strings.TrimLeft(arg, "-")
+		if arg == "-resume" {
+			return true
+		}
+
+		if strings.HasPrefix(arg, "-resume=") {
+			resume, err := strconv.ParseBool(strings.TrimPrefix(arg, "-resume="))
+			return err == nil && resume
+		}
+	}
+
+	return false
+}
+
 func cmdCreateOuter(c CommandLine) error {
 	const (
 		flagLookupMachineName = "flag-lookup"
 	)
+	// The machines resumed have their drivers already.
+	if isResumeFlag(os.Args) {
+		for i := range c.Application().Commands {
+			cmd := &c.Application().Commands[i]
+			if cmd.HasName("create") {
+				addDriverFlagsToCommand(nil, cmd)
+			}
+		}
+		return c.Application().Run(os.Args)
+	}
+
 	driverName := flagHackLookup("--driver")
 
 	// We didn't recognize the driver name.
@@ -339,7 +897,7 @@ func cmdCreateOuter(c CommandLine) error {
 	return c.Application().Run(os.Args)
 }
 
-func getDriverOpts(c CommandLine, mcnflags []mcnflag.Flag) drivers.DriverOptions {
+func getDriverOpts(c CommandLine, mcnflags []mcnflag.Flag) (drivers.DriverOptions, error) {
 	// TODO: This function is pretty damn YOLO and would benefit from some
 	// sanity checking around types and assertions.
 	//
@@ -371,7 +929,11 @@ func getDriverOpts(c CommandLine, mcnflags []mcnflag.Flag) drivers.DriverOptions
 		}
 	}
 
-	return driverOpts
+	if err := resolveCredentials(driverOpts.Values); err != nil {
+		return nil, err
+	}
+
+	return driverOpts, nil
 }
 
 func convertMcnFlagsToCliFlags(mcnFlags []mcnflag.Flag) ([]cli.Flag, error) {
@@ -442,9 +1004,121 @@ func validateSwarmDiscovery(discovery string) error {
 		return err
 	}
 
-	if matched {
+	if !matched {
+		return fmt.Errorf("Swarm Discovery URL was in the wrong format: %s", discovery)
+	}
+
+	backend := strings.SplitN(discovery, "://", 2)[0]
+	for _, supported := range swarm.DiscoveryBackends {
+		if backend == supported {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Swarm Discovery backend %q is not supported, must be one of: %s", backend, strings.Join(swarm.DiscoveryBackends, ", "))
+}
+
+func validateSwarmImage(image string) error {
+	if reImageReference.MatchString(image) {
+		return nil
+	}
+
+	return fmt.Errorf("Swarm image reference was in the wrong format: %q", image)
+}
+
+func validateInstallURL(installURL, sha256 string) error {
+	u, err := url.Parse(installURL)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("Install URL must be an https URL, not %q", installURL)
+	}
+
+	if sha256 != "" && !reSHA256.MatchString(sha256) {
+		return fmt.Errorf("Install script checksum must be 64 hexadecimal digits, not %q", sha256)
+	}
+
+	return nil
+}
+
+// validateInstallMethod checks the engine install method is known, and that
+// the static one is given the version to install.
+func validateInstallMethod(method, version string) error {
+	switch method {
+	case engine.InstallMethodPackage, engine.InstallMethodNone:
+		return nil
+	case engine.InstallMethodStatic:
+		if version == "" {
+			return fmt.Errorf("The %s install method needs --engine-install-version", method)
+		}
+		if !reEngineVersion.MatchString(version) {
+			return fmt.Errorf("Engine version must be a version such as 17.03.2-ce, not %q", version)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("Install method must be %s, %s or %s, not %q", engine.InstallMethodPackage, engine.InstallMethodStatic, engine.InstallMethodNone, method)
+}
+
+// proxyFromContext returns the proxy settings given on the command line,
+// completed with those of the environment if asked to.
+func proxyFromContext(c CommandLine) (httpProxy, httpsProxy, noProxy string) {
+	httpProxy = c.String("http-proxy")
+	httpsProxy = c.String("https-proxy")
+	noProxy = c.String("no-proxy")
+
+	if c.Bool("engine-env-proxy") {
+		for _, v := range []struct {
+			value *string
+			name  string
+		}{
+			{&httpProxy, "HTTP_PROXY"},
+			{&httpsProxy, "HTTPS_PROXY"},
+			{&noProxy, "NO_PROXY"},
+		} {
+			if *v.value == "" {
+				*v.value = os.Getenv(v.name)
+			}
+			if *v.value == "" {
+				*v.value = os.Getenv(strings.ToLower(v.name))
+			}
+		}
+	}
+
+	return httpProxy, httpsProxy, noProxy
+}
+
+func validateProxy(proxy string) error {
+	if proxy == "" {
+		return nil
+	}
+
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("Proxy must be a URL such as http://proxy.example.com:3128, not %q", proxy)
+	}
+
+	return nil
+}
+
+func validateTimezone(zone string) error {
+	if zone == "" {
 		return nil
 	}
 
-	return fmt.Errorf("Swarm Discovery URL was in the wrong format: %s", discovery)
+	if zone == "Local" {
+		return fmt.Errorf("Time zone must be a name from the tz database, such as Europe/Paris, not %q", zone)
+	}
+
+	if _, err := time.LoadLocation(zone); err != nil {
+		return fmt.Errorf("Unknown time zone %q: %s", zone, err)
+	}
+
+	return nil
 }
@@ -0,0 +1,189 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/docker/docker/pkg/term"
+	"github.com/docker/machine/libmachine/keychain"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	// credentialService identifies the credentials in the keychain.
+	credentialService = "docker-machine-credential"
+
+	// credentialPrefix starts the values of the flags of the drivers, or of
+	// their environment variables, which name a credential of the keychain
+	// rather than give it.
+	credentialPrefix = "credential:"
+)
+
+var (
+	errExpectedCredentialName = errors.New("Error: Expected one credential name as an argument")
+	errEmptyCredential        = errors.New("Error: the credential is empty")
+
+	reCredentialName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+)
+
+// credentialFlags are the flags of the drivers giving a key, a secret, a token
+// or a password, whose values can name a credential of the keychain. The other
+// flags are passed as they are, so their values don't read the keychain.
+var credentialFlags = map[string]bool{
+	"amazonec2-access-key":      true,
+	"amazonec2-secret-key":      true,
+	"amazonec2-session-token":   true,
+	"azure-password":            true,
+	"digitalocean-access-token": true,
+	"exoscale-api-key":          true,
+	"exoscale-api-secret-key":   true,
+	"hetzner-api-token":         true,
+	"hyperv-share-password":     true,
+	"openstack-password":        true,
+	"rackspace-api-key":         true,
+	"softlayer-api-key":         true,
+	"vmwarefusion-ssh-password": true,
+	"vmwarevcloudair-password":  true,
+	"vmwarevsphere-password":    true,
+}
+
+// getCredential returns the credential name from the keychain.
+var getCredential = func(name string) (string, error) {
+	return keychain.Get(credentialService, name)
+}
+
+func validateCredentialName(name string) error {
+	if !reCredentialName.MatchString(name) {
+		return fmt.Errorf("Invalid credential name %q, it must start with a letter or a digit, followed by letters, digits, '.', '_' or '-'", name)
+	}
+
+	return nil
+}
+
+// resolveCredential returns the credential named by value if it starts with
+// credentialPrefix, else value.
+func resolveCredential(value string) (string, error) {
+	if !strings.HasPrefix(value, credentialPrefix) {
+		return value, nil
+	}
+
+	name := strings.TrimPrefix(value, credentialPrefix)
+	credential, err := getCredential(name)
+	if err == keychain.ErrNotFound {
+		return "", fmt.Errorf("There's no credential %q in the keychain, add it with 'docker-machine credential add %s'", name, name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("Error reading the credential %q from the keychain: %s", name, err)
+	}
+
+	return credential, nil
+}
+
+// resolveCredentials replaces the values of the credential flags of a driver
+// which name a credential with the credential.
+func resolveCredentials(values map[string]interface{}) error {
+	for flagName, value := range values {
+		if !credentialFlags[flagName] {
+			continue
+		}
+		if v, ok := value.(string); ok {
+			resolved, err := resolveCredential(v)
+			if err != nil {
+				return fmt.Errorf("Error with --%s: %s", flagName, err)
+			}
+			values[flagName] = resolved
+		}
+	}
+
+	return nil
+}
+
+// readCredential reads the credential from stdin, without echoing it when
+// stdin is a terminal.
+func readCredential(name string) (string, error) {
+	var credential []byte
+	var err error
+
+	if fd := os.Stdin.Fd(); term.IsTerminal(fd) {
+		fmt.Fprintf(os.Stderr, "Credential %s: ", name)
+		credential, err = terminal.ReadPassword(int(fd))
+		fmt.Fprintln(os.Stderr)
+	} else {
+		credential, err = ioutil.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	value := strings.TrimRight(string(credential), "\r\n")
+	if value == "" {
+		return "", errEmptyCredential
+	}
+
+	return value, nil
+}
+
+func cmdCredentialAdd(c CommandLine) error {
+	if len(c.Args()) != 1 {
+		return errExpectedCredentialName
+	}
+
+	name := c.Args().First()
+	if err := validateCredentialName(name); err != nil {
+		return err
+	}
+
+	credential, err := readCredential(name)
+	if err != nil {
+		return err
+	}
+
+	if err := keychain.Set(credentialService, name, "Docker Machine credential "+name, credential); err != nil {
+		return fmt.Errorf("Error storing the credential %q in the keychain: %s", name, err)
+	}
+
+	return nil
+}
+
+func cmdCredentialLs(c CommandLine) error {
+	if len(c.Args()) > 0 {
+		return errTooManyArguments
+	}
+
+	names, err := keychain.List(credentialService)
+	if err != nil {
+		return fmt.Errorf("Error listing the credentials of the keychain: %s", err)
+	}
+
+	for _, name := range names {
+		fmt.Println(name)
+	}
+
+	return nil
+}
+
+func cmdCredentialRm(c CommandLine) error {
+	if len(c.Args()) == 0 {
+		return errors.New("Error: Expected at least one credential name as an argument")
+	}
+
+	errs := []error{}
+	for _, name := range c.Args() {
+		err := keychain.Delete(credentialService, name)
+		if err == keychain.ErrNotFound {
+			errs = append(errs, fmt.Errorf("There's no credential %q in the keychain", name))
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("Error removing the credential %q from the keychain: %s", name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return consolidateErrs(errs)
+	}
+
+	return nil
+}
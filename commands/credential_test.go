@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/docker/machine/libmachine/keychain"
+	"github.com/stretchr/testify/assert"
+)
+
+func fakeCredentials(credentials map[string]string) func() {
+	original := getCredential
+	getCredential = func(name string) (string, error) {
+		credential, ok := credentials[name]
+		if !ok {
+			return "", keychain.ErrNotFound
+		}
+		return credential, nil
+	}
+
+	return func() {
+		getCredential = original
+	}
+}
+
+func TestValidateCredentialName(t *testing.T) {
+	for _, name := range []string{"do", "aws-prod", "team.token_2"} {
+		assert.NoError(t, validateCredentialName(name), name)
+	}
+
+	for _, name := range []string{"", "-do", "aws prod", "a:b", "a/b"} {
+		assert.Error(t, validateCredentialName(name), name)
+	}
+}
+
+func TestResolveCredentials(t *testing.T) {
+	defer fakeCredentials(map[string]string{"do": "tok-123", "aws": "AKIA"})()
+
+	values := map[string]interface{}{
+		"digitalocean-access-token": "credential:do",
+		"digitalocean-region":       "nyc3",
+		"amazonec2-access-key":      "credential:aws",
+		"amazonec2-tags":            []string{"credential:aws", "env,prod"},
+		"engine-env":                "credential:do",
+		"digitalocean-size":         1,
+		"digitalocean-ipv6":         true,
+	}
+
+	assert.NoError(t, resolveCredentials(values))
+	assert.Equal(t, map[string]interface{}{
+		"digitalocean-access-token": "tok-123",
+		"digitalocean-region":       "nyc3",
+		"amazonec2-access-key":      "AKIA",
+		"amazonec2-tags":            []string{"credential:aws", "env,prod"},
+		"engine-env":                "credential:do",
+		"digitalocean-size":         1,
+		"digitalocean-ipv6":         true,
+	}, values)
+}
+
+func TestResolveCredentialsMissing(t *testing.T) {
+	defer fakeCredentials(map[string]string{})()
+
+	err := resolveCredentials(map[string]interface{}{
+		"digitalocean-access-token": "credential:do",
+	})
+
+	assert.EqualError(t, err, `Error with --digitalocean-access-token: There's no credential "do" in the keychain, add it with 'docker-machine credential add do'`)
+}
@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/provision"
+	"github.com/docker/machine/libmachine/state"
+)
+
+// writeDaemonConfig prints each file which was found, preceded by its path.
+func writeDaemonConfig(w io.Writer, files []provision.DaemonConfigFile) {
+	for _, file := range files {
+		if !file.Found {
+			continue
+		}
+
+		fmt.Fprintf(w, "# %s\n%s\n", file.Path, file.Content)
+	}
+}
+
+// saveDaemonConfig saves each file which was found under dir, at the same
+// path as on the machine.
+func saveDaemonConfig(dir string, files []provision.DaemonConfigFile) error {
+	for _, file := range files {
+		if !file.Found {
+			continue
+		}
+
+		dest := filepath.Join(dir, filepath.FromSlash(file.Path))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+
+		if err := ioutil.WriteFile(dest, []byte(file.Content), 0644); err != nil {
+			return err
+		}
+
+		log.Infof("Saved %s to %s", file.Path, dest)
+	}
+
+	return nil
+}
+
+func cmdDaemonConfig(c CommandLine) error {
+	if len(c.Args()) != 1 {
+		return ErrExpectedOneMachine
+	}
+
+	h, err := getFirstArgHost(c)
+	if err != nil {
+		return err
+	}
+
+	currentState, err := h.Driver.GetState()
+	if err != nil {
+		return err
+	}
+
+	if currentState != state.Running {
+		return fmt.Errorf("Error: Cannot fetch the daemon config: Host %q is not running", h.Name)
+	}
+
+	provisioner, err := provision.DetectProvisioner(h.Driver)
+	if err != nil {
+		return err
+	}
+
+	files, err := provision.FetchDaemonConfig(provisioner)
+	if err != nil {
+		return err
+	}
+
+	distro := "this distribution"
+	if info, err := provisioner.GetOsReleaseInfo(); err == nil && info != nil && info.PrettyName != "" {
+		distro = info.PrettyName
+	}
+
+	for _, file := range files {
+		if !file.Found {
+			log.Warnf("%s was not found on %s, where it is expected for %s", file.Path, h.Name, distro)
+		}
+	}
+
+	if dir := c.String("output-dir"); dir != "" {
+		return saveDaemonConfig(dir, files)
+	}
+
+	writeDaemonConfig(os.Stdout, files)
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/machine/libmachine/provision"
+	"github.com/stretchr/testify/assert"
+)
+
+var testDaemonConfigFiles = []provision.DaemonConfigFile{
+	{Path: "/etc/docker/daemon.json"},
+	{Path: "/var/lib/boot2docker/profile", Content: "EXTRA_ARGS=''\n", Found: true},
+}
+
+func TestWriteDaemonConfig(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	writeDaemonConfig(out, testDaemonConfigFiles)
+
+	assert.Equal(t, "# /var/lib/boot2docker/profile\nEXTRA_ARGS=''\n\n", out.String())
+}
+
+func TestSaveDaemonConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = saveDaemonConfig(dir, testDaemonConfigFiles)
+
+	assert.NoError(t, err)
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "var", "lib", "boot2docker", "profile"))
+	assert.NoError(t, err)
+	assert.Equal(t, "EXTRA_ARGS=''\n", string(content))
+
+	_, err = os.Stat(filepath.Join(dir, "etc", "docker", "daemon.json"))
+	assert.True(t, os.IsNotExist(err))
+}
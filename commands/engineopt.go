@@ -0,0 +1,183 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/codegangsta/cli"
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/engine"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/persist"
+	"github.com/docker/machine/libmachine/state"
+)
+
+var engineOptionFlags = []cli.Flag{
+	cli.StringSliceFlag{
+		Name:  "engine-opt",
+		Usage: "Specify arbitrary flags of the engine in the form flag=value",
+		Value: &cli.StringSlice{},
+	},
+	cli.StringSliceFlag{
+		Name:  "engine-label",
+		Usage: "Specify labels of the engine",
+		Value: &cli.StringSlice{},
+	},
+	cli.StringSliceFlag{
+		Name:  "engine-insecure-registry",
+		Usage: "Specify insecure registries to allow with the engine",
+		Value: &cli.StringSlice{},
+	},
+	cli.StringSliceFlag{
+		Name:  "engine-registry-mirror",
+		Usage: "Specify registry mirrors to use",
+		Value: &cli.StringSlice{},
+	},
+}
+
+var errNoEngineOptionGiven = errors.New("Error: Expected at least one of --engine-opt, --engine-label, --engine-insecure-registry or --engine-registry-mirror")
+
+// engineOptionChanges are the values given to set-engine-opt and
+// unset-engine-opt, by engine option.
+type engineOptionChanges struct {
+	ArbitraryFlags   []string
+	Labels           []string
+	InsecureRegistry []string
+	RegistryMirror   []string
+}
+
+func getEngineOptionChanges(c CommandLine) (engineOptionChanges, error) {
+	changes := engineOptionChanges{
+		ArbitraryFlags:   c.StringSlice("engine-opt"),
+		Labels:           c.StringSlice("engine-label"),
+		InsecureRegistry: c.StringSlice("engine-insecure-registry"),
+		RegistryMirror:   c.StringSlice("engine-registry-mirror"),
+	}
+
+	if len(changes.ArbitraryFlags)+len(changes.Labels)+len(changes.InsecureRegistry)+len(changes.RegistryMirror) == 0 {
+		return changes, errNoEngineOptionGiven
+	}
+
+	return changes, nil
+}
+
+// editFunc edits the values of an engine option with the changes, the values
+// being matched by key.
+type editFunc func(values, changes []string, key func(value string) string) []string
+
+// apply edits each engine option with its changes. The flags and the labels,
+// given as key=value, are matched on their key, the registries on their
+// value.
+func (changes engineOptionChanges) apply(opts *engine.Options, edit editFunc) {
+	opts.ArbitraryFlags = edit(opts.ArbitraryFlags, changes.ArbitraryFlags, optionKey)
+	opts.Labels = edit(opts.Labels, changes.Labels, optionKey)
+	opts.InsecureRegistry = edit(opts.InsecureRegistry, changes.InsecureRegistry, optionValue)
+	opts.RegistryMirror = edit(opts.RegistryMirror, changes.RegistryMirror, optionValue)
+}
+
+// optionKey returns the key of a key=value option, the whole option if it
+// has no value.
+func optionKey(value string) string {
+	return strings.SplitN(value, "=", 2)[0]
+}
+
+func optionValue(value string) string {
+	return value
+}
+
+// addValues sets the changes in values: a change replaces the values with
+// the same key, in place, or is appended if there's none.
+func addValues(values, changes []string, key func(value string) string) []string {
+	for _, change := range changes {
+		edited := []string{}
+		replaced := false
+		for _, value := range values {
+			if key(value) != key(change) {
+				edited = append(edited, value)
+			} else if !replaced {
+				edited = append(edited, change)
+				replaced = true
+			}
+		}
+
+		if !replaced {
+			edited = append(edited, change)
+		}
+		values = edited
+	}
+
+	return values
+}
+
+// removeValues returns values without those with the key of a change.
+func removeValues(values, changes []string, key func(value string) string) []string {
+	removed := map[string]bool{}
+	for _, change := range changes {
+		removed[key(change)] = true
+	}
+
+	kept := []string{}
+	for _, value := range values {
+		if !removed[key(value)] {
+			kept = append(kept, value)
+		}
+	}
+
+	return kept
+}
+
+// editEngineOptions edits the engine options of the machine, saves it and
+// provisions it again so that the daemon is configured with them and
+// restarted. A machine which isn't running is configured the next time it's
+// provisioned.
+func editEngineOptions(c CommandLine, edit editFunc) error {
+	if len(c.Args()) != 1 {
+		return ErrExpectedOneMachine
+	}
+
+	changes, err := getEngineOptionChanges(c)
+	if err != nil {
+		return err
+	}
+
+	store := getStore(c)
+	if err := persist.Update(store, c.Args().First(), func(h *host.Host) error {
+		changes.apply(h.HostOptions.EngineOptions, edit)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	h, err := getFirstArgHost(c)
+	if err != nil {
+		return err
+	}
+
+	currentState, err := h.Driver.GetState()
+	if err != nil {
+		return err
+	}
+
+	if currentState != state.Running {
+		log.Infof("%s isn't running, run 'docker-machine provision %s' once it is to configure its engine.", h.Name, h.Name)
+		return nil
+	}
+
+	log.Infof("Configuring the engine of %s...", h.Name)
+
+	if err := libmachine.Provision(store, h); err != nil {
+		return fmt.Errorf("Error configuring the engine of %s: %s", h.Name, err)
+	}
+
+	return saveHost(store, h)
+}
+
+func cmdConfigSetEngineOpt(c CommandLine) error {
+	return editEngineOptions(c, addValues)
+}
+
+func cmdConfigUnsetEngineOpt(c CommandLine) error {
+	return editEngineOptions(c, removeValues)
+}
@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/docker/machine/libmachine/engine"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddValues(t *testing.T) {
+	assert.Equal(t, []string{"a", "b", "c"}, addValues([]string{"a", "b"}, []string{"b", "c"}, optionValue))
+	assert.Equal(t, []string{"a"}, addValues(nil, []string{"a", "a"}, optionValue))
+}
+
+func TestAddValuesReplacesSameKey(t *testing.T) {
+	values := []string{"log-driver=json-file", "debug", "log-driver=syslog", "dns=8.8.8.8"}
+
+	assert.Equal(t, []string{"log-driver=journald", "debug", "dns=8.8.8.8"}, addValues(values, []string{"log-driver=journald"}, optionKey))
+	assert.Equal(t, []string{"log-driver=json-file", "debug=false", "log-driver=syslog", "dns=8.8.8.8"}, addValues(values, []string{"debug=false"}, optionKey))
+}
+
+func TestRemoveValues(t *testing.T) {
+	assert.Equal(t, []string{"a"}, removeValues([]string{"a", "b", "c"}, []string{"b", "c", "d"}, optionValue))
+	assert.Equal(t, []string{}, removeValues(nil, []string{"a"}, optionValue))
+}
+
+func TestRemoveValuesMatchesKey(t *testing.T) {
+	values := []string{"env=dev", "team=web", "env=staging"}
+
+	assert.Equal(t, []string{"team=web"}, removeValues(values, []string{"env"}, optionKey))
+	assert.Equal(t, []string{"team=web"}, removeValues(values, []string{"env=prod"}, optionKey))
+}
+
+func TestEngineOptionChangesApply(t *testing.T) {
+	opts := &engine.Options{
+		ArbitraryFlags:   []string{"log-driver=json-file"},
+		Labels:           []string{"env=dev"},
+		InsecureRegistry: []string{"registry.local:5000"},
+		RegistryMirror:   []string{},
+	}
+
+	changes := engineOptionChanges{
+		Labels:         []string{"env=staging"},
+		RegistryMirror: []string{"https://mirror.local"},
+	}
+
+	changes.apply(opts, addValues)
+
+	assert.Equal(t, []string{"log-driver=json-file"}, opts.ArbitraryFlags)
+	assert.Equal(t, []string{"env=staging"}, opts.Labels)
+	assert.Equal(t, []string{"registry.local:5000"}, opts.InsecureRegistry)
+	assert.Equal(t, []string{"https://mirror.local"}, opts.RegistryMirror)
+
+	changes.apply(opts, removeValues)
+
+	assert.Equal(t, []string{"log-driver=json-file"}, opts.ArbitraryFlags)
+	assert.Equal(t, []string{}, opts.Labels)
+	assert.Equal(t, []string{"registry.local:5000"}, opts.InsecureRegistry)
+	assert.Equal(t, []string{}, opts.RegistryMirror)
+}
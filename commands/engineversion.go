@@ -0,0 +1,200 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/mcndockerclient"
+)
+
+// exitCodeEngineVersionTooOld is the exit status of engine-version when the
+// engine is older than the required minimum, so that scripts can tell it
+// apart from other failures, which exit with 1.
+const exitCodeEngineVersionTooOld = 3
+
+var reEngineVersion = regexp.MustCompile(`^v?(\d+)\.(\d+)(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+
+// engineVersion is a parsed Docker engine version. Build metadata is dropped,
+// as it doesn't take part in comparisons.
+type engineVersion struct {
+	Major, Minor, Patch int
+	PreRelease          string
+}
+
+// ErrEngineVersionTooOld is returned when the engine of a machine is older
+// than the required minimum.
+type ErrEngineVersionTooOld struct {
+	MachineName string
+	Actual      string
+	Minimum     string
+}
+
+func (e ErrEngineVersionTooOld) Error() string {
+	return fmt.Sprintf("Docker engine %s of %s is older than the required %s", e.Actual, e.MachineName, e.Minimum)
+}
+
+// ExitCode returns the exit status of the command.
+func (e ErrEngineVersionTooOld) ExitCode() int {
+	return exitCodeEngineVersionTooOld
+}
+
+// parseEngineVersion parses a version such as 1.10.0, 1.10.0-rc1 or
+// 17.03.1-ce+build.
+func parseEngineVersion(s string) (engineVersion, error) {
+	res := reEngineVersion.FindStringSubmatch(strings.TrimSpace(s))
+	if res == nil {
+		return engineVersion{}, fmt.Errorf("invalid version %q", s)
+	}
+
+	v := engineVersion{PreRelease: res[4]}
+	v.Major, _ = strconv.Atoi(res[1])
+	v.Minor, _ = strconv.Atoi(res[2])
+	if res[3] != "" {
+		v.Patch, _ = strconv.Atoi(res[3])
+	}
+
+	// The edition suffixes of Docker releases aren't pre-releases.
+	if v.PreRelease == "ce" || v.PreRelease == "ee" {
+		v.PreRelease = ""
+	}
+
+	return v, nil
+}
+
+// lessThan reports whether v is older than other, following the precedence
+// rules of semantic versioning: a pre-release is older than its release.
+func (v engineVersion) lessThan(other engineVersion) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	if v.Patch != other.Patch {
+		return v.Patch < other.Patch
+	}
+
+	switch {
+	case v.PreRelease == other.PreRelease:
+		return false
+	case v.PreRelease == "":
+		return false
+	case other.PreRelease == "":
+		return true
+	}
+
+	return preReleaseLessThan(v.PreRelease, other.PreRelease)
+}
+
+// preReleaseLessThan compares two pre-release versions identifier by
+// identifier, numerically when both are numbers.
+func preReleaseLessThan(a, b string) bool {
+	aIDs, bIDs := strings.Split(a, "."), strings.Split(b, ".")
+
+	for i := 0; i < len(aIDs) && i < len(bIDs); i++ {
+		if aIDs[i] == bIDs[i] {
+			continue
+		}
+
+		aNum, aErr := strconv.Atoi(aIDs[i])
+		bNum, bErr := strconv.Atoi(bIDs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			return aNum < bNum
+		case aErr == nil:
+			return true
+		case bErr == nil:
+			return false
+		}
+
+		return aIDs[i] < bIDs[i]
+	}
+
+	return len(aIDs) < len(bIDs)
+}
+
+// checkEngineVersion returns an ErrEngineVersionTooOld if actual is older
+// than minimum.
+func checkEngineVersion(machineName, actual, minimum string) error {
+	minVersion, err := parseEngineVersion(minimum)
+	if err != nil {
+		return fmt.Errorf("Error parsing the minimum version: %s", err)
+	}
+
+	actualVersion, err := parseEngineVersion(actual)
+	if err != nil {
+		return fmt.Errorf("Error parsing the engine version of %s: %s", machineName, err)
+	}
+
+	if actualVersion.lessThan(minVersion) {
+		return ErrEngineVersionTooOld{
+			MachineName: machineName,
+			Actual:      actual,
+			Minimum:     minimum,
+		}
+	}
+
+	return nil
+}
+
+// getEngineVersion asks the Docker daemon of the machine for its version,
+// authenticating with the client certificate of the machine.
+func getEngineVersion(machineName, dockerHost string, authOptions *auth.Options) (string, error) {
+	client, err := mcndockerclient.DefaultPool.Get(machineName, dockerHost, authOptions)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := client.Get("/version")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected response from the Docker daemon: %s", resp.Status)
+	}
+
+	var version struct {
+		Version string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil {
+		return "", err
+	}
+
+	return version.Version, nil
+}
+
+func cmdEngineVersion(c CommandLine) error {
+	if len(c.Args()) != 1 {
+		return ErrExpectedOneMachine
+	}
+
+	h, err := getFirstArgHost(c)
+	if err != nil {
+		return err
+	}
+
+	dockerHost, authOptions, err := runConnectionBoilerplate(h, c)
+	if err != nil {
+		return fmt.Errorf("Error running connection boilerplate: %s", err)
+	}
+
+	version, err := getEngineVersion(h.Name, dockerHost, authOptions)
+	if err != nil {
+		return fmt.Errorf("Error getting the Docker engine version of %s: %s", h.Name, err)
+	}
+
+	fmt.Println(version)
+
+	if minimum := c.String("min"); minimum != "" {
+		return checkEngineVersion(h.Name, version, minimum)
+	}
+
+	return nil
+}
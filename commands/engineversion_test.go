@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEngineVersion(t *testing.T) {
+	var tests = []struct {
+		version  string
+		expected engineVersion
+	}{
+		{"1.10.0", engineVersion{1, 10, 0, ""}},
+		{"1.10.0-rc1", engineVersion{1, 10, 0, "rc1"}},
+		{"1.9.1-fc23+build.4", engineVersion{1, 9, 1, "fc23"}},
+		{"17.03.1-ce", engineVersion{17, 3, 1, ""}},
+		{"v1.12", engineVersion{1, 12, 0, ""}},
+		{" 1.11.2\n", engineVersion{1, 11, 2, ""}},
+	}
+
+	for _, test := range tests {
+		v, err := parseEngineVersion(test.version)
+
+		assert.NoError(t, err, test.version)
+		assert.Equal(t, test.expected, v, test.version)
+	}
+}
+
+func TestParseEngineVersionInvalid(t *testing.T) {
+	for _, version := range []string{"", "1", "latest", "1.10.0 beta"} {
+		_, err := parseEngineVersion(version)
+
+		assert.Error(t, err, version)
+	}
+}
+
+func TestEngineVersionLessThan(t *testing.T) {
+	var tests = []struct {
+		a, b     string
+		lessThan bool
+	}{
+		{"1.9.1", "1.10.0", true},
+		{"1.10.0", "1.9.1", false},
+		{"1.10.0", "1.10.0", false},
+		{"1.10.0-rc1", "1.10.0", true},
+		{"1.10.0", "1.10.0-rc1", false},
+		{"1.10.0-rc1", "1.10.0-rc2", true},
+		{"1.10.0-rc.2", "1.10.0-rc.10", true},
+		{"1.10.0-alpha", "1.10.0-alpha.1", true},
+		{"1.10.0+build1", "1.10.0+build2", false},
+		{"17.03.0-ce", "17.03.0", false},
+	}
+
+	for _, test := range tests {
+		a, _ := parseEngineVersion(test.a)
+		b, _ := parseEngineVersion(test.b)
+
+		assert.Equal(t, test.lessThan, a.lessThan(b), "%s < %s", test.a, test.b)
+	}
+}
+
+func TestCheckEngineVersion(t *testing.T) {
+	assert.NoError(t, checkEngineVersion("dev", "1.10.3", "1.10.0"))
+	assert.NoError(t, checkEngineVersion("dev", "1.10.0", "1.10.0"))
+
+	err := checkEngineVersion("dev", "1.10.0-rc1", "1.10.0")
+
+	assert.EqualError(t, err, "Docker engine 1.10.0-rc1 of dev is older than the required 1.10.0")
+	assert.Equal(t, exitCodeEngineVersionTooOld, err.(exitCoder).ExitCode())
+}
+
+func TestCheckEngineVersionInvalid(t *testing.T) {
+	err := checkEngineVersion("dev", "1.10.0", "latest")
+	assert.Error(t, err)
+	_, ok := err.(exitCoder)
+	assert.False(t, ok)
+
+	err = checkEngineVersion("dev", "unknown", "1.10.0")
+	assert.Error(t, err)
+}
@@ -1,8 +1,11 @@
 package commands
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -20,6 +23,7 @@ const (
 var (
 	errImproperEnvArgs      = errors.New("Error: Expected one machine name")
 	errImproperUnsetEnvArgs = errors.New("Error: Expected no machine name when the -u flag is present")
+	errJSONAndShell         = errors.New("Error: --json and --shell can't be used together")
 )
 
 type ShellConfig struct {
@@ -35,11 +39,51 @@ type ShellConfig struct {
 	NoProxyValue    string
 }
 
+// envVar is a variable set by env.
+type envVar struct {
+	Name  string
+	Value string
+}
+
+// envVars returns the variables of the configuration, in the order they're
+// printed.
+func (shellCfg *ShellConfig) envVars() []envVar {
+	vars := []envVar{
+		{"DOCKER_TLS_VERIFY", shellCfg.DockerTLSVerify},
+		{"DOCKER_HOST", shellCfg.DockerHost},
+		{"DOCKER_CERT_PATH", shellCfg.DockerCertPath},
+		{"DOCKER_MACHINE_NAME", shellCfg.MachineName},
+	}
+
+	if shellCfg.NoProxyVar != "" {
+		vars = append(vars, envVar{shellCfg.NoProxyVar, shellCfg.NoProxyValue})
+	}
+
+	return vars
+}
+
+// normalizeShell returns the shell whose syntax the output is written in,
+// PowerShell Core being the same as Windows PowerShell.
+func normalizeShell(userShell string) string {
+	switch userShell {
+	case "pwsh", "pwsh.exe", "powershell.exe":
+		return "powershell"
+	case "nushell", "nu.exe":
+		return "nu"
+	}
+
+	return userShell
+}
+
 func cmdEnv(c CommandLine) error {
 	// Ensure that log messages always go to stderr when this command is
 	// being run (it is intended to be run in a subshell)
 	log.SetOutWriter(os.Stderr)
 
+	if c.Bool("json") && c.String("shell") != "" {
+		return errJSONAndShell
+	}
+
 	if c.Bool("unset") {
 		return unset(c)
 	}
@@ -47,56 +91,67 @@ func cmdEnv(c CommandLine) error {
 }
 
 func set(c CommandLine) error {
-	if len(c.Args()) != 1 {
+	if len(c.Args()) > 1 {
 		return errImproperEnvArgs
 	}
 
-	host, err := getFirstArgHost(c)
-	if err != nil {
-		return err
+	hostName := c.Args().First()
+	if hostName == "" {
+		name, err := machineNameFromWorkingDir()
+		if err != nil {
+			return err
+		}
+
+		if name == "" {
+			return errImproperEnvArgs
+		}
+		hostName = name
 	}
 
-	dockerHost, _, err := runConnectionBoilerplate(host, c)
+	host, err := loadHost(getStore(c), hostName)
 	if err != nil {
-		return fmt.Errorf("Error running connection boilerplate: %s", err)
+		return fmt.Errorf("Error trying to get host %q: %s", hostName, err)
 	}
 
-	userShell, err := getShell(c)
+	dockerHost, _, err := runConnectionBoilerplate(host, c)
 	if err != nil {
-		return err
+		return fmt.Errorf("Error running connection boilerplate: %s", err)
 	}
 
 	shellCfg := &ShellConfig{
 		DockerCertPath:  filepath.Join(mcndirs.GetMachineDir(), host.Name),
 		DockerHost:      dockerHost,
 		DockerTLSVerify: "1",
-		UsageHint:       generateUsageHint(userShell, os.Args),
 		MachineName:     host.Name,
 	}
 
-	if c.Bool("no-proxy") {
+	if c.Bool("no-proxy") || c.Bool("no-proxy-subnet") || len(c.StringSlice("no-proxy-extra")) > 0 {
 		ip, err := host.Driver.GetIP()
 		if err != nil {
 			return fmt.Errorf("Error getting host IP: %s", err)
 		}
 
-		noProxyVar, noProxyValue := findNoProxyFromEnv()
+		entries := noProxyEntries(host, ip, c.Bool("no-proxy-subnet"), c.StringSlice("no-proxy-extra"))
 
-		// add the docker host to the no_proxy list idempotently
-		switch {
-		case noProxyValue == "":
-			noProxyValue = ip
-		case strings.Contains(noProxyValue, ip):
-		//ip already in no_proxy list, nothing to do
-		default:
-			noProxyValue = fmt.Sprintf("%s,%s", noProxyValue, ip)
-		}
+		noProxyVar, noProxyValue := findNoProxyFromEnv()
 
 		shellCfg.NoProxyVar = noProxyVar
-		shellCfg.NoProxyValue = noProxyValue
+		shellCfg.NoProxyValue = addNoProxy(noProxyValue, entries)
 	}
 
+	if c.Bool("json") {
+		return writeEnvJSON(os.Stdout, shellCfg)
+	}
+
+	userShell, err := getShell(c)
+	if err != nil {
+		return err
+	}
+	shellCfg.UsageHint = generateUsageHint(userShell, os.Args)
+
 	switch userShell {
+	case "nu":
+		return writeEnvNuon(os.Stdout, shellCfg)
 	case "fish":
 		shellCfg.Prefix = "set -gx "
 		shellCfg.Suffix = "\";\n"
@@ -123,20 +178,25 @@ func unset(c CommandLine) error {
 		return errImproperUnsetEnvArgs
 	}
 
-	userShell, err := getShell(c)
-	if err != nil {
-		return err
+	shellCfg := &ShellConfig{}
+
+	if c.Bool("no-proxy") {
+		shellCfg.NoProxyVar, shellCfg.NoProxyValue = findNoProxyFromEnv()
 	}
 
-	shellCfg := &ShellConfig{
-		UsageHint: generateUsageHint(userShell, os.Args),
+	if c.Bool("json") {
+		return writeUnsetEnvJSON(os.Stdout, shellCfg)
 	}
 
-	if c.Bool("no-proxy") {
-		shellCfg.NoProxyVar, shellCfg.NoProxyValue = findNoProxyFromEnv()
+	userShell, err := getShell(c)
+	if err != nil {
+		return err
 	}
+	shellCfg.UsageHint = generateUsageHint(userShell, os.Args)
 
 	switch userShell {
+	case "nu":
+		return writeUnsetEnvNuon(os.Stdout, shellCfg)
 	case "fish":
 		shellCfg.Prefix = "set -e "
 		shellCfg.Suffix = ";\n"
@@ -168,10 +228,76 @@ func executeTemplateStdout(shellCfg *ShellConfig) error {
 	return tmpl.Execute(os.Stdout, shellCfg)
 }
 
+// writeEnvJSON writes the variables as a JSON object, for the tools and
+// shells which can't evaluate any of the formats.
+func writeEnvJSON(out io.Writer, shellCfg *ShellConfig) error {
+	vars := map[string]string{}
+	for _, v := range shellCfg.envVars() {
+		vars[v.Name] = v.Value
+	}
+
+	data, err := json.MarshalIndent(vars, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
+// writeUnsetEnvJSON writes the names of the variables to unset as a JSON
+// array.
+func writeUnsetEnvJSON(out io.Writer, shellCfg *ShellConfig) error {
+	names := []string{}
+	for _, v := range shellCfg.envVars() {
+		names = append(names, v.Name)
+	}
+
+	data, err := json.Marshal(names)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
+// writeEnvNuon writes the variables as a Nushell record, which load-env sets
+// since Nushell can't evaluate the output of a command.
+func writeEnvNuon(out io.Writer, shellCfg *ShellConfig) error {
+	var buf bytes.Buffer
+
+	buf.WriteString("{\n")
+	for _, v := range shellCfg.envVars() {
+		value, err := json.Marshal(v.Value)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&buf, "    %s: %s\n", v.Name, value)
+	}
+	buf.WriteString("}\n")
+	buf.WriteString(shellCfg.UsageHint)
+
+	_, err := out.Write(buf.Bytes())
+	return err
+}
+
+// writeUnsetEnvNuon writes the names of the variables to unset as a Nushell
+// list, for hide-env.
+func writeUnsetEnvNuon(out io.Writer, shellCfg *ShellConfig) error {
+	names := []string{}
+	for _, v := range shellCfg.envVars() {
+		names = append(names, fmt.Sprintf("%q", v.Name))
+	}
+
+	_, err := fmt.Fprintf(out, "[%s]\n%s", strings.Join(names, " "), shellCfg.UsageHint)
+	return err
+}
+
 func getShell(c CommandLine) (string, error) {
 	userShell := c.String("shell")
 	if userShell != "" {
-		return userShell, nil
+		return normalizeShell(userShell), nil
 	}
 	return detectShell()
 }
@@ -203,6 +329,14 @@ func generateUsageHint(userShell string, args []string) string {
 	case "cmd":
 		cmd = fmt.Sprintf("\tFOR /f \"tokens=*\" %%i IN ('%s') DO %%i", commandLine)
 		comment = "REM"
+	case "nu":
+		if isUnsetCommandLine(args) {
+			cmd = fmt.Sprintf("hide-env ...(^%s | from nuon)", commandLine)
+		} else {
+			cmd = fmt.Sprintf("^%s | from nuon | load-env", commandLine)
+		}
+	case "direnv":
+		return fmt.Sprintf("%s Run this command to set up the .envrc of your project: \n%s %s > .envrc && direnv allow\n", comment, comment, commandLine)
 	default:
 		cmd = fmt.Sprintf("eval \"$(%s)\"", commandLine)
 	}
@@ -210,6 +344,17 @@ func generateUsageHint(userShell string, args []string) string {
 	return fmt.Sprintf("%s Run this command to configure your shell: \n%s %s\n", comment, comment, cmd)
 }
 
+// isUnsetCommandLine tells whether the command line unsets the variables.
+func isUnsetCommandLine(args []string) bool {
+	for _, arg := range args {
+		if arg == "-u" || arg == "--unset" || arg == "-unset" {
+			return true
+		}
+	}
+
+	return false
+}
+
 func detectShell() (string, error) {
 	// attempt to get the SHELL env var
 	shell := filepath.Base(os.Getenv("SHELL"))
@@ -224,5 +369,5 @@ func detectShell() (string, error) {
 		return "", ErrUnknownShell
 	}
 
-	return shell, nil
+	return normalizeShell(shell), nil
 }
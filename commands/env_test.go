@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"bytes"
 	"testing"
 
 	"strings"
@@ -37,6 +38,11 @@ func TestHints(t *testing.T) {
 		{"cmd", "./machine env --shell=cmd --swarm default", "REM Run this command to configure your shell: \nREM \tFOR /f \"tokens=*\" %i IN ('./machine env --shell=cmd --swarm default') DO %i\n"},
 		{"cmd", "./machine env --shell=cmd --no-proxy --swarm default", "REM Run this command to configure your shell: \nREM \tFOR /f \"tokens=*\" %i IN ('./machine env --shell=cmd --no-proxy --swarm default') DO %i\n"},
 		{"cmd", "./machine env --shell=cmd --unset", "REM Run this command to configure your shell: \nREM \tFOR /f \"tokens=*\" %i IN ('./machine env --shell=cmd --unset') DO %i\n"},
+
+		{"nu", "./machine env --shell=nu default", "# Run this command to configure your shell: \n# ^./machine env --shell=nu default | from nuon | load-env\n"},
+		{"nu", "./machine env --shell=nu --unset", "# Run this command to configure your shell: \n# hide-env ...(^./machine env --shell=nu --unset | from nuon)\n"},
+
+		{"direnv", "./machine env --shell=direnv default", "# Run this command to set up the .envrc of your project: \n# ./machine env --shell=direnv default > .envrc && direnv allow\n"},
 	}
 
 	for _, test := range tests {
@@ -45,3 +51,75 @@ func TestHints(t *testing.T) {
 		assert.Equal(t, test.expectedHints, hints)
 	}
 }
+
+func TestNormalizeShell(t *testing.T) {
+	assert.Equal(t, "powershell", normalizeShell("pwsh"))
+	assert.Equal(t, "powershell", normalizeShell("powershell"))
+	assert.Equal(t, "nu", normalizeShell("nushell"))
+	assert.Equal(t, "nu", normalizeShell("nu"))
+	assert.Equal(t, "bash", normalizeShell("bash"))
+}
+
+func testShellConfig() *ShellConfig {
+	return &ShellConfig{
+		DockerCertPath:  "/machines/dev",
+		DockerHost:      "tcp://1.2.3.4:2376",
+		DockerTLSVerify: "1",
+		MachineName:     "dev",
+		NoProxyVar:      "NO_PROXY",
+		NoProxyValue:    "1.2.3.4",
+	}
+}
+
+func TestWriteEnvJSON(t *testing.T) {
+	var out bytes.Buffer
+
+	err := writeEnvJSON(&out, testShellConfig())
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{
+    "DOCKER_CERT_PATH": "/machines/dev",
+    "DOCKER_HOST": "tcp://1.2.3.4:2376",
+    "DOCKER_MACHINE_NAME": "dev",
+    "DOCKER_TLS_VERIFY": "1",
+    "NO_PROXY": "1.2.3.4"
+}
+`, out.String())
+}
+
+func TestWriteUnsetEnvJSON(t *testing.T) {
+	var out bytes.Buffer
+
+	err := writeUnsetEnvJSON(&out, &ShellConfig{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `["DOCKER_TLS_VERIFY","DOCKER_HOST","DOCKER_CERT_PATH","DOCKER_MACHINE_NAME"]`+"\n", out.String())
+}
+
+func TestWriteEnvNuon(t *testing.T) {
+	var out bytes.Buffer
+	shellCfg := testShellConfig()
+	shellCfg.UsageHint = "# hint\n"
+
+	err := writeEnvNuon(&out, shellCfg)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{
+    DOCKER_TLS_VERIFY: "1"
+    DOCKER_HOST: "tcp://1.2.3.4:2376"
+    DOCKER_CERT_PATH: "/machines/dev"
+    DOCKER_MACHINE_NAME: "dev"
+    NO_PROXY: "1.2.3.4"
+}
+# hint
+`, out.String())
+}
+
+func TestWriteUnsetEnvNuon(t *testing.T) {
+	var out bytes.Buffer
+
+	err := writeUnsetEnvNuon(&out, &ShellConfig{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `["DOCKER_TLS_VERIFY" "DOCKER_HOST" "DOCKER_CERT_PATH" "DOCKER_MACHINE_NAME"]`+"\n", out.String())
+}
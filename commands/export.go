@@ -0,0 +1,438 @@
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/cert"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/persist"
+)
+
+// exportFormat versions the layout of the archives of export, for import to
+// refuse the archives of a later version.
+const exportFormat = 1
+
+const (
+	manifestEntry   = "manifest.json"
+	machineEntryDir = "machine/"
+	certsEntryDir   = "certs/"
+)
+
+var errExpectedOneArchive = errors.New("Error: Expected the archive of a machine as the argument")
+
+// exportManifest is the first entry of the archives, describing the machine
+// they hold.
+type exportManifest struct {
+	Format int
+	Name   string
+	// StorePath is the storage path the machine was exported from, and
+	// Separator the separator of its paths, for the paths of the machine to
+	// be rewritten where it's imported.
+	StorePath string
+	Separator string
+}
+
+// diskImageExts are the extensions of the disks of local machines, left out
+// of the archives: the virtual machines themselves stay registered with the
+// hypervisor of the workstation they were created on.
+var diskImageExts = []string{".vmdk", ".vdi", ".vhd", ".vhdx", ".qcow2", ".img", ".iso", ".raw"}
+
+// skipExportedFile tells whether a file of the directory of the machine is
+// left out of its archive.
+func skipExportedFile(name string) bool {
+	base := filepath.Base(name)
+	if base == ".lock" || base == "config.json" || base == "config.json.bak" || strings.HasPrefix(base, ".config.json") {
+		return true
+	}
+
+	ext := strings.ToLower(filepath.Ext(base))
+	for _, diskExt := range diskImageExts {
+		if ext == diskExt {
+			return true
+		}
+	}
+
+	return false
+}
+
+func addTarFile(tw *tar.Writer, name string, mode int64, size int64, modTime time.Time, r io.Reader) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     mode,
+		Size:     size,
+		ModTime:  modTime,
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return err
+	}
+
+	_, err := io.Copy(tw, r)
+	return err
+}
+
+func addTarData(tw *tar.Writer, name string, data []byte) error {
+	return addTarFile(tw, name, 0600, int64(len(data)), time.Now(), bytes.NewReader(data))
+}
+
+func addTarPath(tw *tar.Writer, name, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	return addTarFile(tw, name, int64(info.Mode().Perm()), info.Size(), info.ModTime(), f)
+}
+
+// exportHost writes the archive of the machine: its configuration, the
+// files of its directory and the certificates the client connects to it
+// with.
+func exportHost(w io.Writer, h *host.Host, storePath string) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	manifest, err := json.MarshalIndent(exportManifest{
+		Format:    exportFormat,
+		Name:      h.Name,
+		StorePath: storePath,
+		Separator: string(filepath.Separator),
+	}, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	if err := addTarData(tw, manifestEntry, manifest); err != nil {
+		return err
+	}
+
+	config, err := json.MarshalIndent(h, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	if err := addTarData(tw, machineEntryDir+"config.json", config); err != nil {
+		return err
+	}
+
+	machineDir := filepath.Join(storePath, "machines", h.Name)
+	if err := filepath.Walk(machineDir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() || skipExportedFile(file) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(machineDir, file)
+		if err != nil {
+			return err
+		}
+
+		return addTarPath(tw, machineEntryDir+filepath.ToSlash(rel), file)
+	}); err != nil {
+		return err
+	}
+
+	authOptions := h.HostOptions.AuthOptions
+	for name, file := range map[string]string{
+		"ca.pem":   authOptions.CaCertPath,
+		"cert.pem": authOptions.ClientCertPath,
+		"key.pem":  authOptions.ClientKeyPath,
+	} {
+		if err := addTarPath(tw, certsEntryDir+name, file); err != nil {
+			return fmt.Errorf("Error adding the certificates of %s: %s", h.Name, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gw.Close()
+}
+
+func cmdExport(c CommandLine) error {
+	if len(c.Args()) != 1 {
+		return ErrExpectedOneMachine
+	}
+
+	name := c.Args().First()
+	h, err := getStore(c).Load(name)
+	if err != nil {
+		return err
+	}
+
+	output := c.String("output")
+	if output == "" {
+		output = name + ".tar.gz"
+	}
+
+	f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if err := exportHost(f, h, c.GlobalString("storage-path")); err != nil {
+		f.Close()
+		os.Remove(output)
+		return fmt.Errorf("Error exporting %s: %s", name, err)
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	log.Infof("Exported %s to %s", name, output)
+	log.Warn("The archive holds the keys and the credentials of the machine, keep it safe.")
+
+	return nil
+}
+
+// useMachineCerts makes the client connect to the machine with the
+// certificates of its archive, kept in the directory of the machine.
+func useMachineCerts(authOptions *auth.Options, machineDir string) {
+	certDir := filepath.Join(machineDir, "certs")
+
+	authOptions.CertDir = certDir
+	authOptions.CaCertPath = filepath.Join(certDir, "ca.pem")
+	authOptions.CaPrivateKeyPath = filepath.Join(certDir, "ca-key.pem")
+	authOptions.ClientCertPath = filepath.Join(certDir, "cert.pem")
+	authOptions.ClientKeyPath = filepath.Join(certDir, "key.pem")
+}
+
+// useLocalCerts makes the client connect to the machine with the
+// certificates of the workstation.
+func useLocalCerts(authOptions *auth.Options, certInfo cert.PathInfo) {
+	authOptions.CertDir = filepath.Dir(certInfo.CaCertPath)
+	authOptions.CaCertPath = certInfo.CaCertPath
+	authOptions.CaPrivateKeyPath = certInfo.CaPrivateKeyPath
+	authOptions.ClientCertPath = certInfo.ClientCertPath
+	authOptions.ClientKeyPath = certInfo.ClientKeyPath
+}
+
+func readManifest(tr *tar.Reader) (exportManifest, error) {
+	manifest := exportManifest{}
+
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != manifestEntry {
+		return manifest, errors.New("Error: This is not the archive of a machine")
+	}
+
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return manifest, fmt.Errorf("Error reading the manifest of the archive: %s", err)
+	}
+
+	if manifest.Format > exportFormat {
+		return manifest, errors.New("The archive is from a later version, please upgrade your Docker Machine client.")
+	}
+
+	if !host.ValidateHostName(manifest.Name) {
+		return manifest, mcnerror.ErrInvalidHostname
+	}
+
+	return manifest, nil
+}
+
+// extractEntry writes the file of the archive into the directory of the
+// machine, refusing the entries which would land outside of it.
+func extractEntry(tr *tar.Reader, hdr *tar.Header, machineDir string) error {
+	name := path.Clean(hdr.Name)
+
+	var rel string
+	switch {
+	case strings.HasPrefix(name, machineEntryDir):
+		rel = strings.TrimPrefix(name, machineEntryDir)
+	case strings.HasPrefix(name, certsEntryDir):
+		rel = path.Join("certs", strings.TrimPrefix(name, certsEntryDir))
+	default:
+		return nil
+	}
+
+	if rel == "" || path.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+		return fmt.Errorf("Error: The archive holds the invalid file %q", hdr.Name)
+	}
+
+	file := filepath.Join(machineDir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(hdr.Mode).Perm()&0700)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, tr); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// importHost adds the machine of the archive to the store, its files into
+// the storage path. The client connects to it with the certificates of the
+// archive, or with those of localCerts when given.
+func importHost(store persist.Store, r io.Reader, storePath string, localCerts *cert.PathInfo) (*host.Host, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errors.New("Error: This is not the archive of a machine")
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+
+	manifest, err := readManifest(tr)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := store.Exists(manifest.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if exists {
+		return nil, mcnerror.ErrHostAlreadyExists{
+			Name: manifest.Name,
+		}
+	}
+
+	machinesDir := filepath.Join(storePath, "machines")
+	if err := os.MkdirAll(machinesDir, 0700); err != nil {
+		return nil, err
+	}
+
+	// The machine is extracted apart, and moved into the storage path
+	// only once it's read.
+	tmpDir, err := ioutil.TempDir(machinesDir, ".import-"+manifest.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmpMachineDir := filepath.Join(tmpDir, "machines", manifest.Name)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error reading the archive: %s", err)
+		}
+
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+
+		if err := extractEntry(tr, hdr, tmpMachineDir); err != nil {
+			return nil, err
+		}
+	}
+
+	h, err := persist.Filestore{Path: tmpDir}.Load(manifest.Name)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading the configuration of %s: %s", manifest.Name, err)
+	}
+
+	// The configuration is saved in the store afresh.
+	for _, name := range []string{"config.json", "config.json.bak"} {
+		if err := os.Remove(filepath.Join(tmpMachineDir, name)); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	h.Revision = 0
+
+	machineDir := filepath.Join(machinesDir, manifest.Name)
+	if err := persist.RelocateHost(h, manifest.StorePath, manifest.Separator, storePath); err != nil {
+		return nil, err
+	}
+
+	if localCerts != nil {
+		if err := os.RemoveAll(filepath.Join(tmpMachineDir, "certs")); err != nil {
+			return nil, err
+		}
+		useLocalCerts(h.HostOptions.AuthOptions, *localCerts)
+	} else {
+		useMachineCerts(h.HostOptions.AuthOptions, machineDir)
+	}
+
+	if err := os.Rename(tmpMachineDir, machineDir); err != nil {
+		return nil, err
+	}
+
+	if err := store.Save(h); err != nil {
+		os.RemoveAll(machineDir)
+		return nil, fmt.Errorf("Error attempting to save host to store: %s", err)
+	}
+
+	return h, nil
+}
+
+func cmdImport(c CommandLine) error {
+	if len(c.Args()) != 1 {
+		return errExpectedOneArchive
+	}
+
+	f, err := os.Open(c.Args().First())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var localCerts *cert.PathInfo
+	if c.Bool("regenerate-certs") {
+		certInfo := getCertPathInfoFromContext(c)
+		localCerts = &certInfo
+	}
+
+	store := getStore(c)
+	h, err := importHost(store, f, c.GlobalString("storage-path"), localCerts)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Imported %s", h.Name)
+
+	if localCerts == nil {
+		return nil
+	}
+
+	if err := cert.BootstrapCertificates(h.HostOptions.AuthOptions); err != nil {
+		return fmt.Errorf("Error generating the certificates of the client: %s", err)
+	}
+
+	h, err = loadHost(store, h.Name)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Regenerating the TLS certificates of %s", h.Name)
+
+	if err := h.ConfigureAuth(); err != nil {
+		return fmt.Errorf("Error regenerating the certificates of %s: %s, run 'docker-machine regenerate-certs %s' once it's running", h.Name, err, h.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/machine/drivers/none"
+	"github.com/docker/machine/libmachine/cert"
+	"github.com/docker/machine/libmachine/hosttest"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/persist"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkipExportedFile(t *testing.T) {
+	assert.False(t, skipExportedFile("/a/machines/dev/id_rsa"))
+	assert.False(t, skipExportedFile("/a/machines/dev/server.pem"))
+	assert.True(t, skipExportedFile("/a/machines/dev/config.json"))
+	assert.True(t, skipExportedFile("/a/machines/dev/.lock"))
+	assert.True(t, skipExportedFile("/a/machines/dev/disk.vmdk"))
+	assert.True(t, skipExportedFile("/a/machines/dev/boot2docker.ISO"))
+}
+
+// saveExportTestHost saves a machine in a new storage path, with its files
+// and the certificates of the client.
+func saveExportTestHost(t *testing.T) (persist.Filestore, string) {
+	storePath, err := ioutil.TempDir("", "machine-export-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := hosttest.GetDefaultTestHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	machineDir := filepath.Join(storePath, "machines", h.Name)
+	certDir := filepath.Join(storePath, "certs")
+	h.Driver = none.NewDriver(h.Name, storePath)
+	h.Driver.(*none.Driver).SSHKeyPath = filepath.Join(machineDir, "id_rsa")
+	h.RawDriver, _ = json.Marshal(h.Driver)
+	useLocalCerts(h.HostOptions.AuthOptions, cert.PathInfo{
+		CaCertPath:       filepath.Join(certDir, "ca.pem"),
+		CaPrivateKeyPath: filepath.Join(certDir, "ca-key.pem"),
+		ClientCertPath:   filepath.Join(certDir, "cert.pem"),
+		ClientKeyPath:    filepath.Join(certDir, "key.pem"),
+	})
+	h.HostOptions.AuthOptions.StorePath = machineDir
+	h.HostOptions.AuthOptions.ServerCertPath = filepath.Join(machineDir, "server.pem")
+
+	store := persist.Filestore{Path: storePath}
+	if err := store.Save(h); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(certDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	for file, content := range map[string]string{
+		filepath.Join(machineDir, "id_rsa"):     "ssh key",
+		filepath.Join(machineDir, "server.pem"): "server cert",
+		filepath.Join(machineDir, "disk.vmdk"):  "disk",
+		filepath.Join(certDir, "ca.pem"):        "ca cert",
+		filepath.Join(certDir, "ca-key.pem"):    "ca key",
+		filepath.Join(certDir, "cert.pem"):      "client cert",
+		filepath.Join(certDir, "key.pem"):       "client key",
+	} {
+		if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return store, storePath
+}
+
+func exportTestHost(t *testing.T, store persist.Store, storePath string) *bytes.Buffer {
+	h, err := store.Load(hosttest.DefaultHostName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var archive bytes.Buffer
+	if err := exportHost(&archive, h, storePath); err != nil {
+		t.Fatal(err)
+	}
+
+	return &archive
+}
+
+func TestExportImport(t *testing.T) {
+	store, storePath := saveExportTestHost(t)
+	defer os.RemoveAll(storePath)
+
+	archive := exportTestHost(t, store, storePath)
+
+	importPath, err := ioutil.TempDir("", "machine-import-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(importPath)
+
+	importStore := persist.Filestore{Path: importPath}
+	h, err := importHost(importStore, archive, importPath, nil)
+	assert.NoError(t, err)
+
+	machineDir := filepath.Join(importPath, "machines", hosttest.DefaultHostName)
+	certDir := filepath.Join(machineDir, "certs")
+
+	imported, err := importStore.Load(h.Name)
+	assert.NoError(t, err)
+
+	authOptions := imported.HostOptions.AuthOptions
+	assert.Equal(t, machineDir, authOptions.StorePath)
+	assert.Equal(t, filepath.Join(machineDir, "server.pem"), authOptions.ServerCertPath)
+	assert.Equal(t, certDir, authOptions.CertDir)
+	assert.Equal(t, filepath.Join(certDir, "ca.pem"), authOptions.CaCertPath)
+	assert.Equal(t, filepath.Join(certDir, "key.pem"), authOptions.ClientKeyPath)
+	assert.Equal(t, filepath.Join(machineDir, "id_rsa"), imported.Driver.(*none.Driver).SSHKeyPath)
+
+	var rawDriver none.Driver
+	assert.NoError(t, json.Unmarshal(imported.RawDriver, &rawDriver))
+	assert.Equal(t, importPath, rawDriver.StorePath)
+	assert.Equal(t, filepath.Join(machineDir, "id_rsa"), rawDriver.SSHKeyPath)
+
+	for file, content := range map[string]string{
+		filepath.Join(machineDir, "id_rsa"):     "ssh key",
+		filepath.Join(machineDir, "server.pem"): "server cert",
+		filepath.Join(certDir, "ca.pem"):        "ca cert",
+		filepath.Join(certDir, "cert.pem"):      "client cert",
+		filepath.Join(certDir, "key.pem"):       "client key",
+	} {
+		data, err := ioutil.ReadFile(file)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(data))
+	}
+
+	for _, file := range []string{
+		filepath.Join(machineDir, "disk.vmdk"),
+		filepath.Join(certDir, "ca-key.pem"),
+	} {
+		_, err := os.Stat(file)
+		assert.True(t, os.IsNotExist(err), file)
+	}
+}
+
+func TestImportExisting(t *testing.T) {
+	store, storePath := saveExportTestHost(t)
+	defer os.RemoveAll(storePath)
+
+	archive := exportTestHost(t, store, storePath)
+
+	_, err := importHost(store, archive, storePath, nil)
+	assert.Equal(t, mcnerror.ErrHostAlreadyExists{Name: hosttest.DefaultHostName}, err)
+}
+
+func TestImportWithLocalCerts(t *testing.T) {
+	store, storePath := saveExportTestHost(t)
+	defer os.RemoveAll(storePath)
+
+	archive := exportTestHost(t, store, storePath)
+
+	importPath, err := ioutil.TempDir("", "machine-import-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(importPath)
+
+	localCerts := cert.PathInfo{
+		CaCertPath:       filepath.Join(importPath, "certs", "ca.pem"),
+		CaPrivateKeyPath: filepath.Join(importPath, "certs", "ca-key.pem"),
+		ClientCertPath:   filepath.Join(importPath, "certs", "cert.pem"),
+		ClientKeyPath:    filepath.Join(importPath, "certs", "key.pem"),
+	}
+
+	h, err := importHost(persist.Filestore{Path: importPath}, archive, importPath, &localCerts)
+	assert.NoError(t, err)
+	assert.Equal(t, localCerts.CaCertPath, h.HostOptions.AuthOptions.CaCertPath)
+	assert.Equal(t, filepath.Join(importPath, "certs"), h.HostOptions.AuthOptions.CertDir)
+
+	_, err = os.Stat(filepath.Join(importPath, "machines", h.Name, "certs"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestImportNotAnArchive(t *testing.T) {
+	_, err := importHost(persist.Filestore{Path: "/nonexistent"}, bytes.NewBufferString("not an archive"), "/nonexistent", nil)
+	assert.EqualError(t, err, "Error: This is not the archive of a machine")
+}
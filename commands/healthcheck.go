@@ -0,0 +1,275 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/cert"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/persist"
+	"github.com/docker/machine/libmachine/state"
+)
+
+const (
+	repairRestart         = "restart"
+	repairRegenerateCerts = "regenerate-certs"
+)
+
+// healthCheckDialTimeout bounds the check that the Docker port answers.
+var healthCheckDialTimeout = 5 * time.Second
+
+// healthProblem is what a health check found wrong with a machine.
+type healthProblem int
+
+const (
+	problemNone healthProblem = iota
+	problemState
+	problemUnreachable
+	problemCerts
+)
+
+// healthReport is the outcome of the health check of a machine.
+type healthReport struct {
+	Name    string
+	State   state.State
+	Problem healthProblem
+	Detail  string
+	Repair  string
+}
+
+// healthChecker checks that machines are running, that their Docker port
+// answers and that their certificates are valid, and repairs them if asked.
+type healthChecker struct {
+	repairs      map[string]bool
+	dial         func(addr string) error
+	validateCert func(addr string, authOptions *auth.Options) (bool, error)
+}
+
+func newHealthChecker(repairs []string) (*healthChecker, error) {
+	checker := &healthChecker{
+		repairs: map[string]bool{},
+		dial: func(addr string) error {
+			conn, err := net.DialTimeout("tcp", addr, healthCheckDialTimeout)
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		},
+		validateCert: cert.ValidateCertificate,
+	}
+
+	for _, repair := range repairs {
+		if repair != repairRestart && repair != repairRegenerateCerts {
+			return nil, fmt.Errorf("Unsupported repair %q, expected %s or %s", repair, repairRestart, repairRegenerateCerts)
+		}
+		checker.repairs[repair] = true
+	}
+
+	return checker, nil
+}
+
+// check fills the state of the machine and the first problem found in the
+// report.
+func (hc *healthChecker) check(h *host.Host, report *healthReport) {
+	report.Problem, report.Detail = problemNone, ""
+
+	s, err := h.Driver.GetState()
+	if err != nil {
+		report.State, report.Problem, report.Detail = state.Error, problemState, fmt.Sprintf("error getting state: %s", err)
+		return
+	}
+
+	report.State = s
+	if s != state.Running {
+		report.Problem, report.Detail = problemState, fmt.Sprintf("machine is %s", strings.ToLower(s.String()))
+		return
+	}
+
+	dockerHost, err := h.Driver.GetURL()
+	if err != nil {
+		report.Problem, report.Detail = problemUnreachable, fmt.Sprintf("error getting URL: %s", err)
+		return
+	}
+
+	u, err := url.Parse(dockerHost)
+	if err != nil {
+		report.Problem, report.Detail = problemUnreachable, fmt.Sprintf("error parsing URL %s: %s", dockerHost, err)
+		return
+	}
+
+	if err := hc.dial(u.Host); err != nil {
+		report.Problem, report.Detail = problemUnreachable, fmt.Sprintf("Docker port unreachable: %s", err)
+		return
+	}
+
+	if h.HostOptions != nil && h.HostOptions.AuthOptions != nil {
+		valid, err := hc.validateCert(u.Host, h.HostOptions.AuthOptions)
+		switch {
+		case err != nil:
+			report.Problem, report.Detail = problemCerts, fmt.Sprintf("certificates invalid: %s", err)
+		case !valid:
+			report.Problem, report.Detail = problemCerts, "certificates invalid"
+		}
+	}
+}
+
+// repair runs the repair which fixes the problem found, if it was asked for:
+// stopped machines are started, running machines whose Docker port doesn't
+// answer are restarted and invalid certificates are regenerated.
+func (hc *healthChecker) repair(h *host.Host, report healthReport) (string, error) {
+	switch {
+	case report.Problem == problemState && report.State != state.Error && hc.repairs[repairRestart]:
+		return "started", h.Start()
+	case report.Problem == problemUnreachable && hc.repairs[repairRestart]:
+		return "restarted", h.Restart()
+	case report.Problem == problemCerts && hc.repairs[repairRegenerateCerts]:
+		return "regenerated certificates", h.ConfigureAuth()
+	}
+
+	return "", nil
+}
+
+// run checks the machine, repairs it if needed and checks it again after a
+// repair.
+func (hc *healthChecker) run(h *host.Host) healthReport {
+	report := healthReport{Name: h.Name}
+	hc.check(h, &report)
+
+	if report.Problem == problemNone {
+		return report
+	}
+
+	repair, err := hc.repair(h, report)
+	if repair == "" {
+		return report
+	}
+
+	if err != nil {
+		report.Repair = fmt.Sprintf("%s failed: %s", repair, err)
+		return report
+	}
+
+	log.Infof("(%s) Machine %s after: %s", h.Name, repair, report.Detail)
+	report.Repair = repair
+	hc.check(h, &report)
+
+	return report
+}
+
+func writeHealthReports(out io.Writer, reports []healthReport) error {
+	w := tabwriter.NewWriter(out, 5, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTATE\tHEALTH\tREPAIR")
+
+	for _, report := range reports {
+		health := "healthy"
+		if report.Problem != problemNone {
+			health = report.Detail
+		}
+
+		repair := "-"
+		if report.Repair != "" {
+			repair = report.Repair
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", report.Name, report.State, health, repair)
+	}
+
+	return w.Flush()
+}
+
+// checkHosts runs a round of health checks, saving the repaired machines.
+func checkHosts(out io.Writer, store persist.Store, checker *healthChecker, hosts []*host.Host) (int, error) {
+	reports := []healthReport{}
+	unhealthy := 0
+
+	for _, h := range hosts {
+		report := checker.run(h)
+		reports = append(reports, report)
+
+		if report.Problem != problemNone {
+			unhealthy++
+		}
+
+		if report.Repair != "" {
+			if err := saveHost(store, h); err != nil {
+				log.Errorf("Error saving %s: %s", h.Name, err)
+			}
+		}
+	}
+
+	return unhealthy, writeHealthReports(out, reports)
+}
+
+func cmdHealthcheck(c CommandLine) error {
+	var interval time.Duration
+	if c.String("interval") != "" {
+		var err error
+		if interval, err = time.ParseDuration(c.String("interval")); err != nil || interval < 0 {
+			return fmt.Errorf("Invalid interval %q, expected a duration such as 30s or 5m", c.String("interval"))
+		}
+	}
+
+	checker, err := newHealthChecker(c.StringSlice("repair"))
+	if err != nil {
+		return err
+	}
+
+	store := getStore(c)
+
+	// The machines are loaded again on each round, so that those created or
+	// removed since are checked or dropped.
+	loadHosts := func() ([]*host.Host, error) {
+		if len(c.Args()) > 0 {
+			return getHostsFromContext(c)
+		}
+		return listHosts(store)
+	}
+
+	hosts, err := loadHosts()
+	if err != nil {
+		return err
+	}
+
+	if len(hosts) == 0 {
+		return errors.New("No machine to check")
+	}
+
+	if interval == 0 {
+		unhealthy, err := checkHosts(os.Stdout, store, checker, hosts)
+		if err != nil {
+			return err
+		}
+
+		if unhealthy > 0 {
+			return fmt.Errorf("%d of %d machines are unhealthy", unhealthy, len(hosts))
+		}
+
+		return nil
+	}
+
+	for {
+		fmt.Fprintln(os.Stdout, time.Now().Format(time.RFC3339))
+		if _, err := checkHosts(os.Stdout, store, checker, hosts); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stdout)
+
+		time.Sleep(interval)
+
+		reloaded, err := loadHosts()
+		if err != nil {
+			log.Errorf("Error loading the machines, checking the same ones again: %s", err)
+			continue
+		}
+		hosts = reloaded
+	}
+}
@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHealthChecker(repairs []string, dialErr error, certValid bool) *healthChecker {
+	checker, _ := newHealthChecker(repairs)
+	checker.dial = func(addr string) error {
+		return dialErr
+	}
+	checker.validateCert = func(addr string, authOptions *auth.Options) (bool, error) {
+		if !certValid {
+			return false, errors.New("x509: certificate has expired")
+		}
+		return true, nil
+	}
+
+	return checker
+}
+
+func newHealthCheckTestHost(s state.State) (*host.Host, *fakedriver.Driver) {
+	driver := &fakedriver.Driver{
+		MockState: s,
+		MockURL:   "tcp://1.2.3.4:2376",
+	}
+
+	return &host.Host{
+		Name:        "dev",
+		Driver:      driver,
+		HostOptions: &host.Options{AuthOptions: &auth.Options{}},
+	}, driver
+}
+
+func TestNewHealthCheckerUnsupportedRepair(t *testing.T) {
+	_, err := newHealthChecker([]string{"restart", "reinstall"})
+
+	assert.EqualError(t, err, `Unsupported repair "reinstall", expected restart or regenerate-certs`)
+}
+
+func TestHealthCheckHealthyMachine(t *testing.T) {
+	h, _ := newHealthCheckTestHost(state.Running)
+
+	report := newTestHealthChecker(nil, nil, true).run(h)
+
+	assert.Equal(t, healthReport{Name: "dev", State: state.Running}, report)
+}
+
+func TestHealthCheckStoppedMachine(t *testing.T) {
+	h, _ := newHealthCheckTestHost(state.Saved)
+
+	report := newTestHealthChecker(nil, nil, true).run(h)
+
+	assert.Equal(t, problemState, report.Problem)
+	assert.Equal(t, "machine is saved", report.Detail)
+	assert.Empty(t, report.Repair)
+}
+
+func TestHealthCheckRestartsStoppedMachine(t *testing.T) {
+	h, driver := newHealthCheckTestHost(state.Stopped)
+
+	report := newTestHealthChecker([]string{repairRestart}, nil, true).run(h)
+
+	assert.Equal(t, state.Running, driver.MockState)
+	assert.Equal(t, healthReport{Name: "dev", State: state.Running, Repair: "started"}, report)
+}
+
+func TestHealthCheckUnreachableDockerPort(t *testing.T) {
+	h, _ := newHealthCheckTestHost(state.Running)
+
+	report := newTestHealthChecker(nil, errors.New("connection refused"), true).run(h)
+
+	assert.Equal(t, problemUnreachable, report.Problem)
+	assert.Equal(t, "Docker port unreachable: connection refused", report.Detail)
+}
+
+func TestHealthCheckInvalidCertificates(t *testing.T) {
+	h, _ := newHealthCheckTestHost(state.Running)
+
+	report := newTestHealthChecker([]string{repairRestart}, nil, false).run(h)
+
+	assert.Equal(t, problemCerts, report.Problem)
+	assert.Equal(t, "certificates invalid: x509: certificate has expired", report.Detail)
+	assert.Empty(t, report.Repair)
+}
+
+func TestHealthCheckCertificatesInvalidWithoutError(t *testing.T) {
+	h, _ := newHealthCheckTestHost(state.Running)
+	checker := newTestHealthChecker(nil, nil, true)
+	checker.validateCert = func(addr string, authOptions *auth.Options) (bool, error) {
+		return false, nil
+	}
+
+	report := checker.run(h)
+
+	assert.Equal(t, problemCerts, report.Problem)
+	assert.Equal(t, "certificates invalid", report.Detail)
+}
+
+func TestWriteHealthReports(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	err := writeHealthReports(out, []healthReport{
+		{Name: "dev", State: state.Running, Repair: "started"},
+		{Name: "ci", State: state.Stopped, Problem: problemState, Detail: "machine is stopped"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `NAME   STATE     HEALTH               REPAIR
+dev    Running   healthy              started
+ci     Stopped   machine is stopped   -
+`, out.String())
+}
@@ -0,0 +1,235 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/state"
+)
+
+// inventoryStoppedGroup groups the machines which aren't running, or whose
+// state couldn't be read, so that playbooks can skip them.
+const inventoryStoppedGroup = "stopped"
+
+var reInventoryGroupInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// inventoryHost holds what the inventory needs to know about a machine.
+type inventoryHost struct {
+	Name       string
+	DriverName string
+	Labels     []string
+	State      state.State
+	SSHHost    string
+	SSHPort    int
+	SSHUser    string
+	SSHKeyPath string
+}
+
+type inventoryGroup struct {
+	Hosts []string `json:"hosts"`
+}
+
+// getInventoryHost reads the state and the SSH details of h. Those of a
+// machine which isn't running are left empty.
+func getInventoryHost(h *host.Host) inventoryHost {
+	item := inventoryHost{
+		Name:       h.Name,
+		DriverName: h.DriverName,
+		SSHUser:    h.Driver.GetSSHUsername(),
+		SSHKeyPath: h.Driver.GetSSHKeyPath(),
+	}
+	if h.HostOptions != nil {
+		if h.HostOptions.EngineOptions != nil {
+			item.Labels = append(item.Labels, h.HostOptions.EngineOptions.Labels...)
+		}
+		item.Labels = append(item.Labels, formatLabels(h.HostOptions.Labels)...)
+	}
+
+	currentState, err := h.Driver.GetState()
+	if err != nil {
+		item.State = state.Error
+		return item
+	}
+	item.State = currentState
+
+	if currentState != state.Running {
+		return item
+	}
+
+	if item.SSHHost, err = h.Driver.GetSSHHostname(); err != nil {
+		item.State = state.Error
+		return item
+	}
+
+	if item.SSHPort, err = h.Driver.GetSSHPort(); err != nil {
+		item.State = state.Error
+		return item
+	}
+
+	return item
+}
+
+// getInventoryHosts reads the machines in parallel, giving up on those which
+// don't answer within stateTimeoutDuration.
+func getInventoryHosts(hosts []*host.Host) []inventoryHost {
+	items := make(chan inventoryHost)
+
+	for _, h := range hosts {
+		go func(h *host.Host) {
+			answer := make(chan inventoryHost, 1)
+			go func() {
+				answer <- getInventoryHost(h)
+			}()
+
+			select {
+			case item := <-answer:
+				items <- item
+			case <-time.After(stateTimeoutDuration):
+				items <- inventoryHost{Name: h.Name, DriverName: h.DriverName, State: state.Timeout}
+			}
+		}(h)
+	}
+
+	inventoryHosts := []inventoryHost{}
+	for range hosts {
+		inventoryHosts = append(inventoryHosts, <-items)
+	}
+
+	sort.Sort(byInventoryHostName(inventoryHosts))
+
+	return inventoryHosts
+}
+
+type byInventoryHostName []inventoryHost
+
+func (h byInventoryHostName) Len() int           { return len(h) }
+func (h byInventoryHostName) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h byInventoryHostName) Less(i, j int) bool { return h[i].Name < h[j].Name }
+
+// inventoryGroupName turns the parts into a valid Ansible group name.
+func inventoryGroupName(parts ...string) string {
+	return reInventoryGroupInvalidChars.ReplaceAllString(strings.Join(parts, "_"), "_")
+}
+
+// buildInventory returns the Ansible dynamic inventory of the machines. The
+// running ones are grouped by driver and by engine label, the others are
+// only in the stopped group. The SSH details are given as host variables.
+func buildInventory(hosts []inventoryHost) map[string]interface{} {
+	groups := map[string]*inventoryGroup{
+		inventoryStoppedGroup: {Hosts: []string{}},
+	}
+	hostVars := map[string]map[string]interface{}{}
+
+	addToGroup := func(name, hostName string) {
+		group, present := groups[name]
+		if !present {
+			group = &inventoryGroup{Hosts: []string{}}
+			groups[name] = group
+		}
+		group.Hosts = append(group.Hosts, hostName)
+	}
+
+	for _, h := range hosts {
+		vars := map[string]interface{}{
+			"machine_driver": h.DriverName,
+			"machine_state":  h.State.String(),
+		}
+		if h.SSHUser != "" {
+			vars["ansible_user"] = h.SSHUser
+		}
+		if h.SSHKeyPath != "" {
+			vars["ansible_ssh_private_key_file"] = h.SSHKeyPath
+		}
+		hostVars[h.Name] = vars
+
+		if h.State != state.Running {
+			addToGroup(inventoryStoppedGroup, h.Name)
+			continue
+		}
+
+		if h.SSHHost != "" {
+			vars["ansible_host"] = h.SSHHost
+		}
+		if h.SSHPort != 0 {
+			vars["ansible_port"] = h.SSHPort
+		}
+
+		addToGroup(inventoryGroupName("driver", h.DriverName), h.Name)
+		for _, label := range h.Labels {
+			addToGroup(inventoryGroupName(append([]string{"label"}, strings.SplitN(label, "=", 2)...)...), h.Name)
+		}
+	}
+
+	inventory := map[string]interface{}{
+		"_meta": map[string]interface{}{
+			"hostvars": hostVars,
+		},
+	}
+	for name, group := range groups {
+		inventory[name] = group
+	}
+
+	return inventory
+}
+
+func writeInventory(out io.Writer, hosts []inventoryHost) error {
+	return writeInventoryJSON(out, buildInventory(hosts))
+}
+
+// writeInventoryHostVars writes the variables of the machine called name, as
+// Ansible asks for with --host. They're empty if it isn't one of the hosts.
+func writeInventoryHostVars(out io.Writer, hosts []inventoryHost, name string) error {
+	hostVars := buildInventory(hosts)["_meta"].(map[string]interface{})["hostvars"].(map[string]map[string]interface{})
+
+	vars, present := hostVars[name]
+	if !present {
+		vars = map[string]interface{}{}
+	}
+
+	return writeInventoryJSON(out, vars)
+}
+
+func writeInventoryJSON(out io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
+func cmdInventory(c CommandLine) error {
+	if len(c.Args()) != 0 {
+		return errTooManyArguments
+	}
+
+	hostName := c.String("host")
+	if hostName == "" {
+		hosts, err := listHosts(getStore(c))
+		if err != nil {
+			return err
+		}
+
+		return writeInventory(os.Stdout, getInventoryHosts(hosts))
+	}
+
+	if c.Bool("list") {
+		return errors.New("--list and --host can't be used together")
+	}
+
+	h, err := loadHost(getStore(c), hostName)
+	if err != nil {
+		return err
+	}
+
+	return writeInventoryHostVars(os.Stdout, getInventoryHosts([]*host.Host{h}), hostName)
+}
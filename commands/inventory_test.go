@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/engine"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildInventoryGroupsRunningMachines(t *testing.T) {
+	inventory := buildInventory([]inventoryHost{
+		{Name: "dev", DriverName: "virtualbox", Labels: []string{"env=dev", "gpu"}, State: state.Running, SSHHost: "127.0.0.1", SSHPort: 52722, SSHUser: "docker", SSHKeyPath: "/machines/dev/id_rsa"},
+		{Name: "web", DriverName: "amazonec2", Labels: []string{"env=prod.eu"}, State: state.Running, SSHHost: "52.1.2.3", SSHPort: 22, SSHUser: "ubuntu"},
+	})
+
+	assert.Equal(t, []string{"dev"}, inventory["driver_virtualbox"].(*inventoryGroup).Hosts)
+	assert.Equal(t, []string{"web"}, inventory["driver_amazonec2"].(*inventoryGroup).Hosts)
+	assert.Equal(t, []string{"dev"}, inventory["label_env_dev"].(*inventoryGroup).Hosts)
+	assert.Equal(t, []string{"dev"}, inventory["label_gpu"].(*inventoryGroup).Hosts)
+	assert.Equal(t, []string{"web"}, inventory["label_env_prod_eu"].(*inventoryGroup).Hosts)
+	assert.Empty(t, inventory["stopped"].(*inventoryGroup).Hosts)
+
+	hostVars := inventory["_meta"].(map[string]interface{})["hostvars"].(map[string]map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"ansible_host":                 "127.0.0.1",
+		"ansible_port":                 52722,
+		"ansible_user":                 "docker",
+		"ansible_ssh_private_key_file": "/machines/dev/id_rsa",
+		"machine_driver":               "virtualbox",
+		"machine_state":                "Running",
+	}, hostVars["dev"])
+	assert.Equal(t, "52.1.2.3", hostVars["web"]["ansible_host"])
+	_, present := hostVars["web"]["ansible_ssh_private_key_file"]
+	assert.False(t, present)
+}
+
+func TestBuildInventoryGroupsStoppedMachinesSeparately(t *testing.T) {
+	inventory := buildInventory([]inventoryHost{
+		{Name: "dev", DriverName: "virtualbox", Labels: []string{"env=dev"}, State: state.Running, SSHHost: "127.0.0.1", SSHPort: 52722},
+		{Name: "old", DriverName: "virtualbox", Labels: []string{"env=dev"}, State: state.Stopped, SSHUser: "docker"},
+		{Name: "lost", DriverName: "virtualbox", State: state.Timeout},
+	})
+
+	assert.Equal(t, []string{"dev"}, inventory["driver_virtualbox"].(*inventoryGroup).Hosts)
+	assert.Equal(t, []string{"dev"}, inventory["label_env_dev"].(*inventoryGroup).Hosts)
+	assert.Equal(t, []string{"old", "lost"}, inventory["stopped"].(*inventoryGroup).Hosts)
+
+	hostVars := inventory["_meta"].(map[string]interface{})["hostvars"].(map[string]map[string]interface{})
+	_, present := hostVars["old"]["ansible_host"]
+	assert.False(t, present)
+	assert.Equal(t, "docker", hostVars["old"]["ansible_user"])
+	assert.Equal(t, "Timeout", hostVars["lost"]["machine_state"])
+}
+
+func TestWriteInventoryIsJSON(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	err := writeInventory(out, []inventoryHost{
+		{Name: "dev", DriverName: "virtualbox", State: state.Running, SSHHost: "127.0.0.1", SSHPort: 22},
+	})
+	assert.NoError(t, err)
+
+	var inventory struct {
+		Meta struct {
+			HostVars map[string]map[string]interface{} `json:"hostvars"`
+		} `json:"_meta"`
+		DriverVirtualbox inventoryGroup `json:"driver_virtualbox"`
+		Stopped          inventoryGroup `json:"stopped"`
+	}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &inventory))
+	assert.Equal(t, []string{"dev"}, inventory.DriverVirtualbox.Hosts)
+	assert.Equal(t, []string{}, inventory.Stopped.Hosts)
+	assert.Equal(t, "127.0.0.1", inventory.Meta.HostVars["dev"]["ansible_host"])
+}
+
+func TestWriteInventoryHostVars(t *testing.T) {
+	hosts := []inventoryHost{
+		{Name: "dev", DriverName: "virtualbox", State: state.Running, SSHHost: "127.0.0.1", SSHPort: 22, SSHUser: "docker"},
+	}
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, writeInventoryHostVars(out, hosts, "dev"))
+
+	var vars map[string]interface{}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &vars))
+	assert.Equal(t, "127.0.0.1", vars["ansible_host"])
+	assert.Equal(t, "docker", vars["ansible_user"])
+
+	out.Reset()
+	assert.NoError(t, writeInventoryHostVars(out, hosts, "unknown"))
+	assert.Equal(t, "{}\n", out.String())
+}
+
+func TestGetInventoryHosts(t *testing.T) {
+	hosts := []*host.Host{
+		{
+			Name:        "web",
+			DriverName:  "fakedriver",
+			Driver:      &fakedriver.Driver{MockState: state.Stopped},
+			HostOptions: &host.Options{EngineOptions: &engine.Options{Labels: []string{"env=prod"}}},
+		},
+		{
+			Name:        "dev",
+			DriverName:  "fakedriver",
+			Driver:      &fakedriver.Driver{MockState: state.Running},
+			HostOptions: &host.Options{},
+		},
+	}
+
+	items := getInventoryHosts(hosts)
+
+	assert.Equal(t, 2, len(items))
+	assert.Equal(t, "dev", items[0].Name)
+	assert.Equal(t, state.Running, items[0].State)
+	assert.Equal(t, "web", items[1].Name)
+	assert.Equal(t, state.Stopped, items[1].State)
+	assert.Equal(t, []string{"env=prod"}, items[1].Labels)
+}
@@ -1,5 +1,9 @@
 package commands
 
+import "github.com/docker/machine/libmachine/log"
+
 func cmdKill(c CommandLine) error {
+	log.Warn("Killing a machine powers it off without shutting it down, data which isn't written to disk yet may be lost.")
+
 	return runActionWithContext("kill", c)
 }
@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/persist"
+)
+
+var (
+	errExpectedMachineAndLabels = errors.New("Error: Expected a machine name, followed by key=value labels to set or key- labels to remove")
+
+	reLabelKey = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+)
+
+func validateLabelKey(key string) error {
+	if !reLabelKey.MatchString(key) {
+		return fmt.Errorf("Invalid label key %q, it must start with a letter or a digit, followed by letters, digits, '.', '_', '/' or '-'", key)
+	}
+
+	return nil
+}
+
+// splitLabel splits a label given as key=value, or as key for an empty
+// value.
+func splitLabel(label string) (string, string) {
+	kv := strings.SplitN(label, "=", 2)
+	if len(kv) == 1 {
+		return kv[0], ""
+	}
+
+	return kv[0], kv[1]
+}
+
+// parseLabels parses the labels given as key=value, or as key for an empty
+// value.
+func parseLabels(labels []string) (map[string]string, error) {
+	parsed := map[string]string{}
+	for _, label := range labels {
+		key, value := splitLabel(label)
+		if err := validateLabelKey(key); err != nil {
+			return nil, err
+		}
+		parsed[key] = value
+	}
+
+	return parsed, nil
+}
+
+// formatLabels returns the labels as key=value, or as key for an empty value,
+// sorted.
+func formatLabels(labels map[string]string) []string {
+	formatted := []string{}
+	for key, value := range labels {
+		if value == "" {
+			formatted = append(formatted, key)
+		} else {
+			formatted = append(formatted, key+"="+value)
+		}
+	}
+	sort.Strings(formatted)
+
+	return formatted
+}
+
+// editLabels sets the labels given as key=value, or as key, and removes those
+// given as key-.
+func editLabels(labels map[string]string, changes []string) (map[string]string, error) {
+	edited := map[string]string{}
+	for key, value := range labels {
+		edited[key] = value
+	}
+
+	for _, change := range changes {
+		if strings.HasSuffix(change, "-") && !strings.Contains(change, "=") {
+			key := strings.TrimSuffix(change, "-")
+			if err := validateLabelKey(key); err != nil {
+				return nil, err
+			}
+			delete(edited, key)
+			continue
+		}
+
+		key, value := splitLabel(change)
+		if err := validateLabelKey(key); err != nil {
+			return nil, err
+		}
+		edited[key] = value
+	}
+
+	if len(edited) == 0 {
+		return nil, nil
+	}
+
+	return edited, nil
+}
+
+// cmdLabel prints the labels of the machine, or edits them.
+func cmdLabel(c CommandLine) error {
+	if len(c.Args()) == 0 {
+		return errExpectedMachineAndLabels
+	}
+
+	store := getStore(c)
+	name := c.Args().First()
+	changes := c.Args().Tail()
+
+	if len(changes) == 0 {
+		h, err := store.Load(name)
+		if err != nil {
+			return err
+		}
+
+		for _, label := range formatLabels(h.HostOptions.Labels) {
+			fmt.Println(label)
+		}
+
+		return nil
+	}
+
+	return persist.Update(store, name, func(h *host.Host) error {
+		labels, err := editLabels(h.HostOptions.Labels, changes)
+		if err != nil {
+			return err
+		}
+
+		h.HostOptions.Labels = labels
+		return nil
+	})
+}
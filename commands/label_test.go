@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLabels(t *testing.T) {
+	labels, err := parseLabels([]string{"env=staging", "gpu", "team=a=b"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "staging", "gpu": "", "team": "a=b"}, labels)
+
+	_, err = parseLabels([]string{"=staging"})
+	assert.Error(t, err)
+
+	_, err = parseLabels([]string{"my env=staging"})
+	assert.Error(t, err)
+}
+
+func TestFormatLabels(t *testing.T) {
+	assert.Equal(t, []string{"env=staging", "gpu"}, formatLabels(map[string]string{"gpu": "", "env": "staging"}))
+	assert.Empty(t, formatLabels(nil))
+}
+
+func TestEditLabels(t *testing.T) {
+	labels := map[string]string{"env": "staging", "gpu": ""}
+
+	edited, err := editLabels(labels, []string{"env=prod", "gpu-", "team=web"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod", "team": "web"}, edited)
+	assert.Equal(t, map[string]string{"env": "staging", "gpu": ""}, labels)
+
+	edited, err = editLabels(labels, []string{"env-", "gpu-"})
+	assert.NoError(t, err)
+	assert.Nil(t, edited)
+
+	_, err = editLabels(labels, []string{"-"})
+	assert.Error(t, err)
+}
@@ -1,13 +1,16 @@
 package commands
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"sort"
 	"strings"
 	"text/tabwriter"
+	"text/template"
 	"time"
 
 	"github.com/docker/machine/libmachine/drivers"
@@ -22,12 +25,17 @@ var (
 	stateTimeoutDuration = 10 * time.Second
 )
 
-// FilterOptions -
+// FilterOptions are the filters of ls, given as key=value. The machines
+// listed match one of the values of each key.
 type FilterOptions struct {
 	SwarmName  []string
 	DriverName []string
 	State      []string
 	Name       []string
+	Label      []string
+	// Exclude are the filters given as key!=value, excluding the machines
+	// which match any of them.
+	Exclude *FilterOptions
 }
 
 type HostListItem struct {
@@ -37,6 +45,17 @@ type HostListItem struct {
 	State        state.State
 	URL          string
 	SwarmOptions *swarm.Options
+	Labels       map[string]string `json:",omitempty"`
+}
+
+// MarshalJSON renders the state by name rather than by number.
+func (item HostListItem) MarshalJSON() ([]byte, error) {
+	type hostListItem HostListItem
+
+	return json.Marshal(struct {
+		hostListItem
+		State string
+	}{hostListItem(item), item.State.String()})
 }
 
 func cmdLs(c CommandLine) error {
@@ -46,6 +65,28 @@ func cmdLs(c CommandLine) error {
 		return err
 	}
 
+	output := c.String("output")
+	if output != "" && output != "table" && output != "json" {
+		return fmt.Errorf("Unsupported output %q, expected table or json", output)
+	}
+
+	format := c.String("format")
+	if format != "" && output != "" {
+		return errors.New("--format and --output can't be used together")
+	}
+
+	window, err := certExpiryWindow(c)
+	if err != nil {
+		return err
+	}
+
+	var tmpl *template.Template
+	if format != "" {
+		if tmpl, err = template.New("").Funcs(funcMap).Parse(format); err != nil {
+			return fmt.Errorf("Template parsing error: %v", err)
+		}
+	}
+
 	store := getStore(c)
 	hostList, err := listHosts(store)
 	if err != nil {
@@ -62,26 +103,25 @@ func cmdLs(c CommandLine) error {
 		return nil
 	}
 
-	swarmMasters := make(map[string]string)
-	swarmInfo := make(map[string]string)
-
-	w := tabwriter.NewWriter(os.Stdout, 5, 1, 3, ' ', 0)
-	fmt.Fprintln(w, "NAME\tACTIVE\tDRIVER\tSTATE\tURL\tSWARM")
+	items := getHostListItems(hostList)
 
-	for _, host := range hostList {
-		swarmOptions := host.HostOptions.SwarmOptions
-		if swarmOptions.Master {
-			swarmMasters[swarmOptions.Discovery] = host.Name
-		}
+	sortHostListItemsByName(items)
 
-		if swarmOptions.Discovery != "" {
-			swarmInfo[host.Name] = swarmOptions.Discovery
-		}
+	switch {
+	case tmpl != nil:
+		return writeHostListItemsTemplate(os.Stdout, tmpl, items)
+	case output == "json":
+		return writeHostListItemsJSON(os.Stdout, items)
 	}
 
-	items := getHostListItems(hostList)
+	printCertExpiryWarnings(hostList, window)
 
-	sortHostListItemsByName(items)
+	return writeHostListItemsTable(os.Stdout, items, getSwarmMasters(hostList))
+}
+
+func writeHostListItemsTable(out io.Writer, items []HostListItem, swarmMasters map[string]string) error {
+	w := tabwriter.NewWriter(out, 5, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tACTIVE\tDRIVER\tSTATE\tURL\tSWARM")
 
 	for _, item := range items {
 		activeString := "-"
@@ -101,41 +141,98 @@ func cmdLs(c CommandLine) error {
 			item.Name, activeString, item.DriverName, item.State, item.URL, swarmInfo)
 	}
 
-	w.Flush()
+	return w.Flush()
+}
+
+// writeHostListItemsTemplate renders each item with tmpl, one per line.
+func writeHostListItemsTemplate(out io.Writer, tmpl *template.Template, items []HostListItem) error {
+	for _, item := range items {
+		if err := tmpl.Execute(out, item); err != nil {
+			return err
+		}
+		fmt.Fprintln(out)
+	}
 
 	return nil
 }
 
+func writeHostListItemsJSON(out io.Writer, items []HostListItem) error {
+	data, err := json.MarshalIndent(items, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
+// parseFilters parses the filters given as key=value, or key!=value to
+// exclude the machines matching them.
 func parseFilters(filters []string) (FilterOptions, error) {
 	options := FilterOptions{}
 	for _, f := range filters {
-		kv := strings.SplitN(f, "=", 2)
-		if len(kv) != 2 {
+		i := strings.Index(f, "=")
+		if i <= 0 {
 			return options, errors.New("Unsupported filter syntax.")
 		}
-		key, value := kv[0], kv[1]
-
-		switch key {
-		case "swarm":
-			options.SwarmName = append(options.SwarmName, value)
-		case "driver":
-			options.DriverName = append(options.DriverName, value)
-		case "state":
-			options.State = append(options.State, value)
-		case "name":
-			options.Name = append(options.Name, value)
-		default:
-			return options, fmt.Errorf("Unsupported filter key '%s'", key)
+		key, value := f[:i], f[i+1:]
+
+		target := &options
+		if strings.HasSuffix(key, "!") {
+			key = strings.TrimSuffix(key, "!")
+			if options.Exclude == nil {
+				options.Exclude = &FilterOptions{}
+			}
+			target = options.Exclude
+		}
+
+		if err := target.add(key, value); err != nil {
+			return options, err
 		}
 	}
 	return options, nil
 }
 
+// add adds the value of the filter key.
+func (options *FilterOptions) add(key, value string) error {
+	switch key {
+	case "swarm":
+		options.SwarmName = append(options.SwarmName, value)
+	case "driver":
+		options.DriverName = append(options.DriverName, value)
+	case "state":
+		options.State = append(options.State, value)
+	case "name":
+		if _, err := regexp.Compile(value); err != nil {
+			return fmt.Errorf("Invalid name filter %q: %s", value, err)
+		}
+		options.Name = append(options.Name, value)
+	case "label":
+		labelKey, _ := splitLabel(value)
+		if err := validateLabelKey(labelKey); err != nil {
+			return err
+		}
+		options.Label = append(options.Label, value)
+	default:
+		return fmt.Errorf("Unsupported filter key '%s'", key)
+	}
+
+	return nil
+}
+
+// isEmpty tells whether there are no filters.
+func (options *FilterOptions) isEmpty() bool {
+	return options == nil ||
+		len(options.SwarmName) == 0 &&
+			len(options.DriverName) == 0 &&
+			len(options.State) == 0 &&
+			len(options.Name) == 0 &&
+			len(options.Label) == 0 &&
+			options.Exclude.isEmpty()
+}
+
 func filterHosts(hosts []*host.Host, filters FilterOptions) []*host.Host {
-	if len(filters.SwarmName) == 0 &&
-		len(filters.DriverName) == 0 &&
-		len(filters.State) == 0 &&
-		len(filters.Name) == 0 {
+	if filters.isEmpty() {
 		return hosts
 	}
 
@@ -161,13 +258,30 @@ func getSwarmMasters(hosts []*host.Host) map[string]string {
 	return swarmMasters
 }
 
+// filterHost tells whether the machine matches one of the values of each
+// key of the filters, and none of the values of the excluding filters.
 func filterHost(host *host.Host, filters FilterOptions, swarmMasters map[string]string) bool {
+	if filters.Exclude != nil && excludesHost(host, *filters.Exclude, swarmMasters) {
+		return false
+	}
+
 	swarmMatches := matchesSwarmName(host, filters.SwarmName, swarmMasters)
 	driverMatches := matchesDriverName(host, filters.DriverName)
 	stateMatches := matchesState(host, filters.State)
 	nameMatches := matchesName(host, filters.Name)
+	labelMatches := matchesLabel(host, filters.Label)
+
+	return swarmMatches && driverMatches && stateMatches && nameMatches && labelMatches
+}
 
-	return swarmMatches && driverMatches && stateMatches && nameMatches
+// excludesHost tells whether the machine matches any of the values of the
+// excluding filters.
+func excludesHost(host *host.Host, filters FilterOptions, swarmMasters map[string]string) bool {
+	return (len(filters.SwarmName) > 0 && matchesSwarmName(host, filters.SwarmName, swarmMasters)) ||
+		(len(filters.DriverName) > 0 && matchesDriverName(host, filters.DriverName)) ||
+		(len(filters.State) > 0 && matchesState(host, filters.State)) ||
+		(len(filters.Name) > 0 && matchesName(host, filters.Name)) ||
+		(len(filters.Label) > 0 && matchesLabel(host, filters.Label))
 }
 
 func matchesSwarmName(host *host.Host, swarmNames []string, swarmMasters map[string]string) bool {
@@ -200,12 +314,14 @@ func matchesState(host *host.Host, states []string) bool {
 	if len(states) == 0 {
 		return true
 	}
+
+	s, err := host.Driver.GetState()
+	if err != nil {
+		log.Warn(err)
+	}
+
 	for _, n := range states {
-		s, err := host.Driver.GetState()
-		if err != nil {
-			log.Warn(err)
-		}
-		if n == s.String() {
+		if strings.EqualFold(n, s.String()) {
 			return true
 		}
 	}
@@ -217,10 +333,10 @@ func matchesName(host *host.Host, names []string) bool {
 		return true
 	}
 	for _, n := range names {
+		// The names are checked to be valid expressions by parseFilters.
 		r, err := regexp.Compile(n)
 		if err != nil {
-			// TODO: remove that call to Fatal
-			log.Fatal(err)
+			continue
 		}
 		if r.MatchString(host.Driver.GetMachineName()) {
 			return true
@@ -229,6 +345,22 @@ func matchesName(host *host.Host, names []string) bool {
 	return false
 }
 
+// matchesLabel tells whether the machine has one of the labels, given as
+// key=value, or as key to match any value.
+func matchesLabel(host *host.Host, labels []string) bool {
+	if len(labels) == 0 {
+		return true
+	}
+	for _, l := range labels {
+		key, value := splitLabel(l)
+		actual, ok := host.HostOptions.Labels[key]
+		if ok && (!strings.Contains(l, "=") || actual == value) {
+			return true
+		}
+	}
+	return false
+}
+
 func attemptGetHostState(h *host.Host, stateQueryChan chan<- HostListItem) {
 	stateCh := make(chan state.State)
 	urlCh := make(chan string)
@@ -274,6 +406,7 @@ func attemptGetHostState(h *host.Host, stateQueryChan chan<- HostListItem) {
 		State:        currentState,
 		URL:          url,
 		SwarmOptions: h.HostOptions.SwarmOptions,
+		Labels:       h.HostOptions.Labels,
 	}
 }
 
@@ -296,6 +429,7 @@ func getHostState(h *host.Host, hostListItemsChan chan<- HostListItem) {
 			Name:       h.Name,
 			DriverName: h.Driver.DriverName(),
 			State:      state.Timeout,
+			Labels:     h.HostOptions.Labels,
 		}
 	}
 }
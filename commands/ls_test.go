@@ -2,9 +2,11 @@ package commands
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"os"
 	"testing"
+	"text/template"
 
 	"github.com/docker/machine/drivers/fakedriver"
 	"github.com/docker/machine/libmachine/host"
@@ -53,6 +55,27 @@ func TestParseFiltersValueWithEqual(t *testing.T) {
 	assert.Equal(t, actual, FilterOptions{DriverName: []string{"bar=baz"}})
 }
 
+func TestParseFiltersLabel(t *testing.T) {
+	actual, _ := parseFilters([]string{"label=env=staging", "label=gpu"})
+	assert.Equal(t, actual, FilterOptions{Label: []string{"env=staging", "gpu"}})
+}
+
+func TestParseFiltersExclude(t *testing.T) {
+	actual, _ := parseFilters([]string{"driver=amazonec2", "state!=Stopped", "label!=env=prod"})
+	assert.Equal(t, actual, FilterOptions{DriverName: []string{"amazonec2"}, Exclude: &FilterOptions{State: []string{"Stopped"}, Label: []string{"env=prod"}}})
+}
+
+func TestParseFiltersErrorsGivenInvalidValues(t *testing.T) {
+	_, err := parseFilters([]string{"name=("})
+	assert.Error(t, err)
+
+	_, err = parseFilters([]string{"label= env"})
+	assert.Error(t, err)
+
+	_, err = parseFilters([]string{"=foo"})
+	assert.EqualError(t, err, "Unsupported filter syntax.")
+}
+
 func TestFilterHostsReturnsSameGivenNoFilters(t *testing.T) {
 	opts := FilterOptions{}
 	hosts := []*host.Host{
@@ -291,6 +314,82 @@ func captureStdout() (chan string, *os.File) {
 	return out, w
 }
 
+func TestFilterHostsByLabel(t *testing.T) {
+	opts := FilterOptions{
+		Label: []string{"env=staging", "gpu"},
+	}
+	node1 :=
+		&host.Host{
+			Name:        "node1",
+			DriverName:  "fakedriver",
+			HostOptions: &host.Options{Labels: map[string]string{"env": "staging"}},
+		}
+	node2 :=
+		&host.Host{
+			Name:        "node2",
+			DriverName:  "fakedriver",
+			HostOptions: &host.Options{Labels: map[string]string{"env": "prod"}},
+		}
+	node3 :=
+		&host.Host{
+			Name:        "node3",
+			DriverName:  "fakedriver",
+			HostOptions: &host.Options{Labels: map[string]string{"env": "prod", "gpu": ""}},
+		}
+	node4 :=
+		&host.Host{
+			Name:        "node4",
+			DriverName:  "fakedriver",
+			HostOptions: &host.Options{},
+		}
+	hosts := []*host.Host{node1, node2, node3, node4}
+	expected := []*host.Host{node1, node3}
+
+	assert.EqualValues(t, filterHosts(hosts, opts), expected)
+}
+
+func TestFilterHostsExclude(t *testing.T) {
+	opts := FilterOptions{
+		DriverName: []string{"fakedriver"},
+		Exclude: &FilterOptions{
+			State: []string{"stopped"},
+			Label: []string{"env=prod"},
+		},
+	}
+	node1 :=
+		&host.Host{
+			Name:        "node1",
+			DriverName:  "fakedriver",
+			HostOptions: &host.Options{Labels: map[string]string{"env": "staging"}},
+			Driver:      &fakedriver.Driver{MockState: state.Running},
+		}
+	node2 :=
+		&host.Host{
+			Name:        "node2",
+			DriverName:  "fakedriver",
+			HostOptions: &host.Options{Labels: map[string]string{"env": "prod"}},
+			Driver:      &fakedriver.Driver{MockState: state.Running},
+		}
+	node3 :=
+		&host.Host{
+			Name:        "node3",
+			DriverName:  "fakedriver",
+			HostOptions: &host.Options{},
+			Driver:      &fakedriver.Driver{MockState: state.Stopped},
+		}
+	node4 :=
+		&host.Host{
+			Name:        "node4",
+			DriverName:  "virtualbox",
+			HostOptions: &host.Options{},
+			Driver:      &fakedriver.Driver{MockState: state.Running},
+		}
+	hosts := []*host.Host{node1, node2, node3, node4}
+	expected := []*host.Host{node1}
+
+	assert.EqualValues(t, filterHosts(hosts, opts), expected)
+}
+
 func TestGetHostListItems(t *testing.T) {
 	hostListItemsChan := make(chan HostListItem)
 
@@ -473,3 +572,59 @@ func TestIsActive(t *testing.T) {
 		assert.NoError(t, err)
 	}
 }
+
+func testHostListItems() []HostListItem {
+	return []HostListItem{
+		{
+			Name:         "master",
+			Active:       true,
+			DriverName:   "virtualbox",
+			State:        state.Running,
+			URL:          "tcp://192.168.99.100:2376",
+			SwarmOptions: &swarm.Options{Master: true, Discovery: "token://abc"},
+		},
+		{
+			Name:         "node",
+			DriverName:   "virtualbox",
+			State:        state.Stopped,
+			SwarmOptions: &swarm.Options{Discovery: "token://abc"},
+		},
+	}
+}
+
+func TestWriteHostListItemsTable(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	err := writeHostListItemsTable(out, testHostListItems(), map[string]string{"token://abc": "master"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "NAME     ACTIVE   DRIVER       STATE     URL                         SWARM\n"+
+		"master   *        virtualbox   Running   tcp://192.168.99.100:2376   master (master)\n"+
+		"node     -        virtualbox   Stopped                               master\n", out.String())
+}
+
+func TestWriteHostListItemsTemplate(t *testing.T) {
+	out := &bytes.Buffer{}
+	tmpl := template.Must(template.New("").Funcs(funcMap).Parse("{{.Name}} {{.State}} {{.URL}}"))
+
+	err := writeHostListItemsTemplate(out, tmpl, testHostListItems())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "master Running tcp://192.168.99.100:2376\nnode Stopped \n", out.String())
+}
+
+func TestWriteHostListItemsJSON(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	err := writeHostListItemsJSON(out, testHostListItems())
+	assert.NoError(t, err)
+
+	items := []map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &items))
+	assert.Len(t, items, 2)
+	assert.Equal(t, "master", items[0]["Name"])
+	assert.Equal(t, true, items[0]["Active"])
+	assert.Equal(t, "Running", items[0]["State"])
+	assert.Equal(t, "tcp://192.168.99.100:2376", items[0]["URL"])
+	assert.Equal(t, "Stopped", items[1]["State"])
+}
@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// machineEnvFile is the name of the file which selects the machine of a
+// project, looked up from the working directory like git looks up .git.
+const machineEnvFile = ".machine-env"
+
+// findMachineEnv looks for a .machine-env file in dir and then in each of its
+// parents, and returns the machine name it contains along with its path. The
+// name is empty if there is no such file.
+func findMachineEnv(dir string) (string, string, error) {
+	for {
+		path := filepath.Join(dir, machineEnvFile)
+
+		name, err := readMachineEnv(path)
+		if err == nil {
+			return name, path, nil
+		}
+
+		if !os.IsNotExist(err) {
+			return "", "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", nil
+		}
+		dir = parent
+	}
+}
+
+// readMachineEnv returns the machine name in a .machine-env file: its first
+// line which is neither blank nor a # comment.
+func readMachineEnv(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return line, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", fmt.Errorf("%s doesn't name a machine", path)
+}
+
+// machineNameFromWorkingDir returns the machine selected by the .machine-env
+// file of the working directory tree, or an empty string if there is none.
+func machineNameFromWorkingDir() (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	name, _, err := findMachineEnv(wd)
+
+	return name, err
+}
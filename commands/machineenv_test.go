@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindMachineEnvWalksUpParents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	nested := filepath.Join(dir, "project", "src", "pkg")
+	os.MkdirAll(nested, 0755)
+	ioutil.WriteFile(filepath.Join(dir, "project", machineEnvFile), []byte("# project machine\n\n  dev  \n"), 0644)
+
+	name, path, err := findMachineEnv(nested)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "dev", name)
+	assert.Equal(t, filepath.Join(dir, "project", machineEnvFile), path)
+}
+
+func TestFindMachineEnvPrefersClosestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	nested := filepath.Join(dir, "project")
+	os.MkdirAll(nested, 0755)
+	ioutil.WriteFile(filepath.Join(dir, machineEnvFile), []byte("default\n"), 0644)
+	ioutil.WriteFile(filepath.Join(nested, machineEnvFile), []byte("dev\n"), 0644)
+
+	name, _, err := findMachineEnv(nested)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "dev", name)
+}
+
+func TestFindMachineEnvWithoutFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name, path, err := findMachineEnv(dir)
+
+	assert.NoError(t, err)
+	assert.Empty(t, name)
+	assert.Empty(t, path)
+}
+
+func TestFindMachineEnvEmptyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, machineEnvFile)
+	ioutil.WriteFile(path, []byte("# nothing here\n"), 0644)
+
+	_, _, err = findMachineEnv(dir)
+
+	assert.EqualError(t, err, path+" doesn't name a machine")
+}
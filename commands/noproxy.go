@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// splitNoProxy splits comma separated hosts and networks, dropping the blank
+// ones.
+func splitNoProxy(values []string) []string {
+	entries := []string{}
+	for _, value := range values {
+		for _, entry := range strings.Split(value, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	return entries
+}
+
+// noProxyEntries returns what to add to NO_PROXY for the machine reached on
+// ip: its IP, along with its network with subnet, and the extra hosts and
+// networks, along with those recorded for the machine when it was created.
+func noProxyEntries(h *host.Host, ip string, subnet bool, extra []string) []string {
+	entries := []string{ip}
+
+	if h.HostOptions != nil && h.HostOptions.NoProxySubnet {
+		subnet = true
+	}
+
+	if subnet {
+		network, err := drivers.GetSubnet(h.Driver)
+		switch {
+		case err != nil:
+			log.Warnf("Error getting the network of %s, adding its IP only to NO_PROXY: %s", h.Name, err)
+		case network == "":
+			log.Infof("The %s driver doesn't tell the network of %s, adding its IP only to NO_PROXY", h.DriverName, h.Name)
+		default:
+			entries = append(entries, network)
+		}
+	}
+
+	if h.HostOptions != nil {
+		entries = append(entries, h.HostOptions.NoProxy...)
+	}
+
+	return append(entries, splitNoProxy(extra)...)
+}
+
+// addNoProxy adds the entries to the NO_PROXY value, idempotently.
+func addNoProxy(value string, entries []string) string {
+	existing := splitNoProxy([]string{value})
+
+	present := map[string]bool{}
+	for _, entry := range existing {
+		present[entry] = true
+	}
+
+	for _, entry := range entries {
+		if !present[entry] {
+			existing = append(existing, entry)
+			present[entry] = true
+		}
+	}
+
+	return strings.Join(existing, ",")
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitNoProxy(t *testing.T) {
+	entries := splitNoProxy([]string{"localhost, .example.com", "", "10.0.0.0/8"})
+
+	assert.Equal(t, []string{"localhost", ".example.com", "10.0.0.0/8"}, entries)
+}
+
+// subnetDriver is a fake driver which knows the network of its machine.
+type subnetDriver struct {
+	fakedriver.Driver
+	subnet string
+	err    error
+}
+
+func (d *subnetDriver) GetSubnet() (string, error) {
+	return d.subnet, d.err
+}
+
+func TestNoProxyEntries(t *testing.T) {
+	h := &host.Host{
+		Name:        "dev",
+		Driver:      &subnetDriver{subnet: "192.168.99.0/24"},
+		HostOptions: &host.Options{NoProxy: []string{"registry.local"}},
+	}
+
+	assert.Equal(t, []string{"192.168.99.100", "registry.local", "10.1.0.0/16"}, noProxyEntries(h, "192.168.99.100", false, []string{"10.1.0.0/16"}))
+	assert.Equal(t, []string{"192.168.99.100", "192.168.99.0/24", "registry.local"}, noProxyEntries(h, "192.168.99.100", true, nil))
+}
+
+func TestNoProxyEntriesGivenSubnetOfHost(t *testing.T) {
+	h := &host.Host{
+		Name:        "dev",
+		Driver:      &subnetDriver{subnet: "192.168.99.0/24"},
+		HostOptions: &host.Options{NoProxySubnet: true},
+	}
+
+	assert.Equal(t, []string{"192.168.99.100", "192.168.99.0/24"}, noProxyEntries(h, "192.168.99.100", false, nil))
+}
+
+func TestNoProxyEntriesWithoutSubnet(t *testing.T) {
+	for _, d := range []drivers.Driver{
+		&fakedriver.Driver{},
+		&subnetDriver{},
+		&subnetDriver{err: errors.New("unreachable")},
+	} {
+		h := &host.Host{Name: "dev", Driver: d, HostOptions: &host.Options{}}
+
+		assert.Equal(t, []string{"54.12.34.56"}, noProxyEntries(h, "54.12.34.56", true, nil))
+	}
+}
+
+func TestAddNoProxy(t *testing.T) {
+	assert.Equal(t, "192.168.99.100", addNoProxy("", []string{"192.168.99.100"}))
+	assert.Equal(t, "localhost,192.168.99.100", addNoProxy("localhost", []string{"192.168.99.100"}))
+	assert.Equal(t, "localhost,192.168.99.100", addNoProxy("localhost,192.168.99.100", []string{"192.168.99.100"}))
+	assert.Equal(t, "192.168.99.1000,192.168.99.100", addNoProxy("192.168.99.1000", []string{"192.168.99.100"}))
+}
@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+var (
+	errExpectedMachineAndPortForward = errors.New("Error: Expected a machine name and a port forwarding rule as arguments")
+	errExpectedMachineAndRuleName    = errors.New("Error: Expected a machine name and a rule name as arguments")
+)
+
+// writePortForwards writes the port forwarding rules of a machine.
+func writePortForwards(out io.Writer, rules []drivers.PortForward) error {
+	w := tabwriter.NewWriter(out, 5, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPROTOCOL\tHOST\tGUEST")
+
+	for _, rule := range rules {
+		hostIP := rule.HostIP
+		if hostIP == "" {
+			hostIP = "*"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s:%d\t%d\n", rule.Name, rule.Protocol, hostIP, rule.HostPort, rule.GuestPort)
+	}
+
+	return w.Flush()
+}
+
+// getPortForwarderHost loads the machine named by the first argument,
+// checking it can forward ports and that argsCount arguments were given.
+func getPortForwarderHost(c CommandLine, argsCount int, errArgs error) (*host.Host, drivers.PortForwarder, error) {
+	if len(c.Args()) != argsCount {
+		return nil, nil, errArgs
+	}
+
+	h, err := getFirstArgHost(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	forwarder, err := drivers.AsPortForwarder(h.Driver)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return h, forwarder, nil
+}
+
+func cmdPortAdd(c CommandLine) error {
+	h, forwarder, err := getPortForwarderHost(c, 2, errExpectedMachineAndPortForward)
+	if err != nil {
+		return err
+	}
+
+	rule, err := drivers.ParsePortForward(c.Args()[1])
+	if err != nil {
+		return err
+	}
+
+	if err := forwarder.AddPortForward(rule); err != nil {
+		return fmt.Errorf("Error adding port forwarding rule %s to %s: %s", rule.Name, h.Name, err)
+	}
+
+	if err := saveHost(getStore(c), h); err != nil {
+		return err
+	}
+
+	log.Infof("Port forwarding rule %s added to %s", rule.Name, h.Name)
+	return nil
+}
+
+func cmdPortRm(c CommandLine) error {
+	h, forwarder, err := getPortForwarderHost(c, 2, errExpectedMachineAndRuleName)
+	if err != nil {
+		return err
+	}
+
+	name := c.Args()[1]
+	if err := forwarder.RemovePortForward(name); err != nil {
+		return fmt.Errorf("Error removing port forwarding rule %s of %s: %s", name, h.Name, err)
+	}
+
+	if err := saveHost(getStore(c), h); err != nil {
+		return err
+	}
+
+	log.Infof("Port forwarding rule %s removed from %s", name, h.Name)
+	return nil
+}
+
+func cmdPortLs(c CommandLine) error {
+	h, forwarder, err := getPortForwarderHost(c, 1, ErrExpectedOneMachine)
+	if err != nil {
+		return err
+	}
+
+	rules, err := forwarder.ListPortForwards()
+	if err != nil {
+		return fmt.Errorf("Error listing the port forwarding rules of %s: %s", h.Name, err)
+	}
+
+	return writePortForwards(os.Stdout, rules)
+}
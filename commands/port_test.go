@@ -0,0 +1,24 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWritePortForwards(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	err := writePortForwards(out, []drivers.PortForward{
+		{Name: "web", Protocol: "tcp", HostPort: 8080, GuestPort: 80},
+		{Name: "dns", Protocol: "udp", HostIP: "127.0.0.1", HostPort: 5353, GuestPort: 53},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `NAME   PROTOCOL   HOST             GUEST
+web    tcp        *:8080           80
+dns    udp        127.0.0.1:5353   53
+`, out.String())
+}
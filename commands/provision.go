@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/docker/machine/libmachine"
+)
+
+// cmdProvision provisions the machines again, one after the other, and saves
+// them.
+func cmdProvision(c CommandLine) error {
+	hosts, err := getHostsFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	if len(hosts) == 0 {
+		return ErrNoMachineSpecified
+	}
+
+	store := getStore(c)
+	errs := []error{}
+	for _, h := range hosts {
+		if err := libmachine.Provision(store, h); err != nil {
+			errs = append(errs, fmt.Errorf("Error provisioning %s: %s", h.Name, err))
+			continue
+		}
+
+		if err := saveHost(store, h); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return consolidateErrs(errs)
+	}
+
+	return nil
+}
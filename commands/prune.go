@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnerror"
+)
+
+// pruneReason returns why the storage directory for a host should be pruned,
+// or an empty string if it backs a valid machine and must be kept.
+func pruneReason(h *host.Host, loadErr error) string {
+	if loadErr != nil {
+		return fmt.Sprintf("host record cannot be loaded: %s", loadErr)
+	}
+
+	if _, err := h.Driver.GetState(); mcnerror.IsMachineNotExist(err) {
+		return "the VM backing this host no longer exists"
+	}
+
+	return ""
+}
+
+func cmdPrune(c CommandLine) error {
+	dryRun := c.Bool("dry-run")
+	store := getStore(c)
+	machinesDir := filepath.Join(c.GlobalString("storage-path"), "machines")
+
+	dir, err := ioutil.ReadDir(machinesDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	type candidate struct {
+		name, reason string
+	}
+
+	candidates := []candidate{}
+	for _, file := range dir {
+		if !file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+
+		h, err := store.Load(file.Name())
+		if err == nil {
+			d, err := newPluginDriver(h.DriverName, h.RawDriver)
+			if err != nil {
+				log.Warnf("Skipping %s, could not check its driver state: %s", file.Name(), err)
+				continue
+			}
+			h.Driver = d
+		}
+
+		if reason := pruneReason(h, err); reason != "" {
+			candidates = append(candidates, candidate{file.Name(), reason})
+		}
+	}
+
+	if len(candidates) == 0 {
+		log.Info("Nothing to prune")
+		return nil
+	}
+
+	for _, cand := range candidates {
+		name, reason := cand.name, cand.reason
+		if dryRun {
+			log.Infof("Would remove %s: %s", name, reason)
+			continue
+		}
+
+		if !c.Bool("force") {
+			ok, err := confirmInput(fmt.Sprintf("Remove %s (%s)?", name, reason))
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				continue
+			}
+		}
+
+		if err := os.RemoveAll(filepath.Join(machinesDir, name)); err != nil {
+			log.Errorf("Error removing %s: %s", name, err)
+		} else {
+			log.Infof("Removed %s", name)
+		}
+	}
+
+	return nil
+}
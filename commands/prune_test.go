@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+)
+
+type missingVMDriver struct {
+	*fakedriver.Driver
+}
+
+func (d *missingVMDriver) GetState() (state.State, error) {
+	return state.Error, errors.New("machine does not exist")
+}
+
+func TestPruneReasonKeepsValidHost(t *testing.T) {
+	h := &host.Host{
+		Name:   "foo",
+		Driver: &fakedriver.Driver{MockState: state.Stopped},
+	}
+
+	assert.Empty(t, pruneReason(h, nil))
+}
+
+func TestPruneReasonGivenUnloadableHost(t *testing.T) {
+	reason := pruneReason(nil, errors.New("unexpected end of JSON input"))
+
+	assert.Equal(t, "host record cannot be loaded: unexpected end of JSON input", reason)
+}
+
+func TestPruneReasonGivenMissingVM(t *testing.T) {
+	h := &host.Host{
+		Name:   "foo",
+		Driver: &missingVMDriver{&fakedriver.Driver{}},
+	}
+
+	assert.Equal(t, "the VM backing this host no longer exists", pruneReason(h, nil))
+}
@@ -1,10 +1,172 @@
 package commands
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/cert"
+	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
 )
 
+// parseCertValidity parses a certificate validity, a number of days such as
+// 365d or a duration such as 8760h. It's zero if empty.
+func parseCertValidity(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days <= 0 {
+			return 0, fmt.Errorf("Certificate validity must be a number of days such as 365d or a duration such as 8760h, not %q", s)
+		}
+
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	validity, err := time.ParseDuration(s)
+	if err != nil || validity <= 0 {
+		return 0, fmt.Errorf("Certificate validity must be a number of days such as 365d or a duration such as 8760h, not %q", s)
+	}
+
+	return validity, nil
+}
+
+// serverCertOptionsFromContext returns the server certificate options given
+// on the command line, checked.
+func serverCertOptionsFromContext(c CommandLine) (cert.Options, error) {
+	validity, err := parseCertValidity(c.String("tls-cert-validity"))
+	if err != nil {
+		return cert.Options{}, err
+	}
+
+	opts := cert.Options{
+		KeyType:  c.String("tls-key-type"),
+		Bits:     c.Int("tls-key-bits"),
+		Validity: validity,
+	}
+
+	return opts, cert.ValidateOptions(opts)
+}
+
+// updateServerCertOptions sets the SANs added and the server certificate
+// options given to regenerate-certs in the auth options of a machine.
+func updateServerCertOptions(authOptions *auth.Options, sans []string, opts cert.Options) {
+	authOptions.ServerCertSANs = addValues(authOptions.ServerCertSANs, sans, optionValue)
+
+	if opts.KeyType != "" {
+		authOptions.ServerKeyType = opts.KeyType
+	}
+	if opts.Bits != 0 {
+		authOptions.ServerKeyBits = opts.Bits
+	}
+	if opts.Validity != 0 {
+		authOptions.ServerCertValidity = opts.Validity
+	}
+}
+
+// hasOwnCA tells whether the machine has a CA of its own, kept in its
+// directory with its key, rather than the CA of the workstation shared by
+// all the machines.
+func hasOwnCA(authOptions *auth.Options) bool {
+	certDir := filepath.Join(authOptions.StorePath, "certs")
+	if filepath.Dir(authOptions.CaCertPath) != certDir || filepath.Dir(authOptions.CaPrivateKeyPath) != certDir {
+		return false
+	}
+
+	_, err := os.Stat(authOptions.CaPrivateKeyPath)
+	return err == nil
+}
+
+// createOwnCA gives the machine a CA of its own, in its directory, and a
+// client certificate signed by it. The CA is generated locally, even when the
+// certificates are signed by Vault or a signing command, for share to sign
+// the certificates of the colleagues with it. The server certificate must be
+// regenerated for the engine to trust the new CA.
+func createOwnCA(authOptions *auth.Options) error {
+	certDir := filepath.Join(authOptions.StorePath, "certs")
+	if err := os.MkdirAll(certDir, 0700); err != nil {
+		return err
+	}
+
+	machineCerts := *authOptions
+	useMachineCerts(&machineCerts, authOptions.StorePath)
+	machineCerts.Signer = nil
+
+	for _, path := range []string{machineCerts.CaCertPath, machineCerts.CaPrivateKeyPath, machineCerts.ClientCertPath, machineCerts.ClientKeyPath} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	generator := cert.NewX509CertGenerator()
+	org := mcnutils.GetUsername()
+
+	if err := generator.GenerateCACertificate(machineCerts.CaCertPath, machineCerts.CaPrivateKeyPath, org, 2048); err != nil {
+		return fmt.Errorf("Error generating the CA of the machine: %s", err)
+	}
+
+	if err := generator.GenerateCert([]string{""}, machineCerts.ClientCertPath, machineCerts.ClientKeyPath, machineCerts.CaCertPath, machineCerts.CaPrivateKeyPath, org+".<bootstrap>", 2048); err != nil {
+		return fmt.Errorf("Error generating the client certificate of the machine: %s", err)
+	}
+
+	*authOptions = machineCerts
+
+	return nil
+}
+
+// hostsToRegenerateCerts returns the machines given as arguments, or all of
+// them with --all.
+func hostsToRegenerateCerts(c CommandLine) ([]*host.Host, error) {
+	if !c.Bool("all") {
+		return getHostsFromContext(c)
+	}
+
+	if len(c.Args()) > 0 {
+		return nil, errors.New("Error: machine names can't be given with --all")
+	}
+
+	return listHosts(getStore(c))
+}
+
 func cmdRegenerateCerts(c CommandLine) error {
+	opts, err := serverCertOptionsFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	window, err := certExpiryWindow(c)
+	if err != nil {
+		return err
+	}
+
+	hosts, err := hostsToRegenerateCerts(c)
+	if err != nil {
+		return err
+	}
+
+	if len(hosts) == 0 {
+		return ErrNoMachineSpecified
+	}
+
+	clientCerts := []*auth.Options{}
+	renewedCAs := map[string]bool{}
+	if c.Bool("expiring") {
+		hosts, clientCerts, renewedCAs = selectExpiringCerts(hosts, window, time.Now())
+		if len(hosts) == 0 && len(clientCerts) == 0 {
+			log.Infof("No certificate expires within %d day(s)", c.Int("cert-expiry-days"))
+			return nil
+		}
+	}
+
 	if !c.Bool("force") {
 		ok, err := confirmInput("Regenerate TLS machine certs?  Warning: this is irreversible.")
 		if err != nil {
@@ -16,7 +178,42 @@ func cmdRegenerateCerts(c CommandLine) error {
 		}
 	}
 
+	for _, authOptions := range clientCerts {
+		log.Infof("Regenerating the client certificate %s", authOptions.ClientCertPath)
+
+		if err := regenerateClientCert(authOptions); err != nil {
+			return fmt.Errorf("Error regenerating the client certificate: %s", err)
+		}
+	}
+
+	if len(hosts) == 0 {
+		return nil
+	}
+
+	for _, h := range hosts {
+		updateServerCertOptions(h.HostOptions.AuthOptions, c.StringSlice("san"), opts)
+
+		if c.Bool("own-ca") || renewedCAs[h.Name] {
+			log.Infof("Creating a CA of its own for %s", h.Name)
+
+			if err := createOwnCA(h.HostOptions.AuthOptions); err != nil {
+				return err
+			}
+		}
+	}
+
 	log.Infof("Regenerating TLS certificates")
 
-	return runActionWithContext("configureAuth", c)
+	if errs := runActionForeachMachine("configureAuth", hosts); len(errs) > 0 {
+		return consolidateErrs(errs)
+	}
+
+	store := getStore(c)
+	for _, h := range hosts {
+		if err := saveHost(store, h); err != nil {
+			return fmt.Errorf("Error saving host to store: %s", err)
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/cert"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCertValidity(t *testing.T) {
+	validity, err := parseCertValidity("365d")
+	assert.NoError(t, err)
+	assert.Equal(t, 365*24*time.Hour, validity)
+
+	validity, err = parseCertValidity("8760h")
+	assert.NoError(t, err)
+	assert.Equal(t, 8760*time.Hour, validity)
+
+	validity, err = parseCertValidity("")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), validity)
+
+	for _, s := range []string{"0d", "-1d", "xd", "1y", "-5h"} {
+		_, err := parseCertValidity(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestUpdateServerCertOptions(t *testing.T) {
+	authOptions := &auth.Options{
+		ServerCertSANs: []string{"dev.example.com"},
+		ServerKeyBits:  4096,
+	}
+
+	updateServerCertOptions(authOptions, []string{"bastion.example.com", "dev.example.com"}, cert.Options{KeyType: cert.KeyTypeECDSA})
+
+	assert.Equal(t, []string{"dev.example.com", "bastion.example.com"}, authOptions.ServerCertSANs)
+	assert.Equal(t, cert.KeyTypeECDSA, authOptions.ServerKeyType)
+	assert.Equal(t, 4096, authOptions.ServerKeyBits)
+	assert.Equal(t, time.Duration(0), authOptions.ServerCertValidity)
+}
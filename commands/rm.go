@@ -3,11 +3,18 @@ package commands
 import (
 	"errors"
 	"fmt"
+	"strings"
 
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/log"
 )
 
 func cmdRm(c CommandLine) error {
+	if isBulk(c) {
+		return rmBulk(c)
+	}
+
 	if len(c.Args()) == 0 {
 		c.ShowHelp()
 		return errors.New("You must specify a machine name")
@@ -22,19 +29,48 @@ func cmdRm(c CommandLine) error {
 			return fmt.Errorf("Error removing host %q: %s", hostName, err)
 		}
 
-		if err := h.Driver.Remove(); err != nil {
-			if !force {
-				log.Errorf("Provider error removing machine %q: %s", hostName, err)
-				continue
-			}
+		if err := libmachine.Remove(store, h, force); err != nil {
+			log.Error(err)
+			continue
 		}
 
-		if err := store.Remove(hostName); err != nil {
-			log.Errorf("Error removing machine %q from store: %s", hostName, err)
-		} else {
-			log.Infof("Successfully removed %s", hostName)
-		}
+		log.Infof("Successfully removed %s", hostName)
 	}
 
 	return nil
 }
+
+// rmBulk removes the machines selected by --all or --filter, once confirmed.
+func rmBulk(c CommandLine) error {
+	hosts, err := getTargetHostsFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	if len(hosts) == 0 {
+		log.Info("No machine matches")
+		return nil
+	}
+
+	if !c.Bool("yes") {
+		names := []string{}
+		for _, h := range hosts {
+			names = append(names, h.Name)
+		}
+
+		ok, err := confirmInput(fmt.Sprintf("Remove the %d machines %s?", len(hosts), strings.Join(names, ", ")))
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return nil
+		}
+	}
+
+	store := getStore(c)
+	force := c.Bool("force")
+	return runBulkAction(hosts, "Removed", func(h *host.Host) error {
+		return libmachine.Remove(store, h, force)
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"strings"
 
+	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/persist"
 )
@@ -65,7 +66,13 @@ func cmdScp(c CommandLine) error {
 	store := getStore(c)
 	hostInfoLoader := &storeHostInfoLoader{store}
 
-	cmd, err := getScpCmd(src, dest, c.Bool("recursive"), hostInfoLoader)
+	var cmd *exec.Cmd
+	var err error
+	if c.Bool("rsync") {
+		cmd, err = getRsyncCmd(src, dest, hostInfoLoader)
+	} else {
+		cmd, err = getScpCmd(src, dest, c.Bool("recursive"), hostInfoLoader)
+	}
 	if err != nil {
 		return err
 	}
@@ -123,6 +130,91 @@ func getScpCmd(src, dest string, recursive bool, hostInfoLoader HostInfoLoader)
 	return cmd, nil
 }
 
+// rsyncInstallCmd makes sure rsync is available on a machine, installing it
+// on boot2docker which doesn't ship it.
+const rsyncInstallCmd = "command -v rsync >/dev/null || tce-load -wi rsync"
+
+// getRsyncCmd returns the rsync command which syncs src to dest over SSH,
+// only sending the parts of the files which changed. rsync is installed on
+// the remote machine if needed.
+func getRsyncCmd(src, dest string, hostInfoLoader HostInfoLoader) (*exec.Cmd, error) {
+	cmdPath, err := exec.LookPath("rsync")
+	if err != nil {
+		return nil, errors.New("Error: You must have a copy of the rsync binary locally to use the rsync feature.")
+	}
+
+	srcHost, srcPath, srcOpts, err := getInfoForScpArg(src, hostInfoLoader)
+	if err != nil {
+		return nil, err
+	}
+
+	destHost, destPath, destOpts, err := getInfoForScpArg(dest, hostInfoLoader)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unlike scp, rsync can't relay files between two remote hosts.
+	if srcHost != nil && destHost != nil {
+		return nil, errors.New("Error: rsync can only sync files between the local host and a machine, use scp to copy from machine to machine.")
+	}
+
+	remoteHost := srcHost
+	if remoteHost == nil {
+		remoteHost = destHost
+	}
+
+	if remoteHost != nil {
+		if err := installRsync(remoteHost); err != nil {
+			return nil, err
+		}
+	}
+
+	sshCmd := append([]string{"ssh"}, baseSSHArgs...)
+	sshCmd = append(sshCmd, srcOpts...)
+	sshCmd = append(sshCmd, destOpts...)
+
+	srcArg, err := generateLocationArg(srcHost, srcPath)
+	if err != nil {
+		return nil, err
+	}
+
+	destArg, err := generateLocationArg(destHost, destPath)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(cmdPath, "-az", "-e", rsyncRemoteShell(sshCmd), srcArg, destArg)
+	log.Debug(*cmd)
+	return cmd, nil
+}
+
+// rsyncRemoteShell returns the -e option of rsync running args. rsync splits
+// it on spaces, so each argument is single quoted, e.g. for an SSH key path
+// with spaces, a single quote being doubled within the quotes.
+func rsyncRemoteShell(args []string) string {
+	quoted := []string{}
+	for _, arg := range args {
+		quoted = append(quoted, "'"+strings.Replace(arg, "'", "''", -1)+"'")
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// installRsync runs rsyncInstallCmd on the machine. Only the machines loaded
+// from the store can run commands, the others are assumed to have rsync.
+func installRsync(hostInfo HostInfo) error {
+	d, ok := hostInfo.(drivers.Driver)
+	if !ok {
+		return nil
+	}
+
+	if _, err := drivers.RunSSHCommandFromDriver(d, rsyncInstallCmd); err != nil {
+		return fmt.Errorf("Error installing rsync on %s: %s", hostInfo.GetMachineName(), err)
+	}
+
+	return nil
+}
+
 func getInfoForScpArg(hostAndPath string, hostInfoLoader HostInfoLoader) (HostInfo, string, []string, error) {
 	// Local path.  e.g. "/tmp/foo"
 	if !strings.Contains(hostAndPath, ":") {
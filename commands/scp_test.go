@@ -113,3 +113,37 @@ func TestGetScpCmd(t *testing.T) {
 	assert.Equal(t, expectedCmd, cmd)
 	assert.NoError(t, err)
 }
+
+func TestRsyncRemoteShell(t *testing.T) {
+	assert.Equal(t, `'ssh' '-i' '/Users/John Doe/.docker/machine/id_rsa' '-o' 'ProxyCommand=ssh -W %h:%p ''bastion'''`,
+		rsyncRemoteShell([]string{"ssh", "-i", "/Users/John Doe/.docker/machine/id_rsa", "-o", "ProxyCommand=ssh -W %h:%p 'bastion'"}))
+}
+
+func TestGetRsyncCmd(t *testing.T) {
+	if _, err := exec.LookPath("rsync"); err != nil {
+		t.Skip("rsync is not installed")
+	}
+
+	hostInfoLoader := MockHostInfoLoader{MockHostInfo{
+		ip:          "12.34.56.78",
+		sshUsername: "root",
+		sshKeyPath:  "/fake/keypath/id_rsa",
+	}}
+
+	cmd, err := getRsyncCmd("/tmp/foo/", "myfunhost:/home/docker/foo", &hostInfoLoader)
+
+	expectedArgs := []string{
+		"-az",
+		"-e",
+		"'ssh' '-o' 'IdentitiesOnly=yes' '-o' 'StrictHostKeyChecking=no' '-o' 'UserKnownHostsFile=/dev/null' '-o' 'LogLevel=quiet' '-i' '/fake/keypath/id_rsa'",
+		"/tmp/foo/",
+		"root@12.34.56.78:/home/docker/foo",
+	}
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedArgs, cmd.Args[1:])
+
+	_, err = getRsyncCmd("myfunhost:/tmp/foo", "otherhost:/home/docker/foo", &hostInfoLoader)
+
+	assert.Error(t, err)
+}
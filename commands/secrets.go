@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/persist"
+	"github.com/docker/machine/libmachine/secrets"
+)
+
+var (
+	errSecretsKeyExists = errors.New("There's a secrets key already, the machines saved with it couldn't be loaded with a new one")
+	errNoSecretsKey     = fmt.Errorf("There's no secrets key, run 'docker-machine secrets init' or set %s", secrets.KeyEnvVar)
+)
+
+func cmdSecretsInit(c CommandLine) error {
+	key, err := secrets.LookupKey()
+	if err != nil {
+		return err
+	}
+
+	if key != nil {
+		return errSecretsKeyExists
+	}
+
+	generated, err := secrets.GenerateKey()
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("print") {
+		fmt.Println(generated)
+		return nil
+	}
+
+	if err := secrets.StoreKeychainKey(generated); err != nil {
+		return fmt.Errorf("Error storing the secrets key in the keychain: %s", err)
+	}
+
+	log.Info("The secrets key is stored in the keychain, run 'docker-machine secrets encrypt' to encrypt the secrets of the existing machines.")
+
+	return nil
+}
+
+// cmdSecretsEncrypt saves the machines again, for the secrets of their
+// drivers kept in clear to be encrypted.
+func cmdSecretsEncrypt(c CommandLine) error {
+	key, err := secrets.LookupKey()
+	if err != nil {
+		return err
+	}
+
+	if key == nil {
+		return errNoSecretsKey
+	}
+
+	store := getStore(c)
+	hosts, err := store.List()
+	if err != nil {
+		return fmt.Errorf("Error attempting to list hosts from store: %s", err)
+	}
+
+	errs := []error{}
+	for _, h := range hosts {
+		if err := persist.Update(store, h.Name, func(*host.Host) error { return nil }); err != nil {
+			errs = append(errs, fmt.Errorf("Error encrypting the secrets of %s: %s", h.Name, err))
+			continue
+		}
+
+		log.Infof("Encrypted the secrets of %s", h.Name)
+	}
+
+	if len(errs) > 0 {
+		return consolidateErrs(errs)
+	}
+
+	return nil
+}
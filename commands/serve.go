@@ -0,0 +1,854 @@
+package commands
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/machine/commands/mcndirs"
+	"github.com/docker/machine/libmachine/cert"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/drivers/rpc"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+	"github.com/docker/machine/libmachine/persist"
+)
+
+const (
+	defaultServeAddr = "127.0.0.1:2390"
+	serveCertBits    = 2048
+)
+
+// serveActions are the actions run on a machine by POST
+// /machines/<name>/<action>, each the command of the same name.
+var serveActions = map[string]bool{
+	"start":     true,
+	"stop":      true,
+	"restart":   true,
+	"kill":      true,
+	"provision": true,
+	"upgrade":   true,
+}
+
+// forwardedGlobalFlags are the global flags passed on to the commands run by
+// the server, through their environment variable.
+var forwardedGlobalFlags = map[string]string{
+	"storage-path":        "MACHINE_STORAGE_PATH",
+	"storage-driver":      "MACHINE_STORAGE_DRIVER",
+	"storage-url":         "MACHINE_STORAGE_URL",
+	"storage-tls-ca-cert": "MACHINE_STORAGE_TLS_CA_CERT",
+	"storage-tls-cert":    "MACHINE_STORAGE_TLS_CERT",
+	"storage-tls-key":     "MACHINE_STORAGE_TLS_KEY",
+	"tls-ca-cert":         "MACHINE_TLS_CA_CERT",
+	"tls-ca-key":          "MACHINE_TLS_CA_KEY",
+	"tls-client-cert":     "MACHINE_TLS_CLIENT_CERT",
+	"tls-client-key":      "MACHINE_TLS_CLIENT_KEY",
+	"tls-signing-command": "MACHINE_TLS_SIGNING_COMMAND",
+	"tls-vault-addr":      "MACHINE_TLS_VAULT_ADDR",
+	"tls-vault-token":     "MACHINE_TLS_VAULT_TOKEN",
+	"tls-vault-pki-path":  "MACHINE_TLS_VAULT_PKI_PATH",
+	"tls-vault-role":      "MACHINE_TLS_VAULT_ROLE",
+	"github-api-token":    "MACHINE_GITHUB_API_TOKEN",
+}
+
+// serveCreateOptions are the options of create, shared by the drivers, which
+// the requests of the server can give. The others read the files of its
+// host, run commands on it or connect from it to hosts the client names.
+var serveCreateOptions = []string{
+	"engine-install-url",
+	"engine-install-url-sha256",
+	"engine-install-method",
+	"engine-install-version",
+	"engine-opt",
+	"engine-insecure-registry",
+	"engine-registry-mirror",
+	"engine-label",
+	"engine-storage-driver",
+	"engine-env",
+	"engine-timezone",
+	"engine-inotify-preset",
+	"http-proxy",
+	"https-proxy",
+	"no-proxy",
+	"swarm",
+	"swarm-image",
+	"swarm-master",
+	"swarm-discovery",
+	"swarm-discovery-opt",
+	"swarm-strategy",
+	"swarm-opt",
+	"swarm-host",
+	"swarm-addr",
+	"no-provision",
+	"wireguard",
+	"wireguard-subnet",
+	"wireguard-port",
+	"env-no-proxy",
+	"env-no-proxy-subnet",
+	"label",
+	"tls-san",
+	"tls-key-type",
+	"tls-key-bits",
+	"tls-cert-validity",
+}
+
+// serveDriverOptions are the options of the drivers which the requests of the
+// server can give, by driver. The drivers missing can't be used through the
+// server.
+var serveDriverOptions = map[string][]string{
+	"amazonec2": {
+		"amazonec2-access-key",
+		"amazonec2-secret-key",
+		"amazonec2-session-token",
+		"amazonec2-role-arn",
+		"amazonec2-ami",
+		"amazonec2-region",
+		"amazonec2-vpc-id",
+		"amazonec2-zone",
+		"amazonec2-subnet-id",
+		"amazonec2-security-group",
+		"amazonec2-instance-type",
+		"amazonec2-launch-template",
+		"amazonec2-launch-template-version",
+		"amazonec2-root-size",
+		"amazonec2-iam-instance-profile",
+		"amazonec2-ssh-user",
+		"amazonec2-request-spot-instance",
+		"amazonec2-spot-price",
+		"amazonec2-spot-allocation-strategy",
+		"amazonec2-private-address-only",
+		"amazonec2-use-private-address",
+		"amazonec2-monitoring",
+		"amazonec2-require-imdsv2",
+		"amazonec2-metadata-hop-limit",
+	},
+	"azure": {
+		"azure-docker-port",
+		"azure-docker-swarm-master-port",
+		"azure-image",
+		"azure-location",
+		"azure-password",
+		"azure-size",
+		"azure-ssh-port",
+		"azure-subscription-id",
+		"azure-username",
+	},
+	"digitalocean": {
+		"digitalocean-access-token",
+		"digitalocean-ssh-user",
+		"digitalocean-image",
+		"digitalocean-region",
+		"digitalocean-size",
+		"digitalocean-ipv6",
+		"digitalocean-private-networking",
+		"digitalocean-backups",
+	},
+	"exoscale": {
+		"exoscale-url",
+		"exoscale-api-key",
+		"exoscale-api-secret-key",
+		"exoscale-instance-profile",
+		"exoscale-disk-size",
+		"exoscale-image",
+		"exoscale-security-group",
+		"exoscale-availability-zone",
+	},
+	"google": {
+		"google-zone",
+		"google-machine-type",
+		"google-machine-image",
+		"google-username",
+		"google-project",
+		"google-scopes",
+		"google-disk-size",
+		"google-disk-type",
+		"google-address",
+		"google-preemptible",
+		"google-tags",
+		"google-use-internal-ip",
+	},
+	"hetzner": {
+		"hetzner-api-token",
+		"hetzner-api-endpoint",
+		"hetzner-server-type",
+		"hetzner-image",
+		"hetzner-location",
+		"hetzner-network",
+		"hetzner-use-private-network",
+		"hetzner-label",
+	},
+	"hyperv": {
+		"hyperv-boot2docker-url",
+		"hyperv-virtual-switch",
+		"hyperv-disk-size",
+		"hyperv-memory",
+	},
+	"kvm": {
+		"kvm-memory",
+		"kvm-cpu-count",
+		"kvm-disk-size",
+		"kvm-boot2docker-url",
+		"kvm-network",
+		"kvm-network-cidr",
+	},
+	"none": {
+		"url",
+	},
+	"openstack": {
+		"openstack-auth-url",
+		"openstack-insecure",
+		"openstack-domain-id",
+		"openstack-domain-name",
+		"openstack-username",
+		"openstack-password",
+		"openstack-tenant-name",
+		"openstack-tenant-id",
+		"openstack-region",
+		"openstack-availability-zone",
+		"openstack-endpoint-type",
+		"openstack-flavor-id",
+		"openstack-flavor-name",
+		"openstack-image-id",
+		"openstack-image-name",
+		"openstack-net-id",
+		"openstack-net-name",
+		"openstack-sec-groups",
+		"openstack-nova-network",
+		"openstack-floatingip-pool",
+		"openstack-ip-version",
+		"openstack-ssh-user",
+		"openstack-ssh-port",
+		"openstack-active-timeout",
+	},
+	"qemu": {
+		"qemu-memory",
+		"qemu-cpu-count",
+		"qemu-disk-size",
+		"qemu-boot2docker-url",
+		"qemu-accel",
+		"qemu-ssh-port",
+		"qemu-engine-port",
+	},
+	"rackspace": {
+		"rackspace-username",
+		"rackspace-api-key",
+		"rackspace-region",
+		"rackspace-endpoint-type",
+		"rackspace-image-id",
+		"rackspace-flavor-id",
+		"rackspace-ssh-user",
+		"rackspace-ssh-port",
+		"rackspace-docker-install",
+	},
+	"softlayer": {
+		"softlayer-memory",
+		"softlayer-disk-size",
+		"softlayer-user",
+		"softlayer-api-key",
+		"softlayer-region",
+		"softlayer-cpu",
+		"softlayer-hostname",
+		"softlayer-domain",
+		"softlayer-api-endpoint",
+		"softlayer-hourly-billing",
+		"softlayer-local-disk",
+		"softlayer-private-net-only",
+		"softlayer-image",
+		"softlayer-public-vlan-id",
+		"softlayer-private-vlan-id",
+	},
+	"virtualbox": {
+		"virtualbox-memory",
+		"virtualbox-cpu-count",
+		"virtualbox-disk-size",
+		"virtualbox-boot2docker-url",
+		"virtualbox-hostonly-cidr",
+		"virtualbox-hostonly-cidr-pool",
+		"virtualbox-hostonly-cidr-fallback",
+		"virtualbox-hostonly-allocator",
+		"virtualbox-hostonly-nictype",
+		"virtualbox-hostonly-nicpromisc",
+		"virtualbox-hostonly-index",
+		"virtualbox-mac-address",
+		"virtualbox-dns-proxy",
+		"virtualbox-no-dns-proxy",
+		"virtualbox-host-dns-resolver",
+		"virtualbox-guest-additions",
+		"virtualbox-autostart",
+	},
+	"vmwarefusion": {
+		"vmwarefusion-boot2docker-url",
+		"vmwarefusion-configdrive-url",
+		"vmwarefusion-cpu-count",
+		"vmwarefusion-memory-size",
+		"vmwarefusion-disk-size",
+		"vmwarefusion-ssh-user",
+		"vmwarefusion-ssh-password",
+	},
+	"vmwarevcloudair": {
+		"vmwarevcloudair-username",
+		"vmwarevcloudair-password",
+		"vmwarevcloudair-computeid",
+		"vmwarevcloudair-vdcid",
+		"vmwarevcloudair-orgvdcnetwork",
+		"vmwarevcloudair-edgegateway",
+		"vmwarevcloudair-publicip",
+		"vmwarevcloudair-catalog",
+		"vmwarevcloudair-catalogitem",
+		"vmwarevcloudair-cpu-count",
+		"vmwarevcloudair-memory-size",
+		"vmwarevcloudair-ssh-port",
+		"vmwarevcloudair-docker-port",
+	},
+	"vmwarevsphere": {
+		"vmwarevsphere-cpu-count",
+		"vmwarevsphere-memory-size",
+		"vmwarevsphere-disk-size",
+		"vmwarevsphere-boot2docker-url",
+		"vmwarevsphere-vcenter",
+		"vmwarevsphere-username",
+		"vmwarevsphere-password",
+		"vmwarevsphere-network",
+		"vmwarevsphere-datastore",
+		"vmwarevsphere-datacenter",
+		"vmwarevsphere-pool",
+		"vmwarevsphere-compute-ip",
+	},
+}
+
+// serveForcedOptions are added to the options of the requests of the server,
+// by driver: the machines created through it don't get the home directory of
+// its host shared with them.
+var serveForcedOptions = map[string][]string{
+	"kvm":          {"--kvm-no-share=true"},
+	"virtualbox":   {"--virtualbox-no-share=true"},
+	"vmwarefusion": {"--vmwarefusion-no-share=true"},
+}
+
+// serveOptionAllowed tells whether the requests of the server can give the
+// option to create a machine with the driver.
+func serveOptionAllowed(driverName, name string) bool {
+	for _, option := range append(serveCreateOptions, serveDriverOptions[driverName]...) {
+		if option == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// createRequest is the body of POST /machines.
+type createRequest struct {
+	Name   string
+	Driver string
+	// Options are the flags of create, without their leading dashes, e.g.
+	// {"virtualbox-memory": 2048}. A list repeats the flag.
+	Options map[string]interface{}
+}
+
+// serveEvent is a line of the output of a command run by the server, or its
+// outcome once it's done.
+type serveEvent struct {
+	Output string `json:",omitempty"`
+	Status string `json:",omitempty"`
+	Error  string `json:",omitempty"`
+}
+
+type serveError struct {
+	Error string
+}
+
+// machineServer serves the machines of the store over HTTP. The commands
+// changing machines are run as subprocesses, for them to behave like on the
+// command line and for their output to be streamed to the client.
+type machineServer struct {
+	store   persist.Store
+	binary  string
+	environ []string
+}
+
+func newMachineServer(c CommandLine, store persist.Store) (*machineServer, error) {
+	binary, err := exec.LookPath(os.Args[0])
+	if err != nil {
+		return nil, fmt.Errorf("Error finding the docker-machine binary: %s", err)
+	}
+
+	environ := os.Environ()
+	for flag, envVar := range forwardedGlobalFlags {
+		if value := c.GlobalString(flag); value != "" {
+			environ = append(environ, envVar+"="+value)
+		}
+	}
+
+	return &machineServer{
+		store:   store,
+		binary:  binary,
+		environ: environ,
+	}, nil
+}
+
+func (s *machineServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/machines", s.handleMachines)
+	mux.HandleFunc("/machines/", s.handleMachine)
+
+	return mux
+}
+
+func writeServeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Debugf("Error writing the response: %s", err)
+	}
+}
+
+func writeServeError(w http.ResponseWriter, status int, err error) {
+	writeServeJSON(w, status, serveError{Error: err.Error()})
+}
+
+// closeHostDrivers stops the driver plugins of the machines, which would
+// otherwise run as long as the server does.
+func closeHostDrivers(hosts []*host.Host) {
+	for _, h := range hosts {
+		d := h.Driver
+		if serialDriver, ok := d.(*drivers.SerialDriver); ok {
+			d = serialDriver.Driver
+		}
+
+		if rpcd, ok := d.(*rpcdriver.RPCClientDriver); ok {
+			if err := rpcd.Close(); err != nil {
+				log.Debugf("Error closing the driver plugin of %s: %s", h.Name, err)
+			}
+		}
+	}
+}
+
+func (s *machineServer) handleMachines(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		s.list(w)
+	case "POST":
+		s.create(w, r)
+	default:
+		writeServeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s isn't allowed on /machines", r.Method))
+	}
+}
+
+func (s *machineServer) handleMachine(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/machines/"), "/")
+	name := parts[0]
+
+	if !host.ValidateHostName(name) {
+		writeServeError(w, http.StatusBadRequest, fmt.Errorf("Invalid machine name %q", name))
+		return
+	}
+
+	exists, err := s.store.Exists(name)
+	if err != nil {
+		writeServeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !exists {
+		writeServeError(w, http.StatusNotFound, fmt.Errorf("Host does not exist: %q", name))
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == "GET":
+		s.inspect(w, name)
+	case len(parts) == 1 && r.Method == "DELETE":
+		s.run(w, "rm", name)
+	case len(parts) == 2 && r.Method == "POST" && serveActions[parts[1]]:
+		s.run(w, parts[1], name)
+	default:
+		writeServeError(w, http.StatusNotFound, fmt.Errorf("No %s %s", r.Method, r.URL.Path))
+	}
+}
+
+func (s *machineServer) list(w http.ResponseWriter) {
+	hosts, err := listHosts(s.store)
+	if err != nil {
+		writeServeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer closeHostDrivers(hosts)
+
+	items := getHostListItems(hosts)
+	sortHostListItemsByName(items)
+
+	writeServeJSON(w, http.StatusOK, items)
+}
+
+func (s *machineServer) inspect(w http.ResponseWriter, name string) {
+	h, err := loadHost(s.store, name)
+	if err != nil {
+		writeServeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer closeHostDrivers([]*host.Host{h})
+
+	writeServeJSON(w, http.StatusOK, h)
+}
+
+func (s *machineServer) create(w http.ResponseWriter, r *http.Request) {
+	var req createRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeServeError(w, http.StatusBadRequest, fmt.Errorf("Invalid create request: %s", err))
+		return
+	}
+
+	args, err := createArgs(req)
+	if err != nil {
+		writeServeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	exists, err := s.store.Exists(req.Name)
+	if err != nil {
+		writeServeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if exists {
+		writeServeError(w, http.StatusConflict, fmt.Errorf("Host already exists: %q", req.Name))
+		return
+	}
+
+	s.run(w, args...)
+}
+
+// createArgs returns the arguments of the create command of the request.
+func createArgs(req createRequest) ([]string, error) {
+	if !host.ValidateHostName(req.Name) {
+		return nil, fmt.Errorf("Invalid machine name %q", req.Name)
+	}
+
+	if req.Driver == "" {
+		return nil, errors.New("The driver of the machine is missing")
+	}
+
+	if _, ok := serveDriverOptions[req.Driver]; !ok {
+		return nil, fmt.Errorf("The driver %q can't be used through the server", req.Driver)
+	}
+
+	names := []string{}
+	for name := range req.Options {
+		if name == "" || strings.HasPrefix(name, "-") || strings.Contains(name, "=") {
+			return nil, fmt.Errorf("Invalid option %q, expected a flag of create without its dashes", name)
+		}
+		if !serveOptionAllowed(req.Driver, name) {
+			return nil, fmt.Errorf("The option %q can't be used through the server with the %s driver", name, req.Driver)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	args := []string{"create", "--driver", req.Driver}
+	for _, name := range names {
+		values, err := optionValues(req.Options[name])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid value of the option %q: %s", name, err)
+		}
+
+		for _, value := range values {
+			// The credentials of the keychain of the server are its own.
+			if strings.HasPrefix(value, credentialPrefix) {
+				return nil, fmt.Errorf("Invalid value of the option %q: the credentials of the server can't be used", name)
+			}
+			// The images of the machines are downloaded, not copied from
+			// the host of the server.
+			if (strings.HasSuffix(name, "-boot2docker-url") || strings.HasSuffix(name, "-configdrive-url")) && !strings.HasPrefix(value, "https://") && !strings.HasPrefix(value, "http://") {
+				return nil, fmt.Errorf("Invalid value of the option %q: expected an http or https URL", name)
+			}
+			args = append(args, fmt.Sprintf("--%s=%s", name, value))
+		}
+	}
+
+	args = append(args, serveForcedOptions[req.Driver]...)
+
+	return append(args, req.Name), nil
+}
+
+func optionValues(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, nil
+	case bool:
+		return []string{strconv.FormatBool(v)}, nil
+	case float64:
+		return []string{strconv.FormatFloat(v, 'f', -1, 64)}, nil
+	case []interface{}:
+		values := []string{}
+		for _, item := range v {
+			itemValues, err := optionValues(item)
+			if err != nil || len(itemValues) != 1 {
+				return nil, errors.New("expected a list of strings, numbers or booleans")
+			}
+			values = append(values, itemValues...)
+		}
+		return values, nil
+	}
+
+	return nil, errors.New("expected a string, a number, a boolean or a list of them")
+}
+
+// run runs the command and streams its output to the client as JSON
+// events, one per line, ending with its outcome.
+func (s *machineServer) run(w http.ResponseWriter, args ...string) {
+	log.Infof("Running %s", strings.Join(args, " "))
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	send := func(event serveEvent) {
+		if err := encoder.Encode(event); err != nil {
+			log.Debugf("Error writing the response: %s", err)
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	if err := streamCommand(s.binary, s.environ, args, func(line string) {
+		send(serveEvent{Output: line})
+	}); err != nil {
+		send(serveEvent{Status: "error", Error: err.Error()})
+		return
+	}
+
+	send(serveEvent{Status: "done"})
+}
+
+// streamCommand runs the command and calls output with each line of its
+// standard output and error, as they're written.
+func streamCommand(binary string, environ []string, args []string, output func(string)) error {
+	reader, writer := io.Pipe()
+
+	cmd := exec.Command(binary, args...)
+	cmd.Env = environ
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			output(scanner.Text())
+		}
+		io.Copy(ioutil.Discard, reader)
+		close(done)
+	}()
+
+	err := cmd.Wait()
+	writer.Close()
+	<-done
+
+	return err
+}
+
+// serveCertHosts returns the names the certificate of the server must be
+// valid for to be reached on addr.
+func serveCertHosts(addr string, sans []string) ([]string, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid address %q: %s", addr, err)
+	}
+
+	names := append([]string{"localhost", "127.0.0.1"}, sans...)
+	if ip := net.ParseIP(host); host != "" && (ip == nil || !ip.IsUnspecified()) {
+		names = append(names, host)
+	}
+
+	hosts := []string{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			hosts = append(hosts, name)
+		}
+	}
+
+	return hosts, nil
+}
+
+// servePaths are the files of the CA of the server, which signs its
+// certificate and those of its clients, apart from the CA of the machines:
+// the client certificates of the machines, such as those handed out by
+// share, can't call the server.
+type servePaths struct {
+	CaCertPath       string
+	CaPrivateKeyPath string
+	CertPath         string
+	KeyPath          string
+	ClientCertPath   string
+	ClientKeyPath    string
+}
+
+func newServePaths(certDir string) servePaths {
+	return servePaths{
+		CaCertPath:       filepath.Join(certDir, "serve-ca.pem"),
+		CaPrivateKeyPath: filepath.Join(certDir, "serve-ca-key.pem"),
+		CertPath:         filepath.Join(certDir, "serve-cert.pem"),
+		KeyPath:          filepath.Join(certDir, "serve-key.pem"),
+		ClientCertPath:   filepath.Join(certDir, "serve-client-cert.pem"),
+		ClientKeyPath:    filepath.Join(certDir, "serve-client-key.pem"),
+	}
+}
+
+// serveCertCovers tells whether the certificate at path is signed by the CA
+// of the pool and valid for all the hosts.
+func serveCertCovers(path string, pool *x509.CertPool, hosts []string) bool {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return false
+	}
+
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false
+	}
+
+	if _, err := certificate.Verify(x509.VerifyOptions{Roots: pool, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}); err != nil {
+		return false
+	}
+
+	for _, h := range hosts {
+		if certificate.VerifyHostname(h) != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// bootstrapServeCA creates the CA of the server and the client certificate
+// of this workstation unless they exist. They're generated locally, even
+// when the certificates of the machines are signed by Vault or a signing
+// command.
+func bootstrapServeCA(paths servePaths) error {
+	generator := cert.NewX509CertGenerator()
+	org := mcnutils.GetUsername() + ".serve"
+
+	if _, err := os.Stat(paths.CaCertPath); os.IsNotExist(err) {
+		log.Infof("Creating the CA of the server: %s", paths.CaCertPath)
+
+		if err := generator.GenerateCACertificate(paths.CaCertPath, paths.CaPrivateKeyPath, org, serveCertBits); err != nil {
+			return fmt.Errorf("Error generating the CA of the server: %s", err)
+		}
+	}
+
+	if _, err := os.Stat(paths.ClientCertPath); os.IsNotExist(err) {
+		log.Infof("Creating the client certificate of the server: %s", paths.ClientCertPath)
+
+		if err := generator.GenerateCert([]string{""}, paths.ClientCertPath, paths.ClientKeyPath, paths.CaCertPath, paths.CaPrivateKeyPath, org, serveCertBits); err != nil {
+			return fmt.Errorf("Error generating the client certificate of the server: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// serveTLSConfig returns the TLS configuration of the server: its
+// certificate, generated and signed by its CA unless it exists for the hosts
+// already, and the client certificates signed by its CA required.
+func serveTLSConfig(paths servePaths, hosts []string) (*tls.Config, error) {
+	if err := bootstrapServeCA(paths); err != nil {
+		return nil, err
+	}
+
+	caCert, err := ioutil.ReadFile(paths.CaCertPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("%s holds no valid CA certificate", paths.CaCertPath)
+	}
+
+	if !serveCertCovers(paths.CertPath, pool, hosts) {
+		log.Infof("Generating the certificate of the server for %s...", strings.Join(hosts, ", "))
+
+		org := mcnutils.GetUsername() + ".serve"
+		if err := cert.NewX509CertGenerator().GenerateCert(hosts, paths.CertPath, paths.KeyPath, paths.CaCertPath, paths.CaPrivateKeyPath, org, serveCertBits); err != nil {
+			return nil, fmt.Errorf("Error generating the certificate of the server: %s", err)
+		}
+	}
+
+	keypair, err := tls.LoadX509KeyPair(paths.CertPath, paths.KeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{keypair},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+func cmdServe(c CommandLine) error {
+	if len(c.Args()) != 0 {
+		return errTooManyArguments
+	}
+
+	addr := c.String("addr")
+	hosts, err := serveCertHosts(addr, c.StringSlice("san"))
+	if err != nil {
+		return err
+	}
+
+	certDir := mcndirs.GetMachineCertDir()
+	if err := os.MkdirAll(certDir, 0700); err != nil {
+		return err
+	}
+
+	paths := newServePaths(certDir)
+	tlsConfig, err := serveTLSConfig(paths, hosts)
+	if err != nil {
+		return err
+	}
+
+	store := getStore(c)
+	server, err := newMachineServer(c, store)
+	if err != nil {
+		return err
+	}
+
+	listener, err := tls.Listen("tcp", addr, tlsConfig)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Serving the machines on https://%s, to the clients with a certificate signed by %s", listener.Addr(), paths.CaCertPath)
+
+	return http.Serve(listener, server.handler())
+}
@@ -0,0 +1,184 @@
+package commands
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/machine/libmachine/cert"
+	"github.com/docker/machine/libmachine/persist"
+	"github.com/stretchr/testify/assert"
+)
+
+// emptyStore is a store without any machine.
+type emptyStore struct {
+	persist.Store
+}
+
+func (s emptyStore) Exists(name string) (bool, error) {
+	return false, nil
+}
+
+func TestCreateArgs(t *testing.T) {
+	args, err := createArgs(createRequest{
+		Name:   "dev",
+		Driver: "virtualbox",
+		Options: map[string]interface{}{
+			"virtualbox-memory": float64(2048),
+			"engine-label":      []interface{}{"env=dev", "team=a"},
+			"swarm":             true,
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"create", "--driver", "virtualbox", "--engine-label=env=dev", "--engine-label=team=a", "--swarm=true", "--virtualbox-memory=2048", "--virtualbox-no-share=true", "dev"}, args)
+}
+
+func TestCreateArgsGivenInvalidRequest(t *testing.T) {
+	_, err := createArgs(createRequest{Name: "not valid", Driver: "none"})
+	assert.EqualError(t, err, `Invalid machine name "not valid"`)
+
+	_, err = createArgs(createRequest{Name: "dev"})
+	assert.EqualError(t, err, "The driver of the machine is missing")
+
+	_, err = createArgs(createRequest{Name: "dev", Driver: "none", Options: map[string]interface{}{"--url": "tcp://1.2.3.4:2376"}})
+	assert.EqualError(t, err, `Invalid option "--url", expected a flag of create without its dashes`)
+
+	_, err = createArgs(createRequest{Name: "dev", Driver: "none", Options: map[string]interface{}{"url": map[string]interface{}{}}})
+	assert.EqualError(t, err, `Invalid value of the option "url": expected a string, a number, a boolean or a list of them`)
+}
+
+func TestCreateArgsGivenHostOptions(t *testing.T) {
+	for _, name := range []string{"hook-script", "hook-url", "engine-ca-cert", "user-data", "ssh-proxy-jump", "amazonec2-ssh-proxy-jump"} {
+		_, err := createArgs(createRequest{Name: "dev", Driver: "amazonec2", Options: map[string]interface{}{name: "/etc/shadow"}})
+		assert.EqualError(t, err, fmt.Sprintf("The option %q can't be used through the server with the amazonec2 driver", name))
+	}
+
+	_, err := createArgs(createRequest{Name: "dev", Driver: "kvm", Options: map[string]interface{}{"kvm-connection-uri": "qemu+ext:///system?command=/bin/sh"}})
+	assert.EqualError(t, err, `The option "kvm-connection-uri" can't be used through the server with the kvm driver`)
+
+	_, err = createArgs(createRequest{Name: "dev", Driver: "openstack", Options: map[string]interface{}{"virtualbox-memory": float64(2048)}})
+	assert.EqualError(t, err, `The option "virtualbox-memory" can't be used through the server with the openstack driver`)
+
+	for _, driverName := range []string{"generic", "httpcloud", "unknown"} {
+		_, err = createArgs(createRequest{Name: "dev", Driver: driverName})
+		assert.EqualError(t, err, fmt.Sprintf("The driver %q can't be used through the server", driverName))
+	}
+
+	_, err = createArgs(createRequest{Name: "dev", Driver: "digitalocean", Options: map[string]interface{}{"digitalocean-access-token": "credential:do"}})
+	assert.EqualError(t, err, `Invalid value of the option "digitalocean-access-token": the credentials of the server can't be used`)
+
+	_, err = createArgs(createRequest{Name: "dev", Driver: "digitalocean", Options: map[string]interface{}{"engine-env": []interface{}{"A=b", "credential:do"}}})
+	assert.EqualError(t, err, `Invalid value of the option "engine-env": the credentials of the server can't be used`)
+
+	_, err = createArgs(createRequest{Name: "dev", Driver: "vmwarefusion", Options: map[string]interface{}{"vmwarefusion-configdrive-url": "file:///etc/shadow"}})
+	assert.EqualError(t, err, `Invalid value of the option "vmwarefusion-configdrive-url": expected an http or https URL`)
+
+	_, err = createArgs(createRequest{Name: "dev", Driver: "virtualbox", Options: map[string]interface{}{"virtualbox-boot2docker-url": "file:///etc/shadow"}})
+	assert.EqualError(t, err, `Invalid value of the option "virtualbox-boot2docker-url": expected an http or https URL`)
+
+	_, err = createArgs(createRequest{Name: "dev", Driver: "virtualbox", Options: map[string]interface{}{"virtualbox-boot2docker-url": "https://example.com/boot2docker.iso"}})
+	assert.NoError(t, err)
+}
+
+func TestServeCertHosts(t *testing.T) {
+	hosts, err := serveCertHosts("0.0.0.0:2390", []string{"machines.example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"localhost", "127.0.0.1", "machines.example.com"}, hosts)
+
+	hosts, err = serveCertHosts("10.0.0.5:2390", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"localhost", "127.0.0.1", "10.0.0.5"}, hosts)
+
+	_, err = serveCertHosts("10.0.0.5", nil)
+	assert.Error(t, err)
+}
+
+func TestServeTLSConfig(t *testing.T) {
+	// Other tests replace the generator by a fake one.
+	cert.SetCertGenerator(cert.NewX509CertGenerator())
+
+	dir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	paths := newServePaths(dir)
+	_, err = serveTLSConfig(paths, []string{"localhost", "127.0.0.1"})
+	assert.NoError(t, err)
+
+	caCert, err := ioutil.ReadFile(paths.CaCertPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(caCert)
+
+	assert.True(t, serveCertCovers(paths.CertPath, pool, []string{"localhost", "127.0.0.1"}))
+	assert.False(t, serveCertCovers(paths.CertPath, pool, []string{"localhost", "10.0.0.5"}))
+	assert.False(t, serveCertCovers(filepath.Join(dir, "missing.pem"), pool, []string{"localhost"}))
+
+	// The certificates signed by the CA of the machines aren't trusted.
+	machineCA := filepath.Join(dir, "ca.pem")
+	if err := cert.GenerateCACertificate(machineCA, filepath.Join(dir, "ca-key.pem"), "test-org", 2048); err != nil {
+		t.Fatal(err)
+	}
+	clientCert := filepath.Join(dir, "cert.pem")
+	if err := cert.GenerateCert([]string{""}, clientCert, filepath.Join(dir, "key.pem"), machineCA, filepath.Join(dir, "ca-key.pem"), "test-org", 2048); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.False(t, serveCertCovers(clientCert, pool, nil))
+	assert.True(t, serveCertCovers(paths.ClientCertPath, pool, nil))
+}
+
+func TestServeInvalidMachineName(t *testing.T) {
+	server := &machineServer{store: emptyStore{}}
+	recorder := httptest.NewRecorder()
+
+	server.handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/machines/not%20valid", nil))
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestServeUnknownMachine(t *testing.T) {
+	server := &machineServer{store: emptyStore{}}
+	recorder := httptest.NewRecorder()
+
+	server.handler().ServeHTTP(recorder, httptest.NewRequest("POST", "/machines/missing/start", nil))
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, `{"Error":"Host does not exist: \"missing\""}`+"\n", recorder.Body.String())
+}
+
+func TestServeMethodNotAllowed(t *testing.T) {
+	server := &machineServer{store: emptyStore{}}
+	recorder := httptest.NewRecorder()
+
+	server.handler().ServeHTTP(recorder, httptest.NewRequest("PUT", "/machines", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
+
+func TestStreamCommand(t *testing.T) {
+	lines := []string{}
+
+	err := streamCommand("/bin/sh", nil, []string{"-c", "echo one; echo two >&2"}, func(line string) {
+		lines = append(lines, line)
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"one", "two"}, lines)
+}
+
+func TestStreamCommandFailing(t *testing.T) {
+	err := streamCommand("/bin/sh", nil, []string{"-c", "exit 3"}, func(string) {})
+
+	assert.EqualError(t, err, "exit status 3")
+}
@@ -0,0 +1,162 @@
+package commands
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/docker/machine/libmachine/cert"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+var (
+	reClientName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._@-]*$`)
+
+	errNoClientName = errors.New("Error: The colleague the machine is shared with must be given with --client")
+)
+
+// defaultShareValidity is how long the shared certificates are valid unless
+// --validity is given. They can't be revoked, short of giving the machine a
+// new CA.
+const defaultShareValidity = 30 * 24 * time.Hour
+
+// shareEnv is the script of the bundles setting up the environment for the
+// Docker client.
+const shareEnv = `# Run 'source env.sh' from this directory to talk to the engine of %s.
+export DOCKER_TLS_VERIFY="1"
+export DOCKER_HOST=%q
+export DOCKER_CERT_PATH="$PWD"
+`
+
+func validateClientName(name string) error {
+	if name == "" {
+		return errNoClientName
+	}
+
+	if !reClientName.MatchString(name) {
+		return fmt.Errorf("Error: Invalid client name %q, it must start with a letter or a digit, followed by letters, digits, '.', '_', '@' or '-'", name)
+	}
+
+	return nil
+}
+
+// writeShareBundle writes the bundle of the client certificate of the
+// machine: the certificate and its key, the CA certificate and the script
+// setting up the environment, in a directory named after the machine and the
+// client.
+func writeShareBundle(w io.Writer, h *host.Host, client, dockerHost, certFile, keyFile string) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	dir := h.Name + "-" + client + "/"
+	for name, file := range map[string]string{
+		"ca.pem":   h.HostOptions.AuthOptions.CaCertPath,
+		"cert.pem": certFile,
+		"key.pem":  keyFile,
+	} {
+		if err := addTarPath(tw, dir+name, file); err != nil {
+			return err
+		}
+	}
+
+	if err := addTarData(tw, dir+"env.sh", []byte(fmt.Sprintf(shareEnv, h.Name, dockerHost))); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gw.Close()
+}
+
+// shareHost writes the bundle of a new client certificate of the machine,
+// signed by its CA, for the client to connect to its engine. The machine must
+// have a CA of its own: a certificate signed by the CA shared by the machines
+// would open all of them.
+func shareHost(w io.Writer, h *host.Host, client, dockerHost string, opts cert.Options) error {
+	if !hasOwnCA(h.HostOptions.AuthOptions) {
+		return fmt.Errorf("Error: %s has no CA of its own, a certificate signed by the CA shared by the machines would open all of them. Run 'docker-machine regenerate-certs --own-ca %s' first", h.Name, h.Name)
+	}
+
+	if opts.Validity == 0 {
+		opts.Validity = defaultShareValidity
+	}
+
+	tmpDir, err := ioutil.TempDir("", "machine-share-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	certFile := filepath.Join(tmpDir, "cert.pem")
+	keyFile := filepath.Join(tmpDir, "key.pem")
+	authOptions := h.HostOptions.AuthOptions
+
+	opts.Org = client
+	// The CA of the machine is its own, whatever signs the certificates of
+	// the other machines.
+	if err := cert.NewX509CertGenerator().GenerateCertWithOptions([]string{""}, certFile, keyFile, authOptions.CaCertPath, authOptions.CaPrivateKeyPath, opts); err != nil {
+		return fmt.Errorf("Error generating the client certificate of %s with the CA %s: %s", client, authOptions.CaCertPath, err)
+	}
+
+	return writeShareBundle(w, h, client, dockerHost, certFile, keyFile)
+}
+
+func cmdShare(c CommandLine) error {
+	if len(c.Args()) != 1 {
+		return ErrExpectedOneMachine
+	}
+
+	client := c.String("client")
+	if err := validateClientName(client); err != nil {
+		return err
+	}
+
+	validity, err := parseCertValidity(c.String("validity"))
+	if err != nil {
+		return err
+	}
+
+	h, err := getFirstArgHost(c)
+	if err != nil {
+		return err
+	}
+
+	dockerHost, err := h.GetURL()
+	if err != nil {
+		return fmt.Errorf("Error getting the URL of %s: %s", h.Name, err)
+	}
+
+	output := c.String("output")
+	if output == "" {
+		output = h.Name + "-" + client + ".tar.gz"
+	}
+
+	f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if err := shareHost(f, h, client, dockerHost, cert.Options{Validity: validity}); err != nil {
+		f.Close()
+		os.Remove(output)
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	log.Infof("Wrote the client certificate of %s for %s to %s", client, h.Name, output)
+
+	return nil
+}
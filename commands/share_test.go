@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/machine/libmachine/cert"
+	"github.com/docker/machine/libmachine/hosttest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateClientName(t *testing.T) {
+	assert.NoError(t, validateClientName("alice"))
+	assert.NoError(t, validateClientName("alice@example.com"))
+	assert.Equal(t, errNoClientName, validateClientName(""))
+	assert.Error(t, validateClientName("../alice"))
+	assert.Error(t, validateClientName("alice smith"))
+}
+
+func readBundle(t *testing.T, r io.Reader) map[string][]byte {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[hdr.Name] = data
+	}
+
+	return files
+}
+
+func TestShareHost(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	h, err := hosttest.GetDefaultTestHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authOptions := h.HostOptions.AuthOptions
+	authOptions.StorePath = tmpDir
+	if err := createOwnCA(authOptions); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, hasOwnCA(authOptions))
+	assert.Equal(t, filepath.Join(tmpDir, "certs", "ca.pem"), authOptions.CaCertPath)
+
+	var bundle bytes.Buffer
+	err = shareHost(&bundle, h, "alice", "tcp://1.2.3.4:2376", cert.Options{})
+	assert.NoError(t, err)
+
+	files := readBundle(t, &bundle)
+	dir := h.Name + "-alice/"
+	assert.Equal(t, 4, len(files))
+	assert.Contains(t, string(files[dir+"env.sh"]), `export DOCKER_HOST="tcp://1.2.3.4:2376"`)
+	assert.Contains(t, string(files[dir+"key.pem"]), "PRIVATE KEY")
+
+	caCert, err := ioutil.ReadFile(authOptions.CaCertPath)
+	assert.NoError(t, err)
+	assert.Equal(t, caCert, files[dir+"ca.pem"])
+
+	block, _ := pem.Decode(files[dir+"cert.pem"])
+	if block == nil {
+		t.Fatal("the certificate isn't PEM encoded")
+	}
+	clientCert, err := x509.ParseCertificate(block.Bytes)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, clientCert.Subject.Organization)
+	assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, clientCert.ExtKeyUsage)
+	assert.True(t, clientCert.NotAfter.Before(time.Now().Add(31*24*time.Hour)))
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caCert)
+	_, err = clientCert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+	assert.NoError(t, err)
+}
+
+func TestShareHostWithoutOwnCA(t *testing.T) {
+	h, err := hosttest.GetDefaultTestHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var bundle bytes.Buffer
+	err = shareHost(&bundle, h, "alice", "tcp://1.2.3.4:2376", cert.Options{})
+	assert.EqualError(t, err, "Error: test-host has no CA of its own, a certificate signed by the CA shared by the machines would open all of them. Run 'docker-machine regenerate-certs --own-ca test-host' first")
+}
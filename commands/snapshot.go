@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/state"
+)
+
+var errExpectedMachineAndSnapshot = errors.New("Error: Expected a machine name and a snapshot name as arguments")
+
+// writeSnapshots writes the snapshots of a machine, the current one marked
+// with a star.
+func writeSnapshots(out io.Writer, snapshots []drivers.Snapshot) error {
+	w := tabwriter.NewWriter(out, 5, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCURRENT\tID")
+
+	for _, snapshot := range snapshots {
+		current := "-"
+		if snapshot.Current {
+			current = "*"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\n", snapshot.Name, current, snapshot.ID)
+	}
+
+	return w.Flush()
+}
+
+// restoreSnapshot brings the machine back to a snapshot. A running machine is
+// stopped first and started again afterwards.
+func restoreSnapshot(h *host.Host, name string) error {
+	snapshotter, err := drivers.AsSnapshotter(h.Driver)
+	if err != nil {
+		return err
+	}
+
+	wasRunning := drivers.MachineInState(h.Driver, state.Running)()
+	if wasRunning {
+		if err := h.Stop(); err != nil {
+			return err
+		}
+	}
+
+	if err := snapshotter.RestoreSnapshot(name); err != nil {
+		return err
+	}
+
+	if wasRunning {
+		return h.Start()
+	}
+
+	return nil
+}
+
+// getSnapshotterHost loads the machine named by the first argument, checking
+// it can take snapshots and that argsCount arguments were given.
+func getSnapshotterHost(c CommandLine, argsCount int) (*host.Host, drivers.Snapshotter, error) {
+	if len(c.Args()) != argsCount {
+		if argsCount == 1 {
+			return nil, nil, ErrExpectedOneMachine
+		}
+		return nil, nil, errExpectedMachineAndSnapshot
+	}
+
+	h, err := getFirstArgHost(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	snapshotter, err := drivers.AsSnapshotter(h.Driver)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return h, snapshotter, nil
+}
+
+func cmdSnapshotCreate(c CommandLine) error {
+	h, snapshotter, err := getSnapshotterHost(c, 2)
+	if err != nil {
+		return err
+	}
+
+	name := c.Args()[1]
+	if err := snapshotter.CreateSnapshot(name); err != nil {
+		return fmt.Errorf("Error taking snapshot %s of %s: %s", name, h.Name, err)
+	}
+
+	log.Infof("Snapshot %s of %s taken", name, h.Name)
+	return nil
+}
+
+func cmdSnapshotList(c CommandLine) error {
+	h, snapshotter, err := getSnapshotterHost(c, 1)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := snapshotter.ListSnapshots()
+	if err != nil {
+		return fmt.Errorf("Error listing the snapshots of %s: %s", h.Name, err)
+	}
+
+	return writeSnapshots(os.Stdout, snapshots)
+}
+
+func cmdSnapshotRestore(c CommandLine) error {
+	h, _, err := getSnapshotterHost(c, 2)
+	if err != nil {
+		return err
+	}
+
+	name := c.Args()[1]
+	if err := restoreSnapshot(h, name); err != nil {
+		return fmt.Errorf("Error restoring snapshot %s of %s: %s", name, h.Name, err)
+	}
+
+	if err := saveHost(getStore(c), h); err != nil {
+		return err
+	}
+
+	log.Infof("%s restored to snapshot %s", h.Name, name)
+	return nil
+}
+
+func cmdSnapshotDelete(c CommandLine) error {
+	h, snapshotter, err := getSnapshotterHost(c, 2)
+	if err != nil {
+		return err
+	}
+
+	name := c.Args()[1]
+	if err := snapshotter.DeleteSnapshot(name); err != nil {
+		return fmt.Errorf("Error deleting snapshot %s of %s: %s", name, h.Name, err)
+	}
+
+	log.Infof("Snapshot %s of %s deleted", name, h.Name)
+	return nil
+}
@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteSnapshots(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	err := writeSnapshots(out, []drivers.Snapshot{
+		{Name: "clean", ID: "6f1ab4f6"},
+		{Name: "with-redis", ID: "0b2f4e1c", Current: true},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `NAME         CURRENT   ID
+clean        -         6f1ab4f6
+with-redis   *         0b2f4e1c
+`, out.String())
+}
+
+func newSnapshotTestHost(s state.State) (*host.Host, *fakedriver.Driver) {
+	driver := &fakedriver.Driver{
+		MockName:         "dev",
+		MockState:        s,
+		MockCapabilities: []drivers.Capability{drivers.CapabilityStart, drivers.CapabilityStop, drivers.CapabilitySnapshot},
+		MockSnapshots: []drivers.Snapshot{
+			{Name: "clean", ID: "clean"},
+			{Name: "with-redis", ID: "with-redis", Current: true},
+		},
+	}
+
+	return &host.Host{Name: "dev", Driver: driver}, driver
+}
+
+func TestRestoreSnapshotOfStoppedMachine(t *testing.T) {
+	h, driver := newSnapshotTestHost(state.Stopped)
+
+	assert.NoError(t, restoreSnapshot(h, "clean"))
+	assert.True(t, driver.MockSnapshots[0].Current)
+	assert.Equal(t, state.Stopped, driver.MockState)
+}
+
+func TestRestoreSnapshotOfRunningMachine(t *testing.T) {
+	h, driver := newSnapshotTestHost(state.Running)
+
+	assert.NoError(t, restoreSnapshot(h, "clean"))
+	assert.True(t, driver.MockSnapshots[0].Current)
+	assert.Equal(t, state.Running, driver.MockState)
+}
+
+func TestRestoreSnapshotNotSupported(t *testing.T) {
+	h := &host.Host{Name: "dev", Driver: &fakedriver.Driver{}}
+
+	err := restoreSnapshot(h, "clean")
+
+	assert.Equal(t, drivers.ErrCapabilityNotSupported{DriverName: "Driver", Capability: drivers.CapabilitySnapshot}, err)
+}
@@ -3,6 +3,7 @@ package commands
 import (
 	"fmt"
 
+	"github.com/docker/machine/libmachine/ssh"
 	"github.com/docker/machine/libmachine/state"
 )
 
@@ -15,7 +16,14 @@ func cmdSSH(c CommandLine) error {
 		}
 	}
 
-	name := c.Args().First()
+	// -A forwards the SSH agent, like ssh does.
+	args := c.Args()
+	forwardAgent := len(args) > 0 && args[0] == "-A"
+	if forwardAgent {
+		args = args[1:]
+	}
+
+	name := args.First()
 	if name == "" {
 		return ErrExpectedOneMachine
 	}
@@ -40,5 +48,11 @@ func cmdSSH(c CommandLine) error {
 		return err
 	}
 
-	return client.Shell(c.Args().Tail()...)
+	if forwardAgent {
+		if client, err = ssh.ForwardAgent(client); err != nil {
+			return err
+		}
+	}
+
+	return client.Shell(args.Tail()...)
 }
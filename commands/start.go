@@ -1,11 +1,26 @@
 package commands
 
 import (
+	"fmt"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/persist"
 )
 
 func cmdStart(c CommandLine) error {
-	if err := runActionWithContext("start", c); err != nil {
+	hosts, err := getTargetHostsFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	store := getStore(c)
+	provision := c.Bool("provision")
+
+	if err := runActionOnHosts("start", c, hosts, func(h *host.Host) error {
+		return provisionOnStart(store, h, provision)
+	}); err != nil {
 		return err
 	}
 
@@ -13,3 +28,24 @@ func cmdStart(c CommandLine) error {
 
 	return nil
 }
+
+// provisionOnStart provisions the started machine if it was created with
+// --no-provision and provision is true, or warns about it otherwise.
+func provisionOnStart(store persist.Store, h *host.Host, provision bool) error {
+	if h.IsProvisioned() {
+		return nil
+	}
+
+	if !provision {
+		log.Warnf("%s isn't provisioned yet, so 'docker-machine env' won't work with it. Run 'docker-machine start --provision %s' to provision it.", h.Name, h.Name)
+		return nil
+	}
+
+	log.Infof("Provisioning %s...", h.Name)
+
+	if err := libmachine.Provision(store, h); err != nil {
+		return fmt.Errorf("Error provisioning %s: %s", h.Name, err)
+	}
+
+	return saveHost(store, h)
+}
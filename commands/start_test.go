@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvisionOnStartWarnsWithoutFlag(t *testing.T) {
+	h := &host.Host{
+		Name:        "dev",
+		HostOptions: &host.Options{Unprovisioned: true},
+	}
+
+	err := provisionOnStart(nil, h, false)
+
+	assert.NoError(t, err)
+	assert.False(t, h.IsProvisioned())
+}
+
+func TestProvisionOnStartSkipsProvisionedMachines(t *testing.T) {
+	h := &host.Host{
+		Name:        "dev",
+		HostOptions: &host.Options{},
+	}
+
+	err := provisionOnStart(nil, h, true)
+
+	assert.NoError(t, err)
+	assert.True(t, h.IsProvisioned())
+}
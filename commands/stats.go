@@ -0,0 +1,367 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/machine/libmachine/mcndockerclient"
+)
+
+// statsRefreshInterval is how often the streamed stats are rendered.
+var statsRefreshInterval = time.Second
+
+// runningContainer is a running container, as listed by /containers/json.
+type runningContainer struct {
+	ID    string   `json:"Id"`
+	Names []string `json:"Names"`
+}
+
+func (c runningContainer) name() string {
+	if len(c.Names) == 0 {
+		return ""
+	}
+
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+type cpuStats struct {
+	CPUUsage struct {
+		TotalUsage  uint64   `json:"total_usage"`
+		PercpuUsage []uint64 `json:"percpu_usage"`
+	} `json:"cpu_usage"`
+	SystemUsage uint64 `json:"system_cpu_usage"`
+	OnlineCPUs  uint32 `json:"online_cpus"`
+}
+
+type networkStats struct {
+	RxBytes uint64 `json:"rx_bytes"`
+	TxBytes uint64 `json:"tx_bytes"`
+}
+
+// containerStats is a sample of /containers/<id>/stats. Older daemons report
+// a single network, newer ones one per interface.
+type containerStats struct {
+	CPUStats    cpuStats `json:"cpu_stats"`
+	PreCPUStats cpuStats `json:"precpu_stats"`
+	MemoryStats struct {
+		Usage uint64 `json:"usage"`
+		Limit uint64 `json:"limit"`
+	} `json:"memory_stats"`
+	Network    *networkStats           `json:"network,omitempty"`
+	Networks   map[string]networkStats `json:"networks,omitempty"`
+	BlkioStats struct {
+		IoServiceBytesRecursive []struct {
+			Op    string `json:"op"`
+			Value uint64 `json:"value"`
+		} `json:"io_service_bytes_recursive"`
+	} `json:"blkio_stats"`
+}
+
+// statsRow is the resource usage of a container, as rendered in the table.
+type statsRow struct {
+	ID         string
+	Name       string
+	CPUPercent float64
+	MemUsage   uint64
+	MemLimit   uint64
+	MemPercent float64
+	NetRx      uint64
+	NetTx      uint64
+	BlockRead  uint64
+	BlockWrite uint64
+}
+
+// newStatsRow computes the usage of the container c from the stats sample s,
+// the same way as docker stats does.
+func newStatsRow(c runningContainer, s containerStats) statsRow {
+	row := statsRow{
+		ID:       c.ID,
+		Name:     c.name(),
+		MemUsage: s.MemoryStats.Usage,
+		MemLimit: s.MemoryStats.Limit,
+	}
+
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(s.CPUStats.SystemUsage) - float64(s.PreCPUStats.SystemUsage)
+	cpus := float64(s.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(s.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta > 0 && systemDelta > 0 {
+		row.CPUPercent = cpuDelta / systemDelta * cpus * 100
+	}
+
+	if s.MemoryStats.Limit != 0 {
+		row.MemPercent = float64(s.MemoryStats.Usage) / float64(s.MemoryStats.Limit) * 100
+	}
+
+	if s.Network != nil {
+		row.NetRx, row.NetTx = s.Network.RxBytes, s.Network.TxBytes
+	}
+	for _, n := range s.Networks {
+		row.NetRx += n.RxBytes
+		row.NetTx += n.TxBytes
+	}
+
+	for _, entry := range s.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			row.BlockRead += entry.Value
+		case "write":
+			row.BlockWrite += entry.Value
+		}
+	}
+
+	return row
+}
+
+// formatBytes renders a number of bytes with a decimal unit, e.g. 1.5 MB.
+func formatBytes(size uint64) string {
+	units := []string{"B", "kB", "MB", "GB", "TB", "PB"}
+
+	value := float64(size)
+	unit := 0
+	for value >= 1000 && unit < len(units)-1 {
+		value /= 1000
+		unit++
+	}
+
+	return fmt.Sprintf("%.4g %s", value, units[unit])
+}
+
+// writeStats renders the rows as a table sorted by container name.
+func writeStats(out io.Writer, rows []statsRow) error {
+	sorted := make([]statsRow, len(rows))
+	copy(sorted, rows)
+	sort.Sort(byStatsRowName(sorted))
+
+	w := tabwriter.NewWriter(out, 5, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "CONTAINER\tNAME\tCPU %\tMEM USAGE / LIMIT\tMEM %\tNET I/O\tBLOCK I/O")
+
+	for _, row := range sorted {
+		id := row.ID
+		if len(id) > 12 {
+			id = id[:12]
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%.2f%%\t%s / %s\t%.2f%%\t%s / %s\t%s / %s\n",
+			id, row.Name, row.CPUPercent,
+			formatBytes(row.MemUsage), formatBytes(row.MemLimit), row.MemPercent,
+			formatBytes(row.NetRx), formatBytes(row.NetTx),
+			formatBytes(row.BlockRead), formatBytes(row.BlockWrite))
+	}
+
+	return w.Flush()
+}
+
+type byStatsRowName []statsRow
+
+func (r byStatsRowName) Len() int           { return len(r) }
+func (r byStatsRowName) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r byStatsRowName) Less(i, j int) bool { return r[i].Name < r[j].Name }
+
+// listRunningContainers asks the daemon for its running containers.
+func listRunningContainers(client *mcndockerclient.Client) ([]runningContainer, error) {
+	resp, err := client.Get("/containers/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response from the Docker daemon: %s", resp.Status)
+	}
+
+	containers := []runningContainer{}
+	if err := json.NewDecoder(resp.Body).Decode(&containers); err != nil {
+		return nil, err
+	}
+
+	return containers, nil
+}
+
+// getContainerStats reads a single stats sample of the container.
+func getContainerStats(client *mcndockerclient.Client, c runningContainer) (statsRow, error) {
+	resp, err := client.Get("/containers/" + c.ID + "/stats?stream=false")
+	if err != nil {
+		return statsRow{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statsRow{}, fmt.Errorf("unexpected response from the Docker daemon: %s", resp.Status)
+	}
+
+	var s containerStats
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		return statsRow{}, err
+	}
+
+	return newStatsRow(c, s), nil
+}
+
+// getStatsSnapshot reads a stats sample of every container in parallel.
+// Containers which stopped in the meantime are left out.
+func getStatsSnapshot(client *mcndockerclient.Client, containers []runningContainer) []statsRow {
+	rowsCh := make(chan *statsRow)
+
+	for _, c := range containers {
+		go func(c runningContainer) {
+			row, err := getContainerStats(client, c)
+			if err != nil {
+				rowsCh <- nil
+				return
+			}
+			rowsCh <- &row
+		}(c)
+	}
+
+	rows := []statsRow{}
+	for range containers {
+		if row := <-rowsCh; row != nil {
+			rows = append(rows, *row)
+		}
+	}
+
+	return rows
+}
+
+// statsStreams keeps the last sample streamed by each container.
+type statsStreams struct {
+	client *mcndockerclient.Client
+
+	mu   sync.Mutex
+	rows map[string]statsRow
+	open map[string]bool
+}
+
+func newStatsStreams(client *mcndockerclient.Client) *statsStreams {
+	return &statsStreams{
+		client: client,
+		rows:   map[string]statsRow{},
+		open:   map[string]bool{},
+	}
+}
+
+// follow streams the stats of the containers which aren't streamed yet.
+func (s *statsStreams) follow(containers []runningContainer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range containers {
+		if !s.open[c.ID] {
+			s.open[c.ID] = true
+			go s.stream(c)
+		}
+	}
+}
+
+// stream records the samples of the container until it stops.
+func (s *statsStreams) stream(c runningContainer) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.open, c.ID)
+		delete(s.rows, c.ID)
+		s.mu.Unlock()
+	}()
+
+	resp, err := s.client.Stream("/containers/" + c.ID + "/stats")
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var sample containerStats
+		if err := decoder.Decode(&sample); err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.rows[c.ID] = newStatsRow(c, sample)
+		s.mu.Unlock()
+	}
+}
+
+func (s *statsStreams) snapshot() []statsRow {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows := []statsRow{}
+	for _, row := range s.rows {
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func writeNoRunningContainers(out io.Writer, machineName string) {
+	fmt.Fprintf(out, "No running containers on %s\n", machineName)
+}
+
+func cmdStats(c CommandLine) error {
+	if len(c.Args()) != 1 {
+		return ErrExpectedOneMachine
+	}
+
+	h, err := getFirstArgHost(c)
+	if err != nil {
+		return err
+	}
+
+	dockerHost, authOptions, err := runConnectionBoilerplate(h, c)
+	if err != nil {
+		return fmt.Errorf("Error running connection boilerplate: %s", err)
+	}
+
+	client, err := mcndockerclient.DefaultPool.Get(h.Name, dockerHost, authOptions)
+	if err != nil {
+		return err
+	}
+
+	containers, err := listRunningContainers(client)
+	if err != nil {
+		return fmt.Errorf("Error listing the containers of %s: %s", h.Name, err)
+	}
+
+	if c.Bool("no-stream") {
+		if len(containers) == 0 {
+			writeNoRunningContainers(os.Stdout, h.Name)
+			return nil
+		}
+
+		return writeStats(os.Stdout, getStatsSnapshot(client, containers))
+	}
+
+	streams := newStatsStreams(client)
+	for {
+		streams.follow(containers)
+
+		// Clear the terminal and render from its top left corner.
+		fmt.Print("\033[2J\033[H")
+		if len(containers) == 0 {
+			writeNoRunningContainers(os.Stdout, h.Name)
+		} else if err := writeStats(os.Stdout, streams.snapshot()); err != nil {
+			return err
+		}
+
+		time.Sleep(statsRefreshInterval)
+
+		if containers, err = listRunningContainers(client); err != nil {
+			return fmt.Errorf("Error listing the containers of %s: %s", h.Name, err)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/docker/machine/libmachine/mcndockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+const testContainerStats = `{
+  "cpu_stats": {"cpu_usage": {"total_usage": 400000000, "percpu_usage": [200000000, 200000000]}, "system_cpu_usage": 20000000000},
+  "precpu_stats": {"cpu_usage": {"total_usage": 300000000}, "system_cpu_usage": 18000000000},
+  "memory_stats": {"usage": 52428800, "limit": 1048576000},
+  "networks": {"eth0": {"rx_bytes": 1000, "tx_bytes": 2000}, "eth1": {"rx_bytes": 24, "tx_bytes": 48}},
+  "blkio_stats": {"io_service_bytes_recursive": [
+    {"op": "Read", "value": 4096},
+    {"op": "Write", "value": 8192},
+    {"op": "Total", "value": 12288}
+  ]}
+}`
+
+func TestNewStatsRow(t *testing.T) {
+	var s containerStats
+	assert.NoError(t, json.Unmarshal([]byte(testContainerStats), &s))
+
+	row := newStatsRow(runningContainer{ID: "4b2a7e1c9d3f", Names: []string{"/redis"}}, s)
+
+	assert.Equal(t, "redis", row.Name)
+	assert.InDelta(t, 10.0, row.CPUPercent, 0.001)
+	assert.Equal(t, uint64(52428800), row.MemUsage)
+	assert.InDelta(t, 5.0, row.MemPercent, 0.001)
+	assert.Equal(t, uint64(1024), row.NetRx)
+	assert.Equal(t, uint64(2048), row.NetTx)
+	assert.Equal(t, uint64(4096), row.BlockRead)
+	assert.Equal(t, uint64(8192), row.BlockWrite)
+}
+
+func TestNewStatsRowWithoutPreviousSample(t *testing.T) {
+	var s containerStats
+	assert.NoError(t, json.Unmarshal([]byte(`{"cpu_stats": {"cpu_usage": {"total_usage": 400}, "system_cpu_usage": 2000}, "network": {"rx_bytes": 10, "tx_bytes": 20}}`), &s))
+
+	row := newStatsRow(runningContainer{ID: "4b2a7e1c9d3f"}, s)
+
+	assert.Equal(t, 0.0, row.CPUPercent)
+	assert.Equal(t, 0.0, row.MemPercent)
+	assert.Equal(t, uint64(10), row.NetRx)
+	assert.Equal(t, uint64(20), row.NetTx)
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "0 B", formatBytes(0))
+	assert.Equal(t, "999 B", formatBytes(999))
+	assert.Equal(t, "1.5 kB", formatBytes(1500))
+	assert.Equal(t, "52.43 MB", formatBytes(52428800))
+	assert.Equal(t, "2 GB", formatBytes(2000000000))
+}
+
+func TestWriteStats(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	err := writeStats(out, []statsRow{
+		{ID: "9f1d0c83a6e2a5b7", Name: "web", CPUPercent: 2.1, MemUsage: 41300000, MemLimit: 1045000000, MemPercent: 3.95},
+		{ID: "4b2a7e1c9d3f", Name: "redis", CPUPercent: 0.35, NetRx: 1296, NetTx: 648, BlockRead: 5120000},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `CONTAINER      NAME    CPU %   MEM USAGE / LIMIT    MEM %   NET I/O            BLOCK I/O
+4b2a7e1c9d3f   redis   0.35%   0 B / 0 B            0.00%   1.296 kB / 648 B   5.12 MB / 0 B
+9f1d0c83a6e2   web     2.10%   41.3 MB / 1.045 GB   3.95%   0 B / 0 B          0 B / 0 B
+`, out.String())
+}
+
+func newTestStatsServer(t *testing.T, containers string) (*httptest.Server, *mcndockerclient.Client) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/json":
+			fmt.Fprint(w, containers)
+		case "/containers/4b2a7e1c9d3f/stats":
+			if r.URL.Query().Get("stream") == "false" {
+				fmt.Fprint(w, testContainerStats)
+				return
+			}
+			fmt.Fprintln(w, testContainerStats)
+			fmt.Fprintln(w, testContainerStats)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	return server, &mcndockerclient.Client{Client: http.DefaultClient, BaseURL: server.URL}
+}
+
+func TestGetStatsSnapshot(t *testing.T) {
+	server, client := newTestStatsServer(t, `[{"Id": "4b2a7e1c9d3f", "Names": ["/redis"]}, {"Id": "gone", "Names": ["/gone"]}]`)
+	defer server.Close()
+
+	containers, err := listRunningContainers(client)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(containers))
+
+	rows := getStatsSnapshot(client, containers)
+
+	assert.Equal(t, 1, len(rows))
+	assert.Equal(t, "redis", rows[0].Name)
+}
+
+func TestListRunningContainersWithoutContainers(t *testing.T) {
+	server, client := newTestStatsServer(t, `[]`)
+	defer server.Close()
+
+	containers, err := listRunningContainers(client)
+
+	assert.NoError(t, err)
+	assert.Empty(t, containers)
+	assert.Empty(t, getStatsSnapshot(client, containers))
+}
+
+func TestStatsStreamsDropStoppedContainers(t *testing.T) {
+	server, client := newTestStatsServer(t, `[]`)
+	defer server.Close()
+
+	streams := newStatsStreams(client)
+	streams.follow([]runningContainer{{ID: "4b2a7e1c9d3f", Names: []string{"/redis"}}})
+
+	// The test server ends the stream after two samples, as if the
+	// container stopped.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		streams.mu.Lock()
+		open := len(streams.open)
+		streams.mu.Unlock()
+
+		if open == 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.Empty(t, streams.snapshot())
+}
+
+func TestWriteNoRunningContainers(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	writeNoRunningContainers(out, "dev")
+
+	assert.Equal(t, "No running containers on dev\n", out.String())
+}
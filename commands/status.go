@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/log"
 )
 
@@ -9,17 +10,24 @@ func cmdStatus(c CommandLine) error {
 		return ErrExpectedOneMachine
 	}
 
-	host, err := getFirstArgHost(c)
+	window, err := certExpiryWindow(c)
 	if err != nil {
 		return err
 	}
 
-	currentState, err := host.Driver.GetState()
+	h, err := getFirstArgHost(c)
 	if err != nil {
-		log.Errorf("error getting state for host %s: %s", host.Name, err)
+		return err
+	}
+
+	currentState, err := h.Driver.GetState()
+	if err != nil {
+		log.Errorf("error getting state for host %s: %s", h.Name, err)
 	}
 
 	log.Info(currentState)
 
+	printCertExpiryWarnings([]*host.Host{h}, window)
+
 	return nil
 }
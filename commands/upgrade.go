@@ -1,5 +1,254 @@
 package commands
 
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/provision"
+)
+
+// engineVersionReport is what upgrade --check reports about a machine.
+type engineVersionReport struct {
+	Name      string
+	Version   string
+	Pinned    string
+	Available []string
+}
+
+// byEngineVersion sorts engine versions which can be parsed, oldest first.
+type byEngineVersion []string
+
+func (s byEngineVersion) Len() int {
+	return len(s)
+}
+
+func (s byEngineVersion) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s byEngineVersion) Less(i, j int) bool {
+	a, _ := parseEngineVersion(s[i])
+	b, _ := parseEngineVersion(s[j])
+	return a.lessThan(b)
+}
+
+// newerEngineVersions returns the versions of available which are newer than
+// current, newest first. The versions which can't be parsed are left out.
+func newerEngineVersions(current string, available []string) []string {
+	currentVersion, err := parseEngineVersion(current)
+	if err != nil {
+		return []string{}
+	}
+
+	seen := map[string]bool{}
+	newer := []string{}
+	for _, v := range available {
+		version, err := parseEngineVersion(v)
+		if err != nil || seen[v] || !currentVersion.lessThan(version) {
+			continue
+		}
+
+		seen[v] = true
+		newer = append(newer, v)
+	}
+
+	sort.Sort(sort.Reverse(byEngineVersion(newer)))
+
+	return newer
+}
+
+// checkEngineDowngrade returns an error if going from the current engine
+// version of the machine to version is a downgrade, unless it's allowed.
+func checkEngineDowngrade(machineName, current, version string, allowDowngrade bool) error {
+	currentVersion, err := parseEngineVersion(current)
+	if err != nil {
+		return fmt.Errorf("Error parsing the engine version of %s: %s", machineName, err)
+	}
+
+	targetVersion, err := parseEngineVersion(version)
+	if err != nil {
+		return fmt.Errorf("Error parsing the engine version: %s", err)
+	}
+
+	if targetVersion.lessThan(currentVersion) && !allowDowngrade {
+		return fmt.Errorf("Error: upgrading %s to engine version %s would downgrade it from %s, use --allow-downgrade to do it anyway", machineName, version, current)
+	}
+
+	return nil
+}
+
+// writeEngineVersionReports writes the engine version of the machines and the
+// newer ones they can be upgraded to.
+func writeEngineVersionReports(out io.Writer, reports []engineVersionReport) error {
+	w := tabwriter.NewWriter(out, 5, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVERSION\tPINNED\tAVAILABLE")
+
+	for _, report := range reports {
+		pinned := report.Pinned
+		if pinned == "" {
+			pinned = "-"
+		}
+
+		available := "-"
+		if report.Available == nil {
+			available = "Unknown"
+		} else if len(report.Available) > 0 {
+			available = strings.Join(report.Available, ", ")
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", report.Name, report.Version, pinned, available)
+	}
+
+	return w.Flush()
+}
+
+// currentEngineVersion asks the running machine for the version of its
+// engine.
+func currentEngineVersion(c CommandLine, h *host.Host) (string, error) {
+	dockerHost, authOptions, err := runConnectionBoilerplate(h, c)
+	if err != nil {
+		return "", fmt.Errorf("Error running connection boilerplate: %s", err)
+	}
+
+	version, err := getEngineVersion(h.Name, dockerHost, authOptions)
+	if err != nil {
+		return "", fmt.Errorf("Error getting the Docker engine version of %s: %s", h.Name, err)
+	}
+
+	return version, nil
+}
+
+// availableEngineVersions lists the engine versions the running machine can
+// be upgraded to.
+func availableEngineVersions(h *host.Host) ([]string, error) {
+	provisioner, err := provision.DetectProvisioner(h.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	versioner, err := provision.AsEngineVersioner(provisioner)
+	if err != nil {
+		return nil, err
+	}
+
+	return versioner.AvailableEngineVersions()
+}
+
+func getEngineVersionReport(c CommandLine, h *host.Host) engineVersionReport {
+	report := engineVersionReport{
+		Name:    h.Name,
+		Version: "Unknown",
+		Pinned:  h.PinnedEngineVersion(),
+	}
+
+	version, err := currentEngineVersion(c, h)
+	if err != nil {
+		log.Warn(err)
+		return report
+	}
+	report.Version = version
+
+	available, err := availableEngineVersions(h)
+	if err != nil {
+		log.Warnf("Error listing the engine versions available to %s: %s", h.Name, err)
+		return report
+	}
+	report.Available = newerEngineVersions(version, available)
+
+	return report
+}
+
+func cmdUpgradeCheck(c CommandLine) error {
+	hosts, err := getHostsFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	if len(hosts) == 0 {
+		return ErrNoMachineSpecified
+	}
+
+	reports := []engineVersionReport{}
+	for _, h := range hosts {
+		reports = append(reports, getEngineVersionReport(c, h))
+	}
+
+	return writeEngineVersionReports(os.Stdout, reports)
+}
+
+// upgradeEngineVersion installs version on the machine, refusing to
+// downgrade it unless allowed.
+func upgradeEngineVersion(c CommandLine, h *host.Host, version string) error {
+	current, err := currentEngineVersion(c, h)
+	if err != nil {
+		return err
+	}
+
+	if current == version {
+		log.Infof("%s already runs engine version %s", h.Name, version)
+		h.HostOptions.EngineOptions.InstallVersion = version
+		return nil
+	}
+
+	if err := checkEngineDowngrade(h.Name, current, version, c.Bool("allow-downgrade")); err != nil {
+		return err
+	}
+
+	log.Infof("Upgrading the engine of %s from version %s to %s...", h.Name, current, version)
+
+	if err := h.UpgradeEngine(version); err != nil {
+		return fmt.Errorf("Error upgrading the engine of %s: %s", h.Name, err)
+	}
+
+	return nil
+}
+
 func cmdUpgrade(c CommandLine) error {
-	return runActionWithContext("upgrade", c)
+	if c.Bool("check") {
+		return cmdUpgradeCheck(c)
+	}
+
+	version := c.String("engine-version")
+	if version == "" {
+		return runActionWithContext("upgrade", c)
+	}
+
+	if !reEngineVersion.MatchString(version) {
+		return fmt.Errorf("Engine version must be a version such as 20.10.24, not %q", version)
+	}
+
+	store := getStore(c)
+
+	hosts, err := getHostsFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	if len(hosts) == 0 {
+		return ErrNoMachineSpecified
+	}
+
+	errs := []error{}
+	for _, h := range hosts {
+		if err := upgradeEngineVersion(c, h, version); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if err := saveHost(store, h); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return consolidateErrs(errs)
+	}
+
+	return nil
 }
@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewerEngineVersions(t *testing.T) {
+	available := []string{"19.03.15", "20.10.24", "20.10.9", "invalid", "20.10.24", "20.10.10"}
+
+	assert.Equal(t, []string{"20.10.24", "20.10.10"}, newerEngineVersions("20.10.9", available))
+	assert.Equal(t, []string{}, newerEngineVersions("20.10.24", available))
+	assert.Equal(t, []string{}, newerEngineVersions("invalid", available))
+}
+
+func TestCheckEngineDowngrade(t *testing.T) {
+	assert.NoError(t, checkEngineDowngrade("dev", "19.03.15", "20.10.24", false))
+	assert.Error(t, checkEngineDowngrade("dev", "20.10.24", "19.03.15", false))
+	assert.NoError(t, checkEngineDowngrade("dev", "20.10.24", "19.03.15", true))
+	assert.Error(t, checkEngineDowngrade("dev", "unknown", "19.03.15", true))
+}
+
+func TestWriteEngineVersionReports(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	err := writeEngineVersionReports(out, []engineVersionReport{
+		{Name: "dev", Version: "20.10.9", Available: []string{"20.10.24", "20.10.10"}},
+		{Name: "prod", Version: "20.10.24", Pinned: "20.10.24", Available: []string{}},
+		{Name: "b2d", Version: "Unknown"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `NAME   VERSION    PINNED     AVAILABLE
+dev    20.10.9    -          20.10.24, 20.10.10
+prod   20.10.24   20.10.24   -
+b2d    Unknown    -          Unknown
+`, out.String())
+}
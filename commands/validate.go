@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/machine/libmachine/drivers/plugin/localbinary"
+	"github.com/docker/machine/libmachine/host"
+)
+
+// hostRecordProblem describes why a persisted host record can't be used and
+// how to fix it.
+type hostRecordProblem struct {
+	Problem     string
+	Remediation string
+}
+
+// lookupDriverPlugin checks that the plugin binary of a driver is installed.
+var lookupDriverPlugin = func(driverName string) error {
+	_, err := localbinary.NewPlugin(driverName)
+	return err
+}
+
+// validateHostRecord checks that the host record stored in dir can be loaded.
+// Unlike the store, it never migrates or otherwise writes the record.
+func validateHostRecord(dir, name string) []hostRecordProblem {
+	configPath := filepath.Join(dir, "config.json")
+
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		remediation := fmt.Sprintf("Remove the leftover directory with '%s prune'", os.Args[0])
+		if _, err := os.Stat(configPath + ".bak"); err == nil {
+			remediation = fmt.Sprintf("Restore the backup from %s.bak", configPath)
+		}
+		return []hostRecordProblem{{fmt.Sprintf("Unable to read the host record: %s", err), remediation}}
+	}
+
+	h, _, err := host.MigrateHost(&host.Host{Name: name}, data)
+	if err != nil {
+		return []hostRecordProblem{{
+			fmt.Sprintf("Unable to unmarshal the host record: %s", err),
+			fmt.Sprintf("Fix %s by hand or remove the machine with '%s rm -f %s'", configPath, os.Args[0], name),
+		}}
+	}
+
+	problems := []hostRecordProblem{}
+
+	if h.DriverName == "" {
+		problems = append(problems, hostRecordProblem{
+			"The host record has no driver name",
+			fmt.Sprintf("Set \"DriverName\" in %s", configPath),
+		})
+	} else if err := lookupDriverPlugin(h.DriverName); err != nil {
+		problems = append(problems, hostRecordProblem{
+			fmt.Sprintf("Unknown driver %q: %s", h.DriverName, err),
+			fmt.Sprintf("Install docker-machine-driver-%s in your PATH", h.DriverName),
+		})
+	}
+
+	if h.HostOptions == nil || h.HostOptions.AuthOptions == nil {
+		problems = append(problems, hostRecordProblem{
+			"The host record has no TLS settings",
+			fmt.Sprintf("Remove the machine with '%s rm -f %s' and create it again", os.Args[0], name),
+		})
+	}
+
+	if h.HostOptions == nil || h.HostOptions.EngineOptions == nil {
+		problems = append(problems, hostRecordProblem{
+			"The host record has no engine settings",
+			fmt.Sprintf("Remove the machine with '%s rm -f %s' and create it again", os.Args[0], name),
+		})
+	}
+
+	return problems
+}
+
+func cmdValidate(c CommandLine) error {
+	machinesDir := filepath.Join(c.GlobalString("storage-path"), "machines")
+
+	dir, err := ioutil.ReadDir(machinesDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	invalid := []string{}
+	for _, file := range dir {
+		if !file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+
+		problems := validateHostRecord(filepath.Join(machinesDir, file.Name()), file.Name())
+		if len(problems) == 0 {
+			fmt.Printf("%s: OK\n", file.Name())
+			continue
+		}
+
+		invalid = append(invalid, file.Name())
+		for _, p := range problems {
+			fmt.Printf("%s: %s\n", file.Name(), p.Problem)
+			fmt.Printf("%s  %s\n", strings.Repeat(" ", len(file.Name())), p.Remediation)
+		}
+	}
+
+	if len(invalid) > 0 {
+		return fmt.Errorf("Invalid host records: %s", strings.Join(invalid, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/machine/drivers/errdriver"
+	"github.com/docker/machine/libmachine/hosttest"
+	"github.com/docker/machine/libmachine/persist"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestMachinesDir(t *testing.T) (persist.Filestore, string) {
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := persist.Filestore{Path: tmpDir}
+	return store, filepath.Join(tmpDir, "machines")
+}
+
+func withDriverPlugins(found bool, f func()) {
+	defer func(lookup func(string) error) {
+		lookupDriverPlugin = lookup
+	}(lookupDriverPlugin)
+
+	lookupDriverPlugin = func(driverName string) error {
+		if found {
+			return nil
+		}
+		return errdriver.NotLoadable{Name: driverName}
+	}
+
+	f()
+}
+
+func TestValidateHostRecordValid(t *testing.T) {
+	store, machinesDir := getTestMachinesDir(t)
+	defer os.RemoveAll(store.Path)
+
+	h, err := hosttest.GetDefaultTestHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.HostOptions.AuthOptions.StorePath = filepath.Join(machinesDir, h.Name)
+	if err := store.Save(h); err != nil {
+		t.Fatal(err)
+	}
+
+	withDriverPlugins(true, func() {
+		assert.Empty(t, validateHostRecord(filepath.Join(machinesDir, h.Name), h.Name))
+	})
+}
+
+func TestValidateHostRecordUnknownDriver(t *testing.T) {
+	store, machinesDir := getTestMachinesDir(t)
+	defer os.RemoveAll(store.Path)
+
+	h, err := hosttest.GetDefaultTestHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(h); err != nil {
+		t.Fatal(err)
+	}
+
+	withDriverPlugins(false, func() {
+		problems := validateHostRecord(filepath.Join(machinesDir, h.Name), h.Name)
+
+		assert.Equal(t, 1, len(problems))
+		assert.Contains(t, problems[0].Problem, `Unknown driver "none"`)
+		assert.Equal(t, "Install docker-machine-driver-none in your PATH", problems[0].Remediation)
+	})
+}
+
+func TestValidateHostRecordMissingConfig(t *testing.T) {
+	store, machinesDir := getTestMachinesDir(t)
+	defer os.RemoveAll(store.Path)
+
+	dir := filepath.Join(machinesDir, "broken")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	problems := validateHostRecord(dir, "broken")
+
+	assert.Equal(t, 1, len(problems))
+	assert.Contains(t, problems[0].Problem, "Unable to read the host record")
+}
+
+func TestValidateHostRecordUnmarshalError(t *testing.T) {
+	store, machinesDir := getTestMachinesDir(t)
+	defer os.RemoveAll(store.Path)
+
+	dir := filepath.Join(machinesDir, "broken")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"ConfigVersion": 3,`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	problems := validateHostRecord(dir, "broken")
+
+	assert.Equal(t, 1, len(problems))
+	assert.Contains(t, problems[0].Problem, "Unable to unmarshal the host record")
+}
+
+func TestValidateHostRecordMissingFields(t *testing.T) {
+	store, machinesDir := getTestMachinesDir(t)
+	defer os.RemoveAll(store.Path)
+
+	dir := filepath.Join(machinesDir, "broken")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"ConfigVersion": 3, "RawDriver": "e30="}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	withDriverPlugins(true, func() {
+		problems := validateHostRecord(dir, "broken")
+
+		assert.Equal(t, 3, len(problems))
+		assert.Equal(t, "The host record has no driver name", problems[0].Problem)
+		assert.Equal(t, "The host record has no TLS settings", problems[1].Problem)
+		assert.Equal(t, "The host record has no engine settings", problems[2].Problem)
+	})
+}
@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/wireguard"
+)
+
+// wireGuardOptionsFromContext returns the WireGuard options of a machine
+// created with --wireguard, or nil.
+func wireGuardOptionsFromContext(c CommandLine) (*wireguard.Options, error) {
+	if !c.Bool("wireguard") {
+		return nil, nil
+	}
+
+	return wireguard.NewOptions(c.String("wireguard-subnet"), c.Int("wireguard-port"))
+}
+
+// wireGuardMembers returns the machines of the overlay which are created.
+func wireGuardMembers(hosts []*host.Host) []*host.Host {
+	members := []*host.Host{}
+	for _, h := range hosts {
+		if h.UsesWireGuard() && h.HostOptions.WireGuardOptions.Address != "" && h.HostOptions.CreateStep == "" {
+			members = append(members, h)
+		}
+	}
+
+	return members
+}
+
+func cmdWireGuardSync(c CommandLine) error {
+	return libmachine.SyncWireGuardMesh(getStore(c))
+}
+
+// writeWireGuardMembers writes the overlay address and the endpoint of the
+// machines, as recorded when they were provisioned.
+func writeWireGuardMembers(out io.Writer, members []*host.Host) error {
+	w := tabwriter.NewWriter(out, 5, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tADDRESS\tENDPOINT\tPUBLIC KEY")
+
+	for _, h := range members {
+		opts := h.HostOptions.WireGuardOptions
+		endpoint := opts.Endpoint
+		if endpoint == "" {
+			endpoint = "Unknown"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", h.Name, opts.Address, endpoint, opts.PublicKey)
+	}
+
+	return w.Flush()
+}
+
+func cmdWireGuardLs(c CommandLine) error {
+	hosts, err := getStore(c).List()
+	if err != nil {
+		return fmt.Errorf("Error attempting to list hosts from store: %s", err)
+	}
+
+	return writeWireGuardMembers(os.Stdout, wireGuardMembers(hosts))
+}
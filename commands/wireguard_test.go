@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/wireguard"
+	"github.com/stretchr/testify/assert"
+)
+
+func wireGuardHost(name, subnet, address string) *host.Host {
+	return &host.Host{
+		Name: name,
+		HostOptions: &host.Options{
+			WireGuardOptions: &wireguard.Options{
+				Subnet:     subnet,
+				Address:    address,
+				ListenPort: wireguard.DefaultListenPort,
+				Endpoint:   "1.2.3.4:51820",
+				PublicKey:  name + "key",
+			},
+		},
+	}
+}
+
+func TestWireGuardMembers(t *testing.T) {
+	created := wireGuardHost("created", "10.200.0.0/24", "10.200.0.1")
+	creating := wireGuardHost("creating", "10.200.0.0/24", "10.200.0.2")
+	creating.HostOptions.CreateStep = "provision"
+	plain := &host.Host{Name: "plain", HostOptions: &host.Options{}}
+
+	members := wireGuardMembers([]*host.Host{created, creating, plain})
+
+	assert.Equal(t, []*host.Host{created}, members)
+}
+
+func TestWriteWireGuardMembers(t *testing.T) {
+	var out bytes.Buffer
+
+	err := writeWireGuardMembers(&out, []*host.Host{wireGuardHost("first", "10.200.0.0/24", "10.200.0.1")})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "NAME    ADDRESS      ENDPOINT        PUBLIC KEY\nfirst   10.200.0.1   1.2.3.4:51820   firstkey\n", out.String())
+}
@@ -33,11 +33,25 @@ const (
 	defaultSecurityGroup     = machineSecurityGroupName
 	defaultSSHUser           = "ubuntu"
 	defaultSpotPrice         = "0.50"
+
+	// defaultSpotAllocationStrategy requests the spot instances by
+	// RequestSpotInstances. The other strategies request them with a fleet.
+	defaultSpotAllocationStrategy = "lowest-price"
+
+	spotLaunchTemplatePrefix = "docker-machine-"
+	roleSessionPrefix        = "docker-machine-"
 )
 
 var (
 	dockerPort = 2376
 	swarmPort  = 3376
+
+	spotAllocationStrategies = []string{
+		defaultSpotAllocationStrategy,
+		"capacity-optimized",
+		"capacity-optimized-prioritized",
+		"price-capacity-optimized",
+	}
 )
 
 type Driver struct {
@@ -67,6 +81,33 @@ type Driver struct {
 	PrivateIPOnly       bool
 	UsePrivateIP        bool
 	Monitoring          bool
+	UserDataFile        string
+
+	// LaunchTemplate is the id (lt-...) or the name of the launch template
+	// of the instance, and LaunchTemplateVersion its version.
+	LaunchTemplate        string
+	LaunchTemplateVersion string
+
+	// SpotAllocationStrategy picks the instance type of the spot instance
+	// among those of InstanceType, and SpotLaunchTemplateId is the launch
+	// template created to request it with a fleet, kept to request a new
+	// instance when it's interrupted.
+	SpotAllocationStrategy string
+	SpotLaunchTemplateId   string
+
+	// Profile is the AWS CLI profile whose SSO credentials are used, and
+	// RoleArn the role assumed with the credentials, when no access key is
+	// given. The instance profile of the EC2 instance running machine is
+	// used otherwise. The temporary credentials are never stored.
+	Profile     string
+	RoleArn     string
+	credentials amz.Credentials
+
+	// RequireIMDSv2 requires the tokens of IMDSv2 for the metadata service
+	// of the instance, and MetadataHopLimit is the number of network hops
+	// the tokens can go through.
+	RequireIMDSv2    bool
+	MetadataHopLimit int
 }
 
 func (d *Driver) GetCreateFlags() []mcnflag.Flag {
@@ -86,6 +127,16 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Usage:  "AWS Session Token",
 			EnvVar: "AWS_SESSION_TOKEN",
 		},
+		mcnflag.StringFlag{
+			Name:   "amazonec2-profile",
+			Usage:  "AWS CLI profile whose SSO credentials are used when no access key is given",
+			EnvVar: "AWS_PROFILE",
+		},
+		mcnflag.StringFlag{
+			Name:   "amazonec2-role-arn",
+			Usage:  "ARN of an AWS IAM role assumed with the credentials",
+			EnvVar: "AWS_ROLE_ARN",
+		},
 		mcnflag.StringFlag{
 			Name:   "amazonec2-ami",
 			Usage:  "AWS machine image",
@@ -104,8 +155,7 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 		},
 		mcnflag.StringFlag{
 			Name:   "amazonec2-zone",
-			Usage:  "AWS zone for instance (i.e. a,b,c,d,e)",
-			Value:  defaultZone,
+			Usage:  "AWS zone for instance (i.e. a,b,c,d,e), " + defaultZone + " without a launch template",
 			EnvVar: "AWS_ZONE",
 		},
 		mcnflag.StringFlag{
@@ -115,20 +165,26 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 		},
 		mcnflag.StringFlag{
 			Name:   "amazonec2-security-group",
-			Usage:  "AWS VPC security group",
-			Value:  defaultSecurityGroup,
+			Usage:  "AWS VPC security group, " + defaultSecurityGroup + " without a launch template",
 			EnvVar: "AWS_SECURITY_GROUP",
 		},
 		mcnflag.StringFlag{
 			Name:   "amazonec2-instance-type",
-			Usage:  "AWS instance type",
-			Value:  defaultInstanceType,
+			Usage:  "AWS instance type, or a comma-separated list of instance types tried in turn, " + defaultInstanceType + " without a launch template",
 			EnvVar: "AWS_INSTANCE_TYPE",
 		},
+		mcnflag.StringFlag{
+			Name:   "amazonec2-launch-template",
+			Usage:  "AWS launch template of the instance, by id (lt-...) or by name",
+			EnvVar: "AWS_LAUNCH_TEMPLATE",
+		},
+		mcnflag.StringFlag{
+			Name:  "amazonec2-launch-template-version",
+			Usage: "Version of the launch template, its default version if empty",
+		},
 		mcnflag.IntFlag{
 			Name:   "amazonec2-root-size",
-			Usage:  "AWS root disk size (in GB)",
-			Value:  defaultRootSize,
+			Usage:  fmt.Sprintf("AWS root disk size (in GB), %d without a launch template", defaultRootSize),
 			EnvVar: "AWS_ROOT_SIZE",
 		},
 		mcnflag.StringFlag{
@@ -142,6 +198,11 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Value:  defaultSSHUser,
 			EnvVar: "AWS_SSH_USER",
 		},
+		mcnflag.StringFlag{
+			Name:   "amazonec2-ssh-proxy-jump",
+			Usage:  "Bastion to reach the instance through with SSH, [user@]host[:port]",
+			EnvVar: "AWS_SSH_PROXY_JUMP",
+		},
 		mcnflag.BoolFlag{
 			Name:  "amazonec2-request-spot-instance",
 			Usage: "Set this flag to request spot instance",
@@ -151,6 +212,11 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Usage: "AWS spot instance bid price (in dollar)",
 			Value: defaultSpotPrice,
 		},
+		mcnflag.StringFlag{
+			Name:  "amazonec2-spot-allocation-strategy",
+			Usage: "Strategy picking the instance type of the spot instance: lowest-price, capacity-optimized, capacity-optimized-prioritized or price-capacity-optimized",
+			Value: defaultSpotAllocationStrategy,
+		},
 		mcnflag.BoolFlag{
 			Name:  "amazonec2-private-address-only",
 			Usage: "Only use a private IP address",
@@ -163,20 +229,30 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Name:  "amazonec2-monitoring",
 			Usage: "Set this flag to enable CloudWatch monitoring",
 		},
+		mcnflag.BoolFlag{
+			Name:  "amazonec2-require-imdsv2",
+			Usage: "Require the tokens of IMDSv2 for the metadata service of the instance",
+		},
+		mcnflag.IntFlag{
+			Name:  "amazonec2-metadata-hop-limit",
+			Usage: "Number of network hops the tokens of the metadata service can go through, 2 for containers to reach it",
+		},
+		drivers.UserDataFlag,
 	}
 }
 
 func NewDriver(hostName, storePath string) drivers.Driver {
 	id := generateId()
 	return &Driver{
-		Id:                id,
-		AMI:               defaultAmiId,
-		Region:            defaultRegion,
-		InstanceType:      defaultInstanceType,
-		RootSize:          defaultRootSize,
-		Zone:              defaultZone,
-		SecurityGroupName: defaultSecurityGroup,
-		SpotPrice:         defaultSpotPrice,
+		Id:                     id,
+		AMI:                    defaultAmiId,
+		Region:                 defaultRegion,
+		InstanceType:           defaultInstanceType,
+		RootSize:               defaultRootSize,
+		Zone:                   defaultZone,
+		SecurityGroupName:      defaultSecurityGroup,
+		SpotPrice:              defaultSpotPrice,
+		SpotAllocationStrategy: defaultSpotAllocationStrategy,
 		BaseDriver: &drivers.BaseDriver{
 			SSHUser:     defaultSSHUser,
 			MachineName: hostName,
@@ -191,16 +267,15 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 		return err
 	}
 
-	image := flags.String("amazonec2-ami")
-	if len(image) == 0 {
-		image = regionDetails[region].AmiId
-	}
+	launchTemplate := flags.String("amazonec2-launch-template")
 
 	d.AccessKey = flags.String("amazonec2-access-key")
 	d.SecretKey = flags.String("amazonec2-secret-key")
 	d.SessionToken = flags.String("amazonec2-session-token")
+	d.Profile = flags.String("amazonec2-profile")
+	d.RoleArn = flags.String("amazonec2-role-arn")
 	d.Region = region
-	d.AMI = image
+	d.AMI = flags.String("amazonec2-ami")
 	d.RequestSpotInstance = flags.Bool("amazonec2-request-spot-instance")
 	d.SpotPrice = flags.String("amazonec2-spot-price")
 	d.InstanceType = flags.String("amazonec2-instance-type")
@@ -215,20 +290,48 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.SwarmHost = flags.String("swarm-host")
 	d.SwarmDiscovery = flags.String("swarm-discovery")
 	d.SSHUser = flags.String("amazonec2-ssh-user")
+	d.SSHProxyJump = flags.String("amazonec2-ssh-proxy-jump")
 	d.SSHPort = 22
 	d.PrivateIPOnly = flags.Bool("amazonec2-private-address-only")
 	d.UsePrivateIP = flags.Bool("amazonec2-use-private-address")
 	d.Monitoring = flags.Bool("amazonec2-monitoring")
+	d.UserDataFile = flags.String(drivers.UserDataFlag.Name)
+	d.LaunchTemplate = launchTemplate
+	d.LaunchTemplateVersion = flags.String("amazonec2-launch-template-version")
+	d.SpotAllocationStrategy = flags.String("amazonec2-spot-allocation-strategy")
+	d.RequireIMDSv2 = flags.Bool("amazonec2-require-imdsv2")
+	d.MetadataHopLimit = flags.Int("amazonec2-metadata-hop-limit")
+
+	// The values of the launch template are used unless given.
+	if d.LaunchTemplate == "" {
+		d.setDefaults()
+	}
+
+	if d.LaunchTemplate == "" && len(d.instanceTypes()) == 0 {
+		return fmt.Errorf("amazonec2 driver requires the --amazonec2-instance-type option")
+	}
 
-	if d.AccessKey == "" {
-		return fmt.Errorf("amazonec2 driver requires the --amazonec2-access-key option")
+	if !isSpotAllocationStrategy(d.SpotAllocationStrategy) {
+		return fmt.Errorf("Invalid spot allocation strategy %q, it must be one of %s", d.SpotAllocationStrategy, strings.Join(spotAllocationStrategies, ", "))
 	}
 
-	if d.SecretKey == "" {
-		return fmt.Errorf("amazonec2 driver requires the --amazonec2-secret-key option")
+	if d.LaunchTemplate != "" && d.usesSpotFleet() {
+		return fmt.Errorf("The spot instances of several instance types or of the %s allocation strategy are requested with a launch template of their own, --amazonec2-launch-template can't be used with them", d.SpotAllocationStrategy)
 	}
 
-	if d.SubnetId == "" && d.VpcId == "" {
+	if d.AccessKey == "" && d.SecretKey != "" {
+		return fmt.Errorf("amazonec2 driver requires the --amazonec2-access-key option with the --amazonec2-secret-key option")
+	}
+
+	if d.AccessKey != "" && d.SecretKey == "" {
+		return fmt.Errorf("amazonec2 driver requires the --amazonec2-secret-key option with the --amazonec2-access-key option")
+	}
+
+	if d.MetadataHopLimit < 0 || d.MetadataHopLimit > 64 {
+		return fmt.Errorf("Invalid metadata hop limit %d, it must be between 1 and 64", d.MetadataHopLimit)
+	}
+
+	if d.LaunchTemplate == "" && d.SubnetId == "" && d.VpcId == "" {
 		return fmt.Errorf("amazonec2 driver requires either the --amazonec2-subnet-id or --amazonec2-vpc-id option")
 	}
 
@@ -268,6 +371,25 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	return nil
 }
 
+// setDefaults sets the default values of the options which weren't given.
+func (d *Driver) setDefaults() {
+	if d.AMI == "" {
+		d.AMI = regionDetails[d.Region].AmiId
+	}
+	if d.InstanceType == "" {
+		d.InstanceType = defaultInstanceType
+	}
+	if d.RootSize == 0 {
+		d.RootSize = defaultRootSize
+	}
+	if d.Zone == "" {
+		d.Zone = defaultZone
+	}
+	if d.SecurityGroupName == "" {
+		d.SecurityGroupName = defaultSecurityGroup
+	}
+}
+
 // DriverName returns the name of the driver
 func (d *Driver) DriverName() string {
 	return driverName
@@ -285,17 +407,16 @@ func (d *Driver) checkPrereqs() error {
 	}
 
 	regionZone := d.Region + d.Zone
-	if d.SubnetId == "" {
+	if d.SubnetId == "" && d.VpcId != "" {
 		filters := []amz.Filter{
-			{
-				Name:  "availabilityZone",
-				Value: regionZone,
-			},
 			{
 				Name:  "vpc-id",
 				Value: d.VpcId,
 			},
 		}
+		if d.Zone != "" {
+			filters = append(filters, amz.Filter{Name: "availabilityZone", Value: regionZone})
+		}
 
 		subnets, err := d.getClient().GetSubnets(filters)
 		if err != nil {
@@ -344,54 +465,206 @@ func (d *Driver) Create() error {
 		return err
 	}
 
+	userData, err := drivers.ReadUserData(d.UserDataFile)
+	if err != nil {
+		return err
+	}
+
 	log.Infof("Launching instance...")
 
 	if err := d.createKeyPair(); err != nil {
 		return fmt.Errorf("unable to create key pair: %s", err)
 	}
 
-	if err := d.configureSecurityGroup(d.SecurityGroupName); err != nil {
+	if d.SecurityGroupName != "" {
+		if err := d.configureSecurityGroup(d.SecurityGroupName); err != nil {
+			return err
+		}
+	}
+
+	log.Debugf("launching instance in subnet %s", d.SubnetId)
+	instance, err := d.launchInstance(userData)
+	if err != nil {
 		return err
 	}
 
-	bdm := &amz.BlockDeviceMapping{
+	return d.setInstance(instance)
+}
+
+// instanceTypes returns the instance types of the instance, by order of
+// preference.
+func (d *Driver) instanceTypes() []string {
+	instanceTypes := []string{}
+	for _, instanceType := range strings.Split(d.InstanceType, ",") {
+		if instanceType = strings.TrimSpace(instanceType); instanceType != "" {
+			instanceTypes = append(instanceTypes, instanceType)
+		}
+	}
+
+	return instanceTypes
+}
+
+func isSpotAllocationStrategy(strategy string) bool {
+	for _, s := range spotAllocationStrategies {
+		if s == strategy {
+			return true
+		}
+	}
+
+	return false
+}
+
+// usesSpotFleet tells whether the spot instance is requested with a fleet,
+// to pick its instance type among several or with an allocation strategy.
+func (d *Driver) usesSpotFleet() bool {
+	if !d.RequestSpotInstance {
+		return false
+	}
+
+	return len(d.instanceTypes()) > 1 || (d.SpotAllocationStrategy != "" && d.SpotAllocationStrategy != defaultSpotAllocationStrategy)
+}
+
+func (d *Driver) launchTemplate() *amz.LaunchTemplate {
+	if d.LaunchTemplate == "" {
+		return nil
+	}
+
+	template := &amz.LaunchTemplate{
+		Name:    d.LaunchTemplate,
+		Version: d.LaunchTemplateVersion,
+	}
+	if strings.HasPrefix(d.LaunchTemplate, "lt-") {
+		template.Id, template.Name = template.Name, ""
+	}
+
+	return template
+}
+
+// blockDeviceMapping returns the root disk of the instance, nil to leave the
+// one of the launch template.
+func (d *Driver) blockDeviceMapping() *amz.BlockDeviceMapping {
+	if d.RootSize == 0 {
+		return nil
+	}
+
+	return &amz.BlockDeviceMapping{
 		DeviceName:          "/dev/sda1",
 		VolumeSize:          d.RootSize,
 		DeleteOnTermination: true,
 		VolumeType:          "gp2",
 	}
+}
 
-	log.Debugf("launching instance in subnet %s", d.SubnetId)
-	var instance amz.EC2Instance
+// metadataOptions returns the options of the metadata service of the
+// instance, nil to leave those of EC2 or of the launch template.
+func (d *Driver) metadataOptions() *amz.MetadataOptions {
+	if !d.RequireIMDSv2 && d.MetadataHopLimit == 0 {
+		return nil
+	}
+
+	options := &amz.MetadataOptions{HttpPutResponseHopLimit: d.MetadataHopLimit}
+	if d.RequireIMDSv2 {
+		options.HttpTokens = "required"
+	}
+
+	return options
+}
+
+// launchInstance launches the instance, a spot instance if requested.
+func (d *Driver) launchInstance(userData string) (amz.EC2Instance, error) {
+	switch {
+	case d.usesSpotFleet():
+		return d.requestSpotFleet(userData)
+	case d.RequestSpotInstance && d.LaunchTemplate == "" && d.metadataOptions() == nil:
+		return d.requestSpotInstance(userData)
+	}
+
+	return d.runInstance(userData)
+}
+
+// runInstance launches the instance with the first instance type there's
+// capacity for.
+func (d *Driver) runInstance(userData string) (amz.EC2Instance, error) {
+	spotPrice := ""
 	if d.RequestSpotInstance {
-		spotInstanceRequestId, err := d.getClient().RequestSpotInstances(d.AMI, d.InstanceType, d.Zone, 1, d.SecurityGroupId, d.KeyName, d.SubnetId, bdm, d.IamInstanceProfile, d.SpotPrice, d.Monitoring)
-		if err != nil {
-			return fmt.Errorf("Error request spot instance: %s", err)
+		spotPrice = d.SpotPrice
+	}
+
+	// Without instance types, the one of the launch template is used.
+	instanceTypes := d.instanceTypes()
+	if len(instanceTypes) == 0 {
+		instanceTypes = []string{""}
+	}
+
+	var err error
+	for i, instanceType := range instanceTypes {
+		var instance amz.EC2Instance
+		instance, err = d.getClient().RunInstance(d.AMI, instanceType, d.Zone, 1, 1, d.SecurityGroupId, d.KeyName, d.SubnetId, d.blockDeviceMapping(), d.IamInstanceProfile, d.PrivateIPOnly, d.Monitoring, userData, d.launchTemplate(), spotPrice, d.metadataOptions())
+		if err == nil {
+			return instance, nil
 		}
-		var instanceId string
-		var spotInstanceRequestStatus string
-		log.Info("Waiting for spot instance...")
-		// check until fulfilled
-		for instanceId == "" {
-			time.Sleep(time.Second * 5)
-			spotInstanceRequestStatus, instanceId, err = d.getClient().DescribeSpotInstanceRequests(spotInstanceRequestId)
-			if err != nil {
-				return fmt.Errorf("Error describe spot instance request: %s", err)
-			}
-			log.Debugf("spot instance request status: %s", spotInstanceRequestStatus)
+
+		if !amz.HasErrorCode(err, amz.CapacityErrorCodes...) || i == len(instanceTypes)-1 {
+			break
 		}
-		instance, err = d.getClient().GetInstance(instanceId)
+
+		log.Infof("No %s instance available (%s), trying %s...", instanceType, strings.TrimSpace(err.Error()), instanceTypes[i+1])
+	}
+
+	return amz.EC2Instance{}, fmt.Errorf("Error launching instance: %s", err)
+}
+
+func (d *Driver) requestSpotInstance(userData string) (amz.EC2Instance, error) {
+	spotInstanceRequestId, err := d.getClient().RequestSpotInstances(d.AMI, d.instanceTypes()[0], d.Zone, 1, d.SecurityGroupId, d.KeyName, d.SubnetId, d.blockDeviceMapping(), d.IamInstanceProfile, d.SpotPrice, d.Monitoring, userData)
+	if err != nil {
+		return amz.EC2Instance{}, fmt.Errorf("Error request spot instance: %s", err)
+	}
+	var instanceId string
+	var spotInstanceRequestStatus string
+	log.Info("Waiting for spot instance...")
+	// check until fulfilled
+	for instanceId == "" {
+		time.Sleep(time.Second * 5)
+		spotInstanceRequestStatus, instanceId, err = d.getClient().DescribeSpotInstanceRequests(spotInstanceRequestId)
 		if err != nil {
-			return fmt.Errorf("Error get instance: %s", err)
+			return amz.EC2Instance{}, fmt.Errorf("Error describe spot instance request: %s", err)
 		}
-	} else {
-		inst, err := d.getClient().RunInstance(d.AMI, d.InstanceType, d.Zone, 1, 1, d.SecurityGroupId, d.KeyName, d.SubnetId, bdm, d.IamInstanceProfile, d.PrivateIPOnly, d.Monitoring)
+		log.Debugf("spot instance request status: %s", spotInstanceRequestStatus)
+	}
+	instance, err := d.getClient().GetInstance(instanceId)
+	if err != nil {
+		return amz.EC2Instance{}, fmt.Errorf("Error get instance: %s", err)
+	}
+
+	return instance, nil
+}
+
+// requestSpotFleet requests the spot instance with a fleet, from a launch
+// template created the first time.
+func (d *Driver) requestSpotFleet(userData string) (amz.EC2Instance, error) {
+	if d.SpotLaunchTemplateId == "" {
+		id, err := d.getClient().CreateLaunchTemplate(spotLaunchTemplatePrefix+d.MachineName, d.AMI, d.SecurityGroupId, d.KeyName, d.SubnetId, d.blockDeviceMapping(), d.IamInstanceProfile, d.PrivateIPOnly, d.Monitoring, userData, d.metadataOptions())
 		if err != nil {
-			return fmt.Errorf("Error launching instance: %s", err)
+			return amz.EC2Instance{}, fmt.Errorf("Error creating the launch template of the spot instance: %s", err)
 		}
-		instance = inst
+		d.SpotLaunchTemplateId = id
 	}
 
+	log.Infof("Requesting a spot instance among %s (%s)...", strings.Join(d.instanceTypes(), ", "), d.SpotAllocationStrategy)
+
+	template := amz.LaunchTemplate{Id: d.SpotLaunchTemplateId, Version: "$Latest"}
+	instanceId, err := d.getClient().CreateFleet(template, d.instanceTypes(), d.SpotAllocationStrategy, d.SpotPrice)
+	if err != nil {
+		return amz.EC2Instance{}, fmt.Errorf("Error requesting spot instance: %s", err)
+	}
+
+	// The instance may not be described yet, it is once it has its IP.
+	return amz.EC2Instance{InstanceId: instanceId}, nil
+}
+
+// setInstance makes the launched instance the one of the machine, once it's
+// running.
+func (d *Driver) setInstance(instance amz.EC2Instance) error {
 	d.InstanceId = instance.InstanceId
 
 	log.Debug("waiting for ip address to become available")
@@ -399,6 +672,12 @@ func (d *Driver) Create() error {
 		return err
 	}
 
+	if len(instance.NetworkInterfaceSet) == 0 {
+		if inst, err := d.getInstance(); err == nil {
+			instance = *inst
+		}
+	}
+
 	if len(instance.NetworkInterfaceSet) > 0 {
 		d.PrivateIPAddress = instance.NetworkInterfaceSet[0].PrivateIpAddress
 	}
@@ -423,6 +702,69 @@ func (d *Driver) Create() error {
 	return nil
 }
 
+// spotInstanceInterrupted tells whether the spot instance was interrupted by
+// AWS: terminated, or about to be. The terminated instances are forgotten
+// after a while.
+func (d *Driver) spotInstanceInterrupted() (bool, error) {
+	inst, err := d.getInstance()
+	if amz.HasErrorCode(err, amz.ErrorInstanceNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if inst.InstanceState.Name == "terminated" {
+		return true, nil
+	}
+
+	return d.spotInterruptionNotice(inst)
+}
+
+// spotInterruptionNotice tells whether AWS sent the notice that it's going to
+// interrupt the spot instance, two minutes before doing it.
+func (d *Driver) spotInterruptionNotice(inst *amz.EC2Instance) (bool, error) {
+	if inst.SpotInstanceRequestId == "" {
+		return false, nil
+	}
+
+	code, _, err := d.getClient().DescribeSpotInstanceRequests(inst.SpotInstanceRequestId)
+	if err != nil {
+		return false, err
+	}
+
+	return amz.IsSpotInterruption(code), nil
+}
+
+// replaceSpotInstance requests a new spot instance in place of the one which
+// was interrupted, terminating it first if AWS didn't yet. It's a new virtual
+// machine, Docker has to be installed on it again.
+func (d *Driver) replaceSpotInstance() error {
+	log.Infof("The spot instance %s of %s was interrupted, requesting a new one...", d.InstanceId, d.MachineName)
+
+	if err := d.terminate(); err != nil {
+		return err
+	}
+
+	userData, err := drivers.ReadUserData(d.UserDataFile)
+	if err != nil {
+		return err
+	}
+
+	instance, err := d.launchInstance(userData)
+	if err != nil {
+		return err
+	}
+
+	if err := d.setInstance(instance); err != nil {
+		return err
+	}
+
+	log.Warnf("%s runs on the new spot instance %s, run 'docker-machine provision %s' to install Docker on it.", d.MachineName, d.InstanceId, d.MachineName)
+
+	return nil
+}
+
 func (d *Driver) GetURL() (string, error) {
 	ip, err := d.GetIP()
 	if err != nil {
@@ -454,12 +796,25 @@ func (d *Driver) GetIP() (string, error) {
 func (d *Driver) GetState() (state.State, error) {
 	inst, err := d.getInstance()
 	if err != nil {
+		// The interrupted spot instance is started again with a new one.
+		if d.RequestSpotInstance && amz.HasErrorCode(err, amz.ErrorInstanceNotFound) {
+			return state.Stopped, nil
+		}
 		return state.Error, err
 	}
 	switch inst.InstanceState.Name {
 	case "pending":
 		return state.Starting, nil
 	case "running":
+		if d.RequestSpotInstance {
+			notice, err := d.spotInterruptionNotice(inst)
+			if err != nil {
+				log.Debugf("Unable to get the spot instance request of %s: %s", d.MachineName, err)
+			} else if notice {
+				log.Warnf("AWS is interrupting the spot instance %s of %s, run 'docker-machine start %s' to request a new one.", d.InstanceId, d.MachineName, d.MachineName)
+				return state.Stopping, nil
+			}
+		}
 		return state.Running, nil
 	case "stopping":
 		return state.Stopping, nil
@@ -467,6 +822,11 @@ func (d *Driver) GetState() (state.State, error) {
 		return state.Stopping, nil
 	case "stopped":
 		return state.Stopped, nil
+	case "terminated":
+		if d.RequestSpotInstance {
+			return state.Stopped, nil
+		}
+		return state.Error, nil
 	default:
 		return state.Error, nil
 	}
@@ -487,6 +847,17 @@ func (d *Driver) GetSSHUsername() string {
 }
 
 func (d *Driver) Start() error {
+	if d.RequestSpotInstance {
+		interrupted, err := d.spotInstanceInterrupted()
+		if err != nil {
+			return err
+		}
+
+		if interrupted {
+			return d.replaceSpotInstance()
+		}
+	}
+
 	if err := d.getClient().StartInstance(d.InstanceId); err != nil {
 		return err
 	}
@@ -516,6 +887,12 @@ func (d *Driver) Remove() error {
 		return fmt.Errorf("unable to remove key pair: %s", err)
 	}
 
+	if d.SpotLaunchTemplateId != "" {
+		if err := d.getClient().DeleteLaunchTemplate(d.SpotLaunchTemplateId); err != nil {
+			return fmt.Errorf("unable to remove the launch template of the spot instance: %s", err)
+		}
+	}
+
 	return nil
 }
 
@@ -534,8 +911,47 @@ func (d *Driver) Kill() error {
 }
 
 func (d *Driver) getClient() *amz.EC2 {
-	auth := amz.GetAuth(d.AccessKey, d.SecretKey, d.SessionToken)
-	return amz.NewEC2(auth, d.Region)
+	return amz.NewEC2(d.getCredentials(), d.Region)
+}
+
+// getCredentials returns the credentials of the API calls: the access key
+// if given, else the SSO credentials of the profile if given and usable, else
+// those of the instance profile, with which the role is assumed if given. The
+// profile may come from AWS_PROFILE, set for the AWS CLI, and not be an SSO
+// profile.
+func (d *Driver) getCredentials() amz.Credentials {
+	if d.credentials != nil {
+		return d.credentials
+	}
+
+	var credentials amz.Credentials
+	switch {
+	case d.AccessKey != "":
+		credentials = amz.GetAuth(d.AccessKey, d.SecretKey, d.SessionToken)
+	case d.Profile != "":
+		credentials = amz.ChainCredentials{amz.NewSSOCredentials(d.Profile), amz.NewInstanceProfileCredentials()}
+	default:
+		credentials = amz.NewInstanceProfileCredentials()
+	}
+
+	if d.RoleArn != "" {
+		credentials = amz.NewAssumeRoleCredentials(credentials, d.RoleArn, d.roleSessionName(), d.Region)
+	}
+
+	d.credentials = credentials
+
+	return credentials
+}
+
+// roleSessionName names the sessions of the assumed role after the machine,
+// within the 64 characters allowed.
+func (d *Driver) roleSessionName() string {
+	name := roleSessionPrefix + d.MachineName
+	if len(name) > 64 {
+		name = name[:64]
+	}
+
+	return name
 }
 
 func (d *Driver) getInstance() (*amz.EC2Instance, error) {
@@ -596,6 +1012,10 @@ func (d *Driver) terminate() error {
 
 	log.Debugf("terminating instance: %s", d.InstanceId)
 	if err := d.getClient().TerminateInstance(d.InstanceId); err != nil {
+		// The spot instance was interrupted and forgotten already.
+		if amz.HasErrorCode(err, amz.ErrorInstanceNotFound) {
+			return nil
+		}
 		return fmt.Errorf("unable to terminate instance: %s", err)
 	}
 
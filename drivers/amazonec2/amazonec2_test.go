@@ -66,30 +66,39 @@ func getTestStorePath() (string, error) {
 func getDefaultTestDriverFlags() *DriverOptionsMock {
 	return &DriverOptionsMock{
 		Data: map[string]interface{}{
-			"name":                            "test",
-			"url":                             "unix:///var/run/docker.sock",
-			"swarm":                           false,
-			"swarm-host":                      "",
-			"swarm-master":                    false,
-			"swarm-discovery":                 "",
-			"amazonec2-ami":                   "ami-12345",
-			"amazonec2-access-key":            "abcdefg",
-			"amazonec2-secret-key":            "12345",
-			"amazonec2-session-token":         "",
-			"amazonec2-instance-type":         "t1.micro",
-			"amazonec2-vpc-id":                "vpc-12345",
-			"amazonec2-subnet-id":             "subnet-12345",
-			"amazonec2-security-group":        "docker-machine-test",
-			"amazonec2-region":                "us-east-1",
-			"amazonec2-zone":                  "e",
-			"amazonec2-root-size":             10,
-			"amazonec2-iam-instance-profile":  "",
-			"amazonec2-ssh-user":              "ubuntu",
-			"amazonec2-request-spot-instance": false,
-			"amazonec2-spot-price":            "",
-			"amazonec2-private-address-only":  false,
-			"amazonec2-use-private-address":   false,
-			"amazonec2-monitoring":            false,
+			"name":                               "test",
+			"url":                                "unix:///var/run/docker.sock",
+			"swarm":                              false,
+			"swarm-host":                         "",
+			"swarm-master":                       false,
+			"swarm-discovery":                    "",
+			"amazonec2-ami":                      "ami-12345",
+			"amazonec2-access-key":               "abcdefg",
+			"amazonec2-secret-key":               "12345",
+			"amazonec2-session-token":            "",
+			"amazonec2-profile":                  "",
+			"amazonec2-role-arn":                 "",
+			"amazonec2-instance-type":            "t1.micro",
+			"amazonec2-vpc-id":                   "vpc-12345",
+			"amazonec2-subnet-id":                "subnet-12345",
+			"amazonec2-security-group":           "docker-machine-test",
+			"amazonec2-region":                   "us-east-1",
+			"amazonec2-zone":                     "e",
+			"amazonec2-root-size":                10,
+			"amazonec2-iam-instance-profile":     "",
+			"amazonec2-ssh-user":                 "ubuntu",
+			"amazonec2-ssh-proxy-jump":           "",
+			"amazonec2-request-spot-instance":    false,
+			"amazonec2-spot-price":               "",
+			"amazonec2-private-address-only":     false,
+			"amazonec2-use-private-address":      false,
+			"amazonec2-monitoring":               false,
+			"amazonec2-launch-template":          "",
+			"amazonec2-launch-template-version":  "",
+			"amazonec2-spot-allocation-strategy": "lowest-price",
+			"amazonec2-require-imdsv2":           false,
+			"amazonec2-metadata-hop-limit":       0,
+			"user-data":                          "",
 		},
 	}
 }
@@ -256,3 +265,171 @@ func TestSetConfigFromFlags(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Empty(t, checkFlags.InvalidFlags)
 }
+
+func TestInstanceTypes(t *testing.T) {
+	driver := &Driver{InstanceType: "m5.large, m5a.large,,m4.large"}
+	assert.Equal(t, []string{"m5.large", "m5a.large", "m4.large"}, driver.instanceTypes())
+
+	driver.InstanceType = ""
+	assert.Empty(t, driver.instanceTypes())
+}
+
+func TestUsesSpotFleet(t *testing.T) {
+	driver := &Driver{InstanceType: "m5.large", SpotAllocationStrategy: "lowest-price"}
+	assert.False(t, driver.usesSpotFleet())
+
+	driver.RequestSpotInstance = true
+	assert.False(t, driver.usesSpotFleet())
+
+	driver.InstanceType = "m5.large,m5a.large"
+	assert.True(t, driver.usesSpotFleet())
+
+	driver.InstanceType = "m5.large"
+	driver.SpotAllocationStrategy = "capacity-optimized"
+	assert.True(t, driver.usesSpotFleet())
+}
+
+func TestLaunchTemplate(t *testing.T) {
+	driver := &Driver{}
+	assert.Nil(t, driver.launchTemplate())
+
+	driver.LaunchTemplate = "lt-0123456789abcdef0"
+	assert.Equal(t, &amz.LaunchTemplate{Id: "lt-0123456789abcdef0"}, driver.launchTemplate())
+
+	driver.LaunchTemplate = "docker-hosts"
+	driver.LaunchTemplateVersion = "3"
+	assert.Equal(t, &amz.LaunchTemplate{Name: "docker-hosts", Version: "3"}, driver.launchTemplate())
+}
+
+func TestSetConfigFromFlagsInvalidSpotAllocationStrategy(t *testing.T) {
+	flags := getDefaultTestDriverFlags()
+	flags.Data["amazonec2-spot-allocation-strategy"] = "cheapest"
+
+	err := NewDriver(machineTestName, machineTestStorePath).SetConfigFromFlags(flags)
+	assert.EqualError(t, err, `Invalid spot allocation strategy "cheapest", it must be one of lowest-price, capacity-optimized, capacity-optimized-prioritized, price-capacity-optimized`)
+}
+
+func TestSetConfigFromFlagsLaunchTemplateWithSpotFleet(t *testing.T) {
+	flags := getDefaultTestDriverFlags()
+	flags.Data["amazonec2-request-spot-instance"] = true
+	flags.Data["amazonec2-instance-type"] = "m5.large,m5a.large"
+	flags.Data["amazonec2-launch-template"] = "docker-hosts"
+
+	err := NewDriver(machineTestName, machineTestStorePath).SetConfigFromFlags(flags)
+	assert.Error(t, err)
+}
+
+func TestSetConfigFromFlagsLaunchTemplateImage(t *testing.T) {
+	flags := getDefaultTestDriverFlags()
+	flags.Data["amazonec2-ami"] = ""
+	flags.Data["amazonec2-launch-template"] = "docker-hosts"
+	flags.Data["amazonec2-access-key"] = ""
+
+	driver := NewDriver(machineTestName, machineTestStorePath).(*Driver)
+	driver.SetConfigFromFlags(flags)
+
+	assert.Empty(t, driver.AMI)
+	assert.Equal(t, "docker-hosts", driver.LaunchTemplate)
+}
+
+func TestSetConfigFromFlagsLaunchTemplateValues(t *testing.T) {
+	flags := getDefaultTestDriverFlags()
+	flags.Data["amazonec2-launch-template"] = "docker-hosts"
+	flags.Data["amazonec2-instance-type"] = ""
+	flags.Data["amazonec2-root-size"] = 0
+	flags.Data["amazonec2-zone"] = ""
+	flags.Data["amazonec2-security-group"] = ""
+	flags.Data["amazonec2-vpc-id"] = ""
+	flags.Data["amazonec2-subnet-id"] = ""
+
+	driver := NewDriver(machineTestName, machineTestStorePath).(*Driver)
+	err := driver.SetConfigFromFlags(flags)
+
+	assert.NoError(t, err)
+	assert.Empty(t, driver.InstanceType)
+	assert.Empty(t, driver.Zone)
+	assert.Empty(t, driver.SecurityGroupName)
+	assert.Nil(t, driver.blockDeviceMapping())
+}
+
+func TestSetConfigFromFlagsDefaults(t *testing.T) {
+	flags := getDefaultTestDriverFlags()
+	flags.Data["amazonec2-ami"] = ""
+	flags.Data["amazonec2-instance-type"] = ""
+	flags.Data["amazonec2-root-size"] = 0
+	flags.Data["amazonec2-zone"] = ""
+	flags.Data["amazonec2-security-group"] = ""
+	flags.Data["amazonec2-vpc-id"] = ""
+
+	driver := NewDriver(machineTestName, machineTestStorePath).(*Driver)
+	err := driver.SetConfigFromFlags(flags)
+
+	assert.NoError(t, err)
+	assert.Equal(t, regionDetails["us-east-1"].AmiId, driver.AMI)
+	assert.Equal(t, defaultInstanceType, driver.InstanceType)
+	assert.Equal(t, defaultZone, driver.Zone)
+	assert.Equal(t, defaultSecurityGroup, driver.SecurityGroupName)
+	assert.Equal(t, int64(defaultRootSize), driver.blockDeviceMapping().VolumeSize)
+}
+
+func TestSetConfigFromFlagsSecretKeyWithoutAccessKey(t *testing.T) {
+	flags := getDefaultTestDriverFlags()
+	flags.Data["amazonec2-access-key"] = ""
+
+	err := NewDriver(machineTestName, machineTestStorePath).SetConfigFromFlags(flags)
+	assert.EqualError(t, err, "amazonec2 driver requires the --amazonec2-access-key option with the --amazonec2-secret-key option")
+}
+
+func TestSetConfigFromFlagsWithoutKeys(t *testing.T) {
+	flags := getDefaultTestDriverFlags()
+	flags.Data["amazonec2-access-key"] = ""
+	flags.Data["amazonec2-secret-key"] = ""
+	flags.Data["amazonec2-vpc-id"] = ""
+	flags.Data["amazonec2-profile"] = "dev"
+	flags.Data["amazonec2-role-arn"] = "arn:aws:iam::123456789012:role/docker-machine"
+
+	driver := NewDriver(machineTestName, machineTestStorePath).(*Driver)
+	err := driver.SetConfigFromFlags(flags)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "dev", driver.Profile)
+	assert.Equal(t, "arn:aws:iam::123456789012:role/docker-machine", driver.RoleArn)
+}
+
+func TestGetCredentials(t *testing.T) {
+	driver := &Driver{AccessKey: "access", SecretKey: "secret"}
+	assert.Equal(t, amz.Auth{AccessKey: "access", SecretKey: "secret"}, driver.getCredentials())
+
+	driver = &Driver{Profile: "dev"}
+	chain := driver.getCredentials().(amz.ChainCredentials)
+	assert.Len(t, chain, 2)
+	sso := chain[0].(*amz.CachedCredentials).Source.(*amz.SSOCredentials)
+	assert.Equal(t, "dev", sso.Profile)
+	assert.IsType(t, &amz.InstanceProfileCredentials{}, chain[1].(*amz.CachedCredentials).Source)
+
+	driver = &Driver{BaseDriver: &drivers.BaseDriver{MachineName: "dev"}, RoleArn: "arn:aws:iam::123456789012:role/docker-machine", Region: "eu-west-1"}
+	role := driver.getCredentials().(*amz.CachedCredentials).Source.(*amz.AssumeRoleCredentials)
+	assert.Equal(t, "docker-machine-dev", role.SessionName)
+	assert.Equal(t, "eu-west-1", role.Region)
+	assert.IsType(t, &amz.CachedCredentials{}, role.Source)
+	assert.IsType(t, &amz.InstanceProfileCredentials{}, role.Source.(*amz.CachedCredentials).Source)
+}
+
+func TestMetadataOptions(t *testing.T) {
+	driver := &Driver{}
+	assert.Nil(t, driver.metadataOptions())
+
+	driver.RequireIMDSv2 = true
+	assert.Equal(t, &amz.MetadataOptions{HttpTokens: "required"}, driver.metadataOptions())
+
+	driver.MetadataHopLimit = 2
+	assert.Equal(t, &amz.MetadataOptions{HttpTokens: "required", HttpPutResponseHopLimit: 2}, driver.metadataOptions())
+}
+
+func TestSetConfigFromFlagsInvalidMetadataHopLimit(t *testing.T) {
+	flags := getDefaultTestDriverFlags()
+	flags.Data["amazonec2-metadata-hop-limit"] = 65
+
+	err := NewDriver(machineTestName, machineTestStorePath).SetConfigFromFlags(flags)
+	assert.EqualError(t, err, "Invalid metadata hop limit 65, it must be between 1 and 64")
+}
@@ -0,0 +1,15 @@
+package amz
+
+type CreateFleetResponse struct {
+	RequestId string `xml:"requestId"`
+	FleetId   string `xml:"fleetId"`
+	ErrorSet  []struct {
+		ErrorCode    string `xml:"errorCode"`
+		ErrorMessage string `xml:"errorMessage"`
+	} `xml:"errorSet>item"`
+	FleetInstanceSet []struct {
+		InstanceIds  []string `xml:"instanceIds>item"`
+		InstanceType string   `xml:"instanceType"`
+		Lifecycle    string   `xml:"lifecycle"`
+	} `xml:"fleetInstanceSet>item"`
+}
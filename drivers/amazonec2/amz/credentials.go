@@ -0,0 +1,471 @@
+package amz
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	awsauth "github.com/smartystreets/go-aws-auth"
+)
+
+const (
+	// DefaultMetadataEndpoint is the instance metadata service of EC2.
+	DefaultMetadataEndpoint = "http://169.254.169.254"
+
+	// metadataTokenTTL is how long the IMDSv2 tokens are valid, in seconds.
+	metadataTokenTTL = "300"
+
+	// credentialsExpiryWindow is how long before they expire the temporary
+	// credentials are retrieved again.
+	credentialsExpiryWindow = 5 * time.Minute
+
+	stsAPIVersion = "2011-06-15"
+)
+
+// Credentials provide the credentials the API calls are signed with.
+type Credentials interface {
+	Retrieve() (Auth, error)
+}
+
+// Retrieve returns the static credentials.
+func (a Auth) Retrieve() (Auth, error) {
+	return a, nil
+}
+
+// expiringAuth is temporary credentials and their expiry.
+type expiringAuth struct {
+	Auth
+	Expiration time.Time
+}
+
+// temporaryCredentials are retrieved along with their expiry.
+type temporaryCredentials interface {
+	retrieve() (expiringAuth, error)
+}
+
+// CachedCredentials keep the temporary credentials of Source until they
+// expire.
+type CachedCredentials struct {
+	Source temporaryCredentials
+
+	mu   sync.Mutex
+	auth *expiringAuth
+}
+
+func (c *CachedCredentials) Retrieve() (Auth, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.auth != nil && time.Now().Add(credentialsExpiryWindow).Before(c.auth.Expiration) {
+		return c.auth.Auth, nil
+	}
+
+	auth, err := c.Source.retrieve()
+	if err != nil {
+		return Auth{}, err
+	}
+	c.auth = &auth
+
+	return auth.Auth, nil
+}
+
+// ChainCredentials retrieve the credentials from the first of the providers
+// which has them.
+type ChainCredentials []Credentials
+
+func (c ChainCredentials) Retrieve() (Auth, error) {
+	errs := []string{}
+	for _, credentials := range c {
+		auth, err := credentials.Retrieve()
+		if err == nil {
+			return auth, nil
+		}
+		errs = append(errs, err.Error())
+	}
+
+	return Auth{}, fmt.Errorf("No AWS credentials found: %s", strings.Join(errs, ", "))
+}
+
+// InstanceProfileCredentials are the credentials of the instance profile of
+// the EC2 instance running machine, read from the metadata service with an
+// IMDSv2 token.
+type InstanceProfileCredentials struct {
+	Endpoint string
+	Client   *http.Client
+}
+
+// NewInstanceProfileCredentials returns the cached credentials of the
+// instance profile.
+func NewInstanceProfileCredentials() *CachedCredentials {
+	return &CachedCredentials{
+		Source: &InstanceProfileCredentials{
+			Endpoint: DefaultMetadataEndpoint,
+			Client:   &http.Client{Timeout: 2 * time.Second},
+		},
+	}
+}
+
+func (c *InstanceProfileCredentials) metadata(token, path string) ([]byte, error) {
+	req, err := http.NewRequest("GET", c.Endpoint+"/latest/meta-data/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", token)
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s from the metadata service for %s", resp.Status, path)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (c *InstanceProfileCredentials) retrieve() (expiringAuth, error) {
+	req, err := http.NewRequest("PUT", c.Endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return expiringAuth{}, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", metadataTokenTTL)
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return expiringAuth{}, fmt.Errorf("no instance profile: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return expiringAuth{}, fmt.Errorf("no instance profile: %s getting a token from the metadata service", resp.Status)
+	}
+
+	token, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return expiringAuth{}, err
+	}
+
+	roles, err := c.metadata(string(token), "iam/security-credentials/")
+	if err != nil {
+		return expiringAuth{}, fmt.Errorf("no instance profile: %s", err)
+	}
+
+	role := strings.TrimSpace(strings.SplitN(string(roles), "\n", 2)[0])
+	if role == "" {
+		return expiringAuth{}, errors.New("no instance profile attached to the instance")
+	}
+
+	data, err := c.metadata(string(token), "iam/security-credentials/"+role)
+	if err != nil {
+		return expiringAuth{}, fmt.Errorf("error getting the credentials of the instance profile: %s", err)
+	}
+
+	var credentials struct {
+		Code            string
+		AccessKeyId     string
+		SecretAccessKey string
+		Token           string
+		Expiration      time.Time
+	}
+	if err := json.Unmarshal(data, &credentials); err != nil {
+		return expiringAuth{}, fmt.Errorf("error reading the credentials of the instance profile: %s", err)
+	}
+
+	if credentials.Code != "Success" {
+		return expiringAuth{}, fmt.Errorf("error getting the credentials of the instance profile: %s", credentials.Code)
+	}
+
+	return expiringAuth{
+		Auth:       Auth{credentials.AccessKeyId, credentials.SecretAccessKey, credentials.Token},
+		Expiration: credentials.Expiration,
+	}, nil
+}
+
+// SSOCredentials are the credentials of an AWS IAM Identity Center (SSO)
+// profile, from the token cached by aws sso login.
+type SSOCredentials struct {
+	Profile string
+	// ConfigFile is the configuration of the AWS CLI, ~/.aws/config by
+	// default, and CacheDir its SSO cache, ~/.aws/sso/cache by default.
+	ConfigFile string
+	CacheDir   string
+	// Endpoint is the SSO portal, https://portal.sso.REGION.amazonaws.com by
+	// default.
+	Endpoint string
+	Client   *http.Client
+}
+
+// NewSSOCredentials returns the cached credentials of the SSO profile.
+func NewSSOCredentials(profile string) *CachedCredentials {
+	return &CachedCredentials{
+		Source: &SSOCredentials{
+			Profile: profile,
+			Client:  &http.Client{Timeout: 30 * time.Second},
+		},
+	}
+}
+
+// readConfigFile returns the sections of the configuration of the AWS CLI,
+// by name.
+func readConfigFile(path string) (map[string]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sections := map[string]map[string]string{}
+	var section map[string]string
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.Join(strings.Fields(line[1:len(line)-1]), " ")
+			section = map[string]string{}
+			sections[name] = section
+			continue
+		}
+
+		if i := strings.Index(line, "="); i != -1 && section != nil {
+			section[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+		}
+	}
+
+	return sections, scanner.Err()
+}
+
+func homeDir() string {
+	if home := os.Getenv("HOME"); home != "" {
+		return home
+	}
+
+	return os.Getenv("USERPROFILE")
+}
+
+func (c *SSOCredentials) profile() (map[string]string, error) {
+	configFile := c.ConfigFile
+	if configFile == "" {
+		configFile = os.Getenv("AWS_CONFIG_FILE")
+	}
+	if configFile == "" {
+		configFile = filepath.Join(homeDir(), ".aws", "config")
+	}
+
+	sections, err := readConfigFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	name := "profile " + c.Profile
+	if c.Profile == "default" {
+		name = "default"
+	}
+
+	profile, ok := sections[name]
+	if !ok {
+		return nil, fmt.Errorf("no profile %s in %s", c.Profile, configFile)
+	}
+
+	// The SSO settings are in an sso-session section since version 2.9 of
+	// the AWS CLI, and in the profile before.
+	if session := profile["sso_session"]; session != "" {
+		settings, ok := sections["sso-session "+session]
+		if !ok {
+			return nil, fmt.Errorf("no sso-session %s in %s", session, configFile)
+		}
+
+		for k, v := range settings {
+			if _, ok := profile[k]; !ok {
+				profile[k] = v
+			}
+		}
+	}
+
+	for _, k := range []string{"sso_account_id", "sso_role_name", "sso_region", "sso_start_url"} {
+		if profile[k] == "" {
+			return nil, fmt.Errorf("the profile %s isn't an SSO profile, it has no %s", c.Profile, k)
+		}
+	}
+
+	return profile, nil
+}
+
+func (c *SSOCredentials) retrieve() (expiringAuth, error) {
+	profile, err := c.profile()
+	if err != nil {
+		return expiringAuth{}, err
+	}
+
+	cacheKey := profile["sso_session"]
+	if cacheKey == "" {
+		cacheKey = profile["sso_start_url"]
+	}
+	hash := sha1.Sum([]byte(cacheKey))
+
+	cacheDir := c.CacheDir
+	if cacheDir == "" {
+		cacheDir = filepath.Join(homeDir(), ".aws", "sso", "cache")
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(cacheDir, hex.EncodeToString(hash[:])+".json"))
+	if err != nil {
+		return expiringAuth{}, fmt.Errorf("no SSO token for the profile %s, run 'aws sso login --profile %s'", c.Profile, c.Profile)
+	}
+
+	var token struct {
+		AccessToken string    `json:"accessToken"`
+		ExpiresAt   time.Time `json:"expiresAt"`
+	}
+	if err := json.Unmarshal(data, &token); err != nil {
+		return expiringAuth{}, fmt.Errorf("error reading the SSO token of the profile %s: %s", c.Profile, err)
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		return expiringAuth{}, fmt.Errorf("the SSO token of the profile %s expired, run 'aws sso login --profile %s'", c.Profile, c.Profile)
+	}
+
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://portal.sso.%s.amazonaws.com", profile["sso_region"])
+	}
+
+	v := url.Values{}
+	v.Set("account_id", profile["sso_account_id"])
+	v.Set("role_name", profile["sso_role_name"])
+
+	req, err := http.NewRequest("GET", endpoint+"/federation/credentials?"+v.Encode(), nil)
+	if err != nil {
+		return expiringAuth{}, err
+	}
+	req.Header.Set("x-amz-sso_bearer_token", token.AccessToken)
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return expiringAuth{}, fmt.Errorf("error getting the credentials of the SSO profile %s: %s", c.Profile, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return expiringAuth{}, fmt.Errorf("error getting the credentials of the SSO profile %s: %s", c.Profile, resp.Status)
+	}
+
+	var credentials struct {
+		RoleCredentials struct {
+			AccessKeyId     string `json:"accessKeyId"`
+			SecretAccessKey string `json:"secretAccessKey"`
+			SessionToken    string `json:"sessionToken"`
+			Expiration      int64  `json:"expiration"`
+		} `json:"roleCredentials"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&credentials); err != nil {
+		return expiringAuth{}, fmt.Errorf("error reading the credentials of the SSO profile %s: %s", c.Profile, err)
+	}
+
+	role := credentials.RoleCredentials
+	return expiringAuth{
+		Auth:       Auth{role.AccessKeyId, role.SecretAccessKey, role.SessionToken},
+		Expiration: time.Unix(0, role.Expiration*int64(time.Millisecond)),
+	}, nil
+}
+
+// AssumeRoleCredentials are the credentials of a role assumed through STS
+// with the credentials of Source.
+type AssumeRoleCredentials struct {
+	Source      Credentials
+	RoleArn     string
+	SessionName string
+	// Endpoint is STS, https://sts.REGION.amazonaws.com by default.
+	Endpoint string
+	Region   string
+	Client   *http.Client
+}
+
+// NewAssumeRoleCredentials returns the cached credentials of the role.
+func NewAssumeRoleCredentials(source Credentials, roleArn, sessionName, region string) *CachedCredentials {
+	return &CachedCredentials{
+		Source: &AssumeRoleCredentials{
+			Source:      source,
+			RoleArn:     roleArn,
+			SessionName: sessionName,
+			Region:      region,
+			Client:      &http.Client{Timeout: 30 * time.Second},
+		},
+	}
+}
+
+type AssumeRoleResponse struct {
+	Credentials struct {
+		AccessKeyId     string    `xml:"AccessKeyId"`
+		SecretAccessKey string    `xml:"SecretAccessKey"`
+		SessionToken    string    `xml:"SessionToken"`
+		Expiration      time.Time `xml:"Expiration"`
+	} `xml:"AssumeRoleResult>Credentials"`
+}
+
+func (c *AssumeRoleCredentials) retrieve() (expiringAuth, error) {
+	auth, err := c.Source.Retrieve()
+	if err != nil {
+		return expiringAuth{}, err
+	}
+
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://sts.%s.amazonaws.com", c.Region)
+	}
+
+	v := url.Values{}
+	v.Set("Action", "AssumeRole")
+	v.Set("Version", stsAPIVersion)
+	v.Set("RoleArn", c.RoleArn)
+	v.Set("RoleSessionName", c.SessionName)
+
+	req, err := http.NewRequest("GET", endpoint+"/?"+v.Encode(), nil)
+	if err != nil {
+		return expiringAuth{}, err
+	}
+
+	awsauth.Sign4(req, awsauth.Credentials{
+		AccessKeyID:     auth.AccessKey,
+		SecretAccessKey: auth.SecretKey,
+		SecurityToken:   auth.SessionToken,
+	})
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return expiringAuth{}, fmt.Errorf("Error assuming the role %s: %s", c.RoleArn, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return expiringAuth{}, fmt.Errorf("Error assuming the role %s: %s", c.RoleArn, newAwsApiResponseError(*resp))
+	}
+
+	unmarshalledResponse := AssumeRoleResponse{}
+	if err := getDecodedResponse(*resp, &unmarshalledResponse); err != nil {
+		return expiringAuth{}, err
+	}
+
+	credentials := unmarshalledResponse.Credentials
+	return expiringAuth{
+		Auth:       Auth{credentials.AccessKeyId, credentials.SecretAccessKey, credentials.SessionToken},
+		Expiration: credentials.Expiration,
+	}, nil
+}
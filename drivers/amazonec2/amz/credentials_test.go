@@ -0,0 +1,196 @@
+package amz
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeCredentials struct {
+	auth  Auth
+	err   error
+	calls int
+}
+
+func (c *fakeCredentials) retrieve() (expiringAuth, error) {
+	c.calls++
+	return expiringAuth{Auth: c.auth, Expiration: time.Now().Add(time.Hour)}, c.err
+}
+
+func (c *fakeCredentials) Retrieve() (Auth, error) {
+	auth, err := c.retrieve()
+	return auth.Auth, err
+}
+
+func TestCachedCredentials(t *testing.T) {
+	source := &fakeCredentials{auth: Auth{"access", "secret", "token"}}
+	credentials := &CachedCredentials{Source: source}
+
+	for i := 0; i < 2; i++ {
+		auth, err := credentials.Retrieve()
+		assert.NoError(t, err)
+		assert.Equal(t, Auth{"access", "secret", "token"}, auth)
+	}
+	assert.Equal(t, 1, source.calls)
+
+	credentials.auth.Expiration = time.Now().Add(time.Minute)
+	credentials.Retrieve()
+	assert.Equal(t, 2, source.calls)
+}
+
+func TestChainCredentials(t *testing.T) {
+	credentials := ChainCredentials{
+		&fakeCredentials{err: errors.New("no SSO token")},
+		&fakeCredentials{auth: Auth{"access", "secret", ""}},
+	}
+
+	auth, err := credentials.Retrieve()
+	assert.NoError(t, err)
+	assert.Equal(t, "access", auth.AccessKey)
+
+	_, err = ChainCredentials{&fakeCredentials{err: errors.New("no SSO token")}}.Retrieve()
+	assert.EqualError(t, err, "No AWS credentials found: no SSO token")
+}
+
+func TestInstanceProfileCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" && r.URL.Path == "/latest/api/token" {
+			assert.Equal(t, metadataTokenTTL, r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds"))
+			fmt.Fprint(w, "imds-token")
+			return
+		}
+
+		if r.Header.Get("X-aws-ec2-metadata-token") != "imds-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/latest/meta-data/iam/security-credentials/":
+			fmt.Fprint(w, "docker-machine\n")
+		case "/latest/meta-data/iam/security-credentials/docker-machine":
+			fmt.Fprint(w, `{"Code":"Success","AccessKeyId":"access","SecretAccessKey":"secret","Token":"token","Expiration":"2030-01-02T15:04:05Z"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	credentials := &InstanceProfileCredentials{Endpoint: server.URL, Client: http.DefaultClient}
+	auth, err := credentials.retrieve()
+
+	assert.NoError(t, err)
+	assert.Equal(t, Auth{"access", "secret", "token"}, auth.Auth)
+	assert.Equal(t, time.Date(2030, 1, 2, 15, 4, 5, 0, time.UTC), auth.Expiration)
+}
+
+func TestInstanceProfileCredentialsWithoutProfile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			fmt.Fprint(w, "imds-token")
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	credentials := &InstanceProfileCredentials{Endpoint: server.URL, Client: http.DefaultClient}
+	_, err := credentials.retrieve()
+
+	assert.Error(t, err)
+}
+
+func TestSSOCredentials(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/federation/credentials", r.URL.Path)
+		assert.Equal(t, "123456789012", r.URL.Query().Get("account_id"))
+		assert.Equal(t, "Developer", r.URL.Query().Get("role_name"))
+		assert.Equal(t, "sso-token", r.Header.Get("x-amz-sso_bearer_token"))
+		fmt.Fprint(w, `{"roleCredentials":{"accessKeyId":"access","secretAccessKey":"secret","sessionToken":"token","expiration":1893596645000}}`)
+	}))
+	defer server.Close()
+
+	configFile := filepath.Join(tmpDir, "config")
+	config := `[profile dev]
+sso_session = corp
+sso_account_id = 123456789012
+sso_role_name = Developer
+
+[sso-session corp]
+sso_start_url = https://corp.awsapps.com/start
+sso_region = eu-west-1
+`
+	if err := ioutil.WriteFile(configFile, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	hash := sha1.Sum([]byte("corp"))
+	token := fmt.Sprintf(`{"accessToken":"sso-token","expiresAt":%q}`, time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	if err := ioutil.WriteFile(filepath.Join(tmpDir, hex.EncodeToString(hash[:])+".json"), []byte(token), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	credentials := &SSOCredentials{
+		Profile:    "dev",
+		ConfigFile: configFile,
+		CacheDir:   tmpDir,
+		Endpoint:   server.URL,
+		Client:     http.DefaultClient,
+	}
+	auth, err := credentials.retrieve()
+
+	assert.NoError(t, err)
+	assert.Equal(t, Auth{"access", "secret", "token"}, auth.Auth)
+	assert.Equal(t, int64(1893596645), auth.Expiration.Unix())
+
+	credentials.Profile = "prod"
+	_, err = credentials.retrieve()
+	assert.EqualError(t, err, fmt.Sprintf("no profile prod in %s", configFile))
+}
+
+func TestAssumeRoleCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "AssumeRole", r.URL.Query().Get("Action"))
+		assert.Equal(t, "arn:aws:iam::123456789012:role/docker-machine", r.URL.Query().Get("RoleArn"))
+		assert.Equal(t, "docker-machine-dev", r.URL.Query().Get("RoleSessionName"))
+		assert.True(t, strings.Contains(r.Header.Get("Authorization"), "Credential=source/"))
+		fmt.Fprint(w, `<AssumeRoleResponse><AssumeRoleResult><Credentials>
+    <AccessKeyId>access</AccessKeyId>
+    <SecretAccessKey>secret</SecretAccessKey>
+    <SessionToken>token</SessionToken>
+    <Expiration>2030-01-02T15:04:05Z</Expiration>
+</Credentials></AssumeRoleResult></AssumeRoleResponse>`)
+	}))
+	defer server.Close()
+
+	credentials := &AssumeRoleCredentials{
+		Source:      Auth{AccessKey: "source", SecretKey: "secret"},
+		RoleArn:     "arn:aws:iam::123456789012:role/docker-machine",
+		SessionName: "docker-machine-dev",
+		Endpoint:    server.URL,
+		Region:      "us-east-1",
+		Client:      http.DefaultClient,
+	}
+	auth, err := credentials.retrieve()
+
+	assert.NoError(t, err)
+	assert.Equal(t, Auth{"access", "secret", "token"}, auth.Auth)
+	assert.Equal(t, time.Date(2030, 1, 2, 15, 4, 5, 0, time.UTC), auth.Expiration)
+}
@@ -14,11 +14,15 @@ import (
 	awsauth "github.com/smartystreets/go-aws-auth"
 )
 
+// apiVersion is the version of the EC2 API called, the first with launch
+// templates and fleets.
+const apiVersion = "2016-11-15"
+
 type (
 	EC2 struct {
-		Endpoint string
-		Auth     Auth
-		Region   string
+		Endpoint    string
+		Credentials Credentials
+		Region      string
 	}
 
 	Instance struct {
@@ -96,7 +100,8 @@ type (
 				Primary          bool   `xml:"primary"`
 			} `xml:"privateIpAddressesSet>item"`
 		} `xml:"networkInterfaceSet>item"`
-		EbsOptimized bool `xml:"ebsOptimized"`
+		EbsOptimized          bool   `xml:"ebsOptimized"`
+		SpotInstanceRequestId string `xml:"spotInstanceRequestId"`
 	}
 
 	RunInstancesResponse struct {
@@ -122,7 +127,7 @@ func newAwsApiResponseError(r http.Response) error {
 	}
 	msg := ""
 	for _, e := range errorResponse.Errors {
-		msg += fmt.Sprintf("%s\n", e.Message)
+		msg += fmt.Sprintf("%s: %s\n", e.Code, e.Message)
 	}
 	return fmt.Errorf("Non-200 API response: code=%d message=%s", r.StatusCode, msg)
 }
@@ -139,17 +144,17 @@ func getDecodedResponse(r http.Response, into interface{}) error {
 	return nil
 }
 
-func NewEC2(auth Auth, region string) *EC2 {
+func NewEC2(credentials Credentials, region string) *EC2 {
 	endpoint := fmt.Sprintf("https://ec2.%s.amazonaws.com", region)
 	return &EC2{
-		Endpoint: endpoint,
-		Auth:     auth,
-		Region:   region,
+		Endpoint:    endpoint,
+		Credentials: credentials,
+		Region:      region,
 	}
 }
 
 func (e *EC2) awsApiCall(v url.Values) (*http.Response, error) {
-	v.Set("Version", "2014-06-15")
+	v.Set("Version", apiVersion)
 	log.Debug("Making AWS API call with values:")
 	mcnutils.DumpVal(v)
 	client := &http.Client{}
@@ -160,10 +165,16 @@ func (e *EC2) awsApiCall(v url.Values) (*http.Response, error) {
 	}
 	req.Header.Add("Content-type", "application/json")
 
+	auth, err := e.Credentials.Retrieve()
+	if err != nil {
+		// Some callers close the body before checking the error.
+		return &http.Response{Body: http.NoBody}, err
+	}
+
 	awsauth.Sign4(req, awsauth.Credentials{
-		AccessKeyID:     e.Auth.AccessKey,
-		SecretAccessKey: e.Auth.SecretKey,
-		SecurityToken:   e.Auth.SessionToken,
+		AccessKeyID:     auth.AccessKey,
+		SecretAccessKey: auth.SecretKey,
+		SecurityToken:   auth.SessionToken,
 	})
 	resp, err := client.Do(req)
 	if err != nil {
@@ -177,27 +188,46 @@ func (e *EC2) awsApiCall(v url.Values) (*http.Response, error) {
 	return resp, nil
 }
 
-func (e *EC2) RunInstance(amiId string, instanceType string, zone string, minCount int, maxCount int, securityGroup string, keyName string, subnetId string, bdm *BlockDeviceMapping, role string, privateIPOnly bool, monitoring bool) (EC2Instance, error) {
+// RunInstance launches an instance, from the launch template when given,
+// the other arguments overriding its values. With a launch template, the
+// zone, the instance type, the security group, the subnet and the block
+// device mapping are only set when given. The instance is a spot instance
+// when spotPrice is given.
+func (e *EC2) RunInstance(amiId string, instanceType string, zone string, minCount int, maxCount int, securityGroup string, keyName string, subnetId string, bdm *BlockDeviceMapping, role string, privateIPOnly bool, monitoring bool, userData string, template *LaunchTemplate, spotPrice string, metadata *MetadataOptions) (EC2Instance, error) {
 	instance := Instance{}
 	v := url.Values{}
 	v.Set("Action", "RunInstances")
-	v.Set("ImageId", amiId)
-	v.Set("Placement.AvailabilityZone", e.Region+zone)
+	if len(amiId) > 0 {
+		v.Set("ImageId", amiId)
+	}
+	if template == nil || len(zone) > 0 {
+		v.Set("Placement.AvailabilityZone", e.Region+zone)
+	}
 	v.Set("MinCount", strconv.Itoa(minCount))
 	v.Set("MaxCount", strconv.Itoa(maxCount))
 	v.Set("KeyName", keyName)
-	v.Set("InstanceType", instanceType)
-	v.Set("NetworkInterface.0.DeviceIndex", "0")
-	v.Set("NetworkInterface.0.SecurityGroupId.0", securityGroup)
-	v.Set("NetworkInterface.0.SubnetId", subnetId)
-	if privateIPOnly {
-		v.Set("NetworkInterface.0.AssociatePublicIpAddress", "0")
-	} else {
-		v.Set("NetworkInterface.0.AssociatePublicIpAddress", "1")
+	if template == nil || len(instanceType) > 0 {
+		v.Set("InstanceType", instanceType)
+	}
+	// The network interface replaces the one of the launch template, it's
+	// only set when there's something to override.
+	if template == nil || len(securityGroup) > 0 || len(subnetId) > 0 || privateIPOnly {
+		v.Set("NetworkInterface.0.DeviceIndex", "0")
+		if template == nil || len(securityGroup) > 0 {
+			v.Set("NetworkInterface.0.SecurityGroupId.0", securityGroup)
+		}
+		if template == nil || len(subnetId) > 0 {
+			v.Set("NetworkInterface.0.SubnetId", subnetId)
+		}
+		if privateIPOnly {
+			v.Set("NetworkInterface.0.AssociatePublicIpAddress", "0")
+		} else {
+			v.Set("NetworkInterface.0.AssociatePublicIpAddress", "1")
+		}
 	}
 	if monitoring {
 		v.Set("Monitoring.Enabled", "1")
-	} else {
+	} else if template == nil {
 		v.Set("Monitoring.Enabled", "0")
 	}
 
@@ -205,6 +235,10 @@ func (e *EC2) RunInstance(amiId string, instanceType string, zone string, minCou
 		v.Set("IamInstanceProfile.Name", role)
 	}
 
+	if len(userData) > 0 {
+		v.Set("UserData", base64.StdEncoding.EncodeToString([]byte(userData)))
+	}
+
 	if bdm != nil {
 		v.Set("BlockDeviceMapping.0.DeviceName", bdm.DeviceName)
 		v.Set("BlockDeviceMapping.0.VirtualName", bdm.VirtualName)
@@ -217,6 +251,19 @@ func (e *EC2) RunInstance(amiId string, instanceType string, zone string, minCou
 		v.Set("BlockDeviceMapping.0.Ebs.DeleteOnTermination", strconv.Itoa(deleteOnTerm))
 	}
 
+	if template != nil {
+		template.set(v, "LaunchTemplate.")
+	}
+
+	if len(spotPrice) > 0 {
+		v.Set("InstanceMarketOptions.MarketType", "spot")
+		v.Set("InstanceMarketOptions.SpotOptions.MaxPrice", spotPrice)
+	}
+
+	if metadata != nil {
+		metadata.set(v, "MetadataOptions.")
+	}
+
 	resp, err := e.awsApiCall(v)
 
 	if err != nil {
@@ -238,7 +285,7 @@ func (e *EC2) RunInstance(amiId string, instanceType string, zone string, minCou
 	return instance.info, nil
 }
 
-func (e *EC2) RequestSpotInstances(amiId string, instanceType string, zone string, instanceCount int, securityGroup string, keyName string, subnetId string, bdm *BlockDeviceMapping, role string, spotPrice string, monitoring bool) (string, error) {
+func (e *EC2) RequestSpotInstances(amiId string, instanceType string, zone string, instanceCount int, securityGroup string, keyName string, subnetId string, bdm *BlockDeviceMapping, role string, spotPrice string, monitoring bool, userData string) (string, error) {
 	v := url.Values{}
 	v.Set("Action", "RequestSpotInstances")
 	v.Set("LaunchSpecification.ImageId", amiId)
@@ -261,6 +308,10 @@ func (e *EC2) RequestSpotInstances(amiId string, instanceType string, zone strin
 		v.Set("LaunchSpecification.IamInstanceProfile.Name", role)
 	}
 
+	if len(userData) > 0 {
+		v.Set("LaunchSpecification.UserData", base64.StdEncoding.EncodeToString([]byte(userData)))
+	}
+
 	if bdm != nil {
 		v.Set("LaunchSpecification.BlockDeviceMapping.0.DeviceName", bdm.DeviceName)
 		v.Set("LaunchSpecification.BlockDeviceMapping.0.VirtualName", bdm.VirtualName)
@@ -318,6 +369,112 @@ func (e *EC2) DescribeSpotInstanceRequests(spotInstanceRequestId string) (string
 	return "fulfilled", unmarshalledResponse.SpotInstanceRequestSet[0].InstanceId, nil
 }
 
+// CreateLaunchTemplate creates the launch template of the instances
+// requested by CreateFleet, returning its id.
+func (e *EC2) CreateLaunchTemplate(name string, amiId string, securityGroup string, keyName string, subnetId string, bdm *BlockDeviceMapping, role string, privateIPOnly bool, monitoring bool, userData string, metadata *MetadataOptions) (string, error) {
+	v := url.Values{}
+	v.Set("Action", "CreateLaunchTemplate")
+	v.Set("LaunchTemplateName", name)
+	v.Set("LaunchTemplateData.ImageId", amiId)
+	v.Set("LaunchTemplateData.KeyName", keyName)
+	v.Set("LaunchTemplateData.NetworkInterface.1.DeviceIndex", "0")
+	v.Set("LaunchTemplateData.NetworkInterface.1.SecurityGroupId.1", securityGroup)
+	v.Set("LaunchTemplateData.NetworkInterface.1.SubnetId", subnetId)
+	v.Set("LaunchTemplateData.NetworkInterface.1.DeleteOnTermination", "true")
+	v.Set("LaunchTemplateData.NetworkInterface.1.AssociatePublicIpAddress", strconv.FormatBool(!privateIPOnly))
+	v.Set("LaunchTemplateData.Monitoring.Enabled", strconv.FormatBool(monitoring))
+
+	if len(role) > 0 {
+		v.Set("LaunchTemplateData.IamInstanceProfile.Name", role)
+	}
+
+	if len(userData) > 0 {
+		v.Set("LaunchTemplateData.UserData", base64.StdEncoding.EncodeToString([]byte(userData)))
+	}
+
+	if bdm != nil {
+		v.Set("LaunchTemplateData.BlockDeviceMapping.1.DeviceName", bdm.DeviceName)
+		v.Set("LaunchTemplateData.BlockDeviceMapping.1.Ebs.VolumeSize", strconv.FormatInt(bdm.VolumeSize, 10))
+		v.Set("LaunchTemplateData.BlockDeviceMapping.1.Ebs.VolumeType", bdm.VolumeType)
+		v.Set("LaunchTemplateData.BlockDeviceMapping.1.Ebs.DeleteOnTermination", strconv.FormatBool(bdm.DeleteOnTermination))
+	}
+
+	if metadata != nil {
+		metadata.set(v, "LaunchTemplateData.MetadataOptions.")
+	}
+
+	resp, err := e.awsApiCall(v)
+	if err != nil {
+		return "", newAwsApiCallError(err)
+	}
+
+	unmarshalledResponse := CreateLaunchTemplateResponse{}
+	if err := getDecodedResponse(*resp, &unmarshalledResponse); err != nil {
+		return "", err
+	}
+
+	return unmarshalledResponse.LaunchTemplate.LaunchTemplateId, nil
+}
+
+func (e *EC2) DeleteLaunchTemplate(id string) error {
+	v := url.Values{}
+	v.Set("Action", "DeleteLaunchTemplate")
+	v.Set("LaunchTemplateId", id)
+
+	resp, err := e.awsApiCall(v)
+	if err != nil {
+		return newAwsApiCallError(err)
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// CreateFleet requests a spot instance of one of the instance types from the
+// launch template, picked by the allocation strategy, and returns its id.
+// The instance types are given by order of priority.
+func (e *EC2) CreateFleet(template LaunchTemplate, instanceTypes []string, allocationStrategy string, spotPrice string) (string, error) {
+	v := url.Values{}
+	v.Set("Action", "CreateFleet")
+	v.Set("Type", "instant")
+	v.Set("TargetCapacitySpecification.TotalTargetCapacity", "1")
+	v.Set("TargetCapacitySpecification.DefaultTargetCapacityType", "spot")
+	v.Set("SpotOptions.AllocationStrategy", allocationStrategy)
+	template.set(v, "LaunchTemplateConfigs.1.LaunchTemplateSpecification.")
+
+	for i, instanceType := range instanceTypes {
+		prefix := fmt.Sprintf("LaunchTemplateConfigs.1.Overrides.%d.", i+1)
+		v.Set(prefix+"InstanceType", instanceType)
+		v.Set(prefix+"Priority", strconv.Itoa(i))
+		if len(spotPrice) > 0 {
+			v.Set(prefix+"MaxPrice", spotPrice)
+		}
+	}
+
+	resp, err := e.awsApiCall(v)
+	if err != nil {
+		return "", newAwsApiCallError(err)
+	}
+
+	unmarshalledResponse := CreateFleetResponse{}
+	if err := getDecodedResponse(*resp, &unmarshalledResponse); err != nil {
+		return "", err
+	}
+
+	for _, instances := range unmarshalledResponse.FleetInstanceSet {
+		if len(instances.InstanceIds) > 0 {
+			return instances.InstanceIds[0], nil
+		}
+	}
+
+	msg := ""
+	for _, e := range unmarshalledResponse.ErrorSet {
+		msg += fmt.Sprintf("%s: %s\n", e.ErrorCode, e.ErrorMessage)
+	}
+
+	return "", fmt.Errorf("No spot instance could be launched: %s", msg)
+}
+
 func (e *EC2) DeleteKeyPair(name string) error {
 	v := url.Values{}
 	v.Set("Action", "DeleteKeyPair")
@@ -395,7 +552,6 @@ func (e *EC2) CreateTags(id string, tags map[string]string) error {
 	}
 
 	resp, err := e.awsApiCall(v)
-	defer resp.Body.Close()
 	if err != nil {
 		return err
 	}
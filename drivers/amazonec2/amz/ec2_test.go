@@ -0,0 +1,166 @@
+package amz
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testEC2 returns an EC2 client calling a server which answers with
+// response, and the parameters of the calls it got.
+func testEC2(status int, response string) (*EC2, *[]url.Values, func()) {
+	calls := []url.Values{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Query())
+		w.WriteHeader(status)
+		fmt.Fprint(w, response)
+	}))
+
+	e := NewEC2(GetAuth("access", "secret", ""), "us-east-1")
+	e.Endpoint = server.URL
+
+	return e, &calls, server.Close
+}
+
+func TestHasErrorCode(t *testing.T) {
+	err := errors.New("Problem with AWS API call: Non-200 API response: code=500 message=InsufficientInstanceCapacity: no capacity\n")
+
+	assert.True(t, HasErrorCode(err, CapacityErrorCodes...))
+	assert.False(t, HasErrorCode(err, ErrorInstanceNotFound))
+	assert.False(t, HasErrorCode(nil, ErrorInstanceNotFound))
+}
+
+func TestIsSpotInterruption(t *testing.T) {
+	assert.True(t, IsSpotInterruption("marked-for-termination"))
+	assert.True(t, IsSpotInterruption("instance-terminated-by-price"))
+	assert.False(t, IsSpotInterruption("fulfilled"))
+	assert.False(t, IsSpotInterruption("instance-terminated-by-user"))
+}
+
+func TestRunInstanceWithLaunchTemplateAndSpotPrice(t *testing.T) {
+	e, calls, close := testEC2(http.StatusOK, `<RunInstancesResponse><instancesSet><item><instanceId>i-1234</instanceId></item></instancesSet></RunInstancesResponse>`)
+	defer close()
+
+	instance, err := e.RunInstance("", "m5.large", "a", 1, 1, "sg-1", "key", "subnet-1", nil, "", false, false, "", &LaunchTemplate{Id: "lt-1", Version: "2"}, "0.10", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "i-1234", instance.InstanceId)
+
+	params := (*calls)[0]
+	assert.Equal(t, apiVersion, params.Get("Version"))
+	assert.Empty(t, params.Get("ImageId"))
+	assert.Equal(t, "lt-1", params.Get("LaunchTemplate.LaunchTemplateId"))
+	assert.Equal(t, "2", params.Get("LaunchTemplate.Version"))
+	assert.Equal(t, "spot", params.Get("InstanceMarketOptions.MarketType"))
+	assert.Equal(t, "0.10", params.Get("InstanceMarketOptions.SpotOptions.MaxPrice"))
+}
+
+func TestRunInstanceLeavesLaunchTemplateValues(t *testing.T) {
+	e, calls, close := testEC2(http.StatusOK, `<RunInstancesResponse><instancesSet><item><instanceId>i-1234</instanceId></item></instancesSet></RunInstancesResponse>`)
+	defer close()
+
+	_, err := e.RunInstance("", "", "", 1, 1, "", "key", "", nil, "", false, false, "", &LaunchTemplate{Name: "docker-hosts"}, "", nil)
+
+	assert.NoError(t, err)
+
+	params := (*calls)[0]
+	assert.Equal(t, "docker-hosts", params.Get("LaunchTemplate.LaunchTemplateName"))
+	for _, name := range []string{"Placement.AvailabilityZone", "InstanceType", "NetworkInterface.0.DeviceIndex", "NetworkInterface.0.SecurityGroupId.0", "NetworkInterface.0.SubnetId", "BlockDeviceMapping.0.DeviceName", "Monitoring.Enabled"} {
+		_, ok := params[name]
+		assert.False(t, ok, name)
+	}
+}
+
+func TestRunInstanceCapacityError(t *testing.T) {
+	e, _, close := testEC2(http.StatusInternalServerError, `<Response><Errors><Error><Code>InsufficientInstanceCapacity</Code><Message>no capacity</Message></Error></Errors></Response>`)
+	defer close()
+
+	_, err := e.RunInstance("ami-1", "m5.large", "a", 1, 1, "sg-1", "key", "subnet-1", nil, "", false, false, "", nil, "", nil)
+
+	assert.True(t, HasErrorCode(err, CapacityErrorCodes...))
+}
+
+func TestCreateFleet(t *testing.T) {
+	e, calls, close := testEC2(http.StatusOK, `<CreateFleetResponse>
+    <fleetId>fleet-1</fleetId>
+    <errorSet>
+        <item><errorCode>InsufficientInstanceCapacity</errorCode><errorMessage>no capacity</errorMessage></item>
+    </errorSet>
+    <fleetInstanceSet>
+        <item><instanceIds><item>i-1234</item></instanceIds><instanceType>m5a.large</instanceType><lifecycle>spot</lifecycle></item>
+    </fleetInstanceSet>
+</CreateFleetResponse>`)
+	defer close()
+
+	instanceId, err := e.CreateFleet(LaunchTemplate{Id: "lt-1", Version: "$Latest"}, []string{"m5.large", "m5a.large"}, "capacity-optimized-prioritized", "0.10")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "i-1234", instanceId)
+
+	params := (*calls)[0]
+	assert.Equal(t, "instant", params.Get("Type"))
+	assert.Equal(t, "capacity-optimized-prioritized", params.Get("SpotOptions.AllocationStrategy"))
+	assert.Equal(t, "lt-1", params.Get("LaunchTemplateConfigs.1.LaunchTemplateSpecification.LaunchTemplateId"))
+	assert.Equal(t, "m5.large", params.Get("LaunchTemplateConfigs.1.Overrides.1.InstanceType"))
+	assert.Equal(t, "0", params.Get("LaunchTemplateConfigs.1.Overrides.1.Priority"))
+	assert.Equal(t, "m5a.large", params.Get("LaunchTemplateConfigs.1.Overrides.2.InstanceType"))
+	assert.Equal(t, "0.10", params.Get("LaunchTemplateConfigs.1.Overrides.2.MaxPrice"))
+}
+
+func TestCreateFleetWithoutInstance(t *testing.T) {
+	e, _, close := testEC2(http.StatusOK, `<CreateFleetResponse>
+    <errorSet>
+        <item><errorCode>InsufficientInstanceCapacity</errorCode><errorMessage>no capacity</errorMessage></item>
+    </errorSet>
+</CreateFleetResponse>`)
+	defer close()
+
+	_, err := e.CreateFleet(LaunchTemplate{Id: "lt-1"}, []string{"m5.large"}, "capacity-optimized", "")
+
+	assert.True(t, HasErrorCode(err, CapacityErrorCodes...))
+}
+
+func TestRunInstanceRequiringIMDSv2(t *testing.T) {
+	e, calls, close := testEC2(http.StatusOK, `<RunInstancesResponse><instancesSet><item><instanceId>i-1234</instanceId></item></instancesSet></RunInstancesResponse>`)
+	defer close()
+
+	_, err := e.RunInstance("ami-1", "m5.large", "a", 1, 1, "sg-1", "key", "subnet-1", nil, "", false, false, "", nil, "", &MetadataOptions{HttpTokens: "required", HttpPutResponseHopLimit: 2})
+
+	assert.NoError(t, err)
+
+	params := (*calls)[0]
+	assert.Equal(t, "enabled", params.Get("MetadataOptions.HttpEndpoint"))
+	assert.Equal(t, "required", params.Get("MetadataOptions.HttpTokens"))
+	assert.Equal(t, "2", params.Get("MetadataOptions.HttpPutResponseHopLimit"))
+}
+
+func TestCreateLaunchTemplateRequiringIMDSv2(t *testing.T) {
+	e, calls, close := testEC2(http.StatusOK, `<CreateLaunchTemplateResponse><launchTemplate><launchTemplateId>lt-1</launchTemplateId></launchTemplate></CreateLaunchTemplateResponse>`)
+	defer close()
+
+	id, err := e.CreateLaunchTemplate("docker-machine-dev", "ami-1", "sg-1", "key", "subnet-1", nil, "", false, false, "", &MetadataOptions{HttpTokens: "required"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "lt-1", id)
+
+	params := (*calls)[0]
+	assert.Equal(t, "required", params.Get("LaunchTemplateData.MetadataOptions.HttpTokens"))
+	assert.Empty(t, params.Get("LaunchTemplateData.MetadataOptions.HttpPutResponseHopLimit"))
+}
+
+func TestAwsApiCallWithoutCredentials(t *testing.T) {
+	e, calls, close := testEC2(http.StatusOK, "")
+	defer close()
+
+	e.Credentials = ChainCredentials{}
+
+	err := e.CreateTags("i-1234", map[string]string{"Name": "dev"})
+
+	assert.Error(t, err)
+	assert.Empty(t, *calls)
+}
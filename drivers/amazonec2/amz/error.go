@@ -1,5 +1,7 @@
 package amz
 
+import "strings"
+
 type ErrorResponse struct {
 	Errors []struct {
 		Code    string
@@ -7,3 +9,19 @@ type ErrorResponse struct {
 	} `xml:"Errors>Error"`
 	RequestID string
 }
+
+// HasErrorCode tells whether the error of an API call is one of the errors
+// codes.
+func HasErrorCode(err error, codes ...string) bool {
+	if err == nil {
+		return false
+	}
+
+	for _, code := range codes {
+		if strings.Contains(err.Error(), code+": ") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,5 +1,41 @@
 package amz
 
 const (
-	ErrorDuplicateGroup = "InvalidGroup.Duplicate"
+	ErrorDuplicateGroup   = "InvalidGroup.Duplicate"
+	ErrorInstanceNotFound = "InvalidInstanceID.NotFound"
 )
+
+// CapacityErrorCodes are the errors of the calls launching instances when
+// there's no capacity left for the instance type, in the zone or for the
+// account, or when the spot price is too low.
+var CapacityErrorCodes = []string{
+	"InsufficientInstanceCapacity",
+	"InsufficientCapacity",
+	"InstanceLimitExceeded",
+	"Unsupported",
+	"SpotMaxPriceTooLow",
+}
+
+// SpotInterruptionCodes are the status codes of the spot instance requests
+// whose instance AWS interrupts: the notice it sends two minutes before, and
+// the reasons of the termination.
+var SpotInterruptionCodes = []string{
+	"marked-for-termination",
+	"marked-for-stop",
+	"instance-terminated-by-price",
+	"instance-terminated-no-capacity",
+	"instance-terminated-capacity-oversubscribed",
+	"instance-terminated-launch-group-constraint",
+}
+
+// IsSpotInterruption tells whether the status code of a spot instance
+// request is one of SpotInterruptionCodes.
+func IsSpotInterruption(code string) bool {
+	for _, c := range SpotInterruptionCodes {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
+}
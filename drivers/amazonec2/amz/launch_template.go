@@ -0,0 +1,32 @@
+package amz
+
+import "net/url"
+
+// LaunchTemplate selects a launch template by its id or its name, and its
+// version, the default one if empty.
+type LaunchTemplate struct {
+	Id      string
+	Name    string
+	Version string
+}
+
+// set sets the parameters of the launch template under prefix.
+func (t *LaunchTemplate) set(v url.Values, prefix string) {
+	if len(t.Id) > 0 {
+		v.Set(prefix+"LaunchTemplateId", t.Id)
+	} else {
+		v.Set(prefix+"LaunchTemplateName", t.Name)
+	}
+
+	if len(t.Version) > 0 {
+		v.Set(prefix+"Version", t.Version)
+	}
+}
+
+type CreateLaunchTemplateResponse struct {
+	RequestId      string `xml:"requestId"`
+	LaunchTemplate struct {
+		LaunchTemplateId   string `xml:"launchTemplateId"`
+		LaunchTemplateName string `xml:"launchTemplateName"`
+	} `xml:"launchTemplate"`
+}
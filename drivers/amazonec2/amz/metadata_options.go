@@ -0,0 +1,28 @@
+package amz
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// MetadataOptions are the options of the metadata service of an instance.
+// HttpTokens is required for the instance to only accept IMDSv2 calls, made
+// with a token, and HttpPutResponseHopLimit is the number of network hops
+// the token can go through, the default of EC2 if zero.
+type MetadataOptions struct {
+	HttpTokens              string
+	HttpPutResponseHopLimit int
+}
+
+// set sets the parameters of the metadata options under prefix.
+func (o *MetadataOptions) set(v url.Values, prefix string) {
+	v.Set(prefix+"HttpEndpoint", "enabled")
+
+	if len(o.HttpTokens) > 0 {
+		v.Set(prefix+"HttpTokens", o.HttpTokens)
+	}
+
+	if o.HttpPutResponseHopLimit > 0 {
+		v.Set(prefix+"HttpPutResponseHopLimit", strconv.Itoa(o.HttpPutResponseHopLimit))
+	}
+}
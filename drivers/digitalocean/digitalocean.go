@@ -27,6 +27,7 @@ type Driver struct {
 	IPv6              bool
 	Backups           bool
 	PrivateNetworking bool
+	UserDataFile      string
 }
 
 const (
@@ -83,6 +84,7 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Name:   "digitalocean-backups",
 			Usage:  "enable backups for droplet",
 		},
+		drivers.UserDataFlag,
 	}
 }
 
@@ -115,6 +117,7 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.IPv6 = flags.Bool("digitalocean-ipv6")
 	d.PrivateNetworking = flags.Bool("digitalocean-private-networking")
 	d.Backups = flags.Bool("digitalocean-backups")
+	d.UserDataFile = flags.String(drivers.UserDataFlag.Name)
 	d.SwarmMaster = flags.Bool("swarm-master")
 	d.SwarmHost = flags.String("swarm-host")
 	d.SwarmDiscovery = flags.String("swarm-discovery")
@@ -144,6 +147,11 @@ func (d *Driver) PreCreateCheck() error {
 }
 
 func (d *Driver) Create() error {
+	userData, err := drivers.ReadUserData(d.UserDataFile)
+	if err != nil {
+		return err
+	}
+
 	log.Infof("Creating SSH key...")
 
 	key, err := d.createSSHKey()
@@ -166,6 +174,7 @@ func (d *Driver) Create() error {
 		PrivateNetworking: d.PrivateNetworking,
 		Backups:           d.Backups,
 		SSHKeys:           []godo.DropletCreateSSHKey{{ID: d.SSHKeyID}},
+		UserData:          userData,
 	}
 
 	newDroplet, _, err := client.Droplets.Create(createRequest)
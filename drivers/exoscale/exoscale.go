@@ -30,6 +30,7 @@ type Driver struct {
 	KeyPair          string
 	PublicKey        string
 	ID               string `json:"Id"`
+	UserDataFile     string
 }
 
 const (
@@ -88,6 +89,7 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Value:  defaultAvailabilityZone,
 			Usage:  "exoscale availibility zone",
 		},
+		drivers.UserDataFlag,
 	}
 }
 
@@ -130,6 +132,7 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	}
 	d.SecurityGroup = strings.Join(securityGroups, ",")
 	d.AvailabilityZone = flags.String("exoscale-availability-zone")
+	d.UserDataFile = flags.String(drivers.UserDataFlag.Name)
 	d.SwarmMaster = flags.Bool("swarm-master")
 	d.SwarmHost = flags.String("swarm-host")
 	d.SwarmDiscovery = flags.String("swarm-discovery")
@@ -223,6 +226,15 @@ func (d *Driver) createDefaultSecurityGroup(client *egoscale.Client, group strin
 }
 
 func (d *Driver) Create() error {
+	cloudInit, err := d.getCloudInit()
+	if err != nil {
+		return err
+	}
+	userdata, err := drivers.ReadUserData(d.UserDataFile, cloudInit)
+	if err != nil {
+		return err
+	}
+
 	log.Infof("Querying exoscale for the requested parameters...")
 	client := egoscale.NewClient(d.URL, d.APIKey, d.APISecretKey)
 	topology, err := client.GetTopology()
@@ -289,10 +301,6 @@ func (d *Driver) Create() error {
 
 	log.Infof("Spawn exoscale host...")
 
-	userdata, err := d.getCloudInit()
-	if err != nil {
-		return err
-	}
 	log.Debugf("Using the following cloud-init file:")
 	log.Debugf("%s", userdata)
 
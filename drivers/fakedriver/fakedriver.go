@@ -1,6 +1,8 @@
 package fakedriver
 
 import (
+	"fmt"
+
 	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/mcnflag"
 	"github.com/docker/machine/libmachine/state"
@@ -11,6 +13,27 @@ type Driver struct {
 	MockState state.State
 	MockURL   string
 	MockName  string
+
+	// MockCapabilities overrides the default capabilities if not nil.
+	MockCapabilities []drivers.Capability
+
+	// MockSnapshots are the snapshots of the machine, taken and restored
+	// by name.
+	MockSnapshots []drivers.Snapshot
+
+	// MockPortForwards are the port forwarding rules of the machine.
+	MockPortForwards []drivers.PortForward
+
+	// MockSharedFolders are the folders of the host shared with the machine.
+	MockSharedFolders []drivers.SharedFolder
+}
+
+func (d *Driver) Capabilities() []drivers.Capability {
+	if d.MockCapabilities != nil {
+		return d.MockCapabilities
+	}
+
+	return drivers.DefaultCapabilities
 }
 
 func (d *Driver) GetCreateFlags() []mcnflag.Flag {
@@ -81,9 +104,80 @@ func (d *Driver) Restart() error {
 }
 
 func (d *Driver) Kill() error {
+	d.MockState = state.Stopped
 	return nil
 }
 
 func (d *Driver) Upgrade() error {
 	return nil
 }
+
+func (d *Driver) CreateSnapshot(name string) error {
+	for i := range d.MockSnapshots {
+		d.MockSnapshots[i].Current = false
+	}
+	d.MockSnapshots = append(d.MockSnapshots, drivers.Snapshot{Name: name, ID: name, Current: true})
+	return nil
+}
+
+func (d *Driver) ListSnapshots() ([]drivers.Snapshot, error) {
+	return d.MockSnapshots, nil
+}
+
+func (d *Driver) RestoreSnapshot(name string) error {
+	if d.MockState == state.Running {
+		return fmt.Errorf("%s must be stopped to restore snapshot %s", d.MockName, name)
+	}
+
+	found := false
+	for i := range d.MockSnapshots {
+		d.MockSnapshots[i].Current = d.MockSnapshots[i].Name == name
+		found = found || d.MockSnapshots[i].Current
+	}
+	if !found {
+		return fmt.Errorf("snapshot %s not found", name)
+	}
+
+	return nil
+}
+
+func (d *Driver) DeleteSnapshot(name string) error {
+	for i, snapshot := range d.MockSnapshots {
+		if snapshot.Name == name {
+			d.MockSnapshots = append(d.MockSnapshots[:i], d.MockSnapshots[i+1:]...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("snapshot %s not found", name)
+}
+
+func (d *Driver) AddPortForward(rule drivers.PortForward) error {
+	for _, existing := range d.MockPortForwards {
+		if existing.Name == rule.Name {
+			return fmt.Errorf("port forwarding rule %s already exists", rule.Name)
+		}
+	}
+
+	d.MockPortForwards = append(d.MockPortForwards, rule)
+	return nil
+}
+
+func (d *Driver) ListPortForwards() ([]drivers.PortForward, error) {
+	return d.MockPortForwards, nil
+}
+
+func (d *Driver) RemovePortForward(name string) error {
+	for i, rule := range d.MockPortForwards {
+		if rule.Name == name {
+			d.MockPortForwards = append(d.MockPortForwards[:i], d.MockPortForwards[i+1:]...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("port forwarding rule %s not found", name)
+}
+
+func (d *Driver) SharedFolders() ([]drivers.SharedFolder, error) {
+	return d.MockSharedFolders, nil
+}
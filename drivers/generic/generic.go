@@ -13,6 +13,7 @@ import (
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/mcnflag"
 	"github.com/docker/machine/libmachine/mcnutils"
+	"github.com/docker/machine/libmachine/ssh"
 	"github.com/docker/machine/libmachine/state"
 )
 
@@ -44,7 +45,7 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 		},
 		mcnflag.StringFlag{
 			Name:  "generic-ssh-key",
-			Usage: "SSH private key path",
+			Usage: "SSH private key path, empty to use the keys of the SSH agent",
 			Value: defaultSourceSSHKey,
 		},
 		mcnflag.IntFlag{
@@ -89,23 +90,29 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 		return errors.New("generic driver requires the --generic-ip-address option")
 	}
 
-	if d.SSHKey == "" {
-		return errors.New("generic driver requires the --generic-ssh-key option")
+	if d.SSHKey == "" && ssh.AgentSocket() == "" {
+		return errors.New("generic driver requires the --generic-ssh-key option, or an SSH agent")
 	}
 
 	return nil
 }
 
 func (d *Driver) Create() error {
-	log.Info("Importing SSH key...")
+	if d.SSHKey == "" {
+		// The key never leaves the agent, e.g. when it's on a hardware
+		// token.
+		log.Info("Using the keys of the SSH agent...")
+	} else {
+		log.Info("Importing SSH key...")
 
-	// TODO: validate the key is a valid key
-	if err := mcnutils.CopyFile(d.SSHKey, d.GetSSHKeyPath()); err != nil {
-		return fmt.Errorf("unable to copy ssh key: %s", err)
-	}
+		// TODO: validate the key is a valid key
+		if err := mcnutils.CopyFile(d.SSHKey, d.GetSSHKeyPath()); err != nil {
+			return fmt.Errorf("unable to copy ssh key: %s", err)
+		}
 
-	if err := os.Chmod(d.GetSSHKeyPath(), 0600); err != nil {
-		return fmt.Errorf("unable to set permissions on the ssh key: %s", err)
+		if err := os.Chmod(d.GetSSHKeyPath(), 0600); err != nil {
+			return fmt.Errorf("unable to set permissions on the ssh key: %s", err)
+		}
 	}
 
 	log.Debugf("IP: %s", d.IPAddress)
@@ -134,6 +141,12 @@ func (d *Driver) GetState() (state.State, error) {
 	return st, nil
 }
 
+// Capabilities only includes kill, which shuts the host down over SSH. There
+// is no way to start it again.
+func (d *Driver) Capabilities() []drivers.Capability {
+	return []drivers.Capability{drivers.CapabilityKill}
+}
+
 func (d *Driver) Start() error {
 	return errors.New("generic driver does not support start")
 }
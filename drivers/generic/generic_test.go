@@ -1,6 +1,7 @@
 package generic
 
 import (
+	"os"
 	"testing"
 
 	"github.com/docker/machine/libmachine/drivers"
@@ -23,3 +24,25 @@ func TestSetConfigFromFlags(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Empty(t, checkFlags.InvalidFlags)
 }
+
+func TestSetConfigFromFlagsWithoutKey(t *testing.T) {
+	driver := NewDriver("default", "path")
+
+	checkFlags := &drivers.CheckDriverOptions{
+		FlagsValues: map[string]interface{}{
+			"generic-ip-address": "localhost",
+			"generic-ssh-key":    "",
+		},
+		CreateFlags: driver.GetCreateFlags(),
+	}
+
+	defer os.Setenv("SSH_AUTH_SOCK", os.Getenv("SSH_AUTH_SOCK"))
+
+	os.Setenv("SSH_AUTH_SOCK", "/tmp/agent.sock")
+	err := driver.SetConfigFromFlags(checkFlags)
+	assert.NoError(t, err)
+
+	os.Unsetenv("SSH_AUTH_SOCK")
+	err = driver.SetConfigFromFlags(checkFlags)
+	assert.Error(t, err)
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/ssh"
 	raw "google.golang.org/api/compute/v1"
@@ -177,6 +178,11 @@ func (c *ComputeUtil) instance() (*raw.Instance, error) {
 
 // createInstance creates a GCE VM instance.
 func (c *ComputeUtil) createInstance(d *Driver) error {
+	userData, err := drivers.ReadUserData(d.UserDataFile)
+	if err != nil {
+		return err
+	}
+
 	log.Infof("Creating instance.")
 	// The rule will either exist or be nil in case of an error.
 	if rule, _ := c.firewallRule(); rule == nil {
@@ -219,6 +225,18 @@ func (c *ComputeUtil) createInstance(d *Driver) error {
 		},
 	}
 
+	// cloud-init reads the user data from the metadata of the instance.
+	var userDataItems []*raw.MetadataItems
+	if userData != "" {
+		userDataItems = []*raw.MetadataItems{
+			{
+				Key:   "user-data",
+				Value: &userData,
+			},
+		}
+		instance.Metadata = &raw.Metadata{Items: userDataItems}
+	}
+
 	if c.address != "" {
 		staticAddress, err := c.staticAddress()
 		if err != nil {
@@ -266,12 +284,12 @@ func (c *ComputeUtil) createInstance(d *Driver) error {
 	metaDataValue := c.userName + ":" + string(sshKey) + "\n"
 	op, err = c.service.Instances.SetMetadata(c.project, c.zone, c.instanceName, &raw.Metadata{
 		Fingerprint: instance.Metadata.Fingerprint,
-		Items: []*raw.MetadataItems{
+		Items: append([]*raw.MetadataItems{
 			{
 				Key:   "sshKeys",
 				Value: &metaDataValue,
 			},
-		},
+		}, userDataItems...),
 	}).Do()
 	if err != nil {
 		return err
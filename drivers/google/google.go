@@ -26,6 +26,7 @@ type Driver struct {
 	DiskSize      int
 	Project       string
 	Tags          string
+	UserDataFile  string
 }
 
 const (
@@ -110,6 +111,7 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Usage:  "Use internal GCE Instance IP rather than public one",
 			EnvVar: "GOOGLE_USE_INTERNAL_IP",
 		},
+		drivers.UserDataFlag,
 	}
 }
 
@@ -165,6 +167,7 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.UseInternalIP = flags.Bool("google-use-internal-ip")
 	d.Scopes = flags.String("google-scopes")
 	d.Tags = flags.String("google-tags")
+	d.UserDataFile = flags.String(drivers.UserDataFlag.Name)
 	d.SwarmMaster = flags.Bool("swarm-master")
 	d.SwarmHost = flags.String("swarm-host")
 	d.SwarmDiscovery = flags.String("swarm-discovery")
@@ -0,0 +1,251 @@
+package hetzner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultEndpoint = "https://api.hetzner.cloud/v1"
+
+	// apiTimeout bounds each request to the API, so that an unresponsive
+	// API doesn't hang the commands.
+	apiTimeout = 30 * time.Second
+)
+
+// Client talks to the Hetzner Cloud API with a project token.
+type Client struct {
+	Token      string
+	Endpoint   string
+	HTTPClient *http.Client
+}
+
+// APIError is an error answered by the API.
+type APIError struct {
+	StatusCode int
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("Hetzner Cloud API error %d (%s): %s", e.StatusCode, e.Code, e.Message)
+}
+
+// isNotFound reports whether err is the API answering that a resource
+// doesn't exist.
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*APIError)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+type SSHKey struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	PublicKey string `json:"public_key"`
+}
+
+type Server struct {
+	ID         int                `json:"id"`
+	Name       string             `json:"name"`
+	Status     string             `json:"status"`
+	PublicNet  ServerPublicNet    `json:"public_net"`
+	PrivateNet []ServerPrivateNet `json:"private_net"`
+}
+
+type ServerPublicNet struct {
+	IPv4 struct {
+		IP string `json:"ip"`
+	} `json:"ipv4"`
+}
+
+type ServerPrivateNet struct {
+	Network int    `json:"network"`
+	IP      string `json:"ip"`
+}
+
+type ServerCreateRequest struct {
+	Name             string            `json:"name"`
+	ServerType       string            `json:"server_type"`
+	Image            string            `json:"image"`
+	Location         string            `json:"location,omitempty"`
+	SSHKeys          []int             `json:"ssh_keys"`
+	Networks         []int             `json:"networks,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	UserData         string            `json:"user_data,omitempty"`
+	StartAfterCreate bool              `json:"start_after_create"`
+}
+
+type Network struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	IPRange string `json:"ip_range"`
+}
+
+// namedResource is the part of server types and locations the driver checks.
+type namedResource struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func NewClient(token, endpoint string) *Client {
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+
+	return &Client{
+		Token:      token,
+		Endpoint:   endpoint,
+		HTTPClient: &http.Client{Timeout: apiTimeout},
+	}
+}
+
+// do sends a request to the API, decoding the answer into out unless it is
+// nil.
+func (c *Client) do(method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		bodyJSON, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(bodyJSON)
+	}
+
+	req, err := http.NewRequest(method, c.Endpoint+path, reader)
+	if err != nil {
+		return fmt.Errorf("Error with request: %v - %q", path, err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var answer struct {
+			Error APIError `json:"error"`
+		}
+		json.Unmarshal(data, &answer)
+		answer.Error.StatusCode = resp.StatusCode
+		return &answer.Error
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(data, out)
+}
+
+func (c *Client) CreateSSHKey(name, publicKey string) (*SSHKey, error) {
+	var answer struct {
+		SSHKey SSHKey `json:"ssh_key"`
+	}
+
+	request := SSHKey{Name: name, PublicKey: publicKey}
+	if err := c.do("POST", "/ssh_keys", request, &answer); err != nil {
+		return nil, err
+	}
+
+	return &answer.SSHKey, nil
+}
+
+func (c *Client) DeleteSSHKey(id int) error {
+	return c.do("DELETE", "/ssh_keys/"+strconv.Itoa(id), nil, nil)
+}
+
+func (c *Client) CreateServer(request ServerCreateRequest) (*Server, error) {
+	var answer struct {
+		Server Server `json:"server"`
+	}
+
+	if err := c.do("POST", "/servers", request, &answer); err != nil {
+		return nil, err
+	}
+
+	return &answer.Server, nil
+}
+
+func (c *Client) GetServer(id int) (*Server, error) {
+	var answer struct {
+		Server Server `json:"server"`
+	}
+
+	if err := c.do("GET", "/servers/"+strconv.Itoa(id), nil, &answer); err != nil {
+		return nil, err
+	}
+
+	return &answer.Server, nil
+}
+
+func (c *Client) DeleteServer(id int) error {
+	return c.do("DELETE", "/servers/"+strconv.Itoa(id), nil, nil)
+}
+
+// ServerAction runs an action, e.g. poweron or shutdown, on a server.
+func (c *Client) ServerAction(id int, action string) error {
+	return c.do("POST", fmt.Sprintf("/servers/%d/actions/%s", id, action), nil, nil)
+}
+
+// GetNetwork returns the network whose ID or name is idOrName, or nil if
+// there is none.
+func (c *Client) GetNetwork(idOrName string) (*Network, error) {
+	if id, err := strconv.Atoi(idOrName); err == nil {
+		var answer struct {
+			Network Network `json:"network"`
+		}
+
+		if err := c.do("GET", "/networks/"+strconv.Itoa(id), nil, &answer); err != nil {
+			if isNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+
+		return &answer.Network, nil
+	}
+
+	var answer struct {
+		Networks []Network `json:"networks"`
+	}
+
+	if err := c.do("GET", "/networks?name="+url.QueryEscape(idOrName), nil, &answer); err != nil {
+		return nil, err
+	}
+
+	if len(answer.Networks) == 0 {
+		return nil, nil
+	}
+
+	return &answer.Networks[0], nil
+}
+
+// exists reports whether a resource of the collection, e.g. server_types or
+// locations, is named name.
+func (c *Client) exists(collection, name string) (bool, error) {
+	answer := map[string][]namedResource{}
+
+	if err := c.do("GET", "/"+collection+"?name="+url.QueryEscape(name), nil, &answer); err != nil {
+		return false, err
+	}
+
+	return len(answer[collection]) > 0, nil
+}
@@ -0,0 +1,361 @@
+package hetzner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/docker/machine/libmachine/mcnutils"
+	"github.com/docker/machine/libmachine/ssh"
+	"github.com/docker/machine/libmachine/state"
+)
+
+type Driver struct {
+	*drivers.BaseDriver
+	APIToken          string
+	APIEndpoint       string
+	ServerID          int
+	ServerType        string
+	Image             string
+	Location          string
+	SSHKeyID          int
+	Networks          []string
+	NetworkIDs        []int
+	UsePrivateNetwork bool
+	Labels            map[string]string
+	UserDataFile      string
+}
+
+const (
+	defaultServerType = "cx22"
+	defaultImage      = "ubuntu-22.04"
+	defaultLocation   = "fsn1"
+)
+
+// GetCreateFlags registers the flags this driver adds to
+// "docker hosts create"
+func (d *Driver) GetCreateFlags() []mcnflag.Flag {
+	return []mcnflag.Flag{
+		mcnflag.StringFlag{
+			EnvVar: "HETZNER_API_TOKEN",
+			Name:   "hetzner-api-token",
+			Usage:  "Hetzner Cloud API token of the project",
+		},
+		mcnflag.StringFlag{
+			EnvVar: "HETZNER_API_ENDPOINT",
+			Name:   "hetzner-api-endpoint",
+			Usage:  "Hetzner Cloud API endpoint",
+			Value:  defaultEndpoint,
+		},
+		mcnflag.StringFlag{
+			EnvVar: "HETZNER_SERVER_TYPE",
+			Name:   "hetzner-server-type",
+			Usage:  "Hetzner Cloud server type",
+			Value:  defaultServerType,
+		},
+		mcnflag.StringFlag{
+			EnvVar: "HETZNER_IMAGE",
+			Name:   "hetzner-image",
+			Usage:  "Hetzner Cloud image",
+			Value:  defaultImage,
+		},
+		mcnflag.StringFlag{
+			EnvVar: "HETZNER_LOCATION",
+			Name:   "hetzner-location",
+			Usage:  "Hetzner Cloud location",
+			Value:  defaultLocation,
+		},
+		mcnflag.StringSliceFlag{
+			EnvVar: "HETZNER_NETWORK",
+			Name:   "hetzner-network",
+			Usage:  "ID or name of a private network to attach the server to",
+		},
+		mcnflag.BoolFlag{
+			EnvVar: "HETZNER_USE_PRIVATE_NETWORK",
+			Name:   "hetzner-use-private-network",
+			Usage:  "Reach the server through its address on the first private network",
+		},
+		mcnflag.StringSliceFlag{
+			EnvVar: "HETZNER_LABEL",
+			Name:   "hetzner-label",
+			Usage:  "Label of the server, key=value",
+		},
+		drivers.UserDataFlag,
+	}
+}
+
+func NewDriver(hostName, storePath string) *Driver {
+	return &Driver{
+		APIEndpoint: defaultEndpoint,
+		ServerType:  defaultServerType,
+		Image:       defaultImage,
+		Location:    defaultLocation,
+		BaseDriver: &drivers.BaseDriver{
+			MachineName: hostName,
+			StorePath:   storePath,
+		},
+	}
+}
+
+func (d *Driver) GetSSHHostname() (string, error) {
+	return d.GetIP()
+}
+
+// DriverName returns the name of the driver
+func (d *Driver) DriverName() string {
+	return "hetzner"
+}
+
+func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
+	d.APIToken = flags.String("hetzner-api-token")
+	d.APIEndpoint = flags.String("hetzner-api-endpoint")
+	d.ServerType = flags.String("hetzner-server-type")
+	d.Image = flags.String("hetzner-image")
+	d.Location = flags.String("hetzner-location")
+	d.Networks = flags.StringSlice("hetzner-network")
+	d.UsePrivateNetwork = flags.Bool("hetzner-use-private-network")
+	d.UserDataFile = flags.String(drivers.UserDataFlag.Name)
+	d.SwarmMaster = flags.Bool("swarm-master")
+	d.SwarmHost = flags.String("swarm-host")
+	d.SwarmDiscovery = flags.String("swarm-discovery")
+	d.SSHUser = "root"
+	d.SSHPort = 22
+
+	if d.APIToken == "" {
+		return fmt.Errorf("hetzner driver requires the --hetzner-api-token option")
+	}
+
+	if d.UsePrivateNetwork && len(d.Networks) == 0 {
+		return fmt.Errorf("--hetzner-use-private-network requires a --hetzner-network")
+	}
+
+	labels, err := parseLabels(flags.StringSlice("hetzner-label"))
+	if err != nil {
+		return err
+	}
+	d.Labels = labels
+
+	return nil
+}
+
+// parseLabels parses the key=value labels of the server.
+func parseLabels(specs []string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("Invalid label %q, expected key=value", spec)
+		}
+		labels[parts[0]] = parts[1]
+	}
+
+	return labels, nil
+}
+
+func (d *Driver) getClient() *Client {
+	return NewClient(d.APIToken, d.APIEndpoint)
+}
+
+// PreCreateCheck checks the server type and the location exist, and resolves
+// the networks to attach the server to.
+func (d *Driver) PreCreateCheck() error {
+	client := d.getClient()
+
+	for _, check := range []struct{ collection, name, flag string }{
+		{"server_types", d.ServerType, "--hetzner-server-type"},
+		{"locations", d.Location, "--hetzner-location"},
+	} {
+		exists, err := client.exists(check.collection, check.name)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("hetzner requires a valid %s, %q doesn't exist", check.flag, check.name)
+		}
+	}
+
+	d.NetworkIDs = []int{}
+	for _, idOrName := range d.Networks {
+		network, err := client.GetNetwork(idOrName)
+		if err != nil {
+			return err
+		}
+		if network == nil {
+			return fmt.Errorf("The Hetzner Cloud network %q doesn't exist", idOrName)
+		}
+		d.NetworkIDs = append(d.NetworkIDs, network.ID)
+	}
+
+	return nil
+}
+
+func (d *Driver) Create() error {
+	userData, err := drivers.ReadUserData(d.UserDataFile)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Creating SSH key...")
+
+	key, err := d.createSSHKey()
+	if err != nil {
+		return err
+	}
+
+	d.SSHKeyID = key.ID
+
+	log.Infof("Creating Hetzner Cloud server...")
+
+	server, err := d.getClient().CreateServer(ServerCreateRequest{
+		Name:             d.MachineName,
+		ServerType:       d.ServerType,
+		Image:            d.Image,
+		Location:         d.Location,
+		SSHKeys:          []int{d.SSHKeyID},
+		Networks:         d.NetworkIDs,
+		Labels:           d.Labels,
+		UserData:         userData,
+		StartAfterCreate: true,
+	})
+	if err != nil {
+		// Without a server, the machine may not be removed, which would
+		// leave the key behind.
+		if deleteErr := d.getClient().DeleteSSHKey(d.SSHKeyID); deleteErr != nil {
+			log.Warnf("Error deleting the SSH key %d of the server which failed to be created: %s", d.SSHKeyID, deleteErr)
+		} else {
+			d.SSHKeyID = 0
+		}
+		return err
+	}
+
+	d.ServerID = server.ID
+
+	log.Info("Waiting for the IP address of the server...")
+	if err := mcnutils.WaitFor(d.serverHasIP); err != nil {
+		return fmt.Errorf("Error waiting for the IP address of the server: %s", err)
+	}
+
+	log.Debugf("Created server ID %d, IP address %s", d.ServerID, d.IPAddress)
+
+	return nil
+}
+
+// serverHasIP sets the address of the machine once the server has it: its
+// public address, or its address on the first private network.
+func (d *Driver) serverHasIP() bool {
+	server, err := d.getClient().GetServer(d.ServerID)
+	if err != nil {
+		log.Debugf("Error getting server %d: %s", d.ServerID, err)
+		return false
+	}
+
+	d.IPAddress = serverIP(server, d.UsePrivateNetwork, d.NetworkIDs)
+	return d.IPAddress != ""
+}
+
+func serverIP(server *Server, private bool, networkIDs []int) string {
+	if !private {
+		return server.PublicNet.IPv4.IP
+	}
+
+	for _, privateNet := range server.PrivateNet {
+		if len(networkIDs) > 0 && privateNet.Network == networkIDs[0] {
+			return privateNet.IP
+		}
+	}
+
+	return ""
+}
+
+func (d *Driver) createSSHKey() (*SSHKey, error) {
+	if err := ssh.GenerateSSHKey(d.GetSSHKeyPath()); err != nil {
+		return nil, err
+	}
+
+	publicKey, err := ioutil.ReadFile(d.publicSSHKeyPath())
+	if err != nil {
+		return nil, err
+	}
+
+	return d.getClient().CreateSSHKey(d.MachineName, string(publicKey))
+}
+
+func (d *Driver) GetURL() (string, error) {
+	ip, err := d.GetIP()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("tcp://%s", net.JoinHostPort(ip, "2376")), nil
+}
+
+func (d *Driver) GetState() (state.State, error) {
+	server, err := d.getClient().GetServer(d.ServerID)
+	if err != nil {
+		return state.Error, err
+	}
+
+	return serverState(server.Status), nil
+}
+
+func serverState(status string) state.State {
+	switch status {
+	case "initializing", "starting":
+		return state.Starting
+	case "running":
+		return state.Running
+	case "stopping":
+		return state.Stopping
+	case "off":
+		return state.Stopped
+	}
+
+	return state.None
+}
+
+func (d *Driver) Start() error {
+	return d.getClient().ServerAction(d.ServerID, "poweron")
+}
+
+func (d *Driver) Stop() error {
+	return d.getClient().ServerAction(d.ServerID, "shutdown")
+}
+
+func (d *Driver) Restart() error {
+	return d.getClient().ServerAction(d.ServerID, "reboot")
+}
+
+func (d *Driver) Kill() error {
+	return d.getClient().ServerAction(d.ServerID, "poweroff")
+}
+
+func (d *Driver) Remove() error {
+	client := d.getClient()
+
+	if d.ServerID != 0 {
+		if err := client.DeleteServer(d.ServerID); err != nil {
+			if !isNotFound(err) {
+				return err
+			}
+			log.Infof("Hetzner Cloud server doesn't exist, assuming it is already deleted")
+		}
+	}
+
+	if d.SSHKeyID != 0 {
+		if err := client.DeleteSSHKey(d.SSHKeyID); err != nil {
+			if !isNotFound(err) {
+				return err
+			}
+			log.Infof("Hetzner Cloud SSH key doesn't exist, assuming it is already deleted")
+		}
+	}
+
+	return nil
+}
+
+func (d *Driver) publicSSHKeyPath() string {
+	return d.GetSSHKeyPath() + ".pub"
+}
@@ -0,0 +1,208 @@
+package hetzner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAPI answers requests by method and path, with a status and a JSON
+// body, and records them.
+type fakeAPI struct {
+	answers  map[string]string
+	statuses map[string]int
+	requests []string
+	bodies   map[string]string
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	request := r.Method + " " + r.URL.RequestURI()
+	f.requests = append(f.requests, request)
+
+	body, _ := ioutil.ReadAll(r.Body)
+	f.bodies[request] = string(body)
+
+	if r.Header.Get("Authorization") != "Bearer TOKEN" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if status, ok := f.statuses[request]; ok {
+		w.WriteHeader(status)
+	}
+	fmt.Fprint(w, f.answers[request])
+}
+
+func newTestDriver(answers map[string]string, statuses map[string]int) (*Driver, *fakeAPI, func()) {
+	api := &fakeAPI{answers: answers, statuses: statuses, bodies: map[string]string{}}
+	server := httptest.NewServer(api)
+
+	d := NewDriver("default", "path")
+	d.APIToken = "TOKEN"
+	d.APIEndpoint = server.URL
+
+	return d, api, server.Close
+}
+
+func TestSetConfigFromFlags(t *testing.T) {
+	driver := NewDriver("default", "path")
+
+	checkFlags := &drivers.CheckDriverOptions{
+		FlagsValues: map[string]interface{}{
+			"hetzner-api-token": "TOKEN",
+			"hetzner-label":     []string{"env=dev", "team=web"},
+		},
+		CreateFlags: driver.GetCreateFlags(),
+	}
+
+	err := driver.SetConfigFromFlags(checkFlags)
+
+	assert.NoError(t, err)
+	assert.Empty(t, checkFlags.InvalidFlags)
+	assert.Equal(t, map[string]string{"env": "dev", "team": "web"}, driver.Labels)
+}
+
+func TestSetConfigFromFlagsErrors(t *testing.T) {
+	for _, values := range []map[string]interface{}{
+		{},
+		{"hetzner-api-token": "TOKEN", "hetzner-use-private-network": true},
+		{"hetzner-api-token": "TOKEN", "hetzner-label": []string{"env"}},
+	} {
+		driver := NewDriver("default", "path")
+
+		err := driver.SetConfigFromFlags(&drivers.CheckDriverOptions{
+			FlagsValues: values,
+			CreateFlags: driver.GetCreateFlags(),
+		})
+
+		assert.Error(t, err, fmt.Sprint(values))
+	}
+}
+
+func TestServerState(t *testing.T) {
+	assert.Equal(t, state.Starting, serverState("initializing"))
+	assert.Equal(t, state.Running, serverState("running"))
+	assert.Equal(t, state.Stopping, serverState("stopping"))
+	assert.Equal(t, state.Stopped, serverState("off"))
+	assert.Equal(t, state.None, serverState("migrating"))
+}
+
+func TestServerIP(t *testing.T) {
+	server := &Server{PrivateNet: []ServerPrivateNet{{Network: 7, IP: "10.0.0.2"}}}
+	server.PublicNet.IPv4.IP = "203.0.113.4"
+
+	assert.Equal(t, "203.0.113.4", serverIP(server, false, []int{7}))
+	assert.Equal(t, "10.0.0.2", serverIP(server, true, []int{7}))
+	assert.Empty(t, serverIP(server, true, []int{8}))
+}
+
+func TestPreCreateCheck(t *testing.T) {
+	d, api, cleanup := newTestDriver(map[string]string{
+		"GET /server_types?name=cx22": `{"server_types":[{"id":1,"name":"cx22"}]}`,
+		"GET /locations?name=fsn1":    `{"locations":[{"id":1,"name":"fsn1"}]}`,
+		"GET /networks?name=backend":  `{"networks":[{"id":7,"name":"backend"}]}`,
+		"GET /networks/9":             `{"network":{"id":9,"name":"other"}}`,
+	}, nil)
+	defer cleanup()
+	d.Networks = []string{"backend", "9"}
+
+	assert.NoError(t, d.PreCreateCheck())
+	assert.Equal(t, []int{7, 9}, d.NetworkIDs)
+	assert.Len(t, api.requests, 4)
+}
+
+func TestPreCreateCheckUnknownLocation(t *testing.T) {
+	d, _, cleanup := newTestDriver(map[string]string{
+		"GET /server_types?name=cx22": `{"server_types":[{"id":1,"name":"cx22"}]}`,
+		"GET /locations?name=fsn1":    `{"locations":[]}`,
+	}, nil)
+	defer cleanup()
+
+	assert.EqualError(t, d.PreCreateCheck(), `hetzner requires a valid --hetzner-location, "fsn1" doesn't exist`)
+}
+
+func TestCreateServer(t *testing.T) {
+	d, api, cleanup := newTestDriver(map[string]string{
+		"POST /servers": `{"server":{"id":42,"name":"default","status":"initializing"}}`,
+	}, map[string]int{
+		"POST /servers": http.StatusCreated,
+	})
+	defer cleanup()
+
+	server, err := d.getClient().CreateServer(ServerCreateRequest{
+		Name:       "default",
+		ServerType: "cx22",
+		Image:      "ubuntu-22.04",
+		SSHKeys:    []int{3},
+		Networks:   []int{7},
+		Labels:     map[string]string{"env": "dev"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 42, server.ID)
+
+	request := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal([]byte(api.bodies["POST /servers"]), &request))
+	assert.Equal(t, "cx22", request["server_type"])
+	assert.Equal(t, []interface{}{float64(7)}, request["networks"])
+	assert.Equal(t, map[string]interface{}{"env": "dev"}, request["labels"])
+}
+
+func TestCreateDeletesSSHKeyWhenServerFails(t *testing.T) {
+	d, api, cleanup := newTestDriver(map[string]string{
+		"POST /ssh_keys": `{"ssh_key":{"id":3,"name":"default"}}`,
+		"POST /servers":  `{"error":{"code":"resource_limit_exceeded","message":"server limit exceeded"}}`,
+	}, map[string]int{
+		"POST /ssh_keys":     http.StatusCreated,
+		"POST /servers":      http.StatusForbidden,
+		"DELETE /ssh_keys/3": http.StatusNoContent,
+	})
+	defer cleanup()
+
+	storePath, err := ioutil.TempDir("", "hetzner-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(storePath)
+	d.StorePath = storePath
+	assert.NoError(t, os.MkdirAll(d.ResolveStorePath("."), 0700))
+
+	err = d.Create()
+
+	assert.EqualError(t, err, "Hetzner Cloud API error 403 (resource_limit_exceeded): server limit exceeded")
+	assert.Equal(t, []string{"POST /ssh_keys", "POST /servers", "DELETE /ssh_keys/3"}, api.requests)
+	assert.Equal(t, 0, d.SSHKeyID)
+}
+
+func TestAPIError(t *testing.T) {
+	d, _, cleanup := newTestDriver(map[string]string{
+		"GET /servers/42": `{"error":{"code":"not_found","message":"server with ID '42' not found"}}`,
+	}, map[string]int{
+		"GET /servers/42": http.StatusNotFound,
+	})
+	defer cleanup()
+
+	_, err := d.getClient().GetServer(42)
+
+	assert.True(t, isNotFound(err))
+	assert.EqualError(t, err, "Hetzner Cloud API error 404 (not_found): server with ID '42' not found")
+}
+
+func TestRemoveDeletedServer(t *testing.T) {
+	d, api, cleanup := newTestDriver(nil, map[string]int{
+		"DELETE /servers/42": http.StatusNotFound,
+		"DELETE /ssh_keys/3": http.StatusNoContent,
+	})
+	defer cleanup()
+	d.ServerID = 42
+	d.SSHKeyID = 3
+
+	assert.NoError(t, d.Remove())
+	assert.Equal(t, []string{"DELETE /servers/42", "DELETE /ssh_keys/3"}, api.requests)
+}
@@ -0,0 +1,185 @@
+package httpcloud
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/docker/machine/libmachine/state"
+)
+
+// Config describes the API of a provider: how to create, look up, power and
+// delete a server with HTTP calls, and where the fields the driver needs are
+// in the answers.
+type Config struct {
+	Endpoint string
+	Headers  map[string]string
+	SSHUser  string
+	SSHPort  int
+
+	// StartAfterCreate runs the Start call once the server is created, for
+	// providers which create servers powered off.
+	StartAfterCreate bool
+
+	Create  Call
+	Get     Call
+	Delete  Call
+	Start   *Call
+	Stop    *Call
+	Restart *Call
+	Kill    *Call
+
+	Fields Fields
+
+	// States maps the states of the provider, found at Fields.State, to
+	// those of Machine, e.g. "running": "Running".
+	States map[string]string
+}
+
+// Call is an HTTP call to the API. The path, body and headers are Go
+// templates, see templateData.
+type Call struct {
+	Method string
+	Path   string
+	Body   string
+}
+
+// Fields are the dotted paths, e.g. server.public_ip.address or
+// servers.0.id, of the fields in the JSON answers of the API.
+type Fields struct {
+	// ID is the ID of the server in the answer to Create.
+	ID string
+	// IP and State are the address and state of the server in the answer
+	// to Get.
+	IP    string
+	State string
+}
+
+// templateData is what the templates of the calls are rendered with.
+type templateData struct {
+	Name         string
+	ID           string
+	SSHPublicKey string
+	UserData     string
+	Vars         map[string]string
+}
+
+var templateFuncs = template.FuncMap{
+	// json quotes a value for a JSON body, e.g. "name": {{json .Name}}.
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	// replace replaces old by new in s, e.g. the spaces of an SSH key.
+	"replace": func(s, old, new string) string {
+		return strings.Replace(s, old, new, -1)
+	},
+}
+
+// LoadConfig reads and checks the configuration at path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("Invalid configuration %s: %s", path, err)
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("Invalid configuration %s: %s", path, err)
+	}
+
+	return config, nil
+}
+
+func (c *Config) validate() error {
+	if c.Endpoint == "" {
+		return fmt.Errorf("the endpoint is missing")
+	}
+
+	for name, call := range map[string]*Call{"create": &c.Create, "get": &c.Get, "delete": &c.Delete} {
+		if call.Path == "" {
+			return fmt.Errorf("the %s call is missing", name)
+		}
+	}
+
+	if c.StartAfterCreate && c.Start == nil {
+		return fmt.Errorf("startAfterCreate requires a start call")
+	}
+
+	if c.Fields.ID == "" || c.Fields.IP == "" {
+		return fmt.Errorf("the id and ip fields are required")
+	}
+
+	for providerState, machineState := range c.States {
+		if _, err := parseState(machineState); err != nil {
+			return fmt.Errorf("state %s: %s", providerState, err)
+		}
+	}
+
+	return nil
+}
+
+// parseState returns the state of Machine named name.
+func parseState(name string) (state.State, error) {
+	for s := state.Running; s <= state.Timeout; s++ {
+		if s.String() == name {
+			return s, nil
+		}
+	}
+
+	return state.None, fmt.Errorf("unknown state %q", name)
+}
+
+// render renders the template text with data.
+func render(text string, data templateData) (string, error) {
+	tmpl, err := template.New("").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// lookupField returns the value at the dotted path in a decoded JSON answer,
+// formatted as a string, or "" if there is none.
+func lookupField(answer interface{}, path string) string {
+	value := answer
+	for _, key := range strings.Split(path, ".") {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			value = node[key]
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return ""
+			}
+			value = node[index]
+		default:
+			return ""
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+
+	return ""
+}
@@ -0,0 +1,326 @@
+package httpcloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/docker/machine/libmachine/mcnutils"
+	"github.com/docker/machine/libmachine/ssh"
+	"github.com/docker/machine/libmachine/state"
+)
+
+// Driver creates servers on providers without a driver of their own, by
+// making the HTTP calls described by a configuration file. The configuration
+// is kept with the machine, so later changes of the file don't affect it.
+type Driver struct {
+	*drivers.BaseDriver
+	Config       *Config
+	Vars         map[string]string
+	ServerID     string
+	UserDataFile string
+}
+
+// httpError is an unexpected status answered by the API.
+type httpError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *httpError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Body)
+}
+
+func NewDriver(hostName, storePath string) *Driver {
+	return &Driver{
+		BaseDriver: &drivers.BaseDriver{
+			MachineName: hostName,
+			StorePath:   storePath,
+		},
+	}
+}
+
+// GetCreateFlags registers the flags this driver adds to
+// "docker hosts create"
+func (d *Driver) GetCreateFlags() []mcnflag.Flag {
+	return []mcnflag.Flag{
+		mcnflag.StringFlag{
+			EnvVar: "HTTPCLOUD_CONFIG",
+			Name:   "httpcloud-config",
+			Usage:  "Path of the JSON file describing the API of the provider",
+		},
+		mcnflag.StringSliceFlag{
+			EnvVar: "HTTPCLOUD_VAR",
+			Name:   "httpcloud-var",
+			Usage:  "Variable of the templates of the calls, key=value, e.g. token=...",
+		},
+		drivers.UserDataFlag,
+	}
+}
+
+// DriverName returns the name of the driver
+func (d *Driver) DriverName() string {
+	return "httpcloud"
+}
+
+func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
+	d.UserDataFile = flags.String(drivers.UserDataFlag.Name)
+	d.SwarmMaster = flags.Bool("swarm-master")
+	d.SwarmHost = flags.String("swarm-host")
+	d.SwarmDiscovery = flags.String("swarm-discovery")
+
+	path := flags.String("httpcloud-config")
+	if path == "" {
+		return fmt.Errorf("httpcloud driver requires the --httpcloud-config option")
+	}
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	d.Config = config
+
+	d.Vars = map[string]string{}
+	for _, spec := range flags.StringSlice("httpcloud-var") {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf("Invalid variable %q, expected key=value", spec)
+		}
+		d.Vars[parts[0]] = parts[1]
+	}
+
+	d.SSHUser = config.SSHUser
+	if d.SSHUser == "" {
+		d.SSHUser = "root"
+	}
+	d.SSHPort = config.SSHPort
+	if d.SSHPort == 0 {
+		d.SSHPort = 22
+	}
+
+	return nil
+}
+
+func (d *Driver) GetSSHHostname() (string, error) {
+	return d.GetIP()
+}
+
+func (d *Driver) templateData() (templateData, error) {
+	data := templateData{
+		Name: d.MachineName,
+		ID:   d.ServerID,
+		Vars: d.Vars,
+	}
+
+	// The key and the user data are only needed to create the server.
+	if d.ServerID == "" {
+		publicKey, err := ioutil.ReadFile(d.publicSSHKeyPath())
+		if err != nil {
+			return data, err
+		}
+		data.SSHPublicKey = strings.TrimSpace(string(publicKey))
+
+		if data.UserData, err = drivers.ReadUserData(d.UserDataFile); err != nil {
+			return data, err
+		}
+	}
+
+	return data, nil
+}
+
+// call makes an HTTP call of the configuration and decodes its JSON answer,
+// if any.
+func (d *Driver) call(call Call) (interface{}, error) {
+	data, err := d.templateData()
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := render(call.Path, data)
+	if err != nil {
+		return nil, fmt.Errorf("Error rendering the path %q: %s", call.Path, err)
+	}
+
+	var body io.Reader
+	if call.Body != "" {
+		rendered, err := render(call.Body, data)
+		if err != nil {
+			return nil, fmt.Errorf("Error rendering the body of %s: %s", path, err)
+		}
+		body = strings.NewReader(rendered)
+	}
+
+	method := call.Method
+	if method == "" {
+		method = "GET"
+	}
+
+	req, err := http.NewRequest(method, d.Config.Endpoint+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range d.Config.Headers {
+		rendered, err := render(value, data)
+		if err != nil {
+			return nil, fmt.Errorf("Error rendering the header %s: %s", name, err)
+		}
+		req.Header.Set(name, rendered)
+	}
+
+	log.Debugf("%s %s", method, req.URL)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &httpError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(content))}
+	}
+
+	var answer interface{}
+	if len(content) > 0 {
+		if err := json.Unmarshal(content, &answer); err != nil {
+			return nil, fmt.Errorf("Invalid answer to %s %s: %s", method, path, err)
+		}
+	}
+
+	return answer, nil
+}
+
+// optionalCall makes a call the configuration may not describe.
+func (d *Driver) optionalCall(name string, call *Call) error {
+	if call == nil {
+		return fmt.Errorf("The configuration of the httpcloud driver has no %s call", name)
+	}
+
+	_, err := d.call(*call)
+	return err
+}
+
+func (d *Driver) Create() error {
+	log.Infof("Creating SSH key...")
+	if err := ssh.GenerateSSHKey(d.GetSSHKeyPath()); err != nil {
+		return err
+	}
+
+	log.Infof("Creating server...")
+	answer, err := d.call(d.Config.Create)
+	if err != nil {
+		return err
+	}
+
+	id := lookupField(answer, d.Config.Fields.ID)
+	if id == "" {
+		return fmt.Errorf("The answer to the create call has no %s field", d.Config.Fields.ID)
+	}
+	d.ServerID = id
+
+	if d.Config.StartAfterCreate {
+		log.Infof("Starting server %s...", d.ServerID)
+		if err := d.Start(); err != nil {
+			return err
+		}
+	}
+
+	log.Info("Waiting for the IP address of the server...")
+	if err := mcnutils.WaitFor(d.serverHasIP); err != nil {
+		return fmt.Errorf("Error waiting for the IP address of the server: %s", err)
+	}
+
+	log.Debugf("Created server %s, IP address %s", d.ServerID, d.IPAddress)
+
+	return nil
+}
+
+func (d *Driver) serverHasIP() bool {
+	answer, err := d.call(d.Config.Get)
+	if err != nil {
+		log.Debugf("Error getting server %s: %s", d.ServerID, err)
+		return false
+	}
+
+	d.IPAddress = lookupField(answer, d.Config.Fields.IP)
+	return d.IPAddress != ""
+}
+
+func (d *Driver) GetURL() (string, error) {
+	ip, err := d.GetIP()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("tcp://%s", net.JoinHostPort(ip, "2376")), nil
+}
+
+// GetState maps the state of the server through the states of the
+// configuration. Without a state field, a server which answers is running.
+func (d *Driver) GetState() (state.State, error) {
+	answer, err := d.call(d.Config.Get)
+	if err != nil {
+		return state.Error, err
+	}
+
+	if d.Config.Fields.State == "" {
+		return state.Running, nil
+	}
+
+	name, ok := d.Config.States[lookupField(answer, d.Config.Fields.State)]
+	if !ok {
+		return state.None, nil
+	}
+
+	return parseState(name)
+}
+
+func (d *Driver) Start() error {
+	return d.optionalCall("start", d.Config.Start)
+}
+
+func (d *Driver) Stop() error {
+	return d.optionalCall("stop", d.Config.Stop)
+}
+
+func (d *Driver) Restart() error {
+	return d.optionalCall("restart", d.Config.Restart)
+}
+
+func (d *Driver) Kill() error {
+	return d.optionalCall("kill", d.Config.Kill)
+}
+
+func (d *Driver) Remove() error {
+	if d.ServerID == "" {
+		return nil
+	}
+
+	if _, err := d.call(d.Config.Delete); err != nil {
+		if httpErr, ok := err.(*httpError); ok && httpErr.StatusCode == http.StatusNotFound {
+			log.Infof("Server %s doesn't exist, assuming it is already deleted", d.ServerID)
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (d *Driver) publicSSHKeyPath() string {
+	return d.GetSSHKeyPath() + ".pub"
+}
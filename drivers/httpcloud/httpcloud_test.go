@@ -0,0 +1,208 @@
+package httpcloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+)
+
+const testConfig = `{
+  "endpoint": "%s",
+  "headers": {"X-Auth-Token": "{{.Vars.token}}"},
+  "startAfterCreate": true,
+  "create": {"method": "POST", "path": "/servers", "body": "{\"name\": {{json .Name}}, \"key\": {{json .SSHPublicKey}}}"},
+  "get": {"path": "/servers/{{.ID}}"},
+  "delete": {"method": "DELETE", "path": "/servers/{{.ID}}"},
+  "start": {"method": "POST", "path": "/servers/{{.ID}}/action", "body": "{\"action\": \"poweron\"}"},
+  "fields": {"id": "server.id", "ip": "server.public_ip.address", "state": "server.state"},
+  "states": {"running": "Running", "stopped": "Stopped", "starting": "Starting"}
+}`
+
+// fakeAPI answers requests by method and path and records them.
+type fakeAPI struct {
+	answers  map[string]string
+	statuses map[string]int
+	requests []string
+	bodies   map[string]string
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	request := r.Method + " " + r.URL.RequestURI()
+	f.requests = append(f.requests, request)
+
+	body, _ := ioutil.ReadAll(r.Body)
+	f.bodies[request] = string(body)
+
+	if r.Header.Get("X-Auth-Token") != "TOKEN" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if status, ok := f.statuses[request]; ok {
+		w.WriteHeader(status)
+	}
+	fmt.Fprint(w, f.answers[request])
+}
+
+func newTestDriver(t *testing.T, api *fakeAPI) (*Driver, func()) {
+	server := httptest.NewServer(api)
+
+	storePath, err := ioutil.TempDir("", "httpcloud-test-")
+	assert.NoError(t, err)
+
+	configPath := filepath.Join(storePath, "config.json")
+	assert.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(testConfig, server.URL)), 0600))
+
+	d := NewDriver("default", storePath)
+	assert.NoError(t, os.MkdirAll(d.ResolveStorePath("."), 0700))
+
+	err = d.SetConfigFromFlags(&drivers.CheckDriverOptions{
+		FlagsValues: map[string]interface{}{
+			"httpcloud-config": configPath,
+			"httpcloud-var":    []string{"token=TOKEN"},
+		},
+		CreateFlags: d.GetCreateFlags(),
+	})
+	assert.NoError(t, err)
+
+	return d, func() {
+		server.Close()
+		os.RemoveAll(storePath)
+	}
+}
+
+func TestSetConfigFromFlags(t *testing.T) {
+	d, cleanup := newTestDriver(t, &fakeAPI{})
+	defer cleanup()
+
+	assert.Equal(t, map[string]string{"token": "TOKEN"}, d.Vars)
+	assert.Equal(t, "root", d.SSHUser)
+	assert.Equal(t, 22, d.SSHPort)
+	assert.True(t, d.Config.StartAfterCreate)
+	assert.Equal(t, "server.public_ip.address", d.Config.Fields.IP)
+}
+
+func TestSetConfigFromFlagsWithoutConfig(t *testing.T) {
+	d := NewDriver("default", "path")
+
+	checkFlags := &drivers.CheckDriverOptions{
+		FlagsValues: map[string]interface{}{},
+		CreateFlags: d.GetCreateFlags(),
+	}
+
+	assert.EqualError(t, d.SetConfigFromFlags(checkFlags), "httpcloud driver requires the --httpcloud-config option")
+	assert.Empty(t, checkFlags.InvalidFlags)
+}
+
+func TestValidateConfig(t *testing.T) {
+	valid := func() *Config {
+		return &Config{
+			Endpoint: "https://api.example.com",
+			Create:   Call{Path: "/servers"},
+			Get:      Call{Path: "/servers/{{.ID}}"},
+			Delete:   Call{Path: "/servers/{{.ID}}"},
+			Fields:   Fields{ID: "id", IP: "ip"},
+		}
+	}
+
+	assert.NoError(t, valid().validate())
+
+	config := valid()
+	config.Delete = Call{}
+	assert.EqualError(t, config.validate(), "the delete call is missing")
+
+	config = valid()
+	config.StartAfterCreate = true
+	assert.EqualError(t, config.validate(), "startAfterCreate requires a start call")
+
+	config = valid()
+	config.States = map[string]string{"up": "Up"}
+	assert.EqualError(t, config.validate(), `state up: unknown state "Up"`)
+}
+
+func TestLookupField(t *testing.T) {
+	var answer interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"servers": [{"id": 42, "ip": "203.0.113.4", "up": true}]}`), &answer))
+
+	assert.Equal(t, "42", lookupField(answer, "servers.0.id"))
+	assert.Equal(t, "203.0.113.4", lookupField(answer, "servers.0.ip"))
+	assert.Equal(t, "true", lookupField(answer, "servers.0.up"))
+	assert.Empty(t, lookupField(answer, "servers.1.id"))
+	assert.Empty(t, lookupField(answer, "servers.0.missing"))
+	assert.Empty(t, lookupField(answer, "servers"))
+}
+
+func TestRender(t *testing.T) {
+	data := templateData{Name: `my "dev"`, Vars: map[string]string{"zone": "fr-par-1"}}
+
+	out, err := render(`{"name": {{json .Name}}, "zone": "{{.Vars.zone}}"}`, data)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name": "my \"dev\"", "zone": "fr-par-1"}`, out)
+
+	out, err = render(`{{replace "ssh-rsa AAAA me" " " "_"}}`, data)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ssh-rsa_AAAA_me", out)
+
+	_, err = render("{{.Vars.missing}}", data)
+
+	assert.Error(t, err)
+}
+
+func TestCreate(t *testing.T) {
+	api := &fakeAPI{
+		answers: map[string]string{
+			"POST /servers":            `{"server": {"id": "abc", "state": "stopped"}}`,
+			"GET /servers/abc":         `{"server": {"id": "abc", "state": "starting", "public_ip": {"address": "203.0.113.4"}}}`,
+			"POST /servers/abc/action": `{}`,
+		},
+		bodies: map[string]string{},
+	}
+	d, cleanup := newTestDriver(t, api)
+	defer cleanup()
+
+	assert.NoError(t, d.Create())
+	assert.Equal(t, "abc", d.ServerID)
+	assert.Equal(t, "203.0.113.4", d.IPAddress)
+	assert.Equal(t, []string{"POST /servers", "POST /servers/abc/action", "GET /servers/abc"}, api.requests)
+
+	body := map[string]string{}
+	assert.NoError(t, json.Unmarshal([]byte(api.bodies["POST /servers"]), &body))
+	assert.Equal(t, "default", body["name"])
+	assert.Contains(t, body["key"], "ssh-rsa ")
+
+	s, err := d.GetState()
+
+	assert.NoError(t, err)
+	assert.Equal(t, state.Starting, s)
+}
+
+func TestUnconfiguredCall(t *testing.T) {
+	d, cleanup := newTestDriver(t, &fakeAPI{bodies: map[string]string{}})
+	defer cleanup()
+
+	assert.EqualError(t, d.Stop(), "The configuration of the httpcloud driver has no stop call")
+}
+
+func TestRemoveDeletedServer(t *testing.T) {
+	api := &fakeAPI{
+		statuses: map[string]int{"DELETE /servers/abc": http.StatusNotFound},
+		bodies:   map[string]string{},
+	}
+	d, cleanup := newTestDriver(t, api)
+	defer cleanup()
+	d.ServerID = "abc"
+
+	assert.NoError(t, d.Remove())
+	assert.Equal(t, []string{"DELETE /servers/abc"}, api.requests)
+}
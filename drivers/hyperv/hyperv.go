@@ -25,6 +25,10 @@ type Driver struct {
 	diskImage      string
 	DiskSize       int
 	MemSize        int
+	ShareFolders   []drivers.SharedFolder
+	ShareUsername  string
+	// SharePassword isn't saved: it's given to the VM when it's created.
+	SharePassword string `json:"-"`
 }
 
 const (
@@ -69,6 +73,21 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Usage: "Hyper-V memory size for host in MB.",
 			Value: defaultMemory,
 		},
+		mcnflag.StringSliceFlag{
+			Name:  "hyperv-share-folder",
+			Usage: "Hyper-V folder of the host shared with the VM over SMB: host-path:guest-path[:option,...], the options being ro, rw, consistent, cached and delegated.",
+			Value: []string{},
+		},
+		mcnflag.StringFlag{
+			Name:   "hyperv-share-username",
+			Usage:  "Hyper-V user the VM mounts the shared folders as. Defaults to the current user.",
+			EnvVar: "HYPERV_SHARE_USERNAME",
+		},
+		mcnflag.StringFlag{
+			Name:   "hyperv-share-password",
+			Usage:  "Hyper-V password of the user the VM mounts the shared folders as.",
+			EnvVar: "HYPERV_SHARE_PASSWORD",
+		},
 	}
 }
 
@@ -78,11 +97,24 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.vSwitch = flags.String("hyperv-virtual-switch")
 	d.DiskSize = flags.Int("hyperv-disk-size")
 	d.MemSize = flags.Int("hyperv-memory")
+	d.ShareUsername = flags.String("hyperv-share-username")
+	d.SharePassword = flags.String("hyperv-share-password")
 	d.SwarmMaster = flags.Bool("swarm-master")
 	d.SwarmHost = flags.String("swarm-host")
 	d.SwarmDiscovery = flags.String("swarm-discovery")
 	d.SSHUser = "docker"
 	d.SSHPort = 22
+
+	shareFolders, err := drivers.ParseSharedFolders(flags.StringSlice("hyperv-share-folder"))
+	if err != nil {
+		return err
+	}
+	d.ShareFolders = shareFolders
+
+	if len(d.ShareFolders) > 0 && d.ShareUsername == "" {
+		d.ShareUsername = os.Getenv("USERNAME")
+	}
+
 	return nil
 }
 
@@ -207,6 +239,10 @@ func (d *Driver) Create() error {
 		return err
 	}
 
+	if err := d.createSMBShares(); err != nil {
+		return err
+	}
+
 	log.Infof("Starting  VM...")
 	if err := d.Start(); err != nil {
 		return err
@@ -259,7 +295,11 @@ func (d *Driver) Start() error {
 	}
 
 	d.IPAddress, err = d.GetIP()
-	return err
+	if err != nil {
+		return err
+	}
+
+	return d.mountSharedFolders()
 }
 
 func (d *Driver) Stop() error {
@@ -299,8 +339,12 @@ func (d *Driver) Remove() error {
 		"Remove-VM",
 		"-Name", d.MachineName,
 		"-Force"}
-	_, err = execute(command)
-	return err
+	if _, err = execute(command); err != nil {
+		return err
+	}
+
+	d.removeSMBShares()
+	return nil
 }
 
 func (d *Driver) Restart() error {
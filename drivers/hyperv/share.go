@@ -0,0 +1,136 @@
+package hyperv
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+)
+
+// smbCredentialsPath is where the VM keeps the credentials it mounts the SMB
+// shares of the host with. /var/lib/boot2docker survives reboots.
+const smbCredentialsPath = "/var/lib/boot2docker/smb-credentials"
+
+// Hyper-V has no shared folders of its own: the folders are shared by the
+// host over SMB and mounted with CIFS in the VM.
+
+// smbShareName returns the name of the SMB share of the host backing the
+// folder, unique to the machine.
+func (d *Driver) smbShareName(folder drivers.SharedFolder) string {
+	return d.MachineName + "_" + strings.Replace(folder.Name, "/", "_", -1)
+}
+
+func (d *Driver) SharedFolders() ([]drivers.SharedFolder, error) {
+	return d.ShareFolders, nil
+}
+
+// createSMBShares shares the folders over SMB with the user the VM mounts
+// them as.
+func (d *Driver) createSMBShares() error {
+	for _, folder := range d.ShareFolders {
+		if _, err := os.Stat(folder.HostPath); err != nil {
+			return fmt.Errorf("unable to share %s with the VM: %s", folder.HostPath, err)
+		}
+
+		access := "-FullAccess"
+		if folder.ReadOnly {
+			access = "-ReadAccess"
+		}
+
+		log.Debugf("Sharing %s at %s", folder.HostPath, folder.GuestPath)
+		command := []string{
+			"New-SmbShare",
+			"-Name", quote(d.smbShareName(folder)),
+			"-Path", quote(folder.HostPath),
+			access, quote(d.ShareUsername)}
+		if _, err := execute(command); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeSMBShares removes the SMB shares of the machine, leaving the folders
+// as they are.
+func (d *Driver) removeSMBShares() {
+	for _, folder := range d.ShareFolders {
+		command := []string{
+			"Remove-SmbShare",
+			"-Name", quote(d.smbShareName(folder)),
+			"-Force"}
+		if _, err := execute(command); err != nil {
+			log.Warnf("Unable to remove SMB share %s: %s", d.smbShareName(folder), err)
+		}
+	}
+}
+
+// cifsCache maps the consistency of a folder to the caching mode of CIFS.
+func cifsCache(consistency drivers.Consistency) string {
+	switch consistency {
+	case drivers.ConsistencyCached, drivers.ConsistencyDelegated:
+		return "loose"
+	}
+
+	return "strict"
+}
+
+// shellQuote quotes s for the shell of the VM.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// cifsMountCommand returns the command mounting the folder, shared by the
+// host at hostIP, in the VM.
+func (d *Driver) cifsMountCommand(hostIP string, folder drivers.SharedFolder) string {
+	options := fmt.Sprintf("credentials=%s,uid=1000,gid=50,cache=%s", smbCredentialsPath, cifsCache(folder.Consistency))
+	if folder.ReadOnly {
+		options += ",ro"
+	}
+
+	return fmt.Sprintf("sudo mkdir -p %[1]s && (mountpoint -q %[1]s || sudo mount -t cifs //%[2]s/%[3]s %[1]s -o %[4]s)",
+		shellQuote(folder.GuestPath), hostIP, d.smbShareName(folder), options)
+}
+
+// saveCredentialsCommand saves the credentials given on its standard input
+// for the VM to mount the shares with.
+var saveCredentialsCommand = fmt.Sprintf("sudo sh -c 'umask 077 && cat > %s'", smbCredentialsPath)
+
+// mountSharedFolders mounts the SMB shares of the host in the VM, which
+// doesn't remember them across reboots.
+func (d *Driver) mountSharedFolders() error {
+	if len(d.ShareFolders) == 0 {
+		return nil
+	}
+
+	hostIP, err := mcnutils.HostIPFor(d.IPAddress)
+	if err != nil {
+		return fmt.Errorf("unable to mount the shared folders: %s", err)
+	}
+
+	if err := drivers.WaitForSSH(d); err != nil {
+		return err
+	}
+
+	// The password is only known while the machine is created, the VM
+	// keeps the credentials across reboots. It's given on the standard input
+	// of the command, for it not to be logged.
+	if d.SharePassword != "" {
+		credentials := fmt.Sprintf("username=%s\npassword=%s\n", d.ShareUsername, d.SharePassword)
+		if _, err := drivers.RunSSHCommandWithInputFromDriver(d, saveCredentialsCommand, []byte(credentials)); err != nil {
+			return err
+		}
+	}
+
+	for _, folder := range d.ShareFolders {
+		log.Debugf("Mounting %s at %s", folder.HostPath, folder.GuestPath)
+		if _, err := drivers.RunSSHCommandFromDriver(d, d.cifsMountCommand(hostIP, folder)); err != nil {
+			return fmt.Errorf("unable to mount %s at %s: %s", folder.HostPath, folder.GuestPath, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package hyperv
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCIFSMountCommand(t *testing.T) {
+	driver := NewDriver("default", "path").(*Driver)
+
+	command := driver.cifsMountCommand("192.168.1.10", drivers.SharedFolder{
+		Name:        "c/src",
+		HostPath:    `C:\src`,
+		GuestPath:   "/c/src",
+		ReadOnly:    true,
+		Consistency: drivers.ConsistencyCached,
+	})
+
+	assert.Equal(t, "sudo mkdir -p '/c/src' && (mountpoint -q '/c/src' || sudo mount -t cifs //192.168.1.10/default_c_src '/c/src' -o credentials=/var/lib/boot2docker/smb-credentials,uid=1000,gid=50,cache=loose,ro)", command)
+}
+
+func TestCIFSCache(t *testing.T) {
+	assert.Equal(t, "strict", cifsCache(drivers.ConsistencyConsistent))
+	assert.Equal(t, "strict", cifsCache(""))
+	assert.Equal(t, "loose", cifsCache(drivers.ConsistencyDelegated))
+}
+
+func TestSharePasswordIsntSaved(t *testing.T) {
+	driver := NewDriver("default", "path").(*Driver)
+	driver.SharePassword = "secret"
+
+	data, err := json.Marshal(driver)
+
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+}
@@ -0,0 +1,97 @@
+package hyperv
+
+import (
+	"strings"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// Capabilities adds snapshots, which Hyper-V calls checkpoints, and shared
+// folders to the default capabilities.
+func (d *Driver) Capabilities() []drivers.Capability {
+	return []drivers.Capability{
+		drivers.CapabilityStart,
+		drivers.CapabilityStop,
+		drivers.CapabilityKill,
+		drivers.CapabilitySnapshot,
+		drivers.CapabilitySharedFolders,
+	}
+}
+
+func (d *Driver) CreateSnapshot(name string) error {
+	log.Infof("Taking snapshot %s of %s...", name, d.MachineName)
+	command := []string{
+		"Checkpoint-VM",
+		"-Name", d.MachineName,
+		"-SnapshotName", quote(name)}
+	_, err := execute(command)
+	return err
+}
+
+func (d *Driver) ListSnapshots() ([]drivers.Snapshot, error) {
+	command := []string{
+		"(",
+		"Get-VMSnapshot",
+		"-VMName", d.MachineName,
+		").Name"}
+	stdout, err := execute(command)
+	if err != nil {
+		return nil, err
+	}
+
+	command = []string{
+		"(",
+		"Get-VM",
+		"-Name", d.MachineName,
+		").ParentSnapshotName"}
+	current, err := execute(command)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSnapshots(stdout, strings.TrimSpace(current)), nil
+}
+
+func (d *Driver) RestoreSnapshot(name string) error {
+	log.Infof("Restoring snapshot %s of %s...", name, d.MachineName)
+	command := []string{
+		"Restore-VMSnapshot",
+		"-VMName", d.MachineName,
+		"-Name", quote(name),
+		"-Confirm:$false"}
+	_, err := execute(command)
+	return err
+}
+
+func (d *Driver) DeleteSnapshot(name string) error {
+	log.Infof("Deleting snapshot %s of %s...", name, d.MachineName)
+	command := []string{
+		"Remove-VMSnapshot",
+		"-VMName", d.MachineName,
+		"-Name", quote(name)}
+	_, err := execute(command)
+	return err
+}
+
+// parseSnapshots reads the snapshot names printed one per line by
+// Get-VMSnapshot, current being the name of the snapshot the VM derives from.
+func parseSnapshots(out, current string) []drivers.Snapshot {
+	snapshots := []drivers.Snapshot{}
+
+	for _, name := range parseStdout(out) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		snapshots = append(snapshots, drivers.Snapshot{Name: name, ID: name, Current: name == current})
+	}
+
+	return snapshots
+}
+
+// quote makes a PowerShell string literal of s.
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
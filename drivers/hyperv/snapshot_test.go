@@ -0,0 +1,21 @@
+package hyperv
+
+import (
+	"testing"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSnapshots(t *testing.T) {
+	snapshots := parseSnapshots("clean\r\nwith redis\r\n", "with redis")
+
+	assert.Equal(t, []drivers.Snapshot{
+		{Name: "clean", ID: "clean"},
+		{Name: "with redis", ID: "with redis", Current: true},
+	}, snapshots)
+}
+
+func TestQuote(t *testing.T) {
+	assert.Equal(t, "'it''s clean'", quote("it's clean"))
+}
@@ -0,0 +1,165 @@
+package kvm
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"text/template"
+)
+
+var templateFuncs = template.FuncMap{
+	"xml": func(s string) (string, error) {
+		var buf bytes.Buffer
+		if err := xml.EscapeText(&buf, []byte(s)); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	},
+}
+
+// domainTemplate is the libvirt definition of a machine: it boots the
+// boot2docker ISO, with the disk as its persistent storage, on the NAT
+// network for outgoing traffic and on the host-only network through which
+// docker-machine talks to it.
+var domainTemplate = template.Must(template.New("domain").Funcs(templateFuncs).Parse(`<domain type='kvm'>
+  <name>{{xml .Name}}</name>
+  <memory unit='MiB'>{{.Memory}}</memory>
+  <vcpu>{{.CPU}}</vcpu>
+  <features>
+    <acpi/>
+    <apic/>
+    <pae/>
+  </features>
+  <cpu mode='host-passthrough'/>
+  <os>
+    <type>hvm</type>
+    <boot dev='cdrom'/>
+    <boot dev='hd'/>
+    <bootmenu enable='no'/>
+  </os>
+  <devices>
+    <disk type='file' device='cdrom'>
+      <source file='{{xml .ISO}}'/>
+      <target dev='hdc' bus='ide'/>
+      <readonly/>
+    </disk>
+    <disk type='file' device='disk'>
+      <driver name='qemu' type='raw' cache='default' io='threads'/>
+      <source file='{{xml .Disk}}'/>
+      <target dev='vda' bus='virtio'/>
+    </disk>
+    <interface type='network'>
+      <source network='{{xml .NATNetwork}}'/>
+      <model type='virtio'/>
+    </interface>
+    <interface type='network'>
+      <source network='{{xml .Network}}'/>
+      <model type='virtio'/>
+    </interface>
+{{- if .ShareDir}}
+    <filesystem type='mount' accessmode='mapped'>
+      <source dir='{{xml .ShareDir}}'/>
+      <target dir='{{xml .ShareTag}}'/>
+    </filesystem>
+{{- end}}
+    <serial type='pty'>
+      <target port='0'/>
+    </serial>
+    <console type='pty'>
+      <target type='serial' port='0'/>
+    </console>
+    <rng model='virtio'>
+      <backend model='random'>/dev/random</backend>
+    </rng>
+  </devices>
+</domain>
+`))
+
+// networkTemplate is the libvirt definition of an isolated network, with a
+// DHCP server, reachable from the host only.
+var networkTemplate = template.Must(template.New("network").Funcs(templateFuncs).Parse(`<network>
+  <name>{{xml .Name}}</name>
+  <ip address='{{.Address}}' netmask='{{.Netmask}}'>
+    <dhcp>
+      <range start='{{.DHCPStart}}' end='{{.DHCPEnd}}'/>
+    </dhcp>
+  </ip>
+</network>
+`))
+
+type domainConfig struct {
+	Name       string
+	Memory     int
+	CPU        int
+	ISO        string
+	Disk       string
+	NATNetwork string
+	Network    string
+	ShareDir   string
+	ShareTag   string
+}
+
+type networkConfig struct {
+	Name      string
+	Address   string
+	Netmask   string
+	DHCPStart string
+	DHCPEnd   string
+}
+
+func domainXML(config domainConfig) (string, error) {
+	var buf bytes.Buffer
+	if err := domainTemplate.Execute(&buf, config); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// newNetworkConfig returns the configuration of the network name, whose host
+// address is the IP of cidr. The DHCP server hands out the addresses which
+// follow it in the subnet.
+func newNetworkConfig(name, cidr string) (networkConfig, error) {
+	ip, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return networkConfig{}, err
+	}
+
+	if ip.To4() == nil {
+		return networkConfig{}, fmt.Errorf("%s isn't an IPv4 network", cidr)
+	}
+
+	address := ipToUint32(ip)
+	broadcast := ipToUint32(network.IP) | ^ipToUint32(net.IP(network.Mask))
+
+	if address == ipToUint32(network.IP) || address+1 >= broadcast {
+		return networkConfig{}, fmt.Errorf("%s leaves no address to hand out after %s", cidr, ip)
+	}
+
+	return networkConfig{
+		Name:      name,
+		Address:   ip.String(),
+		Netmask:   net.IP(network.Mask).String(),
+		DHCPStart: uint32ToIP(address + 1).String(),
+		DHCPEnd:   uint32ToIP(broadcast - 1).String(),
+	}, nil
+}
+
+func ipToUint32(ip net.IP) uint32 {
+	ip = ip.To4()
+	return uint32(ip[0])<<24 | uint32(ip[1])<<16 | uint32(ip[2])<<8 | uint32(ip[3])
+}
+
+func uint32ToIP(n uint32) net.IP {
+	return net.IPv4(byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func networkXML(config networkConfig) (string, error) {
+	var buf bytes.Buffer
+	if err := networkTemplate.Execute(&buf, config); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
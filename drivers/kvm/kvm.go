@@ -0,0 +1,488 @@
+package kvm
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/docker/machine/libmachine/mcnutils"
+	"github.com/docker/machine/libmachine/ssh"
+	"github.com/docker/machine/libmachine/state"
+)
+
+const (
+	defaultMemory        = 1024
+	defaultCPU           = 1
+	defaultDiskSize      = 20000
+	defaultConnectionURI = "qemu:///system"
+	defaultNetwork       = "docker-machines"
+	defaultNetworkCIDR   = "192.168.42.1/24"
+	natNetwork           = "default"
+	shareDir             = "/home"
+	shareTag             = "hosthome"
+)
+
+var (
+	errKVMNotAvailable = errors.New("/dev/kvm doesn't exist, make sure the CPU supports virtualization and that the kvm modules are loaded")
+	errIPNotFound      = errors.New("IP address not found, the machine hasn't got a DHCP lease yet")
+
+	// kvmDevice is checked for before creating a machine.
+	kvmDevice = "/dev/kvm"
+
+	// waitInterval is how long to wait between two checks of the machine
+	// while it boots or shuts down.
+	waitInterval = time.Second
+)
+
+// Driver creates boot2docker machines on the local KVM hypervisor, through
+// libvirt.
+type Driver struct {
+	*drivers.BaseDriver
+	ConnectionURI  string
+	Memory         int
+	CPU            int
+	DiskSize       int
+	Boot2DockerURL string
+	Network        string
+	NetworkCIDR    string
+	NoShare        bool
+
+	cmd Virsh
+}
+
+// NewDriver creates a new KVM driver with default settings.
+func NewDriver(hostName, storePath string) *Driver {
+	return &Driver{
+		ConnectionURI: defaultConnectionURI,
+		Memory:        defaultMemory,
+		CPU:           defaultCPU,
+		DiskSize:      defaultDiskSize,
+		Network:       defaultNetwork,
+		NetworkCIDR:   defaultNetworkCIDR,
+		BaseDriver: &drivers.BaseDriver{
+			MachineName: hostName,
+			StorePath:   storePath,
+		},
+	}
+}
+
+// GetCreateFlags registers the flags this driver adds to
+// "docker hosts create"
+func (d *Driver) GetCreateFlags() []mcnflag.Flag {
+	return []mcnflag.Flag{
+		mcnflag.IntFlag{
+			Name:   "kvm-memory",
+			Usage:  "Size of memory for host in MB",
+			Value:  defaultMemory,
+			EnvVar: "KVM_MEMORY_SIZE",
+		},
+		mcnflag.IntFlag{
+			Name:   "kvm-cpu-count",
+			Usage:  "Number of CPUs for the machine",
+			Value:  defaultCPU,
+			EnvVar: "KVM_CPU_COUNT",
+		},
+		mcnflag.IntFlag{
+			Name:   "kvm-disk-size",
+			Usage:  "Size of disk for host in MB",
+			Value:  defaultDiskSize,
+			EnvVar: "KVM_DISK_SIZE",
+		},
+		mcnflag.StringFlag{
+			Name:   "kvm-boot2docker-url",
+			Usage:  "The URL of the boot2docker image. Defaults to the latest available version",
+			Value:  "",
+			EnvVar: "KVM_BOOT2DOCKER_URL",
+		},
+		mcnflag.StringFlag{
+			Name:   "kvm-connection-uri",
+			Usage:  "URI of the libvirt daemon",
+			Value:  defaultConnectionURI,
+			EnvVar: "KVM_CONNECTION_URI",
+		},
+		mcnflag.StringFlag{
+			Name:   "kvm-network",
+			Usage:  "Name of the libvirt host-only network of the machine, created if missing",
+			Value:  defaultNetwork,
+			EnvVar: "KVM_NETWORK",
+		},
+		mcnflag.StringFlag{
+			Name:   "kvm-network-cidr",
+			Usage:  "Host address and subnet of a created host-only network",
+			Value:  defaultNetworkCIDR,
+			EnvVar: "KVM_NETWORK_CIDR",
+		},
+		mcnflag.BoolFlag{
+			Name:   "kvm-no-share",
+			Usage:  "Disable the mount of your home directory",
+			EnvVar: "KVM_NO_SHARE",
+		},
+	}
+}
+
+func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
+	d.Memory = flags.Int("kvm-memory")
+	d.CPU = flags.Int("kvm-cpu-count")
+	d.DiskSize = flags.Int("kvm-disk-size")
+	d.Boot2DockerURL = flags.String("kvm-boot2docker-url")
+	d.ConnectionURI = flags.String("kvm-connection-uri")
+	d.Network = flags.String("kvm-network")
+	d.NetworkCIDR = flags.String("kvm-network-cidr")
+	d.NoShare = flags.Bool("kvm-no-share")
+	d.SwarmMaster = flags.Bool("swarm-master")
+	d.SwarmHost = flags.String("swarm-host")
+	d.SwarmDiscovery = flags.String("swarm-discovery")
+	d.SSHUser = "docker"
+	d.SSHPort = 22
+
+	return nil
+}
+
+// DriverName returns the name of the driver
+func (d *Driver) DriverName() string {
+	return "kvm"
+}
+
+func (d *Driver) virsh() Virsh {
+	if d.cmd == nil {
+		d.cmd = &VirshCmd{URI: d.ConnectionURI}
+	}
+
+	return d.cmd
+}
+
+func (d *Driver) GetSSHHostname() (string, error) {
+	return d.GetIP()
+}
+
+func (d *Driver) GetSSHUsername() string {
+	if d.SSHUser == "" {
+		d.SSHUser = "docker"
+	}
+
+	return d.SSHUser
+}
+
+func (d *Driver) GetURL() (string, error) {
+	ip, err := d.GetIP()
+	if err != nil {
+		return "", err
+	}
+	if ip == "" {
+		return "", nil
+	}
+
+	return fmt.Sprintf("tcp://%s", net.JoinHostPort(ip, "2376")), nil
+}
+
+// GetIP returns the address leased to the machine on the host-only network.
+func (d *Driver) GetIP() (string, error) {
+	s, err := d.GetState()
+	if err != nil {
+		return "", err
+	}
+	if s != state.Running {
+		return "", drivers.ErrHostIsNotRunning
+	}
+
+	out, err := d.virsh().virshOut("domiflist", d.MachineName)
+	if err != nil {
+		return "", err
+	}
+
+	mac := parseInterfaceMAC(out, d.Network)
+	if mac == "" {
+		return "", fmt.Errorf("%s has no interface on the network %s", d.MachineName, d.Network)
+	}
+
+	out, err = d.virsh().virshOut("net-dhcp-leases", d.Network)
+	if err != nil {
+		return "", err
+	}
+
+	ip := parseLeaseIP(out, mac)
+	if ip == "" {
+		return "", errIPNotFound
+	}
+
+	return ip, nil
+}
+
+func (d *Driver) GetState() (state.State, error) {
+	out, err := d.virsh().virshOut("domstate", d.MachineName)
+	if err != nil {
+		return state.Error, err
+	}
+
+	return parseDomainState(out), nil
+}
+
+func (d *Driver) PreCreateCheck() error {
+	if _, err := os.Stat(kvmDevice); err != nil {
+		return errKVMNotAvailable
+	}
+
+	if err := d.virsh().virsh("version"); err != nil {
+		return err
+	}
+
+	if _, err := newNetworkConfig(d.Network, d.NetworkCIDR); err != nil {
+		return fmt.Errorf("Invalid host-only network %s: %s", d.NetworkCIDR, err)
+	}
+
+	return nil
+}
+
+func (d *Driver) Create() error {
+	b2dutils := mcnutils.NewB2dUtils(d.StorePath)
+	if err := b2dutils.CopyIsoToMachineDir(d.Boot2DockerURL, d.MachineName); err != nil {
+		return err
+	}
+
+	log.Infof("Creating SSH key...")
+	if err := ssh.GenerateSSHKey(d.GetSSHKeyPath()); err != nil {
+		return err
+	}
+
+	log.Infof("Creating disk image...")
+	if err := d.generateDiskImage(); err != nil {
+		return err
+	}
+
+	log.Infof("Creating KVM VM...")
+	config := domainConfig{
+		Name:       d.MachineName,
+		Memory:     d.Memory,
+		CPU:        d.CPU,
+		ISO:        d.ResolveStorePath("boot2docker.iso"),
+		Disk:       d.diskPath(),
+		NATNetwork: natNetwork,
+		Network:    d.Network,
+	}
+	if d.shareEnabled() {
+		config.ShareDir = shareDir
+		config.ShareTag = shareTag
+	}
+
+	definition, err := domainXML(config)
+	if err != nil {
+		return err
+	}
+
+	definitionPath := d.ResolveStorePath(d.MachineName + ".xml")
+	if err := ioutil.WriteFile(definitionPath, []byte(definition), 0600); err != nil {
+		return err
+	}
+
+	if err := d.virsh().virsh("define", definitionPath); err != nil {
+		return err
+	}
+
+	log.Infof("Starting KVM VM...")
+	return d.Start()
+}
+
+// setupNetworks makes sure the NAT network is active, and the host-only
+// network exists and is active.
+func (d *Driver) setupNetworks() error {
+	if err := d.startNetwork(natNetwork); err != nil {
+		return fmt.Errorf("The libvirt network %s isn't available: %s", natNetwork, err)
+	}
+
+	if err := d.virsh().virsh("net-info", d.Network); err != nil {
+		log.Infof("Creating the host-only network %s (%s)...", d.Network, d.NetworkCIDR)
+
+		config, err := newNetworkConfig(d.Network, d.NetworkCIDR)
+		if err != nil {
+			return err
+		}
+
+		definition, err := networkXML(config)
+		if err != nil {
+			return err
+		}
+
+		definitionPath := d.ResolveStorePath(d.Network + "-network.xml")
+		if err := ioutil.WriteFile(definitionPath, []byte(definition), 0600); err != nil {
+			return err
+		}
+
+		if err := d.virsh().virsh("net-define", definitionPath); err != nil {
+			return err
+		}
+
+		if err := d.virsh().virsh("net-autostart", d.Network); err != nil {
+			return err
+		}
+	}
+
+	return d.startNetwork(d.Network)
+}
+
+// startNetwork starts the network unless it is active already.
+func (d *Driver) startNetwork(name string) error {
+	out, err := d.virsh().virshOut("net-info", name)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "Active:" && fields[1] == "yes" {
+			return nil
+		}
+	}
+
+	return d.virsh().virsh("net-start", name)
+}
+
+func (d *Driver) Start() error {
+	if err := d.setupNetworks(); err != nil {
+		return err
+	}
+
+	if err := d.virsh().virsh("start", d.MachineName); err != nil {
+		return err
+	}
+
+	log.Infof("Waiting for an IP...")
+	if err := mcnutils.WaitForSpecific(func() bool {
+		ip, err := d.GetIP()
+		if err != nil {
+			log.Debugf("Waiting for the IP of %s: %s", d.MachineName, err)
+			return false
+		}
+		d.IPAddress = ip
+		return true
+	}, 120, waitInterval); err != nil {
+		return fmt.Errorf("Error waiting for the IP of %s: %s", d.MachineName, err)
+	}
+
+	if d.shareEnabled() {
+		return d.mountShare()
+	}
+
+	return nil
+}
+
+// mountShare mounts the home directories shared through 9p.
+func (d *Driver) mountShare() error {
+	if err := drivers.WaitForSSH(d); err != nil {
+		return err
+	}
+
+	log.Debugf("Mounting %s...", shareDir)
+	command := fmt.Sprintf("sudo mkdir -p %s && sudo mount -t 9p -o trans=virtio,version=9p2000.L %s %s", shareDir, shareTag, shareDir)
+	if _, err := drivers.RunSSHCommandFromDriver(d, command); err != nil {
+		return fmt.Errorf("Error mounting %s: %s", shareDir, err)
+	}
+
+	return nil
+}
+
+func (d *Driver) shareEnabled() bool {
+	if d.NoShare {
+		return false
+	}
+
+	_, err := os.Stat(shareDir)
+	return err == nil
+}
+
+func (d *Driver) Stop() error {
+	if err := d.virsh().virsh("shutdown", d.MachineName); err != nil {
+		return err
+	}
+
+	if err := d.waitForState(state.Stopped); err != nil {
+		return err
+	}
+
+	d.IPAddress = ""
+	return nil
+}
+
+func (d *Driver) Kill() error {
+	if err := d.virsh().virsh("destroy", d.MachineName); err != nil {
+		return err
+	}
+
+	if err := d.waitForState(state.Stopped); err != nil {
+		return err
+	}
+
+	d.IPAddress = ""
+	return nil
+}
+
+func (d *Driver) Restart() error {
+	s, err := d.GetState()
+	if err != nil {
+		return err
+	}
+
+	if s == state.Running {
+		if err := d.Stop(); err != nil {
+			return err
+		}
+	}
+
+	return d.Start()
+}
+
+func (d *Driver) Remove() error {
+	out, err := d.virsh().virshOut("list", "--all", "--name")
+	if err != nil {
+		return err
+	}
+
+	if !containsLine(out, d.MachineName) {
+		log.Debugf("The domain %s doesn't exist, there's nothing to remove", d.MachineName)
+		return nil
+	}
+
+	s, err := d.GetState()
+	if err != nil {
+		return err
+	}
+
+	if s == state.Running || s == state.Paused {
+		if err := d.Kill(); err != nil {
+			return err
+		}
+	}
+
+	return d.virsh().virsh("undefine", d.MachineName)
+}
+
+func (d *Driver) waitForState(expected state.State) error {
+	return mcnutils.WaitForSpecific(func() bool {
+		s, err := d.GetState()
+		if err != nil {
+			log.Debugf("Error getting the state of %s: %s", d.MachineName, err)
+			return false
+		}
+		return s == expected
+	}, 120, waitInterval)
+}
+
+func (d *Driver) diskPath() string {
+	return d.ResolveStorePath("disk.raw")
+}
+
+func (d *Driver) publicSSHKeyPath() string {
+	return d.GetSSHKeyPath() + ".pub"
+}
+
+// generateDiskImage creates the sparse disk of the machine, which tells
+// boot2docker to format it and install the SSH key.
+func (d *Driver) generateDiskImage() error {
+	return mcnutils.MakeB2dDiskImage(d.diskPath(), d.publicSSHKeyPath(), d.DiskSize)
+}
@@ -0,0 +1,293 @@
+package kvm
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	stdOutDomIfList = ` Interface  Type       Source     Model       MAC
+-------------------------------------------------------
+ vnet0      network    default    virtio      52:54:00:aa:bb:01
+ vnet1      network    docker-machines virtio      52:54:00:aa:bb:02
+`
+
+	stdOutDHCPLeases = ` Expiry Time          MAC address        Protocol  IP address                Hostname        Client ID or DUID
+-------------------------------------------------------------------------------------------------------------------
+ 2016-01-25 15:23:12  52:54:00:aa:bb:02  ipv4      192.168.42.17/24          boot2docker     -
+ 2016-01-25 15:20:01  52:54:00:aa:bb:03  ipv4      192.168.42.18/24          boot2docker     -
+`
+
+	stdOutNetInfoInactive = `Name:           docker-machines
+UUID:           7a1d5e8c-3b55-4a5c-9d8b-0c9c1c0e2f11
+Active:         no
+Persistent:     yes
+Autostart:      yes
+Bridge:         virbr1
+`
+)
+
+// virshScript answers the virsh commands with canned outputs, by command
+// line, and records them. The outputs and errors of a command are given in
+// the order of its calls, the last one answering any further call.
+type virshScript struct {
+	stdOut map[string][]string
+	errs   map[string][]error
+	calls  []string
+}
+
+func (v *virshScript) virsh(args ...string) error {
+	_, err := v.virshOut(args...)
+	return err
+}
+
+func (v *virshScript) virshOut(args ...string) (string, error) {
+	command := strings.Join(args, " ")
+	v.calls = append(v.calls, command)
+
+	return next(v.stdOut, command), nextErr(v.errs, command)
+}
+
+func next(answers map[string][]string, command string) string {
+	values := answers[command]
+	if len(values) == 0 {
+		return ""
+	}
+	if len(values) > 1 {
+		answers[command] = values[1:]
+	}
+	return values[0]
+}
+
+func nextErr(answers map[string][]error, command string) error {
+	values := answers[command]
+	if len(values) == 0 {
+		return nil
+	}
+	if len(values) > 1 {
+		answers[command] = values[1:]
+	}
+	return values[0]
+}
+
+func newTestDriver(script *virshScript) *Driver {
+	d := NewDriver("default", "path")
+	d.cmd = script
+	return d
+}
+
+func TestSetConfigFromFlags(t *testing.T) {
+	driver := NewDriver("default", "path")
+
+	checkFlags := &drivers.CheckDriverOptions{
+		FlagsValues: map[string]interface{}{},
+		CreateFlags: driver.GetCreateFlags(),
+	}
+
+	err := driver.SetConfigFromFlags(checkFlags)
+
+	assert.NoError(t, err)
+	assert.Empty(t, checkFlags.InvalidFlags)
+}
+
+func TestParseDomainState(t *testing.T) {
+	assert.Equal(t, state.Running, parseDomainState("running\n\n"))
+	assert.Equal(t, state.Stopped, parseDomainState("shut off\n"))
+	assert.Equal(t, state.Paused, parseDomainState("paused\n"))
+	assert.Equal(t, state.Stopping, parseDomainState("in shutdown\n"))
+	assert.Equal(t, state.Error, parseDomainState("crashed\n"))
+	assert.Equal(t, state.None, parseDomainState("no state\n"))
+}
+
+func TestParseInterfaceMAC(t *testing.T) {
+	assert.Equal(t, "52:54:00:aa:bb:02", parseInterfaceMAC(stdOutDomIfList, "docker-machines"))
+	assert.Equal(t, "52:54:00:aa:bb:01", parseInterfaceMAC(stdOutDomIfList, "default"))
+	assert.Empty(t, parseInterfaceMAC(stdOutDomIfList, "other"))
+}
+
+func TestParseLeaseIP(t *testing.T) {
+	assert.Equal(t, "192.168.42.17", parseLeaseIP(stdOutDHCPLeases, "52:54:00:AA:BB:02"))
+	assert.Empty(t, parseLeaseIP(stdOutDHCPLeases, "52:54:00:aa:bb:04"))
+}
+
+func TestNewNetworkConfig(t *testing.T) {
+	config, err := newNetworkConfig("docker-machines", "192.168.42.1/24")
+
+	assert.NoError(t, err)
+	assert.Equal(t, networkConfig{
+		Name:      "docker-machines",
+		Address:   "192.168.42.1",
+		Netmask:   "255.255.255.0",
+		DHCPStart: "192.168.42.2",
+		DHCPEnd:   "192.168.42.254",
+	}, config)
+}
+
+func TestNewNetworkConfigErrors(t *testing.T) {
+	for _, cidr := range []string{"192.168.42.1", "192.168.42.0/24", "192.168.42.254/24", "fd00::1/64"} {
+		_, err := newNetworkConfig("docker-machines", cidr)
+		assert.Error(t, err, cidr)
+	}
+}
+
+func TestNetworkXML(t *testing.T) {
+	config, err := newNetworkConfig("docker-machines", "10.1.0.1/16")
+	assert.NoError(t, err)
+
+	xml, err := networkXML(config)
+
+	assert.NoError(t, err)
+	assert.Contains(t, xml, "<name>docker-machines</name>")
+	assert.Contains(t, xml, "<ip address='10.1.0.1' netmask='255.255.0.0'>")
+	assert.Contains(t, xml, "<range start='10.1.0.2' end='10.1.255.254'/>")
+	assert.NotContains(t, xml, "<forward")
+}
+
+func TestDomainXML(t *testing.T) {
+	config := domainConfig{
+		Name:       "dev",
+		Memory:     2048,
+		CPU:        2,
+		ISO:        "/store/machines/dev/boot2docker.iso",
+		Disk:       "/store/machines/dev/disk.raw",
+		NATNetwork: "default",
+		Network:    "docker-machines",
+	}
+
+	xml, err := domainXML(config)
+
+	assert.NoError(t, err)
+	assert.Contains(t, xml, "<name>dev</name>")
+	assert.Contains(t, xml, "<memory unit='MiB'>2048</memory>")
+	assert.Contains(t, xml, "<vcpu>2</vcpu>")
+	assert.Contains(t, xml, "<source file='/store/machines/dev/boot2docker.iso'/>")
+	assert.Contains(t, xml, "<source network='docker-machines'/>")
+	assert.NotContains(t, xml, "<filesystem")
+
+	config.ShareDir = "/home"
+	config.ShareTag = "hosthome"
+	config.Disk = "/store/it's & here/disk.raw"
+
+	xml, err = domainXML(config)
+
+	assert.NoError(t, err)
+	assert.Contains(t, xml, "<source dir='/home'/>")
+	assert.Contains(t, xml, "<target dir='hosthome'/>")
+	assert.Contains(t, xml, "<source file='/store/it&#39;s &amp; here/disk.raw'/>")
+}
+
+func TestGetIP(t *testing.T) {
+	d := newTestDriver(&virshScript{
+		stdOut: map[string][]string{
+			"domstate default":                {"running\n"},
+			"domiflist default":               {stdOutDomIfList},
+			"net-dhcp-leases docker-machines": {stdOutDHCPLeases},
+		},
+	})
+
+	ip, err := d.GetIP()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.42.17", ip)
+}
+
+func TestGetIPNotRunning(t *testing.T) {
+	d := newTestDriver(&virshScript{
+		stdOut: map[string][]string{
+			"domstate default": {"shut off\n"},
+		},
+	})
+
+	_, err := d.GetIP()
+
+	assert.Equal(t, drivers.ErrHostIsNotRunning, err)
+}
+
+func TestGetIPWithoutLease(t *testing.T) {
+	d := newTestDriver(&virshScript{
+		stdOut: map[string][]string{
+			"domstate default":  {"running\n"},
+			"domiflist default": {stdOutDomIfList},
+		},
+	})
+
+	_, err := d.GetIP()
+
+	assert.Equal(t, errIPNotFound, err)
+}
+
+func TestSetupNetworksCreatesMissingNetwork(t *testing.T) {
+	storePath, err := ioutil.TempDir("", "kvm-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(storePath)
+
+	script := &virshScript{
+		stdOut: map[string][]string{
+			"net-info default":         {"Name: default\nActive:         yes\n"},
+			"net-info docker-machines": {"", stdOutNetInfoInactive},
+		},
+		errs: map[string][]error{
+			"net-info docker-machines": {errors.New("failed to get network 'docker-machines'"), nil},
+		},
+	}
+	d := newTestDriver(script)
+	d.StorePath = storePath
+	assert.NoError(t, os.MkdirAll(d.ResolveStorePath("."), 0700))
+
+	err = d.setupNetworks()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"net-info default",
+		"net-info docker-machines",
+		"net-define " + filepath.Join(storePath, "machines", "default", "docker-machines-network.xml"),
+		"net-autostart docker-machines",
+		"net-info docker-machines",
+		"net-start docker-machines",
+	}, script.calls)
+}
+
+func TestStartNetworkStartsInactiveNetwork(t *testing.T) {
+	script := &virshScript{
+		stdOut: map[string][]string{
+			"net-info docker-machines": {stdOutNetInfoInactive},
+		},
+	}
+	d := newTestDriver(script)
+
+	assert.NoError(t, d.startNetwork("docker-machines"))
+	assert.Equal(t, []string{"net-info docker-machines", "net-start docker-machines"}, script.calls)
+}
+
+func TestRemoveMissingDomain(t *testing.T) {
+	script := &virshScript{
+		stdOut: map[string][]string{
+			"list --all --name": {"other\n\n"},
+		},
+	}
+	d := newTestDriver(script)
+
+	assert.NoError(t, d.Remove())
+	assert.Equal(t, []string{"list --all --name"}, script.calls)
+}
+
+func TestRemoveRunningDomain(t *testing.T) {
+	script := &virshScript{
+		stdOut: map[string][]string{
+			"list --all --name": {"default\n\n"},
+			"domstate default":  {"running\n", "shut off\n"},
+		},
+	}
+	d := newTestDriver(script)
+
+	assert.NoError(t, d.Remove())
+	assert.Equal(t, []string{"list --all --name", "domstate default", "destroy default", "domstate default", "undefine default"}, script.calls)
+}
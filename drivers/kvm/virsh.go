@@ -0,0 +1,119 @@
+package kvm
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/state"
+)
+
+var (
+	errVirshNotFound = errors.New("virsh not found, make sure libvirt is installed")
+)
+
+// Virsh runs the commands of virsh, the libvirt command line.
+type Virsh interface {
+	virsh(args ...string) error
+	virshOut(args ...string) (string, error)
+}
+
+// VirshCmd runs the virsh binary against the libvirt daemon at URI.
+type VirshCmd struct {
+	URI string
+}
+
+func (v *VirshCmd) virsh(args ...string) error {
+	_, err := v.virshOut(args...)
+	return err
+}
+
+func (v *VirshCmd) virshOut(args ...string) (string, error) {
+	path, err := exec.LookPath("virsh")
+	if err != nil {
+		return "", errVirshNotFound
+	}
+
+	if v.URI != "" {
+		args = append([]string{"--connect", v.URI}, args...)
+	}
+
+	cmd := exec.Command(path, args...)
+	log.Debugf("COMMAND: %v %v", path, strings.Join(args, " "))
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	log.Debugf("STDOUT:\n{\n%v}", stdout.String())
+	log.Debugf("STDERR:\n{\n%v}", stderr.String())
+	if err != nil {
+		return stdout.String(), fmt.Errorf("%v %v failed: %s", path, strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
+
+// parseDomainState turns the output of virsh domstate into a state.
+func parseDomainState(out string) state.State {
+	switch strings.TrimSpace(out) {
+	case "running", "idle":
+		return state.Running
+	case "paused", "pmsuspended":
+		return state.Paused
+	case "in shutdown":
+		return state.Stopping
+	case "shut off":
+		return state.Stopped
+	case "crashed", "dying":
+		return state.Error
+	}
+
+	return state.None
+}
+
+// parseInterfaceMAC returns the MAC address of the interface of a domain
+// which is attached to network, from the output of virsh domiflist.
+func parseInterfaceMAC(out, network string) string {
+	s := bufio.NewScanner(strings.NewReader(out))
+	for s.Scan() {
+		// Interface  Type  Source  Model  MAC
+		fields := strings.Fields(s.Text())
+		if len(fields) == 5 && fields[1] == "network" && fields[2] == network {
+			return fields[4]
+		}
+	}
+
+	return ""
+}
+
+// parseLeaseIP returns the IPv4 address leased to mac, from the output of
+// virsh net-dhcp-leases.
+func parseLeaseIP(out, mac string) string {
+	s := bufio.NewScanner(strings.NewReader(out))
+	for s.Scan() {
+		// Expiry date (2 fields)  MAC  Protocol  IP/prefix  Hostname  Client ID
+		fields := strings.Fields(s.Text())
+		if len(fields) >= 5 && strings.EqualFold(fields[2], mac) && fields[3] == "ipv4" {
+			return strings.SplitN(fields[4], "/", 2)[0]
+		}
+	}
+
+	return ""
+}
+
+// containsLine reports whether one of the lines of out is line.
+func containsLine(out, line string) bool {
+	for _, l := range strings.Split(out, "\n") {
+		if strings.TrimSpace(l) == line {
+			return true
+		}
+	}
+
+	return false
+}
@@ -75,6 +75,12 @@ func (d *Driver) GetState() (state.State, error) {
 	return state.Running, nil
 }
 
+// Capabilities returns no capabilities, hosts without a driver can't be
+// managed.
+func (d *Driver) Capabilities() []drivers.Capability {
+	return []drivers.Capability{}
+}
+
 func (d *Driver) Kill() error {
 	return fmt.Errorf("hosts without a driver cannot be killed")
 }
@@ -60,6 +60,7 @@ func (c *GenericClient) CreateInstance(d *Driver) (string, error) {
 		ImageRef:         d.ImageId,
 		SecurityGroups:   d.SecurityGroups,
 		AvailabilityZone: d.AvailabilityZone,
+		UserData:         d.userData,
 	}
 	if d.NetworkId != "" {
 		serverOpts.Networks = []servers.Network{
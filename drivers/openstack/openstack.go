@@ -42,6 +42,8 @@ type Driver struct {
 	ComputeNetwork   bool
 	FloatingIpPoolId string
 	IpVersion        int
+	UserDataFile     string
+	userData         []byte
 	client           Client
 }
 
@@ -189,12 +191,18 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Usage:  "OpenStack SSH port",
 			Value:  defaultSSHPort,
 		},
+		mcnflag.StringFlag{
+			EnvVar: "OS_SSH_PROXY_JUMP",
+			Name:   "openstack-ssh-proxy-jump",
+			Usage:  "Bastion to reach the instance through with SSH, [user@]host[:port]",
+		},
 		mcnflag.IntFlag{
 			EnvVar: "OS_ACTIVE_TIMEOUT",
 			Name:   "openstack-active-timeout",
 			Usage:  "OpenStack active timeout",
 			Value:  defaultActiveTimeout,
 		},
+		drivers.UserDataFlag,
 	}
 }
 
@@ -252,8 +260,10 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	}
 	d.FloatingIpPool = flags.String("openstack-floatingip-pool")
 	d.IpVersion = flags.Int("openstack-ip-version")
+	d.UserDataFile = flags.String(drivers.UserDataFlag.Name)
 	d.ComputeNetwork = flags.Bool("openstack-nova-network")
 	d.SSHUser = flags.String("openstack-ssh-user")
+	d.SSHProxyJump = flags.String("openstack-ssh-proxy-jump")
 	d.SSHPort = flags.Int("openstack-ssh-port")
 	d.SwarmMaster = flags.Bool("swarm-master")
 	d.SwarmHost = flags.String("swarm-host")
@@ -339,6 +349,14 @@ func (d *Driver) GetState() (state.State, error) {
 }
 
 func (d *Driver) Create() error {
+	userData, err := drivers.ReadUserData(d.UserDataFile)
+	if err != nil {
+		return err
+	}
+	if userData != "" {
+		d.userData = []byte(userData)
+	}
+
 	d.KeyPairName = fmt.Sprintf("%s-%s", d.MachineName, mcnutils.GenerateRandomID())
 
 	if err := d.resolveIds(); err != nil {
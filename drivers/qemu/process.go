@@ -0,0 +1,209 @@
+package qemu
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	accelAuto = "auto"
+	accelKVM  = "kvm"
+	accelHVF  = "hvf"
+	accelTCG  = "tcg"
+)
+
+var (
+	errQemuNotFound = errors.New("qemu not found, make sure QEMU is installed or give its path with --qemu-program")
+
+	// kvmDevice is checked for to use KVM acceleration on Linux.
+	kvmDevice = "/dev/kvm"
+
+	// hvfSupported reports whether the Hypervisor framework of macOS is
+	// available. Tests replace it.
+	hvfSupported = func() bool {
+		out, err := exec.Command("sysctl", "-n", "kern.hv_support").Output()
+		return err == nil && strings.TrimSpace(string(out)) == "1"
+	}
+)
+
+// detectAccel returns the accelerator to use on goos when asked for auto:
+// KVM on Linux, the Hypervisor framework on macOS, or software emulation
+// when neither is available.
+func detectAccel(goos string) string {
+	switch goos {
+	case "linux":
+		if _, err := os.Stat(kvmDevice); err == nil {
+			return accelKVM
+		}
+	case "darwin":
+		if hvfSupported() {
+			return accelHVF
+		}
+	}
+
+	return accelTCG
+}
+
+// qemuConfig is what the command line of the machine is built from.
+type qemuConfig struct {
+	Name       string
+	Memory     int
+	CPU        int
+	Accel      string
+	ISO        string
+	Disk       string
+	SSHPort    int
+	EnginePort int
+	PidFile    string
+	Monitor    string
+}
+
+// qemuArgs returns the arguments of qemu-system booting the machine in the
+// background. The machine has a user-mode network interface, the SSH and
+// Docker ports of which are forwarded to ports of the loopback of the host.
+func qemuArgs(config qemuConfig) []string {
+	args := []string{
+		"-name", config.Name,
+		"-machine", "accel=" + config.Accel,
+		"-m", strconv.Itoa(config.Memory),
+		"-smp", strconv.Itoa(config.CPU),
+	}
+
+	if config.Accel != accelTCG {
+		args = append(args, "-cpu", "host")
+	}
+
+	hostfwd := fmt.Sprintf("hostfwd=tcp:127.0.0.1:%d-:22,hostfwd=tcp:127.0.0.1:%d-:2376", config.SSHPort, config.EnginePort)
+
+	return append(args,
+		"-boot", "d",
+		"-cdrom", config.ISO,
+		"-drive", "file="+config.Disk+",if=virtio,format=raw",
+		"-netdev", "user,id=net0,"+hostfwd,
+		"-device", "virtio-net-pci,netdev=net0",
+		"-display", "none",
+		"-monitor", "unix:"+config.Monitor+",server,nowait",
+		"-pidfile", config.PidFile,
+		"-daemonize",
+	)
+}
+
+// lookQemu returns the path of program.
+func lookQemu(program string) (string, error) {
+	path, err := exec.LookPath(program)
+	if err != nil {
+		return "", errQemuNotFound
+	}
+
+	return path, nil
+}
+
+// runQemu runs program, which daemonizes once the machine is started.
+func runQemu(program string, args ...string) error {
+	path, err := lookQemu(program)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(path, args...)
+	log.Debugf("COMMAND: %v %v", path, strings.Join(args, " "))
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	log.Debugf("STDOUT:\n{\n%v}", stdout.String())
+	log.Debugf("STDERR:\n{\n%v}", stderr.String())
+	if err != nil {
+		return fmt.Errorf("%v failed: %s", path, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// readPid returns the process of the machine, written by qemu to pidFile,
+// or nil if it isn't running. The pid file is left behind when qemu is
+// killed, and its pid may have been reused since, so the process is only
+// taken for qemu while the monitor of the machine accepts connections.
+func readPid(pidFile, monitor string) (*os.Process, error) {
+	content, err := ioutil.ReadFile(pidFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid pid file %s: %s", pidFile, err)
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return nil, nil
+	}
+
+	if err := process.Signal(syscall.Signal(0)); err != nil {
+		return nil, nil
+	}
+
+	conn, err := net.DialTimeout("unix", monitor, 5*time.Second)
+	if err != nil {
+		log.Debugf("The monitor %s doesn't answer, process %d isn't qemu: %s", monitor, pid, err)
+		return nil, nil
+	}
+	conn.Close()
+
+	return process, nil
+}
+
+// sendMonitorCommand sends a command to the monitor of the machine.
+func sendMonitorCommand(monitor, command string) error {
+	conn, err := net.DialTimeout("unix", monitor, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = fmt.Fprintf(conn, "%s\n", command)
+	return err
+}
+
+// freePorts returns n distinct ports of the loopback of the host nothing
+// listens on. Another process may listen on them before qemu does, which
+// makes qemu fail to start with a hostForwardError.
+func freePorts(n int) ([]int, error) {
+	ports := []int{}
+	for i := 0; i < n; i++ {
+		// The listeners are kept open until all the ports are picked so
+		// that they differ.
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, err
+		}
+		defer listener.Close()
+
+		ports = append(ports, listener.Addr().(*net.TCPAddr).Port)
+	}
+
+	return ports, nil
+}
+
+// isHostForwardError tells whether qemu failed to start because it couldn't
+// listen on a forwarded port.
+func isHostForwardError(err error) bool {
+	return strings.Contains(err.Error(), "host forwarding rule")
+}
@@ -0,0 +1,437 @@
+package qemu
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/docker/machine/libmachine/mcnutils"
+	"github.com/docker/machine/libmachine/ssh"
+	"github.com/docker/machine/libmachine/state"
+)
+
+const (
+	defaultMemory   = 1024
+	defaultCPU      = 1
+	defaultDiskSize = 20000
+	defaultProgram  = "qemu-system-x86_64"
+
+	// localhost is where the forwarded ports of the machine listen.
+	localhost = "127.0.0.1"
+
+	// maxPortPicks is how many times the forwarded ports which were picked
+	// are picked again when they are taken as the machine starts.
+	maxPortPicks = 3
+)
+
+var (
+	// waitInterval is how long to wait between two checks of the machine
+	// while it shuts down.
+	waitInterval = time.Second
+)
+
+// Driver creates boot2docker machines with QEMU, on hosts without another
+// hypervisor. The machines use user-mode networking, so they don't need any
+// privilege, and are reached through ports forwarded from the loopback of
+// the host.
+type Driver struct {
+	*drivers.BaseDriver
+	Memory         int
+	CPU            int
+	DiskSize       int
+	Boot2DockerURL string
+	Program        string
+	Accel          string
+	EnginePort     int
+
+	// PickedSSHPort and PickedEnginePort tell that the forwarded ports
+	// weren't given but picked among the free ones, so that others are
+	// picked if they're taken when the machine starts.
+	PickedSSHPort    bool
+	PickedEnginePort bool
+
+	// run and goos are replaced by tests.
+	run  func(program string, args ...string) error
+	goos string
+}
+
+// NewDriver creates a new QEMU driver with default settings.
+func NewDriver(hostName, storePath string) *Driver {
+	return &Driver{
+		Memory:   defaultMemory,
+		CPU:      defaultCPU,
+		DiskSize: defaultDiskSize,
+		Program:  defaultProgram,
+		Accel:    accelAuto,
+		BaseDriver: &drivers.BaseDriver{
+			MachineName: hostName,
+			StorePath:   storePath,
+		},
+	}
+}
+
+// GetCreateFlags registers the flags this driver adds to
+// "docker hosts create"
+func (d *Driver) GetCreateFlags() []mcnflag.Flag {
+	return []mcnflag.Flag{
+		mcnflag.IntFlag{
+			Name:   "qemu-memory",
+			Usage:  "Size of memory for host in MB",
+			Value:  defaultMemory,
+			EnvVar: "QEMU_MEMORY_SIZE",
+		},
+		mcnflag.IntFlag{
+			Name:   "qemu-cpu-count",
+			Usage:  "Number of CPUs for the machine",
+			Value:  defaultCPU,
+			EnvVar: "QEMU_CPU_COUNT",
+		},
+		mcnflag.IntFlag{
+			Name:   "qemu-disk-size",
+			Usage:  "Size of disk for host in MB",
+			Value:  defaultDiskSize,
+			EnvVar: "QEMU_DISK_SIZE",
+		},
+		mcnflag.StringFlag{
+			Name:   "qemu-boot2docker-url",
+			Usage:  "The URL of the boot2docker image. Defaults to the latest available version",
+			Value:  "",
+			EnvVar: "QEMU_BOOT2DOCKER_URL",
+		},
+		mcnflag.StringFlag{
+			Name:   "qemu-program",
+			Usage:  "Name or path of the qemu-system binary",
+			Value:  defaultProgram,
+			EnvVar: "QEMU_PROGRAM",
+		},
+		mcnflag.StringFlag{
+			Name:   "qemu-accel",
+			Usage:  "Accelerator of the machine: kvm, hvf, tcg, or auto to use kvm or hvf when available",
+			Value:  accelAuto,
+			EnvVar: "QEMU_ACCEL",
+		},
+		mcnflag.IntFlag{
+			Name:   "qemu-ssh-port",
+			Usage:  "Port of the host forwarded to the SSH port of the machine, a free one if 0",
+			EnvVar: "QEMU_SSH_PORT",
+		},
+		mcnflag.IntFlag{
+			Name:   "qemu-engine-port",
+			Usage:  "Port of the host forwarded to the Docker port of the machine, a free one if 0",
+			EnvVar: "QEMU_ENGINE_PORT",
+		},
+	}
+}
+
+func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
+	d.Memory = flags.Int("qemu-memory")
+	d.CPU = flags.Int("qemu-cpu-count")
+	d.DiskSize = flags.Int("qemu-disk-size")
+	d.Boot2DockerURL = flags.String("qemu-boot2docker-url")
+	d.Program = flags.String("qemu-program")
+	d.Accel = flags.String("qemu-accel")
+	d.SSHPort = flags.Int("qemu-ssh-port")
+	d.EnginePort = flags.Int("qemu-engine-port")
+	d.SwarmMaster = flags.Bool("swarm-master")
+	d.SwarmHost = flags.String("swarm-host")
+	d.SwarmDiscovery = flags.String("swarm-discovery")
+	d.SSHUser = "docker"
+
+	switch d.Accel {
+	case "":
+		d.Accel = accelAuto
+	case accelAuto, accelKVM, accelHVF, accelTCG:
+	default:
+		return fmt.Errorf("Invalid accelerator %q, expected auto, kvm, hvf or tcg", d.Accel)
+	}
+
+	return nil
+}
+
+// DriverName returns the name of the driver
+func (d *Driver) DriverName() string {
+	return "qemu"
+}
+
+func (d *Driver) runProgram(args ...string) error {
+	if d.run == nil {
+		d.run = runQemu
+	}
+
+	return d.run(d.Program, args...)
+}
+
+func (d *Driver) hostOS() string {
+	if d.goos == "" {
+		return runtime.GOOS
+	}
+
+	return d.goos
+}
+
+func (d *Driver) GetSSHHostname() (string, error) {
+	return localhost, nil
+}
+
+func (d *Driver) GetSSHUsername() string {
+	if d.SSHUser == "" {
+		d.SSHUser = "docker"
+	}
+
+	return d.SSHUser
+}
+
+func (d *Driver) GetURL() (string, error) {
+	s, err := d.GetState()
+	if err != nil {
+		return "", err
+	}
+	if s != state.Running {
+		return "", drivers.ErrHostIsNotRunning
+	}
+
+	return fmt.Sprintf("tcp://%s", net.JoinHostPort(localhost, strconv.Itoa(d.EnginePort))), nil
+}
+
+// GetIP returns the loopback of the host, the ports of the machine being
+// forwarded there.
+func (d *Driver) GetIP() (string, error) {
+	s, err := d.GetState()
+	if err != nil {
+		return "", err
+	}
+	if s != state.Running {
+		return "", drivers.ErrHostIsNotRunning
+	}
+
+	return localhost, nil
+}
+
+func (d *Driver) GetState() (state.State, error) {
+	process, err := readPid(d.pidFile(), d.monitorPath())
+	if err != nil {
+		return state.Error, err
+	}
+	if process == nil {
+		return state.Stopped, nil
+	}
+
+	return state.Running, nil
+}
+
+func (d *Driver) PreCreateCheck() error {
+	if _, err := lookQemu(d.Program); err != nil {
+		return err
+	}
+
+	if d.Accel == accelAuto {
+		d.Accel = detectAccel(d.hostOS())
+		if d.Accel == accelTCG {
+			log.Warn("No hardware acceleration is available, the machine will be slow")
+		}
+	}
+
+	return d.pickPorts()
+}
+
+// pickPorts picks the forwarded ports which weren't given.
+func (d *Driver) pickPorts() error {
+	ports := []*int{}
+	if d.SSHPort == 0 {
+		ports = append(ports, &d.SSHPort)
+		d.PickedSSHPort = true
+	}
+	if d.EnginePort == 0 {
+		ports = append(ports, &d.EnginePort)
+		d.PickedEnginePort = true
+	}
+
+	free, err := freePorts(len(ports))
+	if err != nil {
+		return err
+	}
+	for i, port := range ports {
+		*port = free[i]
+	}
+
+	if d.SSHPort == d.EnginePort {
+		return fmt.Errorf("The SSH and Docker ports of the machine can't both be forwarded to port %d", d.SSHPort)
+	}
+
+	return nil
+}
+
+// repickPorts picks other free ports for the forwarded ports which were
+// picked. It tells whether there were.
+func (d *Driver) repickPorts() (bool, error) {
+	if !d.PickedSSHPort && !d.PickedEnginePort {
+		return false, nil
+	}
+
+	if d.PickedSSHPort {
+		d.SSHPort = 0
+	}
+	if d.PickedEnginePort {
+		d.EnginePort = 0
+	}
+
+	return true, d.pickPorts()
+}
+
+func (d *Driver) Create() error {
+	b2dutils := mcnutils.NewB2dUtils(d.StorePath)
+	if err := b2dutils.CopyIsoToMachineDir(d.Boot2DockerURL, d.MachineName); err != nil {
+		return err
+	}
+
+	log.Infof("Creating SSH key...")
+	if err := ssh.GenerateSSHKey(d.GetSSHKeyPath()); err != nil {
+		return err
+	}
+
+	log.Infof("Creating disk image...")
+	if err := d.generateDiskImage(); err != nil {
+		return err
+	}
+
+	log.Infof("Starting QEMU VM...")
+	return d.Start()
+}
+
+func (d *Driver) config() qemuConfig {
+	return qemuConfig{
+		Name:       d.MachineName,
+		Memory:     d.Memory,
+		CPU:        d.CPU,
+		Accel:      d.Accel,
+		ISO:        d.ResolveStorePath("boot2docker.iso"),
+		Disk:       d.diskPath(),
+		SSHPort:    d.SSHPort,
+		EnginePort: d.EnginePort,
+		PidFile:    d.pidFile(),
+		Monitor:    d.monitorPath(),
+	}
+}
+
+func (d *Driver) Start() error {
+	s, err := d.GetState()
+	if err != nil {
+		return err
+	}
+	if s == state.Running {
+		log.Infof("%s is already running", d.MachineName)
+		return nil
+	}
+
+	d.IPAddress = localhost
+	err = d.runProgram(qemuArgs(d.config())...)
+
+	// Another process may have taken the ports picked for the machine since.
+	for i := 0; i < maxPortPicks && err != nil && isHostForwardError(err); i++ {
+		repicked, pickErr := d.repickPorts()
+		if pickErr != nil {
+			return pickErr
+		}
+		if !repicked {
+			break
+		}
+
+		log.Infof("A port forwarded to %s is taken, forwarding ports %d and %d instead...", d.MachineName, d.SSHPort, d.EnginePort)
+		err = d.runProgram(qemuArgs(d.config())...)
+	}
+
+	return err
+}
+
+// Stop has the machine power down through ACPI.
+func (d *Driver) Stop() error {
+	if err := sendMonitorCommand(d.monitorPath(), "system_powerdown"); err != nil {
+		return err
+	}
+
+	return d.waitForState(state.Stopped)
+}
+
+func (d *Driver) Kill() error {
+	process, err := readPid(d.pidFile(), d.monitorPath())
+	if err != nil {
+		return err
+	}
+	if process == nil {
+		return nil
+	}
+
+	if err := process.Kill(); err != nil {
+		return err
+	}
+
+	if err := d.waitForState(state.Stopped); err != nil {
+		return err
+	}
+
+	if err := os.Remove(d.pidFile()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (d *Driver) Restart() error {
+	s, err := d.GetState()
+	if err != nil {
+		return err
+	}
+
+	if s == state.Running {
+		if err := d.Stop(); err != nil {
+			return err
+		}
+	}
+
+	return d.Start()
+}
+
+// Remove kills the machine, its files being removed with the store of the
+// machine.
+func (d *Driver) Remove() error {
+	return d.Kill()
+}
+
+func (d *Driver) waitForState(expected state.State) error {
+	return mcnutils.WaitForSpecific(func() bool {
+		s, err := d.GetState()
+		if err != nil {
+			log.Debugf("Error getting the state of %s: %s", d.MachineName, err)
+			return false
+		}
+		return s == expected
+	}, 120, waitInterval)
+}
+
+func (d *Driver) diskPath() string {
+	return d.ResolveStorePath("disk.raw")
+}
+
+func (d *Driver) pidFile() string {
+	return d.ResolveStorePath("qemu.pid")
+}
+
+func (d *Driver) monitorPath() string {
+	return d.ResolveStorePath("monitor.sock")
+}
+
+func (d *Driver) publicSSHKeyPath() string {
+	return d.GetSSHKeyPath() + ".pub"
+}
+
+// generateDiskImage creates the sparse disk of the machine, which tells
+// boot2docker to format it and install the SSH key.
+func (d *Driver) generateDiskImage() error {
+	return mcnutils.MakeB2dDiskImage(d.diskPath(), d.publicSSHKeyPath(), d.DiskSize)
+}
@@ -0,0 +1,256 @@
+package qemu
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDriver(t *testing.T) (*Driver, func()) {
+	storePath, err := ioutil.TempDir("", "qemu-test-")
+	assert.NoError(t, err)
+
+	d := NewDriver("default", storePath)
+	assert.NoError(t, os.MkdirAll(d.ResolveStorePath("."), 0700))
+
+	return d, func() { os.RemoveAll(storePath) }
+}
+
+func TestSetConfigFromFlags(t *testing.T) {
+	driver := NewDriver("default", "path")
+
+	checkFlags := &drivers.CheckDriverOptions{
+		FlagsValues: map[string]interface{}{},
+		CreateFlags: driver.GetCreateFlags(),
+	}
+
+	err := driver.SetConfigFromFlags(checkFlags)
+
+	assert.NoError(t, err)
+	assert.Empty(t, checkFlags.InvalidFlags)
+	assert.Equal(t, accelAuto, driver.Accel)
+}
+
+func TestSetConfigFromFlagsInvalidAccel(t *testing.T) {
+	driver := NewDriver("default", "path")
+
+	checkFlags := &drivers.CheckDriverOptions{
+		FlagsValues: map[string]interface{}{
+			"qemu-accel": "xen",
+		},
+		CreateFlags: driver.GetCreateFlags(),
+	}
+
+	err := driver.SetConfigFromFlags(checkFlags)
+
+	assert.EqualError(t, err, `Invalid accelerator "xen", expected auto, kvm, hvf or tcg`)
+}
+
+func TestDetectAccel(t *testing.T) {
+	defer func(device string, hvf func() bool) {
+		kvmDevice, hvfSupported = device, hvf
+	}(kvmDevice, hvfSupported)
+
+	kvmDevice = os.TempDir()
+	assert.Equal(t, accelKVM, detectAccel("linux"))
+
+	kvmDevice = filepath.Join(os.TempDir(), "no-such-kvm-device")
+	assert.Equal(t, accelTCG, detectAccel("linux"))
+
+	hvfSupported = func() bool { return true }
+	assert.Equal(t, accelHVF, detectAccel("darwin"))
+
+	hvfSupported = func() bool { return false }
+	assert.Equal(t, accelTCG, detectAccel("darwin"))
+
+	assert.Equal(t, accelTCG, detectAccel("windows"))
+}
+
+func TestQemuArgs(t *testing.T) {
+	config := qemuConfig{
+		Name:       "dev",
+		Memory:     2048,
+		CPU:        2,
+		Accel:      accelKVM,
+		ISO:        "/store/machines/dev/boot2docker.iso",
+		Disk:       "/store/machines/dev/disk.raw",
+		SSHPort:    2222,
+		EnginePort: 12376,
+		PidFile:    "/store/machines/dev/qemu.pid",
+		Monitor:    "/store/machines/dev/monitor.sock",
+	}
+
+	args := strings.Join(qemuArgs(config), " ")
+
+	assert.Contains(t, args, "-name dev -machine accel=kvm -m 2048 -smp 2 -cpu host ")
+	assert.Contains(t, args, "-cdrom /store/machines/dev/boot2docker.iso")
+	assert.Contains(t, args, "-drive file=/store/machines/dev/disk.raw,if=virtio,format=raw")
+	assert.Contains(t, args, "-netdev user,id=net0,hostfwd=tcp:127.0.0.1:2222-:22,hostfwd=tcp:127.0.0.1:12376-:2376")
+	assert.Contains(t, args, "-monitor unix:/store/machines/dev/monitor.sock,server,nowait")
+	assert.Contains(t, args, "-pidfile /store/machines/dev/qemu.pid -daemonize")
+
+	config.Accel = accelTCG
+
+	assert.NotContains(t, strings.Join(qemuArgs(config), " "), "-cpu host")
+}
+
+func TestPickPorts(t *testing.T) {
+	d := NewDriver("default", "path")
+	d.SSHPort = 2222
+
+	assert.NoError(t, d.pickPorts())
+	assert.Equal(t, 2222, d.SSHPort)
+	assert.NotEqual(t, 0, d.EnginePort)
+
+	d.EnginePort = 2222
+
+	assert.Error(t, d.pickPorts())
+}
+
+func TestRepickPorts(t *testing.T) {
+	d := NewDriver("default", "path")
+	d.SSHPort = 2222
+
+	assert.NoError(t, d.pickPorts())
+	picked := d.EnginePort
+
+	repicked, err := d.repickPorts()
+
+	assert.NoError(t, err)
+	assert.True(t, repicked)
+	assert.Equal(t, 2222, d.SSHPort)
+	assert.NotEqual(t, picked, d.EnginePort)
+
+	d.PickedEnginePort = false
+	repicked, err = d.repickPorts()
+
+	assert.NoError(t, err)
+	assert.False(t, repicked)
+}
+
+func TestGetStateWithoutPidFile(t *testing.T) {
+	d, cleanup := newTestDriver(t)
+	defer cleanup()
+
+	s, err := d.GetState()
+
+	assert.NoError(t, err)
+	assert.Equal(t, state.Stopped, s)
+
+	_, err = d.GetIP()
+
+	assert.Equal(t, drivers.ErrHostIsNotRunning, err)
+}
+
+func TestGetStateRunning(t *testing.T) {
+	d, cleanup := newTestDriver(t)
+	defer cleanup()
+	d.EnginePort = 12376
+
+	// The test itself stands for the qemu process, listening on the monitor.
+	assert.NoError(t, ioutil.WriteFile(d.pidFile(), []byte(fmt.Sprintf("%d\n", os.Getpid())), 0600))
+	monitor, err := net.Listen("unix", d.monitorPath())
+	assert.NoError(t, err)
+	defer monitor.Close()
+
+	s, err := d.GetState()
+
+	assert.NoError(t, err)
+	assert.Equal(t, state.Running, s)
+
+	url, err := d.GetURL()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "tcp://127.0.0.1:12376", url)
+}
+
+func TestStart(t *testing.T) {
+	d, cleanup := newTestDriver(t)
+	defer cleanup()
+	d.Accel = accelTCG
+	d.SSHPort = 2222
+	d.EnginePort = 12376
+
+	calls := [][]string{}
+	d.run = func(program string, args ...string) error {
+		calls = append(calls, append([]string{program}, args...))
+		return nil
+	}
+
+	assert.NoError(t, d.Start())
+	assert.Len(t, calls, 1)
+	assert.Equal(t, defaultProgram, calls[0][0])
+	assert.Equal(t, qemuArgs(d.config()), calls[0][1:])
+	assert.Equal(t, "127.0.0.1", d.IPAddress)
+}
+
+func TestGetStateWithReusedPid(t *testing.T) {
+	d, cleanup := newTestDriver(t)
+	defer cleanup()
+
+	// The pid of the killed qemu is now the one of the test, which doesn't
+	// listen on the monitor.
+	assert.NoError(t, ioutil.WriteFile(d.pidFile(), []byte(fmt.Sprintf("%d\n", os.Getpid())), 0600))
+
+	s, err := d.GetState()
+
+	assert.NoError(t, err)
+	assert.Equal(t, state.Stopped, s)
+}
+
+func TestStartPicksOtherPortsWhenTaken(t *testing.T) {
+	d, cleanup := newTestDriver(t)
+	defer cleanup()
+	d.Accel = accelTCG
+	d.SSHPort = 2222
+	assert.NoError(t, d.pickPorts())
+	taken := d.EnginePort
+
+	calls := 0
+	d.run = func(program string, args ...string) error {
+		calls++
+		if calls == 1 {
+			return fmt.Errorf("qemu-system-x86_64 failed: Could not set up host forwarding rule 'tcp:127.0.0.1:%d-:2376'", taken)
+		}
+		return nil
+	}
+
+	assert.NoError(t, d.Start())
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 2222, d.SSHPort)
+	assert.NotEqual(t, taken, d.EnginePort)
+}
+
+func TestStartDoesNotRepickGivenPorts(t *testing.T) {
+	d, cleanup := newTestDriver(t)
+	defer cleanup()
+	d.Accel = accelTCG
+	d.SSHPort = 2222
+	d.EnginePort = 12376
+
+	calls := 0
+	d.run = func(program string, args ...string) error {
+		calls++
+		return errors.New("qemu-system-x86_64 failed: Could not set up host forwarding rule 'tcp:127.0.0.1:2222-:22'")
+	}
+
+	assert.Error(t, d.Start())
+	assert.Equal(t, 1, calls)
+}
+
+func TestKillStopped(t *testing.T) {
+	d, cleanup := newTestDriver(t)
+	defer cleanup()
+
+	assert.NoError(t, d.Kill())
+	assert.NoError(t, d.Remove())
+}
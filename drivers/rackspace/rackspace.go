@@ -77,6 +77,7 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Usage: "Set if docker have to be installed on the machine",
 			Value: defaultDockerInstall,
 		},
+		drivers.UserDataFlag,
 	}
 }
 
@@ -121,6 +122,7 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.FlavorId = flags.String("rackspace-flavor-id")
 	d.SSHUser = flags.String("rackspace-ssh-user")
 	d.SSHPort = flags.Int("rackspace-ssh-port")
+	d.UserDataFile = flags.String(drivers.UserDataFlag.Name)
 	d.SwarmMaster = flags.Bool("swarm-master")
 	d.SwarmHost = flags.String("swarm-host")
 	d.SwarmDiscovery = flags.String("swarm-discovery")
@@ -0,0 +1,107 @@
+package virtualbox
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+)
+
+var errNoFreeHostOnlySubnet = errors.New("every subnet of the host-only CIDR pool is already used by a host-only network")
+
+// HostOnlyAllocator picks the subnet of a new host-only network among a pool
+// of candidates, given the host-only networks which already exist.
+type HostOnlyAllocator interface {
+	Choose(existing map[string]*hostOnlyNetwork, pool []net.IPNet) (net.IPNet, error)
+}
+
+// SequentialAllocator picks the lowest free subnet of the pool.
+type SequentialAllocator struct{}
+
+func (SequentialAllocator) Choose(existing map[string]*hostOnlyNetwork, pool []net.IPNet) (net.IPNet, error) {
+	free := freeHostOnlySubnets(existing, pool)
+	if len(free) == 0 {
+		return net.IPNet{}, errNoFreeHostOnlySubnet
+	}
+
+	sort.Sort(byIPNet(free))
+
+	return free[0], nil
+}
+
+// RandomAllocator picks any free subnet of the pool, which makes it less
+// likely for several hosts sharing a network to pick the same one.
+type RandomAllocator struct {
+	// Rand is the source of randomness, the global one if nil.
+	Rand *rand.Rand
+}
+
+func (a RandomAllocator) Choose(existing map[string]*hostOnlyNetwork, pool []net.IPNet) (net.IPNet, error) {
+	free := freeHostOnlySubnets(existing, pool)
+	if len(free) == 0 {
+		return net.IPNet{}, errNoFreeHostOnlySubnet
+	}
+
+	if a.Rand != nil {
+		return free[a.Rand.Intn(len(free))], nil
+	}
+
+	return free[rand.Intn(len(free))], nil
+}
+
+// hostOnlyAllocatorByName returns the allocator selected with
+// --virtualbox-hostonly-allocator.
+func hostOnlyAllocatorByName(name string) (HostOnlyAllocator, error) {
+	switch name {
+	case "", "sequential":
+		return SequentialAllocator{}, nil
+	case "random":
+		return RandomAllocator{}, nil
+	}
+
+	return nil, fmt.Errorf("unknown host-only allocator %q, expected sequential or random", name)
+}
+
+// freeHostOnlySubnets returns the subnets of the pool which neither match nor
+// overlap any existing host-only network, in the order of the pool.
+func freeHostOnlySubnets(existing map[string]*hostOnlyNetwork, pool []net.IPNet) []net.IPNet {
+	free := []net.IPNet{}
+
+	for _, candidate := range pool {
+		subnet := net.IPNet{IP: candidate.IP.Mask(candidate.Mask), Mask: candidate.Mask}
+		if !hostOnlySubnetUsed(existing, subnet) {
+			free = append(free, candidate)
+		}
+	}
+
+	return free
+}
+
+func hostOnlySubnetUsed(existing map[string]*hostOnlyNetwork, subnet net.IPNet) bool {
+	for _, n := range existing {
+		for _, ipv4 := range n.ipv4Networks() {
+			if _, bits := ipv4.Mask.Size(); ipv4.IP == nil || bits == 0 {
+				// Unset or non canonical mask, such as the buggy one.
+				continue
+			}
+
+			used := net.IPNet{IP: ipv4.IP.Mask(ipv4.Mask), Mask: ipv4.Mask}
+			if used.Contains(subnet.IP) || subnet.Contains(used.IP) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// byIPNet sorts subnets by address.
+type byIPNet []net.IPNet
+
+func (n byIPNet) Len() int      { return len(n) }
+func (n byIPNet) Swap(i, j int) { n[i], n[j] = n[j], n[i] }
+func (n byIPNet) Less(i, j int) bool {
+	return bytes.Compare(n[i].IP.To16(), n[j].IP.To16()) < 0
+}
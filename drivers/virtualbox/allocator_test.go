@@ -0,0 +1,160 @@
+package virtualbox
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustParseCIDRs(t *testing.T, cidrs ...string) []net.IPNet {
+	pool, err := parseHostOnlyCIDRPool(cidrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pool
+}
+
+func existingHostOnlyNetworks(cidrs ...string) map[string]*hostOnlyNetwork {
+	nets := map[string]*hostOnlyNetwork{}
+	for i, cidr := range cidrs {
+		ip, network, _ := net.ParseCIDR(cidr)
+		n := &hostOnlyNetwork{Name: fmt.Sprintf("vboxnet%d", i)}
+		n.NetworkName = "HostInterfaceNetworking-" + n.Name
+		n.IPv4 = net.IPNet{IP: ip, Mask: network.Mask}
+		nets[n.NetworkName] = n
+	}
+
+	return nets
+}
+
+func TestSequentialAllocatorPicksLowestFree(t *testing.T) {
+	existing := existingHostOnlyNetworks("192.168.99.1/24")
+	pool := mustParseCIDRs(t, "192.168.102.1/24", "192.168.99.1/24", "192.168.101.1/24")
+
+	cidr, err := SequentialAllocator{}.Choose(existing, pool)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.101.1/24", cidr.String())
+}
+
+func TestSequentialAllocatorSkipsOverlappingSubnets(t *testing.T) {
+	existing := existingHostOnlyNetworks("192.168.96.1/22")
+	pool := mustParseCIDRs(t, "192.168.99.1/24", "192.168.100.1/24")
+
+	cidr, err := SequentialAllocator{}.Choose(existing, pool)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.100.1/24", cidr.String())
+}
+
+func TestSequentialAllocatorIgnoresBuggyNetmask(t *testing.T) {
+	existing := existingHostOnlyNetworks("192.168.99.1/24")
+	existing["HostInterfaceNetworking-vboxnet0"].IPv4.Mask = parseIPv4Mask(buggyNetmask)
+	pool := mustParseCIDRs(t, "192.168.99.1/24")
+
+	cidr, err := SequentialAllocator{}.Choose(existing, pool)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.99.1/24", cidr.String())
+}
+
+func TestAllocatorsFailWhenPoolIsExhausted(t *testing.T) {
+	existing := existingHostOnlyNetworks("192.168.99.1/24", "192.168.100.1/24")
+	pool := mustParseCIDRs(t, "192.168.99.1/24", "192.168.100.1/24")
+
+	for _, allocator := range []HostOnlyAllocator{SequentialAllocator{}, RandomAllocator{}} {
+		_, err := allocator.Choose(existing, pool)
+
+		assert.Equal(t, errNoFreeHostOnlySubnet, err)
+	}
+}
+
+func TestRandomAllocatorPicksOnlyFreeSubnets(t *testing.T) {
+	existing := existingHostOnlyNetworks("192.168.99.1/24")
+	pool := mustParseCIDRs(t, "192.168.99.1/24", "192.168.100.1/24", "192.168.101.1/24")
+	allocator := RandomAllocator{Rand: rand.New(rand.NewSource(1))}
+
+	picked := map[string]bool{}
+	for i := 0; i < 50; i++ {
+		cidr, err := allocator.Choose(existing, pool)
+
+		assert.NoError(t, err)
+		picked[cidr.String()] = true
+	}
+
+	assert.Equal(t, map[string]bool{"192.168.100.1/24": true, "192.168.101.1/24": true}, picked)
+}
+
+func TestHostOnlyAllocatorByName(t *testing.T) {
+	allocator, err := hostOnlyAllocatorByName("random")
+	assert.NoError(t, err)
+	assert.IsType(t, RandomAllocator{}, allocator)
+
+	allocator, err = hostOnlyAllocatorByName("")
+	assert.NoError(t, err)
+	assert.IsType(t, SequentialAllocator{}, allocator)
+
+	_, err = hostOnlyAllocatorByName("lowest")
+	assert.EqualError(t, err, `unknown host-only allocator "lowest", expected sequential or random`)
+}
+
+func TestAllocateHostOnlyCIDR(t *testing.T) {
+	storePath, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(storePath)
+
+	driver := NewDriver("default", storePath)
+	driver.HostOnlyCIDRPool = []string{"192.168.99.1/24", "192.168.100.1/24"}
+	driver.VBoxManager = &VBoxManagerMock{
+		args:   "list hostonlyifs",
+		stdOut: stdOutOneHostOnlyNetwork,
+	}
+
+	err = driver.allocateHostOnlyCIDR()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.100.1/24", driver.HostOnlyCIDR)
+}
+
+func TestParseHostOnlyCIDRPoolRejectsNetworkAddress(t *testing.T) {
+	_, err := parseHostOnlyCIDRPool([]string{"192.168.99.0/24"})
+
+	assert.EqualError(t, err, `invalid host-only CIDR "192.168.99.0/24": `+ErrNetworkAddrCidr.Error())
+}
+
+func TestAllocateHostOnlyCIDRSkipsReservedSubnets(t *testing.T) {
+	storePath, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(storePath)
+
+	other := newHostOnlyReservations(storePath, "other")
+	assert.NoError(t, other.reserve(mustParseCIDRs(t, "192.168.100.1/24")[0]))
+
+	driver := NewDriver("default", storePath)
+	driver.HostOnlyCIDRPool = []string{"192.168.99.1/24", "192.168.100.1/24", "192.168.101.1/24"}
+	driver.VBoxManager = &VBoxManagerMock{
+		args:   "list hostonlyifs",
+		stdOut: stdOutOneHostOnlyNetwork,
+	}
+
+	err = driver.allocateHostOnlyCIDR()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.101.1/24", driver.HostOnlyCIDR)
+
+	reservations, err := other.load()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(reservations))
+	assert.Equal(t, "default", reservations[1].Machine)
+	assert.Equal(t, "192.168.101.1/24", reservations[1].CIDR)
+}
@@ -0,0 +1,161 @@
+package virtualbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+// VBoxCommand is the audit record of a single VBoxManage invocation.
+type VBoxCommand struct {
+	Time     time.Time
+	Args     string
+	Stdout   string
+	Stderr   string
+	Error    string `json:",omitempty"`
+	Mutating bool
+}
+
+// VBoxManagerRecorder wraps a VBoxManager and records every command run
+// through it, so that changes made to the VirtualBox installation can be
+// audited. Records are kept in memory and, if LogPath is set, appended to
+// that file as one JSON object per line. Hook, if set, is notified around
+// each command, and the commands which fail are reported as
+// VBoxManageErrors, with their stderr.
+type VBoxManagerRecorder struct {
+	VBoxManager
+	LogPath string
+	Hook    VBoxHook `json:"-"`
+
+	lock     sync.Mutex
+	commands []VBoxCommand
+}
+
+func (v *VBoxManagerRecorder) vbm(args ...string) error {
+	_, _, err := v.vbmOutErr(args...)
+	return err
+}
+
+func (v *VBoxManagerRecorder) vbmOut(args ...string) (string, error) {
+	stdout, _, err := v.vbmOutErr(args...)
+	return stdout, err
+}
+
+func (v *VBoxManagerRecorder) vbmOutErr(args ...string) (string, string, error) {
+	return v.vbmOutErrContext(context.Background(), args...)
+}
+
+func (v *VBoxManagerRecorder) vbmOutErrContext(ctx context.Context, args ...string) (string, string, error) {
+	hook := v.Hook
+	if hook == nil {
+		hook = VBoxHookFuncs{}
+	}
+
+	stdout, stderr, err := callWithVBoxHook(hook, args, func(args ...string) (string, string, error) {
+		return vbmOutErrContext(ctx, v.VBoxManager, args...)
+	})
+	if err != nil && err != ErrVBMNotFound && !isContextError(err) {
+		if _, wrapped := err.(*VBoxManageError); !wrapped {
+			err = &VBoxManageError{Args: args, Stderr: stderr, Err: err}
+		}
+	}
+
+	cmd := VBoxCommand{
+		Time:     time.Now(),
+		Args:     strings.Join(args, " "),
+		Stdout:   stdout,
+		Stderr:   stderr,
+		Mutating: isMutatingCommand(args),
+	}
+	if err != nil {
+		cmd.Error = err.Error()
+	}
+
+	v.record(cmd)
+
+	return stdout, stderr, err
+}
+
+func (v *VBoxManagerRecorder) record(cmd VBoxCommand) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	v.commands = append(v.commands, cmd)
+
+	if v.LogPath == "" {
+		return
+	}
+
+	// Auditing must never get in the way of managing the VM, so failing
+	// to write the trail is not fatal.
+	if err := appendJSONLine(v.LogPath, cmd); err != nil {
+		log.Warnf("Unable to write VirtualBox audit log %s: %s", v.LogPath, err)
+	}
+}
+
+// Commands returns every command recorded so far.
+func (v *VBoxManagerRecorder) Commands() []VBoxCommand {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	return append([]VBoxCommand{}, v.commands...)
+}
+
+// Mutations returns the recorded commands which changed the VirtualBox
+// configuration, leaving out read-only ones such as list or showvminfo.
+func (v *VBoxManagerRecorder) Mutations() []VBoxCommand {
+	mutations := []VBoxCommand{}
+	for _, cmd := range v.Commands() {
+		if cmd.Mutating {
+			mutations = append(mutations, cmd)
+		}
+	}
+
+	return mutations
+}
+
+// WriteMutations writes the arguments of each recorded mutation to w, one
+// command per line.
+func (v *VBoxManagerRecorder) WriteMutations(w io.Writer) error {
+	for _, cmd := range v.Mutations() {
+		if _, err := fmt.Fprintf(w, "VBoxManage %s\n", cmd.Args); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isMutatingCommand reports whether the VBoxManage command described by args
+// can change the VirtualBox configuration.
+func isMutatingCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	switch args[0] {
+	case "list", "showvminfo", "showhdinfo", "showmediuminfo", "getextradata", "--version", "-v", "-version":
+		return false
+	case "guestproperty":
+		return len(args) < 2 || (args[1] != "get" && args[1] != "enumerate")
+	}
+
+	return true
+}
+
+func appendJSONLine(path string, v interface{}) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return json.NewEncoder(f).Encode(v)
+}
@@ -0,0 +1,120 @@
+package virtualbox
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsMutatingCommand(t *testing.T) {
+	var tests = []struct {
+		args     []string
+		mutating bool
+	}{
+		{[]string{"list", "hostonlyifs"}, false},
+		{[]string{"showvminfo", "default", "--machinereadable"}, false},
+		{[]string{"--version"}, false},
+		{[]string{"guestproperty", "get", "default", "/VirtualBox/GuestInfo/Net/1/V4/IP"}, false},
+		{[]string{"guestproperty", "set", "default", "/VirtualBox/GuestAdd/SharedFolders/MountDir", "/"}, true},
+		{[]string{"hostonlyif", "create"}, true},
+		{[]string{"dhcpserver", "add", "--netname", "HostInterfaceNetworking-vboxnet0"}, true},
+		{[]string{"modifyvm", "default", "--nic2", "hostonly"}, true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.mutating, isMutatingCommand(test.args), "%v", test.args)
+	}
+}
+
+func TestRecorderRecordsCommands(t *testing.T) {
+	recorder := &VBoxManagerRecorder{
+		VBoxManager: &VBoxManagerScript{
+			stdOut: map[string]string{
+				"list hostonlyifs":  stdOutOneHostOnlyNetwork,
+				"hostonlyif create": "Interface 'vboxnet1' was successfully created",
+			},
+		},
+	}
+
+	_, err := listHostOnlyNetworks(recorder)
+	assert.NoError(t, err)
+
+	_, err = createHostonlyNet(recorder)
+	assert.NoError(t, err)
+
+	commands := recorder.Commands()
+	assert.Equal(t, 2, len(commands))
+	assert.Equal(t, "list hostonlyifs", commands[0].Args)
+	assert.Equal(t, stdOutOneHostOnlyNetwork, commands[0].Stdout)
+	assert.False(t, commands[0].Mutating)
+	assert.Equal(t, "hostonlyif create", commands[1].Args)
+	assert.True(t, commands[1].Mutating)
+
+	mutations := recorder.Mutations()
+	assert.Equal(t, 1, len(mutations))
+	assert.Equal(t, "hostonlyif create", mutations[0].Args)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, recorder.WriteMutations(buf))
+	assert.Equal(t, "VBoxManage hostonlyif create\n", buf.String())
+}
+
+func TestRecorderAppendsToLogPath(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	recorder := &VBoxManagerRecorder{
+		VBoxManager: &VBoxManagerScript{},
+		LogPath:     filepath.Join(tmpDir, "audit.log"),
+	}
+
+	assert.NoError(t, recorder.vbm("hostonlyif", "create"))
+	assert.NoError(t, recorder.vbm("list", "vms"))
+
+	f, err := os.Open(recorder.LogPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	commands := []VBoxCommand{}
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		cmd := VBoxCommand{}
+		assert.NoError(t, json.Unmarshal(s.Bytes(), &cmd))
+		commands = append(commands, cmd)
+	}
+
+	assert.Equal(t, 2, len(commands))
+	assert.Equal(t, "hostonlyif create", commands[0].Args)
+	assert.True(t, commands[0].Mutating)
+	assert.Equal(t, "list vms", commands[1].Args)
+	assert.False(t, commands[1].Mutating)
+}
+
+func TestRecorderLogPathSurvivesDriverReload(t *testing.T) {
+	driver := newTestDriver("default")
+	driver.VBoxManager.(*VBoxManagerRecorder).LogPath = "/tmp/audit.log"
+
+	data, err := json.Marshal(driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := newTestDriver("")
+	assert.NoError(t, json.Unmarshal(data, reloaded))
+
+	recorder, ok := reloaded.VBoxManager.(*VBoxManagerRecorder)
+	assert.True(t, ok)
+	assert.Equal(t, "/tmp/audit.log", recorder.LogPath)
+	assert.IsType(t, &VBoxCmdManager{}, recorder.VBoxManager)
+}
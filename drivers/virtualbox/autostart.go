@@ -0,0 +1,27 @@
+package virtualbox
+
+import (
+	"regexp"
+	"strings"
+)
+
+var reAutostartDBPath = regexp.MustCompile(`(?m)^Autostart database path:\s*(.*)$`)
+
+// autostartConfigured reports whether the host is set up to start the VMs
+// which have autostart enabled when it boots. VirtualBox only does it if an
+// autostart database is configured for the VBoxAutostart service to read.
+func autostartConfigured(vbox VBoxManager) (bool, error) {
+	stdout, err := vbox.vbmOut("list", "systemproperties")
+	if err != nil {
+		return false, err
+	}
+
+	res := reAutostartDBPath.FindStringSubmatch(stdout)
+	if res == nil {
+		return false, nil
+	}
+
+	path := strings.TrimSpace(res[1])
+
+	return path != "" && path != "<none>", nil
+}
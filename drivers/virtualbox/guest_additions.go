@@ -0,0 +1,95 @@
+package virtualbox
+
+import (
+	"strings"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// guestAdditionsPort is the SATA port of the DVD drive holding the guest
+// additions ISO, after the boot ISO and the disk.
+const guestAdditionsPort = "2"
+
+// guestAdditionsInstallCommand mounts the DVD drive holding the guest
+// additions ISO, whichever /dev/srN it is, and runs the installer.
+const guestAdditionsInstallCommand = `sudo mkdir -p /mnt/vbox-additions && ` +
+	`for dev in /dev/sr*; do ` +
+	`sudo mount -t iso9660 -o ro $dev /mnt/vbox-additions 2>/dev/null || continue; ` +
+	`[ -f /mnt/vbox-additions/VBoxLinuxAdditions.run ] && break; ` +
+	`sudo umount /mnt/vbox-additions; ` +
+	`done && ` +
+	`sudo sh /mnt/vbox-additions/VBoxLinuxAdditions.run --nox11; status=$?; ` +
+	`sudo umount /mnt/vbox-additions; exit $status`
+
+// boot2dockerCheckCommand succeeds on boot2docker, which ships its own guest
+// additions and, running from its ISO, loses any other at the next boot.
+const boot2dockerCheckCommand = "grep -q '^ID=boot2docker' /etc/os-release"
+
+// guestAdditionsVersion returns the version of the guest additions running
+// in the VM, or an empty string if none are.
+func guestAdditionsVersion(machineName string, vbox VBoxManager) (string, error) {
+	stdout, err := vbox.vbmOut("guestproperty", "get", machineName, "/VirtualBox/GuestAdd/Version")
+	if err != nil {
+		return "", err
+	}
+
+	stdout = strings.TrimSpace(stdout)
+	if !strings.HasPrefix(stdout, "Value:") {
+		// No value set!
+		return "", nil
+	}
+
+	return strings.TrimSpace(strings.TrimPrefix(stdout, "Value:")), nil
+}
+
+// attachGuestAdditions inserts the guest additions ISO bundled with the
+// VirtualBox of the host into the VM, which must be stopped. It is inserted
+// at each start so that it follows upgrades of VirtualBox.
+func (d *Driver) attachGuestAdditions() error {
+	return d.vbm("storageattach", d.MachineName,
+		"--storagectl", "SATA",
+		"--port", guestAdditionsPort,
+		"--device", "0",
+		"--type", "dvddrive",
+		"--medium", "additions")
+}
+
+// updateGuestAdditions installs the guest additions in the running VM unless
+// those of the VirtualBox version of the host are already running, or the
+// VM runs boot2docker.
+func (d *Driver) updateGuestAdditions() error {
+	hostVersion, err := getVBoxVersion(d.VBoxManager)
+	if err != nil {
+		return err
+	}
+
+	current, err := guestAdditionsVersion(d.MachineName, d.VBoxManager)
+	if err != nil {
+		return err
+	}
+
+	if current != "" {
+		if guestVersion, err := parseVBoxVersion(current); err == nil && guestVersion == hostVersion {
+			log.Debugf("The VirtualBox guest additions %s are up to date", current)
+			d.GuestAdditionsVersion = current
+			return nil
+		}
+	}
+
+	if _, err := drivers.RunSSHCommandFromDriver(d, boot2dockerCheckCommand); err == nil {
+		log.Infof("Not installing the VirtualBox guest additions %s on boot2docker, which ships its own", hostVersion)
+		d.GuestAdditionsVersion = current
+		return nil
+	}
+
+	log.Infof("Installing the VirtualBox guest additions %s...", hostVersion)
+
+	if _, err := drivers.RunSSHCommandFromDriver(d, guestAdditionsInstallCommand); err != nil {
+		return err
+	}
+
+	d.GuestAdditionsVersion = hostVersion.String()
+
+	return nil
+}
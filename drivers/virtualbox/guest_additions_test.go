@@ -0,0 +1,59 @@
+package virtualbox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuestAdditionsVersion(t *testing.T) {
+	vbox := &VBoxManagerMock{
+		args:   "guestproperty get default /VirtualBox/GuestAdd/Version",
+		stdOut: "Value: 5.0.20\n",
+	}
+
+	version, err := guestAdditionsVersion("default", vbox)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "5.0.20", version)
+}
+
+func TestGuestAdditionsVersionNotInstalled(t *testing.T) {
+	vbox := &VBoxManagerMock{
+		args:   "guestproperty get default /VirtualBox/GuestAdd/Version",
+		stdOut: "No value set!\n",
+	}
+
+	version, err := guestAdditionsVersion("default", vbox)
+
+	assert.NoError(t, err)
+	assert.Empty(t, version)
+}
+
+func TestUpdateGuestAdditionsSkipsCurrentVersion(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"--version": "5.0.20r106931\n",
+			"guestproperty get default /VirtualBox/GuestAdd/Version": "Value: 5.0.20\n",
+		},
+	}
+	driver := NewDriver("default", "path")
+	driver.VBoxManager = vbox
+
+	err := driver.updateGuestAdditions()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "5.0.20", driver.GuestAdditionsVersion)
+	assert.Equal(t, []string{"--version", "guestproperty get default /VirtualBox/GuestAdd/Version"}, vbox.calls)
+}
+
+func TestAttachGuestAdditions(t *testing.T) {
+	vbox := &VBoxManagerScript{}
+	driver := NewDriver("default", "path")
+	driver.VBoxManager = vbox
+
+	err := driver.attachGuestAdditions()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"storageattach default --storagectl SATA --port 2 --device 0 --type dvddrive --medium additions"}, vbox.calls)
+}
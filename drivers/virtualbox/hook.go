@@ -0,0 +1,97 @@
+package virtualbox
+
+import (
+	"sync"
+	"time"
+)
+
+// VBoxCall is a VBoxManage command which ran, as reported to a VBoxHook.
+type VBoxCall struct {
+	Args     []string
+	Duration time.Duration
+	Err      error
+}
+
+// VBoxHook is notified around VBoxManage commands, e.g. to measure how long
+// each kind of command takes. Hooks are called from the goroutine running the
+// command, and must be safe for concurrent use.
+type VBoxHook interface {
+	// Before is called before the command with args runs.
+	Before(args []string)
+	// After is called once the command ran.
+	After(call VBoxCall)
+}
+
+// VBoxHookFuncs turns functions into a VBoxHook. Nil functions are skipped.
+type VBoxHookFuncs struct {
+	BeforeFunc func(args []string)
+	AfterFunc  func(call VBoxCall)
+}
+
+func (h VBoxHookFuncs) Before(args []string) {
+	if h.BeforeFunc != nil {
+		h.BeforeFunc(args)
+	}
+}
+
+func (h VBoxHookFuncs) After(call VBoxCall) {
+	if h.AfterFunc != nil {
+		h.AfterFunc(call)
+	}
+}
+
+type vboxHooks []VBoxHook
+
+func (h vboxHooks) Before(args []string) {
+	for _, hook := range h {
+		hook.Before(args)
+	}
+}
+
+func (h vboxHooks) After(call VBoxCall) {
+	for _, hook := range h {
+		hook.After(call)
+	}
+}
+
+// ChainVBoxHooks returns a hook notifying each of hooks in turn.
+func ChainVBoxHooks(hooks ...VBoxHook) VBoxHook {
+	return vboxHooks(hooks)
+}
+
+var (
+	vboxHookLock sync.RWMutex
+	vboxHook     VBoxHook = VBoxHookFuncs{}
+)
+
+// SetVBoxHook installs the hook notified around every VBoxManage command run
+// by this process, nil restoring the default hook, which does nothing.
+func SetVBoxHook(hook VBoxHook) {
+	if hook == nil {
+		hook = VBoxHookFuncs{}
+	}
+
+	vboxHookLock.Lock()
+	defer vboxHookLock.Unlock()
+
+	vboxHook = hook
+}
+
+func currentVBoxHook() VBoxHook {
+	vboxHookLock.RLock()
+	defer vboxHookLock.RUnlock()
+
+	return vboxHook
+}
+
+// callWithVBoxHook runs the command with args through run, notifying hook.
+func callWithVBoxHook(hook VBoxHook, args []string, run func(args ...string) (string, string, error)) (string, string, error) {
+	hook.Before(args)
+
+	start := time.Now()
+	stdout, stderr, err := run(args...)
+
+	hook.After(VBoxCall{Args: args, Duration: time.Since(start), Err: err})
+
+	return stdout, stderr, err
+}
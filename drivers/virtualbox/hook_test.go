@@ -0,0 +1,122 @@
+package virtualbox
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// slowVBoxManager answers every command after a delay.
+type slowVBoxManager struct {
+	VBoxManagerMock
+	delay time.Duration
+}
+
+func (v *slowVBoxManager) vbmOutErr(args ...string) (string, string, error) {
+	time.Sleep(v.delay)
+	return v.VBoxManagerMock.vbmOutErr(args...)
+}
+
+// recordingHook records the calls it is notified of.
+type recordingHook struct {
+	lock   sync.Mutex
+	before [][]string
+	after  []VBoxCall
+}
+
+func (h *recordingHook) Before(args []string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.before = append(h.before, args)
+}
+
+func (h *recordingHook) After(call VBoxCall) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.after = append(h.after, call)
+}
+
+func TestVBoxManagerRecorderHookObservesCommandAndDuration(t *testing.T) {
+	hook := &recordingHook{}
+	vbox := &VBoxManagerRecorder{
+		VBoxManager: &slowVBoxManager{
+			VBoxManagerMock: VBoxManagerMock{args: "list hostonlyifs", stdOut: stdOutOneHostOnlyNetwork},
+			delay:           20 * time.Millisecond,
+		},
+		Hook: hook,
+	}
+
+	nets, err := listHostOnlyNetworks(vbox)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(nets))
+	assert.Equal(t, [][]string{{"list", "hostonlyifs"}}, hook.before)
+	assert.Equal(t, 1, len(hook.after))
+	assert.Equal(t, []string{"list", "hostonlyifs"}, hook.after[0].Args)
+	assert.True(t, hook.after[0].Duration >= 20*time.Millisecond)
+	assert.NoError(t, hook.after[0].Err)
+}
+
+func TestVBoxManagerRecorderHookObservesErrors(t *testing.T) {
+	hook := &recordingHook{}
+	vbox := &VBoxManagerRecorder{
+		VBoxManager: &VBoxManagerMock{args: "hostonlyif create", err: errors.New("exit status 1")},
+		Hook:        hook,
+	}
+
+	err := vbox.vbm("hostonlyif", "create")
+
+	assert.EqualError(t, err, "exit status 1")
+	assert.EqualError(t, hook.after[0].Err, "exit status 1")
+}
+
+func TestVBoxManagerRecorderWithoutHook(t *testing.T) {
+	vbox := &VBoxManagerRecorder{VBoxManager: &VBoxManagerMock{args: "--version", stdOut: "5.0.8r103449"}}
+
+	stdout, err := vbox.vbmOut("--version")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "5.0.8r103449", stdout)
+}
+
+func TestChainVBoxHooks(t *testing.T) {
+	first, second := &recordingHook{}, &recordingHook{}
+	calls := []string{}
+	vbox := &VBoxManagerRecorder{
+		VBoxManager: &VBoxManagerMock{args: "list vms"},
+		Hook: ChainVBoxHooks(first, VBoxHookFuncs{
+			AfterFunc: func(call VBoxCall) { calls = append(calls, call.Args[0]) },
+		}, second),
+	}
+
+	assert.NoError(t, vbox.vbm("list", "vms"))
+
+	assert.Equal(t, 1, len(first.after))
+	assert.Equal(t, 1, len(second.after))
+	assert.Equal(t, []string{"list"}, calls)
+}
+
+func TestVBoxCmdManagerNotifiesGlobalHook(t *testing.T) {
+	hook := &recordingHook{}
+	SetVBoxHook(hook)
+	defer SetVBoxHook(nil)
+
+	vbox := &VBoxCmdManager{Transport: &recordingTransport{}}
+
+	_, err := vbox.vbmOut("list", "hostonlyifs")
+
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"list", "hostonlyifs"}}, hook.before)
+	assert.Equal(t, []string{"list", "hostonlyifs"}, hook.after[0].Args)
+	assert.True(t, hook.after[0].Duration > 0)
+}
+
+func TestTransportOfLooksThroughNestedRecorders(t *testing.T) {
+	transport := &recordingTransport{}
+	vbox := &VBoxManagerRecorder{VBoxManager: &VBoxManagerRecorder{VBoxManager: &VBoxCmdManager{Transport: transport}}}
+
+	assert.Equal(t, transport, transportOf(vbox))
+}
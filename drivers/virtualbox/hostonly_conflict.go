@@ -0,0 +1,425 @@
+package virtualbox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// maxHostOnlyCIDRCandidates is how many free CIDRs a conflict error
+	// suggests.
+	maxHostOnlyCIDRCandidates = 3
+
+	// maxHostOnlyCIDRSteps bounds how far from the requested CIDR free ones
+	// are looked for.
+	maxHostOnlyCIDRSteps = 256
+)
+
+// hostInterfaceNetworks lists the IPv4 networks the interfaces of the host
+// are attached to, VPN tunnels included. Tests replace it to fake the host.
+var hostInterfaceNetworks = readHostInterfaceNetworks
+
+// hostRoutes lists the IPv4 routes of the routing table of the host, which
+// also holds the networks reached through a VPN without being attached to
+// them. Tests replace it to fake the host.
+var hostRoutes = readHostRoutes
+
+// hostInterfaceNetwork is a network an interface of the host is attached to,
+// or, for a route, reaches.
+type hostInterfaceNetwork struct {
+	Name   string
+	HwAddr net.HardwareAddr
+	Subnet net.IPNet
+}
+
+// ErrHostOnlyCIDRConflict is returned when the host-only CIDR of a machine
+// overlaps a network of the host, which would leave it with ambiguous
+// routes. Candidates are free CIDRs next to the requested one.
+type ErrHostOnlyCIDRConflict struct {
+	CIDR       string
+	Network    string
+	Interface  string
+	Candidates []string
+}
+
+func (e ErrHostOnlyCIDRConflict) Error() string {
+	msg := fmt.Sprintf("host-only CIDR %s overlaps with network %s of %s", e.CIDR, e.Network, e.Interface)
+	if len(e.Candidates) == 0 {
+		return msg + ", and no free CIDR was found next to it"
+	}
+
+	return fmt.Sprintf("%s, pick a free one such as %s with --virtualbox-hostonly-cidr or use --virtualbox-hostonly-cidr-fallback", msg, strings.Join(e.Candidates, ", "))
+}
+
+func readHostInterfaceNetworks() ([]hostInterfaceNetwork, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	networks := []hostInterfaceNetwork{}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			log.Debugf("Unable to read the addresses of interface %s: %s", iface.Name, err)
+			continue
+		}
+
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.To4() == nil {
+				continue
+			}
+
+			networks = append(networks, hostInterfaceNetwork{
+				Name:   iface.Name,
+				HwAddr: iface.HardwareAddr,
+				Subnet: net.IPNet{IP: ipNet.IP.Mask(ipNet.Mask).To4(), Mask: ipNet.Mask},
+			})
+		}
+	}
+
+	return networks, nil
+}
+
+// backsHostOnlyNetwork reports whether the interface is the one of a
+// host-only network, which is matched by hardware address since on Windows
+// its name isn't the one VirtualBox reports.
+func (h hostInterfaceNetwork) backsHostOnlyNetwork(nets map[string]*hostOnlyNetwork) bool {
+	for _, n := range nets {
+		if len(n.HwAddr) > 0 && bytes.Equal(h.HwAddr, n.HwAddr) || h.Name == n.Name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// usedNetwork is a subnet already in use on the host, by a host-only network
+// or another interface.
+type usedNetwork struct {
+	Owner    string
+	Subnet   net.IPNet
+	HostOnly bool
+}
+
+func (u usedNetwork) overlaps(subnet net.IPNet) bool {
+	return u.Subnet.Contains(subnet.IP) || subnet.Contains(u.Subnet.IP)
+}
+
+// usedHostNetworks returns the subnets of the host-only networks and of the
+// other interfaces of the host.
+func usedHostNetworks(nets map[string]*hostOnlyNetwork) ([]usedNetwork, error) {
+	used := []usedNetwork{}
+
+	for _, n := range sortedHostOnlyNetworks(nets) {
+		for _, ipv4 := range n.ipv4Networks() {
+			if _, bits := ipv4.Mask.Size(); ipv4.IP == nil || bits == 0 {
+				// Unset or non canonical mask, such as the buggy one.
+				continue
+			}
+
+			used = append(used, usedNetwork{
+				Owner:    n.Name,
+				Subnet:   net.IPNet{IP: ipv4.IP.Mask(ipv4.Mask), Mask: ipv4.Mask},
+				HostOnly: true,
+			})
+		}
+	}
+
+	ifaces, err := hostInterfaceNetworks()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, iface := range ifaces {
+		if iface.backsHostOnlyNetwork(nets) {
+			continue
+		}
+
+		used = append(used, usedNetwork{Owner: iface.Name, Subnet: iface.Subnet})
+	}
+
+	// The routing table can't be read everywhere, the interfaces are then
+	// the only networks checked.
+	routes, err := hostRoutes()
+	if err != nil {
+		log.Debugf("Unable to read the routing table of the host: %s", err)
+		return used, nil
+	}
+
+	for _, route := range routes {
+		if isRoutedHostNetwork(route.Subnet, used, ifaces) {
+			used = append(used, usedNetwork{Owner: route.Name, Subnet: route.Subnet})
+		}
+	}
+
+	return used, nil
+}
+
+// isRoutedHostNetwork tells whether the subnet of a route is a network the
+// host reaches besides those of its interfaces, e.g. through a VPN. The
+// default, host, loopback and multicast routes aren't, nor are the routes
+// within the network of an interface or a host-only network, which some hosts
+// list as well.
+func isRoutedHostNetwork(subnet net.IPNet, used []usedNetwork, ifaces []hostInterfaceNetwork) bool {
+	ones, bits := subnet.Mask.Size()
+	if bits != 32 || ones == 0 || ones == 32 || subnet.IP.IsLoopback() || subnet.IP.IsMulticast() {
+		return false
+	}
+
+	for _, iface := range ifaces {
+		if containsNetwork(iface.Subnet, subnet) {
+			return false
+		}
+	}
+
+	for _, u := range used {
+		if containsNetwork(u.Subnet, subnet) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// containsNetwork tells whether the network outer contains inner.
+func containsNetwork(outer, inner net.IPNet) bool {
+	outerOnes, _ := outer.Mask.Size()
+	innerOnes, _ := inner.Mask.Size()
+	return outerOnes <= innerOnes && outer.Contains(inner.IP)
+}
+
+// parseProcNetRoute parses the IPv4 routing table of Linux, as read from
+// /proc/net/route, where the addresses are hexadecimal in host byte order.
+func parseProcNetRoute(content string) []hostInterfaceNetwork {
+	routes := []hostInterfaceNetwork{}
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 8 || fields[0] == "Iface" {
+			continue
+		}
+
+		dest, errDest := strconv.ParseUint(fields[1], 16, 32)
+		mask, errMask := strconv.ParseUint(fields[7], 16, 32)
+		if errDest != nil || errMask != nil {
+			continue
+		}
+
+		ip := make(net.IP, net.IPv4len)
+		binary.LittleEndian.PutUint32(ip, uint32(dest))
+		ipMask := make(net.IPMask, net.IPv4len)
+		binary.LittleEndian.PutUint32(ipMask, uint32(mask))
+
+		routes = append(routes, hostInterfaceNetwork{
+			Name:   fields[0],
+			Subnet: net.IPNet{IP: ip.Mask(ipMask), Mask: ipMask},
+		})
+	}
+
+	return routes
+}
+
+// parseNetstatRoutes parses the IPv4 routing table printed by netstat -rn on
+// macOS. The destinations leave out their trailing zero octets, e.g. 10.8/16,
+// and those without a prefix length are as long as their octets.
+func parseNetstatRoutes(output string) []hostInterfaceNetwork {
+	routes := []hostInterfaceNetwork{}
+	netifColumn := -1
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] == "Destination" {
+			for i, field := range fields {
+				if field == "Netif" {
+					netifColumn = i
+				}
+			}
+			continue
+		}
+
+		if netifColumn < 0 || len(fields) <= netifColumn {
+			continue
+		}
+
+		subnet, ok := parseNetstatDestination(fields[0])
+		if !ok {
+			continue
+		}
+
+		routes = append(routes, hostInterfaceNetwork{Name: fields[netifColumn], Subnet: subnet})
+	}
+
+	return routes
+}
+
+func parseNetstatDestination(dest string) (net.IPNet, bool) {
+	parts := strings.SplitN(dest, "/", 2)
+	octets := strings.Split(parts[0], ".")
+	if len(octets) > net.IPv4len {
+		return net.IPNet{}, false
+	}
+
+	ip := make(net.IP, net.IPv4len)
+	for i, octet := range octets {
+		value, err := strconv.ParseUint(octet, 10, 8)
+		if err != nil {
+			return net.IPNet{}, false
+		}
+		ip[i] = byte(value)
+	}
+
+	ones := 8 * len(octets)
+	if len(parts) == 2 {
+		var err error
+		if ones, err = strconv.Atoi(parts[1]); err != nil || ones < 0 || ones > 32 {
+			return net.IPNet{}, false
+		}
+	}
+
+	mask := net.CIDRMask(ones, 32)
+	return net.IPNet{IP: ip.Mask(mask), Mask: mask}, true
+}
+
+// parseWindowsRoutes parses the IPv4 routes of Windows, printed as their
+// destination prefix followed by the alias of their interface on each line.
+func parseWindowsRoutes(output string) []hostInterfaceNetwork {
+	routes := []hostInterfaceNetwork{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		_, subnet, err := net.ParseCIDR(fields[0])
+		if err != nil || subnet.IP.To4() == nil {
+			continue
+		}
+
+		routes = append(routes, hostInterfaceNetwork{
+			Name:   strings.TrimSpace(fields[1]),
+			Subnet: net.IPNet{IP: subnet.IP.To4(), Mask: subnet.Mask},
+		})
+	}
+
+	return routes
+}
+
+// findHostOnlyCIDRConflict returns the used network requested overlaps. A
+// host-only network with the very same subnet is no conflict, the machine
+// shares it.
+func findHostOnlyCIDRConflict(requested net.IPNet, used []usedNetwork) *usedNetwork {
+	requestedOnes, _ := requested.Mask.Size()
+
+	for i, u := range used {
+		ones, _ := u.Subnet.Mask.Size()
+		if u.HostOnly && ones == requestedOnes && u.Subnet.IP.Equal(requested.IP) {
+			continue
+		}
+
+		if u.overlaps(requested) {
+			return &used[i]
+		}
+	}
+
+	return nil
+}
+
+// nextFreeHostOnlyCIDRs returns up to max CIDRs following ip/mask, with the
+// same mask and host part, whose subnets overlap no used network. They stay
+// within the first octet of ip so as not to leave its private range.
+func nextFreeHostOnlyCIDRs(ip net.IP, mask net.IPMask, used []usedNetwork, max int) []net.IPNet {
+	ip4 := ip.To4()
+	ones, bits := mask.Size()
+	if ip4 == nil || bits != 32 || ones < 8 {
+		return nil
+	}
+
+	size := uint32(1) << uint(bits-ones)
+	addr := binary.BigEndian.Uint32(ip4)
+
+	free := []net.IPNet{}
+	for step := 1; step <= maxHostOnlyCIDRSteps && len(free) < max; step++ {
+		next := addr + uint32(step)*size
+		if next>>24 != addr>>24 {
+			break
+		}
+
+		candidateIP := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(candidateIP, next)
+		subnet := net.IPNet{IP: candidateIP.Mask(mask), Mask: mask}
+
+		taken := false
+		for _, u := range used {
+			if u.overlaps(subnet) {
+				taken = true
+				break
+			}
+		}
+
+		if !taken {
+			free = append(free, net.IPNet{IP: candidateIP, Mask: mask})
+		}
+	}
+
+	return free
+}
+
+// checkHostOnlyCIDR checks that the host-only CIDR of the machine doesn't
+// overlap a network of the host. On a conflict, the next free CIDR replaces
+// it if HostOnlyCIDRFallback is set, otherwise an ErrHostOnlyCIDRConflict
+// listing free ones is returned.
+func (d *Driver) checkHostOnlyCIDR(ip net.IP, network *net.IPNet) (net.IP, *net.IPNet, error) {
+	nets, err := listHostOnlyNetworks(d.VBoxManager)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	used, err := usedHostNetworks(nets)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conflict := findHostOnlyCIDRConflict(net.IPNet{IP: network.IP, Mask: network.Mask}, used)
+	if conflict == nil {
+		return ip, network, nil
+	}
+
+	max := maxHostOnlyCIDRCandidates
+	if d.HostOnlyCIDRFallback {
+		max = 1
+	}
+	free := nextFreeHostOnlyCIDRs(ip, network.Mask, used, max)
+
+	if !d.HostOnlyCIDRFallback || len(free) == 0 {
+		candidates := []string{}
+		for _, cidr := range free {
+			candidates = append(candidates, cidr.String())
+		}
+
+		return nil, nil, ErrHostOnlyCIDRConflict{
+			CIDR:       d.hostOnlyCIDR(),
+			Network:    conflict.Subnet.String(),
+			Interface:  conflict.Owner,
+			Candidates: candidates,
+		}
+	}
+
+	log.Warnf("Host-only CIDR %s overlaps with network %s of %s, using %s instead", d.hostOnlyCIDR(), conflict.Subnet.String(), conflict.Owner, free[0].String())
+	d.HostOnlyCIDR = free[0].String()
+
+	return parseAndValidateCIDR(d.HostOnlyCIDR)
+}
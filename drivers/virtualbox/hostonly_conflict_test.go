@@ -0,0 +1,206 @@
+package virtualbox
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeHostInterfaceNetworks replaces the networks of the host for a test,
+// with no other route, returning a function restoring the real ones.
+func fakeHostInterfaceNetworks(networks ...hostInterfaceNetwork) func() {
+	return fakeHostNetworks(networks, nil)
+}
+
+func fakeHostNetworks(networks, routes []hostInterfaceNetwork) func() {
+	realNetworks, realRoutes := hostInterfaceNetworks, hostRoutes
+	hostInterfaceNetworks = func() ([]hostInterfaceNetwork, error) {
+		return networks, nil
+	}
+	hostRoutes = func() ([]hostInterfaceNetwork, error) {
+		return routes, nil
+	}
+
+	return func() {
+		hostInterfaceNetworks, hostRoutes = realNetworks, realRoutes
+	}
+}
+
+func hostNetwork(name, cidr string) hostInterfaceNetwork {
+	_, subnet, _ := net.ParseCIDR(cidr)
+	return hostInterfaceNetwork{Name: name, Subnet: *subnet}
+}
+
+func TestCheckHostOnlyCIDRWithoutConflict(t *testing.T) {
+	defer fakeHostInterfaceNetworks(hostNetwork("en0", "10.0.0.0/24"), hostNetwork("vboxnet0", "192.168.99.0/24"))()
+
+	driver := newTestDriver("default")
+	driver.VBoxManager = &VBoxManagerMock{args: "list hostonlyifs", stdOut: stdOutOneHostOnlyNetwork}
+	ip, network, _ := parseAndValidateCIDR("192.168.99.1/24")
+
+	checkedIP, checkedNetwork, err := driver.checkHostOnlyCIDR(ip, network)
+
+	assert.NoError(t, err)
+	assert.Equal(t, ip, checkedIP)
+	assert.Equal(t, network, checkedNetwork)
+}
+
+func TestCheckHostOnlyCIDRConflictsWithHostInterface(t *testing.T) {
+	defer fakeHostInterfaceNetworks(hostNetwork("utun2", "192.168.96.0/22"), hostNetwork("en0", "192.168.101.0/24"))()
+
+	driver := newTestDriver("default")
+	driver.HostOnlyCIDR = "192.168.99.1/24"
+	driver.VBoxManager = &VBoxManagerMock{args: "list hostonlyifs", stdOut: ""}
+	ip, network, _ := parseAndValidateCIDR(driver.HostOnlyCIDR)
+
+	_, _, err := driver.checkHostOnlyCIDR(ip, network)
+
+	assert.Equal(t, ErrHostOnlyCIDRConflict{
+		CIDR:       "192.168.99.1/24",
+		Network:    "192.168.96.0/22",
+		Interface:  "utun2",
+		Candidates: []string{"192.168.100.1/24", "192.168.102.1/24", "192.168.103.1/24"},
+	}, err)
+	assert.EqualError(t, err, "host-only CIDR 192.168.99.1/24 overlaps with network 192.168.96.0/22 of utun2, pick a free one such as 192.168.100.1/24, 192.168.102.1/24, 192.168.103.1/24 with --virtualbox-hostonly-cidr or use --virtualbox-hostonly-cidr-fallback")
+}
+
+func TestCheckHostOnlyCIDRConflictsWithRoute(t *testing.T) {
+	defer fakeHostNetworks(
+		[]hostInterfaceNetwork{hostNetwork("en0", "10.0.0.0/24"), hostNetwork("utun3", "172.20.0.2/32")},
+		[]hostInterfaceNetwork{
+			hostNetwork("en0", "0.0.0.0/0"),
+			hostNetwork("en0", "10.0.0.0/24"),
+			hostNetwork("en0", "10.0.0.1/32"),
+			hostNetwork("lo0", "127.0.0.0/8"),
+			hostNetwork("en0", "224.0.0.0/4"),
+			hostNetwork("utun3", "192.168.96.0/20"),
+		},
+	)()
+
+	driver := newTestDriver("default")
+	driver.HostOnlyCIDR = "192.168.99.1/24"
+	driver.VBoxManager = &VBoxManagerMock{args: "list hostonlyifs", stdOut: ""}
+	ip, network, _ := parseAndValidateCIDR(driver.HostOnlyCIDR)
+
+	_, _, err := driver.checkHostOnlyCIDR(ip, network)
+
+	assert.IsType(t, ErrHostOnlyCIDRConflict{}, err)
+	assert.Equal(t, "192.168.96.0/20", err.(ErrHostOnlyCIDRConflict).Network)
+	assert.Equal(t, "utun3", err.(ErrHostOnlyCIDRConflict).Interface)
+	assert.Equal(t, []string{"192.168.112.1/24", "192.168.113.1/24", "192.168.114.1/24"}, err.(ErrHostOnlyCIDRConflict).Candidates)
+}
+
+func TestUsedHostNetworksSkipsRoutesOfHostOnlyNetworks(t *testing.T) {
+	defer fakeHostNetworks(nil, []hostInterfaceNetwork{hostNetwork("VirtualBox Host-Only Network", "192.168.99.0/24")})()
+
+	nets, err := listHostOnlyNetworks(&VBoxManagerMock{args: "list hostonlyifs", stdOut: stdOutOneHostOnlyNetwork})
+	assert.NoError(t, err)
+
+	used, err := usedHostNetworks(nets)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(used))
+	assert.Equal(t, "vboxnet0", used[0].Owner)
+}
+
+func TestParseProcNetRoute(t *testing.T) {
+	routes := parseProcNetRoute(`Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+eth0	00000000	0101A8C0	0003	0	0	100	00000000	0	0	0
+eth0	0001A8C0	00000000	0001	0	0	100	00FFFFFF	0	0	0
+tun0	0000080A	00000000	0001	0	0	0	0000FFFF	0	0	0
+`)
+
+	assert.Equal(t, []hostInterfaceNetwork{
+		hostNetwork("eth0", "0.0.0.0/0"),
+		hostNetwork("eth0", "192.168.1.0/24"),
+		hostNetwork("tun0", "10.8.0.0/16"),
+	}, routes)
+}
+
+func TestParseNetstatRoutes(t *testing.T) {
+	routes := parseNetstatRoutes(`Routing tables
+
+Internet:
+Destination        Gateway            Flags        Netif Expire
+default            192.168.1.1        UGScg          en0
+10.8/16            10.8.0.1           UGSc         utun3
+127                127.0.0.1          UCS            lo0
+192.168.1          link#6             UCS            en0      !
+192.168.1.1        a0:b1:c2:d3:e4:f5  UHLWIir        en0   1177
+`)
+
+	assert.Equal(t, []hostInterfaceNetwork{
+		hostNetwork("utun3", "10.8.0.0/16"),
+		hostNetwork("lo0", "127.0.0.0/8"),
+		hostNetwork("en0", "192.168.1.0/24"),
+		hostNetwork("en0", "192.168.1.1/32"),
+	}, routes)
+}
+
+func TestParseWindowsRoutes(t *testing.T) {
+	routes := parseWindowsRoutes("0.0.0.0/0 Wi-Fi\r\n10.8.0.0/16 Ethernet 2\r\n\r\n")
+
+	assert.Equal(t, []hostInterfaceNetwork{
+		hostNetwork("Wi-Fi", "0.0.0.0/0"),
+		hostNetwork("Ethernet 2", "10.8.0.0/16"),
+	}, routes)
+}
+
+func TestCheckHostOnlyCIDRConflictsWithHostOnlyNetwork(t *testing.T) {
+	defer fakeHostInterfaceNetworks()()
+
+	driver := newTestDriver("default")
+	driver.HostOnlyCIDR = "192.168.99.1/23"
+	driver.VBoxManager = &VBoxManagerMock{args: "list hostonlyifs", stdOut: stdOutOneHostOnlyNetwork}
+	ip, network, _ := parseAndValidateCIDR(driver.HostOnlyCIDR)
+
+	_, _, err := driver.checkHostOnlyCIDR(ip, network)
+
+	assert.IsType(t, ErrHostOnlyCIDRConflict{}, err)
+	assert.Equal(t, "vboxnet0", err.(ErrHostOnlyCIDRConflict).Interface)
+}
+
+func TestCheckHostOnlyCIDRFallback(t *testing.T) {
+	defer fakeHostInterfaceNetworks(hostNetwork("en0", "192.168.99.0/24"))()
+
+	driver := newTestDriver("default")
+	driver.HostOnlyCIDR = "192.168.99.1/24"
+	driver.HostOnlyCIDRFallback = true
+	driver.VBoxManager = &VBoxManagerMock{args: "list hostonlyifs", stdOut: ""}
+	ip, network, _ := parseAndValidateCIDR(driver.HostOnlyCIDR)
+
+	ip, network, err := driver.checkHostOnlyCIDR(ip, network)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.100.1/24", driver.HostOnlyCIDR)
+	assert.Equal(t, "192.168.100.1", ip.String())
+	assert.Equal(t, "192.168.100.0/24", network.String())
+}
+
+func TestUsedHostNetworksSkipsHostOnlyInterfaces(t *testing.T) {
+	hwAddr, _ := net.ParseMAC("0a:00:27:00:00:00")
+	vboxnet := hostNetwork("VirtualBox Host-Only Ethernet Adapter", "192.168.99.0/24")
+	vboxnet.HwAddr = hwAddr
+	defer fakeHostInterfaceNetworks(vboxnet, hostNetwork("en0", "10.0.0.0/24"))()
+
+	nets, err := listHostOnlyNetworks(&VBoxManagerMock{args: "list hostonlyifs", stdOut: stdOutOneHostOnlyNetwork})
+	assert.NoError(t, err)
+
+	used, err := usedHostNetworks(nets)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(used))
+	assert.Equal(t, "vboxnet0", used[0].Owner)
+	assert.True(t, used[0].HostOnly)
+	assert.Equal(t, "en0", used[1].Owner)
+}
+
+func TestNextFreeHostOnlyCIDRsStaysInFirstOctet(t *testing.T) {
+	ip, network, _ := parseAndValidateCIDR("10.255.254.1/24")
+
+	free := nextFreeHostOnlyCIDRs(ip, network.Mask, nil, 3)
+
+	assert.Equal(t, 1, len(free))
+	assert.Equal(t, "10.255.255.1/24", free[0].String())
+}
@@ -0,0 +1,120 @@
+package virtualbox
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	minHostOnlyMTU = 576
+	maxHostOnlyMTU = 9000
+)
+
+var (
+	// setHostInterfaceMTU and hostInterfaceMTU change and read the MTU of
+	// an interface of the host. VBoxManage can't, so the OS does.
+	setHostInterfaceMTU = runHostInterfaceMTUCommand
+	hostInterfaceMTU    = readHostInterfaceMTU
+)
+
+// validateHostOnlyMTU checks that a requested MTU is in a sane range, zero
+// meaning that the default one is kept.
+func validateHostOnlyMTU(mtu int) error {
+	if mtu != 0 && (mtu < minHostOnlyMTU || mtu > maxHostOnlyMTU) {
+		return fmt.Errorf("the host-only MTU must be between %d and %d, not %d", minHostOnlyMTU, maxHostOnlyMTU, mtu)
+	}
+
+	return nil
+}
+
+// hostInterfaceOf returns the interface of the host backing the host-only
+// network. It is looked up by hardware address, as on Windows the name of the
+// interface isn't the one VirtualBox reports.
+func hostInterfaceOf(n *hostOnlyNetwork) (*net.Interface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, iface := range ifaces {
+		if len(n.HwAddr) > 0 && bytes.Equal(iface.HardwareAddr, n.HwAddr) {
+			return &ifaces[i], nil
+		}
+	}
+
+	for i, iface := range ifaces {
+		if iface.Name == n.Name {
+			return &ifaces[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("no interface of the host backs the host-only network %s", n.Name)
+}
+
+func readHostInterfaceMTU(n *hostOnlyNetwork) (int, error) {
+	iface, err := hostInterfaceOf(n)
+	if err != nil {
+		return 0, err
+	}
+
+	return iface.MTU, nil
+}
+
+func runHostInterfaceMTUCommand(n *hostOnlyNetwork, mtu int) error {
+	args, err := hostInterfaceMTUCommand(n, mtu)
+	if err != nil {
+		return err
+	}
+
+	if output, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %s\n%s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// asRoot prefixes the command with sudo unless Machine runs as root, as
+// changing the interfaces of the host needs it.
+func asRoot(args []string) []string {
+	if os.Geteuid() == 0 {
+		return args
+	}
+
+	return append([]string{"sudo"}, args...)
+}
+
+// applyHostOnlyMTU sets the MTU of a host-only network the machine owns to
+// mtu, unless it is zero or the interface has it already, and records the
+// effective MTU of the network. The MTU of a network the machine only
+// reuses is left alone, as other machines use it.
+func applyHostOnlyMTU(n *hostOnlyNetwork, mtu int, owned bool) error {
+	effective, err := hostInterfaceMTU(n)
+	if err != nil {
+		log.Debugf("Unable to read the MTU of host-only interface %s: %s", n.Name, err)
+	}
+
+	if mtu != 0 && owned && (err != nil || effective != mtu) {
+		if err := setHostInterfaceMTU(n, mtu); err != nil {
+			return fmt.Errorf("unable to set the MTU of host-only interface %s to %d: %s", n.Name, mtu, err)
+		}
+		n.MTU = mtu
+		return nil
+	}
+
+	if err != nil {
+		return nil
+	}
+
+	n.MTU = effective
+	if mtu != 0 && effective != mtu {
+		log.Warnf("Host-only interface %s has an MTU of %d instead of %d", n.Name, effective, mtu)
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package virtualbox
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeHostInterfaces replaces the OS calls on the host interfaces with a map
+// of their MTUs, restored by the returned function.
+func fakeHostInterfaces(mtus map[string]int, setErr error) func() {
+	set, get := setHostInterfaceMTU, hostInterfaceMTU
+
+	setHostInterfaceMTU = func(n *hostOnlyNetwork, mtu int) error {
+		if setErr != nil {
+			return setErr
+		}
+		mtus[n.Name] = mtu
+		return nil
+	}
+	hostInterfaceMTU = func(n *hostOnlyNetwork) (int, error) {
+		mtu, present := mtus[n.Name]
+		if !present {
+			return 0, errors.New("no such interface")
+		}
+		return mtu, nil
+	}
+
+	return func() {
+		setHostInterfaceMTU, hostInterfaceMTU = set, get
+	}
+}
+
+func newCreatingVBoxManagerScript() *VBoxManagerScript {
+	return &VBoxManagerScript{
+		stdOut: map[string]string{
+			"hostonlyif create": "Interface 'vboxnet1' was successfully created",
+			"list dhcpservers":  stdOutCreatedDHCPServer,
+		},
+		stdOutSeq: map[string][]string{
+			"list hostonlyifs": {stdOutOneHostOnlyNetwork, stdOutOneHostOnlyNetwork + fmt.Sprintf(stdOutCreatedHostOnlyNetwork, "Up")},
+		},
+	}
+}
+
+func TestValidateHostOnlyMTU(t *testing.T) {
+	for _, mtu := range []int{0, 576, 1400, 9000} {
+		assert.NoError(t, validateHostOnlyMTU(mtu), "%d", mtu)
+	}
+
+	for _, mtu := range []int{-1, 575, 9001} {
+		assert.Error(t, validateHostOnlyMTU(mtu), "%d", mtu)
+	}
+}
+
+func TestCreateHostOnlyNetworkWithMTU(t *testing.T) {
+	mtus := map[string]int{"vboxnet1": 1500}
+	defer fakeHostInterfaces(mtus, nil)()
+
+	vbox := newCreatingVBoxManagerScript()
+
+	hostOnlyNet, created, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.100.1"), Netmask: parseIPv4Mask("255.255.255.0"), DHCPIP: net.ParseIP("192.168.100.6"), DHCPLowerIP: net.ParseIP("192.168.100.100"), DHCPUpperIP: net.ParseIP("192.168.100.254"), MTU: 1400}, vbox)
+
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, 1400, mtus["vboxnet1"])
+	assert.Equal(t, 1400, hostOnlyNet.MTU)
+}
+
+func TestCreateHostOnlyNetworkWithoutMTURecordsTheDefault(t *testing.T) {
+	mtus := map[string]int{"vboxnet1": 1500}
+	defer fakeHostInterfaces(mtus, errors.New("unexpected MTU change"))()
+
+	vbox := newCreatingVBoxManagerScript()
+
+	hostOnlyNet, _, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.100.1"), Netmask: parseIPv4Mask("255.255.255.0"), DHCPIP: net.ParseIP("192.168.100.6"), DHCPLowerIP: net.ParseIP("192.168.100.100"), DHCPUpperIP: net.ParseIP("192.168.100.254")}, vbox)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1500, hostOnlyNet.MTU)
+}
+
+func TestCreateHostOnlyNetworkFailsToSetMTU(t *testing.T) {
+	defer fakeHostInterfaces(map[string]int{}, errors.New("ip link set dev vboxnet1 mtu 1400 failed: exit status 2"))()
+
+	vbox := newCreatingVBoxManagerScript()
+
+	_, _, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.100.1"), Netmask: parseIPv4Mask("255.255.255.0"), DHCPIP: net.ParseIP("192.168.100.6"), DHCPLowerIP: net.ParseIP("192.168.100.100"), DHCPUpperIP: net.ParseIP("192.168.100.254"), MTU: 1400}, vbox)
+
+	assert.EqualError(t, err, "unable to set the MTU of host-only interface vboxnet1 to 1400: ip link set dev vboxnet1 mtu 1400 failed: exit status 2")
+	assert.Contains(t, vbox.calls, "hostonlyif remove vboxnet1")
+}
+
+func TestApplyHostOnlyMTUReappliesToOwnedNetwork(t *testing.T) {
+	mtus := map[string]int{"vboxnet0": 1500}
+	defer fakeHostInterfaces(mtus, nil)()
+
+	n := &hostOnlyNetwork{Name: "vboxnet0"}
+
+	assert.NoError(t, applyHostOnlyMTU(n, 1400, true))
+	assert.Equal(t, 1400, mtus["vboxnet0"])
+	assert.Equal(t, 1400, n.MTU)
+}
+
+func TestApplyHostOnlyMTULeavesInterfaceWithTheMTU(t *testing.T) {
+	defer fakeHostInterfaces(map[string]int{"vboxnet0": 1400}, errors.New("unexpected MTU change"))()
+
+	n := &hostOnlyNetwork{Name: "vboxnet0"}
+
+	assert.NoError(t, applyHostOnlyMTU(n, 1400, true))
+	assert.Equal(t, 1400, n.MTU)
+}
+
+func TestGetHostOnlyNetworkLeavesMTUOfReusedNetwork(t *testing.T) {
+	mtus := map[string]int{"vboxnet0": 1500}
+	defer fakeHostInterfaces(mtus, errors.New("unexpected MTU change"))()
+
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs": stdOutOneHostOnlyNetwork,
+		},
+	}
+
+	hostOnlyNet, created, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.99.1"), Netmask: parseIPv4Mask("255.255.255.0"), MTU: 1400}, vbox)
+
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, 1500, hostOnlyNet.MTU)
+}
+
+func TestGetOrCreateHostOnlyNetworkRejectsInvalidMTU(t *testing.T) {
+	vbox := &VBoxManagerScript{}
+
+	_, _, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.99.1"), Netmask: parseIPv4Mask("255.255.255.0"), MTU: 100}, vbox)
+
+	assert.EqualError(t, err, "the host-only MTU must be between 576 and 9000, not 100")
+	assert.Empty(t, vbox.calls)
+}
+
+func TestHostInterfaceOfByName(t *testing.T) {
+	ifaces, err := net.Interfaces()
+	if err != nil || len(ifaces) == 0 {
+		t.Skip("no interface to look up")
+	}
+
+	iface, err := hostInterfaceOf(&hostOnlyNetwork{Name: ifaces[0].Name})
+
+	assert.NoError(t, err)
+	assert.Equal(t, ifaces[0].MTU, iface.MTU)
+
+	_, err = hostInterfaceOf(&hostOnlyNetwork{Name: "vboxnet-does-not-exist"})
+	assert.Error(t, err)
+}
@@ -2,34 +2,86 @@ package virtualbox
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"net"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/docker/machine/libmachine/log"
 )
 
 const (
 	buggyNetmask = "0f000000"
 )
 
+// buggyNetmaskVersions is the range of VirtualBox versions, lower bound
+// included and upper bound excluded, which can report buggyNetmask for a
+// newly created host-only interface on Windows 10.
+var buggyNetmaskVersions = [2]vboxVersion{{5, 0, 0}, {5, 1, 0}}
+
 var (
 	reHostonlyInterfaceCreated            = regexp.MustCompile(`Interface '(.+)' was successfully created`)
+	reHostOnlyInterfaceIndex              = regexp.MustCompile(`(\d+)$`)
 	errDuplicateHostOnlyInterfaceNetworks = errors.New("VirtualBox is configured with multiple host-only interfaces with the same IP. Please remove all of them but one.")
+	errHostOnlyNetworkNotFound            = errors.New("host-only network not found")
+	errHostOnlyNetworkNotSettled          = errors.New("host-only network creation did not settle")
+
+	// How long to wait for a newly created host-only network to show up.
+	hostOnlyNetworkSettleTimeout = 10 * time.Second
+
+	// How long to wait for the DHCP server of a newly created host-only
+	// network to be ready. Zero skips waiting.
+	dhcpServerReadyTimeout = 10 * time.Second
 )
 
+// mediumType is the kind of medium of a host-only interface.
+type mediumType int
+
+const (
+	mediumUnknown mediumType = iota
+	mediumEthernet
+)
+
+// parseMediumType parses the MediumType of a host-only interface. The casing
+// varies across VirtualBox versions.
+func parseMediumType(s string) mediumType {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "ethernet":
+		return mediumEthernet
+	}
+
+	return mediumUnknown
+}
+
+func (m mediumType) String() string {
+	switch m {
+	case mediumEthernet:
+		return "Ethernet"
+	}
+
+	return "unknown"
+}
+
 // Host-only network.
 type hostOnlyNetwork struct {
 	Name        string
 	GUID        string
 	DHCP        bool
-	IPv4        net.IPNet
+	IPv4        net.IPNet   // primary IPv4 network
+	IPv4Addrs   []net.IPNet // all IPv4 networks, including the primary one
 	IPv6        net.IPNet
 	HwAddr      net.HardwareAddr
-	Medium      string
+	Medium      mediumType
+	MediumName  string // MediumType as reported by VirtualBox
 	Status      string
 	NetworkName string // referenced in DHCP.NetworkName
+	MTU         int    // of the host interface, 0 if unknown
 }
 
 // Save changes the configuration of the host-only network.
@@ -54,6 +106,20 @@ func (n *hostOnlyNetwork) Save(vbox VBoxManager) error {
 	return nil
 }
 
+// unhealthyReason returns why the host-only network can't be used as is, or
+// an empty string if it is healthy.
+func (n *hostOnlyNetwork) unhealthyReason() string {
+	if n.Status == "Down" {
+		return "its interface is down"
+	}
+
+	if n.IPv4.IP == nil || n.IPv4.IP.IsUnspecified() {
+		return "its interface has no IP address"
+	}
+
+	return ""
+}
+
 // createHostonlyNet creates a new host-only network.
 func createHostonlyNet(vbox VBoxManager) (*hostOnlyNetwork, error) {
 	out, err := vbox.vbmOut("hostonlyif", "create")
@@ -66,7 +132,10 @@ func createHostonlyNet(vbox VBoxManager) (*hostOnlyNetwork, error) {
 		return nil, errors.New("failed to create hostonly interface")
 	}
 
-	return &hostOnlyNetwork{Name: res[1]}, nil
+	return &hostOnlyNetwork{
+		Name:        res[1],
+		NetworkName: "HostInterfaceNetworking-" + res[1],
+	}, nil
 }
 
 // listHostOnlyNetworks gets all host-only networks in a  map keyed by HostonlyNet.NetworkName.
@@ -99,9 +168,21 @@ func listHostOnlyNetworks(vbox VBoxManager) (map[string]*hostOnlyNetwork, error)
 		case "DHCP":
 			n.DHCP = (val != "Disabled")
 		case "IPAddress":
-			n.IPv4.IP = net.ParseIP(val)
+			// Multi-homed interfaces list one IPAddress/NetworkMask
+			// pair per address, the first one being the primary.
+			ip := net.ParseIP(val)
+			n.IPv4Addrs = append(n.IPv4Addrs, net.IPNet{IP: ip})
+			if len(n.IPv4Addrs) == 1 {
+				n.IPv4.IP = ip
+			}
 		case "NetworkMask":
-			n.IPv4.Mask = parseIPv4Mask(val)
+			mask := parseIPv4Mask(val)
+			if len(n.IPv4Addrs) > 0 {
+				n.IPv4Addrs[len(n.IPv4Addrs)-1].Mask = mask
+			}
+			if len(n.IPv4Addrs) <= 1 {
+				n.IPv4.Mask = mask
+			}
 		case "IPV6Address":
 			n.IPv6.IP = net.ParseIP(val)
 		case "IPV6NetworkMaskPrefixLength":
@@ -117,7 +198,8 @@ func listHostOnlyNetworks(vbox VBoxManager) (map[string]*hostOnlyNetwork, error)
 			}
 			n.HwAddr = mac
 		case "MediumType":
-			n.Medium = val
+			n.Medium = parseMediumType(val)
+			n.MediumName = val
 		case "Status":
 			n.Status = val
 		case "VBoxNetworkName":
@@ -133,60 +215,867 @@ func listHostOnlyNetworks(vbox VBoxManager) (map[string]*hostOnlyNetwork, error)
 	return m, nil
 }
 
-func getHostOnlyNetwork(nets map[string]*hostOnlyNetwork, hostIP net.IP, netmask net.IPMask) *hostOnlyNetwork {
+// diffHostOnlyNetwork describes how the actual host-only network differs from
+// the desired one, one field per line, e.g. "IPv4 netmask: got 255.255.0.0,
+// want 255.255.255.0". Every field is compared both ways, so a field only set
+// on one of the networks is reported too, as "none" on the other. A nil
+// network differs from any other.
+func diffHostOnlyNetwork(desired, actual *hostOnlyNetwork) []string {
+	switch {
+	case desired == nil && actual == nil:
+		return []string{}
+	case actual == nil:
+		return []string{"no host-only network"}
+	case desired == nil:
+		return []string{fmt.Sprintf("unexpected host-only network %s", actual.Name)}
+	}
+
+	diff := []string{}
+	add := func(field, got, want string) {
+		if got == want {
+			return
+		}
+		if got == "" {
+			got = "none"
+		}
+		if want == "" {
+			want = "none"
+		}
+		diff = append(diff, fmt.Sprintf("%s: got %s, want %s", field, got, want))
+	}
+
+	add("Name", actual.Name, desired.Name)
+	add("GUID", actual.GUID, desired.GUID)
+	add("NetworkName", actual.NetworkName, desired.NetworkName)
+	add("IPv4 address", ipString(actual.IPv4.IP), ipString(desired.IPv4.IP))
+	add("IPv4 netmask", maskString(actual.IPv4.Mask), maskString(desired.IPv4.Mask))
+	add("IPv4 addresses", ipNetsString(actual.IPv4Addrs), ipNetsString(desired.IPv4Addrs))
+	add("IPv6 address", ipString(actual.IPv6.IP), ipString(desired.IPv6.IP))
+	add("IPv6 prefix length", prefixLengthString(actual.IPv6.Mask), prefixLengthString(desired.IPv6.Mask))
+	add("HwAddr", actual.HwAddr.String(), desired.HwAddr.String())
+	add("MediumName", actual.MediumName, desired.MediumName)
+	add("DHCP", enabledString(actual.DHCP), enabledString(desired.DHCP))
+	add("Status", actual.Status, desired.Status)
+	add("MTU", mtuString(actual.MTU), mtuString(desired.MTU))
+
+	return diff
+}
+
+func ipString(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
+func maskString(mask net.IPMask) string {
+	if mask == nil {
+		return ""
+	}
+	return net.IP(mask).String()
+}
+
+func prefixLengthString(mask net.IPMask) string {
+	if mask == nil {
+		return ""
+	}
+	ones, _ := mask.Size()
+	return strconv.Itoa(ones)
+}
+
+func ipNetsString(nets []net.IPNet) string {
+	strs := []string{}
+	for _, n := range nets {
+		strs = append(strs, n.String())
+	}
+	return strings.Join(strs, ",")
+}
+
+func enabledString(enabled bool) string {
+	if enabled {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+func mtuString(mtu int) string {
+	if mtu == 0 {
+		return ""
+	}
+	return strconv.Itoa(mtu)
+}
+
+// listActiveHostOnlyNetworks gets the host-only networks which can be used
+// right away, in a map keyed by HostonlyNet.NetworkName.
+func listActiveHostOnlyNetworks(vbox VBoxManager) (map[string]*hostOnlyNetwork, error) {
+	nets, err := listHostOnlyNetworks(vbox)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, n := range nets {
+		if !n.isActive() {
+			delete(nets, name)
+		}
+	}
+
+	return nets, nil
+}
+
+// isActive tells whether the host-only network can be used right away, i.e.
+// whether it's healthy.
+func (n *hostOnlyNetwork) isActive() bool {
+	return n.unhealthyReason() == ""
+}
+
+// sortedHostOnlyNetworks returns the networks of nets sorted by interface
+// name, in natural order: vboxnet2 comes before vboxnet10.
+func sortedHostOnlyNetworks(nets map[string]*hostOnlyNetwork) []*hostOnlyNetwork {
+	sorted := []*hostOnlyNetwork{}
 	for _, n := range nets {
-		// Second part of this conditional handles a race where
-		// VirtualBox returns us the incorrect netmask value for the
-		// newly created interface.
-		if hostIP.Equal(n.IPv4.IP) &&
-			(netmask.String() == n.IPv4.Mask.String() || n.IPv4.Mask.String() == buggyNetmask) {
-			return n
+		sorted = append(sorted, n)
+	}
+
+	sort.Sort(byHostOnlyNetworkName(sorted))
+
+	return sorted
+}
+
+type byHostOnlyNetworkName []*hostOnlyNetwork
+
+func (n byHostOnlyNetworkName) Len() int           { return len(n) }
+func (n byHostOnlyNetworkName) Swap(i, j int)      { n[i], n[j] = n[j], n[i] }
+func (n byHostOnlyNetworkName) Less(i, j int) bool { return naturalLess(n[i].Name, n[j].Name) }
+
+// naturalLess compares a and b as strings, except that runs of digits are
+// compared by their numeric value.
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		aDigits, bDigits := leadingDigits(a), leadingDigits(b)
+		if aDigits != "" && bDigits != "" {
+			aTrimmed, bTrimmed := strings.TrimLeft(aDigits, "0"), strings.TrimLeft(bDigits, "0")
+			if len(aTrimmed) != len(bTrimmed) {
+				return len(aTrimmed) < len(bTrimmed)
+			}
+			if aTrimmed != bTrimmed {
+				return aTrimmed < bTrimmed
+			}
+			a, b = a[len(aDigits):], b[len(bDigits):]
+			continue
+		}
+
+		if a[0] != b[0] {
+			return a[0] < b[0]
 		}
+		a, b = a[1:], b[1:]
 	}
 
-	return nil
+	return len(a) < len(b)
 }
 
-func getOrCreateHostOnlyNetwork(hostIP net.IP, netmask net.IPMask, dhcpIP net.IP, dhcpLowerIP net.IP, dhcpUpperIP net.IP, vbox VBoxManager) (*hostOnlyNetwork, error) {
+func leadingDigits(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+
+	return s[:i]
+}
+
+// getHostOnlyNetworkByGUID looks up a host-only network by its GUID, which
+// unlike its name or subnet doesn't change if the interfaces get renumbered.
+func getHostOnlyNetworkByGUID(guid string, vbox VBoxManager) (*hostOnlyNetwork, error) {
 	nets, err := listHostOnlyNetworks(vbox)
 	if err != nil {
 		return nil, err
 	}
 
+	byGUID := map[string]*hostOnlyNetwork{}
+	for _, n := range nets {
+		byGUID[n.GUID] = n
+	}
+
+	n, present := byGUID[guid]
+	if !present {
+		return nil, errHostOnlyNetworkNotFound
+	}
+
+	return n, nil
+}
+
+// mediumName returns the medium of the host-only interface for display,
+// preferring the name VirtualBox reported over the parsed type.
+func (n *hostOnlyNetwork) mediumName() string {
+	if n.MediumName != "" {
+		return n.MediumName
+	}
+
+	return n.Medium.String()
+}
+
+// ipv4Networks returns all the IPv4 networks of the host-only network.
+func (n *hostOnlyNetwork) ipv4Networks() []net.IPNet {
+	if len(n.IPv4Addrs) == 0 {
+		return []net.IPNet{n.IPv4}
+	}
+
+	return n.IPv4Addrs
+}
+
+// hasBuggyNetmask reports whether the installed VirtualBox may misreport the
+// netmask of newly created host-only interfaces. If the version can't be
+// determined, it is assumed not to, so that networks must match exactly.
+func hasBuggyNetmask(vbox VBoxManager) bool {
+	v, err := getVBoxVersion(vbox)
+	if err != nil {
+		log.Debugf("Unable to get the VirtualBox version, assuming netmasks are reported correctly: %s", err)
+		return false
+	}
+
+	return !v.lessThan(buggyNetmaskVersions[0]) && v.lessThan(buggyNetmaskVersions[1])
+}
+
+// findHostOnlyNetwork returns the network matching hostIP and netmask. The
+// VirtualBox version is only looked up when there is no exact match, to decide
+// whether a network reported with the buggy netmask may be used instead.
+func findHostOnlyNetwork(nets map[string]*hostOnlyNetwork, hostIP net.IP, netmask net.IPMask, vbox VBoxManager) *hostOnlyNetwork {
+	if n := getHostOnlyNetwork(nets, hostIP, netmask, false); n != nil {
+		return n
+	}
+
+	if !hasBuggyNetmask(vbox) {
+		return nil
+	}
+
+	return getHostOnlyNetwork(nets, hostIP, netmask, true)
+}
+
+// getHostOnlyNetwork returns the network matching hostIP and netmask. If
+// lenientMask is true, a network reported with the buggy netmask matches too.
+func getHostOnlyNetwork(nets map[string]*hostOnlyNetwork, hostIP net.IP, netmask net.IPMask, lenientMask bool) *hostOnlyNetwork {
+	// Look for an exact match first so that a network reported with the
+	// buggy netmask never wins over one which really has the right subnet.
+	for _, n := range nets {
+		for _, ipv4 := range n.ipv4Networks() {
+			if hostIP.Equal(ipv4.IP) && netmask.String() == ipv4.Mask.String() {
+				return n
+			}
+		}
+	}
+
+	if !lenientMask {
+		return nil
+	}
+
+	// This handles a race where VirtualBox returns us the incorrect netmask
+	// value for the newly created interface.
+	for _, n := range nets {
+		for _, ipv4 := range n.ipv4Networks() {
+			if hostIP.Equal(ipv4.IP) && ipv4.Mask.String() == buggyNetmask {
+				return n
+			}
+		}
+	}
+
+	return nil
+}
+
+// dhcpRequirement is the state of the DHCP server a caller requires of a
+// host-only network.
+type dhcpRequirement int
+
+const (
+	// dhcpAny reuses a matching network whatever the state of its DHCP
+	// server.
+	dhcpAny dhcpRequirement = iota
+	// dhcpEnabled requires the DHCP server of the network to be enabled.
+	dhcpEnabled
+	// dhcpDisabled requires the network to have no enabled DHCP server, for
+	// static addressing.
+	dhcpDisabled
+)
+
+// hostOnlyNetworkRequest describes the host-only network a machine needs.
+type hostOnlyNetworkRequest struct {
+	// HostIP and Netmask are the address of the host on the network.
+	HostIP  net.IP
+	Netmask net.IPMask
+	// DHCPIP, DHCPLowerIP and DHCPUpperIP configure the DHCP server of a
+	// created network.
+	DHCPIP      net.IP
+	DHCPLowerIP net.IP
+	DHCPUpperIP net.IP
+	// IfIndex is the index of the host-only interface the network must be,
+	// as told by hostOnlyInterfaceIndex, if not nil.
+	IfIndex *int
+	// DHCP is the state of the DHCP server required of the network.
+	DHCP dhcpRequirement
+	// RecreateUnhealthy removes and creates again a matching network which
+	// is unhealthy, unless a VM is attached to it.
+	RecreateUnhealthy bool
+	// MTU is the MTU of a created network, the default one if zero.
+	MTU int
+}
+
+// getOrCreateHostOnlyNetwork returns the host-only network matching the
+// request, creating it if none exists. If an existing network doesn't have
+// the DHCP server state required, its DHCP server is toggled rather than
+// left as is. The returned boolean is true if the network was created by
+// this call rather than reused. The returned network records the effective
+// MTU.
+func getOrCreateHostOnlyNetwork(req hostOnlyNetworkRequest, vbox VBoxManager) (*hostOnlyNetwork, bool, error) {
+	return getOrCreateHostOnlyNetworkContext(context.Background(), req, vbox)
+}
+
+// getOrCreateHostOnlyNetworkContext is getOrCreateHostOnlyNetwork, stopping
+// with ctx.Err() once ctx is done. The VBoxManage command which is running
+// at that point is killed, no other one is started and the polling for the
+// network and its DHCP server stops.
+func getOrCreateHostOnlyNetworkContext(ctx context.Context, req hostOnlyNetworkRequest, vbox VBoxManager) (*hostOnlyNetwork, bool, error) {
+	cleanup := vbox
+	vbox = contextBoundVBoxManager{cleanup, ctx}
+
+	if err := validateHostOnlyMTU(req.MTU); err != nil {
+		return nil, false, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	if err := checkHostOnlyGatewayIP(req.HostIP, req.DHCPIP, req.DHCPLowerIP, req.DHCPUpperIP); err != nil {
+		return nil, false, err
+	}
+
+	nets, err := listHostOnlyNetworks(vbox)
+	if err != nil {
+		return nil, false, err
+	}
+
 	if len(nets) != countUniqueIps(nets) {
-		return nil, errDuplicateHostOnlyInterfaceNetworks
+		return nil, false, errDuplicateHostOnlyInterfaceNetworks
 	}
 
-	hostOnlyNet := getHostOnlyNetwork(nets, hostIP, netmask)
+	hostOnlyNet := findHostOnlyNetwork(nets, req.HostIP, req.Netmask, vbox)
+	if req.IfIndex != nil {
+		if err := checkHostOnlyInterfaceIndex(nets, hostOnlyNet, *req.IfIndex, req.HostIP, req.Netmask); err != nil {
+			return nil, false, err
+		}
+	}
+	if hostOnlyNet == nil && req.IfIndex == nil {
+		hostOnlyNet, err = resizeHostOnlyNetwork(nets, req.HostIP, req.Netmask, req.DHCPIP, req.DHCPLowerIP, req.DHCPUpperIP, vbox)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	if hostOnlyNet != nil && req.RecreateUnhealthy {
+		if reason := hostOnlyNet.unhealthyReason(); reason != "" {
+			if err := removeUnhealthyHostOnlyNetwork(hostOnlyNet, reason, vbox); err != nil {
+				return nil, false, err
+			}
+			delete(nets, hostOnlyNet.NetworkName)
+			hostOnlyNet = nil
+		}
+	}
 	if hostOnlyNet != nil {
-		return hostOnlyNet, nil
+		if err := reconfigureHostOnlyDHCP(hostOnlyNet, req.DHCP, req.DHCPIP, req.DHCPLowerIP, req.DHCPUpperIP, vbox); err != nil {
+			return nil, false, err
+		}
+		if err := applyHostOnlyMTU(hostOnlyNet, req.MTU, false); err != nil {
+			return nil, false, err
+		}
+		return hostOnlyNet, false, nil
+	}
+
+	requested := net.IPNet{IP: req.HostIP.Mask(req.Netmask), Mask: req.Netmask}
+	if n, conflict := findOverlappingHostOnlyNetwork(requested, nets); n != nil {
+		return nil, false, fmt.Errorf("host-only network %s overlaps with %s on %s", requested.String(), conflict.String(), n.Name)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
 	}
 
 	// No existing host-only interface found. Create a new one.
 	hostOnlyNet, err = createHostonlyNet(vbox)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	hostOnlyNet.IPv4.IP = hostIP
-	hostOnlyNet.IPv4.Mask = netmask
+	// VirtualBox doesn't let us name the interface, it picks the first free
+	// one. Undo the creation if that isn't the one we were asked for.
+	if req.IfIndex != nil && hostOnlyInterfaceIndex(hostOnlyNet.Name) != *req.IfIndex {
+		removeCreatedHostOnlyInterface(hostOnlyNet.Name, cleanup)
+		return nil, false, fmt.Errorf("VirtualBox created host-only interface %s instead of the one of index %d", hostOnlyNet.Name, *req.IfIndex)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	hostOnlyNet.IPv4.IP = req.HostIP
+	hostOnlyNet.IPv4.Mask = req.Netmask
 	if err := hostOnlyNet.Save(vbox); err != nil {
+		return nil, false, err
+	}
+
+	created := hostOnlyNet.Name
+	hostOnlyNet, err = waitForHostOnlyNetworkContext(ctx, hostOnlyNet.NetworkName, hostOnlyNetworkSettleTimeout, vbox)
+	if err != nil {
+		removeCreatedHostOnlyInterface(created, cleanup)
+		return nil, false, err
+	}
+
+	if err := applyHostOnlyMTU(hostOnlyNet, req.MTU, true); err != nil {
+		removeCreatedHostOnlyInterface(hostOnlyNet.Name, cleanup)
+		return nil, false, err
+	}
+
+	if req.DHCP == dhcpDisabled {
+		// Some platforms add an enabled DHCP server to new interfaces.
+		if err := reconfigureHostOnlyDHCP(hostOnlyNet, req.DHCP, req.DHCPIP, req.DHCPLowerIP, req.DHCPUpperIP, vbox); err != nil {
+			return nil, false, err
+		}
+		return hostOnlyNet, true, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	dhcpSrv := dhcpServer{}
+	dhcpSrv.IPv4.IP = req.DHCPIP
+	dhcpSrv.IPv4.Mask = req.Netmask
+	dhcpSrv.LowerIP = req.DHCPLowerIP
+	dhcpSrv.UpperIP = req.DHCPUpperIP
+	dhcpSrv.Enabled = true
+	if err := addHostonlyDHCP(hostOnlyNet.Name, dhcpSrv, vbox); err != nil {
+		return nil, false, err
+	}
+
+	if dhcpServerReadyTimeout > 0 {
+		if err := waitForDHCPServerContext(ctx, vbox, hostOnlyNet.NetworkName, dhcpServerReadyTimeout); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return hostOnlyNet, true, nil
+}
+
+// removeHostOnlyNetwork removes the host-only interface of the network along
+// with its DHCP server, if it has one.
+func removeHostOnlyNetwork(hostOnlyNet *hostOnlyNetwork, vbox VBoxManager) error {
+	if err := vbox.vbm("dhcpserver", "remove", "--netname", hostOnlyNet.NetworkName); err != nil {
+		log.Debugf("Unable to remove the DHCP server of %s: %s", hostOnlyNet.Name, err)
+	}
+
+	return vbox.vbm("hostonlyif", "remove", hostOnlyNet.Name)
+}
+
+// removeCreatedHostOnlyInterface removes the host-only interface a failed
+// getOrCreateHostOnlyNetwork or reconcileHostOnlyNetwork created, so that it
+// isn't left behind.
+func removeCreatedHostOnlyInterface(name string, vbox VBoxManager) {
+	if err := vbox.vbm("hostonlyif", "remove", name); err != nil {
+		log.Warnf("Unable to remove host-only interface %s: %s", name, err)
+	}
+}
+
+// resizeHostOnlyNetwork looks for a host-only network which has hostIP with
+// another netmask, such as a /25 when a /24 is requested, and changes its
+// netmask in place along with the range of its DHCP server. It returns nil if
+// there is none, or if resizing it would overlap another network. It fails if
+// a VM is attached to the network, as resizing it would change the network
+// under the machines using it.
+func resizeHostOnlyNetwork(nets map[string]*hostOnlyNetwork, hostIP net.IP, netmask net.IPMask, dhcpIP, dhcpLowerIP, dhcpUpperIP net.IP, vbox VBoxManager) (*hostOnlyNetwork, error) {
+	var resized *hostOnlyNetwork
+	for _, n := range sortedHostOnlyNetworks(nets) {
+		if _, bits := n.IPv4.Mask.Size(); bits != 0 && hostIP.Equal(n.IPv4.IP) {
+			resized = n
+			break
+		}
+	}
+	if resized == nil {
+		return nil, nil
+	}
+
+	others := map[string]*hostOnlyNetwork{}
+	for networkName, n := range nets {
+		if n != resized {
+			others[networkName] = n
+		}
+	}
+
+	requested := net.IPNet{IP: hostIP.Mask(netmask), Mask: netmask}
+	if n, _ := findOverlappingHostOnlyNetwork(requested, others); n != nil {
+		return nil, nil
+	}
+
+	users, err := vmsUsingHostOnlyNetwork(resized.Name, "", vbox)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(users) > 0 {
+		return nil, fmt.Errorf("host-only network %s has the address %s with the netmask %s, which can't be changed to %s as it is used by %s", resized.Name, hostIP, net.IP(resized.IPv4.Mask), net.IP(netmask), strings.Join(users, ", "))
+	}
+
+	log.Infof("Changing the netmask of host-only network %s from %s to %s", resized.Name, net.IP(resized.IPv4.Mask), net.IP(netmask))
+
+	resized.IPv4.Mask = netmask
+	if err := resized.Save(vbox); err != nil {
+		return nil, err
+	}
+
+	if dhcpIP == nil || dhcpLowerIP == nil || dhcpUpperIP == nil {
+		return resized, nil
+	}
+
+	dhcps, err := getDHCPServers(vbox)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, present := dhcps[resized.NetworkName]; !present {
+		return resized, nil
+	}
+
+	if err := updateDHCPServerRange(vbox, resized.NetworkName, requested, dhcpLowerIP, dhcpUpperIP, dhcpIP); err != nil {
 		return nil, err
 	}
 
-	dhcp := dhcpServer{}
-	dhcp.IPv4.IP = dhcpIP
-	dhcp.IPv4.Mask = netmask
-	dhcp.LowerIP = dhcpLowerIP
-	dhcp.UpperIP = dhcpUpperIP
-	dhcp.Enabled = true
-	if err := addHostonlyDHCP(hostOnlyNet.Name, dhcp, vbox); err != nil {
+	return resized, nil
+}
+
+// updateDHCPServerRange changes the address and range of the existing DHCP
+// server of networkName in place, for instance after its subnet was resized.
+// Whether the server is enabled is kept as is. The addresses must be within
+// subnet, which becomes the netmask of the server.
+func updateDHCPServerRange(vbox VBoxManager, networkName string, subnet net.IPNet, lower, upper, serverIP net.IP) error {
+	for _, ip := range []net.IP{lower, upper, serverIP} {
+		if !subnet.Contains(ip) {
+			return fmt.Errorf("DHCP address %s isn't within %s", ip, subnet.String())
+		}
+	}
+
+	if bytes.Compare(lower.To16(), upper.To16()) > 0 {
+		return fmt.Errorf("invalid DHCP range %s-%s, the lower address is above the upper one", lower, upper)
+	}
+
+	dhcps, err := getDHCPServers(vbox)
+	if err != nil {
+		return err
+	}
+
+	dhcpSrv, present := dhcps[networkName]
+	if !present {
+		return fmt.Errorf("host-only network %s has no DHCP server", networkName)
+	}
+
+	enable := "--disable"
+	if dhcpSrv.Enabled {
+		enable = "--enable"
+	}
+
+	return vbox.vbm("dhcpserver", "modify",
+		"--netname", networkName,
+		"--ip", serverIP.String(),
+		"--netmask", net.IP(subnet.Mask).String(),
+		"--lowerip", lower.String(),
+		"--upperip", upper.String(),
+		enable)
+}
+
+// checkHostOnlyGatewayIP fails if the addresses given to the machines of a
+// host-only network include hostIP, the address of the host on it, whether the
+// network is matched or created. A machine given that address would clobber
+// the host endpoint and neither side could reach the other.
+func checkHostOnlyGatewayIP(hostIP, dhcpIP, dhcpLowerIP, dhcpUpperIP net.IP) error {
+	if hostIP == nil {
+		return nil
+	}
+
+	if dhcpIP != nil && dhcpIP.Equal(hostIP) {
+		return fmt.Errorf("the DHCP server address %s is the host's own address on the host-only network", dhcpIP)
+	}
+
+	if dhcpLowerIP != nil && dhcpUpperIP != nil &&
+		bytes.Compare(dhcpLowerIP.To16(), hostIP.To16()) <= 0 && bytes.Compare(hostIP.To16(), dhcpUpperIP.To16()) <= 0 {
+		return fmt.Errorf("the DHCP range %s-%s includes %s, the host's own address on the host-only network, which a machine could be given", dhcpLowerIP, dhcpUpperIP, hostIP)
+	}
+
+	return nil
+}
+
+// removeUnhealthyHostOnlyNetwork removes an unhealthy host-only network so
+// that it can be created again. It fails if any VM is attached to it, as
+// removing the interface would leave them without it.
+func removeUnhealthyHostOnlyNetwork(hostOnlyNet *hostOnlyNetwork, reason string, vbox VBoxManager) error {
+	users, err := vmsUsingHostOnlyNetwork(hostOnlyNet.Name, "", vbox)
+	if err != nil {
+		return err
+	}
+
+	if len(users) > 0 {
+		return fmt.Errorf("host-only network %s is unhealthy, %s, but can't be recreated as it is used by %s", hostOnlyNet.Name, reason, strings.Join(users, ", "))
+	}
+
+	log.Warnf("Recreating host-only network %s, %s", hostOnlyNet.Name, reason)
+
+	return removeHostOnlyNetwork(hostOnlyNet, vbox)
+}
+
+// reconfigureHostOnlyDHCP enables or disables the DHCP server of an existing
+// host-only network, if its state doesn't match the required one. The other
+// settings of an existing DHCP server are kept; dhcpIP, dhcpLowerIP and
+// dhcpUpperIP are only used if the network has none.
+func reconfigureHostOnlyDHCP(hostOnlyNet *hostOnlyNetwork, dhcp dhcpRequirement, dhcpIP, dhcpLowerIP, dhcpUpperIP net.IP, vbox VBoxManager) error {
+	if dhcp == dhcpAny {
+		return nil
+	}
+
+	dhcps, err := getDHCPServers(vbox)
+	if err != nil {
+		return err
+	}
+
+	dhcpSrv, present := dhcps[hostOnlyNet.NetworkName]
+	enabled := present && dhcpSrv.Enabled
+	if enabled == (dhcp == dhcpEnabled) {
+		return nil
+	}
+
+	if !present {
+		dhcpSrv = &dhcpServer{
+			IPv4:    net.IPNet{IP: dhcpIP, Mask: hostOnlyNet.IPv4.Mask},
+			LowerIP: dhcpLowerIP,
+			UpperIP: dhcpUpperIP,
+		}
+	}
+	dhcpSrv.Enabled = dhcp == dhcpEnabled
+
+	if dhcpSrv.Enabled {
+		log.Infof("Enabling the DHCP server of host-only network %s", hostOnlyNet.Name)
+	} else {
+		log.Infof("Disabling the DHCP server of host-only network %s", hostOnlyNet.Name)
+	}
+
+	return addHostonlyDHCP(hostOnlyNet.Name, *dhcpSrv, vbox)
+}
+
+// findOverlappingHostOnlyNetwork returns the host-only network with a subnet
+// which partially overlaps requested, i.e. contains it or is contained by it
+// without being identical, along with the conflicting subnet. Overlapping
+// subnets leave the host with ambiguous routes.
+func findOverlappingHostOnlyNetwork(requested net.IPNet, nets map[string]*hostOnlyNetwork) (*hostOnlyNetwork, *net.IPNet) {
+	requestedOnes, _ := requested.Mask.Size()
+
+	for _, n := range sortedHostOnlyNetworks(nets) {
+		for _, ipv4 := range n.ipv4Networks() {
+			ones, bits := ipv4.Mask.Size()
+			if ipv4.IP == nil || bits == 0 {
+				// Unset or non canonical mask, such as the buggy one.
+				continue
+			}
+
+			existing := net.IPNet{IP: ipv4.IP.Mask(ipv4.Mask), Mask: ipv4.Mask}
+			if ones == requestedOnes && existing.IP.Equal(requested.IP) {
+				continue
+			}
+
+			if existing.Contains(requested.IP) || requested.Contains(existing.IP) {
+				return n, &existing
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// waitForHostOnlyNetwork polls the host-only networks until the one
+// referenced by networkName is listed and up. On slow hosts, a newly created
+// interface can take a while to become visible and attaching a VM to it
+// before that fails.
+func waitForHostOnlyNetwork(networkName string, timeout time.Duration, vbox VBoxManager) (*hostOnlyNetwork, error) {
+	return waitForHostOnlyNetworkContext(context.Background(), networkName, timeout, vbox)
+}
+
+func waitForHostOnlyNetworkContext(ctx context.Context, networkName string, timeout time.Duration, vbox VBoxManager) (*hostOnlyNetwork, error) {
+	var hostOnlyNet *hostOnlyNetwork
+
+	err := pollWithBackoff(ctx, timeout, errHostOnlyNetworkNotSettled, func() (bool, error) {
+		nets, err := listHostOnlyNetworks(vbox)
+		if err != nil {
+			return false, err
+		}
+
+		if n, present := nets[networkName]; present && n.Status == "Up" {
+			hostOnlyNet = n
+			return true, nil
+		}
+
+		log.Debugf("Waiting for host-only network %s to come up...", networkName)
+		return false, nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return hostOnlyNet, nil
 }
 
+// waitForDHCPServer polls the DHCP servers until the one of networkName is
+// enabled. VirtualBox starts it asynchronously, and a machine booting before
+// it is up doesn't get a lease.
+func waitForDHCPServer(vbox VBoxManager, networkName string, timeout time.Duration) error {
+	return waitForDHCPServerContext(context.Background(), vbox, networkName, timeout)
+}
+
+func waitForDHCPServerContext(ctx context.Context, vbox VBoxManager, networkName string, timeout time.Duration) error {
+	errTimeout := fmt.Errorf("timed out waiting for the DHCP server of %s", networkName)
+
+	return pollWithBackoff(ctx, timeout, errTimeout, func() (bool, error) {
+		dhcps, err := getDHCPServers(vbox)
+		if err != nil {
+			return false, err
+		}
+
+		if dhcp, present := dhcps[networkName]; present && dhcp.Enabled {
+			return true, nil
+		}
+
+		log.Debugf("Waiting for the DHCP server of %s to be ready...", networkName)
+		return false, nil
+	})
+}
+
+// pollWithBackoff calls f until it returns true, waiting a little longer
+// between each call. It returns errTimeout if f isn't done within timeout,
+// and ctx.Err() as soon as ctx is done.
+func pollWithBackoff(ctx context.Context, timeout time.Duration, errTimeout error, f func() (bool, error)) error {
+	deadline := time.Now().Add(timeout)
+	backoff := 100 * time.Millisecond
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		done, err := f()
+		if err != nil {
+			return err
+		}
+
+		if done {
+			return nil
+		}
+
+		if time.Now().Add(backoff).After(deadline) {
+			return errTimeout
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		if backoff < time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// hostOnlyInterfaceIndex returns the index of the host-only interface named
+// name by VBoxManage, the number its name ends with: 2 for vboxnet2, or for
+// "VirtualBox Host-Only Ethernet Adapter #2" on Windows. The name of the first
+// interface of Windows has no number, its index is 0.
+func hostOnlyInterfaceIndex(name string) int {
+	res := reHostOnlyInterfaceIndex.FindStringSubmatch(name)
+	if res == nil {
+		return 0
+	}
+
+	index, err := strconv.Atoi(res[1])
+	if err != nil {
+		return -1
+	}
+
+	return index
+}
+
+// checkHostOnlyInterfaceIndex verifies that the host-only interface of the
+// index can serve the requested subnet: either it is the matched network, or
+// it doesn't exist yet and no other interface already serves the subnet.
+func checkHostOnlyInterfaceIndex(nets map[string]*hostOnlyNetwork, matched *hostOnlyNetwork, index int, hostIP net.IP, netmask net.IPMask) error {
+	if matched != nil {
+		if hostOnlyInterfaceIndex(matched.Name) != index {
+			return fmt.Errorf("the requested host-only network is already configured on %s, not on the interface of index %d", matched.Name, index)
+		}
+		return nil
+	}
+
+	for _, n := range sortedHostOnlyNetworks(nets) {
+		if hostOnlyInterfaceIndex(n.Name) == index {
+			desired := *n
+			desired.IPv4 = net.IPNet{IP: hostIP, Mask: netmask}
+			return fmt.Errorf("host-only interface %s is already configured with an incompatible network %s (%s)", n.Name, n.IPv4.String(), strings.Join(diffHostOnlyNetwork(&desired, n), "; "))
+		}
+	}
+
+	return nil
+}
+
+// reconcileHostOnlyNetwork makes sure a host-only network with the given
+// subnet exists, recreating it if it has gone missing (e.g. it was removed by
+// hand or lost during a VirtualBox upgrade). networkName is the NetworkName the
+// machine was last attached to: if its DHCP server is still registered and
+// enabled, it is re-established on the recreated network, otherwise the DHCP
+// server some platforms add to new interfaces is disabled. Calling it when the
+// network already exists is a no-op which returns the existing network. The
+// returned boolean is true if the network was recreated.
+func reconcileHostOnlyNetwork(networkName string, hostIP net.IP, netmask net.IPMask, vbox VBoxManager) (*hostOnlyNetwork, bool, error) {
+	nets, err := listHostOnlyNetworks(vbox)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if hostOnlyNet := findHostOnlyNetwork(nets, hostIP, netmask, vbox); hostOnlyNet != nil {
+		return hostOnlyNet, false, nil
+	}
+
+	log.Infof("Host-only network %s is missing, recreating it...", networkName)
+
+	dhcps, err := getDHCPServers(vbox)
+	if err != nil {
+		return nil, false, err
+	}
+
+	hostOnlyNet, err := createHostonlyNet(vbox)
+	if err != nil {
+		return nil, false, err
+	}
+
+	hostOnlyNet.IPv4.IP = hostIP
+	hostOnlyNet.IPv4.Mask = netmask
+	if err := hostOnlyNet.Save(vbox); err != nil {
+		removeCreatedHostOnlyInterface(hostOnlyNet.Name, vbox)
+		return nil, false, err
+	}
+
+	if dhcp, present := dhcps[networkName]; present && dhcp.Enabled {
+		if err := addHostonlyDHCP(hostOnlyNet.Name, *dhcp, vbox); err != nil {
+			removeCreatedHostOnlyInterface(hostOnlyNet.Name, vbox)
+			return nil, false, err
+		}
+	} else if err := reconfigureHostOnlyDHCP(hostOnlyNet, dhcpDisabled, nil, nil, nil, vbox); err != nil {
+		removeCreatedHostOnlyInterface(hostOnlyNet.Name, vbox)
+		return nil, false, err
+	}
+
+	return hostOnlyNet, true, nil
+}
+
 func countUniqueIps(nets map[string]*hostOnlyNetwork) int {
 	ips := map[string]bool{}
 
@@ -197,6 +1086,99 @@ func countUniqueIps(nets map[string]*hostOnlyNetwork) int {
 	return len(ips)
 }
 
+// removeHostOnlyNetworkIfUnused removes the host-only network referenced by
+// networkName, along with its DHCP server, unless a registered VM other than
+// machineName still has an adapter attached to it.
+func removeHostOnlyNetworkIfUnused(networkName, machineName string, vbox VBoxManager) error {
+	nets, err := listHostOnlyNetworks(vbox)
+	if err != nil {
+		return err
+	}
+
+	hostOnlyNet, present := nets[networkName]
+	if !present {
+		return nil
+	}
+
+	users, err := vmsUsingHostOnlyNetwork(hostOnlyNet.Name, machineName, vbox)
+	if err != nil {
+		return err
+	}
+
+	if len(users) > 0 {
+		log.Debugf("Keeping host-only network %s, it is used by %s", hostOnlyNet.Name, strings.Join(users, ", "))
+		return nil
+	}
+
+	return removeHostOnlyNetwork(hostOnlyNet, vbox)
+}
+
+// removeHostOnlyNetworkByCIDR removes the host-only network configured with
+// the host address and netmask of cidr, along with its DHCP server. It fails
+// if no network or several networks match, or if VMs are attached to it.
+func removeHostOnlyNetworkByCIDR(vbox VBoxManager, cidr net.IPNet) error {
+	nets, err := listHostOnlyNetworks(vbox)
+	if err != nil {
+		return err
+	}
+
+	matches := []string{}
+	for _, n := range sortedHostOnlyNetworks(nets) {
+		if getHostOnlyNetwork(map[string]*hostOnlyNetwork{n.NetworkName: n}, cidr.IP, cidr.Mask, false) != nil {
+			matches = append(matches, n.Name)
+		}
+	}
+	if len(matches) > 1 {
+		return fmt.Errorf("host-only network %s is configured on several interfaces: %s", cidr.String(), strings.Join(matches, ", "))
+	}
+
+	hostOnlyNet := getHostOnlyNetwork(nets, cidr.IP, cidr.Mask, false)
+	if hostOnlyNet == nil {
+		return fmt.Errorf("%s: %s", errHostOnlyNetworkNotFound, cidr.String())
+	}
+
+	users, err := vmsUsingHostOnlyNetwork(hostOnlyNet.Name, "", vbox)
+	if err != nil {
+		return err
+	}
+
+	if len(users) > 0 {
+		return fmt.Errorf("host-only network %s on %s can't be removed as it is used by %s", cidr.String(), hostOnlyNet.Name, strings.Join(users, ", "))
+	}
+
+	return removeHostOnlyNetwork(hostOnlyNet, vbox)
+}
+
+// vmsUsingHostOnlyNetwork returns the registered VMs, other than exclude,
+// which have an adapter attached to the host-only interface named ifname.
+func vmsUsingHostOnlyNetwork(ifname, exclude string, vbox VBoxManager) ([]string, error) {
+	vms, err := listVMs(vbox)
+	if err != nil {
+		return nil, err
+	}
+
+	users := []string{}
+	for _, name := range vms {
+		if name == exclude {
+			continue
+		}
+
+		vm, err := getVMInfo(name, vbox)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, adapter := range vm.HostOnlyAdapters {
+			if adapter == ifname {
+				users = append(users, name)
+				break
+			}
+		}
+	}
+
+	return users, nil
+}
+
 // DHCP server info.
 type dhcpServer struct {
 	NetworkName string
@@ -0,0 +1,81 @@
+package virtualbox
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+// hostOnlyNetworkState is what a snapshot records of a host-only network.
+type hostOnlyNetworkState struct {
+	Name string
+	IPv4 net.IPNet
+	DHCP bool
+}
+
+// hostOnlyNetworkSnapshot is the set of host-only networks which existed at
+// a point in time, keyed by NetworkName. Integration tests which create
+// networks take one first and restore it on teardown, so that failed runs
+// don't leave interfaces behind.
+type hostOnlyNetworkSnapshot map[string]hostOnlyNetworkState
+
+// snapshotHostOnlyNetworks records the host-only networks which currently
+// exist.
+func snapshotHostOnlyNetworks(vbox VBoxManager) (hostOnlyNetworkSnapshot, error) {
+	nets, err := listHostOnlyNetworks(vbox)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := hostOnlyNetworkSnapshot{}
+	for networkName, n := range nets {
+		snapshot[networkName] = hostOnlyNetworkState{
+			Name: n.Name,
+			IPv4: n.IPv4,
+			DHCP: n.DHCP,
+		}
+	}
+
+	return snapshot, nil
+}
+
+// createdSince returns the networks of nets which aren't in the snapshot,
+// sorted by name. Networks which were in the snapshot are never returned,
+// even if their configuration changed since.
+func (s hostOnlyNetworkSnapshot) createdSince(nets map[string]*hostOnlyNetwork) []*hostOnlyNetwork {
+	created := []*hostOnlyNetwork{}
+	for _, n := range sortedHostOnlyNetworks(nets) {
+		if _, present := s[n.NetworkName]; !present {
+			created = append(created, n)
+		}
+	}
+
+	return created
+}
+
+// restore removes the host-only networks created since the snapshot was
+// taken, along with their DHCP server. The networks which already existed are
+// left untouched.
+func (s hostOnlyNetworkSnapshot) restore(vbox VBoxManager) error {
+	nets, err := listHostOnlyNetworks(vbox)
+	if err != nil {
+		return err
+	}
+
+	failed := []string{}
+	for _, n := range s.createdSince(nets) {
+		log.Debugf("Removing host-only network %s created since the snapshot", n.Name)
+
+		if err := removeHostOnlyNetwork(n, vbox); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", n.Name, err))
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("unable to remove host-only networks: %s", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
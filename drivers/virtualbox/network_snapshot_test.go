@@ -0,0 +1,79 @@
+package virtualbox
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotHostOnlyNetworks(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs": stdOutOneHostOnlyNetwork,
+		},
+	}
+
+	snapshot, err := snapshotHostOnlyNetworks(vbox)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(snapshot))
+	state := snapshot["HostInterfaceNetworking-vboxnet0"]
+	assert.Equal(t, "192.168.99.1/24", state.IPv4.String())
+	assert.Equal(t, "vboxnet0", state.Name)
+	assert.False(t, state.DHCP)
+}
+
+func TestHostOnlyNetworkSnapshotCreatedSince(t *testing.T) {
+	snapshot := hostOnlyNetworkSnapshot{
+		"HostInterfaceNetworking-vboxnet0": {Name: "vboxnet0"},
+	}
+	nets := map[string]*hostOnlyNetwork{
+		"HostInterfaceNetworking-vboxnet0": {Name: "vboxnet0", NetworkName: "HostInterfaceNetworking-vboxnet0", DHCP: true},
+		"HostInterfaceNetworking-vboxnet2": {Name: "vboxnet2", NetworkName: "HostInterfaceNetworking-vboxnet2"},
+		"HostInterfaceNetworking-vboxnet1": {Name: "vboxnet1", NetworkName: "HostInterfaceNetworking-vboxnet1"},
+	}
+
+	created := snapshot.createdSince(nets)
+
+	assert.Equal(t, 2, len(created))
+	assert.Equal(t, "vboxnet1", created[0].Name)
+	assert.Equal(t, "vboxnet2", created[1].Name)
+}
+
+func TestHostOnlyNetworkSnapshotRestore(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOutSeq: map[string][]string{
+			"list hostonlyifs": {stdOutOneHostOnlyNetwork, stdOutOneHostOnlyNetwork + fmt.Sprintf(stdOutCreatedHostOnlyNetwork, "Up")},
+		},
+	}
+
+	snapshot, err := snapshotHostOnlyNetworks(vbox)
+	assert.NoError(t, err)
+
+	err = snapshot.restore(vbox)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"list hostonlyifs",
+		"list hostonlyifs",
+		"dhcpserver remove --netname HostInterfaceNetworking-vboxnet1",
+		"hostonlyif remove vboxnet1",
+	}, vbox.calls)
+}
+
+func TestHostOnlyNetworkSnapshotRestoreNothingCreated(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs": stdOutTwoHostOnlyNetwork,
+		},
+	}
+
+	snapshot, err := snapshotHostOnlyNetworks(vbox)
+	assert.NoError(t, err)
+
+	err = snapshot.restore(vbox)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"list hostonlyifs", "list hostonlyifs"}, vbox.calls)
+}
@@ -1,9 +1,14 @@
 package virtualbox
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -47,6 +52,88 @@ VBoxNetworkName: HostInterfaceNetworking-vboxnet1
 
 `
 
+const stdOutUnorderedHostOnlyNetworks = `Name:            vboxnet10
+GUID:            786f6276-656e-4a74-8000-0a002700000a
+DHCP:            Disabled
+IPAddress:       192.168.110.1
+NetworkMask:     255.255.255.0
+IPV6Address:
+IPV6NetworkMaskPrefixLength: 0
+HardwareAddress: 0a:00:27:00:00:0a
+MediumType:      Ethernet
+Status:          Up
+VBoxNetworkName: HostInterfaceNetworking-vboxnet10
+
+Name:            vboxnet2
+GUID:            786f6276-656e-4274-8000-0a0027000002
+DHCP:            Disabled
+IPAddress:       192.168.102.1
+NetworkMask:     255.255.255.0
+IPV6Address:
+IPV6NetworkMaskPrefixLength: 0
+HardwareAddress: 0a:00:27:00:00:02
+MediumType:      Ethernet
+Status:          Up
+VBoxNetworkName: HostInterfaceNetworking-vboxnet2
+
+Name:            vboxnet1
+GUID:            786f6276-656e-4174-8000-0a0027000001
+DHCP:            Disabled
+IPAddress:       192.168.101.1
+NetworkMask:     255.255.255.0
+IPV6Address:
+IPV6NetworkMaskPrefixLength: 0
+HardwareAddress: 0a:00:27:00:00:01
+MediumType:      Ethernet
+Status:          Up
+VBoxNetworkName: HostInterfaceNetworking-vboxnet1
+
+`
+
+const stdOutMultiHomedHostOnlyNetwork = `Name:            vboxnet0
+GUID:            786f6276-656e-4074-8000-0a0027000000
+DHCP:            Disabled
+IPAddress:       192.168.99.1
+NetworkMask:     255.255.255.0
+IPAddress:       10.10.0.1
+NetworkMask:     255.255.0.0
+IPV6Address:
+IPV6NetworkMaskPrefixLength: 0
+HardwareAddress: 0a:00:27:00:00:00
+MediumType:      Ethernet
+Status:          Up
+VBoxNetworkName: HostInterfaceNetworking-vboxnet0
+
+`
+
+const stdOutCreatedHostOnlyNetwork = `Name:            vboxnet1
+GUID:            786f6276-656e-4174-8000-0a0027000001
+DHCP:            Disabled
+IPAddress:       192.168.100.1
+NetworkMask:     255.255.255.0
+IPV6Address:
+IPV6NetworkMaskPrefixLength: 0
+HardwareAddress: 0a:00:27:00:00:01
+MediumType:      Ethernet
+Status:          %s
+VBoxNetworkName: HostInterfaceNetworking-vboxnet1
+
+`
+
+const stdOutWirelessHostOnlyNetwork = `Name:            vboxnet0
+GUID:            786f6276-656e-4074-8000-0a0027000000
+DHCP:            Disabled
+IPAddress:       192.168.99.1
+NetworkMask:     255.255.255.0
+IPV6Address:
+IPV6NetworkMaskPrefixLength: 0
+HardwareAddress: 0a:00:27:00:00:00
+MediumType:      WirelessLAN
+Status:          Up
+VBoxNetworkName: HostInterfaceNetworking-vboxnet0
+
+`
+
 // Tests that when we have a host only network which matches our expectations,
 // it gets returned correctly.
 func TestGetHostOnlyNetworkHappy(t *testing.T) {
@@ -62,9 +149,9 @@ func TestGetHostOnlyNetworkHappy(t *testing.T) {
 		"HostInterfaceNetworking-vboxnet0": expectedHostOnlyNetwork,
 	}
 
-	n := getHostOnlyNetwork(vboxNets, ip, ipnet.Mask)
+	n := getHostOnlyNetwork(vboxNets, ip, ipnet.Mask, false)
 	if !reflect.DeepEqual(n, expectedHostOnlyNetwork) {
-		t.Fatalf("Expected result of calling getHostOnlyNetwork to be the same as expected but it was not:\nexpected: %+v\nactual: %+v\n", expectedHostOnlyNetwork, n)
+		t.Fatalf("Expected result of calling getHostOnlyNetwork to be the same as expected but it was not:\n%s", strings.Join(diffHostOnlyNetwork(expectedHostOnlyNetwork, n), "\n"))
 	}
 }
 
@@ -89,7 +176,7 @@ func TestGetHostOnlyNetworkNotFound(t *testing.T) {
 		"HostInterfaceNetworking-vboxnet0": vboxNet,
 	}
 
-	n := getHostOnlyNetwork(vboxNets, ip, ipnet.Mask)
+	n := getHostOnlyNetwork(vboxNets, ip, ipnet.Mask, false)
 	if n != nil {
 		t.Fatalf("Expected vbox net to be nil but it has a value: %+v\n", n)
 	}
@@ -118,9 +205,133 @@ func TestGetHostOnlyNetworkWindows10Bug(t *testing.T) {
 
 	// The Mask that we are passing in will be the "legitimate" mask, so it
 	// must differ from the magic buggy mask.
-	n := getHostOnlyNetwork(vboxNets, ip, net.IPMask(net.ParseIP("255.255.255.0").To4()))
+	n := getHostOnlyNetwork(vboxNets, ip, net.IPMask(net.ParseIP("255.255.255.0").To4()), true)
 	if !reflect.DeepEqual(n, expectedHostOnlyNetwork) {
-		t.Fatalf("Expected result of calling getHostOnlyNetwork to be the same as expected but it was not:\nexpected: %+v\nactual: %+v\n", expectedHostOnlyNetwork, n)
+		t.Fatalf("Expected result of calling getHostOnlyNetwork to be the same as expected but it was not:\n%s", strings.Join(diffHostOnlyNetwork(expectedHostOnlyNetwork, n), "\n"))
+	}
+}
+
+func TestGetHostOnlyNetworkStrictIgnoresBuggyNetmask(t *testing.T) {
+	ip, ipnet, err := net.ParseCIDR("192.168.99.1/24")
+	if err != nil {
+		t.Fatalf("Error parsing cidr: %s", err)
+	}
+
+	vboxNets := map[string]*hostOnlyNetwork{
+		"HostInterfaceNetworking-vboxnet0": {
+			IPv4: net.IPNet{IP: ip, Mask: net.IPMask(net.ParseIP("15.0.0.0").To4())},
+		},
+	}
+
+	assert.Nil(t, getHostOnlyNetwork(vboxNets, ip, ipnet.Mask, false))
+}
+
+func TestFindHostOnlyNetworkChecksVersionForBuggyNetmask(t *testing.T) {
+	ip, ipnet, err := net.ParseCIDR("192.168.99.1/24")
+	if err != nil {
+		t.Fatalf("Error parsing cidr: %s", err)
+	}
+
+	buggyHostOnlyNetwork := &hostOnlyNetwork{
+		IPv4: net.IPNet{IP: ip, Mask: net.IPMask(net.ParseIP("15.0.0.0").To4())},
+	}
+	vboxNets := map[string]*hostOnlyNetwork{
+		"HostInterfaceNetworking-vboxnet0": buggyHostOnlyNetwork,
+	}
+
+	affected := &VBoxManagerScript{stdOut: map[string]string{"--version": "5.0.8r103449"}}
+	assert.Equal(t, buggyHostOnlyNetwork, findHostOnlyNetwork(vboxNets, ip, ipnet.Mask, affected))
+
+	fixed := &VBoxManagerScript{stdOut: map[string]string{"--version": "5.1.30r118389"}}
+	assert.Nil(t, findHostOnlyNetwork(vboxNets, ip, ipnet.Mask, fixed))
+}
+
+func TestHasBuggyNetmask(t *testing.T) {
+	var tests = []struct {
+		version  string
+		expected bool
+	}{
+		{"4.3.30r101610", false},
+		{"5.0.0r101573", true},
+		{"5.0.8r103449", true},
+		{"5.1.0r108711", false},
+		{"6.1.16r140961", false},
+		{"garbage", false},
+	}
+
+	for _, test := range tests {
+		vbox := &VBoxManagerMock{
+			args:   "--version",
+			stdOut: test.version,
+		}
+
+		assert.Equal(t, test.expected, hasBuggyNetmask(vbox), test.version)
+	}
+
+	assert.False(t, hasBuggyNetmask(&VBoxManagerMock{args: "--version", err: errors.New("failure")}))
+}
+
+// Tests that an exact match wins over a network which only matches because of
+// the Windows 10 netmask bug, whatever the map iteration order.
+func TestGetHostOnlyNetworkPrefersExactMatch(t *testing.T) {
+	ip, ipnet, err := net.ParseCIDR("192.168.99.1/24")
+	if err != nil {
+		t.Fatalf("Error parsing cidr: %s", err)
+	}
+
+	exactHostOnlyNetwork := &hostOnlyNetwork{
+		Name: "vboxnet0",
+		IPv4: net.IPNet{IP: ip, Mask: ipnet.Mask},
+	}
+	buggyHostOnlyNetwork := &hostOnlyNetwork{
+		Name: "vboxnet1",
+		IPv4: net.IPNet{IP: ip, Mask: net.IPMask(net.ParseIP("15.0.0.0").To4())},
+	}
+
+	vboxNets := map[string]*hostOnlyNetwork{
+		"HostInterfaceNetworking-vboxnet0": exactHostOnlyNetwork,
+		"HostInterfaceNetworking-vboxnet1": buggyHostOnlyNetwork,
+	}
+
+	for i := 0; i < 20; i++ {
+		n := getHostOnlyNetwork(vboxNets, ip, ipnet.Mask, true)
+		assert.Equal(t, exactHostOnlyNetwork, n)
+	}
+}
+
+func TestSortedHostOnlyNetworks(t *testing.T) {
+	vbox := &VBoxManagerMock{
+		args:   "list hostonlyifs",
+		stdOut: stdOutUnorderedHostOnlyNetworks,
+	}
+
+	nets, err := listHostOnlyNetworks(vbox)
+	assert.NoError(t, err)
+
+	names := []string{}
+	for _, n := range sortedHostOnlyNetworks(nets) {
+		names = append(names, n.Name)
+	}
+
+	assert.Equal(t, []string{"vboxnet1", "vboxnet2", "vboxnet10"}, names)
+}
+
+func TestNaturalLess(t *testing.T) {
+	var tests = []struct {
+		a, b     string
+		lessThan bool
+	}{
+		{"vboxnet2", "vboxnet10", true},
+		{"vboxnet10", "vboxnet2", false},
+		{"vboxnet2", "vboxnet2", false},
+		{"vboxnet02", "vboxnet10", true},
+		{"vboxnet", "vboxnet0", true},
+		{"en1", "vboxnet0", true},
+		{"VirtualBox Host-Only Ethernet Adapter #2", "VirtualBox Host-Only Ethernet Adapter #10", true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.lessThan, naturalLess(test.a, test.b), "%s < %s", test.a, test.b)
 	}
 }
 
@@ -145,7 +356,8 @@ func TestListHostOnlyNetworks(t *testing.T) {
 	assert.Equal(t, "ffffff00", net.IPv4.Mask.String())
 	assert.Empty(t, net.IPv6.IP)
 	assert.Equal(t, "0a:00:27:00:00:00", net.HwAddr.String())
-	assert.Equal(t, "Ethernet", net.Medium)
+	assert.Equal(t, mediumEthernet, net.Medium)
+	assert.Equal(t, "Ethernet", net.mediumName())
 	assert.Equal(t, "Up", net.Status)
 	assert.Equal(t, "HostInterfaceNetworking-vboxnet0", net.NetworkName)
 }
@@ -171,11 +383,34 @@ func TestListTwoHostOnlyNetworks(t *testing.T) {
 	assert.Equal(t, "ffffff00", net.IPv4.Mask.String())
 	assert.Empty(t, net.IPv6.IP)
 	assert.Equal(t, "0a:00:27:00:00:01", net.HwAddr.String())
-	assert.Equal(t, "Ethernet", net.Medium)
+	assert.Equal(t, mediumEthernet, net.Medium)
+	assert.Equal(t, "Ethernet", net.mediumName())
 	assert.Equal(t, "Up", net.Status)
 	assert.Equal(t, "HostInterfaceNetworking-vboxnet1", net.NetworkName)
 }
 
+func TestGetHostOnlyNetworkByGUID(t *testing.T) {
+	vbox := &VBoxManagerMock{
+		args:   "list hostonlyifs",
+		stdOut: stdOutTwoHostOnlyNetwork,
+	}
+
+	net, err := getHostOnlyNetworkByGUID("786f6276-656e-4074-8000-0a0027000000", vbox)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "vboxnet0", net.Name)
+
+	net, err = getHostOnlyNetworkByGUID("786f6276-656e-4174-8000-0a0027000001", vbox)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "vboxnet1", net.Name)
+
+	net, err = getHostOnlyNetworkByGUID("786f6276-656e-4274-8000-0a0027000002", vbox)
+
+	assert.Nil(t, net)
+	assert.Equal(t, errHostOnlyNetworkNotFound, err)
+}
+
 func TestListHostOnlyNetworksDontRelyOnEmptyLinesForParsing(t *testing.T) {
 	vbox := &VBoxManagerMock{
 		args: "list hostonlyifs",
@@ -205,11 +440,72 @@ func TestGetHostOnlyNetwork(t *testing.T) {
 		stdOut: stdOutOneHostOnlyNetwork,
 	}
 
-	net, err := getOrCreateHostOnlyNetwork(net.ParseIP("192.168.99.1"), parseIPv4Mask("255.255.255.0"), nil, nil, nil, vbox)
+	net, created, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.99.1"), Netmask: parseIPv4Mask("255.255.255.0")}, vbox)
 
 	assert.NotNil(t, net)
 	assert.Equal(t, "HostInterfaceNetworking-vboxnet0", net.NetworkName)
+	assert.False(t, created)
+	assert.NoError(t, err)
+}
+
+func TestGetHostOnlyNetworkKeepsMatchingDHCP(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs": stdOutOneHostOnlyNetwork,
+			"list dhcpservers": stdOutOneDHCPServer,
+		},
+	}
+
+	_, created, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.99.1"), Netmask: parseIPv4Mask("255.255.255.0"), DHCP: dhcpEnabled}, vbox)
+
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, []string{"list hostonlyifs", "list dhcpservers"}, vbox.calls)
+}
+
+func TestGetHostOnlyNetworkDisablesMismatchedDHCP(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs": stdOutOneHostOnlyNetwork,
+			"list dhcpservers": stdOutOneDHCPServer,
+		},
+	}
+
+	net, created, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.99.1"), Netmask: parseIPv4Mask("255.255.255.0"), DHCPIP: net.ParseIP("192.168.99.7"), DHCP: dhcpDisabled}, vbox)
+
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, "vboxnet0", net.Name)
+	assert.Equal(t, "dhcpserver modify --netname HostInterfaceNetworking-vboxnet0 --ip 192.168.99.6 --netmask 255.255.255.0 --lowerip 192.168.99.100 --upperip 192.168.99.254 --disable", vbox.calls[len(vbox.calls)-1])
+}
+
+func TestGetHostOnlyNetworkEnablesMismatchedDHCP(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs": stdOutOneHostOnlyNetwork,
+			"list dhcpservers": strings.Replace(stdOutOneDHCPServer, "Enabled:        Yes", "Enabled:        No", 1),
+		},
+	}
+
+	_, created, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.99.1"), Netmask: parseIPv4Mask("255.255.255.0"), DHCP: dhcpEnabled}, vbox)
+
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, "dhcpserver modify --netname HostInterfaceNetworking-vboxnet0 --ip 192.168.99.6 --netmask 255.255.255.0 --lowerip 192.168.99.100 --upperip 192.168.99.254 --enable", vbox.calls[len(vbox.calls)-1])
+}
+
+func TestGetHostOnlyNetworkAddsMissingDHCP(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs": stdOutOneHostOnlyNetwork,
+			"list dhcpservers": "",
+		},
+	}
+
+	_, _, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.99.1"), Netmask: parseIPv4Mask("255.255.255.0"), DHCPIP: net.ParseIP("192.168.99.7"), DHCPLowerIP: net.ParseIP("192.168.99.100"), DHCPUpperIP: net.ParseIP("192.168.99.254"), DHCP: dhcpEnabled}, vbox)
+
 	assert.NoError(t, err)
+	assert.Equal(t, "dhcpserver add --netname HostInterfaceNetworking-vboxnet0 --ip 192.168.99.7 --netmask 255.255.255.0 --lowerip 192.168.99.100 --upperip 192.168.99.254 --enable", vbox.calls[len(vbox.calls)-1])
 }
 
 func TestFailWithDuplicateHostOnlyNetworks(t *testing.T) {
@@ -218,8 +514,956 @@ func TestFailWithDuplicateHostOnlyNetworks(t *testing.T) {
 		stdOut: stdOutTwoHostOnlyNetwork,
 	}
 
-	net, err := getOrCreateHostOnlyNetwork(net.ParseIP("192.168.99.1"), parseIPv4Mask("255.255.255.0"), nil, nil, nil, vbox)
+	net, created, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.99.1"), Netmask: parseIPv4Mask("255.255.255.0")}, vbox)
 
 	assert.Nil(t, net)
+	assert.False(t, created)
 	assert.Equal(t, errDuplicateHostOnlyInterfaceNetworks, err)
 }
+
+func TestCreateHostOnlyNetwork(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"hostonlyif create": "Interface 'vboxnet1' was successfully created",
+			"list dhcpservers":  stdOutCreatedDHCPServer,
+		},
+		stdOutSeq: map[string][]string{
+			"list hostonlyifs": {stdOutOneHostOnlyNetwork, stdOutOneHostOnlyNetwork + fmt.Sprintf(stdOutCreatedHostOnlyNetwork, "Up")},
+		},
+	}
+
+	net, created, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.100.1"), Netmask: parseIPv4Mask("255.255.255.0"), DHCPIP: net.ParseIP("192.168.100.6"), DHCPLowerIP: net.ParseIP("192.168.100.100"), DHCPUpperIP: net.ParseIP("192.168.100.254")}, vbox)
+
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "vboxnet1", net.Name)
+	assert.Equal(t, "HostInterfaceNetworking-vboxnet1", net.NetworkName)
+}
+
+func TestCreateHostOnlyNetworkRecreatesUnhealthyNetwork(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"hostonlyif create": "Interface 'vboxnet1' was successfully created",
+			"list dhcpservers":  stdOutCreatedDHCPServer,
+		},
+		stdOutSeq: map[string][]string{
+			"list hostonlyifs": {fmt.Sprintf(stdOutCreatedHostOnlyNetwork, "Down"), fmt.Sprintf(stdOutCreatedHostOnlyNetwork, "Up")},
+		},
+	}
+
+	net, created, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.100.1"), Netmask: parseIPv4Mask("255.255.255.0"), DHCPIP: net.ParseIP("192.168.100.6"), DHCPLowerIP: net.ParseIP("192.168.100.100"), DHCPUpperIP: net.ParseIP("192.168.100.254"), RecreateUnhealthy: true}, vbox)
+
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "vboxnet1", net.Name)
+	assert.Contains(t, vbox.calls, "hostonlyif remove vboxnet1")
+	assert.Contains(t, vbox.calls, "hostonlyif create")
+}
+
+func TestGetHostOnlyNetworkKeepsUnhealthyNetworkByDefault(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs": fmt.Sprintf(stdOutCreatedHostOnlyNetwork, "Down"),
+		},
+	}
+
+	net, created, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.100.1"), Netmask: parseIPv4Mask("255.255.255.0")}, vbox)
+
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, "Down", net.Status)
+	assert.Equal(t, []string{"list hostonlyifs"}, vbox.calls)
+}
+
+func TestFailToRecreateUnhealthyNetworkInUse(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs":                   fmt.Sprintf(stdOutCreatedHostOnlyNetwork, "Down"),
+			"list vms":                           `"other" {0b5c2e48-3b2a-4d0f-8f5e-6a8f1e6b8a01}`,
+			"showvminfo other --machinereadable": `hostonlyadapter2="vboxnet1"`,
+		},
+	}
+
+	_, _, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.100.1"), Netmask: parseIPv4Mask("255.255.255.0"), RecreateUnhealthy: true}, vbox)
+
+	assert.EqualError(t, err, "host-only network vboxnet1 is unhealthy, its interface is down, but can't be recreated as it is used by other")
+	assert.NotContains(t, vbox.calls, "hostonlyif remove vboxnet1")
+}
+
+func TestHostOnlyNetworkUnhealthyReason(t *testing.T) {
+	healthy := &hostOnlyNetwork{Status: "Up", IPv4: net.IPNet{IP: net.ParseIP("192.168.99.1")}}
+	down := &hostOnlyNetwork{Status: "Down", IPv4: net.IPNet{IP: net.ParseIP("192.168.99.1")}}
+	noIP := &hostOnlyNetwork{Status: "Up", IPv4: net.IPNet{IP: net.IPv4zero}}
+
+	assert.Empty(t, healthy.unhealthyReason())
+	assert.Equal(t, "its interface is down", down.unhealthyReason())
+	assert.Equal(t, "its interface has no IP address", noIP.unhealthyReason())
+	assert.Equal(t, "its interface has no IP address", (&hostOnlyNetwork{}).unhealthyReason())
+}
+
+const stdOutOneDHCPServer = `NetworkName:    HostInterfaceNetworking-vboxnet0
+IP:             192.168.99.6
+NetworkMask:    255.255.255.0
+lowerIPAddress: 192.168.99.100
+upperIPAddress: 192.168.99.254
+Enabled:        Yes
+
+`
+
+const stdOutCreatedDHCPServer = `NetworkName:    HostInterfaceNetworking-vboxnet1
+IP:             192.168.100.6
+NetworkMask:    255.255.255.0
+lowerIPAddress: 192.168.100.100
+upperIPAddress: 192.168.100.254
+Enabled:        Yes
+
+`
+
+func TestReconcileHostOnlyNetworkRecreatesMissingNetwork(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs":  "",
+			"list dhcpservers":  stdOutOneDHCPServer,
+			"hostonlyif create": "Interface 'vboxnet0' was successfully created",
+		},
+	}
+
+	net, created, err := reconcileHostOnlyNetwork("HostInterfaceNetworking-vboxnet0", net.ParseIP("192.168.99.1"), parseIPv4Mask("255.255.255.0"), vbox)
+
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "vboxnet0", net.Name)
+	assert.Equal(t, "HostInterfaceNetworking-vboxnet0", net.NetworkName)
+	assert.Contains(t, vbox.calls, "hostonlyif ipconfig vboxnet0 --ip 192.168.99.1 --netmask 255.255.255.0")
+	assert.Contains(t, vbox.calls, "dhcpserver modify --netname HostInterfaceNetworking-vboxnet0 --ip 192.168.99.6 --netmask 255.255.255.0 --lowerip 192.168.99.100 --upperip 192.168.99.254 --enable")
+}
+
+func TestReconcileHostOnlyNetworkSkipsDisabledDHCP(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs":  "",
+			"list dhcpservers":  strings.Replace(stdOutOneDHCPServer, "Yes", "No", 1),
+			"hostonlyif create": "Interface 'vboxnet0' was successfully created",
+		},
+	}
+
+	_, _, err := reconcileHostOnlyNetwork("HostInterfaceNetworking-vboxnet0", net.ParseIP("192.168.99.1"), parseIPv4Mask("255.255.255.0"), vbox)
+
+	assert.NoError(t, err)
+	for _, call := range vbox.calls {
+		assert.False(t, strings.HasPrefix(call, "dhcpserver"), "unexpected call: %s", call)
+	}
+}
+
+func TestReconcileHostOnlyNetworkKeepsDHCPDisabled(t *testing.T) {
+	disabled := strings.Replace(stdOutOneDHCPServer, "Yes", "No", 1)
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs":  "",
+			"hostonlyif create": "Interface 'vboxnet0' was successfully created",
+		},
+		stdOutSeq: map[string][]string{
+			"list dhcpservers": {disabled, stdOutOneDHCPServer},
+		},
+	}
+
+	_, _, err := reconcileHostOnlyNetwork("HostInterfaceNetworking-vboxnet0", net.ParseIP("192.168.99.1"), parseIPv4Mask("255.255.255.0"), vbox)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "dhcpserver modify --netname HostInterfaceNetworking-vboxnet0 --ip 192.168.99.6 --netmask 255.255.255.0 --lowerip 192.168.99.100 --upperip 192.168.99.254 --disable", vbox.calls[len(vbox.calls)-1])
+}
+
+func TestReconcileHostOnlyNetworkIsNoopWhenNetworkExists(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs": stdOutOneHostOnlyNetwork,
+		},
+	}
+
+	net, created, err := reconcileHostOnlyNetwork("HostInterfaceNetworking-vboxnet0", net.ParseIP("192.168.99.1"), parseIPv4Mask("255.255.255.0"), vbox)
+
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, "vboxnet0", net.Name)
+	assert.Equal(t, []string{"list hostonlyifs"}, vbox.calls)
+}
+
+func TestRemoveHostOnlyNetworkIfUnused(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs":                     stdOutOneHostOnlyNetwork,
+			"list vms":                             `"default" {0b5c2e48-3b2a-4d0f-8f5e-6a8f1e6b8a01}`,
+			"showvminfo default --machinereadable": `hostonlyadapter2="vboxnet0"`,
+		},
+	}
+
+	err := removeHostOnlyNetworkIfUnused("HostInterfaceNetworking-vboxnet0", "dev", vbox)
+
+	assert.NoError(t, err)
+	assert.NotContains(t, vbox.calls, "hostonlyif remove vboxnet0")
+
+	err = removeHostOnlyNetworkIfUnused("HostInterfaceNetworking-vboxnet0", "default", vbox)
+
+	assert.NoError(t, err)
+	assert.Contains(t, vbox.calls, "dhcpserver remove --netname HostInterfaceNetworking-vboxnet0")
+	assert.Contains(t, vbox.calls, "hostonlyif remove vboxnet0")
+}
+
+func TestListMultiHomedHostOnlyNetworks(t *testing.T) {
+	vbox := &VBoxManagerMock{
+		args:   "list hostonlyifs",
+		stdOut: stdOutMultiHomedHostOnlyNetwork,
+	}
+
+	nets, err := listHostOnlyNetworks(vbox)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(nets))
+
+	net := nets["HostInterfaceNetworking-vboxnet0"]
+
+	assert.Equal(t, "192.168.99.1", net.IPv4.IP.String())
+	assert.Equal(t, "ffffff00", net.IPv4.Mask.String())
+	assert.Equal(t, 2, len(net.IPv4Addrs))
+	assert.Equal(t, "192.168.99.1", net.IPv4Addrs[0].IP.String())
+	assert.Equal(t, "ffffff00", net.IPv4Addrs[0].Mask.String())
+	assert.Equal(t, "10.10.0.1", net.IPv4Addrs[1].IP.String())
+	assert.Equal(t, "ffff0000", net.IPv4Addrs[1].Mask.String())
+}
+
+func TestGetHostOnlyNetworkMatchesSecondaryAddress(t *testing.T) {
+	vbox := &VBoxManagerMock{
+		args:   "list hostonlyifs",
+		stdOut: stdOutMultiHomedHostOnlyNetwork,
+	}
+
+	nets, err := listHostOnlyNetworks(vbox)
+	assert.NoError(t, err)
+
+	n := getHostOnlyNetwork(nets, net.ParseIP("10.10.0.1"), parseIPv4Mask("255.255.0.0"), false)
+
+	assert.NotNil(t, n)
+	assert.Equal(t, "vboxnet0", n.Name)
+
+	n = getHostOnlyNetwork(nets, net.ParseIP("10.10.0.1"), parseIPv4Mask("255.255.255.0"), false)
+
+	assert.Nil(t, n)
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestHostOnlyInterfaceIndex(t *testing.T) {
+	assert.Equal(t, 0, hostOnlyInterfaceIndex("vboxnet0"))
+	assert.Equal(t, 12, hostOnlyInterfaceIndex("vboxnet12"))
+	assert.Equal(t, 0, hostOnlyInterfaceIndex("VirtualBox Host-Only Ethernet Adapter"))
+	assert.Equal(t, 2, hostOnlyInterfaceIndex("VirtualBox Host-Only Ethernet Adapter #2"))
+}
+
+func TestGetHostOnlyNetworkWithInterfaceIndexOnWindows(t *testing.T) {
+	vbox := &VBoxManagerMock{
+		args:   "list hostonlyifs",
+		stdOut: strings.Replace(stdOutOneHostOnlyNetwork, "vboxnet0", "VirtualBox Host-Only Ethernet Adapter #2", -1),
+	}
+
+	net, created, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.99.1"), Netmask: parseIPv4Mask("255.255.255.0"), IfIndex: intPtr(2)}, vbox)
+
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, "VirtualBox Host-Only Ethernet Adapter #2", net.Name)
+}
+
+func TestGetHostOnlyNetworkWithInterfaceIndex(t *testing.T) {
+	vbox := &VBoxManagerMock{
+		args:   "list hostonlyifs",
+		stdOut: stdOutOneHostOnlyNetwork,
+	}
+
+	net, created, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.99.1"), Netmask: parseIPv4Mask("255.255.255.0"), IfIndex: intPtr(0)}, vbox)
+
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, "vboxnet0", net.Name)
+}
+
+func TestFailWithInterfaceIndexOfIncompatibleNetwork(t *testing.T) {
+	vbox := &VBoxManagerMock{
+		args:   "list hostonlyifs",
+		stdOut: stdOutOneHostOnlyNetwork,
+	}
+
+	net, _, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.100.1"), Netmask: parseIPv4Mask("255.255.255.0"), IfIndex: intPtr(0)}, vbox)
+
+	assert.Nil(t, net)
+	assert.EqualError(t, err, "host-only interface vboxnet0 is already configured with an incompatible network 192.168.99.1/24 (IPv4 address: got 192.168.99.1, want 192.168.100.1)")
+}
+
+func TestFailWithInterfaceIndexOtherThanMatchedNetwork(t *testing.T) {
+	vbox := &VBoxManagerMock{
+		args:   "list hostonlyifs",
+		stdOut: stdOutOneHostOnlyNetwork,
+	}
+
+	net, _, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.99.1"), Netmask: parseIPv4Mask("255.255.255.0"), IfIndex: intPtr(3)}, vbox)
+
+	assert.Nil(t, net)
+	assert.EqualError(t, err, "the requested host-only network is already configured on vboxnet0, not on the interface of index 3")
+}
+
+func TestCreateHostOnlyNetworkWithInterfaceIndex(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"hostonlyif create": "Interface 'vboxnet1' was successfully created",
+			"list dhcpservers":  stdOutCreatedDHCPServer,
+		},
+		stdOutSeq: map[string][]string{
+			"list hostonlyifs": {stdOutOneHostOnlyNetwork, stdOutOneHostOnlyNetwork + fmt.Sprintf(stdOutCreatedHostOnlyNetwork, "Up")},
+		},
+	}
+
+	net, created, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.100.1"), Netmask: parseIPv4Mask("255.255.255.0"), DHCPIP: net.ParseIP("192.168.100.6"), DHCPLowerIP: net.ParseIP("192.168.100.100"), DHCPUpperIP: net.ParseIP("192.168.100.254"), IfIndex: intPtr(1)}, vbox)
+
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "vboxnet1", net.Name)
+}
+
+func TestFailWhenVirtualBoxCreatesAnotherInterface(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs":  stdOutOneHostOnlyNetwork,
+			"hostonlyif create": "Interface 'vboxnet1' was successfully created",
+		},
+	}
+
+	net, _, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.100.1"), Netmask: parseIPv4Mask("255.255.255.0"), DHCPIP: net.ParseIP("192.168.100.6"), DHCPLowerIP: net.ParseIP("192.168.100.100"), DHCPUpperIP: net.ParseIP("192.168.100.254"), IfIndex: intPtr(4)}, vbox)
+
+	assert.Nil(t, net)
+	assert.EqualError(t, err, "VirtualBox created host-only interface vboxnet1 instead of the one of index 4")
+	assert.Contains(t, vbox.calls, "hostonlyif remove vboxnet1")
+}
+
+func TestWaitForHostOnlyNetworkPollsUntilUp(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOutSeq: map[string][]string{
+			"list hostonlyifs": {stdOutOneHostOnlyNetwork, stdOutOneHostOnlyNetwork + fmt.Sprintf(stdOutCreatedHostOnlyNetwork, "Up")},
+		},
+	}
+
+	net, err := waitForHostOnlyNetwork("HostInterfaceNetworking-vboxnet1", 5*time.Second, vbox)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "vboxnet1", net.Name)
+	assert.Equal(t, "Up", net.Status)
+	assert.Equal(t, []string{"list hostonlyifs", "list hostonlyifs"}, vbox.calls)
+}
+
+func TestWaitForHostOnlyNetworkWaitsForStatusUp(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOutSeq: map[string][]string{
+			"list hostonlyifs": {fmt.Sprintf(stdOutCreatedHostOnlyNetwork, "Down"), fmt.Sprintf(stdOutCreatedHostOnlyNetwork, "Up")},
+		},
+	}
+
+	net, err := waitForHostOnlyNetwork("HostInterfaceNetworking-vboxnet1", 5*time.Second, vbox)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Up", net.Status)
+	assert.Equal(t, 2, len(vbox.calls))
+}
+
+func TestWaitForHostOnlyNetworkNotSettled(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs": stdOutOneHostOnlyNetwork,
+		},
+	}
+
+	net, err := waitForHostOnlyNetwork("HostInterfaceNetworking-vboxnet1", 0, vbox)
+
+	assert.Nil(t, net)
+	assert.Equal(t, errHostOnlyNetworkNotSettled, err)
+}
+
+func TestWaitForHostOnlyNetworkStopsWhenCancelled(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs": stdOutOneHostOnlyNetwork,
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	net, err := waitForHostOnlyNetworkContext(ctx, "HostInterfaceNetworking-vboxnet1", time.Minute, vbox)
+
+	assert.Nil(t, net)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
+
+func TestGetOrCreateHostOnlyNetworkCancelled(t *testing.T) {
+	vbox := &VBoxManagerScript{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	net, created, err := getOrCreateHostOnlyNetworkContext(ctx, hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.99.1"), Netmask: parseIPv4Mask("255.255.255.0")}, vbox)
+
+	assert.Nil(t, net)
+	assert.False(t, created)
+	assert.Equal(t, context.Canceled, err)
+	assert.Empty(t, vbox.calls)
+}
+
+func TestCreateHostOnlyNetworkNotSettled(t *testing.T) {
+	defer func(timeout time.Duration) {
+		hostOnlyNetworkSettleTimeout = timeout
+	}(hostOnlyNetworkSettleTimeout)
+	hostOnlyNetworkSettleTimeout = 0
+
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs":  stdOutOneHostOnlyNetwork,
+			"hostonlyif create": "Interface 'vboxnet1' was successfully created",
+		},
+	}
+
+	net, created, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.100.1"), Netmask: parseIPv4Mask("255.255.255.0"), DHCPIP: net.ParseIP("192.168.100.6"), DHCPLowerIP: net.ParseIP("192.168.100.100"), DHCPUpperIP: net.ParseIP("192.168.100.254")}, vbox)
+
+	assert.Nil(t, net)
+	assert.False(t, created)
+	assert.EqualError(t, err, "host-only network creation did not settle")
+	assert.Equal(t, "hostonlyif remove vboxnet1", vbox.calls[len(vbox.calls)-1])
+}
+
+func TestFindOverlappingHostOnlyNetwork(t *testing.T) {
+	vbox := &VBoxManagerMock{
+		args:   "list hostonlyifs",
+		stdOut: stdOutOneHostOnlyNetwork,
+	}
+	nets, err := listHostOnlyNetworks(vbox)
+	assert.NoError(t, err)
+
+	var tests = []struct {
+		requested string
+		conflict  string
+	}{
+		{"192.168.99.0/25", "192.168.99.0/24"},
+		{"192.168.99.128/25", "192.168.99.0/24"},
+		{"192.168.0.0/16", "192.168.99.0/24"},
+		{"192.168.99.0/24", ""},
+		{"192.168.100.0/24", ""},
+		{"10.0.0.0/8", ""},
+	}
+
+	for _, test := range tests {
+		_, requested, err := net.ParseCIDR(test.requested)
+		assert.NoError(t, err)
+
+		n, conflict := findOverlappingHostOnlyNetwork(*requested, nets)
+
+		if test.conflict == "" {
+			assert.Nil(t, n, test.requested)
+		} else {
+			assert.Equal(t, "vboxnet0", n.Name, test.requested)
+			assert.Equal(t, test.conflict, conflict.String(), test.requested)
+		}
+	}
+}
+
+func TestFindOverlappingHostOnlyNetworkIgnoresBuggyNetmask(t *testing.T) {
+	vbox := &VBoxManagerMock{
+		args:   "list hostonlyifs",
+		stdOut: strings.Replace(stdOutOneHostOnlyNetwork, "255.255.255.0", "15.0.0.0", -1),
+	}
+	nets, err := listHostOnlyNetworks(vbox)
+	assert.NoError(t, err)
+
+	_, requested, _ := net.ParseCIDR("192.168.99.0/25")
+	n, _ := findOverlappingHostOnlyNetwork(*requested, nets)
+
+	assert.Nil(t, n)
+}
+
+func TestFailToCreateOverlappingHostOnlyNetwork(t *testing.T) {
+	var tests = []struct {
+		hostIP  string
+		netmask string
+		err     string
+	}{
+		{"192.168.99.129", "255.255.255.128", "host-only network 192.168.99.128/25 overlaps with 192.168.99.0/24 on vboxnet0"},
+		{"192.168.0.1", "255.255.0.0", "host-only network 192.168.0.0/16 overlaps with 192.168.99.0/24 on vboxnet0"},
+	}
+
+	for _, test := range tests {
+		vbox := &VBoxManagerScript{
+			stdOut: map[string]string{
+				"list hostonlyifs":  stdOutOneHostOnlyNetwork,
+				"hostonlyif create": "Interface 'vboxnet1' was successfully created",
+			},
+		}
+
+		net, created, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP(test.hostIP), Netmask: parseIPv4Mask(test.netmask)}, vbox)
+
+		assert.Nil(t, net)
+		assert.False(t, created)
+		assert.EqualError(t, err, test.err)
+		assert.NotContains(t, vbox.calls, "hostonlyif create")
+	}
+}
+
+func TestParseMediumType(t *testing.T) {
+	var tests = []struct {
+		medium string
+		want   mediumType
+	}{
+		{"Ethernet", mediumEthernet},
+		{"ethernet", mediumEthernet},
+		{"ETHERNET", mediumEthernet},
+		{"WirelessLAN", mediumUnknown},
+		{"", mediumUnknown},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, parseMediumType(test.medium), test.medium)
+	}
+}
+
+func TestListHostOnlyNetworksWithUnknownMedium(t *testing.T) {
+	vbox := &VBoxManagerMock{
+		args:   "list hostonlyifs",
+		stdOut: stdOutWirelessHostOnlyNetwork,
+	}
+
+	nets, err := listHostOnlyNetworks(vbox)
+
+	assert.NoError(t, err)
+	net := nets["HostInterfaceNetworking-vboxnet0"]
+	assert.Equal(t, mediumUnknown, net.Medium)
+	assert.Equal(t, "unknown", net.Medium.String())
+	assert.Equal(t, "WirelessLAN", net.mediumName())
+}
+
+func TestListActiveHostOnlyNetworks(t *testing.T) {
+	vbox := &VBoxManagerMock{
+		args: "list hostonlyifs",
+		stdOut: `Name:            vboxnet0
+IPAddress:       192.168.99.1
+NetworkMask:     255.255.255.0
+Status:          Up
+VBoxNetworkName: HostInterfaceNetworking-vboxnet0
+
+Name:            vboxnet1
+IPAddress:       192.168.100.1
+NetworkMask:     255.255.255.0
+Status:          Down
+VBoxNetworkName: HostInterfaceNetworking-vboxnet1
+
+Name:            vboxnet2
+IPAddress:       0.0.0.0
+NetworkMask:     0.0.0.0
+Status:          Up
+VBoxNetworkName: HostInterfaceNetworking-vboxnet2
+
+Name:            vboxnet3
+Status:          Up
+VBoxNetworkName: HostInterfaceNetworking-vboxnet3
+
+Name:            vboxnet4
+IPAddress:       192.168.101.1
+NetworkMask:     255.255.255.0
+Status:          Up
+VBoxNetworkName: HostInterfaceNetworking-vboxnet4
+`,
+	}
+
+	nets, err := listActiveHostOnlyNetworks(vbox)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(nets))
+	assert.Equal(t, "vboxnet0", nets["HostInterfaceNetworking-vboxnet0"].Name)
+	assert.Equal(t, "vboxnet4", nets["HostInterfaceNetworking-vboxnet4"].Name)
+}
+
+func TestListActiveHostOnlyNetworksFailsWithVBoxManage(t *testing.T) {
+	vbox := &VBoxManagerMock{
+		args: "list hostonlyifs",
+		err:  errors.New("Failure"),
+	}
+
+	nets, err := listActiveHostOnlyNetworks(vbox)
+
+	assert.Nil(t, nets)
+	assert.Error(t, err)
+}
+
+func TestWaitForDHCPServerPollsUntilEnabled(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOutSeq: map[string][]string{
+			"list dhcpservers": {"", strings.Replace(stdOutCreatedDHCPServer, "Yes", "No", 1), stdOutCreatedDHCPServer},
+		},
+	}
+
+	err := waitForDHCPServer(vbox, "HostInterfaceNetworking-vboxnet1", 5*time.Second)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"list dhcpservers", "list dhcpservers", "list dhcpservers"}, vbox.calls)
+}
+
+func TestWaitForDHCPServerTimesOut(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list dhcpservers": stdOutOneDHCPServer,
+		},
+	}
+
+	err := waitForDHCPServer(vbox, "HostInterfaceNetworking-vboxnet1", 0)
+
+	assert.EqualError(t, err, "timed out waiting for the DHCP server of HostInterfaceNetworking-vboxnet1")
+}
+
+func TestCreateHostOnlyNetworkWaitsForDHCPServer(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"hostonlyif create": "Interface 'vboxnet1' was successfully created",
+		},
+		stdOutSeq: map[string][]string{
+			"list hostonlyifs": {stdOutOneHostOnlyNetwork, stdOutOneHostOnlyNetwork + fmt.Sprintf(stdOutCreatedHostOnlyNetwork, "Up")},
+			"list dhcpservers": {"", "", stdOutCreatedDHCPServer},
+		},
+	}
+
+	_, created, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.100.1"), Netmask: parseIPv4Mask("255.255.255.0"), DHCPIP: net.ParseIP("192.168.100.6"), DHCPLowerIP: net.ParseIP("192.168.100.100"), DHCPUpperIP: net.ParseIP("192.168.100.254")}, vbox)
+
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "list dhcpservers", vbox.calls[len(vbox.calls)-1])
+}
+
+func TestGetOrCreateHostOnlyNetworkRejectsHostIPInDHCPRange(t *testing.T) {
+	vbox := &VBoxManagerScript{}
+
+	_, _, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.100.100"), Netmask: parseIPv4Mask("255.255.255.0"), DHCPIP: net.ParseIP("192.168.100.6"), DHCPLowerIP: net.ParseIP("192.168.100.100"), DHCPUpperIP: net.ParseIP("192.168.100.254")}, vbox)
+
+	assert.EqualError(t, err, "the DHCP range 192.168.100.100-192.168.100.254 includes 192.168.100.100, the host's own address on the host-only network, which a machine could be given")
+	assert.Empty(t, vbox.calls)
+}
+
+func TestGetOrCreateHostOnlyNetworkRejectsHostIPAsDHCPServer(t *testing.T) {
+	vbox := &VBoxManagerScript{}
+
+	_, _, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.99.1"), Netmask: parseIPv4Mask("255.255.255.0"), DHCPIP: net.ParseIP("192.168.99.1"), DHCPLowerIP: net.ParseIP("192.168.99.100"), DHCPUpperIP: net.ParseIP("192.168.99.254")}, vbox)
+
+	assert.EqualError(t, err, "the DHCP server address 192.168.99.1 is the host's own address on the host-only network")
+	assert.Empty(t, vbox.calls)
+}
+
+func TestGetOrCreateHostOnlyNetworkResizesMatchingNetwork(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs": stdOutOneHostOnlyNetwork,
+			"list dhcpservers": stdOutOneDHCPServer,
+		},
+	}
+
+	net, created, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.99.1"), Netmask: parseIPv4Mask("255.255.255.128"), DHCPIP: net.ParseIP("192.168.99.6"), DHCPLowerIP: net.ParseIP("192.168.99.64"), DHCPUpperIP: net.ParseIP("192.168.99.126")}, vbox)
+
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, "vboxnet0", net.Name)
+	assert.Equal(t, "ffffff80", net.IPv4.Mask.String())
+	assert.Contains(t, vbox.calls, "hostonlyif ipconfig vboxnet0 --ip 192.168.99.1 --netmask 255.255.255.128")
+	assert.Contains(t, vbox.calls, "dhcpserver modify --netname HostInterfaceNetworking-vboxnet0 --ip 192.168.99.6 --netmask 255.255.255.128 --lowerip 192.168.99.64 --upperip 192.168.99.126 --enable")
+	assert.NotContains(t, vbox.calls, "hostonlyif create")
+}
+
+func TestGetOrCreateHostOnlyNetworkRefusesToResizeNetworkInUse(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs":                   stdOutOneHostOnlyNetwork,
+			"list dhcpservers":                   stdOutOneDHCPServer,
+			"list vms":                           `"other" {0b5c2e48-3b2a-4d0f-8f5e-6a8f1e6b8a01}`,
+			"showvminfo other --machinereadable": `hostonlyadapter2="vboxnet0"`,
+		},
+	}
+
+	_, _, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.99.1"), Netmask: parseIPv4Mask("255.255.255.128"), DHCPIP: net.ParseIP("192.168.99.6"), DHCPLowerIP: net.ParseIP("192.168.99.64"), DHCPUpperIP: net.ParseIP("192.168.99.126")}, vbox)
+
+	assert.EqualError(t, err, "host-only network vboxnet0 has the address 192.168.99.1 with the netmask 255.255.255.0, which can't be changed to 255.255.255.128 as it is used by other")
+	assert.NotContains(t, vbox.calls, "hostonlyif ipconfig vboxnet0 --ip 192.168.99.1 --netmask 255.255.255.128")
+}
+
+func TestUpdateDHCPServerRangeValidatesRange(t *testing.T) {
+	_, subnet, _ := net.ParseCIDR("192.168.99.0/25")
+
+	var tests = []struct {
+		lower, upper, server string
+		err                  string
+	}{
+		{"192.168.99.100", "192.168.99.254", "192.168.99.6", "DHCP address 192.168.99.254 isn't within 192.168.99.0/25"},
+		{"192.168.99.100", "192.168.99.120", "192.168.100.6", "DHCP address 192.168.100.6 isn't within 192.168.99.0/25"},
+		{"192.168.99.120", "192.168.99.100", "192.168.99.6", "invalid DHCP range 192.168.99.120-192.168.99.100, the lower address is above the upper one"},
+	}
+
+	for _, test := range tests {
+		vbox := &VBoxManagerScript{}
+
+		err := updateDHCPServerRange(vbox, "HostInterfaceNetworking-vboxnet0", *subnet, net.ParseIP(test.lower), net.ParseIP(test.upper), net.ParseIP(test.server))
+
+		assert.EqualError(t, err, test.err)
+		assert.Empty(t, vbox.calls)
+	}
+}
+
+func TestUpdateDHCPServerRangeWithoutServer(t *testing.T) {
+	_, subnet, _ := net.ParseCIDR("192.168.99.0/24")
+	vbox := &VBoxManagerScript{}
+
+	err := updateDHCPServerRange(vbox, "HostInterfaceNetworking-vboxnet0", *subnet, net.ParseIP("192.168.99.100"), net.ParseIP("192.168.99.254"), net.ParseIP("192.168.99.6"))
+
+	assert.EqualError(t, err, "host-only network HostInterfaceNetworking-vboxnet0 has no DHCP server")
+}
+
+// vboxManagerFailing fails the command failOn as VBoxManage would, printing
+// stdErr, and runs the other ones with the script.
+type vboxManagerFailing struct {
+	VBoxManagerScript
+	failOn string
+	stdErr string
+}
+
+func (v *vboxManagerFailing) vbm(args ...string) error {
+	_, _, err := v.vbmOutErr(args...)
+	return err
+}
+
+func (v *vboxManagerFailing) vbmOut(args ...string) (string, error) {
+	stdout, _, err := v.vbmOutErr(args...)
+	return stdout, err
+}
+
+func (v *vboxManagerFailing) vbmOutErr(args ...string) (string, string, error) {
+	if strings.Join(args, " ") == v.failOn {
+		v.calls = append(v.calls, v.failOn)
+		return "", v.stdErr, errors.New("exit status 1")
+	}
+
+	return v.VBoxManagerScript.vbmOutErr(args...)
+}
+
+func TestGetOrCreateHostOnlyNetworkKeepsVBoxManageStderr(t *testing.T) {
+	vbox := &vboxManagerFailing{
+		failOn: "hostonlyif create",
+		stdErr: "0%...\nProgress state: NS_ERROR_FAILURE\nVBoxManage: error: Failed to create the host-only adapter\nVBoxManage: error: VBoxNetAdpCtl: Error while adding new interface: failed to open /dev/vboxnetctl: No such file or directory\n",
+	}
+
+	_, _, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.99.1"), Netmask: parseIPv4Mask("255.255.255.0"), DHCP: dhcpEnabled}, &VBoxManagerRecorder{VBoxManager: vbox})
+
+	vbmErr, ok := err.(*VBoxManageError)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"hostonlyif", "create"}, vbmErr.Args)
+	assert.Equal(t, vbox.stdErr, vbmErr.Stderr)
+	assert.Contains(t, err.Error(), "VBoxManage hostonlyif create failed: exit status 1")
+	assert.Contains(t, err.Error(), "VBoxNetAdpCtl: Error while adding new interface: failed to open /dev/vboxnetctl")
+}
+
+func TestGetOrCreateHostOnlyNetworkKeepsDHCPServerStderr(t *testing.T) {
+	vbox := &vboxManagerFailing{
+		VBoxManagerScript: VBoxManagerScript{
+			stdOut: map[string]string{
+				"hostonlyif create": "Interface 'vboxnet1' was successfully created",
+			},
+			stdOutSeq: map[string][]string{
+				"list hostonlyifs": {stdOutOneHostOnlyNetwork, stdOutOneHostOnlyNetwork + fmt.Sprintf(stdOutCreatedHostOnlyNetwork, "Up")},
+			},
+		},
+		failOn: "dhcpserver add --netname HostInterfaceNetworking-vboxnet1 --ip 192.168.100.6 --netmask 255.255.255.0 --lowerip 192.168.100.100 --upperip 192.168.100.254 --enable",
+		stdErr: "VBoxManage: error: DHCP server already exists\n",
+	}
+
+	_, _, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{HostIP: net.ParseIP("192.168.100.1"), Netmask: parseIPv4Mask("255.255.255.0"), DHCPIP: net.ParseIP("192.168.100.6"), DHCPLowerIP: net.ParseIP("192.168.100.100"), DHCPUpperIP: net.ParseIP("192.168.100.254"), DHCP: dhcpEnabled}, &VBoxManagerRecorder{VBoxManager: vbox})
+
+	assert.IsType(t, &VBoxManageError{}, err)
+	assert.Contains(t, err.Error(), "VBoxManage: error: DHCP server already exists")
+}
+
+func TestReconcileHostOnlyNetworkRemovesInterfaceWhenIPConfigFails(t *testing.T) {
+	vbox := &vboxManagerFailing{
+		VBoxManagerScript: VBoxManagerScript{
+			stdOut: map[string]string{
+				"list hostonlyifs":  "",
+				"list dhcpservers":  stdOutOneDHCPServer,
+				"hostonlyif create": "Interface 'vboxnet0' was successfully created",
+			},
+		},
+		failOn: "hostonlyif ipconfig vboxnet0 --ip 192.168.99.1 --netmask 255.255.255.0",
+		stdErr: "VBoxManage: error: Code E_FAIL (0x80004005)\n",
+	}
+
+	_, created, err := reconcileHostOnlyNetwork("HostInterfaceNetworking-vboxnet0", net.ParseIP("192.168.99.1"), parseIPv4Mask("255.255.255.0"), vbox)
+
+	assert.Error(t, err)
+	assert.False(t, created)
+	assert.Equal(t, "hostonlyif remove vboxnet0", vbox.calls[len(vbox.calls)-1])
+}
+
+func TestReconcileHostOnlyNetworkRemovesInterfaceWhenDHCPFails(t *testing.T) {
+	vbox := &vboxManagerFailing{
+		VBoxManagerScript: VBoxManagerScript{
+			stdOut: map[string]string{
+				"list hostonlyifs":  "",
+				"list dhcpservers":  stdOutOneDHCPServer,
+				"hostonlyif create": "Interface 'vboxnet0' was successfully created",
+			},
+		},
+		failOn: "dhcpserver modify --netname HostInterfaceNetworking-vboxnet0 --ip 192.168.99.6 --netmask 255.255.255.0 --lowerip 192.168.99.100 --upperip 192.168.99.254 --enable",
+		stdErr: "VBoxManage: error: DHCP server does not exist\n",
+	}
+
+	_, created, err := reconcileHostOnlyNetwork("HostInterfaceNetworking-vboxnet0", net.ParseIP("192.168.99.1"), parseIPv4Mask("255.255.255.0"), vbox)
+
+	assert.Error(t, err)
+	assert.False(t, created)
+	assert.Equal(t, "hostonlyif remove vboxnet0", vbox.calls[len(vbox.calls)-1])
+}
+
+func TestDiffHostOnlyNetworkMatching(t *testing.T) {
+	vbox := &VBoxManagerMock{
+		args:   "list hostonlyifs",
+		stdOut: stdOutOneHostOnlyNetwork,
+	}
+	desired, err := listHostOnlyNetworks(vbox)
+	assert.NoError(t, err)
+	actual, err := listHostOnlyNetworks(vbox)
+	assert.NoError(t, err)
+
+	assert.Empty(t, diffHostOnlyNetwork(desired["HostInterfaceNetworking-vboxnet0"], actual["HostInterfaceNetworking-vboxnet0"]))
+}
+
+func TestDiffHostOnlyNetworkListsEveryDifference(t *testing.T) {
+	desired := &hostOnlyNetwork{
+		Name:   "vboxnet0",
+		IPv4:   net.IPNet{IP: net.ParseIP("192.168.99.1"), Mask: parseIPv4Mask("255.255.255.0")},
+		IPv6:   net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)},
+		DHCP:   true,
+		Status: "Up",
+		MTU:    1400,
+	}
+	actual := &hostOnlyNetwork{
+		Name:   "vboxnet1",
+		IPv4:   net.IPNet{IP: net.ParseIP("192.168.100.1"), Mask: parseIPv4Mask("255.255.0.0")},
+		Status: "Down",
+		MTU:    1500,
+	}
+
+	assert.Equal(t, []string{
+		"Name: got vboxnet1, want vboxnet0",
+		"IPv4 address: got 192.168.100.1, want 192.168.99.1",
+		"IPv4 netmask: got 255.255.0.0, want 255.255.255.0",
+		"IPv6 address: got none, want fe80::1",
+		"IPv6 prefix length: got none, want 64",
+		"DHCP: got disabled, want enabled",
+		"Status: got Down, want Up",
+		"MTU: got 1500, want 1400",
+	}, diffHostOnlyNetwork(desired, actual))
+}
+
+func TestDiffHostOnlyNetworkReportsFieldsSetOnOneSide(t *testing.T) {
+	desired := &hostOnlyNetwork{Name: "vboxnet0", Status: "Up"}
+	actual := &hostOnlyNetwork{
+		Name:   "vboxnet0",
+		IPv4:   net.IPNet{IP: net.ParseIP("192.168.99.1"), Mask: parseIPv4Mask("255.255.255.0")},
+		DHCP:   true,
+		Status: "Up",
+		MTU:    1500,
+	}
+
+	assert.Equal(t, []string{
+		"IPv4 address: got 192.168.99.1, want none",
+		"IPv4 netmask: got 255.255.255.0, want none",
+		"DHCP: got enabled, want disabled",
+		"MTU: got 1500, want none",
+	}, diffHostOnlyNetwork(desired, actual))
+	assert.Equal(t, []string{
+		"IPv4 address: got none, want 192.168.99.1",
+		"IPv4 netmask: got none, want 255.255.255.0",
+		"DHCP: got disabled, want enabled",
+		"MTU: got none, want 1500",
+	}, diffHostOnlyNetwork(actual, desired))
+	assert.Equal(t, []string{"no host-only network"}, diffHostOnlyNetwork(desired, nil))
+	assert.Equal(t, []string{"unexpected host-only network vboxnet0"}, diffHostOnlyNetwork(nil, actual))
+}
+
+func TestRemoveHostOnlyNetworkByCIDR(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs":                     stdOutOneHostOnlyNetwork + fmt.Sprintf(stdOutCreatedHostOnlyNetwork, "Up"),
+			"list dhcpservers":                     stdOutCreatedDHCPServer,
+			"list vms":                             `"default" {0b5c2e48-3b2a-4d0f-8f5e-6a8f1e6b8a01}`,
+			"showvminfo default --machinereadable": `hostonlyadapter2="vboxnet0"`,
+		},
+	}
+
+	err := removeHostOnlyNetworkByCIDR(vbox, net.IPNet{IP: net.ParseIP("192.168.100.1"), Mask: parseIPv4Mask("255.255.255.0")})
+
+	assert.NoError(t, err)
+	assert.Contains(t, vbox.calls, "dhcpserver remove --netname HostInterfaceNetworking-vboxnet1")
+	assert.Equal(t, "hostonlyif remove vboxnet1", vbox.calls[len(vbox.calls)-1])
+	assert.NotContains(t, vbox.calls, "hostonlyif remove vboxnet0")
+}
+
+func TestRemoveHostOnlyNetworkByCIDRWithoutDHCPServer(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs": stdOutOneHostOnlyNetwork,
+		},
+	}
+
+	err := removeHostOnlyNetworkByCIDR(vbox, net.IPNet{IP: net.ParseIP("192.168.99.1"), Mask: parseIPv4Mask("255.255.255.0")})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "hostonlyif remove vboxnet0", vbox.calls[len(vbox.calls)-1])
+}
+
+func TestRemoveHostOnlyNetworkByCIDRRefusesAttachedNetwork(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs":                     stdOutOneHostOnlyNetwork,
+			"list vms":                             `"default" {0b5c2e48-3b2a-4d0f-8f5e-6a8f1e6b8a01}`,
+			"showvminfo default --machinereadable": `hostonlyadapter2="vboxnet0"`,
+		},
+	}
+
+	err := removeHostOnlyNetworkByCIDR(vbox, net.IPNet{IP: net.ParseIP("192.168.99.1"), Mask: parseIPv4Mask("255.255.255.0")})
+
+	assert.EqualError(t, err, "host-only network 192.168.99.1/24 on vboxnet0 can't be removed as it is used by default")
+	assert.NotContains(t, vbox.calls, "hostonlyif remove vboxnet0")
+}
+
+func TestRemoveHostOnlyNetworkByCIDRFailsOnDuplicates(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs": stdOutTwoHostOnlyNetwork,
+		},
+	}
+
+	err := removeHostOnlyNetworkByCIDR(vbox, net.IPNet{IP: net.ParseIP("192.168.99.1"), Mask: parseIPv4Mask("255.255.255.0")})
+
+	assert.EqualError(t, err, "host-only network 192.168.99.1/24 is configured on several interfaces: vboxnet0, vboxnet1")
+	assert.Equal(t, []string{"list hostonlyifs"}, vbox.calls)
+}
+
+func TestRemoveHostOnlyNetworkByCIDRNotFound(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"list hostonlyifs": stdOutOneHostOnlyNetwork,
+		},
+	}
+
+	err := removeHostOnlyNetworkByCIDR(vbox, net.IPNet{IP: net.ParseIP("192.168.50.1"), Mask: parseIPv4Mask("255.255.255.0")})
+
+	assert.EqualError(t, err, "host-only network not found: 192.168.50.1/24")
+}
@@ -0,0 +1,122 @@
+package virtualbox
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// The first adapter is the NAT one used for SSH, the second the
+	// host-only one used to reach the engine.
+	firstExtraNIC = 3
+	maxNICs       = 8
+)
+
+var reBridgedInterface = regexp.MustCompile(`(?m)^Name:\s+(.+?)\s*$`)
+
+// extraNIC is a network adapter added to the VM on top of the NAT and the
+// host-only ones. Attachment is the host-only network or the host interface
+// it is attached to.
+type extraNIC struct {
+	Type       string
+	Attachment string
+}
+
+// parseExtraNIC parses the description of an adapter, one of "nat",
+// "hostonly:<network>" or "bridged:<host interface>".
+func parseExtraNIC(spec string) (extraNIC, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	nic := extraNIC{Type: parts[0]}
+	if len(parts) == 2 {
+		nic.Attachment = parts[1]
+	}
+
+	switch nic.Type {
+	case "nat":
+		if nic.Attachment == "" {
+			return nic, nil
+		}
+	case "hostonly", "bridged":
+		if nic.Attachment != "" {
+			return nic, nil
+		}
+	}
+
+	return extraNIC{}, fmt.Errorf("invalid network adapter %q: expected nat, hostonly:<network> or bridged:<host interface>", spec)
+}
+
+// extraNICs returns the adapters to add to the VM, the bridged adapter first.
+func (d *Driver) extraNICs() ([]extraNIC, error) {
+	specs := d.NICs
+	if d.BridgedAdapter != "" {
+		specs = append([]string{"bridged:" + d.BridgedAdapter}, specs...)
+	}
+
+	if len(specs) > maxNICs-firstExtraNIC+1 {
+		return nil, fmt.Errorf("a VM has at most %d network adapters, %d can be added", maxNICs, maxNICs-firstExtraNIC+1)
+	}
+
+	nics := []extraNIC{}
+	for _, spec := range specs {
+		nic, err := parseExtraNIC(spec)
+		if err != nil {
+			return nil, err
+		}
+		nics = append(nics, nic)
+	}
+
+	return nics, nil
+}
+
+// bridgedInterface returns the name VirtualBox gives to the host interface
+// name, which on some hosts is followed by a description, e.g.
+// "en0: Wi-Fi (AirPort)".
+func bridgedInterface(name string, vbox VBoxManager) (string, error) {
+	out, err := vbox.vbmOut("list", "bridgedifs")
+	if err != nil {
+		return "", err
+	}
+
+	for _, match := range reBridgedInterface.FindAllStringSubmatch(out, -1) {
+		if match[1] == name || strings.HasPrefix(match[1], name+":") {
+			return match[1], nil
+		}
+	}
+
+	return "", fmt.Errorf("no host interface %q to bridge the VM to, see VBoxManage list bridgedifs", name)
+}
+
+// attachExtraNICs adds the extra adapters to the VM, from the third one.
+func (d *Driver) attachExtraNICs() error {
+	nics, err := d.extraNICs()
+	if err != nil {
+		return err
+	}
+
+	for i, nic := range nics {
+		n := fmt.Sprintf("%d", firstExtraNIC+i)
+		args := []string{"modifyvm", d.MachineName,
+			"--nic" + n, nic.Type,
+			"--nictype" + n, d.HostOnlyNicType,
+			"--cableconnected" + n, "on",
+		}
+
+		switch nic.Type {
+		case "hostonly":
+			args = append(args, "--hostonlyadapter"+n, nic.Attachment)
+		case "bridged":
+			iface, err := bridgedInterface(nic.Attachment, d.VBoxManager)
+			if err != nil {
+				return err
+			}
+			args = append(args, "--bridgeadapter"+n, iface)
+		}
+
+		if err := d.vbm(args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
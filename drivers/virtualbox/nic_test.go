@@ -0,0 +1,77 @@
+package virtualbox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const stdOutBridgedInterfaces = `Name:            en0: Wi-Fi (AirPort)
+GUID:            00306e65-0000-4000-8000-a45e60e1a2b3
+DHCP:            Disabled
+
+Name:            eth1
+GUID:            00316874-0000-4000-8000-0800271a2b3c
+DHCP:            Disabled
+`
+
+func TestParseExtraNIC(t *testing.T) {
+	for spec, expected := range map[string]extraNIC{
+		"nat":               {Type: "nat"},
+		"hostonly:vboxnet1": {Type: "hostonly", Attachment: "vboxnet1"},
+		"bridged:en0":       {Type: "bridged", Attachment: "en0"},
+	} {
+		nic, err := parseExtraNIC(spec)
+		assert.NoError(t, err, spec)
+		assert.Equal(t, expected, nic, spec)
+	}
+
+	for _, spec := range []string{"", "nat:eth0", "hostonly", "bridged:", "intnet:foo"} {
+		_, err := parseExtraNIC(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestExtraNICsLimit(t *testing.T) {
+	driver := newTestDriver("default")
+	driver.BridgedAdapter = "en0"
+	driver.NICs = []string{"nat", "nat", "nat", "nat", "nat", "nat"}
+
+	_, err := driver.extraNICs()
+	assert.Error(t, err)
+
+	driver.NICs = driver.NICs[1:]
+	nics, err := driver.extraNICs()
+	assert.NoError(t, err)
+	assert.Equal(t, extraNIC{Type: "bridged", Attachment: "en0"}, nics[0])
+}
+
+func TestBridgedInterface(t *testing.T) {
+	vbox := &VBoxManagerScript{stdOut: map[string]string{"list bridgedifs": stdOutBridgedInterfaces}}
+
+	iface, err := bridgedInterface("en0", vbox)
+	assert.NoError(t, err)
+	assert.Equal(t, "en0: Wi-Fi (AirPort)", iface)
+
+	iface, err = bridgedInterface("eth1", vbox)
+	assert.NoError(t, err)
+	assert.Equal(t, "eth1", iface)
+
+	_, err = bridgedInterface("en", vbox)
+	assert.Error(t, err)
+}
+
+func TestAttachExtraNICs(t *testing.T) {
+	vbox := &VBoxManagerScript{stdOut: map[string]string{"list bridgedifs": stdOutBridgedInterfaces}}
+	driver := newTestDriver("default")
+	driver.VBoxManager = vbox
+	driver.BridgedAdapter = "en0"
+	driver.NICs = []string{"hostonly:vboxnet1"}
+
+	assert.NoError(t, driver.attachExtraNICs())
+	assert.Equal(t, []string{
+		"list bridgedifs",
+		"modifyvm default --nic3 bridged --nictype3 82540EM --cableconnected3 on --bridgeadapter3 en0: Wi-Fi (AirPort)",
+		"modifyvm default --nic4 hostonly --nictype4 82540EM --cableconnected4 on --hostonlyadapter4 vboxnet1",
+	}, vbox.calls)
+}
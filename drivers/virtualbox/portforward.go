@@ -0,0 +1,119 @@
+package virtualbox
+
+import (
+	"fmt"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/state"
+)
+
+// sshPortForward is the rule of the NAT adapter which SSH goes through, it
+// can't be added or removed by the user.
+const sshPortForward = "ssh"
+
+// parsePortForwards parses the rules given with --virtualbox-port-forward.
+func parsePortForwards(specs []string) ([]drivers.PortForward, error) {
+	rules := []drivers.PortForward{}
+	for _, spec := range specs {
+		rule, err := drivers.ParsePortForward(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := checkPortForward(rules, rule); err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// checkPortForward returns an error if rule clashes with the SSH rule or one
+// of rules.
+func checkPortForward(rules []drivers.PortForward, rule drivers.PortForward) error {
+	if rule.Name == sshPortForward {
+		return fmt.Errorf("port forwarding rule %s is reserved", sshPortForward)
+	}
+
+	for _, existing := range rules {
+		if existing.Name == rule.Name {
+			return fmt.Errorf("port forwarding rule %s already exists", rule.Name)
+		}
+		if existing.Protocol == rule.Protocol && existing.HostPort == rule.HostPort {
+			return fmt.Errorf("host port %d/%s is already forwarded by rule %s", rule.HostPort, rule.Protocol, existing.Name)
+		}
+	}
+
+	return nil
+}
+
+// natpfRule formats rule the way VBoxManage expects it:
+// name,protocol,host-ip,host-port,guest-ip,guest-port.
+func natpfRule(rule drivers.PortForward) string {
+	return fmt.Sprintf("%s,%s,%s,%d,,%d", rule.Name, rule.Protocol, rule.HostIP, rule.HostPort, rule.GuestPort)
+}
+
+// natpf changes the rules of the NAT adapter, through controlvm if the VM is
+// running since modifyvm only works on a stopped one.
+func (d *Driver) natpf(args ...string) error {
+	s, err := d.GetState()
+	if err != nil {
+		return err
+	}
+
+	if s == state.Running || s == state.Paused {
+		return d.vbm(append([]string{"controlvm", d.MachineName, "natpf1"}, args...)...)
+	}
+
+	return d.vbm(append([]string{"modifyvm", d.MachineName, "--natpf1"}, args...)...)
+}
+
+// applyPortForwards adds the rules given at creation to the new VM.
+func (d *Driver) applyPortForwards() error {
+	for _, rule := range d.PortForwards {
+		if err := d.vbm("modifyvm", d.MachineName, "--natpf1", natpfRule(rule)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *Driver) AddPortForward(rule drivers.PortForward) error {
+	if err := checkPortForward(d.PortForwards, rule); err != nil {
+		return err
+	}
+
+	log.Infof("Forwarding host port %d/%s to port %d of %s...", rule.HostPort, rule.Protocol, rule.GuestPort, d.MachineName)
+	if err := d.natpf(natpfRule(rule)); err != nil {
+		return err
+	}
+
+	d.PortForwards = append(d.PortForwards, rule)
+	return nil
+}
+
+func (d *Driver) ListPortForwards() ([]drivers.PortForward, error) {
+	return d.PortForwards, nil
+}
+
+func (d *Driver) RemovePortForward(name string) error {
+	for i, rule := range d.PortForwards {
+		if rule.Name != name {
+			continue
+		}
+
+		log.Infof("Removing port forwarding rule %s of %s...", name, d.MachineName)
+		if err := d.natpf("delete", name); err != nil {
+			return err
+		}
+
+		d.PortForwards = append(d.PortForwards[:i], d.PortForwards[i+1:]...)
+		return nil
+	}
+
+	return fmt.Errorf("port forwarding rule %s not found", name)
+}
@@ -0,0 +1,88 @@
+package virtualbox
+
+import (
+	"testing"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePortForwards(t *testing.T) {
+	rules, err := parsePortForwards([]string{"web=8080:80", "5353:53/udp"})
+	assert.NoError(t, err)
+	assert.Equal(t, []drivers.PortForward{
+		{Name: "web", Protocol: "tcp", HostPort: 8080, GuestPort: 80},
+		{Name: "udp-5353", Protocol: "udp", HostPort: 5353, GuestPort: 53},
+	}, rules)
+
+	for _, specs := range [][]string{
+		{"ssh=2222:22"},
+		{"web=8080:80", "web=8081:81"},
+		{"web=8080:80", "api=8080:81"},
+	} {
+		_, err := parsePortForwards(specs)
+		assert.Error(t, err, "%v", specs)
+	}
+}
+
+func TestAddPortForwardToStoppedMachine(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"showvminfo default --machinereadable": `VMState="poweroff"`,
+		},
+	}
+	driver := newTestDriver("default")
+	driver.VBoxManager = vbox
+
+	rule := drivers.PortForward{Name: "web", Protocol: "tcp", HostPort: 8080, GuestPort: 80}
+	assert.NoError(t, driver.AddPortForward(rule))
+
+	assert.Equal(t, "modifyvm default --natpf1 web,tcp,,8080,,80", vbox.calls[len(vbox.calls)-1])
+	assert.Equal(t, []drivers.PortForward{rule}, driver.PortForwards)
+}
+
+func TestAddPortForwardToRunningMachine(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"showvminfo default --machinereadable": `VMState="running"`,
+		},
+	}
+	driver := newTestDriver("default")
+	driver.VBoxManager = vbox
+
+	assert.NoError(t, driver.AddPortForward(drivers.PortForward{Name: "dns", Protocol: "udp", HostIP: "127.0.0.1", HostPort: 5353, GuestPort: 53}))
+
+	assert.Equal(t, "controlvm default natpf1 dns,udp,127.0.0.1,5353,,53", vbox.calls[len(vbox.calls)-1])
+}
+
+func TestAddPortForwardAlreadyExists(t *testing.T) {
+	vbox := &VBoxManagerScript{}
+	driver := newTestDriver("default")
+	driver.VBoxManager = vbox
+	driver.PortForwards = []drivers.PortForward{{Name: "web", Protocol: "tcp", HostPort: 8080, GuestPort: 80}}
+
+	err := driver.AddPortForward(drivers.PortForward{Name: "web", Protocol: "tcp", HostPort: 8081, GuestPort: 81})
+
+	assert.EqualError(t, err, "port forwarding rule web already exists")
+	assert.Empty(t, vbox.calls)
+}
+
+func TestRemovePortForward(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"showvminfo default --machinereadable": `VMState="running"`,
+		},
+	}
+	driver := newTestDriver("default")
+	driver.VBoxManager = vbox
+	driver.PortForwards = []drivers.PortForward{
+		{Name: "web", Protocol: "tcp", HostPort: 8080, GuestPort: 80},
+		{Name: "api", Protocol: "tcp", HostPort: 8081, GuestPort: 81},
+	}
+
+	assert.NoError(t, driver.RemovePortForward("web"))
+	assert.Equal(t, "controlvm default natpf1 delete web", vbox.calls[len(vbox.calls)-1])
+	assert.Equal(t, []drivers.PortForward{{Name: "api", Protocol: "tcp", HostPort: 8081, GuestPort: 81}}, driver.PortForwards)
+
+	assert.EqualError(t, driver.RemovePortForward("web"), "port forwarding rule web not found")
+}
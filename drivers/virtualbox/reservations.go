@@ -0,0 +1,254 @@
+package virtualbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+const hostOnlyReservationsFile = "hostonly-reservations.json"
+
+var (
+	// hostOnlyReservationTimeout is how long a reservation is honoured. A
+	// create which takes longer, or which crashed, no longer holds its CIDR.
+	hostOnlyReservationTimeout = 10 * time.Minute
+
+	// hostOnlyReservationsLockTimeout is how long the lock of the reservations
+	// file can be held before it is considered abandoned.
+	hostOnlyReservationsLockTimeout = 30 * time.Second
+
+	hostOnlyReservationsPollInterval = 100 * time.Millisecond
+)
+
+// hostOnlyReservation records the CIDR of the host-only network a machine is
+// about to create, before the network exists.
+type hostOnlyReservation struct {
+	Machine  string
+	CIDR     string
+	Reserved time.Time
+}
+
+func (r hostOnlyReservation) subnet() (net.IPNet, error) {
+	ip, network, err := net.ParseCIDR(r.CIDR)
+	if err != nil {
+		return net.IPNet{}, err
+	}
+
+	return net.IPNet{IP: ip.Mask(network.Mask), Mask: network.Mask}, nil
+}
+
+func (r hostOnlyReservation) overlaps(subnet net.IPNet) bool {
+	reserved, err := r.subnet()
+	if err != nil {
+		return false
+	}
+
+	return reserved.Contains(subnet.IP) || subnet.Contains(reserved.IP)
+}
+
+// hostOnlyReservations is the registry, shared by the machines of a store, of
+// the host-only CIDRs being set up. It keeps two docker-machine processes from
+// both picking a subnet which is free only until either creates its network.
+type hostOnlyReservations struct {
+	path    string
+	machine string
+}
+
+func newHostOnlyReservations(storePath, machine string) *hostOnlyReservations {
+	return &hostOnlyReservations{
+		path:    filepath.Join(storePath, hostOnlyReservationsFile),
+		machine: machine,
+	}
+}
+
+// others returns the live reservations of the other machines.
+func (r *hostOnlyReservations) others(reservations []hostOnlyReservation) []hostOnlyReservation {
+	others := []hostOnlyReservation{}
+	for _, reservation := range reservations {
+		if reservation.Machine != r.machine {
+			others = append(others, reservation)
+		}
+	}
+
+	return others
+}
+
+// allocate reserves a CIDR chosen by choose, which is given the subnets
+// reserved by the other machines.
+func (r *hostOnlyReservations) allocate(choose func(reserved []net.IPNet) (net.IPNet, error)) (net.IPNet, error) {
+	var cidr net.IPNet
+
+	err := r.update(func(reservations []hostOnlyReservation) ([]hostOnlyReservation, error) {
+		others := r.others(reservations)
+
+		reserved := []net.IPNet{}
+		for _, reservation := range others {
+			if subnet, err := reservation.subnet(); err == nil {
+				reserved = append(reserved, subnet)
+			}
+		}
+
+		var err error
+		if cidr, err = choose(reserved); err != nil {
+			return nil, err
+		}
+
+		return append(others, hostOnlyReservation{Machine: r.machine, CIDR: cidr.String(), Reserved: time.Now()}), nil
+	})
+
+	return cidr, err
+}
+
+// reserve reserves cidr. Another machine setting up the very same network is
+// waited for, so that the network is created once and then shared, while one
+// setting up an overlapping network is an error.
+func (r *hostOnlyReservations) reserve(cidr net.IPNet) error {
+	subnet := net.IPNet{IP: cidr.IP.Mask(cidr.Mask), Mask: cidr.Mask}
+
+	for {
+		pending := false
+
+		err := r.update(func(reservations []hostOnlyReservation) ([]hostOnlyReservation, error) {
+			others := r.others(reservations)
+			for _, reservation := range others {
+				if !reservation.overlaps(subnet) {
+					continue
+				}
+
+				if reserved, _ := reservation.subnet(); reserved.String() == subnet.String() {
+					pending = true
+					return nil, nil
+				}
+
+				return nil, fmt.Errorf("host-only network %s overlaps with %s, which is being set up for %s", subnet.String(), reservation.CIDR, reservation.Machine)
+			}
+
+			return append(others, hostOnlyReservation{Machine: r.machine, CIDR: cidr.String(), Reserved: time.Now()}), nil
+		})
+		if err != nil || !pending {
+			return err
+		}
+
+		log.Debugf("Waiting for another machine to set up the host-only network %s", subnet.String())
+		time.Sleep(hostOnlyReservationsPollInterval)
+	}
+}
+
+// release drops the reservation of the machine, if any.
+func (r *hostOnlyReservations) release() error {
+	return r.update(func(reservations []hostOnlyReservation) ([]hostOnlyReservation, error) {
+		return r.others(reservations), nil
+	})
+}
+
+// update runs f with the live reservations while holding the lock of the
+// registry, and saves those it returns, unless they are nil.
+func (r *hostOnlyReservations) update(f func([]hostOnlyReservation) ([]hostOnlyReservation, error)) error {
+	unlock, err := r.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	reservations, err := r.load()
+	if err != nil {
+		return err
+	}
+
+	updated, err := f(reservations)
+	if err != nil || updated == nil {
+		return err
+	}
+
+	data, err := json.Marshal(updated)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(r.path, data, 0600)
+}
+
+// load returns the reservations which haven't timed out.
+func (r *hostOnlyReservations) load() ([]hostOnlyReservation, error) {
+	data, err := ioutil.ReadFile(r.path)
+	if os.IsNotExist(err) {
+		return []hostOnlyReservation{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	all := []hostOnlyReservation{}
+	if err := json.Unmarshal(data, &all); err != nil {
+		log.Warnf("Ignoring the corrupted host-only reservations in %s: %s", r.path, err)
+		return []hostOnlyReservation{}, nil
+	}
+
+	live := []hostOnlyReservation{}
+	for _, reservation := range all {
+		if time.Since(reservation.Reserved) < hostOnlyReservationTimeout {
+			live = append(live, reservation)
+		}
+	}
+
+	return live, nil
+}
+
+// lock takes the lock of the registry, a file which only one process can
+// create. A lock left behind by a crashed process is broken after
+// hostOnlyReservationsLockTimeout.
+func (r *hostOnlyReservations) lock() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0700); err != nil {
+		return nil, err
+	}
+
+	lockPath := r.path + ".lock"
+	for {
+		f, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > hostOnlyReservationsLockTimeout {
+			log.Debugf("Breaking the abandoned lock %s", lockPath)
+			os.Remove(lockPath)
+			continue
+		}
+
+		time.Sleep(hostOnlyReservationsPollInterval)
+	}
+}
+
+// unreservedHostOnlySubnets returns the subnets of the pool which don't
+// overlap any reserved one, in the order of the pool.
+func unreservedHostOnlySubnets(pool []net.IPNet, reserved []net.IPNet) []net.IPNet {
+	unreserved := []net.IPNet{}
+
+	for _, candidate := range pool {
+		subnet := net.IPNet{IP: candidate.IP.Mask(candidate.Mask), Mask: candidate.Mask}
+
+		overlaps := false
+		for _, r := range reserved {
+			if r.Contains(subnet.IP) || subnet.Contains(r.IP) {
+				overlaps = true
+				break
+			}
+		}
+
+		if !overlaps {
+			unreserved = append(unreserved, candidate)
+		}
+	}
+
+	return unreserved
+}
@@ -0,0 +1,134 @@
+package virtualbox
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestReservationsStore(t *testing.T) string {
+	storePath, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return storePath
+}
+
+func mustParseReservationCIDR(cidr string) net.IPNet {
+	ip, network, _ := net.ParseCIDR(cidr)
+	return net.IPNet{IP: ip, Mask: network.Mask}
+}
+
+func TestReserveAndRelease(t *testing.T) {
+	storePath := newTestReservationsStore(t)
+	defer os.RemoveAll(storePath)
+
+	reservations := newHostOnlyReservations(storePath, "default")
+
+	assert.NoError(t, reservations.reserve(mustParseReservationCIDR("192.168.99.1/24")))
+
+	live, err := reservations.load()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(live))
+	assert.Equal(t, "default", live[0].Machine)
+	assert.Equal(t, "192.168.99.1/24", live[0].CIDR)
+
+	assert.NoError(t, reservations.release())
+
+	live, err = reservations.load()
+	assert.NoError(t, err)
+	assert.Empty(t, live)
+
+	_, err = os.Stat(filepath.Join(storePath, hostOnlyReservationsFile+".lock"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestReserveFailsOnOverlappingReservation(t *testing.T) {
+	storePath := newTestReservationsStore(t)
+	defer os.RemoveAll(storePath)
+
+	other := newHostOnlyReservations(storePath, "other")
+	assert.NoError(t, other.reserve(mustParseReservationCIDR("192.168.96.1/20")))
+
+	err := newHostOnlyReservations(storePath, "default").reserve(mustParseReservationCIDR("192.168.99.1/24"))
+
+	assert.EqualError(t, err, "host-only network 192.168.99.0/24 overlaps with 192.168.96.1/20, which is being set up for other")
+}
+
+func TestReserveWaitsForSameNetwork(t *testing.T) {
+	defer func(interval time.Duration) { hostOnlyReservationsPollInterval = interval }(hostOnlyReservationsPollInterval)
+	hostOnlyReservationsPollInterval = time.Millisecond
+
+	storePath := newTestReservationsStore(t)
+	defer os.RemoveAll(storePath)
+
+	other := newHostOnlyReservations(storePath, "other")
+	assert.NoError(t, other.reserve(mustParseReservationCIDR("192.168.99.1/24")))
+
+	reserved := make(chan error)
+	go func() {
+		reserved <- newHostOnlyReservations(storePath, "default").reserve(mustParseReservationCIDR("192.168.99.1/24"))
+	}()
+
+	select {
+	case <-reserved:
+		t.Fatal("Reserved a network another machine is setting up")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.NoError(t, other.release())
+	assert.NoError(t, <-reserved)
+}
+
+func TestStaleReservationsAreIgnored(t *testing.T) {
+	storePath := newTestReservationsStore(t)
+	defer os.RemoveAll(storePath)
+
+	data, _ := json.Marshal([]hostOnlyReservation{
+		{Machine: "crashed", CIDR: "192.168.99.1/24", Reserved: time.Now().Add(-2 * hostOnlyReservationTimeout)},
+	})
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(storePath, hostOnlyReservationsFile), data, 0600))
+
+	reservations := newHostOnlyReservations(storePath, "default")
+
+	assert.NoError(t, reservations.reserve(mustParseReservationCIDR("192.168.99.1/24")))
+
+	live, err := reservations.load()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(live))
+	assert.Equal(t, "default", live[0].Machine)
+}
+
+func TestAbandonedLockIsBroken(t *testing.T) {
+	storePath := newTestReservationsStore(t)
+	defer os.RemoveAll(storePath)
+
+	lockPath := filepath.Join(storePath, hostOnlyReservationsFile+".lock")
+	assert.NoError(t, ioutil.WriteFile(lockPath, nil, 0600))
+	old := time.Now().Add(-2 * hostOnlyReservationsLockTimeout)
+	assert.NoError(t, os.Chtimes(lockPath, old, old))
+
+	err := newHostOnlyReservations(storePath, "default").reserve(mustParseReservationCIDR("192.168.99.1/24"))
+
+	assert.NoError(t, err)
+}
+
+func TestUnreservedHostOnlySubnets(t *testing.T) {
+	pool := []net.IPNet{
+		mustParseReservationCIDR("192.168.99.1/24"),
+		mustParseReservationCIDR("192.168.100.1/24"),
+		mustParseReservationCIDR("192.168.101.1/24"),
+	}
+	reserved := []net.IPNet{mustParseReservationCIDR("192.168.100.0/23")}
+
+	unreserved := unreservedHostOnlySubnets(pool, reserved)
+
+	assert.Equal(t, []net.IPNet{pool[0]}, unreserved)
+}
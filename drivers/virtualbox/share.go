@@ -0,0 +1,92 @@
+package virtualbox
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// defaultSharedFolder returns the folder holding the home directories of
+// the host, shared unless --virtualbox-no-share is given, or nil on hosts
+// without one. boot2docker mounts the shares at /<name>.
+func defaultSharedFolder(goos string) *drivers.SharedFolder {
+	switch goos {
+	case "windows":
+		return &drivers.SharedFolder{Name: "c/Users", HostPath: "c:\\Users", GuestPath: "/c/Users"}
+	case "darwin":
+		return &drivers.SharedFolder{Name: "Users", HostPath: "/Users", GuestPath: "/Users"}
+	case "linux":
+		return &drivers.SharedFolder{Name: "hosthome", HostPath: "/home", GuestPath: "/hosthome"}
+	}
+
+	return nil
+}
+
+// sharedFolders returns the folders shared with the VM: the default one if
+// it exists on the host and no other folder is shared at its guest path,
+// then those given with --virtualbox-share-folder.
+func (d *Driver) sharedFolders() ([]drivers.SharedFolder, error) {
+	folders := []drivers.SharedFolder{}
+
+	if def := defaultSharedFolder(runtime.GOOS); def != nil && !d.NoShare && !d.sharesGuestPath(def.GuestPath) {
+		if _, err := os.Stat(def.HostPath); err == nil {
+			folders = append(folders, *def)
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	return append(folders, d.ShareFolders...), nil
+}
+
+func (d *Driver) sharesGuestPath(guestPath string) bool {
+	for _, folder := range d.ShareFolders {
+		if folder.GuestPath == guestPath {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d *Driver) SharedFolders() ([]drivers.SharedFolder, error) {
+	return d.sharedFolders()
+}
+
+// addSharedFolders adds the shared folders to the VM, for VBoxService to
+// automount them.
+func (d *Driver) addSharedFolders() error {
+	folders, err := d.sharedFolders()
+	if err != nil {
+		return err
+	}
+
+	for _, folder := range folders {
+		if _, err := os.Stat(folder.HostPath); err != nil {
+			return fmt.Errorf("unable to share %s with the VM: %s", folder.HostPath, err)
+		}
+
+		if folder.Consistency != "" && folder.Consistency != drivers.ConsistencyConsistent {
+			log.Warnf("VirtualBox doesn't cache shared folders, %s is shared with the %s option ignored", folder.HostPath, folder.Consistency)
+		}
+
+		log.Debugf("Sharing %s at %s", folder.HostPath, folder.GuestPath)
+		args := []string{"sharedfolder", "add", d.MachineName, "--name", folder.Name, "--hostpath", folder.HostPath, "--automount"}
+		if folder.ReadOnly {
+			args = append(args, "--readonly")
+		}
+		if err := d.vbm(args...); err != nil {
+			return err
+		}
+
+		// enable symlinks
+		if err := d.vbm("setextradata", d.MachineName, "VBoxInternal2/SharedFoldersEnableSymlinksCreate/"+folder.Name, "1"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package virtualbox
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultSharedFolder(t *testing.T) {
+	assert.Equal(t, "c/Users", defaultSharedFolder("windows").Name)
+	assert.Equal(t, "/Users", defaultSharedFolder("darwin").GuestPath)
+	assert.Equal(t, "/hosthome", defaultSharedFolder("linux").GuestPath)
+	assert.Nil(t, defaultSharedFolder("plan9"))
+}
+
+func TestAddSharedFolders(t *testing.T) {
+	hostPath, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(hostPath)
+
+	vbox := &VBoxManagerScript{}
+	driver := newTestDriver("default")
+	driver.VBoxManager = vbox
+	driver.NoShare = true
+	driver.ShareFolders = []drivers.SharedFolder{
+		{Name: "src", HostPath: hostPath, GuestPath: "/src", ReadOnly: true},
+	}
+
+	assert.NoError(t, driver.addSharedFolders())
+	assert.Equal(t, []string{
+		"sharedfolder add default --name src --hostpath " + hostPath + " --automount --readonly",
+		"setextradata default VBoxInternal2/SharedFoldersEnableSymlinksCreate/src 1",
+	}, vbox.calls)
+}
+
+func TestAddSharedFoldersMissingHostPath(t *testing.T) {
+	driver := newTestDriver("default")
+	driver.VBoxManager = &VBoxManagerScript{}
+	driver.NoShare = true
+	driver.ShareFolders = []drivers.SharedFolder{
+		{Name: "src", HostPath: "/does/not/exist", GuestPath: "/src"},
+	}
+
+	assert.Error(t, driver.addSharedFolders())
+}
+
+func TestSharedFoldersOverrideDefault(t *testing.T) {
+	def := defaultSharedFolder(runtime.GOOS)
+	if def == nil {
+		t.Skip("no default shared folder on " + runtime.GOOS)
+	}
+
+	driver := newTestDriver("default")
+	driver.ShareFolders = []drivers.SharedFolder{
+		{Name: def.Name, HostPath: "/srv", GuestPath: def.GuestPath},
+	}
+
+	folders, err := driver.sharedFolders()
+
+	assert.NoError(t, err)
+	assert.Equal(t, driver.ShareFolders, folders)
+}
@@ -0,0 +1,111 @@
+package virtualbox
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/state"
+)
+
+var (
+	reSnapshotField = regexp.MustCompile(`^(SnapshotName|SnapshotUUID)((?:-\d+)*)="(.*)"$`)
+	reNoSnapshots   = regexp.MustCompile(`does not have any snapshots`)
+)
+
+// Capabilities adds snapshots, port forwarding and shared folders to the
+// default capabilities.
+func (d *Driver) Capabilities() []drivers.Capability {
+	return []drivers.Capability{
+		drivers.CapabilityStart,
+		drivers.CapabilityStop,
+		drivers.CapabilityKill,
+		drivers.CapabilitySnapshot,
+		drivers.CapabilityPortForward,
+		drivers.CapabilitySharedFolders,
+	}
+}
+
+func (d *Driver) CreateSnapshot(name string) error {
+	log.Infof("Taking snapshot %s of %s...", name, d.MachineName)
+	return d.vbm("snapshot", d.MachineName, "take", name)
+}
+
+func (d *Driver) ListSnapshots() ([]drivers.Snapshot, error) {
+	stdout, stderr, err := d.vbmOutErr("snapshot", d.MachineName, "list", "--machinereadable")
+	if err != nil {
+		if reNoSnapshots.MatchString(stdout) || reNoSnapshots.MatchString(stderr) {
+			return []drivers.Snapshot{}, nil
+		}
+		return nil, err
+	}
+
+	return parseSnapshots(stdout), nil
+}
+
+func (d *Driver) RestoreSnapshot(name string) error {
+	s, err := d.GetState()
+	if err != nil {
+		return err
+	}
+
+	if s == state.Running || s == state.Paused {
+		return fmt.Errorf("%s must be stopped to restore snapshot %s", d.MachineName, name)
+	}
+
+	log.Infof("Restoring snapshot %s of %s...", name, d.MachineName)
+	return d.vbm("snapshot", d.MachineName, "restore", name)
+}
+
+func (d *Driver) DeleteSnapshot(name string) error {
+	log.Infof("Deleting snapshot %s of %s...", name, d.MachineName)
+	return d.vbm("snapshot", d.MachineName, "delete", name)
+}
+
+// parseSnapshots reads the tree of snapshots printed by
+// `VBoxManage snapshot <vm> list --machinereadable`, in which the fields of
+// each snapshot share a suffix giving its position in the tree, e.g.
+// SnapshotName-1-2.
+func parseSnapshots(out string) []drivers.Snapshot {
+	snapshots := []drivers.Snapshot{}
+	bySuffix := map[string]int{}
+	current := ""
+
+	s := bufio.NewScanner(strings.NewReader(out))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+
+		if strings.HasPrefix(line, "CurrentSnapshotUUID=") {
+			current = strings.Trim(strings.TrimPrefix(line, "CurrentSnapshotUUID="), `"`)
+			continue
+		}
+
+		groups := reSnapshotField.FindStringSubmatch(line)
+		if groups == nil {
+			continue
+		}
+
+		i, ok := bySuffix[groups[2]]
+		if !ok {
+			i = len(snapshots)
+			bySuffix[groups[2]] = i
+			snapshots = append(snapshots, drivers.Snapshot{})
+		}
+
+		switch groups[1] {
+		case "SnapshotName":
+			snapshots[i].Name = groups[3]
+		case "SnapshotUUID":
+			snapshots[i].ID = groups[3]
+		}
+	}
+
+	for i := range snapshots {
+		snapshots[i].Current = current != "" && snapshots[i].ID == current
+	}
+
+	return snapshots
+}
@@ -0,0 +1,107 @@
+package virtualbox
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+const stdOutSnapshots = `SnapshotName="clean"
+SnapshotUUID="6f1ab4f6-8c4f-4a5d-9a3c-2e0c3d7d1b11"
+SnapshotName-1="with-redis"
+SnapshotUUID-1="0b2f4e1c-77aa-4d4e-8f51-9c6a1de2c0a7"
+SnapshotDescription-1="redis pulled"
+SnapshotName-1-1="with-redis-and-db"
+SnapshotUUID-1-1="d8e3c0f2-1b4a-4f3e-a2d7-5e9b8c7a6f40"
+CurrentSnapshotName="with-redis"
+CurrentSnapshotUUID="0b2f4e1c-77aa-4d4e-8f51-9c6a1de2c0a7"
+CurrentSnapshotNode="SnapshotName-1"
+`
+
+func TestParseSnapshots(t *testing.T) {
+	snapshots := parseSnapshots(stdOutSnapshots)
+
+	assert.Equal(t, []drivers.Snapshot{
+		{Name: "clean", ID: "6f1ab4f6-8c4f-4a5d-9a3c-2e0c3d7d1b11"},
+		{Name: "with-redis", ID: "0b2f4e1c-77aa-4d4e-8f51-9c6a1de2c0a7", Current: true},
+		{Name: "with-redis-and-db", ID: "d8e3c0f2-1b4a-4f3e-a2d7-5e9b8c7a6f40"},
+	}, snapshots)
+}
+
+func TestListSnapshots(t *testing.T) {
+	driver := newTestDriver("default")
+	driver.VBoxManager = &VBoxManagerMock{
+		args:   "snapshot default list --machinereadable",
+		stdOut: stdOutSnapshots,
+	}
+
+	snapshots, err := driver.ListSnapshots()
+
+	assert.NoError(t, err)
+	assert.Len(t, snapshots, 3)
+}
+
+func TestListSnapshotsWithoutSnapshots(t *testing.T) {
+	driver := newTestDriver("default")
+	driver.VBoxManager = &VBoxManagerMock{
+		args:   "snapshot default list --machinereadable",
+		stdOut: "This machine does not have any snapshots\n",
+		err:    errors.New("exit status 1"),
+	}
+
+	snapshots, err := driver.ListSnapshots()
+
+	assert.NoError(t, err)
+	assert.Empty(t, snapshots)
+}
+
+func TestCreateAndDeleteSnapshot(t *testing.T) {
+	vbox := &VBoxManagerScript{}
+	driver := newTestDriver("default")
+	driver.VBoxManager = vbox
+
+	assert.NoError(t, driver.CreateSnapshot("clean"))
+	assert.NoError(t, driver.DeleteSnapshot("clean"))
+
+	assert.Equal(t, []string{
+		"snapshot default take clean",
+		"snapshot default delete clean",
+	}, vbox.calls)
+}
+
+func TestRestoreSnapshot(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"showvminfo default --machinereadable": `VMState="poweroff"`,
+		},
+	}
+	driver := newTestDriver("default")
+	driver.VBoxManager = vbox
+
+	assert.NoError(t, driver.RestoreSnapshot("clean"))
+	assert.Equal(t, "snapshot default restore clean", vbox.calls[len(vbox.calls)-1])
+}
+
+func TestRestoreSnapshotOfRunningMachine(t *testing.T) {
+	vbox := &VBoxManagerScript{
+		stdOut: map[string]string{
+			"showvminfo default --machinereadable": `VMState="running"`,
+		},
+	}
+	driver := newTestDriver("default")
+	driver.VBoxManager = vbox
+
+	err := driver.RestoreSnapshot("clean")
+
+	assert.EqualError(t, err, "default must be stopped to restore snapshot clean")
+	assert.Equal(t, []string{"showvminfo default --machinereadable"}, vbox.calls)
+}
+
+func TestCapabilitiesIncludeSnapshot(t *testing.T) {
+	driver := newTestDriver("default")
+
+	assert.True(t, drivers.HasCapability(driver, drivers.CapabilitySnapshot))
+	assert.True(t, drivers.HasCapability(driver, drivers.CapabilityKill))
+}
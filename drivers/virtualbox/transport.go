@@ -0,0 +1,121 @@
+package virtualbox
+
+import (
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// VBoxTransport builds the processes which run VBoxManage, so that it can run
+// somewhere else than next to docker-machine, such as on the Windows host when
+// docker-machine runs in WSL.
+type VBoxTransport interface {
+	// Command returns the process running VBoxManage with args. The args are
+	// those of a local VBoxManage, the transport translates the file paths
+	// among them if it needs to.
+	Command(args ...string) *exec.Cmd
+}
+
+// vboxProber is implemented by the transports which can check that
+// VBoxManage is available before running it.
+type vboxProber interface {
+	probe() error
+}
+
+// lookVBoxManage returns ErrVBMNotFound unless path is an executable.
+func lookVBoxManage(path string) error {
+	if _, err := exec.LookPath(path); err != nil {
+		return ErrVBMNotFound
+	}
+
+	return nil
+}
+
+// localTransport runs the VBoxManage found on this host.
+type localTransport struct {
+	path string
+}
+
+func (t localTransport) Command(args ...string) *exec.Cmd {
+	return exec.Command(t.path, args...)
+}
+
+func (t localTransport) probe() error {
+	return lookVBoxManage(t.path)
+}
+
+// wslTransport runs VBoxManage.exe on the Windows host through WSL interop.
+// Windows processes don't understand Linux paths, so the paths among the args
+// are translated: those under /mnt/<drive> to the drive, the others to the
+// \\wsl$ share of the distribution.
+type wslTransport struct {
+	path   string
+	distro string
+}
+
+func (t wslTransport) Command(args ...string) *exec.Cmd {
+	return exec.Command(t.path, translateWSLArgs(t.distro, args)...)
+}
+
+func (t wslTransport) probe() error {
+	return lookVBoxManage(t.path)
+}
+
+// defaultVBoxTransport returns the WSL transport when running in WSL with
+// only the Windows VBoxManage.exe available, the local transport otherwise.
+func defaultVBoxTransport() VBoxTransport {
+	if distro := os.Getenv("WSL_DISTRO_NAME"); distro != "" && strings.HasSuffix(strings.ToLower(vboxManageCmd), ".exe") {
+		return wslTransport{path: vboxManageCmd, distro: distro}
+	}
+
+	return localTransport{path: vboxManageCmd}
+}
+
+// transportOf returns the transport of vbox, looking through the recorders.
+func transportOf(vbox VBoxManager) VBoxTransport {
+	switch v := vbox.(type) {
+	case *VBoxManagerRecorder:
+		return transportOf(v.VBoxManager)
+	case *VBoxCmdManager:
+		return v.transport()
+	}
+
+	return defaultVBoxTransport()
+}
+
+// translateWSLArgs translates the absolute Linux paths among args. The args
+// of guestproperty are property names, such as /VirtualBox/GuestInfo/OS,
+// which only look like paths, so they are left as is.
+func translateWSLArgs(distro string, args []string) []string {
+	if len(args) > 0 && args[0] == "guestproperty" {
+		return args
+	}
+
+	translated := make([]string, len(args))
+	for i, arg := range args {
+		translated[i] = arg
+		if len(arg) > 1 && strings.HasPrefix(arg, "/") {
+			translated[i] = translateWSLPath(distro, arg)
+		}
+	}
+
+	return translated
+}
+
+// translateWSLPath returns the Windows path of the Linux path p of the WSL
+// distribution distro.
+func translateWSLPath(distro, p string) string {
+	p = path.Clean(p)
+
+	parts := strings.SplitN(strings.TrimPrefix(p, "/"), "/", 3)
+	if len(parts) >= 2 && parts[0] == "mnt" && len(parts[1]) == 1 {
+		drive := strings.ToUpper(parts[1]) + ":\\"
+		if len(parts) == 2 {
+			return drive
+		}
+		return drive + strings.Replace(parts[2], "/", "\\", -1)
+	}
+
+	return `\\wsl$\` + distro + strings.Replace(p, "/", "\\", -1)
+}
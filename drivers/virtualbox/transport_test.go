@@ -0,0 +1,130 @@
+package virtualbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingTransport records the args of each command and runs the test
+// binary as a fake VBoxManage, which echoes them.
+type recordingTransport struct {
+	calls [][]string
+}
+
+func (t *recordingTransport) Command(args ...string) *exec.Cmd {
+	t.calls = append(t.calls, args)
+
+	cmd := exec.Command(os.Args[0], append([]string{"-test.run=TestHelperVBoxManage", "--"}, args...)...)
+	cmd.Env = append(os.Environ(), "GO_WANT_HELPER_VBOXMANAGE=1")
+
+	return cmd
+}
+
+func TestHelperVBoxManage(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_VBOXMANAGE") != "1" {
+		return
+	}
+
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+
+	if len(args) > 1 && args[1] == "hang" {
+		time.Sleep(time.Minute)
+	}
+
+	fmt.Print(strings.Join(args[1:], "|"))
+	os.Exit(0)
+}
+
+func TestVBoxCmdManagerUsesTransport(t *testing.T) {
+	transport := &recordingTransport{}
+	vbox := &VBoxCmdManager{Transport: transport}
+
+	stdout, err := vbox.vbmOut("sharedfolder", "add", "default", "--name", "hosthome", "--hostpath", "/home/docker user")
+
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"sharedfolder", "add", "default", "--name", "hosthome", "--hostpath", "/home/docker user"}}, transport.calls)
+	assert.Equal(t, "sharedfolder|add|default|--name|hosthome|--hostpath|/home/docker user", stdout)
+}
+
+func TestVBoxCmdManagerKillsCommandWhenContextIsDone(t *testing.T) {
+	vbox := &VBoxCmdManager{Transport: &recordingTransport{}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := contextBoundVBoxManager{&VBoxManagerRecorder{VBoxManager: vbox}, ctx}.vbmOut("hang")
+
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < 30*time.Second)
+}
+
+func TestTransportOfLooksThroughRecorder(t *testing.T) {
+	transport := &recordingTransport{}
+	vbox := &VBoxManagerRecorder{VBoxManager: &VBoxCmdManager{Transport: transport}}
+
+	assert.Equal(t, transport, transportOf(vbox))
+}
+
+func TestTranslateWSLPath(t *testing.T) {
+	var tests = []struct {
+		path, expected string
+	}{
+		{"/mnt/c/Users/docker/.docker/machine/machines/default/disk.vmdk", `C:\Users\docker\.docker\machine\machines\default\disk.vmdk`},
+		{"/mnt/d", `D:\`},
+		{"/home/docker/.docker/machine/cache/boot2docker.iso", `\\wsl$\Ubuntu\home\docker\.docker\machine\cache\boot2docker.iso`},
+		{"/mnt/wsl/share", `\\wsl$\Ubuntu\mnt\wsl\share`},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, translateWSLPath("Ubuntu", test.path))
+	}
+}
+
+func TestTranslateWSLArgs(t *testing.T) {
+	args := translateWSLArgs("Ubuntu", []string{"storageattach", "default", "--storagectl", "SATA", "--medium", "/mnt/c/vm/disk.vmdk"})
+	assert.Equal(t, []string{"storageattach", "default", "--storagectl", "SATA", "--medium", `C:\vm\disk.vmdk`}, args)
+
+	args = translateWSLArgs("Ubuntu", []string{"guestproperty", "set", "default", "/VirtualBox/GuestAdd/SharedFolders/MountDir", "/"})
+	assert.Equal(t, []string{"guestproperty", "set", "default", "/VirtualBox/GuestAdd/SharedFolders/MountDir", "/"}, args)
+}
+
+// probingTransport is a recordingTransport which counts its probes.
+type probingTransport struct {
+	recordingTransport
+	probes int
+	err    error
+}
+
+func (t *probingTransport) probe() error {
+	t.probes++
+	return t.err
+}
+
+func TestVBoxCmdManagerReportsMissingVBoxManage(t *testing.T) {
+	vbox := &VBoxCmdManager{Transport: localTransport{path: "/does/not/exist/VBoxManage"}}
+
+	_, err := listHostOnlyNetworks(vbox)
+
+	assert.Equal(t, ErrVBMNotFound, err)
+}
+
+func TestVBoxCmdManagerProbesOnce(t *testing.T) {
+	transport := &probingTransport{err: ErrVBMNotFound}
+	vbox := &VBoxCmdManager{Transport: transport}
+
+	assert.Equal(t, ErrVBMNotFound, vbox.vbm("list", "vms"))
+	assert.Equal(t, ErrVBMNotFound, vbox.vbm("list", "hostonlyifs"))
+	assert.Equal(t, 1, transport.probes)
+	assert.Empty(t, transport.calls)
+}
@@ -2,11 +2,14 @@ package virtualbox
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"os/exec"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/docker/machine/libmachine/log"
 )
@@ -16,9 +19,10 @@ var (
 	reEqualLine       = regexp.MustCompile(`(.+)=(.*)`)
 	reEqualQuoteLine  = regexp.MustCompile(`"(.+)"="(.*)"`)
 	reMachineNotFound = regexp.MustCompile(`Could not find a registered machine named '(.+)'`)
+	reVBoxVersion     = regexp.MustCompile(`^(\d+)\.(\d+)(?:\.(\d+))?`)
 
 	ErrMachineNotExist = errors.New("machine does not exist")
-	ErrVBMNotFound     = errors.New("VBoxManage not found. Make sure VirtualBox is installed and VBoxManage is in the path")
+	ErrVBMNotFound     = errors.New("VBoxManage not found. Make sure VirtualBox is installed and VBoxManage is in the PATH, or set VBOX_INSTALL_PATH to the VirtualBox installation directory")
 
 	vboxManageCmd = detectVBoxManageCmd()
 )
@@ -33,7 +37,33 @@ type VBoxManager interface {
 }
 
 // VBoxCmdManager communicates with VirtualBox through the commandline using `VBoxManage`.
-type VBoxCmdManager struct{}
+type VBoxCmdManager struct {
+	// Transport runs VBoxManage, the default one for this host if nil.
+	Transport VBoxTransport `json:"-"`
+
+	probeOnce sync.Once
+	probeErr  error
+}
+
+// probe checks once that the transport can find VBoxManage, so that a missing
+// VirtualBox is reported as such before any command runs.
+func (v *VBoxCmdManager) probe() error {
+	v.probeOnce.Do(func() {
+		if p, ok := v.transport().(vboxProber); ok {
+			v.probeErr = p.probe()
+		}
+	})
+
+	return v.probeErr
+}
+
+func (v *VBoxCmdManager) transport() VBoxTransport {
+	if v.Transport == nil {
+		return defaultVBoxTransport()
+	}
+
+	return v.Transport
+}
 
 func (v *VBoxCmdManager) vbm(args ...string) error {
 	_, _, err := v.vbmOutErr(args...)
@@ -46,7 +76,21 @@ func (v *VBoxCmdManager) vbmOut(args ...string) (string, error) {
 }
 
 func (v *VBoxCmdManager) vbmOutErr(args ...string) (string, string, error) {
-	cmd := exec.Command(vboxManageCmd, args...)
+	return v.vbmOutErrContext(context.Background(), args...)
+}
+
+func (v *VBoxCmdManager) vbmOutErrContext(ctx context.Context, args ...string) (string, string, error) {
+	if err := v.probe(); err != nil {
+		return "", "", err
+	}
+
+	return callWithVBoxHook(currentVBoxHook(), args, func(args ...string) (string, string, error) {
+		return v.run(ctx, args...)
+	})
+}
+
+func (v *VBoxCmdManager) run(ctx context.Context, args ...string) (string, string, error) {
+	cmd := commandWithContext(ctx, v.transport().Command(args...))
 	log.Debugf("COMMAND: %v %v", vboxManageCmd, strings.Join(args, " "))
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
@@ -62,6 +106,8 @@ func (v *VBoxCmdManager) vbmOutErr(args ...string) (string, string, error) {
 	if err != nil {
 		if ee, ok := err.(*exec.Error); ok && ee.Err == exec.ErrNotFound {
 			err = ErrVBMNotFound
+		} else if ctx.Err() != nil {
+			return stdout.String(), stderrStr, ctx.Err()
 		}
 	}
 
@@ -76,6 +122,83 @@ func (v *VBoxCmdManager) vbmOutErr(args ...string) (string, string, error) {
 	return stdout.String(), stderrStr, err
 }
 
+// commandWithContext returns cmd, killed once ctx is done.
+func commandWithContext(ctx context.Context, cmd *exec.Cmd) *exec.Cmd {
+	ctxCmd := exec.CommandContext(ctx, cmd.Path, cmd.Args[1:]...)
+	ctxCmd.Args = cmd.Args
+	ctxCmd.Env = cmd.Env
+	ctxCmd.Dir = cmd.Dir
+	ctxCmd.Stdin = cmd.Stdin
+
+	return ctxCmd
+}
+
+// contextVBoxManager is implemented by the VBoxManagers which can kill the
+// command they run once a context is done.
+type contextVBoxManager interface {
+	vbmOutErrContext(ctx context.Context, args ...string) (string, string, error)
+}
+
+// vbmOutErrContext runs the command with args through vbox, killing it once
+// ctx is done if vbox can. The other managers don't start it once ctx is
+// done.
+func vbmOutErrContext(ctx context.Context, vbox VBoxManager, args ...string) (string, string, error) {
+	if v, ok := vbox.(contextVBoxManager); ok {
+		return v.vbmOutErrContext(ctx, args...)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return "", "", err
+	}
+
+	return vbox.vbmOutErr(args...)
+}
+
+// contextBoundVBoxManager runs the commands of the VBoxManager it wraps with
+// its context, so that they are killed once it's done.
+type contextBoundVBoxManager struct {
+	VBoxManager
+	ctx context.Context
+}
+
+func (v contextBoundVBoxManager) vbm(args ...string) error {
+	_, _, err := v.vbmOutErr(args...)
+	return err
+}
+
+func (v contextBoundVBoxManager) vbmOut(args ...string) (string, error) {
+	stdout, _, err := v.vbmOutErr(args...)
+	return stdout, err
+}
+
+func (v contextBoundVBoxManager) vbmOutErr(args ...string) (string, string, error) {
+	return vbmOutErrContext(v.ctx, v.VBoxManager, args...)
+}
+
+// isContextError tells whether err is the error of a done context.
+func isContextError(err error) bool {
+	return err == context.Canceled || err == context.DeadlineExceeded
+}
+
+// VBoxManageError is a failed VBoxManage command, along with what it printed
+// on stderr, which usually tells why it failed, e.g. "VBoxNetAdpCtl: Error
+// while adding new interface".
+type VBoxManageError struct {
+	Args   []string
+	Stderr string
+	Err    error
+}
+
+func (e *VBoxManageError) Error() string {
+	msg := e.Err.Error()
+	stderr := strings.TrimSpace(e.Stderr)
+	if stderr == "" || strings.Contains(msg, stderr) {
+		return msg
+	}
+
+	return fmt.Sprintf("VBoxManage %s failed: %s\n%s", strings.Join(e.Args, " "), msg, stderr)
+}
+
 func checkVBoxManageVersion(version string) error {
 	if !strings.HasPrefix(version, "5.") && !strings.HasPrefix(version, "4.") {
 		return fmt.Errorf("We support Virtualbox starting with version 4. Your VirtualBox install is %q. Please upgrade at https://www.virtualbox.org", version)
@@ -83,3 +206,53 @@ func checkVBoxManageVersion(version string) error {
 
 	return nil
 }
+
+// vboxVersion is a VirtualBox version, without its revision.
+type vboxVersion struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+func (v vboxVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// lessThan reports whether v is an older version than other.
+func (v vboxVersion) lessThan(other vboxVersion) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	return v.Patch < other.Patch
+}
+
+// parseVBoxVersion parses the output of `VBoxManage --version`, such as
+// 5.0.8r103449 or 4.3.30_Ubuntur101610.
+func parseVBoxVersion(s string) (vboxVersion, error) {
+	res := reVBoxVersion.FindStringSubmatch(strings.TrimSpace(s))
+	if res == nil {
+		return vboxVersion{}, fmt.Errorf("unable to parse VirtualBox version %q", s)
+	}
+
+	v := vboxVersion{}
+	v.Major, _ = strconv.Atoi(res[1])
+	v.Minor, _ = strconv.Atoi(res[2])
+	if res[3] != "" {
+		v.Patch, _ = strconv.Atoi(res[3])
+	}
+
+	return v, nil
+}
+
+// getVBoxVersion returns the version of the VirtualBox installation.
+func getVBoxVersion(vbox VBoxManager) (vboxVersion, error) {
+	out, err := vbox.vbmOut("--version")
+	if err != nil {
+		return vboxVersion{}, err
+	}
+
+	return parseVBoxVersion(out)
+}
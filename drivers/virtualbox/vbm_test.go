@@ -1,6 +1,7 @@
 package virtualbox
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -40,3 +41,66 @@ func TestCheckVBoxManageVersionInvalid(t *testing.T) {
 		assert.EqualError(t, err, test.expectedError)
 	}
 }
+
+func TestParseVBoxVersion(t *testing.T) {
+	var tests = []struct {
+		version  string
+		expected vboxVersion
+	}{
+		{"5.0.8r103449", vboxVersion{5, 0, 8}},
+		{"4.3.30_Ubuntur101610\n", vboxVersion{4, 3, 30}},
+		{"5.1.0_BETA1r107281", vboxVersion{5, 1, 0}},
+		{"6.1", vboxVersion{6, 1, 0}},
+	}
+
+	for _, test := range tests {
+		v, err := parseVBoxVersion(test.version)
+
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, v)
+	}
+}
+
+func TestParseVBoxVersionInvalid(t *testing.T) {
+	for _, version := range []string{"", "5", "version 5.0.8", "WARNING: The vboxdrv kernel module is not loaded"} {
+		_, err := parseVBoxVersion(version)
+
+		assert.Error(t, err, version)
+	}
+}
+
+func TestVBoxVersionLessThan(t *testing.T) {
+	assert.True(t, vboxVersion{4, 3, 30}.lessThan(vboxVersion{5, 0, 0}))
+	assert.True(t, vboxVersion{5, 0, 8}.lessThan(vboxVersion{5, 1, 0}))
+	assert.True(t, vboxVersion{5, 0, 8}.lessThan(vboxVersion{5, 0, 10}))
+	assert.False(t, vboxVersion{5, 1, 0}.lessThan(vboxVersion{5, 1, 0}))
+	assert.False(t, vboxVersion{6, 0, 0}.lessThan(vboxVersion{5, 9, 9}))
+}
+
+func TestGetVBoxVersion(t *testing.T) {
+	vbox := &VBoxManagerMock{
+		args:   "--version",
+		stdOut: "5.0.8r103449\n",
+	}
+
+	v, err := getVBoxVersion(vbox)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "5.0.8", v.String())
+}
+
+func TestVBoxManageErrorDoesntRepeatStderr(t *testing.T) {
+	err := &VBoxManageError{
+		Args:   []string{"hostonlyif", "create"},
+		Stderr: "VBoxManage: error: VT-x is not available\n",
+		Err:    errors.New("VBoxManage hostonlyif create failed:\nVBoxManage: error: VT-x is not available\n"),
+	}
+
+	assert.Equal(t, "VBoxManage hostonlyif create failed:\nVBoxManage: error: VT-x is not available\n", err.Error())
+}
+
+func TestVBoxManageErrorWithoutStderr(t *testing.T) {
+	err := &VBoxManageError{Args: []string{"hostonlyif", "create"}, Err: errors.New("exit status 1")}
+
+	assert.EqualError(t, err, "exit status 1")
+}
@@ -4,6 +4,7 @@ import (
 	"archive/tar"
 	"bufio"
 	"bytes"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -33,8 +34,10 @@ const (
 	defaultBoot2DockerURL      = ""
 	defaultBoot2DockerImportVM = ""
 	defaultHostOnlyCIDR        = "192.168.99.1/24"
+	defaultHostOnlyAllocator   = "sequential"
 	defaultHostOnlyNictype     = "82540EM"
 	defaultHostOnlyPromiscMode = "deny"
+	defaultHostOnlyIndex       = -1
 	defaultDiskSize            = 20000
 )
 
@@ -47,21 +50,39 @@ var (
 type Driver struct {
 	VBoxManager
 	*drivers.BaseDriver
-	CPU                 int
-	Memory              int
-	DiskSize            int
-	Boot2DockerURL      string
-	Boot2DockerImportVM string
-	HostOnlyCIDR        string
-	HostOnlyNicType     string
-	HostOnlyPromiscMode string
-	NoShare             bool
+	CPU                       int
+	Memory                    int
+	DiskSize                  int
+	Boot2DockerURL            string
+	Boot2DockerImportVM       string
+	HostOnlyCIDR              string
+	HostOnlyCIDRPool          []string
+	HostOnlyCIDRFallback      bool
+	HostOnlyAllocator         string
+	HostOnlyNicType           string
+	HostOnlyPromiscMode       string
+	HostOnlyIndex             int
+	HostOnlyNetworkName       string
+	HostOnlyNetworkOwned      bool
+	HostOnlyRecreateUnhealthy bool
+	HostOnlyMTU               int
+	MACAddress                string
+	BridgedAdapter            string
+	NICs                      []string
+	PortForwards              []drivers.PortForward
+	NoShare                   bool
+	ShareFolders              []drivers.SharedFolder
+	DNSProxy                  bool
+	HostDNSResolver           bool
+	Autostart                 bool
+	GuestAdditions            bool
+	GuestAdditionsVersion     string
 }
 
 // NewDriver creates a new VirtualBox driver with default settings.
 func NewDriver(hostName, storePath string) *Driver {
 	return &Driver{
-		VBoxManager: &VBoxCmdManager{},
+		VBoxManager: &VBoxManagerRecorder{VBoxManager: &VBoxCmdManager{}},
 		BaseDriver: &drivers.BaseDriver{
 			MachineName: hostName,
 			StorePath:   storePath,
@@ -70,8 +91,10 @@ func NewDriver(hostName, storePath string) *Driver {
 		CPU:                 defaultCPU,
 		DiskSize:            defaultDiskSize,
 		HostOnlyCIDR:        defaultHostOnlyCIDR,
+		HostOnlyAllocator:   defaultHostOnlyAllocator,
 		HostOnlyNicType:     defaultHostOnlyNictype,
 		HostOnlyPromiscMode: defaultHostOnlyPromiscMode,
+		HostOnlyIndex:       defaultHostOnlyIndex,
 	}
 }
 
@@ -115,6 +138,23 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Value:  defaultHostOnlyCIDR,
 			EnvVar: "VIRTUALBOX_HOSTONLY_CIDR",
 		},
+		mcnflag.StringSliceFlag{
+			Name:   "virtualbox-hostonly-cidr-pool",
+			Usage:  "Host Only CIDRs to pick from for the machine instead of --virtualbox-hostonly-cidr, skipping those already used by a Host Only interface",
+			Value:  []string{},
+			EnvVar: "VIRTUALBOX_HOSTONLY_CIDR_POOL",
+		},
+		mcnflag.BoolFlag{
+			Name:   "virtualbox-hostonly-cidr-fallback",
+			Usage:  "Use the next free Host Only CIDR when the requested one overlaps a network of the host, instead of failing",
+			EnvVar: "VIRTUALBOX_HOSTONLY_CIDR_FALLBACK",
+		},
+		mcnflag.StringFlag{
+			Name:   "virtualbox-hostonly-allocator",
+			Usage:  "How to pick a CIDR of --virtualbox-hostonly-cidr-pool: sequential (the lowest free one) or random",
+			Value:  defaultHostOnlyAllocator,
+			EnvVar: "VIRTUALBOX_HOSTONLY_ALLOCATOR",
+		},
 		mcnflag.StringFlag{
 			Name:   "virtualbox-hostonly-nictype",
 			Usage:  "Specify the Host Only Network Adapter Type",
@@ -127,11 +167,86 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Value:  defaultHostOnlyPromiscMode,
 			EnvVar: "VIRTUALBOX_HOSTONLY_NIC_PROMISC",
 		},
+		mcnflag.IntFlag{
+			Name:   "virtualbox-hostonly-index",
+			Usage:  "Index of the Host Only interface to use or create, the number its name ends with (-1 to use any)",
+			Value:  defaultHostOnlyIndex,
+			EnvVar: "VIRTUALBOX_HOSTONLY_INDEX",
+		},
+		mcnflag.BoolFlag{
+			Name:   "virtualbox-hostonly-recreate-unhealthy",
+			Usage:  "Remove and create again a matching Host Only interface which is down or has no IP address",
+			EnvVar: "VIRTUALBOX_HOSTONLY_RECREATE_UNHEALTHY",
+		},
+		mcnflag.IntFlag{
+			Name:   "virtualbox-hostonly-mtu",
+			Usage:  "MTU of a created Host Only interface, between 576 and 9000 (default MTU if not set)",
+			EnvVar: "VIRTUALBOX_HOSTONLY_MTU",
+		},
+		mcnflag.StringFlag{
+			Name:   "virtualbox-mac-address",
+			Usage:  "MAC address of the Host Only Network Adapter, such as 08:00:27:12:34:56 (random if not set)",
+			Value:  "",
+			EnvVar: "VIRTUALBOX_MAC_ADDRESS",
+		},
+		mcnflag.StringFlag{
+			Name:   "virtualbox-bridged-adapter",
+			Usage:  "Host interface to bridge a third network adapter to, making the VM reachable from the LAN",
+			EnvVar: "VIRTUALBOX_BRIDGED_ADAPTER",
+		},
+		mcnflag.StringSliceFlag{
+			Name:   "virtualbox-nic",
+			Usage:  "Additional network adapter: nat, hostonly:<network> or bridged:<host interface>",
+			Value:  []string{},
+			EnvVar: "VIRTUALBOX_NIC",
+		},
+		mcnflag.StringSliceFlag{
+			Name:   "virtualbox-port-forward",
+			Usage:  "Forward a port of the host to the VM: [name=][host-ip:]host-port:guest-port[/protocol]",
+			Value:  []string{},
+			EnvVar: "VIRTUALBOX_PORT_FORWARD",
+		},
 		mcnflag.BoolFlag{
 			Name:   "virtualbox-no-share",
 			Usage:  "Disable the mount of your home directory",
 			EnvVar: "VIRTUALBOX_NO_SHARE",
 		},
+		mcnflag.StringSliceFlag{
+			Name:   "virtualbox-share-folder",
+			Usage:  "Share a folder of the host with the VM: host-path:guest-path[:option,...], the options being ro, rw, consistent, cached and delegated",
+			Value:  []string{},
+			EnvVar: "VIRTUALBOX_SHARE_FOLDER",
+		},
+		mcnflag.BoolFlag{
+			Name:   "virtualbox-dns-proxy",
+			Usage:  "Proxy all DNS requests to the host",
+			EnvVar: "VIRTUALBOX_DNS_PROXY",
+		},
+		mcnflag.BoolFlag{
+			Name:   "virtualbox-no-dns-proxy",
+			Usage:  "Disable proxying DNS requests to the host, overrides --virtualbox-dns-proxy",
+			EnvVar: "VIRTUALBOX_NO_DNS_PROXY",
+		},
+		mcnflag.BoolFlag{
+			Name:   "virtualbox-host-dns-resolver",
+			Usage:  "Use the host DNS resolver",
+			EnvVar: "VIRTUALBOX_HOST_DNS_RESOLVER",
+		},
+		mcnflag.BoolFlag{
+			Name:   "virtualbox-guest-additions",
+			Usage:  "Install the VirtualBox guest additions of the host version in the VM, and update them when VirtualBox is upgraded",
+			EnvVar: "VIRTUALBOX_GUEST_ADDITIONS",
+		},
+		mcnflag.BoolFlag{
+			Name:   "virtualbox-autostart",
+			Usage:  "Start the VM when the host boots, which requires the VirtualBox autostart service to be configured",
+			EnvVar: "VIRTUALBOX_AUTOSTART",
+		},
+		mcnflag.StringFlag{
+			Name:   "virtualbox-audit-log",
+			Usage:  "File to append every VBoxManage command run for the machine to",
+			EnvVar: "VIRTUALBOX_AUDIT_LOG",
+		},
 	}
 }
 
@@ -174,15 +289,66 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.SSHUser = "docker"
 	d.Boot2DockerImportVM = flags.String("virtualbox-import-boot2docker-vm")
 	d.HostOnlyCIDR = flags.String("virtualbox-hostonly-cidr")
+	d.HostOnlyCIDRPool = flags.StringSlice("virtualbox-hostonly-cidr-pool")
+	d.HostOnlyCIDRFallback = flags.Bool("virtualbox-hostonly-cidr-fallback")
+	d.HostOnlyAllocator = flags.String("virtualbox-hostonly-allocator")
 	d.HostOnlyNicType = flags.String("virtualbox-hostonly-nictype")
 	d.HostOnlyPromiscMode = flags.String("virtualbox-hostonly-nicpromisc")
+	d.HostOnlyIndex = flags.Int("virtualbox-hostonly-index")
+	d.HostOnlyRecreateUnhealthy = flags.Bool("virtualbox-hostonly-recreate-unhealthy")
+	d.HostOnlyMTU = flags.Int("virtualbox-hostonly-mtu")
+	d.MACAddress = flags.String("virtualbox-mac-address")
+	d.BridgedAdapter = flags.String("virtualbox-bridged-adapter")
+	d.NICs = flags.StringSlice("virtualbox-nic")
 	d.NoShare = flags.Bool("virtualbox-no-share")
+	d.DNSProxy = flags.Bool("virtualbox-dns-proxy") && !flags.Bool("virtualbox-no-dns-proxy")
+	d.HostDNSResolver = flags.Bool("virtualbox-host-dns-resolver")
+	d.Autostart = flags.Bool("virtualbox-autostart")
+	d.GuestAdditions = flags.Bool("virtualbox-guest-additions")
+
+	if recorder, ok := d.VBoxManager.(*VBoxManagerRecorder); ok {
+		recorder.LogPath = flags.String("virtualbox-audit-log")
+	}
+
+	if d.MACAddress != "" {
+		if _, err := vboxMACAddress(d.MACAddress); err != nil {
+			return err
+		}
+	}
+
+	if _, err := d.extraNICs(); err != nil {
+		return err
+	}
+
+	shareFolders, err := drivers.ParseSharedFolders(flags.StringSlice("virtualbox-share-folder"))
+	if err != nil {
+		return err
+	}
+	d.ShareFolders = shareFolders
+
+	portForwards, err := parsePortForwards(flags.StringSlice("virtualbox-port-forward"))
+	if err != nil {
+		return err
+	}
+	d.PortForwards = portForwards
+
+	if _, err := hostOnlyAllocatorByName(d.HostOnlyAllocator); err != nil {
+		return err
+	}
+
+	if _, err := parseHostOnlyCIDRPool(d.HostOnlyCIDRPool); err != nil {
+		return err
+	}
 
 	return nil
 }
 
 // PreCreateCheck checks that VBoxManage exists and works
 func (d *Driver) PreCreateCheck() error {
+	if err := validateHostOnlyMTU(d.HostOnlyMTU); err != nil {
+		return err
+	}
+
 	// Check that VBoxManage exists and works
 	version, err := d.vbmOut("--version")
 	if err != nil {
@@ -194,6 +360,19 @@ func (d *Driver) PreCreateCheck() error {
 		return err
 	}
 
+	nics, err := d.extraNICs()
+	if err != nil {
+		return err
+	}
+
+	for _, nic := range nics {
+		if nic.Type == "bridged" {
+			if _, err := bridgedInterface(nic.Attachment, d.VBoxManager); err != nil {
+				return err
+			}
+		}
+	}
+
 	if d.IsVTXDisabled() {
 		// Let's log a warning to warn the user. When the vm is started, logs
 		// will be checked for an error anyway.
@@ -202,6 +381,14 @@ func (d *Driver) PreCreateCheck() error {
 		log.Warn("This computer doesn't have VT-X/AMD-v enabled. Enabling it in the BIOS is mandatory.")
 	}
 
+	if d.Autostart {
+		if configured, err := autostartConfigured(d.VBoxManager); err != nil {
+			log.Debugf("Unable to check the VirtualBox autostart configuration: %s", err)
+		} else if !configured {
+			log.Warn("The VirtualBox autostart service isn't configured on this host, so the VM won't start when it boots. See the documentation of --virtualbox-autostart.")
+		}
+	}
+
 	return nil
 }
 
@@ -314,8 +501,8 @@ func (d *Driver) Create() error {
 		"--acpi", "on",
 		"--ioapic", "on",
 		"--rtcuseutc", "on",
-		"--natdnshostresolver1", "off",
-		"--natdnsproxy1", "off",
+		"--natdnshostresolver1", onOff(d.HostDNSResolver),
+		"--natdnsproxy1", onOff(d.DNSProxy),
 		"--cpuhotplug", "off",
 		"--pae", "on",
 		"--hpet", "on",
@@ -335,10 +522,24 @@ func (d *Driver) Create() error {
 		return err
 	}
 
+	if d.Autostart {
+		if err := d.vbm("modifyvm", d.MachineName, "--autostart-enabled", "on"); err != nil {
+			return err
+		}
+	}
+
 	if err := d.setupHostOnlyNetwork(d.MachineName); err != nil {
 		return err
 	}
 
+	if err := d.attachExtraNICs(); err != nil {
+		return err
+	}
+
+	if err := d.applyPortForwards(); err != nil {
+		return err
+	}
+
 	if err := d.vbm("storagectl", d.MachineName,
 		"--name", "SATA",
 		"--add", "sata",
@@ -372,41 +573,8 @@ func (d *Driver) Create() error {
 		return err
 	}
 
-	var shareName, shareDir string // TODO configurable at some point
-	switch runtime.GOOS {
-	case "windows":
-		shareName = "c/Users"
-		shareDir = "c:\\Users"
-	case "darwin":
-		shareName = "Users"
-		shareDir = "/Users"
-	case "linux":
-		shareName = "hosthome"
-		shareDir = "/home"
-	}
-
-	if shareDir != "" && !d.NoShare {
-		log.Debugf("setting up shareDir")
-		if _, err := os.Stat(shareDir); err != nil && !os.IsNotExist(err) {
-			return err
-		} else if !os.IsNotExist(err) {
-			if shareName == "" {
-				// parts of the VBox internal code are buggy with share names that start with "/"
-				shareName = strings.TrimLeft(shareDir, "/")
-				// TODO do some basic Windows -> MSYS path conversion
-				// ie, s!^([a-z]+):[/\\]+!\1/!; s!\\!/!g
-			}
-
-			// woo, shareDir exists!  let's carry on!
-			if err := d.vbm("sharedfolder", "add", d.MachineName, "--name", shareName, "--hostpath", shareDir, "--automount"); err != nil {
-				return err
-			}
-
-			// enable symlinks
-			if err := d.vbm("setextradata", d.MachineName, "VBoxInternal2/SharedFoldersEnableSymlinksCreate/"+shareName, "1"); err != nil {
-				return err
-			}
-		}
+	if err := d.addSharedFolders(); err != nil {
+		return err
 	}
 
 	log.Infof("Starting VirtualBox VM...")
@@ -437,9 +605,25 @@ func (d *Driver) Start() error {
 
 	if s == state.Stopped {
 		// check network to re-create if needed
-		if err := d.setupHostOnlyNetwork(d.MachineName); err != nil {
+		if err := d.reconcileHostOnlyNetwork(d.MachineName); err != nil {
 			return fmt.Errorf("Error setting up host only network on machine start: %s", err)
 		}
+
+		if err := d.vbm("modifyvm", d.MachineName,
+			"--natdnshostresolver1", onOff(d.HostDNSResolver),
+			"--natdnsproxy1", onOff(d.DNSProxy)); err != nil {
+			return fmt.Errorf("Error setting up NAT DNS on machine start: %s", err)
+		}
+
+		if err := d.vbm("modifyvm", d.MachineName, "--autostart-enabled", onOff(d.Autostart)); err != nil {
+			return fmt.Errorf("Error setting up autostart on machine start: %s", err)
+		}
+
+		if d.GuestAdditions {
+			if err := d.attachGuestAdditions(); err != nil {
+				return fmt.Errorf("Error inserting the guest additions ISO on machine start: %s", err)
+			}
+		}
 	}
 
 	switch s {
@@ -482,8 +666,17 @@ func (d *Driver) Start() error {
 	}
 
 	d.IPAddress, err = d.GetIP()
+	if err != nil {
+		return err
+	}
 
-	return err
+	if d.GuestAdditions {
+		if err := d.updateGuestAdditions(); err != nil {
+			log.Warnf("Unable to install the VirtualBox guest additions, shared folders and time sync may not work: %s", err)
+		}
+	}
+
+	return nil
 }
 
 func (d *Driver) Stop() error {
@@ -540,7 +733,19 @@ func (d *Driver) Remove() error {
 	}
 	// vbox will not release it's lock immediately after the stop
 	time.Sleep(1 * time.Second)
-	return d.vbm("unregistervm", "--delete", d.MachineName)
+	if err := d.vbm("unregistervm", "--delete", d.MachineName); err != nil {
+		return err
+	}
+
+	// Only clean up host-only networks this machine created, other
+	// machines might still rely on the ones it merely reused.
+	if d.HostOnlyNetworkOwned {
+		if err := removeHostOnlyNetworkIfUnused(d.HostOnlyNetworkName, d.MachineName, d.VBoxManager); err != nil {
+			log.Warnf("Unable to remove host-only network %s: %s", d.HostOnlyNetworkName, err)
+		}
+	}
+
+	return nil
 }
 
 func (d *Driver) Restart() error {
@@ -670,23 +875,136 @@ func (d *Driver) generateDiskImage(size int) error {
 		return err
 	}
 	raw := bytes.NewReader(buf.Bytes())
-	return createDiskImage(d.diskPath(), size, raw)
+	return createDiskImage(transportOf(d.VBoxManager), d.diskPath(), size, raw)
 }
 
-func (d *Driver) setupHostOnlyNetwork(machineName string) error {
-	hostOnlyCIDR := d.HostOnlyCIDR
-
+func (d *Driver) hostOnlyCIDR() string {
 	// This is to assist in migrating from version 0.2 to 0.3 format
 	// it should be removed in a later release
-	if hostOnlyCIDR == "" {
-		hostOnlyCIDR = defaultHostOnlyCIDR
+	if d.HostOnlyCIDR == "" {
+		return defaultHostOnlyCIDR
+	}
+
+	return d.HostOnlyCIDR
+}
+
+// GetSubnet returns the host-only network the machine is reached on.
+func (d *Driver) GetSubnet() (string, error) {
+	_, network, err := parseAndValidateCIDR(d.hostOnlyCIDR())
+	if err != nil {
+		return "", err
+	}
+
+	return network.String(), nil
+}
+
+// hostOnlyInterfaceIndex returns the index of the host-only interface the
+// machine must use, or nil if any interface will do.
+func (d *Driver) hostOnlyInterfaceIndex() *int {
+	if d.HostOnlyIndex < 0 {
+		return nil
+	}
+
+	index := d.HostOnlyIndex
+	return &index
+}
+
+// allocateHostOnlyCIDR picks the host-only CIDR of the machine among
+// HostOnlyCIDRPool with the configured allocator, and records it as its
+// HostOnlyCIDR. The subnets reserved by the machines being set up at the same
+// time are skipped, and the chosen one is reserved in turn. It does nothing
+// if no pool is configured.
+func (d *Driver) allocateHostOnlyCIDR() error {
+	if len(d.HostOnlyCIDRPool) == 0 {
+		return nil
+	}
+
+	pool, err := parseHostOnlyCIDRPool(d.HostOnlyCIDRPool)
+	if err != nil {
+		return err
+	}
+
+	allocator, err := hostOnlyAllocatorByName(d.HostOnlyAllocator)
+	if err != nil {
+		return err
 	}
 
-	ip, network, err := parseAndValidateCIDR(hostOnlyCIDR)
+	nets, err := listHostOnlyNetworks(d.VBoxManager)
 	if err != nil {
 		return err
 	}
 
+	cidr, err := d.hostOnlyReservations().allocate(func(reserved []net.IPNet) (net.IPNet, error) {
+		return allocator.Choose(nets, unreservedHostOnlySubnets(pool, reserved))
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("Allocated host-only CIDR %s", cidr.String())
+	d.HostOnlyCIDR = cidr.String()
+
+	return nil
+}
+
+// parseHostOnlyCIDRPool parses the CIDRs of --virtualbox-hostonly-cidr-pool,
+// which like --virtualbox-hostonly-cidr must be given with a host address.
+func parseHostOnlyCIDRPool(cidrs []string) ([]net.IPNet, error) {
+	pool := []net.IPNet{}
+	for _, cidr := range cidrs {
+		ip, network, err := parseAndValidateCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid host-only CIDR %q: %s", cidr, err)
+		}
+
+		pool = append(pool, net.IPNet{IP: ip, Mask: network.Mask})
+	}
+
+	return pool, nil
+}
+
+// hostOnlyReservations returns the registry of the host-only CIDRs being set
+// up by the machines of the store.
+func (d *Driver) hostOnlyReservations() *hostOnlyReservations {
+	return newHostOnlyReservations(d.StorePath, d.MachineName)
+}
+
+// reserveHostOnlyCIDR reserves the host-only CIDR of the machine until the
+// returned function is called, once its network exists.
+func (d *Driver) reserveHostOnlyCIDR(ip net.IP, network *net.IPNet) (func(), error) {
+	reservations := d.hostOnlyReservations()
+	if err := reservations.reserve(net.IPNet{IP: ip, Mask: network.Mask}); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		if err := reservations.release(); err != nil {
+			log.Warnf("Unable to release the host-only network reservation: %s", err)
+		}
+	}, nil
+}
+
+func (d *Driver) setupHostOnlyNetwork(machineName string) error {
+	if err := d.allocateHostOnlyCIDR(); err != nil {
+		return err
+	}
+
+	ip, network, err := parseAndValidateCIDR(d.hostOnlyCIDR())
+	if err != nil {
+		return err
+	}
+
+	ip, network, err = d.checkHostOnlyCIDR(ip, network)
+	if err != nil {
+		return err
+	}
+
+	release, err := d.reserveHostOnlyCIDR(ip, network)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	dhcpAddr, err := getRandomIPinSubnet(ip)
 	if err != nil {
 		return err
@@ -698,24 +1016,111 @@ func (d *Driver) setupHostOnlyNetwork(machineName string) error {
 
 	log.Debugf("using %s for dhcp address", dhcpAddr)
 
-	hostOnlyNetwork, err := getOrCreateHostOnlyNetwork(
-		ip,
-		network.Mask,
-		dhcpAddr,
-		lowerDHCPIP,
-		upperDHCPIP,
-		d.VBoxManager,
-	)
+	hostOnlyNetwork, created, err := getOrCreateHostOnlyNetwork(hostOnlyNetworkRequest{
+		HostIP:            ip,
+		Netmask:           network.Mask,
+		DHCPIP:            dhcpAddr,
+		DHCPLowerIP:       lowerDHCPIP,
+		DHCPUpperIP:       upperDHCPIP,
+		IfIndex:           d.hostOnlyInterfaceIndex(),
+		DHCP:              dhcpEnabled,
+		RecreateUnhealthy: d.HostOnlyRecreateUnhealthy,
+		MTU:               d.HostOnlyMTU,
+	}, d.VBoxManager)
 	if err != nil {
 		return err
 	}
 
-	return d.vbm("modifyvm", machineName,
+	return d.attachHostOnlyNetwork(machineName, hostOnlyNetwork, created)
+}
+
+// reconcileHostOnlyNetwork rebuilds the host-only network the machine was
+// last attached to if it no longer exists, and re-attaches it to the VM.
+// Machines which never recorded their network go through the regular setup.
+func (d *Driver) reconcileHostOnlyNetwork(machineName string) error {
+	if d.HostOnlyNetworkName == "" {
+		return d.setupHostOnlyNetwork(machineName)
+	}
+
+	ip, network, err := parseAndValidateCIDR(d.hostOnlyCIDR())
+	if err != nil {
+		return err
+	}
+
+	release, err := d.reserveHostOnlyCIDR(ip, network)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	hostOnlyNetwork, created, err := reconcileHostOnlyNetwork(d.HostOnlyNetworkName, ip, network.Mask, d.VBoxManager)
+	if err != nil {
+		return err
+	}
+
+	// The MTU of the interface isn't kept by every host, e.g. when it's
+	// brought up again after a reboot.
+	owned := created || (d.HostOnlyNetworkOwned && hostOnlyNetwork.NetworkName == d.HostOnlyNetworkName)
+	if err := applyHostOnlyMTU(hostOnlyNetwork, d.HostOnlyMTU, owned); err != nil {
+		log.Warnf("Unable to apply the MTU of the host-only network: %s", err)
+	}
+
+	return d.attachHostOnlyNetwork(machineName, hostOnlyNetwork, created)
+}
+
+// attachHostOnlyNetwork attaches the host-only network to the VM's second
+// adapter and records it. The machine owns the network, and is allowed to
+// clean it up on removal, only if it was the one that created it.
+func (d *Driver) attachHostOnlyNetwork(machineName string, hostOnlyNetwork *hostOnlyNetwork, created bool) error {
+	if created {
+		d.HostOnlyNetworkOwned = true
+	} else if hostOnlyNetwork.NetworkName != d.HostOnlyNetworkName {
+		d.HostOnlyNetworkOwned = false
+	}
+	d.HostOnlyNetworkName = hostOnlyNetwork.NetworkName
+
+	args := []string{"modifyvm", machineName,
 		"--nic2", "hostonly",
 		"--nictype2", d.HostOnlyNicType,
 		"--nicpromisc2", d.HostOnlyPromiscMode,
 		"--hostonlyadapter2", hostOnlyNetwork.Name,
-		"--cableconnected2", "on")
+		"--cableconnected2", "on",
+	}
+
+	// The MAC address is set on every attach, so that it survives the
+	// adapter being reconfigured.
+	if d.MACAddress != "" {
+		mac, err := vboxMACAddress(d.MACAddress)
+		if err != nil {
+			return err
+		}
+		args = append(args, "--macaddress2", mac)
+	}
+
+	return d.vbm(args...)
+}
+
+// vboxMACAddress validates a MAC address and returns it in the form VBoxManage
+// expects, twelve hexadecimal digits without separators.
+func vboxMACAddress(mac string) (string, error) {
+	hwAddr, err := net.ParseMAC(mac)
+	if err != nil || len(hwAddr) != 6 {
+		return "", fmt.Errorf("invalid MAC address %q: expected six bytes such as 08:00:27:12:34:56", mac)
+	}
+
+	if hwAddr[0]&1 == 1 {
+		return "", fmt.Errorf("invalid MAC address %q: multicast addresses can't be used by an adapter", mac)
+	}
+
+	return strings.ToUpper(hex.EncodeToString(hwAddr)), nil
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+
+	return "off"
 }
 
 func parseAndValidateCIDR(hostOnlyCIDR string) (net.IP, *net.IPNet, error) {
@@ -734,11 +1139,11 @@ func parseAndValidateCIDR(hostOnlyCIDR string) (net.IP, *net.IPNet, error) {
 
 // createDiskImage makes a disk image at dest with the given size in MB. If r is
 // not nil, it will be read as a raw disk image to convert from.
-func createDiskImage(dest string, size int, r io.Reader) error {
+func createDiskImage(transport VBoxTransport, dest string, size int, r io.Reader) error {
 	// Convert a raw image from stdin to the dest VMDK image.
 	sizeBytes := int64(size) << 20 // usually won't fit in 32-bit int (max 2GB)
 	// FIXME: why isn't this just using the vbm*() functions?
-	cmd := exec.Command(vboxManageCmd, "convertfromraw", "stdin", dest,
+	cmd := transport.Command("convertfromraw", "stdin", dest,
 		fmt.Sprintf("%d", sizeBytes), "--format", "VMDK")
 
 	if os.Getenv("MACHINE_DEBUG") != "" {
@@ -864,6 +1269,11 @@ func getRandomIPinSubnet(baseIP net.IP) (net.IP, error) {
 
 func detectVBoxManageCmdInPath() string {
 	cmd := "VBoxManage"
+	if p := os.Getenv("VBOX_INSTALL_PATH"); p != "" {
+		if path, err := exec.LookPath(filepath.Join(p, cmd)); err == nil {
+			return path
+		}
+	}
 	if path, err := exec.LookPath(cmd); err == nil {
 		return path
 	}
@@ -1,6 +1,8 @@
 package virtualbox
 
 import (
+	"os/exec"
+	"strconv"
 	"strings"
 	"syscall"
 
@@ -24,3 +26,24 @@ func (d *Driver) IsVTXDisabled() bool {
 func detectVBoxManageCmd() string {
 	return detectVBoxManageCmdInPath()
 }
+
+// hostInterfaceMTUCommand returns the command setting the MTU of the
+// interface of the host backing the host-only network.
+func hostInterfaceMTUCommand(n *hostOnlyNetwork, mtu int) ([]string, error) {
+	iface, err := hostInterfaceOf(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return asRoot([]string{"ifconfig", iface.Name, "mtu", strconv.Itoa(mtu)}), nil
+}
+
+// readHostRoutes reads the IPv4 routing table of the host.
+func readHostRoutes() ([]hostInterfaceNetwork, error) {
+	output, err := exec.Command("netstat", "-rn", "-f", "inet").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseNetstatRoutes(string(output)), nil
+}
@@ -2,7 +2,12 @@ package virtualbox
 
 import (
 	"bytes"
+	"fmt"
 	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
 
 	"github.com/docker/machine/libmachine/log"
 )
@@ -32,5 +37,49 @@ func (d *Driver) IsVTXDisabled() bool {
 }
 
 func detectVBoxManageCmd() string {
+	// In WSL, the VirtualBox of the Windows host is in the PATH as
+	// VBoxManage.exe.
+	if os.Getenv("WSL_DISTRO_NAME") != "" {
+		if _, err := exec.LookPath("VBoxManage"); err != nil {
+			if path, err := exec.LookPath("VBoxManage.exe"); err == nil {
+				return path
+			}
+		}
+	}
+
 	return detectVBoxManageCmdInPath()
 }
+
+// hostInterfaceMTUCommand returns the command setting the MTU of the
+// interface of the host backing the host-only network. In WSL, VirtualBox
+// runs on the Windows host, whose interfaces only Windows changes.
+func hostInterfaceMTUCommand(n *hostOnlyNetwork, mtu int) ([]string, error) {
+	if _, ok := defaultVBoxTransport().(wslTransport); ok {
+		return windowsInterfaceMTUCommand(n.Name, mtu), nil
+	}
+
+	iface, err := hostInterfaceOf(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return asRoot([]string{"ip", "link", "set", "dev", iface.Name, "mtu", strconv.Itoa(mtu)}), nil
+}
+
+// windowsInterfaceMTUCommand returns the PowerShell command setting the MTU
+// of the adapter of the Windows host VirtualBox names name, from WSL.
+func windowsInterfaceMTUCommand(name string, mtu int) []string {
+	adapter := "'" + strings.Replace(name, "'", "''", -1) + "'"
+	return []string{"powershell.exe", "-NoProfile", "-NonInteractive", "-Command",
+		fmt.Sprintf("Set-NetIPInterface -InterfaceIndex (Get-NetAdapter -InterfaceDescription %s).ifIndex -AddressFamily IPv4 -NlMtuBytes %d", adapter, mtu)}
+}
+
+// readHostRoutes reads the IPv4 routing table of the host.
+func readHostRoutes() ([]hostInterfaceNetwork, error) {
+	content, err := ioutil.ReadFile("/proc/net/route")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseProcNetRoute(string(content)), nil
+}
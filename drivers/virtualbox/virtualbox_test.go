@@ -54,6 +54,44 @@ func (v *VBoxManagerMock) vbmOutErr(args ...string) (string, string, error) {
 	return "", "", errors.New("Invalid args")
 }
 
+// VBoxManagerScript answers several commands with canned output and records
+// every command it was called with. Commands without canned output succeed
+// with no output. Commands in stdOutSeq get the next output of their sequence
+// on each call, the last one being repeated.
+type VBoxManagerScript struct {
+	stdOut    map[string]string
+	stdOutSeq map[string][]string
+	calls     []string
+}
+
+func (v *VBoxManagerScript) vbm(args ...string) error {
+	_, _, err := v.vbmOutErr(args...)
+	return err
+}
+
+func (v *VBoxManagerScript) vbmOut(args ...string) (string, error) {
+	stdout, _, err := v.vbmOutErr(args...)
+	return stdout, err
+}
+
+func (v *VBoxManagerScript) vbmOutErr(args ...string) (string, string, error) {
+	cmd := strings.Join(args, " ")
+
+	if seq := v.stdOutSeq[cmd]; len(seq) > 0 {
+		i := 0
+		for _, call := range v.calls {
+			if call == cmd && i < len(seq)-1 {
+				i++
+			}
+		}
+		v.calls = append(v.calls, cmd)
+		return seq[i], "", nil
+	}
+
+	v.calls = append(v.calls, cmd)
+	return v.stdOut[cmd], "", nil
+}
+
 func TestState(t *testing.T) {
 	var tests = []struct {
 		stdOut string
@@ -162,6 +200,16 @@ func TestGetIPErrors(t *testing.T) {
 	}
 }
 
+func TestGetSubnet(t *testing.T) {
+	driver := newTestDriver("default")
+	driver.HostOnlyCIDR = "192.168.56.1/21"
+
+	subnet, err := driver.GetSubnet()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.56.0/21", subnet)
+}
+
 func TestParseValidCIDR(t *testing.T) {
 	ip, network, err := parseAndValidateCIDR("192.168.100.1/24")
 
@@ -204,3 +252,122 @@ func TestSetConfigFromFlags(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Empty(t, checkFlags.InvalidFlags)
 }
+
+func TestSetConfigFromFlagsDNS(t *testing.T) {
+	var tests = []struct {
+		flags           map[string]interface{}
+		dnsProxy        bool
+		hostDNSResolver bool
+	}{
+		{map[string]interface{}{}, false, false},
+		{map[string]interface{}{"virtualbox-dns-proxy": true}, true, false},
+		{map[string]interface{}{"virtualbox-dns-proxy": true, "virtualbox-no-dns-proxy": true}, false, false},
+		{map[string]interface{}{"virtualbox-host-dns-resolver": true}, false, true},
+	}
+
+	for _, test := range tests {
+		driver := NewDriver("default", "path")
+
+		checkFlags := &drivers.CheckDriverOptions{
+			FlagsValues: test.flags,
+			CreateFlags: driver.GetCreateFlags(),
+		}
+
+		err := driver.SetConfigFromFlags(checkFlags)
+
+		assert.NoError(t, err)
+		assert.Equal(t, test.dnsProxy, driver.DNSProxy)
+		assert.Equal(t, test.hostDNSResolver, driver.HostDNSResolver)
+	}
+}
+
+func TestSetConfigFromFlagsMACAddress(t *testing.T) {
+	driver := NewDriver("default", "path")
+
+	checkFlags := &drivers.CheckDriverOptions{
+		FlagsValues: map[string]interface{}{"virtualbox-mac-address": "08:00:27:12:34:56"},
+		CreateFlags: driver.GetCreateFlags(),
+	}
+
+	err := driver.SetConfigFromFlags(checkFlags)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "08:00:27:12:34:56", driver.MACAddress)
+}
+
+func TestSetConfigFromFlagsInvalidMACAddress(t *testing.T) {
+	driver := NewDriver("default", "path")
+
+	checkFlags := &drivers.CheckDriverOptions{
+		FlagsValues: map[string]interface{}{"virtualbox-mac-address": "08:00:27:12:34"},
+		CreateFlags: driver.GetCreateFlags(),
+	}
+
+	err := driver.SetConfigFromFlags(checkFlags)
+
+	assert.Error(t, err)
+}
+
+func TestVBoxMACAddress(t *testing.T) {
+	var tests = []struct {
+		mac      string
+		expected string
+		valid    bool
+	}{
+		{"08:00:27:12:34:56", "080027123456", true},
+		{"0a-00-27-ab-cd-ef", "0A0027ABCDEF", true},
+		{"0800.2712.3456", "080027123456", true},
+		{"08:00:27:12:34", "", false},
+		{"01:00:5e:00:00:01", "", false},
+		{"not a mac", "", false},
+	}
+
+	for _, test := range tests {
+		mac, err := vboxMACAddress(test.mac)
+
+		assert.Equal(t, test.expected, mac, test.mac)
+		assert.Equal(t, test.valid, err == nil, test.mac)
+	}
+}
+
+func TestAttachHostOnlyNetworkSetsMACAddress(t *testing.T) {
+	vbox := &VBoxManagerScript{}
+	driver := NewDriver("default", "path")
+	driver.VBoxManager = vbox
+	driver.MACAddress = "08:00:27:12:34:56"
+
+	err := driver.attachHostOnlyNetwork("default", &hostOnlyNetwork{Name: "vboxnet0", NetworkName: "HostInterfaceNetworking-vboxnet0"}, true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"modifyvm default --nic2 hostonly --nictype2 82540EM --nicpromisc2 deny --hostonlyadapter2 vboxnet0 --cableconnected2 on --macaddress2 080027123456"}, vbox.calls)
+}
+
+func TestAutostartConfigured(t *testing.T) {
+	vbox := &VBoxManagerMock{
+		args:   "list systemproperties",
+		stdOut: "Default machine folder:          /home/docker/VirtualBox VMs\nAutostart database path:         /etc/vbox\n",
+	}
+
+	configured, err := autostartConfigured(vbox)
+
+	assert.NoError(t, err)
+	assert.True(t, configured)
+}
+
+func TestAutostartNotConfigured(t *testing.T) {
+	for _, stdOut := range []string{
+		"Autostart database path:         \n",
+		"Autostart database path:         <none>\n",
+		"Default machine folder:          /home/docker/VirtualBox VMs\n",
+	} {
+		vbox := &VBoxManagerMock{
+			args:   "list systemproperties",
+			stdOut: stdOut,
+		}
+
+		configured, err := autostartConfigured(vbox)
+
+		assert.NoError(t, err)
+		assert.False(t, configured)
+	}
+}
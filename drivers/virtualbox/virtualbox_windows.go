@@ -1,6 +1,7 @@
 package virtualbox
 
 import (
+	"strconv"
 	"strings"
 
 	"fmt"
@@ -89,3 +90,26 @@ func findVBoxInstallDirInRegistry() (string, error) {
 
 	return installDir, nil
 }
+
+// hostInterfaceMTUCommand returns the command setting the MTU of the
+// interface of the host backing the host-only network. It needs Machine to
+// run as an administrator.
+func hostInterfaceMTUCommand(n *hostOnlyNetwork, mtu int) ([]string, error) {
+	iface, err := hostInterfaceOf(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{"netsh", "interface", "ipv4", "set", "subinterface", iface.Name, "mtu=" + strconv.Itoa(mtu), "store=persistent"}, nil
+}
+
+// readHostRoutes reads the IPv4 routing table of the host.
+func readHostRoutes() ([]hostInterfaceNetwork, error) {
+	output, err := cmdOutput("powershell.exe", "-NoProfile", "-NonInteractive", "-Command",
+		"Get-NetRoute -AddressFamily IPv4 | ForEach-Object { $_.DestinationPrefix + ' ' + $_.InterfaceAlias }")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseWindowsRoutes(output), nil
+}
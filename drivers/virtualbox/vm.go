@@ -3,13 +3,19 @@ package virtualbox
 import (
 	"bufio"
 	"io"
+	"regexp"
 	"strconv"
 	"strings"
 )
 
+var (
+	reVMNameLine = regexp.MustCompile(`^"(.+)" \{(.*)\}$`)
+)
+
 type VM struct {
-	CPUs   int
-	Memory int
+	CPUs             int
+	Memory           int
+	HostOnlyAdapters []string
 }
 
 func getVMInfo(name string, vbox VBoxManager) (*VM, error) {
@@ -47,6 +53,10 @@ func parseVMInfo(r io.Reader) (*VM, error) {
 				return nil, err
 			}
 			vm.Memory = v
+		default:
+			if strings.HasPrefix(key, "hostonlyadapter") {
+				vm.HostOnlyAdapters = append(vm.HostOnlyAdapters, strings.Trim(val, `"`))
+			}
 		}
 	}
 	if err := s.Err(); err != nil {
@@ -54,3 +64,25 @@ func parseVMInfo(r io.Reader) (*VM, error) {
 	}
 	return vm, nil
 }
+
+// listVMs gets the names of all VMs registered with VirtualBox.
+func listVMs(vbox VBoxManager) ([]string, error) {
+	out, err := vbox.vbmOut("list", "vms")
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	s := bufio.NewScanner(strings.NewReader(out))
+	for s.Scan() {
+		res := reVMNameLine.FindStringSubmatch(s.Text())
+		if res == nil {
+			continue
+		}
+		names = append(names, res[1])
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
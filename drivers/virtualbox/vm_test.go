@@ -36,3 +36,15 @@ func TestVMInfo(t *testing.T) {
 		t.Fatalf("expected memory %d; received %d", vmMemory, vm.Memory)
 	}
 }
+
+func TestVMInfoHostOnlyAdapters(t *testing.T) {
+	r := strings.NewReader(testVMInfoText + "nic2=\"hostonly\"\nhostonlyadapter2=\"vboxnet0\"\n")
+	vm, err := parseVMInfo(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(vm.HostOnlyAdapters) != 1 || vm.HostOnlyAdapters[0] != "vboxnet0" {
+		t.Fatalf("expected host-only adapters [vboxnet0]; received %v", vm.HostOnlyAdapters)
+	}
+}
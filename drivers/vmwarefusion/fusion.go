@@ -47,6 +47,7 @@ type Driver struct {
 	ConfigDriveISO string
 	ConfigDriveURL string
 	NoShare        bool
+	ShareFolders   []drivers.SharedFolder
 }
 
 const (
@@ -108,6 +109,12 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Name:   "vmwarefusion-no-share",
 			Usage:  "Disable the mount of your home directory",
 		},
+		mcnflag.StringSliceFlag{
+			EnvVar: "FUSION_SHARE_FOLDER",
+			Name:   "vmwarefusion-share-folder",
+			Usage:  "Share a folder of the host with the VM: host-path:guest-path[:option,...], the options being ro, rw, consistent, cached and delegated",
+			Value:  []string{},
+		},
 	}
 }
 
@@ -158,6 +165,15 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.SSHPort = 22
 	d.NoShare = flags.Bool("vmwarefusion-no-share")
 
+	shareFolders, err := drivers.ParseSharedFolders(flags.StringSlice("vmwarefusion-share-folder"))
+	if err != nil {
+		return err
+	}
+	for i := range shareFolders {
+		shareFolders[i].Name = hgfsShareName(shareFolders[i].Name)
+	}
+	d.ShareFolders = shareFolders
+
 	// We support a maximum of 16 cpu to be consistent with Virtual Hardware 10
 	// specs.
 	if d.CPU < 1 {
@@ -343,25 +359,7 @@ func (d *Driver) Create() error {
 	// Enable Shared Folders
 	vmrun("-gu", B2DUser, "-gp", B2DPass, "enableSharedFolders", d.vmxPath())
 
-	var shareName, shareDir string // TODO configurable at some point
-	switch runtime.GOOS {
-	case "darwin":
-		shareName = "Users"
-		shareDir = "/Users"
-		// TODO "linux" and "windows"
-	}
-
-	if shareDir != "" && !d.NoShare {
-		if _, err := os.Stat(shareDir); err != nil && !os.IsNotExist(err) {
-			return err
-		} else if !os.IsNotExist(err) {
-			// add shared folder, create mountpoint and mount it.
-			vmrun("-gu", B2DUser, "-gp", B2DPass, "addSharedFolder", d.vmxPath(), shareName, shareDir)
-			command := "[ ! -d " + shareDir + " ]&& sudo mkdir " + shareDir + "; [ -f /usr/local/bin/vmhgfs-fuse ]&& sudo /usr/local/bin/vmhgfs-fuse -o allow_other .host:/" + shareName + " " + shareDir + " || sudo mount -t vmhgfs .host:/" + shareName + " " + shareDir
-			vmrun("-gu", B2DUser, "-gp", B2DPass, "runScriptInGuest", d.vmxPath(), "/bin/sh", command)
-		}
-	}
-	return nil
+	return d.addSharedFolders()
 }
 
 func (d *Driver) Start() error {
@@ -375,25 +373,12 @@ func (d *Driver) Start() error {
 	}
 
 	log.Debugf("Mounting Shared Folders...")
-	var shareName, shareDir string // TODO configurable at some point
-	switch runtime.GOOS {
-	case "darwin":
-		shareName = "Users"
-		shareDir = "/Users"
-		// TODO "linux" and "windows"
-	}
-
-	if shareDir != "" {
-		if _, err := os.Stat(shareDir); err != nil && !os.IsNotExist(err) {
-			return err
-		} else if !os.IsNotExist(err) {
-			// create mountpoint and mount shared folder
-			command := "[ ! -d " + shareDir + " ]&& sudo mkdir " + shareDir + "; [ -f /usr/local/bin/vmhgfs-fuse ]&& sudo /usr/local/bin/vmhgfs-fuse -o allow_other .host:/" + shareName + " " + shareDir + " || sudo mount -t vmhgfs .host:/" + shareName + " " + shareDir
-			vmrun("-gu", B2DUser, "-gp", B2DPass, "runScriptInGuest", d.vmxPath(), "/bin/sh", command)
-		}
+	folders, err := d.sharedFolders()
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return d.mountSharedFolders(folders)
 }
 
 func (d *Driver) Stop() error {
@@ -0,0 +1,111 @@
+package vmwarefusion
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// defaultSharedFolder returns the folder holding the home directories of
+// the host, shared unless --vmwarefusion-no-share is given, or nil on hosts
+// without one.
+func defaultSharedFolder(goos string) *drivers.SharedFolder {
+	switch goos {
+	case "darwin":
+		return &drivers.SharedFolder{Name: "Users", HostPath: "/Users", GuestPath: "/Users"}
+		// TODO "linux" and "windows"
+	}
+
+	return nil
+}
+
+// hgfsShareName turns the name of a shared folder into one HGFS accepts, as
+// a slash would be taken for a path within the share.
+func hgfsShareName(name string) string {
+	return strings.Replace(name, "/", "_", -1)
+}
+
+// sharedFolders returns the folders shared with the VM: the default one if
+// it exists on the host and no other folder is shared at its guest path,
+// then those given with --vmwarefusion-share-folder.
+func (d *Driver) sharedFolders() ([]drivers.SharedFolder, error) {
+	folders := []drivers.SharedFolder{}
+
+	if def := defaultSharedFolder(runtime.GOOS); def != nil && !d.NoShare && !d.sharesGuestPath(def.GuestPath) {
+		if _, err := os.Stat(def.HostPath); err == nil {
+			folders = append(folders, *def)
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	return append(folders, d.ShareFolders...), nil
+}
+
+func (d *Driver) sharesGuestPath(guestPath string) bool {
+	for _, folder := range d.ShareFolders {
+		if folder.GuestPath == guestPath {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d *Driver) SharedFolders() ([]drivers.SharedFolder, error) {
+	return d.sharedFolders()
+}
+
+// hgfsMountCommand returns the command mounting a shared folder in the
+// guest, with vmhgfs-fuse if the VMware tools provide it. The cached and
+// delegated modes let FUSE keep file contents in the page cache.
+func hgfsMountCommand(folder drivers.SharedFolder) string {
+	fuseOptions := "allow_other"
+	mountOptions := ""
+	if folder.ReadOnly {
+		fuseOptions += ",ro"
+		mountOptions = "-o ro "
+	}
+	if folder.Consistency == drivers.ConsistencyCached || folder.Consistency == drivers.ConsistencyDelegated {
+		fuseOptions += ",kernel_cache"
+	}
+
+	return fmt.Sprintf("[ ! -d %[1]s ]&& sudo mkdir -p %[1]s; [ -f /usr/local/bin/vmhgfs-fuse ]&& sudo /usr/local/bin/vmhgfs-fuse -o %[2]s .host:/%[3]s %[1]s || sudo mount -t vmhgfs %[4]s.host:/%[3]s %[1]s",
+		folder.GuestPath, fuseOptions, folder.Name, mountOptions)
+}
+
+// addSharedFolders adds the shared folders to the VM and mounts them.
+func (d *Driver) addSharedFolders() error {
+	folders, err := d.sharedFolders()
+	if err != nil {
+		return err
+	}
+
+	for _, folder := range folders {
+		if _, err := os.Stat(folder.HostPath); err != nil {
+			return fmt.Errorf("unable to share %s with the VM: %s", folder.HostPath, err)
+		}
+
+		log.Debugf("Sharing %s at %s", folder.HostPath, folder.GuestPath)
+		vmrun("-gu", B2DUser, "-gp", B2DPass, "addSharedFolder", d.vmxPath(), folder.Name, folder.HostPath)
+		if folder.ReadOnly {
+			vmrun("-gu", B2DUser, "-gp", B2DPass, "setSharedFolderState", d.vmxPath(), folder.Name, folder.HostPath, "readonly")
+		}
+	}
+
+	return d.mountSharedFolders(folders)
+}
+
+// mountSharedFolders mounts the shared folders in the guest, which doesn't
+// remember them across reboots.
+func (d *Driver) mountSharedFolders(folders []drivers.SharedFolder) error {
+	for _, folder := range folders {
+		vmrun("-gu", B2DUser, "-gp", B2DPass, "runScriptInGuest", d.vmxPath(), "/bin/sh", hgfsMountCommand(folder))
+	}
+
+	return nil
+}
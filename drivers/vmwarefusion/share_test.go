@@ -0,0 +1,24 @@
+package vmwarefusion
+
+import (
+	"testing"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHGFSMountCommand(t *testing.T) {
+	command := hgfsMountCommand(drivers.SharedFolder{Name: "Users", HostPath: "/Users", GuestPath: "/Users"})
+
+	assert.Equal(t, "[ ! -d /Users ]&& sudo mkdir -p /Users; [ -f /usr/local/bin/vmhgfs-fuse ]&& sudo /usr/local/bin/vmhgfs-fuse -o allow_other .host:/Users /Users || sudo mount -t vmhgfs .host:/Users /Users", command)
+}
+
+func TestHGFSMountCommandOptions(t *testing.T) {
+	command := hgfsMountCommand(drivers.SharedFolder{Name: "src_app", HostPath: "/Users/me/app", GuestPath: "/src/app", ReadOnly: true, Consistency: drivers.ConsistencyCached})
+
+	assert.Equal(t, "[ ! -d /src/app ]&& sudo mkdir -p /src/app; [ -f /usr/local/bin/vmhgfs-fuse ]&& sudo /usr/local/bin/vmhgfs-fuse -o allow_other,ro,kernel_cache .host:/src_app /src/app || sudo mount -t vmhgfs -o ro .host:/src_app /src/app", command)
+}
+
+func TestHGFSShareName(t *testing.T) {
+	assert.Equal(t, "src_app", hgfsShareName("src/app"))
+}
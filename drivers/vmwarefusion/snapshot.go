@@ -0,0 +1,77 @@
+package vmwarefusion
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// Capabilities adds snapshots and shared folders to the default
+// capabilities.
+func (d *Driver) Capabilities() []drivers.Capability {
+	return []drivers.Capability{
+		drivers.CapabilityStart,
+		drivers.CapabilityStop,
+		drivers.CapabilityKill,
+		drivers.CapabilitySnapshot,
+		drivers.CapabilitySharedFolders,
+	}
+}
+
+func (d *Driver) CreateSnapshot(name string) error {
+	log.Infof("Taking snapshot %s of %s...", name, d.MachineName)
+	return vmrunSnapshot("snapshot", d.vmxPath(), name)
+}
+
+func (d *Driver) ListSnapshots() ([]drivers.Snapshot, error) {
+	stdout, stderr, err := vmrun("listSnapshots", d.vmxPath())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", err, strings.TrimSpace(stdout+stderr))
+	}
+
+	return parseSnapshots(stdout), nil
+}
+
+// RestoreSnapshot reverts the machine to the snapshot name. vmrun doesn't
+// tell which snapshot is the current one, so ListSnapshots never marks one.
+func (d *Driver) RestoreSnapshot(name string) error {
+	log.Infof("Restoring snapshot %s of %s...", name, d.MachineName)
+	return vmrunSnapshot("revertToSnapshot", d.vmxPath(), name)
+}
+
+func (d *Driver) DeleteSnapshot(name string) error {
+	log.Infof("Deleting snapshot %s of %s...", name, d.MachineName)
+	return vmrunSnapshot("deleteSnapshot", d.vmxPath(), name)
+}
+
+// vmrunSnapshot runs a snapshot command of vmrun, which reports its errors
+// on stdout.
+func vmrunSnapshot(args ...string) error {
+	stdout, stderr, err := vmrun(args...)
+	if err != nil {
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(stdout+stderr))
+	}
+
+	return nil
+}
+
+// parseSnapshots reads the output of `vmrun listSnapshots`, a count followed
+// by the name of each snapshot.
+func parseSnapshots(out string) []drivers.Snapshot {
+	snapshots := []drivers.Snapshot{}
+
+	s := bufio.NewScanner(strings.NewReader(out))
+	for s.Scan() {
+		name := strings.TrimSpace(s.Text())
+		if name == "" || strings.HasPrefix(name, "Total snapshots:") {
+			continue
+		}
+
+		snapshots = append(snapshots, drivers.Snapshot{Name: name, ID: name})
+	}
+
+	return snapshots
+}
@@ -0,0 +1,21 @@
+package vmwarefusion
+
+import (
+	"testing"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSnapshots(t *testing.T) {
+	snapshots := parseSnapshots("Total snapshots: 2\nclean\nwith redis\n")
+
+	assert.Equal(t, []drivers.Snapshot{
+		{Name: "clean", ID: "clean"},
+		{Name: "with redis", ID: "with redis"},
+	}, snapshots)
+}
+
+func TestParseSnapshotsWithoutSnapshots(t *testing.T) {
+	assert.Empty(t, parseSnapshots("Total snapshots: 0\n"))
+}
@@ -1,5 +1,7 @@
 package auth
 
+import "time"
+
 type Options struct {
 	CertDir              string
 	CaCertPath           string
@@ -12,7 +14,26 @@ type Options struct {
 	ServerKeyRemotePath  string
 	ClientCertPath       string
 	ServerCertSANs       []string
+	// ServerKeyType, ServerKeyBits and ServerCertValidity are the key type,
+	// RSA key size and validity of the server certificate, the defaults of
+	// the cert package if empty.
+	ServerKeyType      string        `json:",omitempty"`
+	ServerKeyBits      int           `json:",omitempty"`
+	ServerCertValidity time.Duration `json:",omitempty"`
+	// Signer is the external CA signing the certificates of the machine, nil
+	// if the CA key is kept locally.
+	Signer *Signer `json:",omitempty"`
 	// StorePath is left in for historical reasons, but not really meant to
 	// be used directly.
 	StorePath string
 }
+
+// Signer is an external CA signing the certificates, by running a command or
+// through the PKI secrets engine of Vault. The Vault token isn't saved, it's
+// given on the command line each time.
+type Signer struct {
+	Command      string `json:",omitempty"`
+	VaultAddr    string `json:",omitempty"`
+	VaultPKIPath string `json:",omitempty"`
+	VaultRole    string `json:",omitempty"`
+}
@@ -25,6 +25,11 @@ func BootstrapCertificates(authOptions *auth.Options) error {
 
 	bits := 2048
 
+	generator, err := generatorFor(authOptions)
+	if err != nil {
+		return err
+	}
+
 	if _, err := os.Stat(certDir); err != nil {
 		if os.IsNotExist(err) {
 			if err := os.MkdirAll(certDir, 0700); err != nil {
@@ -43,7 +48,7 @@ func BootstrapCertificates(authOptions *auth.Options) error {
 			return errors.New("The CA key already exists.  Please remove it or specify a different key/cert.")
 		}
 
-		if err := GenerateCACertificate(caCertPath, caPrivateKeyPath, caOrg, bits); err != nil {
+		if err := generator.GenerateCACertificate(caCertPath, caPrivateKeyPath, caOrg, bits); err != nil {
 			return fmt.Errorf("Generating CA certificate failed: %s", err)
 		}
 	}
@@ -66,10 +71,16 @@ func BootstrapCertificates(authOptions *auth.Options) error {
 			return errors.New("The client key already exists.  Please remove it or specify a different key/cert.")
 		}
 
-		if err := GenerateCert([]string{""}, clientCertPath, clientKeyPath, caCertPath, caPrivateKeyPath, org, bits); err != nil {
+		if err := generator.GenerateCert([]string{""}, clientCertPath, clientKeyPath, caCertPath, caPrivateKeyPath, org, bits); err != nil {
 			return fmt.Errorf("Generating client certificate failed: %s", err)
 		}
 	}
 
+	// Existing certificates are kept, they must be those of the external CA
+	// signing the certificates of the machine.
+	if external, ok := generator.(*ExternalCertGenerator); ok {
+		return CheckSignerCA(external.Signer, caCertPath, clientCertPath)
+	}
+
 	return nil
 }
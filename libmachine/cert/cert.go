@@ -1,12 +1,17 @@
 package cert
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"fmt"
 	"io/ioutil"
 	"math/big"
 	"net"
@@ -21,9 +26,32 @@ import (
 
 var defaultGenerator = NewX509CertGenerator()
 
+// The types of the keys of the generated certificates.
+const (
+	KeyTypeRSA   = "rsa"
+	KeyTypeECDSA = "ecdsa"
+)
+
+// DefaultValidity is how long the generated certificates are valid by
+// default.
+const DefaultValidity = 24 * 1080 * time.Hour
+
+// Options are the parameters of a generated certificate and its key.
+type Options struct {
+	Org string
+	// KeyType is KeyTypeRSA, the default, or KeyTypeECDSA for a P-256 key.
+	KeyType string
+	// Bits is the size of an RSA key.
+	Bits int
+	// Validity is how long the certificate is valid, DefaultValidity if
+	// zero.
+	Validity time.Duration
+}
+
 type Generator interface {
 	GenerateCACertificate(certFile, keyFile, org string, bits int) error
 	GenerateCert(hosts []string, certFile, keyFile, caFile, caKeyFile, org string, bits int) error
+	GenerateCertWithOptions(hosts []string, certFile, keyFile, caFile, caKeyFile string, opts Options) error
 	ValidateCertificate(addr string, authOptions *auth.Options) (bool, error)
 }
 
@@ -41,6 +69,31 @@ func GenerateCert(hosts []string, certFile, keyFile, caFile, caKeyFile, org stri
 	return defaultGenerator.GenerateCert(hosts, certFile, keyFile, caFile, caKeyFile, org, bits)
 }
 
+// GenerateCertWithOptions is GenerateCert with the key type and validity of
+// opts.
+func GenerateCertWithOptions(hosts []string, certFile, keyFile, caFile, caKeyFile string, opts Options) error {
+	return defaultGenerator.GenerateCertWithOptions(hosts, certFile, keyFile, caFile, caKeyFile, opts)
+}
+
+// ValidateOptions checks the key type of opts is known.
+func ValidateOptions(opts Options) error {
+	switch opts.KeyType {
+	case "", KeyTypeRSA:
+		if opts.Bits < 0 || (opts.Bits > 0 && opts.Bits < 2048) {
+			return fmt.Errorf("RSA keys must be at least 2048 bits, not %d", opts.Bits)
+		}
+	case KeyTypeECDSA:
+	default:
+		return fmt.Errorf("Key type must be %s or %s, not %q", KeyTypeRSA, KeyTypeECDSA, opts.KeyType)
+	}
+
+	if opts.Validity < 0 {
+		return fmt.Errorf("Certificate validity must be positive, not %s", opts.Validity)
+	}
+
+	return nil
+}
+
 func ValidateCertificate(addr string, authOptions *auth.Options) (bool, error) {
 	return defaultGenerator.ValidateCertificate(addr, authOptions)
 }
@@ -49,6 +102,62 @@ func SetCertGenerator(cg Generator) {
 	defaultGenerator = cg
 }
 
+// ReadPEMCertificates reads the file at path and checks that it holds one or
+// more PEM encoded X.509 certificates and nothing else.
+func ReadPEMCertificates(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rest := data
+	count := 0
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("%s holds a %s, not a certificate", path, block.Type)
+		}
+
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return nil, fmt.Errorf("%s holds an invalid certificate: %s", path, err)
+		}
+
+		count++
+	}
+
+	if count == 0 || len(bytes.TrimSpace(rest)) > 0 {
+		return nil, fmt.Errorf("%s is not a PEM encoded certificate", path)
+	}
+
+	return data, nil
+}
+
+// CertificateExpiry returns when the first certificate of the PEM encoded
+// file at path expires.
+func CertificateExpiry(path string) (time.Time, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return time.Time{}, fmt.Errorf("%s is not a PEM encoded certificate", path)
+	}
+
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s holds an invalid certificate: %s", path, err)
+	}
+
+	return certificate.NotAfter, nil
+}
+
 func (xcg *X509CertGenerator) getTLSConfig(caCert, cert, key []byte, allowInsecure bool) (*tls.Config, error) {
 	// TLS config
 	var tlsConfig tls.Config
@@ -70,12 +179,16 @@ func (xcg *X509CertGenerator) getTLSConfig(caCert, cert, key []byte, allowInsecu
 	return &tlsConfig, nil
 }
 
-func (xcg *X509CertGenerator) newCertificate(org string) (*x509.Certificate, error) {
+func (xcg *X509CertGenerator) newCertificate(org string, validity time.Duration) (*x509.Certificate, error) {
+	if validity == 0 {
+		validity = DefaultValidity
+	}
+
 	now := time.Now()
 	// need to set notBefore slightly in the past to account for time
 	// skew in the VMs otherwise the certs sometimes are not yet valid
 	notBefore := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute()-5, 0, 0, time.Local)
-	notAfter := notBefore.Add(time.Hour * 24 * 1080)
+	notAfter := notBefore.Add(validity)
 
 	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
 	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
@@ -101,7 +214,7 @@ func (xcg *X509CertGenerator) newCertificate(org string) (*x509.Certificate, err
 // and bit size and stores the resulting certificate and key file
 // in the arguments.
 func (xcg *X509CertGenerator) GenerateCACertificate(certFile, keyFile, org string, bits int) error {
-	template, err := xcg.newCertificate(org)
+	template, err := xcg.newCertificate(org, 0)
 	if err != nil {
 		return err
 	}
@@ -146,7 +259,46 @@ func (xcg *X509CertGenerator) GenerateCACertificate(certFile, keyFile, org strin
 // file and key provided.  The provided host names are set to the
 // appropriate certificate fields.
 func (xcg *X509CertGenerator) GenerateCert(hosts []string, certFile, keyFile, caFile, caKeyFile, org string, bits int) error {
-	template, err := xcg.newCertificate(org)
+	return xcg.GenerateCertWithOptions(hosts, certFile, keyFile, caFile, caKeyFile, Options{Org: org, Bits: bits})
+}
+
+// generateKey generates a key of the type and size of opts, returning it
+// with its PEM block.
+func generateKey(opts Options) (crypto.Signer, *pem.Block, error) {
+	switch opts.KeyType {
+	case "", KeyTypeRSA:
+		bits := opts.Bits
+		if bits == 0 {
+			bits = 2048
+		}
+
+		priv, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return priv, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)}, nil
+	case KeyTypeECDSA:
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		der, err := x509.MarshalECPrivateKey(priv)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return priv, &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}, nil
+	}
+
+	return nil, nil, fmt.Errorf("unknown key type %q", opts.KeyType)
+}
+
+// GenerateCertWithOptions is GenerateCert with the key type and validity of
+// opts.
+func (xcg *X509CertGenerator) GenerateCertWithOptions(hosts []string, certFile, keyFile, caFile, caKeyFile string, opts Options) error {
+	template, err := xcg.newCertificate(opts.Org, opts.Validity)
 	if err != nil {
 		return err
 	}
@@ -170,7 +322,7 @@ func (xcg *X509CertGenerator) GenerateCert(hosts []string, certFile, keyFile, ca
 		return err
 	}
 
-	priv, err := rsa.GenerateKey(rand.Reader, bits)
+	priv, keyBlock, err := generateKey(opts)
 	if err != nil {
 		return err
 	}
@@ -180,7 +332,7 @@ func (xcg *X509CertGenerator) GenerateCert(hosts []string, certFile, keyFile, ca
 		return err
 	}
 
-	derBytes, err := x509.CreateCertificate(rand.Reader, template, x509Cert, &priv.PublicKey, tlsCert.PrivateKey)
+	derBytes, err := x509.CreateCertificate(rand.Reader, template, x509Cert, priv.Public(), tlsCert.PrivateKey)
 	if err != nil {
 		return err
 	}
@@ -198,7 +350,7 @@ func (xcg *X509CertGenerator) GenerateCert(hosts []string, certFile, keyFile, ca
 		return err
 	}
 
-	pem.Encode(keyOut, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)})
+	pem.Encode(keyOut, keyBlock)
 	keyOut.Close()
 
 	return nil
@@ -1,10 +1,13 @@
 package cert
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestGenerateCACertificate(t *testing.T) {
@@ -68,3 +71,127 @@ func TestGenerateCert(t *testing.T) {
 		t.Fatalf("key not created at %s", keyPath)
 	}
 }
+
+func TestReadPEMCertificates(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// cleanup
+	defer os.RemoveAll(tmpDir)
+
+	caCertPath := filepath.Join(tmpDir, "ca.pem")
+	caKeyPath := filepath.Join(tmpDir, "key.pem")
+	if err := GenerateCACertificate(caCertPath, caKeyPath, "test-org", 2048); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadPEMCertificates(caCertPath); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadPEMCertificates(caKeyPath); err == nil {
+		t.Fatal("expected a private key to be rejected")
+	}
+
+	garbagePath := filepath.Join(tmpDir, "garbage.pem")
+	if err := ioutil.WriteFile(garbagePath, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadPEMCertificates(garbagePath); err == nil {
+		t.Fatal("expected a file without certificates to be rejected")
+	}
+}
+
+func TestGenerateCertWithOptions(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// cleanup
+	defer os.RemoveAll(tmpDir)
+
+	caCertPath := filepath.Join(tmpDir, "ca.pem")
+	caKeyPath := filepath.Join(tmpDir, "key.pem")
+	certPath := filepath.Join(tmpDir, "cert.pem")
+	keyPath := filepath.Join(tmpDir, "cert-key.pem")
+	if err := GenerateCACertificate(caCertPath, caKeyPath, "test-org", 2048); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := Options{Org: "test-org", KeyType: KeyTypeECDSA, Validity: 48 * time.Hour}
+	if err := GenerateCertWithOptions([]string{"bastion.example.com", "10.0.0.1"}, certPath, keyPath, caCertPath, caKeyPath, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	keyPair, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x509Cert, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if x509Cert.PublicKeyAlgorithm != x509.ECDSA {
+		t.Fatalf("expected an ECDSA key, got %s", x509Cert.PublicKeyAlgorithm)
+	}
+
+	if validity := x509Cert.NotAfter.Sub(x509Cert.NotBefore); validity != 48*time.Hour {
+		t.Fatalf("expected the certificate to be valid 48h, got %s", validity)
+	}
+
+	if len(x509Cert.DNSNames) != 1 || x509Cert.DNSNames[0] != "bastion.example.com" || len(x509Cert.IPAddresses) != 1 {
+		t.Fatalf("unexpected SANs %v %v", x509Cert.DNSNames, x509Cert.IPAddresses)
+	}
+}
+
+func TestValidateOptions(t *testing.T) {
+	for _, opts := range []Options{{}, {KeyType: KeyTypeRSA, Bits: 4096}, {KeyType: KeyTypeECDSA, Validity: time.Hour}} {
+		if err := ValidateOptions(opts); err != nil {
+			t.Fatalf("%v: %s", opts, err)
+		}
+	}
+
+	for _, opts := range []Options{{KeyType: "dsa"}, {KeyType: KeyTypeRSA, Bits: 1024}, {Validity: -time.Hour}} {
+		if err := ValidateOptions(opts); err == nil {
+			t.Fatalf("expected an error for %v", opts)
+		}
+	}
+}
+
+func TestCertificateExpiry(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	caCertPath := filepath.Join(tmpDir, "ca.pem")
+	caKeyPath := filepath.Join(tmpDir, "key.pem")
+	certPath := filepath.Join(tmpDir, "cert.pem")
+	keyPath := filepath.Join(tmpDir, "cert-key.pem")
+	if err := GenerateCACertificate(caCertPath, caKeyPath, "test-org", 2048); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := Options{Org: "test-org", KeyType: KeyTypeECDSA, Validity: 48 * time.Hour}
+	if err := GenerateCertWithOptions([]string{"localhost"}, certPath, keyPath, caCertPath, caKeyPath, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	expiry, err := CertificateExpiry(certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if left := expiry.Sub(time.Now()); left < 47*time.Hour || left > 48*time.Hour {
+		t.Fatalf("expected the certificate to expire in 48h, it expires in %s", left)
+	}
+
+	if _, err := CertificateExpiry(keyPath); err == nil {
+		t.Fatal("expected an error for a key")
+	}
+}
@@ -0,0 +1,329 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/machine/libmachine/auth"
+)
+
+// CSRSigner signs certificate signing requests with a CA whose key isn't
+// kept by machine.
+type CSRSigner interface {
+	// CACertificate returns the PEM encoded certificate of the CA.
+	CACertificate() ([]byte, error)
+
+	// SignCSR signs the PEM encoded request for the hosts with the validity
+	// of opts, returning the PEM encoded certificate. The hosts are empty for
+	// a client certificate.
+	SignCSR(csr []byte, hosts []string, opts Options) ([]byte, error)
+}
+
+// ExternalCertGenerator generates the keys locally and has their
+// certificates signed by an external CA, so that no CA key is needed.
+type ExternalCertGenerator struct {
+	X509CertGenerator
+	Signer CSRSigner
+}
+
+func NewExternalCertGenerator(signer CSRSigner) Generator {
+	return &ExternalCertGenerator{Signer: signer}
+}
+
+// GenerateCACertificate saves the certificate of the external CA, no key is
+// generated.
+func (ecg *ExternalCertGenerator) GenerateCACertificate(certFile, keyFile, org string, bits int) error {
+	caCert, err := ecg.Signer.CACertificate()
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(certFile, caCert, 0644)
+}
+
+func (ecg *ExternalCertGenerator) GenerateCert(hosts []string, certFile, keyFile, caFile, caKeyFile, org string, bits int) error {
+	return ecg.GenerateCertWithOptions(hosts, certFile, keyFile, caFile, caKeyFile, Options{Org: org, Bits: bits})
+}
+
+// GenerateCertWithOptions generates a key and a request for a certificate
+// for the hosts, a client one if hosts is [""], and has the external CA sign
+// it. The CA files are ignored.
+func (ecg *ExternalCertGenerator) GenerateCertWithOptions(hosts []string, certFile, keyFile, caFile, caKeyFile string, opts Options) error {
+	if len(hosts) == 1 && hosts[0] == "" {
+		hosts = []string{}
+	}
+
+	priv, keyBlock, err := generateKey(opts)
+	if err != nil {
+		return err
+	}
+
+	template := &x509.CertificateRequest{
+		Subject: pkix.Name{
+			Organization: []string{opts.Org},
+			CommonName:   opts.Org,
+		},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+
+	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, template, priv)
+	if err != nil {
+		return err
+	}
+
+	certPEM, err := ecg.Signer.SignCSR(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrBytes}), hosts, opts)
+	if err != nil {
+		return fmt.Errorf("Error signing the certificate: %s", err)
+	}
+
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return errors.New("Error signing the certificate: the signer didn't return a PEM encoded certificate")
+	}
+
+	signed, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("Error signing the certificate: %s", err)
+	}
+
+	signedKey, err := x509.MarshalPKIXPublicKey(signed.PublicKey)
+	if err != nil {
+		return fmt.Errorf("Error signing the certificate: %s", err)
+	}
+
+	requestKey, err := x509.MarshalPKIXPublicKey(priv.Public())
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(signedKey, requestKey) {
+		return errors.New("Error signing the certificate: the signed certificate isn't the one of the request")
+	}
+
+	if err := ioutil.WriteFile(certFile, certPEM, 0644); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(keyFile, pem.EncodeToMemory(keyBlock), 0600)
+}
+
+// CommandSigner signs the requests by running a command, for CA keys kept
+// offline such as on a smart card or another host. The command is run with
+// sh -c, gets the request on its standard input, the hosts, comma separated,
+// in MACHINE_CERT_HOSTS, the organization in MACHINE_CERT_ORG and the
+// validity in hours in MACHINE_CERT_VALIDITY_HOURS, and prints the
+// certificate on its standard output. The certificate of the CA has to be given, as it can't be
+// fetched.
+type CommandSigner struct {
+	Command string
+}
+
+func (s *CommandSigner) CACertificate() ([]byte, error) {
+	return nil, errors.New("the certificate of the CA signing with a command has to be given with --tls-ca-cert")
+}
+
+func (s *CommandSigner) SignCSR(csr []byte, hosts []string, opts Options) ([]byte, error) {
+	validity := opts.Validity
+	if validity == 0 {
+		validity = DefaultValidity
+	}
+
+	cmd := exec.Command("sh", "-c", s.Command)
+	cmd.Stdin = bytes.NewReader(csr)
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"MACHINE_CERT_HOSTS="+strings.Join(hosts, ","),
+		"MACHINE_CERT_ORG="+opts.Org,
+		fmt.Sprintf("MACHINE_CERT_VALIDITY_HOURS=%d", int(validity.Hours())),
+	)
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", s.Command, err)
+	}
+
+	return out, nil
+}
+
+// SignerOptions configure the external CA signing the certificates.
+type SignerOptions struct {
+	// Command signs the requests with a CommandSigner.
+	Command string
+	// VaultAddr, VaultToken, VaultPKIPath and VaultRole sign the requests
+	// with a VaultSigner.
+	VaultAddr    string
+	VaultToken   string
+	VaultPKIPath string
+	VaultRole    string
+}
+
+// globalSigner is the external CA given on the command line, nil if none
+// is, and globalVaultToken the Vault token given with it.
+var (
+	globalSigner     *auth.Signer
+	globalVaultToken string
+)
+
+// SetSigner has the external CA configured by opts sign the certificates
+// generated by default, and records it to be saved with the machines created.
+func SetSigner(opts SignerOptions) error {
+	signer, err := NewSigner(opts)
+	if err != nil || signer == nil {
+		return err
+	}
+
+	globalSigner = &auth.Signer{
+		Command:      opts.Command,
+		VaultAddr:    opts.VaultAddr,
+		VaultPKIPath: opts.VaultPKIPath,
+		VaultRole:    opts.VaultRole,
+	}
+	globalVaultToken = opts.VaultToken
+	SetCertGenerator(NewExternalCertGenerator(signer))
+
+	return nil
+}
+
+// GlobalSigner returns the external CA given on the command line, nil if
+// none is.
+func GlobalSigner() *auth.Signer {
+	if globalSigner == nil {
+		return nil
+	}
+
+	signer := *globalSigner
+	return &signer
+}
+
+// generatorFor returns the generator of the certificates of a machine: the
+// external CA saved in its auth options, the local CA if its key is there,
+// the default generator otherwise, e.g. for the machines created with an
+// external CA before it was saved.
+func generatorFor(authOptions *auth.Options) (Generator, error) {
+	if s := authOptions.Signer; s != nil {
+		signer, err := NewSigner(SignerOptions{
+			Command:      s.Command,
+			VaultAddr:    s.VaultAddr,
+			VaultToken:   globalVaultToken,
+			VaultPKIPath: s.VaultPKIPath,
+			VaultRole:    s.VaultRole,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return NewExternalCertGenerator(signer), nil
+	}
+
+	if _, err := os.Stat(authOptions.CaPrivateKeyPath); err == nil {
+		if _, ok := defaultGenerator.(*ExternalCertGenerator); ok {
+			return NewX509CertGenerator(), nil
+		}
+	}
+
+	return defaultGenerator, nil
+}
+
+// GenerateServerCert generates the server certificate of a machine for the
+// hosts, signed by the CA of the machine.
+func GenerateServerCert(authOptions *auth.Options, hosts []string, certFile, keyFile string, opts Options) error {
+	generator, err := generatorFor(authOptions)
+	if err != nil {
+		return err
+	}
+
+	return generator.GenerateCertWithOptions(hosts, certFile, keyFile, authOptions.CaCertPath, authOptions.CaPrivateKeyPath, opts)
+}
+
+// CheckSignerCA checks the CA certificate and the client certificate of the
+// workstation are those of the external CA, when they exist: the server
+// certificates signed by the external CA wouldn't be trusted by the clients
+// otherwise.
+func CheckSignerCA(signer CSRSigner, caCertPath, clientCertPath string) error {
+	stored, err := ioutil.ReadFile(caCertPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	caCert, err := parsePEMCertificate(stored)
+	if err != nil {
+		return fmt.Errorf("Error reading the CA certificate %s: %s", caCertPath, err)
+	}
+
+	// The certificate of the CA of a signing command is the one given.
+	if external, err := signer.CACertificate(); err == nil {
+		externalCert, err := parsePEMCertificate(external)
+		if err != nil {
+			return fmt.Errorf("Error reading the certificate of the external CA: %s", err)
+		}
+
+		if !bytes.Equal(caCert.Raw, externalCert.Raw) {
+			return fmt.Errorf("Error: the CA certificate %s isn't the one of the external CA. Move the certificates of %s away for them to be created again, signed by the external CA, then run 'docker-machine regenerate-certs' on the machines", caCertPath, filepath.Dir(caCertPath))
+		}
+	}
+
+	data, err := ioutil.ReadFile(clientCertPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	clientCert, err := parsePEMCertificate(data)
+	if err != nil {
+		return fmt.Errorf("Error reading the client certificate %s: %s", clientCertPath, err)
+	}
+
+	if err := clientCert.CheckSignatureFrom(caCert); err != nil {
+		return fmt.Errorf("Error: the client certificate %s isn't signed by the CA %s. Move it and its key away for them to be created again", clientCertPath, caCertPath)
+	}
+
+	return nil
+}
+
+func parsePEMCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("not a PEM encoded certificate")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// NewSigner returns the signer configured by opts, or nil if none is.
+func NewSigner(opts SignerOptions) (CSRSigner, error) {
+	switch {
+	case opts.Command != "" && opts.VaultAddr != "":
+		return nil, errors.New("Error: a certificate signing command and a Vault address can't both be given")
+	case opts.Command != "":
+		return &CommandSigner{Command: opts.Command}, nil
+	case opts.VaultAddr != "":
+		if opts.VaultRole == "" {
+			return nil, errors.New("Error: the Vault role signing the certificates has to be given")
+		}
+		return NewVaultSigner(opts.VaultAddr, opts.VaultToken, opts.VaultPKIPath, opts.VaultRole), nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,249 @@
+package cert
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/machine/libmachine/auth"
+)
+
+// localSigner signs the requests with a CA generated in dir.
+type localSigner struct {
+	caCertPath, caKeyPath string
+}
+
+func newLocalSigner(t *testing.T, dir string) *localSigner {
+	s := &localSigner{
+		caCertPath: filepath.Join(dir, "external-ca.pem"),
+		caKeyPath:  filepath.Join(dir, "external-ca-key.pem"),
+	}
+
+	if err := NewX509CertGenerator().GenerateCACertificate(s.caCertPath, s.caKeyPath, "external", 2048); err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func (s *localSigner) CACertificate() ([]byte, error) {
+	return ioutil.ReadFile(s.caCertPath)
+}
+
+func (s *localSigner) SignCSR(csr []byte, hosts []string, opts Options) ([]byte, error) {
+	block, _ := pem.Decode(csr)
+	if block == nil {
+		return nil, errors.New("no request")
+	}
+
+	req, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	caKeyPair, err := tls.LoadX509KeyPair(s.caCertPath, s.caKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	caCert, err := x509.ParseCertificate(caKeyPair.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := (&X509CertGenerator{}).newCertificate(opts.Org, opts.Validity)
+	if err != nil {
+		return nil, err
+	}
+	template.DNSNames = req.DNSNames
+	template.IPAddresses = req.IPAddresses
+
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, req.PublicKey, caKeyPair.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+func TestExternalCertGenerator(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	generator := NewExternalCertGenerator(newLocalSigner(t, tmpDir))
+
+	caCertPath := filepath.Join(tmpDir, "ca.pem")
+	caKeyPath := filepath.Join(tmpDir, "ca-key.pem")
+	certPath := filepath.Join(tmpDir, "server.pem")
+	keyPath := filepath.Join(tmpDir, "server-key.pem")
+
+	if err := generator.GenerateCACertificate(caCertPath, caKeyPath, "test-org", 2048); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(caKeyPath); !os.IsNotExist(err) {
+		t.Fatal("expected no CA key to be generated")
+	}
+
+	opts := Options{Org: "test-org", KeyType: KeyTypeECDSA}
+	if err := generator.GenerateCertWithOptions([]string{"docker.example.com", "10.0.0.1"}, certPath, keyPath, caCertPath, caKeyPath, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	keyPair, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverCert, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caPEM, err := ioutil.ReadFile(caCertPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caPEM)
+	if _, err := serverCert.Verify(x509.VerifyOptions{DNSName: "docker.example.com", Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// otherKeySigner returns a certificate which isn't the one of the request.
+type otherKeySigner struct {
+	*localSigner
+	dir string
+}
+
+func (s *otherKeySigner) SignCSR(csr []byte, hosts []string, opts Options) ([]byte, error) {
+	certPath := filepath.Join(s.dir, "other.pem")
+	if err := NewX509CertGenerator().GenerateCert(hosts, certPath, filepath.Join(s.dir, "other-key.pem"), s.caCertPath, s.caKeyPath, opts.Org, 2048); err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadFile(certPath)
+}
+
+func TestExternalCertGeneratorChecksTheSignedKey(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	generator := NewExternalCertGenerator(&otherKeySigner{newLocalSigner(t, tmpDir), tmpDir})
+
+	err = generator.GenerateCert([]string{"localhost"}, filepath.Join(tmpDir, "cert.pem"), filepath.Join(tmpDir, "key.pem"), "", "", "test-org", 2048)
+	if err == nil || !strings.Contains(err.Error(), "isn't the one of the request") {
+		t.Fatalf("expected the certificate to be refused, got %v", err)
+	}
+}
+
+func TestCommandSigner(t *testing.T) {
+	signer := &CommandSigner{Command: `cat >/dev/null; echo "$MACHINE_CERT_HOSTS $MACHINE_CERT_ORG $MACHINE_CERT_VALIDITY_HOURS"`}
+
+	out, err := signer.SignCSR([]byte("request"), []string{"docker.example.com", "10.0.0.1"}, Options{Org: "test-org"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(out) != "docker.example.com,10.0.0.1 test-org 25920\n" {
+		t.Fatalf("unexpected output %q", out)
+	}
+
+	if _, err := (&CommandSigner{Command: "exit 1"}).SignCSR([]byte("request"), nil, Options{}); err == nil {
+		t.Fatal("expected the failure of the command to be returned")
+	}
+}
+
+func TestNewSigner(t *testing.T) {
+	signer, err := NewSigner(SignerOptions{})
+	if signer != nil || err != nil {
+		t.Fatalf("expected no signer, got %v %v", signer, err)
+	}
+
+	if _, err := NewSigner(SignerOptions{Command: "sign", VaultAddr: "https://vault:8200", VaultRole: "machine"}); err == nil {
+		t.Fatal("expected an error given both a command and Vault")
+	}
+
+	if _, err := NewSigner(SignerOptions{VaultAddr: "https://vault:8200"}); err == nil {
+		t.Fatal("expected an error given no Vault role")
+	}
+}
+
+func TestCheckSignerCA(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	signer := newLocalSigner(t, tmpDir)
+	caCertPath := filepath.Join(tmpDir, "ca.pem")
+	caKeyPath := filepath.Join(tmpDir, "ca-key.pem")
+	certPath := filepath.Join(tmpDir, "cert.pem")
+	keyPath := filepath.Join(tmpDir, "key.pem")
+
+	if err := CheckSignerCA(signer, caCertPath, certPath); err != nil {
+		t.Fatalf("expected no error without certificates, got %s", err)
+	}
+
+	// The certificates of a store created before the external CA was used.
+	local := NewX509CertGenerator()
+	if err := local.GenerateCACertificate(caCertPath, caKeyPath, "local", 2048); err != nil {
+		t.Fatal(err)
+	}
+	if err := local.GenerateCert([]string{""}, certPath, keyPath, caCertPath, caKeyPath, "local", 2048); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CheckSignerCA(signer, caCertPath, certPath); err == nil || !strings.Contains(err.Error(), "isn't the one of the external CA") {
+		t.Fatalf("expected the local CA to be refused, got %v", err)
+	}
+
+	external := NewExternalCertGenerator(signer)
+	if err := external.GenerateCACertificate(caCertPath, caKeyPath, "local", 2048); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CheckSignerCA(signer, caCertPath, certPath); err == nil || !strings.Contains(err.Error(), "isn't signed by the CA") {
+		t.Fatalf("expected the client certificate of the local CA to be refused, got %v", err)
+	}
+
+	if err := external.GenerateCert([]string{""}, certPath, keyPath, caCertPath, caKeyPath, "local", 2048); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CheckSignerCA(signer, caCertPath, certPath); err != nil {
+		t.Fatalf("expected the certificates of the external CA to be accepted, got %s", err)
+	}
+}
+
+func TestGeneratorForSavedSigner(t *testing.T) {
+	generator, err := generatorFor(&auth.Options{Signer: &auth.Signer{Command: "sign"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	external, ok := generator.(*ExternalCertGenerator)
+	if !ok {
+		t.Fatalf("expected an external generator, got %T", generator)
+	}
+
+	if signer, ok := external.Signer.(*CommandSigner); !ok || signer.Command != "sign" {
+		t.Fatalf("expected the saved signing command, got %#v", external.Signer)
+	}
+}
@@ -0,0 +1,135 @@
+package cert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// VaultSigner signs the requests with the PKI secrets engine of HashiCorp
+// Vault, mounted at PKIPath, with the role Role.
+type VaultSigner struct {
+	Addr    string
+	Token   string
+	PKIPath string
+	Role    string
+	client  *http.Client
+}
+
+func NewVaultSigner(addr, token, pkiPath, role string) *VaultSigner {
+	if pkiPath == "" {
+		pkiPath = "pki"
+	}
+
+	return &VaultSigner{
+		Addr:    strings.TrimSuffix(addr, "/"),
+		Token:   token,
+		PKIPath: strings.Trim(pkiPath, "/"),
+		Role:    role,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// vaultError is the body of the responses of Vault to failed requests.
+type vaultError struct {
+	Errors []string `json:"errors"`
+}
+
+func (s *VaultSigner) do(method, path string, body interface{}) ([]byte, error) {
+	var reqBody []byte
+	if body != nil {
+		var err error
+		if reqBody, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequest(method, fmt.Sprintf("%s/v1/%s/%s", s.Addr, s.PKIPath, path), bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Token != "" {
+		req.Header.Set("X-Vault-Token", s.Token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var vErr vaultError
+		if json.Unmarshal(data, &vErr) == nil && len(vErr.Errors) > 0 {
+			return nil, fmt.Errorf("Vault responded %s: %s", resp.Status, strings.Join(vErr.Errors, ", "))
+		}
+		return nil, fmt.Errorf("Vault responded %s", resp.Status)
+	}
+
+	return data, nil
+}
+
+// CACertificate fetches the certificate of the CA of the PKI secrets engine.
+func (s *VaultSigner) CACertificate() ([]byte, error) {
+	return s.do("GET", "ca/pem", nil)
+}
+
+// SignCSR signs the request with the role, with the hosts as subject
+// alternative names and the validity of opts as TTL. The common name is the
+// first host name, or the organization of opts.
+func (s *VaultSigner) SignCSR(csr []byte, hosts []string, opts Options) ([]byte, error) {
+	dnsNames, ips := []string{}, []string{}
+	for _, h := range hosts {
+		if net.ParseIP(h) != nil {
+			ips = append(ips, h)
+		} else {
+			dnsNames = append(dnsNames, h)
+		}
+	}
+
+	commonName := opts.Org
+	if len(dnsNames) > 0 {
+		commonName = dnsNames[0]
+	}
+
+	params := map[string]string{
+		"csr":         string(csr),
+		"common_name": commonName,
+		"alt_names":   strings.Join(dnsNames, ","),
+		"ip_sans":     strings.Join(ips, ","),
+		"format":      "pem",
+	}
+	if opts.Validity != 0 {
+		params["ttl"] = fmt.Sprintf("%dh", int(opts.Validity.Hours()))
+	}
+
+	data, err := s.do("POST", "sign/"+s.Role, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Data struct {
+			Certificate string `json:"certificate"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+
+	return []byte(resp.Data.Certificate), nil
+}
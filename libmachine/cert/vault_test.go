@@ -0,0 +1,68 @@
+package cert
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVaultSigner(t *testing.T) {
+	var params map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s3cr3t" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/machine-pki/ca/pem":
+			w.Write([]byte("CA PEM"))
+		case "/v1/machine-pki/sign/docker":
+			json.NewDecoder(r.Body).Decode(&params)
+			w.Write([]byte(`{"data":{"certificate":"CERT PEM"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	signer := NewVaultSigner(server.URL+"/", "s3cr3t", "/machine-pki/", "docker")
+
+	caCert, err := signer.CACertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(caCert) != "CA PEM" {
+		t.Fatalf("unexpected CA certificate %q", caCert)
+	}
+
+	cert, err := signer.SignCSR([]byte("CSR PEM"), []string{"docker.example.com", "10.0.0.1", "localhost"}, Options{Org: "test-org", Validity: 72 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(cert) != "CERT PEM" {
+		t.Fatalf("unexpected certificate %q", cert)
+	}
+
+	expected := map[string]string{
+		"csr":         "CSR PEM",
+		"common_name": "docker.example.com",
+		"alt_names":   "docker.example.com,localhost",
+		"ip_sans":     "10.0.0.1",
+		"format":      "pem",
+		"ttl":         "72h",
+	}
+	for k, v := range expected {
+		if params[k] != v {
+			t.Fatalf("expected %s to be %q, got %q", k, v, params[k])
+		}
+	}
+
+	signer.Token = "wrong"
+	if _, err := signer.CACertificate(); err == nil || err.Error() != "Vault responded 403 Forbidden: permission denied" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -24,6 +24,14 @@ type BaseDriver struct {
 	SwarmMaster    bool
 	SwarmHost      string
 	SwarmDiscovery string
+	// SSHProxyJump is the bastion through which the machine is reached
+	// with SSH, [user@]host[:port], if any.
+	SSHProxyJump string `json:",omitempty"`
+}
+
+// Capabilities returns the default capabilities
+func (d *BaseDriver) Capabilities() []Capability {
+	return DefaultCapabilities
 }
 
 // DriverName returns the name of the driver
@@ -48,6 +56,18 @@ func (d *BaseDriver) GetIP() (string, error) {
 	return d.IPAddress, nil
 }
 
+// GetSSHProxyJump returns the bastion through which the machine is reached
+// with SSH
+func (d *BaseDriver) GetSSHProxyJump() string {
+	return d.SSHProxyJump
+}
+
+// SetSSHProxyJump sets the bastion through which the machine is reached with
+// SSH
+func (d *BaseDriver) SetSSHProxyJump(jump string) {
+	d.SSHProxyJump = jump
+}
+
 // GetSSHKeyPath returns the ssh key path
 func (d *BaseDriver) GetSSHKeyPath() string {
 	if d.SSHKeyPath == "" {
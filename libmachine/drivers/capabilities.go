@@ -0,0 +1,72 @@
+package drivers
+
+import "fmt"
+
+// Capability is an operation which only some drivers support.
+type Capability string
+
+const (
+	CapabilityStart         Capability = "start"
+	CapabilityStop          Capability = "stop"
+	CapabilityKill          Capability = "kill"
+	CapabilitySnapshot      Capability = "snapshot"
+	CapabilityResize        Capability = "resize"
+	CapabilitySuspend       Capability = "suspend"
+	CapabilityPortForward   Capability = "port-forward"
+	CapabilitySharedFolders Capability = "shared-folders"
+)
+
+// AllCapabilities lists every known capability.
+var AllCapabilities = []Capability{
+	CapabilityStart,
+	CapabilityStop,
+	CapabilityKill,
+	CapabilitySnapshot,
+	CapabilityResize,
+	CapabilitySuspend,
+	CapabilityPortForward,
+	CapabilitySharedFolders,
+}
+
+// DefaultCapabilities are the capabilities of drivers which don't report
+// their own: they can manage the lifecycle of the machine, nothing more.
+var DefaultCapabilities = []Capability{
+	CapabilityStart,
+	CapabilityStop,
+	CapabilityKill,
+}
+
+// ErrCapabilityNotSupported is returned when an operation is requested from a
+// driver which doesn't support it.
+type ErrCapabilityNotSupported struct {
+	DriverName string
+	Capability Capability
+}
+
+func (e ErrCapabilityNotSupported) Error() string {
+	return fmt.Sprintf("driver %s doesn't support %s", e.DriverName, e.Capability)
+}
+
+// HasCapability reports whether the driver supports the capability c.
+func HasCapability(d Driver, c Capability) bool {
+	for _, capability := range d.Capabilities() {
+		if capability == c {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RequireCapability returns an ErrCapabilityNotSupported error unless the
+// driver supports the capability c.
+func RequireCapability(d Driver, c Capability) error {
+	if !HasCapability(d, c) {
+		return ErrCapabilityNotSupported{
+			DriverName: d.DriverName(),
+			Capability: c,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseDriverHasDefaultCapabilities(t *testing.T) {
+	d := &BaseDriver{}
+
+	assert.Equal(t, DefaultCapabilities, d.Capabilities())
+}
+
+func TestErrCapabilityNotSupported(t *testing.T) {
+	err := ErrCapabilityNotSupported{DriverName: "generic", Capability: CapabilityStart}
+
+	assert.EqualError(t, err, "driver generic doesn't support start")
+}
+
+func TestRequireCapability(t *testing.T) {
+	d := &MockDriver{
+		calls:        &CallRecorder{},
+		capabilities: []Capability{CapabilityStop},
+		driverName:   "generic",
+	}
+
+	assert.True(t, HasCapability(d, CapabilityStop))
+	assert.False(t, HasCapability(d, CapabilitySnapshot))
+	assert.NoError(t, RequireCapability(d, CapabilityStop))
+	assert.Equal(t, ErrCapabilityNotSupported{DriverName: "generic", Capability: CapabilityStart}, RequireCapability(d, CapabilityStart))
+}
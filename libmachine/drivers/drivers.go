@@ -12,6 +12,9 @@ import (
 // driver represent different ways hosts can be created (e.g. different
 // hypervisors, different cloud providers)
 type Driver interface {
+	// Capabilities returns the optional operations the driver supports
+	Capabilities() []Capability
+
 	// Create a host using the driver's config
 	Create() error
 
@@ -2,6 +2,7 @@ package localbinary
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -195,7 +196,9 @@ func (lbp *Plugin) execServer() error {
 		case out := <-stdOutCh:
 			log.Info(fmt.Sprintf(pluginOutPrefix, lbp.MachineName), out)
 		case err := <-stdErrCh:
-			log.Debug(fmt.Sprintf(pluginErrPrefix, lbp.MachineName), err)
+			if !logRecord(lbp.MachineName, err) {
+				log.Debug(fmt.Sprintf(pluginErrPrefix, lbp.MachineName), err)
+			}
 		case _ = <-lbp.stopCh:
 			stopStdoutCh <- true
 			stopStderrCh <- true
@@ -207,6 +210,41 @@ func (lbp *Plugin) execServer() error {
 	}
 }
 
+// logRecord logs a record the plugin wrote in the JSON format asked for in
+// the handshake, at its level, or reports its progress event. It returns
+// false if the line isn't such a record, e.g. if the plugin predates it.
+func logRecord(machineName, line string) bool {
+	var record struct {
+		Level   string `json:"level"`
+		Msg     string `json:"msg"`
+		Event   string `json:"event"`
+		Host    string `json:"host"`
+		Step    string `json:"step"`
+		Percent int    `json:"percent"`
+	}
+	if err := json.Unmarshal([]byte(line), &record); err != nil || record.Level == "" {
+		return false
+	}
+
+	if record.Event == "progress" {
+		log.Progress(record.Host, record.Step, record.Percent, record.Msg)
+		return true
+	}
+
+	switch record.Level {
+	case "debug":
+		log.Debug(fmt.Sprintf(pluginErrPrefix, machineName), record.Msg)
+	case "warn":
+		log.Warn(fmt.Sprintf(pluginOutPrefix, machineName), record.Msg)
+	case "error", "fatal":
+		log.Error(fmt.Sprintf(pluginOutPrefix, machineName), record.Msg)
+	default:
+		log.Info(fmt.Sprintf(pluginOutPrefix, machineName), record.Msg)
+	}
+
+	return true
+}
+
 func (lbp *Plugin) Serve() error {
 	return lbp.execServer()
 }
@@ -147,6 +147,24 @@ func TestExecServer(t *testing.T) {
 		t.Fatalf("Error written to log was not what we expected\nexpected: %s\nactual:   %s", expectedErr, logScanner.Text())
 	}
 
+	expectedRecord := fmt.Sprintf("%s%s", fmt.Sprintf(pluginOutPrefix, machineName), "Creating VM...")
+
+	if _, err := io.WriteString(stderrWriter, `{"level":"info","msg":"Creating VM...","time":"2016-01-01T00:00:00Z"}`+"\n"); err != nil {
+		t.Fatalf("Error attempting to write a record in plugin: %s", err)
+	}
+
+	if logScanner.Scan(); logScanner.Text() != expectedRecord {
+		t.Fatalf("Record written to log was not what we expected\nexpected: %s\nactual:   %s", expectedRecord, logScanner.Text())
+	}
+
+	if _, err := io.WriteString(stderrWriter, `{"event":"progress","host":"test","level":"info","msg":"Waiting for an IP...","percent":30,"step":"wait-ip"}`+"\n"); err != nil {
+		t.Fatalf("Error attempting to write a progress event in plugin: %s", err)
+	}
+
+	if logScanner.Scan(); logScanner.Text() != "Waiting for an IP..." {
+		t.Fatalf("Progress written to log was not what we expected\nexpected: %s\nactual:   %s", "Waiting for an IP...", logScanner.Text())
+	}
+
 	lbp.Close()
 
 	if err := <-finalErr; err != nil {
@@ -0,0 +1,113 @@
+package drivers
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// PortForward is a rule forwarding a port of the host to a port of the
+// machine. An empty HostIP listens on every interface of the host.
+type PortForward struct {
+	Name      string
+	Protocol  string
+	HostIP    string
+	HostPort  int
+	GuestPort int
+}
+
+func (p PortForward) String() string {
+	return fmt.Sprintf("%s:%d->%d/%s", p.HostIP, p.HostPort, p.GuestPort, p.Protocol)
+}
+
+// ParsePortForward parses a rule written
+// [name=][host-ip:]host-port:guest-port[/protocol]. The protocol defaults to
+// tcp and the name to the protocol and the host port, e.g. tcp-8080.
+func ParsePortForward(spec string) (PortForward, error) {
+	invalid := fmt.Errorf("invalid port forwarding rule %q: expected [name=][host-ip:]host-port:guest-port[/protocol]", spec)
+	rule := PortForward{Protocol: "tcp"}
+
+	ports := spec
+	if i := strings.Index(ports, "="); i >= 0 {
+		rule.Name, ports = ports[:i], ports[i+1:]
+		if rule.Name == "" || strings.ContainsAny(rule.Name, ",\"") {
+			return PortForward{}, invalid
+		}
+	}
+
+	if i := strings.LastIndex(ports, "/"); i >= 0 {
+		rule.Protocol, ports = ports[i+1:], ports[:i]
+		if rule.Protocol != "tcp" && rule.Protocol != "udp" {
+			return PortForward{}, invalid
+		}
+	}
+
+	parts := strings.Split(ports, ":")
+	switch len(parts) {
+	case 2:
+	case 3:
+		if net.ParseIP(parts[0]) == nil {
+			return PortForward{}, invalid
+		}
+		rule.HostIP, parts = parts[0], parts[1:]
+	default:
+		return PortForward{}, invalid
+	}
+
+	var err error
+	if rule.HostPort, err = parsePort(parts[0]); err != nil {
+		return PortForward{}, invalid
+	}
+	if rule.GuestPort, err = parsePort(parts[1]); err != nil {
+		return PortForward{}, invalid
+	}
+
+	if rule.Name == "" {
+		rule.Name = fmt.Sprintf("%s-%d", rule.Protocol, rule.HostPort)
+	}
+
+	return rule, nil
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if port < 1 || port > 65535 {
+		return 0, fmt.Errorf("port %d out of range", port)
+	}
+	return port, nil
+}
+
+// PortForwarder is implemented by the drivers which support
+// CapabilityPortForward, i.e. those of local virtual machines behind a NAT.
+type PortForwarder interface {
+	// AddPortForward adds the rule, to the running machine too.
+	AddPortForward(rule PortForward) error
+
+	// ListPortForwards returns the rules added to the machine.
+	ListPortForwards() ([]PortForward, error)
+
+	// RemovePortForward removes the rule name.
+	RemovePortForward(name string) error
+}
+
+// AsPortForwarder returns the driver as a PortForwarder, or an
+// ErrCapabilityNotSupported error if it can't forward ports.
+func AsPortForwarder(d Driver) (PortForwarder, error) {
+	if err := RequireCapability(d, CapabilityPortForward); err != nil {
+		return nil, err
+	}
+
+	forwarder, ok := d.(PortForwarder)
+	if !ok {
+		return nil, ErrCapabilityNotSupported{
+			DriverName: d.DriverName(),
+			Capability: CapabilityPortForward,
+		}
+	}
+
+	return forwarder, nil
+}
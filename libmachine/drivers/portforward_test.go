@@ -0,0 +1,38 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePortForward(t *testing.T) {
+	for spec, expected := range map[string]PortForward{
+		"8080:80":                 {Name: "tcp-8080", Protocol: "tcp", HostPort: 8080, GuestPort: 80},
+		"web=8080:80":             {Name: "web", Protocol: "tcp", HostPort: 8080, GuestPort: 80},
+		"5353:53/udp":             {Name: "udp-5353", Protocol: "udp", HostPort: 5353, GuestPort: 53},
+		"127.0.0.1:8443:443":      {Name: "tcp-8443", Protocol: "tcp", HostIP: "127.0.0.1", HostPort: 8443, GuestPort: 443},
+		"dns=0.0.0.0:5353:53/udp": {Name: "dns", Protocol: "udp", HostIP: "0.0.0.0", HostPort: 5353, GuestPort: 53},
+	} {
+		rule, err := ParsePortForward(spec)
+		assert.NoError(t, err, spec)
+		assert.Equal(t, expected, rule, spec)
+	}
+
+	for _, spec := range []string{"", "8080", "8080:80/sctp", "=8080:80", "a,b=8080:80", "host:8080:80", "0:80", "8080:70000", "1:2:3:4"} {
+		_, err := ParsePortForward(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestAsPortForwarderWithoutCapability(t *testing.T) {
+	d := &MockDriver{
+		calls:        &CallRecorder{},
+		capabilities: DefaultCapabilities,
+		driverName:   "generic",
+	}
+
+	_, err := AsPortForwarder(d)
+
+	assert.Equal(t, ErrCapabilityNotSupported{DriverName: "generic", Capability: CapabilityPortForward}, err)
+}
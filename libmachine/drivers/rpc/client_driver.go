@@ -10,7 +10,6 @@ import (
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/mcnflag"
 	"github.com/docker/machine/libmachine/state"
-	"github.com/docker/machine/libmachine/version"
 )
 
 var (
@@ -21,6 +20,9 @@ type RPCClientDriver struct {
 	plugin          localbinary.DriverPlugin
 	heartbeatDoneCh chan bool
 	Client          *InternalClient
+
+	// methods are the methods the plugin serves, nil if it doesn't tell.
+	methods map[string]bool
 }
 
 type RPCCall struct {
@@ -42,6 +44,7 @@ const (
 	GetVersionMethod         = `.GetVersion`
 	CloseMethod              = `.Close`
 	GetCreateFlagsMethod     = `.GetCreateFlags`
+	CapabilitiesMethod       = `.Capabilities`
 	SetConfigRawMethod       = `.SetConfigRaw`
 	GetConfigRawMethod       = `.GetConfigRaw`
 	DriverNameMethod         = `.DriverName`
@@ -64,6 +67,16 @@ const (
 	UpgradeMethod            = `.Upgrade`
 	LocalArtifactPathMethod  = `.LocalArtifactPath`
 	GlobalArtifactPathMethod = `.GlobalArtifactPath`
+	CreateSnapshotMethod     = `.CreateSnapshot`
+	ListSnapshotsMethod      = `.ListSnapshots`
+	RestoreSnapshotMethod    = `.RestoreSnapshot`
+	DeleteSnapshotMethod     = `.DeleteSnapshot`
+	AddPortForwardMethod     = `.AddPortForward`
+	ListPortForwardsMethod   = `.ListPortForwards`
+	RemovePortForwardMethod  = `.RemovePortForward`
+	SharedFoldersMethod      = `.SharedFolders`
+	GetSSHProxyJumpMethod    = `.GetSSHProxyJump`
+	GetSubnetMethod          = `.GetSubnet`
 )
 
 func (ic *InternalClient) Call(serviceMethod string, args interface{}, reply interface{}) error {
@@ -126,16 +139,10 @@ func NewRPCClientDriver(rawDriverData []byte, driverName string) (*RPCClientDriv
 		}
 	}(c)
 
-	var serverVersion int
-	if err := c.Client.Call(GetVersionMethod, struct{}{}, &serverVersion); err != nil {
+	if err := c.handshake(); err != nil {
 		return nil, err
 	}
 
-	if serverVersion != version.APIVersion {
-		return nil, fmt.Errorf("Driver binary uses an incompatible API version (%d)", serverVersion)
-	}
-	log.Debug("Using API Version ", serverVersion)
-
 	if err := c.SetConfigRaw(rawDriverData); err != nil {
 		return nil, err
 	}
@@ -199,6 +206,23 @@ func (c *RPCClientDriver) GetCreateFlags() []mcnflag.Flag {
 	return flags
 }
 
+// Capabilities returns the capabilities of the driver, or the default ones if
+// the plugin predates capability reporting.
+func (c *RPCClientDriver) Capabilities() []drivers.Capability {
+	var capabilities []drivers.Capability
+
+	if !c.supports(CapabilitiesMethod) {
+		return drivers.DefaultCapabilities
+	}
+
+	if err := c.Client.Call(CapabilitiesMethod, struct{}{}, &capabilities); err != nil {
+		log.Debugf("Error attempting call to get driver capabilities, assuming the default ones: %s", err)
+		return drivers.DefaultCapabilities
+	}
+
+	return capabilities
+}
+
 func (c *RPCClientDriver) SetConfigRaw(data []byte) error {
 	return c.Client.Call(SetConfigRawMethod, data, nil)
 }
@@ -316,6 +340,124 @@ func (c *RPCClientDriver) Kill() error {
 	return c.Client.Call(KillMethod, struct{}{}, nil)
 }
 
+func (c *RPCClientDriver) CreateSnapshot(name string) error {
+	if !c.supports(CreateSnapshotMethod) {
+		return errUnsupportedMethod(CreateSnapshotMethod)
+	}
+
+	return c.Client.Call(CreateSnapshotMethod, name, nil)
+}
+
+func (c *RPCClientDriver) ListSnapshots() ([]drivers.Snapshot, error) {
+	if !c.supports(ListSnapshotsMethod) {
+		return nil, errUnsupportedMethod(ListSnapshotsMethod)
+	}
+
+	var snapshots []drivers.Snapshot
+
+	if err := c.Client.Call(ListSnapshotsMethod, struct{}{}, &snapshots); err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+func (c *RPCClientDriver) RestoreSnapshot(name string) error {
+	if !c.supports(RestoreSnapshotMethod) {
+		return errUnsupportedMethod(RestoreSnapshotMethod)
+	}
+
+	return c.Client.Call(RestoreSnapshotMethod, name, nil)
+}
+
+func (c *RPCClientDriver) DeleteSnapshot(name string) error {
+	if !c.supports(DeleteSnapshotMethod) {
+		return errUnsupportedMethod(DeleteSnapshotMethod)
+	}
+
+	return c.Client.Call(DeleteSnapshotMethod, name, nil)
+}
+
+func (c *RPCClientDriver) AddPortForward(rule drivers.PortForward) error {
+	if !c.supports(AddPortForwardMethod) {
+		return errUnsupportedMethod(AddPortForwardMethod)
+	}
+
+	return c.Client.Call(AddPortForwardMethod, rule, nil)
+}
+
+func (c *RPCClientDriver) ListPortForwards() ([]drivers.PortForward, error) {
+	if !c.supports(ListPortForwardsMethod) {
+		return nil, errUnsupportedMethod(ListPortForwardsMethod)
+	}
+
+	var rules []drivers.PortForward
+
+	if err := c.Client.Call(ListPortForwardsMethod, struct{}{}, &rules); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func (c *RPCClientDriver) RemovePortForward(name string) error {
+	if !c.supports(RemovePortForwardMethod) {
+		return errUnsupportedMethod(RemovePortForwardMethod)
+	}
+
+	return c.Client.Call(RemovePortForwardMethod, name, nil)
+}
+
+func (c *RPCClientDriver) SharedFolders() ([]drivers.SharedFolder, error) {
+	if !c.supports(SharedFoldersMethod) {
+		return nil, errUnsupportedMethod(SharedFoldersMethod)
+	}
+
+	var folders []drivers.SharedFolder
+
+	if err := c.Client.Call(SharedFoldersMethod, struct{}{}, &folders); err != nil {
+		return nil, err
+	}
+
+	return folders, nil
+}
+
+// GetSSHProxyJump returns the bastion through which the machine is reached
+// with SSH, or none if the plugin is too old to tell.
+func (c *RPCClientDriver) GetSSHProxyJump() string {
+	var jump string
+
+	if !c.supports(GetSSHProxyJumpMethod) {
+		return ""
+	}
+
+	if err := c.Client.Call(GetSSHProxyJumpMethod, struct{}{}, &jump); err != nil {
+		log.Debugf("Error attempting call to get the SSH bastion: %s", err)
+		return ""
+	}
+
+	return jump
+}
+
+// GetSubnet returns the network the machine is reached on, or none if the
+// plugin is too old to tell.
+func (c *RPCClientDriver) GetSubnet() (string, error) {
+	var subnet string
+
+	if !c.supports(GetSubnetMethod) {
+		return "", nil
+	}
+
+	if err := c.Client.Call(GetSubnetMethod, struct{}{}, &subnet); err != nil {
+		if isUnknownMethod(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return subnet, nil
+}
+
 func (c *RPCClientDriver) LocalArtifactPath(file string) string {
 	var path string
 
@@ -0,0 +1,128 @@
+package rpcdriver
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/version"
+)
+
+// HandshakeMethod is the first call of the client, negotiating the protocol
+// with the plugin.
+const HandshakeMethod = `.Handshake`
+
+// HandshakeArgs describe the client to the plugin.
+type HandshakeArgs struct {
+	// MinVersion and MaxVersion are the versions of the protocol the client
+	// speaks.
+	MinVersion int
+	MaxVersion int
+	// LogFormat is the format the plugin writes its logs in from then on,
+	// log.FormatJSON for the client to get their level and the progress
+	// events. The plugins which don't know the format keep the text one.
+	LogFormat string
+}
+
+// HandshakeReply describes the plugin to the client.
+type HandshakeReply struct {
+	// Version is the version of the protocol the plugin speaks.
+	Version int
+	// Methods are the methods the plugin serves, such as GetSSHProxyJump,
+	// for the client not to call the ones it doesn't.
+	Methods []string
+}
+
+// serverMethods returns the methods of the RPCServerDriver served by
+// net/rpc: those taking an argument and a reply and returning an error.
+func serverMethods() []string {
+	t := reflect.TypeOf(&RPCServerDriver{})
+	errorType := reflect.TypeOf((*error)(nil)).Elem()
+
+	methods := []string{}
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		if m.Type.NumIn() == 3 && m.Type.NumOut() == 1 && m.Type.Out(0) == errorType && m.Type.In(2).Kind() == reflect.Ptr {
+			methods = append(methods, m.Name)
+		}
+	}
+	sort.Strings(methods)
+
+	return methods
+}
+
+// Handshake tells the client the version of the protocol the plugin speaks
+// and the methods it serves.
+func (r *RPCServerDriver) Handshake(args HandshakeArgs, reply *HandshakeReply) error {
+	if version.APIVersion < args.MinVersion || version.APIVersion > args.MaxVersion {
+		return fmt.Errorf("The driver plugin speaks version %d of the plugin protocol, Docker Machine speaks %d to %d", version.APIVersion, args.MinVersion, args.MaxVersion)
+	}
+
+	if args.LogFormat != "" {
+		if err := log.SetFormat(args.LogFormat); err != nil {
+			log.Debugf("Keeping the text logs: %s", err)
+		}
+	}
+
+	reply.Version = version.APIVersion
+	reply.Methods = serverMethods()
+
+	return nil
+}
+
+// handshake negotiates the protocol with the plugin. The plugins predating
+// the handshake only tell their version, and are assumed to serve every
+// method.
+func (c *RPCClientDriver) handshake() error {
+	args := HandshakeArgs{
+		MinVersion: version.MinAPIVersion,
+		MaxVersion: version.APIVersion,
+		LogFormat:  log.FormatJSON,
+	}
+
+	var reply HandshakeReply
+	if err := c.Client.Call(HandshakeMethod, args, &reply); err != nil {
+		if !isUnknownMethod(err) {
+			return err
+		}
+
+		log.Debugf("The driver plugin predates the handshake: %s", err)
+		if err := c.Client.Call(GetVersionMethod, struct{}{}, &reply.Version); err != nil {
+			return err
+		}
+	}
+
+	if reply.Version < version.MinAPIVersion || reply.Version > version.APIVersion {
+		return fmt.Errorf("Driver binary uses an incompatible API version (%d)", reply.Version)
+	}
+	log.Debug("Using API Version ", reply.Version)
+
+	if reply.Methods != nil {
+		c.methods = map[string]bool{}
+		for _, m := range reply.Methods {
+			c.methods[m] = true
+		}
+	}
+
+	return nil
+}
+
+// isUnknownMethod tells whether the plugin failed a call because it doesn't
+// serve the method.
+func isUnknownMethod(err error) bool {
+	return strings.Contains(err.Error(), "can't find method")
+}
+
+// supports tells whether the plugin serves the method, e.g.
+// GetSSHProxyJumpMethod. The plugins which don't tell are assumed to.
+func (c *RPCClientDriver) supports(method string) bool {
+	return c.methods == nil || c.methods[strings.TrimPrefix(method, ".")]
+}
+
+// errUnsupportedMethod is returned by the calls to the methods the plugin
+// doesn't serve.
+func errUnsupportedMethod(method string) error {
+	return fmt.Errorf("The driver plugin doesn't support %s, it may need to be upgraded", strings.TrimPrefix(method, "."))
+}
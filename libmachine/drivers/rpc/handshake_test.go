@@ -0,0 +1,63 @@
+package rpcdriver
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerMethods(t *testing.T) {
+	methods := serverMethods()
+
+	assert.Contains(t, methods, "Handshake")
+	assert.Contains(t, methods, "GetSSHProxyJump")
+	assert.Contains(t, methods, "Create")
+	assert.NotContains(t, methods, "NewRPCServerDriver")
+}
+
+func TestHandshake(t *testing.T) {
+	var reply HandshakeReply
+
+	err := NewRPCServerDriver(nil).Handshake(HandshakeArgs{MinVersion: version.MinAPIVersion, MaxVersion: version.APIVersion}, &reply)
+
+	assert.NoError(t, err)
+	assert.Equal(t, version.APIVersion, reply.Version)
+	assert.Equal(t, serverMethods(), reply.Methods)
+}
+
+func TestHandshakeLogFormat(t *testing.T) {
+	defer log.SetFormat(log.FormatText)
+
+	var reply HandshakeReply
+
+	err := NewRPCServerDriver(nil).Handshake(HandshakeArgs{MinVersion: version.MinAPIVersion, MaxVersion: version.APIVersion, LogFormat: "xml"}, &reply)
+	assert.NoError(t, err)
+
+	err = NewRPCServerDriver(nil).Handshake(HandshakeArgs{MinVersion: version.MinAPIVersion, MaxVersion: version.APIVersion, LogFormat: log.FormatJSON}, &reply)
+	assert.NoError(t, err)
+}
+
+func TestHandshakeGivenIncompatibleClient(t *testing.T) {
+	var reply HandshakeReply
+
+	err := NewRPCServerDriver(nil).Handshake(HandshakeArgs{MinVersion: version.APIVersion + 1, MaxVersion: version.APIVersion + 2}, &reply)
+
+	assert.Error(t, err)
+}
+
+func TestSupports(t *testing.T) {
+	c := &RPCClientDriver{}
+	assert.True(t, c.supports(ListSnapshotsMethod))
+
+	c.methods = map[string]bool{"GetState": true}
+	assert.True(t, c.supports(GetStateMethod))
+	assert.False(t, c.supports(ListSnapshotsMethod))
+}
+
+func TestIsUnknownMethod(t *testing.T) {
+	assert.True(t, isUnknownMethod(errors.New("rpc: can't find method RPCServerDriver.Handshake")))
+	assert.False(t, isUnknownMethod(errors.New("connection refused")))
+}
@@ -104,6 +104,11 @@ func (r *RPCServerDriver) GetCreateFlags(_ *struct{}, reply *[]mcnflag.Flag) err
 	return nil
 }
 
+func (r *RPCServerDriver) Capabilities(_ *struct{}, reply *[]drivers.Capability) error {
+	*reply = r.ActualDriver.Capabilities()
+	return nil
+}
+
 func (r *RPCServerDriver) SetConfigRaw(data []byte, _ *struct{}) error {
 	return json.Unmarshal(data, &r.ActualDriver)
 }
@@ -180,7 +185,13 @@ func (r *RPCServerDriver) Restart(_ *struct{}, _ *struct{}) error {
 }
 
 func (r *RPCServerDriver) SetConfigFromFlags(flags *drivers.DriverOptions, _ *struct{}) error {
-	return r.ActualDriver.SetConfigFromFlags(*flags)
+	if err := r.ActualDriver.SetConfigFromFlags(*flags); err != nil {
+		return err
+	}
+
+	drivers.SetSSHProxyJumpFromFlags(r.ActualDriver, *flags)
+
+	return nil
 }
 
 func (r *RPCServerDriver) Start(_ *struct{}, _ *struct{}) error {
@@ -191,6 +202,90 @@ func (r *RPCServerDriver) Stop(_ *struct{}, _ *struct{}) error {
 	return r.ActualDriver.Stop()
 }
 
+func (r *RPCServerDriver) CreateSnapshot(name string, _ *struct{}) error {
+	snapshotter, err := drivers.AsSnapshotter(r.ActualDriver)
+	if err != nil {
+		return err
+	}
+	return snapshotter.CreateSnapshot(name)
+}
+
+func (r *RPCServerDriver) ListSnapshots(_ *struct{}, reply *[]drivers.Snapshot) error {
+	snapshotter, err := drivers.AsSnapshotter(r.ActualDriver)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := snapshotter.ListSnapshots()
+	*reply = snapshots
+	return err
+}
+
+func (r *RPCServerDriver) RestoreSnapshot(name string, _ *struct{}) error {
+	snapshotter, err := drivers.AsSnapshotter(r.ActualDriver)
+	if err != nil {
+		return err
+	}
+	return snapshotter.RestoreSnapshot(name)
+}
+
+func (r *RPCServerDriver) DeleteSnapshot(name string, _ *struct{}) error {
+	snapshotter, err := drivers.AsSnapshotter(r.ActualDriver)
+	if err != nil {
+		return err
+	}
+	return snapshotter.DeleteSnapshot(name)
+}
+
+func (r *RPCServerDriver) AddPortForward(rule drivers.PortForward, _ *struct{}) error {
+	forwarder, err := drivers.AsPortForwarder(r.ActualDriver)
+	if err != nil {
+		return err
+	}
+	return forwarder.AddPortForward(rule)
+}
+
+func (r *RPCServerDriver) ListPortForwards(_ *struct{}, reply *[]drivers.PortForward) error {
+	forwarder, err := drivers.AsPortForwarder(r.ActualDriver)
+	if err != nil {
+		return err
+	}
+
+	rules, err := forwarder.ListPortForwards()
+	*reply = rules
+	return err
+}
+
+func (r *RPCServerDriver) RemovePortForward(name string, _ *struct{}) error {
+	forwarder, err := drivers.AsPortForwarder(r.ActualDriver)
+	if err != nil {
+		return err
+	}
+	return forwarder.RemovePortForward(name)
+}
+
+func (r *RPCServerDriver) SharedFolders(_ *struct{}, reply *[]drivers.SharedFolder) error {
+	folderer, err := drivers.AsSharedFolderer(r.ActualDriver)
+	if err != nil {
+		return err
+	}
+
+	folders, err := folderer.SharedFolders()
+	*reply = folders
+	return err
+}
+
+func (r *RPCServerDriver) GetSSHProxyJump(_ *struct{}, reply *string) error {
+	*reply = drivers.GetSSHProxyJump(r.ActualDriver)
+	return nil
+}
+
+func (r *RPCServerDriver) GetSubnet(_ *struct{}, reply *string) error {
+	subnet, err := drivers.GetSubnet(r.ActualDriver)
+	*reply = subnet
+	return err
+}
+
 func (r *RPCServerDriver) Heartbeat(_ *struct{}, _ *struct{}) error {
 	r.HeartbeatCh <- true
 	return nil
@@ -34,6 +34,13 @@ func newSerialDriverWithLock(innerDriver Driver, lock sync.Locker) Driver {
 	}
 }
 
+// Capabilities returns the optional operations the driver supports
+func (d *SerialDriver) Capabilities() []Capability {
+	d.Lock()
+	defer d.Unlock()
+	return d.Driver.Capabilities()
+}
+
 // Create a host using the driver's config
 func (d *SerialDriver) Create() error {
 	d.Lock()
@@ -85,6 +92,21 @@ func (d *SerialDriver) GetSSHKeyPath() string {
 	return d.Driver.GetSSHKeyPath()
 }
 
+// GetSSHProxyJump returns the bastion through which the machine is reached
+// with SSH
+func (d *SerialDriver) GetSSHProxyJump() string {
+	d.Lock()
+	defer d.Unlock()
+	return GetSSHProxyJump(d.Driver)
+}
+
+// GetSubnet returns the network the machine is reached on
+func (d *SerialDriver) GetSubnet() (string, error) {
+	d.Lock()
+	defer d.Unlock()
+	return GetSubnet(d.Driver)
+}
+
 // GetSSHPort returns port for use with ssh
 func (d *SerialDriver) GetSSHPort() (int, error) {
 	d.Lock()
@@ -164,3 +186,91 @@ func (d *SerialDriver) Stop() error {
 	defer d.Unlock()
 	return d.Driver.Stop()
 }
+
+// CreateSnapshot saves the current state of the machine as name
+func (d *SerialDriver) CreateSnapshot(name string) error {
+	d.Lock()
+	defer d.Unlock()
+	snapshotter, err := AsSnapshotter(d.Driver)
+	if err != nil {
+		return err
+	}
+	return snapshotter.CreateSnapshot(name)
+}
+
+// ListSnapshots returns the snapshots of the machine
+func (d *SerialDriver) ListSnapshots() ([]Snapshot, error) {
+	d.Lock()
+	defer d.Unlock()
+	snapshotter, err := AsSnapshotter(d.Driver)
+	if err != nil {
+		return nil, err
+	}
+	return snapshotter.ListSnapshots()
+}
+
+// RestoreSnapshot brings the machine back to the snapshot name
+func (d *SerialDriver) RestoreSnapshot(name string) error {
+	d.Lock()
+	defer d.Unlock()
+	snapshotter, err := AsSnapshotter(d.Driver)
+	if err != nil {
+		return err
+	}
+	return snapshotter.RestoreSnapshot(name)
+}
+
+// DeleteSnapshot removes the snapshot name
+func (d *SerialDriver) DeleteSnapshot(name string) error {
+	d.Lock()
+	defer d.Unlock()
+	snapshotter, err := AsSnapshotter(d.Driver)
+	if err != nil {
+		return err
+	}
+	return snapshotter.DeleteSnapshot(name)
+}
+
+// AddPortForward adds a port forwarding rule to the machine
+func (d *SerialDriver) AddPortForward(rule PortForward) error {
+	d.Lock()
+	defer d.Unlock()
+	forwarder, err := AsPortForwarder(d.Driver)
+	if err != nil {
+		return err
+	}
+	return forwarder.AddPortForward(rule)
+}
+
+// ListPortForwards returns the port forwarding rules of the machine
+func (d *SerialDriver) ListPortForwards() ([]PortForward, error) {
+	d.Lock()
+	defer d.Unlock()
+	forwarder, err := AsPortForwarder(d.Driver)
+	if err != nil {
+		return nil, err
+	}
+	return forwarder.ListPortForwards()
+}
+
+// RemovePortForward removes the port forwarding rule name
+func (d *SerialDriver) RemovePortForward(name string) error {
+	d.Lock()
+	defer d.Unlock()
+	forwarder, err := AsPortForwarder(d.Driver)
+	if err != nil {
+		return err
+	}
+	return forwarder.RemovePortForward(name)
+}
+
+// SharedFolders returns the folders of the host shared with the machine
+func (d *SerialDriver) SharedFolders() ([]SharedFolder, error) {
+	d.Lock()
+	defer d.Unlock()
+	folderer, err := AsSharedFolderer(d.Driver)
+	if err != nil {
+		return nil, err
+	}
+	return folderer.SharedFolders()
+}
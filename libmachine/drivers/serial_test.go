@@ -29,17 +29,23 @@ func (l *MockLocker) Unlock() {
 }
 
 type MockDriver struct {
-	calls       *CallRecorder
-	driverName  string
-	flags       []mcnflag.Flag
-	ip          string
-	machineName string
-	sshHostname string
-	sshKeyPath  string
-	sshPort     int
-	sshUsername string
-	url         string
-	state       state.State
+	calls        *CallRecorder
+	capabilities []Capability
+	driverName   string
+	flags        []mcnflag.Flag
+	ip           string
+	machineName  string
+	sshHostname  string
+	sshKeyPath   string
+	sshPort      int
+	sshUsername  string
+	url          string
+	state        state.State
+}
+
+func (d *MockDriver) Capabilities() []Capability {
+	d.calls.record("Capabilities")
+	return d.capabilities
 }
 
 func (d *MockDriver) Create() error {
@@ -132,6 +138,16 @@ func (d *MockDriver) Stop() error {
 	return nil
 }
 
+func TestSerialDriverCapabilities(t *testing.T) {
+	callRecorder := &CallRecorder{}
+
+	driver := newSerialDriverWithLock(&MockDriver{capabilities: DefaultCapabilities, calls: callRecorder}, &MockLocker{calls: callRecorder})
+	capabilities := driver.Capabilities()
+
+	assert.Equal(t, DefaultCapabilities, capabilities)
+	assert.Equal(t, []string{"Lock", "Capabilities", "Unlock"}, callRecorder.calls)
+}
+
 func TestSerialDriverCreate(t *testing.T) {
 	callRecorder := &CallRecorder{}
 
@@ -0,0 +1,134 @@
+package drivers
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Consistency is how closely the view of a shared folder in the machine
+// follows the host, traded against speed. Drivers honour it when their
+// hypervisor offers several caching modes, and ignore it otherwise.
+type Consistency string
+
+const (
+	// ConsistencyConsistent keeps the machine and the host in sync.
+	ConsistencyConsistent Consistency = "consistent"
+	// ConsistencyCached lets the machine cache what it reads from the host.
+	ConsistencyCached Consistency = "cached"
+	// ConsistencyDelegated lets the machine cache its writes too.
+	ConsistencyDelegated Consistency = "delegated"
+)
+
+var reWindowsDrive = regexp.MustCompile(`^[A-Za-z]:[\\/]`)
+
+// SharedFolder is a directory of the host mounted in the machine. Name is
+// the name of the share on the hypervisor side, derived from GuestPath.
+type SharedFolder struct {
+	Name        string
+	HostPath    string
+	GuestPath   string
+	ReadOnly    bool        `json:",omitempty"`
+	Consistency Consistency `json:",omitempty"`
+}
+
+// ParseSharedFolder parses a shared folder written
+// host-path:guest-path[:option,...], the options being ro, rw, consistent,
+// cached and delegated. The host path may start with a Windows drive.
+func ParseSharedFolder(spec string) (SharedFolder, error) {
+	invalid := func(reason string) error {
+		return fmt.Errorf("invalid shared folder %q: %s", spec, reason)
+	}
+
+	drive, rest := "", spec
+	if reWindowsDrive.MatchString(spec) {
+		drive, rest = spec[:2], spec[2:]
+	}
+
+	parts := strings.Split(rest, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return SharedFolder{}, invalid("expected host-path:guest-path[:options]")
+	}
+
+	folder := SharedFolder{
+		HostPath:    drive + parts[0],
+		GuestPath:   path.Clean(parts[1]),
+		Consistency: ConsistencyConsistent,
+	}
+
+	if parts[0] == "" {
+		return SharedFolder{}, invalid("the host path is empty")
+	}
+	if !path.IsAbs(parts[1]) || folder.GuestPath == "/" {
+		return SharedFolder{}, invalid("the guest path must be an absolute path other than /")
+	}
+
+	if len(parts) == 3 {
+		for _, option := range strings.Split(parts[2], ",") {
+			switch option {
+			case "ro":
+				folder.ReadOnly = true
+			case "rw":
+				folder.ReadOnly = false
+			case string(ConsistencyConsistent), string(ConsistencyCached), string(ConsistencyDelegated):
+				folder.Consistency = Consistency(option)
+			default:
+				return SharedFolder{}, invalid(fmt.Sprintf("unknown option %q, expected ro, rw, consistent, cached or delegated", option))
+			}
+		}
+	}
+
+	folder.Name = strings.TrimPrefix(folder.GuestPath, "/")
+
+	return folder, nil
+}
+
+// ParseSharedFolders parses the shared folders given to a driver, which must
+// be mounted at different guest paths.
+func ParseSharedFolders(specs []string) ([]SharedFolder, error) {
+	folders := []SharedFolder{}
+	guestPaths := map[string]bool{}
+
+	for _, spec := range specs {
+		folder, err := ParseSharedFolder(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		if guestPaths[folder.GuestPath] {
+			return nil, fmt.Errorf("several folders are shared at %s", folder.GuestPath)
+		}
+		guestPaths[folder.GuestPath] = true
+
+		folders = append(folders, folder)
+	}
+
+	return folders, nil
+}
+
+// SharedFolderer is implemented by the drivers which support
+// CapabilitySharedFolders, i.e. those of local virtual machines.
+type SharedFolderer interface {
+	// SharedFolders returns the folders of the host shared with the
+	// machine, including the default one of the driver.
+	SharedFolders() ([]SharedFolder, error)
+}
+
+// AsSharedFolderer returns the driver as a SharedFolderer, or an
+// ErrCapabilityNotSupported error if it can't share folders.
+func AsSharedFolderer(d Driver) (SharedFolderer, error) {
+	if err := RequireCapability(d, CapabilitySharedFolders); err != nil {
+		return nil, err
+	}
+
+	folderer, ok := d.(SharedFolderer)
+	if !ok {
+		return nil, ErrCapabilityNotSupported{
+			DriverName: d.DriverName(),
+			Capability: CapabilitySharedFolders,
+		}
+	}
+
+	return folderer, nil
+}
@@ -0,0 +1,43 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSharedFolder(t *testing.T) {
+	for spec, expected := range map[string]SharedFolder{
+		"/Users/me/src:/src":                  {Name: "src", HostPath: "/Users/me/src", GuestPath: "/src", Consistency: ConsistencyConsistent},
+		"/data:/mnt/data/:ro":                 {Name: "mnt/data", HostPath: "/data", GuestPath: "/mnt/data", ReadOnly: true, Consistency: ConsistencyConsistent},
+		"/home/me/go:/go:cached":              {Name: "go", HostPath: "/home/me/go", GuestPath: "/go", Consistency: ConsistencyCached},
+		`C:\Users\me\src:/c/src:ro,delegated`: {Name: "c/src", HostPath: `C:\Users\me\src`, GuestPath: "/c/src", ReadOnly: true, Consistency: ConsistencyDelegated},
+	} {
+		folder, err := ParseSharedFolder(spec)
+		assert.NoError(t, err, spec)
+		assert.Equal(t, expected, folder, spec)
+	}
+
+	for _, spec := range []string{"", "/src", ":/src", "/src:src", "/src:/", "/src:/src:rw,fast", "/a:/b:ro:rw"} {
+		_, err := ParseSharedFolder(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestParseSharedFoldersRejectsSameGuestPath(t *testing.T) {
+	_, err := ParseSharedFolders([]string{"/a:/src", "/b:/src/"})
+
+	assert.EqualError(t, err, "several folders are shared at /src")
+}
+
+func TestAsSharedFoldererWithoutCapability(t *testing.T) {
+	d := &MockDriver{
+		calls:        &CallRecorder{},
+		capabilities: DefaultCapabilities,
+		driverName:   "generic",
+	}
+
+	_, err := AsSharedFolderer(d)
+
+	assert.Equal(t, ErrCapabilityNotSupported{DriverName: "generic", Capability: CapabilitySharedFolders}, err)
+}
@@ -0,0 +1,44 @@
+package drivers
+
+// Snapshot is a saved state of a machine which it can be restored to.
+type Snapshot struct {
+	Name string
+	ID   string
+	// Current is true for the snapshot the machine was last taken or
+	// restored from.
+	Current bool
+}
+
+// Snapshotter is implemented by the drivers which support
+// CapabilitySnapshot, i.e. those of local virtual machines.
+type Snapshotter interface {
+	// CreateSnapshot saves the current state of the machine as name.
+	CreateSnapshot(name string) error
+
+	// ListSnapshots returns the snapshots of the machine.
+	ListSnapshots() ([]Snapshot, error)
+
+	// RestoreSnapshot brings the stopped machine back to the snapshot name.
+	RestoreSnapshot(name string) error
+
+	// DeleteSnapshot removes the snapshot name, leaving the machine as is.
+	DeleteSnapshot(name string) error
+}
+
+// AsSnapshotter returns the driver as a Snapshotter, or an
+// ErrCapabilityNotSupported error if it can't take snapshots.
+func AsSnapshotter(d Driver) (Snapshotter, error) {
+	if err := RequireCapability(d, CapabilitySnapshot); err != nil {
+		return nil, err
+	}
+
+	snapshotter, ok := d.(Snapshotter)
+	if !ok {
+		return nil, ErrCapabilityNotSupported{
+			DriverName: d.DriverName(),
+			Capability: CapabilitySnapshot,
+		}
+	}
+
+	return snapshotter, nil
+}
@@ -0,0 +1,104 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type MockSnapshotter struct {
+	MockDriver
+	snapshots []Snapshot
+}
+
+func (d *MockSnapshotter) CreateSnapshot(name string) error {
+	d.calls.record("CreateSnapshot " + name)
+	return nil
+}
+
+func (d *MockSnapshotter) ListSnapshots() ([]Snapshot, error) {
+	d.calls.record("ListSnapshots")
+	return d.snapshots, nil
+}
+
+func (d *MockSnapshotter) RestoreSnapshot(name string) error {
+	d.calls.record("RestoreSnapshot " + name)
+	return nil
+}
+
+func (d *MockSnapshotter) DeleteSnapshot(name string) error {
+	d.calls.record("DeleteSnapshot " + name)
+	return nil
+}
+
+func TestAsSnapshotter(t *testing.T) {
+	d := &MockSnapshotter{
+		MockDriver: MockDriver{
+			calls:        &CallRecorder{},
+			capabilities: []Capability{CapabilitySnapshot},
+		},
+	}
+
+	snapshotter, err := AsSnapshotter(d)
+
+	assert.NoError(t, err)
+	assert.Equal(t, d, snapshotter)
+}
+
+func TestAsSnapshotterWithoutCapability(t *testing.T) {
+	d := &MockSnapshotter{
+		MockDriver: MockDriver{
+			calls:        &CallRecorder{},
+			capabilities: DefaultCapabilities,
+			driverName:   "virtualbox",
+		},
+	}
+
+	_, err := AsSnapshotter(d)
+
+	assert.Equal(t, ErrCapabilityNotSupported{DriverName: "virtualbox", Capability: CapabilitySnapshot}, err)
+}
+
+func TestAsSnapshotterWithoutImplementation(t *testing.T) {
+	d := &MockDriver{
+		calls:        &CallRecorder{},
+		capabilities: []Capability{CapabilitySnapshot},
+		driverName:   "broken",
+	}
+
+	_, err := AsSnapshotter(d)
+
+	assert.Equal(t, ErrCapabilityNotSupported{DriverName: "broken", Capability: CapabilitySnapshot}, err)
+}
+
+func TestSerialDriverCreateSnapshot(t *testing.T) {
+	callRecorder := &CallRecorder{}
+
+	driver := newSerialDriverWithLock(&MockSnapshotter{MockDriver: MockDriver{capabilities: []Capability{CapabilitySnapshot}, calls: callRecorder}}, &MockLocker{calls: callRecorder})
+	err := driver.(Snapshotter).CreateSnapshot("clean")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Lock", "Capabilities", "CreateSnapshot clean", "Unlock"}, callRecorder.calls)
+}
+
+func TestSerialDriverListSnapshots(t *testing.T) {
+	callRecorder := &CallRecorder{}
+	snapshots := []Snapshot{{Name: "clean", ID: "1", Current: true}}
+
+	driver := newSerialDriverWithLock(&MockSnapshotter{MockDriver: MockDriver{capabilities: []Capability{CapabilitySnapshot}, calls: callRecorder}, snapshots: snapshots}, &MockLocker{calls: callRecorder})
+	listed, err := driver.(Snapshotter).ListSnapshots()
+
+	assert.NoError(t, err)
+	assert.Equal(t, snapshots, listed)
+	assert.Equal(t, []string{"Lock", "Capabilities", "ListSnapshots", "Unlock"}, callRecorder.calls)
+}
+
+func TestSerialDriverSnapshotsNotSupported(t *testing.T) {
+	callRecorder := &CallRecorder{}
+
+	driver := newSerialDriverWithLock(&MockDriver{capabilities: DefaultCapabilities, driverName: "none", calls: callRecorder}, &MockLocker{calls: callRecorder})
+	err := driver.(Snapshotter).RestoreSnapshot("clean")
+
+	assert.Equal(t, ErrCapabilityNotSupported{DriverName: "none", Capability: CapabilitySnapshot}, err)
+	assert.Equal(t, []string{"Lock", "Capabilities", "DriverName", "Unlock"}, callRecorder.calls)
+}
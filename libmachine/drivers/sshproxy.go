@@ -0,0 +1,40 @@
+package drivers
+
+// SSHProxyJumpFlag is the flag of create giving the bastion through which
+// the machine is reached with SSH.
+const SSHProxyJumpFlag = "ssh-proxy-jump"
+
+// SSHProxyJumper is implemented by the drivers whose machines can be reached
+// with SSH through a bastion, all those embedding BaseDriver.
+type SSHProxyJumper interface {
+	// GetSSHProxyJump returns the bastion, [user@]host[:port], or "" if the
+	// machine is reached directly.
+	GetSSHProxyJump() string
+}
+
+// GetSSHProxyJump returns the bastion through which the machine of the driver
+// is reached with SSH, or "" if there's none.
+func GetSSHProxyJump(d Driver) string {
+	jumper, ok := d.(SSHProxyJumper)
+	if !ok {
+		return ""
+	}
+
+	return jumper.GetSSHProxyJump()
+}
+
+// SetSSHProxyJumpFromFlags sets the bastion given to create with
+// SSHProxyJumpFlag, which takes precedence over the one of the flags of the
+// driver.
+func SetSSHProxyJumpFromFlags(d Driver, flags DriverOptions) {
+	jumper, ok := d.(interface {
+		SetSSHProxyJump(jump string)
+	})
+	if !ok {
+		return
+	}
+
+	if jump := flags.String(SSHProxyJumpFlag); jump != "" {
+		jumper.SetSSHProxyJump(jump)
+	}
+}
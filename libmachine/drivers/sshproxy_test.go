@@ -0,0 +1,41 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// proxyJumpDriver is a driver whose machine can be reached through a
+// bastion.
+type proxyJumpDriver struct {
+	*MockDriver
+	jump string
+}
+
+func (d *proxyJumpDriver) GetSSHProxyJump() string {
+	return d.jump
+}
+
+func (d *proxyJumpDriver) SetSSHProxyJump(jump string) {
+	d.jump = jump
+}
+
+func TestSetSSHProxyJumpFromFlags(t *testing.T) {
+	d := &proxyJumpDriver{
+		MockDriver: &MockDriver{calls: &CallRecorder{}},
+		jump:       "ec2-user@bastion.example.com",
+	}
+
+	SetSSHProxyJumpFromFlags(d, &CheckDriverOptions{FlagsValues: map[string]interface{}{}})
+	assert.Equal(t, "ec2-user@bastion.example.com", GetSSHProxyJump(d))
+
+	SetSSHProxyJumpFromFlags(d, &CheckDriverOptions{FlagsValues: map[string]interface{}{
+		SSHProxyJumpFlag: "jump@10.0.0.1:2222",
+	}})
+	assert.Equal(t, "jump@10.0.0.1:2222", GetSSHProxyJump(d))
+}
+
+func TestGetSSHProxyJumpWithoutBastion(t *testing.T) {
+	assert.Equal(t, "", GetSSHProxyJump(&MockDriver{calls: &CallRecorder{}}))
+}
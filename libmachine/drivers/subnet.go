@@ -0,0 +1,20 @@
+package drivers
+
+// Subnetter is implemented by the drivers which know the network their
+// machine is reached on, such as the host-only network of a local VM.
+type Subnetter interface {
+	// GetSubnet returns the network, e.g. 192.168.99.0/24, or "" if it
+	// isn't known.
+	GetSubnet() (string, error)
+}
+
+// GetSubnet returns the network the machine of the driver is reached on, or
+// "" if the driver doesn't know it.
+func GetSubnet(d Driver) (string, error) {
+	subnetter, ok := d.(Subnetter)
+	if !ok {
+		return "", nil
+	}
+
+	return subnetter.GetSubnet()
+}
@@ -0,0 +1,116 @@
+package drivers
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+
+	"github.com/docker/machine/libmachine/mcnflag"
+)
+
+// userDataBoundary separates the parts of merged user data.
+const userDataBoundary = "==docker-machine-user-data=="
+
+// UserDataFlag is the create flag of the cloud drivers which pass a
+// cloud-init script to the machines they create.
+var UserDataFlag = mcnflag.StringFlag{
+	Name:   "user-data",
+	Usage:  "Path to a cloud-init script (#cloud-config, shell script...) run when the machine first boots",
+	EnvVar: "MACHINE_USER_DATA",
+}
+
+// userDataContentTypes maps the first line of the cloud-init formats which
+// can be merged to their MIME type.
+var userDataContentTypes = []struct {
+	prefix      string
+	contentType string
+}{
+	{"#cloud-config", "text/cloud-config"},
+	{"#cloud-boothook", "text/cloud-boothook"},
+	{"#include", "text/x-include-url"},
+	{"#upstart-job", "text/upstart-job"},
+	{"#!", "text/x-shellscript"},
+}
+
+// ReadUserData reads the cloud-init script at path, if any, and merges it
+// with the bootstrap scripts of the driver. It returns an empty string if
+// there's nothing to pass to the machine.
+func ReadUserData(path string, bootstrap ...string) (string, error) {
+	parts := []string{}
+	for _, part := range bootstrap {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("Error reading user data: %s", err)
+		}
+		parts = append(parts, string(data))
+	}
+
+	return MergeUserData(parts...)
+}
+
+// MergeUserData combines cloud-init scripts into a MIME multi-part archive,
+// which cloud-init runs part by part. A single script is returned as is.
+func MergeUserData(parts ...string) (string, error) {
+	switch len(parts) {
+	case 0:
+		return "", nil
+	case 1:
+		return parts[0], nil
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%q\r\nMIME-Version: 1.0\r\n\r\n", userDataBoundary)
+
+	w := multipart.NewWriter(&buf)
+	if err := w.SetBoundary(userDataBoundary); err != nil {
+		return "", err
+	}
+
+	for i, part := range parts {
+		contentType, err := userDataContentType(part)
+		if err != nil {
+			return "", err
+		}
+
+		if strings.Contains(part, userDataBoundary) {
+			return "", fmt.Errorf("User data can't contain %s", userDataBoundary)
+		}
+
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", contentType+`; charset="us-ascii"`)
+		header.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="part-%03d"`, i+1))
+
+		pw, err := w.CreatePart(header)
+		if err != nil {
+			return "", err
+		}
+		if _, err := pw.Write([]byte(part)); err != nil {
+			return "", err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func userDataContentType(part string) (string, error) {
+	for _, t := range userDataContentTypes {
+		if strings.HasPrefix(part, t.prefix) {
+			return t.contentType, nil
+		}
+	}
+
+	return "", fmt.Errorf("Unsupported user data format, it must start with #cloud-config, #cloud-boothook, #include, #upstart-job or #! to be merged")
+}
@@ -0,0 +1,92 @@
+package drivers
+
+import (
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeUserDataSinglePart(t *testing.T) {
+	merged, err := MergeUserData("anything goes")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "anything goes", merged)
+}
+
+func TestMergeUserData(t *testing.T) {
+	bootstrap := "#cloud-config\nmanage_etc_hosts: true\n"
+	script := "#!/bin/sh\necho hello\n"
+
+	merged, err := MergeUserData(bootstrap, script)
+	assert.NoError(t, err)
+
+	header, body := splitMessage(merged)
+	mediaType, params, err := mime.ParseMediaType(header)
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/mixed", mediaType)
+
+	r := multipart.NewReader(strings.NewReader(body), params["boundary"])
+
+	part, err := r.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, `text/cloud-config; charset="us-ascii"`, part.Header.Get("Content-Type"))
+	content, _ := ioutil.ReadAll(part)
+	assert.Equal(t, bootstrap, string(content))
+
+	part, err = r.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, `text/x-shellscript; charset="us-ascii"`, part.Header.Get("Content-Type"))
+	content, _ = ioutil.ReadAll(part)
+	assert.Equal(t, script, string(content))
+}
+
+func TestMergeUserDataUnsupportedFormat(t *testing.T) {
+	_, err := MergeUserData("#cloud-config\n", "just text")
+
+	assert.Error(t, err)
+}
+
+func TestReadUserData(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "user-data")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("#!/bin/sh\n"), 0600))
+
+	userData, err := ReadUserData(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "#!/bin/sh\n", userData)
+
+	userData, err = ReadUserData("", "", "#cloud-config\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "#cloud-config\n", userData)
+
+	userData, err = ReadUserData("")
+	assert.NoError(t, err)
+	assert.Empty(t, userData)
+
+	_, err = ReadUserData(filepath.Join(tmpDir, "missing"))
+	assert.Error(t, err)
+}
+
+// splitMessage returns the Content-Type header and the body of a MIME
+// message.
+func splitMessage(message string) (string, string) {
+	parts := strings.SplitN(message, "\r\n\r\n", 2)
+	for _, line := range strings.Split(parts[0], "\r\n") {
+		if strings.HasPrefix(line, "Content-Type: ") {
+			return strings.TrimPrefix(line, "Content-Type: "), parts[1]
+		}
+	}
+
+	return "", parts[1]
+}
@@ -1,6 +1,7 @@
 package drivers
 
 import (
+	"bytes"
 	"fmt"
 
 	"github.com/docker/machine/libmachine/log"
@@ -20,7 +21,8 @@ func GetSSHClientFromDriver(d Driver) (ssh.Client, error) {
 	}
 
 	auth := &ssh.Auth{
-		Keys: []string{d.GetSSHKeyPath()},
+		Keys:      []string{d.GetSSHKeyPath()},
+		ProxyJump: GetSSHProxyJump(d),
 	}
 
 	client, err := ssh.NewClient(d.GetSSHUsername(), address, port, auth)
@@ -49,6 +51,35 @@ output  : %s
 	return output, nil
 }
 
+// RunSSHCommandWithInputFromDriver runs the command with input as its
+// standard input. Unlike the command, the input isn't logged: secrets are
+// given through it.
+func RunSSHCommandWithInputFromDriver(d Driver, command string, input []byte) (string, error) {
+	client, err := GetSSHClientFromDriver(d)
+	if err != nil {
+		return "", err
+	}
+
+	inputClient, ok := client.(ssh.InputClient)
+	if !ok {
+		return "", fmt.Errorf("The SSH client can't give input to the commands it runs")
+	}
+
+	log.Debugf("About to run SSH command with input:\n%s", command)
+
+	output, err := inputClient.OutputWithInput(command, bytes.NewReader(input))
+	log.Debugf("SSH cmd err, output: %v: %s", err, output)
+	if err != nil {
+		return "", fmt.Errorf(`Something went wrong running an SSH command!
+command : %s
+err     : %v
+output  : %s
+`, command, err, output)
+	}
+
+	return output, nil
+}
+
 func sshAvailableFunc(d Driver) func() bool {
 	return func() bool {
 		log.Debug("Getting to WaitForSSH function...")
@@ -1,5 +1,32 @@
 package engine
 
+import "strings"
+
+// InotifySysctls raises the inotify limits, which file watchers running in
+// development containers quickly exhaust.
+var InotifySysctls = []string{
+	"fs.inotify.max_user_watches=524288",
+	"fs.inotify.max_user_instances=512",
+}
+
+// The ways of installing the engine on the machines whose provisioner installs
+// it.
+const (
+	// InstallMethodPackage runs the install script at InstallURL, which
+	// installs the engine package of the distribution.
+	InstallMethodPackage = "package"
+	// InstallMethodStatic installs the static binaries of InstallVersion
+	// from StaticInstallURL.
+	InstallMethodStatic = "static"
+	// InstallMethodNone doesn't install the engine, for images which come
+	// with it.
+	InstallMethodNone = "none"
+)
+
+// StaticInstallURL is where the static binaries of the engine are downloaded
+// from, under a directory per architecture.
+const StaticInstallURL = "https://download.docker.com/linux/static/stable"
+
 type Options struct {
 	ArbitraryFlags   []string
 	DNS              []string `json:"Dns"`
@@ -14,4 +41,44 @@ type Options struct {
 	TLSVerify        bool `json:"TlsVerify"`
 	RegistryMirror   []string
 	InstallURL       string
+	// InstallURLSHA256 is the checksum the install script fetched from
+	// InstallURL must have to be run, if set.
+	InstallURLSHA256 string `json:",omitempty"`
+	// InstallMethod is one of the InstallMethod constants, the package
+	// method if empty.
+	InstallMethod string `json:",omitempty"`
+	// InstallVersion is the version of the engine installed by the static
+	// method, such as 17.03.2-ce.
+	InstallVersion string `json:",omitempty"`
+	CACerts        []string
+	Timezone       string
+	Sysctls        []string
+	// HTTPProxy, HTTPSProxy and NoProxy are the proxy settings of the
+	// machine, given to the engine and used while provisioning it.
+	HTTPProxy  string `json:",omitempty"`
+	HTTPSProxy string `json:",omitempty"`
+	NoProxy    string `json:",omitempty"`
+}
+
+// ProxyEnv returns the proxy settings as environment variables. Each is set
+// in upper and lower case since tools disagree on the one they read.
+func (o Options) ProxyEnv() []string {
+	env := []string{}
+	for _, v := range []struct{ name, value string }{
+		{"HTTP_PROXY", o.HTTPProxy},
+		{"HTTPS_PROXY", o.HTTPSProxy},
+		{"NO_PROXY", o.NoProxy},
+	} {
+		if v.value != "" {
+			env = append(env, v.name+"="+v.value, strings.ToLower(v.name)+"="+v.value)
+		}
+	}
+
+	return env
+}
+
+// Environment returns the environment variables of the engine: those in Env
+// followed by the proxy settings.
+func (o Options) Environment() []string {
+	return append(append([]string{}, o.Env...), o.ProxyEnv()...)
 }
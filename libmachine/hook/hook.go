@@ -0,0 +1,116 @@
+package hook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+// Event is a point in the life of a machine at which hooks are run.
+type Event string
+
+const (
+	PreCreate     Event = "pre-create"
+	PostProvision Event = "post-provision"
+	PreStop       Event = "pre-stop"
+	PostRemove    Event = "post-remove"
+)
+
+// Aborts reports whether a failing hook stops the operation it runs before.
+// Hooks run after an operation can't undo it, their failures are only
+// logged.
+func (e Event) Aborts() bool {
+	return e == PreCreate || e == PreStop
+}
+
+// Options are the hooks run for the events of a machine. Scripts are run
+// with the event as their argument and the payload on their standard input,
+// webhooks are sent the payload in a POST request.
+type Options struct {
+	Scripts  []string `json:",omitempty"`
+	Webhooks []string `json:",omitempty"`
+}
+
+// Global are the hooks run for every machine, on top of its own.
+var Global Options
+
+// webhookTimeout bounds how long a webhook may hold up the operation.
+const webhookTimeout = 30 * time.Second
+
+// Payload describes the event to the hooks.
+type Payload struct {
+	Event      Event
+	Name       string
+	DriverName string
+	IP         string `json:",omitempty"`
+	URL        string `json:",omitempty"`
+}
+
+// Run runs the global hooks then the ones in opts, which may be nil, and
+// returns the first error.
+func Run(opts *Options, payload Payload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	all := []*Options{&Global}
+	if opts != nil {
+		all = append(all, opts)
+	}
+
+	for _, o := range all {
+		for _, script := range o.Scripts {
+			log.Debugf("Running %s hook %s", payload.Event, script)
+			if err := runScript(script, payload, data); err != nil {
+				return fmt.Errorf("Error running %s hook %s: %s", payload.Event, script, err)
+			}
+		}
+
+		for _, url := range o.Webhooks {
+			log.Debugf("Sending %s hook to %s", payload.Event, url)
+			if err := postWebhook(url, data); err != nil {
+				return fmt.Errorf("Error sending %s hook to %s: %s", payload.Event, url, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func runScript(script string, payload Payload, data []byte) error {
+	cmd := exec.Command(script, string(payload.Event))
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"MACHINE_HOOK_EVENT="+string(payload.Event),
+		"MACHINE_NAME="+payload.Name,
+		"MACHINE_DRIVER="+payload.DriverName,
+		"MACHINE_IP="+payload.IP,
+	)
+
+	return cmd.Run()
+}
+
+func postWebhook(url string, data []byte) error {
+	client := &http.Client{Timeout: webhookTimeout}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
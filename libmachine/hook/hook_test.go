@@ -0,0 +1,75 @@
+package hook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunPostsPayloadToWebhooks(t *testing.T) {
+	var received []Payload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload Payload
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		received = append(received, payload)
+	}))
+	defer server.Close()
+
+	Global = Options{Webhooks: []string{server.URL + "/global"}}
+	defer func() { Global = Options{} }()
+
+	err := Run(&Options{Webhooks: []string{server.URL + "/host"}}, Payload{
+		Event:      PostProvision,
+		Name:       "test",
+		DriverName: "none",
+		IP:         "1.2.3.4",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(received))
+	assert.Equal(t, PostProvision, received[0].Event)
+	assert.Equal(t, "1.2.3.4", received[1].IP)
+}
+
+func TestRunFailsOnWebhookError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	err := Run(&Options{Webhooks: []string{server.URL}}, Payload{Event: PreCreate, Name: "test"})
+
+	assert.Error(t, err)
+}
+
+func TestRunScripts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machine-hook")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "out")
+	script := filepath.Join(dir, "hook.sh")
+	assert.NoError(t, ioutil.WriteFile(script, []byte("#!/bin/sh\necho \"$1 $MACHINE_NAME\" > "+out+"\n"), 0755))
+
+	assert.NoError(t, Run(&Options{Scripts: []string{script}}, Payload{Event: PreStop, Name: "test"}))
+
+	data, err := ioutil.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Equal(t, "pre-stop test\n", string(data))
+
+	assert.Error(t, Run(&Options{Scripts: []string{"/bin/false"}}, Payload{Event: PreStop, Name: "test"}))
+}
+
+func TestAborts(t *testing.T) {
+	assert.True(t, PreCreate.Aborts())
+	assert.True(t, PreStop.Aborts())
+	assert.False(t, PostProvision.Aborts())
+	assert.False(t, PostRemove.Aborts())
+}
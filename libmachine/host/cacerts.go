@@ -0,0 +1,61 @@
+package host
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/machine/libmachine/cert"
+)
+
+// caCertsDir is the directory of a machine keeping the extra CA
+// certificates of its engine.
+const caCertsDir = "ca-certs"
+
+// KeepCACerts copies the extra CA certificates of the engine to the
+// directory of the machine, for them to be installed again whenever it gets
+// provisioned, whatever happens to the files given.
+func (h *Host) KeepCACerts() error {
+	engineOptions := h.HostOptions.EngineOptions
+	if engineOptions == nil || len(engineOptions.CACerts) == 0 {
+		return nil
+	}
+
+	dir := filepath.Join(h.HostOptions.AuthOptions.StorePath, caCertsDir)
+	created := false
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		created = true
+	}
+
+	kept := []string{}
+	for i, caCert := range engineOptions.CACerts {
+		// Already kept when the creation was resumed.
+		if strings.HasPrefix(caCert, dir+string(filepath.Separator)) {
+			kept = append(kept, caCert)
+			continue
+		}
+
+		data, err := cert.ReadPEMCertificates(caCert)
+		if err == nil {
+			err = os.MkdirAll(dir, 0700)
+		}
+		if err == nil {
+			path := filepath.Join(dir, fmt.Sprintf("ca-%d.pem", i))
+			err = ioutil.WriteFile(path, data, 0644)
+			kept = append(kept, path)
+		}
+		if err != nil {
+			if created {
+				os.RemoveAll(dir)
+				os.Remove(h.HostOptions.AuthOptions.StorePath)
+			}
+			return err
+		}
+	}
+
+	engineOptions.CACerts = kept
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package host
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/cert"
+	"github.com/docker/machine/libmachine/engine"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeepCACerts(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	caCert := filepath.Join(tmpDir, "corp-ca.pem")
+	assert.NoError(t, cert.NewX509CertGenerator().GenerateCACertificate(caCert, filepath.Join(tmpDir, "corp-ca-key.pem"), "corp", 2048))
+
+	storePath := filepath.Join(tmpDir, "machines", "dev")
+	h := &Host{
+		HostOptions: &Options{
+			AuthOptions:   &auth.Options{StorePath: storePath},
+			EngineOptions: &engine.Options{CACerts: []string{caCert}},
+		},
+	}
+
+	assert.NoError(t, h.KeepCACerts())
+	kept := filepath.Join(storePath, "ca-certs", "ca-0.pem")
+	assert.Equal(t, []string{kept}, h.HostOptions.EngineOptions.CACerts)
+
+	// The copy is used once the original is gone, e.g. when resuming.
+	assert.NoError(t, os.Remove(caCert))
+	assert.NoError(t, h.KeepCACerts())
+	assert.Equal(t, []string{kept}, h.HostOptions.EngineOptions.CACerts)
+
+	_, err = cert.ReadPEMCertificates(kept)
+	assert.NoError(t, err)
+}
+
+func TestKeepCACertsInvalid(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	storePath := filepath.Join(tmpDir, "machines", "dev")
+	h := &Host{
+		HostOptions: &Options{
+			AuthOptions:   &auth.Options{StorePath: storePath},
+			EngineOptions: &engine.Options{CACerts: []string{filepath.Join(tmpDir, "missing.pem")}},
+		},
+	}
+
+	assert.Error(t, h.KeepCACerts())
+
+	// No directory is left behind for the machine to look like it exists.
+	_, err = os.Stat(storePath)
+	assert.True(t, os.IsNotExist(err))
+}
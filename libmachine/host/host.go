@@ -9,6 +9,8 @@ import (
 	"github.com/docker/machine/libmachine/auth"
 	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/engine"
+	"github.com/docker/machine/libmachine/hook"
+	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/mcnutils"
 	"github.com/docker/machine/libmachine/provision"
 	"github.com/docker/machine/libmachine/provision/pkgaction"
@@ -16,6 +18,7 @@ import (
 	"github.com/docker/machine/libmachine/ssh"
 	"github.com/docker/machine/libmachine/state"
 	"github.com/docker/machine/libmachine/swarm"
+	"github.com/docker/machine/libmachine/wireguard"
 )
 
 var (
@@ -31,6 +34,9 @@ type Host struct {
 	HostOptions   *Options
 	Name          string
 	RawDriver     []byte
+	// Revision counts the saves of the record, for the store to refuse to
+	// overwrite the changes saved by another command since it was loaded.
+	Revision int `json:",omitempty"`
 }
 
 type Options struct {
@@ -40,8 +46,42 @@ type Options struct {
 	EngineOptions *engine.Options
 	SwarmOptions  *swarm.Options
 	AuthOptions   *auth.Options
+	HookOptions   *hook.Options `json:",omitempty"`
+	// Unprovisioned is set when the machine was created without being
+	// provisioned, and cleared once it is. Records which predate it are
+	// provisioned.
+	Unprovisioned bool `json:",omitempty"`
+	// NFSShare mounts the shared folders of the driver over NFS, exported
+	// by the host, in place of the driver's own file sharing.
+	NFSShare bool `json:",omitempty"`
+	// CreateStep is the step the creation of the machine stopped at, empty
+	// once it is created.
+	CreateStep CreateStep `json:",omitempty"`
+	// WireGuardOptions join the machine to the WireGuard overlay between
+	// the machines, nil if it isn't part of it.
+	WireGuardOptions *wireguard.Options `json:",omitempty"`
+	// NoProxy are the hosts and networks env --no-proxy adds to NO_PROXY
+	// along with the machine IP.
+	NoProxy []string `json:",omitempty"`
+	// NoProxySubnet makes env --no-proxy add the network of the machine to
+	// NO_PROXY along with its IP.
+	NoProxySubnet bool `json:",omitempty"`
+	// Labels tag the machine with key=value pairs, for ls to filter the
+	// machines on.
+	Labels map[string]string `json:",omitempty"`
 }
 
+// CreateStep is a step of the creation of a machine, recorded in the store
+// before it is run so that a failed creation can be resumed there.
+type CreateStep string
+
+const (
+	// CreateStepDriver is the creation of the machine by the driver.
+	CreateStepDriver CreateStep = "driver"
+	// CreateStepProvision is the provisioning of the created machine.
+	CreateStepProvision CreateStep = "provision"
+)
+
 type Metadata struct {
 	ConfigVersion int
 	DriverName    string
@@ -68,7 +108,8 @@ func (h *Host) CreateSSHClient() (ssh.Client, error) {
 	}
 
 	auth := &ssh.Auth{
-		Keys: []string{h.Driver.GetSSHKeyPath()},
+		Keys:      []string{h.Driver.GetSSHKeyPath()},
+		ProxyJump: drivers.GetSSHProxyJump(h.Driver),
 	}
 
 	return ssh.NewClient(h.Driver.GetSSHUsername(), addr, port, auth)
@@ -87,18 +128,53 @@ func (h *Host) runActionForState(action func() error, desiredState state.State)
 }
 
 func (h *Host) Start() error {
+	if err := drivers.RequireCapability(h.Driver, drivers.CapabilityStart); err != nil {
+		return err
+	}
+
 	return h.runActionForState(h.Driver.Start, state.Running)
 }
 
 func (h *Host) Stop() error {
+	if err := drivers.RequireCapability(h.Driver, drivers.CapabilityStop); err != nil {
+		return err
+	}
+
+	if err := h.RunHook(hook.PreStop); err != nil {
+		return err
+	}
+
 	return h.runActionForState(h.Driver.Stop, state.Stopped)
 }
 
+// Kill stops the machine without letting it shut down. Drivers which can't
+// kill machines but can stop them get the hardest stop they support instead.
 func (h *Host) Kill() error {
+	if !drivers.HasCapability(h.Driver, drivers.CapabilityKill) && drivers.HasCapability(h.Driver, drivers.CapabilityStop) {
+		log.Warnf("Driver %s can't kill machines, stopping %s instead", h.Driver.DriverName(), h.Name)
+		return h.Stop()
+	}
+
+	if err := drivers.RequireCapability(h.Driver, drivers.CapabilityKill); err != nil {
+		return err
+	}
+
+	if err := h.RunHook(hook.PreStop); err != nil {
+		return err
+	}
+
 	return h.runActionForState(h.Driver.Kill, state.Stopped)
 }
 
 func (h *Host) Restart() error {
+	// Restarting is done by stopping and starting the machine, check both
+	// are supported before doing either.
+	for _, c := range []drivers.Capability{drivers.CapabilityStop, drivers.CapabilityStart} {
+		if err := drivers.RequireCapability(h.Driver, c); err != nil {
+			return err
+		}
+	}
+
 	if drivers.MachineInState(h.Driver, state.Running)() {
 		if err := h.Stop(); err != nil {
 			return err
@@ -130,6 +206,10 @@ func (h *Host) Upgrade() error {
 		return errMachineMustBeRunningForUpgrade
 	}
 
+	if version := h.PinnedEngineVersion(); version != "" {
+		return fmt.Errorf("Error: the engine of %s is pinned to version %s, upgrade it to another one with --engine-version.", h.Name, version)
+	}
+
 	provisioner, err := provision.DetectProvisioner(h.Driver)
 	if err != nil {
 		return err
@@ -145,10 +225,70 @@ func (h *Host) Upgrade() error {
 	return nil
 }
 
+// PinnedEngineVersion returns the version of the engine the machine is pinned
+// to, or "" if it isn't.
+func (h *Host) PinnedEngineVersion() string {
+	if h.HostOptions == nil || h.HostOptions.EngineOptions == nil {
+		return ""
+	}
+
+	return h.HostOptions.EngineOptions.InstallVersion
+}
+
+// UpgradeEngine installs the version of the engine on the running machine,
+// whether it's newer or older than the installed one, and pins the machine to
+// it.
+func (h *Host) UpgradeEngine(version string) error {
+	machineState, err := h.Driver.GetState()
+	if err != nil {
+		return err
+	}
+
+	if machineState != state.Running {
+		return errMachineMustBeRunningForUpgrade
+	}
+
+	provisioner, err := provision.DetectProvisioner(h.Driver)
+	if err != nil {
+		return err
+	}
+
+	engineOptions := *h.HostOptions.EngineOptions
+	engineOptions.InstallVersion = version
+
+	if engineOptions.InstallMethod == engine.InstallMethodStatic {
+		if err := provision.InstallStaticEngine(provisioner, engineOptions); err != nil {
+			return err
+		}
+	} else {
+		versioner, err := provision.AsEngineVersioner(provisioner)
+		if err != nil {
+			return err
+		}
+
+		if err := versioner.InstallEngineVersion(version); err != nil {
+			return err
+		}
+
+		if err := provisioner.Service("docker", serviceaction.Restart); err != nil {
+			return err
+		}
+	}
+
+	h.HostOptions.EngineOptions.InstallVersion = version
+
+	return nil
+}
+
 func (h *Host) GetURL() (string, error) {
 	return h.Driver.GetURL()
 }
 
+// IsProvisioned reports whether the machine went through provisioning.
+func (h *Host) IsProvisioned() bool {
+	return h.HostOptions == nil || !h.HostOptions.Unprovisioned
+}
+
 func (h *Host) ConfigureAuth() error {
 	provisioner, err := provision.DetectProvisioner(h.Driver)
 	if err != nil {
@@ -166,3 +306,35 @@ func (h *Host) ConfigureAuth() error {
 
 	return nil
 }
+
+// RunHook runs the hooks configured for event, globally and for the machine.
+// Only the hooks run before an operation can fail it, the failures of the
+// others are logged.
+func (h *Host) RunHook(event hook.Event) error {
+	payload := hook.Payload{
+		Event:      event,
+		Name:       h.Name,
+		DriverName: h.DriverName,
+	}
+
+	// The machine isn't there to ask before it's created or after it's
+	// removed.
+	if event == hook.PostProvision || event == hook.PreStop {
+		payload.IP, _ = h.Driver.GetIP()
+		payload.URL, _ = h.Driver.GetURL()
+	}
+
+	var opts *hook.Options
+	if h.HostOptions != nil {
+		opts = h.HostOptions.HookOptions
+	}
+
+	if err := hook.Run(opts, payload); err != nil {
+		if event.Aborts() {
+			return err
+		}
+		log.Warn(err)
+	}
+
+	return nil
+}
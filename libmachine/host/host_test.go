@@ -3,7 +3,12 @@ package host
 import (
 	"testing"
 
+	"github.com/docker/machine/drivers/fakedriver"
 	_ "github.com/docker/machine/drivers/none"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/hook"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestValidateHostnameValid(t *testing.T) {
@@ -35,3 +40,62 @@ func TestValidateHostnameInvalid(t *testing.T) {
 		}
 	}
 }
+
+func TestStartFailsFastWithoutCapability(t *testing.T) {
+	h := &Host{
+		Name: "test",
+		Driver: &fakedriver.Driver{
+			MockState:        state.Stopped,
+			MockCapabilities: []drivers.Capability{drivers.CapabilityStop},
+		},
+	}
+
+	assert.EqualError(t, h.Start(), "driver Driver doesn't support start")
+	assert.EqualError(t, h.Restart(), "driver Driver doesn't support start")
+}
+
+func TestKillFailsFastWithoutCapability(t *testing.T) {
+	h := &Host{
+		Name: "test",
+		Driver: &fakedriver.Driver{
+			MockState:        state.Running,
+			MockCapabilities: []drivers.Capability{},
+		},
+	}
+
+	assert.Equal(t, drivers.ErrCapabilityNotSupported{DriverName: "Driver", Capability: drivers.CapabilityKill}, h.Kill())
+}
+
+func TestKillFallsBackToStop(t *testing.T) {
+	h := &Host{
+		Name: "test",
+		Driver: &fakedriver.Driver{
+			MockState:        state.Running,
+			MockCapabilities: []drivers.Capability{drivers.CapabilityStop},
+		},
+	}
+
+	assert.NoError(t, h.Kill())
+	assert.Equal(t, state.Stopped, h.Driver.(*fakedriver.Driver).MockState)
+}
+
+func TestIsProvisioned(t *testing.T) {
+	assert.True(t, (&Host{}).IsProvisioned())
+	assert.True(t, (&Host{HostOptions: &Options{}}).IsProvisioned())
+	assert.False(t, (&Host{HostOptions: &Options{Unprovisioned: true}}).IsProvisioned())
+}
+
+func TestStopAbortedByFailingHook(t *testing.T) {
+	h := &Host{
+		Name: "test",
+		Driver: &fakedriver.Driver{
+			MockState: state.Running,
+		},
+		HostOptions: &Options{
+			HookOptions: &hook.Options{Scripts: []string{"/bin/false"}},
+		},
+	}
+
+	assert.Error(t, h.Stop())
+	assert.Equal(t, state.Running, h.Driver.(*fakedriver.Driver).MockState)
+}
@@ -0,0 +1,70 @@
+package host
+
+import (
+	"fmt"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+	"github.com/docker/machine/libmachine/nfs"
+	"github.com/docker/machine/libmachine/provision"
+)
+
+func (h *Host) usesNFSShares() bool {
+	return h.HostOptions != nil && h.HostOptions.NFSShare
+}
+
+// ConfigureNFSShares exports the shared folders of the driver from the host
+// over NFS and mounts them in the machine, over the driver's own mounts.
+// It does nothing unless the machine was created with NFSShare.
+func (h *Host) ConfigureNFSShares(commander provision.SSHCommander) error {
+	if !h.usesNFSShares() {
+		return nil
+	}
+
+	folderer, err := drivers.AsSharedFolderer(h.Driver)
+	if err != nil {
+		return err
+	}
+
+	folders, err := folderer.SharedFolders()
+	if err != nil {
+		return fmt.Errorf("Error getting the shared folders: %s", err)
+	}
+
+	ip, err := h.Driver.GetIP()
+	if err != nil {
+		return fmt.Errorf("Error getting the IP address of the machine: %s", err)
+	}
+
+	hostIP, err := mcnutils.HostIPFor(ip)
+	if err != nil {
+		return fmt.Errorf("Error finding the address of the host: %s", err)
+	}
+
+	if err := nfs.UpdateExports(h.Name, ip, folders); err != nil {
+		return fmt.Errorf("Error exporting the shared folders: %s", err)
+	}
+
+	for _, folder := range folders {
+		log.Infof("Mounting %s at %s over NFS...", folder.HostPath, folder.GuestPath)
+		if output, err := commander.SSHCommand(nfs.MountCommand(hostIP, folder)); err != nil {
+			return fmt.Errorf("Error mounting %s: %s %s", folder.GuestPath, err, output)
+		}
+
+		if output, err := commander.SSHCommand(nfs.PersistCommand(hostIP, folder)); err != nil {
+			return fmt.Errorf("Error mounting %s at boot: %s %s", folder.GuestPath, err, output)
+		}
+	}
+
+	return nil
+}
+
+// RemoveNFSShares removes the NFS exports of the machine from the host.
+func (h *Host) RemoveNFSShares() error {
+	if !h.usesNFSShares() {
+		return nil
+	}
+
+	return nfs.RemoveExports(h.Name)
+}
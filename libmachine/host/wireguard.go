@@ -0,0 +1,81 @@
+package host
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/provision"
+	"github.com/docker/machine/libmachine/provision/pkgaction"
+	"github.com/docker/machine/libmachine/wireguard"
+)
+
+// UsesWireGuard tells whether the machine is part of the WireGuard overlay.
+func (h *Host) UsesWireGuard() bool {
+	return h.HostOptions != nil && h.HostOptions.WireGuardOptions != nil
+}
+
+// SetWireGuardEndpoint records where the other machines of the overlay reach
+// the machine, on its IP address. It tells whether the endpoint changed.
+func (h *Host) SetWireGuardEndpoint() (bool, error) {
+	opts := h.HostOptions.WireGuardOptions
+
+	ip, err := h.Driver.GetIP()
+	if err != nil {
+		return false, fmt.Errorf("Error getting the IP address of %s: %s", h.Name, err)
+	}
+
+	endpoint := net.JoinHostPort(ip, strconv.Itoa(opts.ListenPort))
+	if endpoint == opts.Endpoint {
+		return false, nil
+	}
+
+	opts.Endpoint = endpoint
+	return true, nil
+}
+
+// InstallWireGuard installs the WireGuard tools on the machine with its
+// package manager, unless they're already there.
+func (h *Host) InstallWireGuard(provisioner provision.Provisioner) error {
+	if _, err := provisioner.SSHCommand(wireguard.CheckCommand); err == nil {
+		return nil
+	}
+
+	log.Infof("Installing WireGuard on %s...", h.Name)
+
+	if err := provisioner.Package(wireguard.PackageName, pkgaction.Install); err != nil {
+		return fmt.Errorf("Error installing WireGuard on %s: %s", h.Name, err)
+	}
+
+	// Some provisioners, e.g. boot2docker's, have no package manager and
+	// install nothing.
+	if _, err := provisioner.SSHCommand(wireguard.CheckCommand); err != nil {
+		osName := "its OS"
+		if info, err := provisioner.GetOsReleaseInfo(); err == nil && info.PrettyName != "" {
+			osName = info.PrettyName
+		}
+		return fmt.Errorf("Error installing WireGuard on %s: %s can't be installed on %s", h.Name, wireguard.PackageName, osName)
+	}
+
+	return nil
+}
+
+// ApplyWireGuard brings the overlay of the machine up with the peers. The
+// configuration, which holds the private key, is given to the machine on the
+// standard input of the SSH command.
+func (h *Host) ApplyWireGuard(peers []wireguard.Peer) error {
+	config, err := wireguard.Config(*h.HostOptions.WireGuardOptions, peers)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Configuring the WireGuard overlay of %s with %d peer(s)...", h.Name, len(peers))
+
+	if output, err := drivers.RunSSHCommandWithInputFromDriver(h.Driver, wireguard.ApplyCommand, []byte(config)); err != nil {
+		return fmt.Errorf("Error configuring WireGuard on %s: %s %s", h.Name, err, output)
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+// Package keychain keeps secrets in the keychain of the OS: the login
+// keychain on OS X, the Secret Service keyring, such as the GNOME Keyring or
+// KWallet, on Linux, and the Credential Manager on Windows. A secret is
+// identified by a service and an account.
+package keychain
+
+import (
+	"bufio"
+	"errors"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned when the keychain has no such secret.
+	ErrNotFound = errors.New("Error: no such secret in the keychain")
+
+	// ErrUnsupported is returned on the platforms without a supported
+	// keychain.
+	ErrUnsupported = errors.New("Error: no supported keychain on this platform")
+)
+
+// IsUnavailable tells whether the error is there being no keychain to use,
+// on the platforms without one or when its tool isn't installed, rather than
+// a failure of the keychain, e.g. when it's locked.
+func IsUnavailable(err error) bool {
+	if err == ErrUnsupported {
+		return true
+	}
+
+	execErr, ok := err.(*exec.Error)
+
+	return ok && execErr.Err == exec.ErrNotFound
+}
+
+// Get returns the secret of the account of the service.
+func Get(service, account string) (string, error) {
+	return get(service, account)
+}
+
+// Set stores the secret of the account of the service, replacing the one
+// stored before. The label describes it to the users of the keychain.
+func Set(service, account, label, secret string) error {
+	return set(service, account, label, secret)
+}
+
+// Delete deletes the secret of the account of the service.
+func Delete(service, account string) error {
+	return del(service, account)
+}
+
+// List returns the accounts of the service with a secret, sorted.
+func List(service string) ([]string, error) {
+	accounts, err := list(service)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(accounts)
+
+	return accounts, nil
+}
+
+// interactiveCommand returns the command line of security in interactive
+// mode, its arguments double quoted. A line holds one command, so that they
+// can't have a newline.
+func interactiveCommand(args ...string) (string, error) {
+	quoted := []string{}
+	for _, arg := range args {
+		if strings.ContainsAny(arg, "\r\n") {
+			return "", errors.New("Error: the secrets of the keychain can't have a newline")
+		}
+
+		arg = strings.Replace(arg, `\`, `\\`, -1)
+		arg = strings.Replace(arg, `"`, `\"`, -1)
+		quoted = append(quoted, `"`+arg+`"`)
+	}
+
+	return strings.Join(quoted, " ") + "\n", nil
+}
+
+// parseDump returns the accounts of the service in the output of security
+// dump-keychain, whose items list their attributes such as
+//
+//	"acct"<blob>="name"
+//	"svce"<blob>="service"
+func parseDump(dump, service string) []string {
+	accounts := []string{}
+
+	var account, itemService string
+	add := func() {
+		if itemService == service && account != "" {
+			accounts = append(accounts, account)
+		}
+		account, itemService = "", ""
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(dump))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case strings.HasPrefix(line, "keychain:"):
+			add()
+		case strings.HasPrefix(line, `"acct"<blob>=`):
+			account = unquoteAttribute(strings.TrimPrefix(line, `"acct"<blob>=`))
+		case strings.HasPrefix(line, `"svce"<blob>=`):
+			itemService = unquoteAttribute(strings.TrimPrefix(line, `"svce"<blob>=`))
+		}
+	}
+	add()
+
+	return accounts
+}
+
+func unquoteAttribute(value string) string {
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		return value[1 : len(value)-1]
+	}
+
+	return ""
+}
+
+// parseSearch returns the accounts in the output of secret-tool search,
+// whose items list their attributes such as
+//
+//	attribute.account = name
+func parseSearch(output string) []string {
+	accounts := []string{}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "attribute.account = ") {
+			accounts = append(accounts, strings.TrimPrefix(line, "attribute.account = "))
+		}
+	}
+
+	return accounts
+}
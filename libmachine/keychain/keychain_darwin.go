@@ -0,0 +1,78 @@
+package keychain
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// errItemNotFound is the exit status of security when there's no such item.
+const errItemNotFound = 44
+
+func isNotFound(err error) bool {
+	exitErr, ok := err.(*exec.ExitError)
+
+	if !ok {
+		return false
+	}
+
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+
+	return ok && status.ExitStatus() == errItemNotFound
+}
+
+func get(service, account string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w").Output()
+	if isNotFound(err) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// set gives the command to security on its standard input, in interactive
+// mode, so that the secret isn't in its arguments, which the other users can
+// read. security goes on after a failed command in this mode and exits with
+// 0, it only reports the error on its output.
+func set(service, account, label, secret string) error {
+	command, err := interactiveCommand("add-generic-password", "-U", "-s", service, "-a", account, "-l", label, "-w", secret)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(command)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return err
+	}
+
+	if output := strings.TrimSpace(string(out)); output != "" {
+		return fmt.Errorf("Error storing the secret in the keychain: %s", output)
+	}
+
+	return nil
+}
+
+func del(service, account string) error {
+	err := exec.Command("security", "delete-generic-password", "-s", service, "-a", account).Run()
+	if isNotFound(err) {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+func list(service string) ([]string, error) {
+	out, err := exec.Command("security", "dump-keychain").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseDump(string(out), service), nil
+}
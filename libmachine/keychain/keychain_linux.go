@@ -0,0 +1,55 @@
+package keychain
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// get tells a missing secret from a failure of secret-tool, e.g. when there's
+// no Secret Service or the keyring is locked, by its error message: it exits
+// with 1 in both cases, but only explains failures.
+func get(service, account string) (string, error) {
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command("secret-tool", "lookup", "service", service, "account", account)
+	cmd.Stderr = stderr
+
+	out, err := cmd.Output()
+	if _, ok := err.(*exec.ExitError); ok {
+		if len(out) == 0 && strings.TrimSpace(stderr.String()) == "" {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("Error reading the secret from the keychain: %s", strings.TrimSpace(stderr.String()))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+func set(service, account, label, secret string) error {
+	cmd := exec.Command("secret-tool", "store", "--label", label, "service", service, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+
+	return cmd.Run()
+}
+
+func del(service, account string) error {
+	if _, err := get(service, account); err != nil {
+		return err
+	}
+
+	return exec.Command("secret-tool", "clear", "service", service, "account", account).Run()
+}
+
+func list(service string) ([]string, error) {
+	// Older versions of secret-tool write the attributes to stderr.
+	out, err := exec.Command("secret-tool", "search", "--all", "service", service).CombinedOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSearch(string(out)), nil
+}
@@ -0,0 +1,20 @@
+//go:build !darwin && !linux && !windows
+// +build !darwin,!linux,!windows
+
+package keychain
+
+func get(service, account string) (string, error) {
+	return "", ErrUnsupported
+}
+
+func set(service, account, label, secret string) error {
+	return ErrUnsupported
+}
+
+func del(service, account string) error {
+	return ErrUnsupported
+}
+
+func list(service string) ([]string, error) {
+	return nil, ErrUnsupported
+}
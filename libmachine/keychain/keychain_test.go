@@ -0,0 +1,80 @@
+package keychain
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDump(t *testing.T) {
+	dump := `keychain: "/Users/user/Library/Keychains/login.keychain-db"
+version: 512
+class: "genp"
+attributes:
+    0x00000007 <blob>="Docker Machine credential do"
+    "acct"<blob>="do"
+    "svce"<blob>="docker-machine-credential"
+keychain: "/Users/user/Library/Keychains/login.keychain-db"
+version: 512
+class: "genp"
+attributes:
+    "acct"<blob>="someone"
+    "svce"<blob>="another-service"
+keychain: "/Users/user/Library/Keychains/login.keychain-db"
+version: 512
+class: "genp"
+attributes:
+    "acct"<blob>=<NULL>
+    "svce"<blob>="docker-machine-credential"
+keychain: "/Users/user/Library/Keychains/login.keychain-db"
+version: 512
+class: "genp"
+attributes:
+    "acct"<blob>="aws-prod"
+    "svce"<blob>="docker-machine-credential"
+`
+
+	assert.Equal(t, []string{"do", "aws-prod"}, parseDump(dump, "docker-machine-credential"))
+	assert.Empty(t, parseDump("", "docker-machine-credential"))
+}
+
+func TestParseSearch(t *testing.T) {
+	output := `[/org/freedesktop/secrets/collection/login/12]
+label = Docker Machine credential do
+secret = tok-123
+created = 2016-01-02 10:00:00
+modified = 2016-01-02 10:00:00
+schema = org.freedesktop.Secret.Generic
+attribute.account = do
+attribute.service = docker-machine-credential
+[/org/freedesktop/secrets/collection/login/13]
+label = Docker Machine credential aws-prod
+attribute.account = aws-prod
+attribute.service = docker-machine-credential
+`
+
+	assert.Equal(t, []string{"do", "aws-prod"}, parseSearch(output))
+	assert.Empty(t, parseSearch(""))
+}
+
+func TestInteractiveCommand(t *testing.T) {
+	command, err := interactiveCommand("add-generic-password", "-l", `Docker Machine "do"`, "-w", `s3cr\et`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `"add-generic-password" "-l" "Docker Machine \"do\"" "-w" "s3cr\\et"`+"\n", command)
+
+	_, err = interactiveCommand("-w", "line\nbreak")
+
+	assert.Error(t, err)
+}
+
+func TestIsUnavailable(t *testing.T) {
+	_, err := exec.Command("docker-machine-no-such-tool").Output()
+
+	assert.True(t, IsUnavailable(err))
+	assert.True(t, IsUnavailable(ErrUnsupported))
+	assert.False(t, IsUnavailable(ErrNotFound))
+	assert.False(t, IsUnavailable(errors.New("the keyring is locked")))
+}
@@ -0,0 +1,168 @@
+package keychain
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	errorNotFound           = syscall.Errno(1168)
+)
+
+var (
+	advapi32           = syscall.NewLazyDLL("advapi32.dll")
+	procCredRead       = advapi32.NewProc("CredReadW")
+	procCredWrite      = advapi32.NewProc("CredWriteW")
+	procCredDelete     = advapi32.NewProc("CredDeleteW")
+	procCredEnumerate  = advapi32.NewProc("CredEnumerateW")
+	procCredFree       = advapi32.NewProc("CredFree")
+	maxCredentialCount = 1 << 16
+)
+
+// credential is the CREDENTIALW of the Credential Manager.
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// target is the name of the credential of the account of the service.
+func target(service, account string) string {
+	return service + ":" + account
+}
+
+func utf16PtrToString(p *uint16) string {
+	if p == nil {
+		return ""
+	}
+
+	s := []uint16{}
+	for ptr := unsafe.Pointer(p); *(*uint16)(ptr) != 0; ptr = unsafe.Pointer(uintptr(ptr) + 2) {
+		s = append(s, *(*uint16)(ptr))
+	}
+
+	return syscall.UTF16ToString(s)
+}
+
+func callError(err error) error {
+	if err == errorNotFound {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+func get(service, account string) (string, error) {
+	name, err := syscall.UTF16PtrFromString(target(service, account))
+	if err != nil {
+		return "", err
+	}
+
+	var cred *credential
+	if r, _, err := procCredRead.Call(uintptr(unsafe.Pointer(name)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred))); r == 0 {
+		return "", callError(err)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	if cred.CredentialBlobSize == 0 {
+		return "", nil
+	}
+
+	blob := (*[1 << 20]byte)(unsafe.Pointer(cred.CredentialBlob))[:cred.CredentialBlobSize:cred.CredentialBlobSize]
+
+	return string(blob), nil
+}
+
+func set(service, account, label, secret string) error {
+	name, err := syscall.UTF16PtrFromString(target(service, account))
+	if err != nil {
+		return err
+	}
+
+	comment, err := syscall.UTF16PtrFromString(label)
+	if err != nil {
+		return err
+	}
+
+	user, err := syscall.UTF16PtrFromString(account)
+	if err != nil {
+		return err
+	}
+
+	blob := []byte(secret)
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         name,
+		Comment:            comment,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+
+	if r, _, err := procCredWrite.Call(uintptr(unsafe.Pointer(&cred)), 0); r == 0 {
+		return err
+	}
+
+	return nil
+}
+
+func del(service, account string) error {
+	name, err := syscall.UTF16PtrFromString(target(service, account))
+	if err != nil {
+		return err
+	}
+
+	if r, _, err := procCredDelete.Call(uintptr(unsafe.Pointer(name)), credTypeGeneric, 0); r == 0 {
+		return callError(err)
+	}
+
+	return nil
+}
+
+func list(service string) ([]string, error) {
+	filter, err := syscall.UTF16PtrFromString(target(service, "*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		count uint32
+		creds *[1 << 16]*credential
+	)
+	if r, _, err := procCredEnumerate.Call(uintptr(unsafe.Pointer(filter)), 0, uintptr(unsafe.Pointer(&count)), uintptr(unsafe.Pointer(&creds))); r == 0 {
+		if err == errorNotFound {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(creds)))
+
+	if int(count) > maxCredentialCount {
+		count = uint32(maxCredentialCount)
+	}
+
+	accounts := []string{}
+	prefix := target(service, "")
+	for _, cred := range creds[:count:count] {
+		name := utf16PtrToString(cred.TargetName)
+		if len(name) > len(prefix) && name[:len(prefix)] == prefix {
+			accounts = append(accounts, name[len(prefix):])
+		}
+	}
+
+	return accounts, nil
+}
@@ -1,19 +1,24 @@
 package libmachine
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 
 	"github.com/docker/machine/libmachine/cert"
 	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/hook"
 	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnerror"
 	"github.com/docker/machine/libmachine/mcnutils"
 	"github.com/docker/machine/libmachine/persist"
 	"github.com/docker/machine/libmachine/provision"
 	"github.com/docker/machine/libmachine/state"
 )
 
+var errMachineMustBeRunningToProvision = errors.New("Error: machine must be running to be provisioned.")
+
 func GetDefaultStore() *persist.Filestore {
 	homeDir := mcnutils.GetHomeDir()
 	certsDir := filepath.Join(homeDir, ".docker", "machine", "certs")
@@ -31,53 +36,251 @@ func Create(store persist.Store, h *host.Host) error {
 		return fmt.Errorf("Error generating certificates: %s", err)
 	}
 
-	log.Info("Running pre-create checks...")
+	if err := h.RunHook(hook.PreCreate); err != nil {
+		return err
+	}
+
+	log.Progress(h.Name, "pre-create-check", 0, "Running pre-create checks...")
 
 	if err := h.Driver.PreCreateCheck(); err != nil {
 		return fmt.Errorf("Error with pre-create check: %s", err)
 	}
 
-	if err := store.Save(h); err != nil {
+	if err := h.KeepCACerts(); err != nil {
+		return fmt.Errorf("Error copying the CA certificates: %s", err)
+	}
+
+	h.HostOptions.CreateStep = host.CreateStepDriver
+	if err := saveNewHost(store, h); err != nil {
 		return fmt.Errorf("Error saving host to store before attempting creation: %s", err)
 	}
 
-	log.Info("Creating machine...")
+	log.Progress(h.Name, "create", 10, "Creating machine...")
+
+	if err := createMachine(store, h); err != nil {
+		return err
+	}
+
+	return provisionMachine(store, h)
+}
+
+// Resume goes on with the creation of a machine from the step it stopped at.
+func Resume(store persist.Store, h *host.Host) error {
+	step := h.HostOptions.CreateStep
+	if step == "" {
+		return fmt.Errorf("%s was created, there is nothing to resume", h.Name)
+	}
+
+	if err := cert.BootstrapCertificates(h.HostOptions.AuthOptions); err != nil {
+		return fmt.Errorf("Error generating certificates: %s", err)
+	}
+
+	// The machine is only created again when it's gone for sure: failing to
+	// get its state, e.g. because of the API of the provider, would create
+	// it twice.
+	s, err := h.Driver.GetState()
+	if err != nil && !mcnerror.IsMachineNotExist(err) {
+		return fmt.Errorf("Error getting the state of %s, not resuming its creation: %s", h.Name, err)
+	}
+	exists := err == nil
+
+	switch {
+	case step == host.CreateStepDriver && !exists:
+		log.Progress(h.Name, "create", 10, "Creating machine again...")
+		if err := createMachine(store, h); err != nil {
+			return err
+		}
+	case exists && s != state.Running:
+		// The driver may have failed once the machine existed, e.g. waiting
+		// for it to get an IP address, or the machine was stopped since.
+		log.Progress(h.Name, "start", 10, fmt.Sprintf("Starting %s...", h.Name))
+		if err := h.Driver.Start(); err != nil {
+			return fmt.Errorf("Error starting machine: %s", err)
+		}
+	}
+
+	return provisionMachine(store, h)
+}
 
+// createMachine has the driver create the machine, saving what it got to in
+// the store whether it succeeds or not.
+func createMachine(store persist.Store, h *host.Host) error {
 	if err := h.Driver.Create(); err != nil {
+		if saveErr := store.Save(h); saveErr != nil {
+			log.Warnf("Error saving host to store after failed creation: %s", saveErr)
+		}
 		return fmt.Errorf("Error in driver during machine creation: %s", err)
 	}
 
+	return nil
+}
+
+// provisionMachine provisions a machine the driver created, then records the
+// creation is over.
+func provisionMachine(store persist.Store, h *host.Host) error {
+	h.HostOptions.CreateStep = host.CreateStepProvision
 	if err := store.Save(h); err != nil {
 		return fmt.Errorf("Error saving host to store after attempting creation: %s", err)
 	}
 
+	if !h.IsProvisioned() {
+		log.Info("Skipping provisioning, the machine will be provisioned by 'start --provision'")
+		h.HostOptions.CreateStep = ""
+		log.Progress(h.Name, "done", 100, "")
+		return nil
+	}
+
 	// TODO: Not really a fan of just checking "none" here.
 	if h.Driver.DriverName() != "none" {
-		log.Info("Waiting for machine to be running, this may take a few minutes...")
+		log.Progress(h.Name, "wait-running", 40, "Waiting for machine to be running, this may take a few minutes...")
 		if err := mcnutils.WaitFor(drivers.MachineInState(h.Driver, state.Running)); err != nil {
 			return fmt.Errorf("Error waiting for machine to be running: %s", err)
 		}
 
-		log.Info("Machine is running, waiting for SSH to be available...")
-		if err := drivers.WaitForSSH(h.Driver); err != nil {
-			return fmt.Errorf("Error waiting for SSH: %s", err)
+		if err := provisionRunning(store, h); err != nil {
+			return err
 		}
+	}
+
+	log.Debug("Reticulating splines...")
+
+	h.HostOptions.CreateStep = ""
+	log.Progress(h.Name, "done", 100, "")
+
+	return nil
+}
 
-		log.Info("Detecting operating system of created instance...")
-		provisioner, err := provision.DetectProvisioner(h.Driver)
-		if err != nil {
-			return fmt.Errorf("Error detecting OS: %s", err)
+// Provision provisions a running machine, again or for the first time if it
+// was created without it, and records that it was. Provisioning again
+// re-detects the OS and applies the options of the machine once more.
+func Provision(store persist.Store, h *host.Host) error {
+	machineState, err := h.Driver.GetState()
+	if err != nil {
+		return err
+	}
+
+	if machineState != state.Running {
+		return errMachineMustBeRunningToProvision
+	}
+
+	return provisionRunning(store, h)
+}
+
+// provisionRunning provisions the running machine, shares its folders over
+// NFS and adds it to its WireGuard overlay.
+func provisionRunning(store persist.Store, h *host.Host) error {
+	log.Progress(h.Name, "wait-ssh", 50, "Machine is running, waiting for SSH to be available...")
+	if err := drivers.WaitForSSH(h.Driver); err != nil {
+		return fmt.Errorf("Error waiting for SSH: %s", err)
+	}
+
+	log.Progress(h.Name, "detect-os", 60, "Detecting operating system of created instance...")
+	provisioner, err := provision.DetectProvisioner(h.Driver)
+	if err != nil {
+		return fmt.Errorf("Error detecting OS: %s", err)
+	}
+
+	log.Progress(h.Name, "provision", 70, "Provisioning created instance...")
+	if err := provisioner.Provision(*h.HostOptions.SwarmOptions, *h.HostOptions.AuthOptions, *h.HostOptions.EngineOptions); err != nil {
+		return fmt.Errorf("Error running provisioning: %s", err)
+	}
+
+	if err := h.ConfigureNFSShares(provisioner); err != nil {
+		return err
+	}
+
+	if err := joinWireGuardMesh(store, h, provisioner); err != nil {
+		return err
+	}
+
+	h.HostOptions.Unprovisioned = false
+
+	return h.RunHook(hook.PostProvision)
+}
+
+// Remove removes the machine with its driver, then its NFS exports and the
+// host from the store, and runs the post-remove hooks. With force, the host
+// is removed from the store even if the driver fails to remove the machine.
+func Remove(store persist.Store, h *host.Host, force bool) error {
+	if err := h.Driver.Remove(); err != nil {
+		if !force {
+			return fmt.Errorf("Provider error removing machine %q: %s", h.Name, err)
 		}
+	}
 
-		log.Info("Provisioning created instance...")
-		if err := provisioner.Provision(*h.HostOptions.SwarmOptions, *h.HostOptions.AuthOptions, *h.HostOptions.EngineOptions); err != nil {
-			return fmt.Errorf("Error running provisioning: %s", err)
+	if err := h.RemoveNFSShares(); err != nil {
+		log.Warnf("Error removing the NFS exports of %q: %s", h.Name, err)
+	}
+
+	if err := store.Remove(h.Name); err != nil {
+		return fmt.Errorf("Error removing machine %q from store: %s", h.Name, err)
+	}
+
+	return h.RunHook(hook.PostRemove)
+}
+
+// DefaultCreateParallelism is how many machines CreateAll creates at the
+// same time when not told otherwise.
+const DefaultCreateParallelism = 5
+
+// CreateAll creates the hosts concurrently, at most parallelism at a time,
+// and returns the errors of those which failed, by host name.
+func CreateAll(store persist.Store, hosts []*host.Host, parallelism int) map[string]error {
+	errs := map[string]error{}
+	if len(hosts) == 0 {
+		return errs
+	}
+
+	if parallelism < 1 {
+		parallelism = DefaultCreateParallelism
+	}
+
+	// The hosts share the CA and the client certificate, which must be
+	// generated once, before the workers race to do it.
+	if err := cert.BootstrapCertificates(hosts[0].HostOptions.AuthOptions); err != nil {
+		for _, h := range hosts {
+			errs[h.Name] = fmt.Errorf("Error generating certificates: %s", err)
 		}
+		return errs
 	}
 
-	log.Debug("Reticulating splines...")
+	type result struct {
+		name string
+		err  error
+	}
 
-	return nil
+	queue := make(chan *host.Host)
+	results := make(chan result)
+
+	for i := 0; i < parallelism && i < len(hosts); i++ {
+		go func() {
+			for h := range queue {
+				log.Infof("(%s) Creating machine...", h.Name)
+				err := Create(store, h)
+				if err != nil {
+					log.Errorf("(%s) Error creating machine: %s", h.Name, err)
+				} else {
+					log.Infof("(%s) Machine created", h.Name)
+				}
+				results <- result{name: h.Name, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		for _, h := range hosts {
+			queue <- h
+		}
+		close(queue)
+	}()
+
+	for range hosts {
+		if r := <-results; r.err != nil {
+			errs[r.name] = r.err
+		}
+	}
+
+	return errs
 }
 
 func SetDebug(val bool) {
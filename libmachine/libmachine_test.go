@@ -0,0 +1,238 @@
+package libmachine
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/engine"
+	"github.com/docker/machine/libmachine/hook"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/persist"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/docker/machine/libmachine/swarm"
+	"github.com/docker/machine/libmachine/version"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingDriver records how many machines are being created at once, and
+// fails the creation of those it is told to.
+type countingDriver struct {
+	*fakedriver.Driver
+	counter *createCounter
+	fail    bool
+	// created tells whether the machine exists, as far as GetState goes.
+	created  bool
+	stateErr error
+}
+
+type createCounter struct {
+	sync.Mutex
+	running int
+	max     int
+	release chan struct{}
+}
+
+func (d *countingDriver) Create() error {
+	d.counter.Lock()
+	d.counter.running++
+	if d.counter.running > d.counter.max {
+		d.counter.max = d.counter.running
+	}
+	d.counter.Unlock()
+
+	<-d.counter.release
+
+	d.counter.Lock()
+	d.counter.running--
+	d.counter.Unlock()
+
+	if d.fail {
+		return errors.New("BOOM")
+	}
+
+	d.created = true
+
+	return nil
+}
+
+func (d *countingDriver) GetState() (state.State, error) {
+	if d.stateErr != nil {
+		return state.Error, d.stateErr
+	}
+
+	if !d.created {
+		return state.Error, errors.New("machine does not exist")
+	}
+
+	return d.Driver.GetState()
+}
+
+func newCountingHost(dir, name string, counter *createCounter, fail bool) *host.Host {
+	certDir := filepath.Join(dir, "certs")
+
+	return &host.Host{
+		ConfigVersion: version.ConfigVersion,
+		Name:          name,
+		DriverName:    "Driver",
+		Driver: &countingDriver{
+			Driver:  &fakedriver.Driver{BaseDriver: &drivers.BaseDriver{MachineName: name, StorePath: dir}},
+			counter: counter,
+			fail:    fail,
+		},
+		HostOptions: &host.Options{
+			AuthOptions: &auth.Options{
+				CertDir:          certDir,
+				CaCertPath:       filepath.Join(certDir, "ca.pem"),
+				CaPrivateKeyPath: filepath.Join(certDir, "ca-key.pem"),
+				ClientCertPath:   filepath.Join(certDir, "cert.pem"),
+				ClientKeyPath:    filepath.Join(certDir, "key.pem"),
+			},
+			EngineOptions: &engine.Options{},
+			SwarmOptions:  &swarm.Options{},
+			Unprovisioned: true,
+		},
+	}
+}
+
+func TestCreateAll(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store := &persist.Filestore{Path: dir}
+	counter := &createCounter{release: make(chan struct{})}
+
+	hosts := []*host.Host{
+		newCountingHost(dir, "m-1", counter, false),
+		newCountingHost(dir, "m-2", counter, true),
+		newCountingHost(dir, "m-3", counter, false),
+		newCountingHost(dir, "m-4", counter, false),
+	}
+
+	done := make(chan map[string]error)
+	go func() {
+		done <- CreateAll(store, hosts, 2)
+	}()
+
+	for range hosts {
+		counter.release <- struct{}{}
+	}
+	errs := <-done
+
+	assert.Len(t, errs, 1)
+	assert.EqualError(t, errs["m-2"], "Error in driver during machine creation: BOOM")
+	assert.True(t, counter.max <= 2)
+
+	for _, name := range []string{"m-1", "m-3", "m-4"} {
+		exists, err := store.Exists(name)
+		assert.NoError(t, err)
+		assert.True(t, exists)
+	}
+}
+
+func TestCreateAllNoHosts(t *testing.T) {
+	assert.Empty(t, CreateAll(&persist.Filestore{}, []*host.Host{}, 2))
+}
+
+func TestResumeFailedCreation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store := &persist.Filestore{Path: dir}
+	counter := &createCounter{release: make(chan struct{}, 2)}
+	counter.release <- struct{}{}
+	counter.release <- struct{}{}
+
+	h := newCountingHost(dir, "m-1", counter, true)
+
+	assert.EqualError(t, Create(store, h), "Error in driver during machine creation: BOOM")
+	assert.Equal(t, host.CreateStepDriver, h.HostOptions.CreateStep)
+
+	// The machine doesn't exist, the driver creates it again.
+	h.Driver.(*countingDriver).fail = false
+
+	assert.NoError(t, Resume(store, h))
+	assert.Equal(t, host.CreateStep(""), h.HostOptions.CreateStep)
+	assert.Empty(t, counter.release)
+}
+
+func TestResumeStoppedMachine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store := &persist.Filestore{Path: dir}
+	h := newCountingHost(dir, "m-1", &createCounter{}, true)
+	h.HostOptions.CreateStep = host.CreateStepDriver
+	driver := h.Driver.(*countingDriver)
+	driver.created = true
+	driver.MockState = state.Stopped
+
+	// The machine exists, it is started rather than created again, which
+	// would block on the counter.
+	assert.NoError(t, Resume(store, h))
+	assert.Equal(t, state.Running, driver.MockState)
+	assert.Equal(t, host.CreateStep(""), h.HostOptions.CreateStep)
+}
+
+func TestResumeUnknownState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	h := newCountingHost(dir, "m-1", &createCounter{}, false)
+	h.HostOptions.CreateStep = host.CreateStepDriver
+	h.Driver.(*countingDriver).stateErr = errors.New("API rate limit exceeded")
+
+	// Failing to get the state doesn't mean the machine is gone, it isn't
+	// created again, which would block on the counter.
+	assert.EqualError(t, Resume(&persist.Filestore{Path: dir}, h), "Error getting the state of m-1, not resuming its creation: API rate limit exceeded")
+}
+
+func TestResumeCreatedMachine(t *testing.T) {
+	h := newCountingHost("", "m-1", &createCounter{}, false)
+
+	assert.EqualError(t, Resume(&persist.Filestore{}, h), "m-1 was created, there is nothing to resume")
+}
+
+func TestProvisionRequiresRunningMachine(t *testing.T) {
+	h := &host.Host{
+		Name:   "test",
+		Driver: &fakedriver.Driver{MockState: state.Stopped},
+	}
+
+	assert.Equal(t, errMachineMustBeRunningToProvision, Provision(nil, h))
+}
+
+func TestRemoveRunsPostRemoveHook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	marker := filepath.Join(dir, "removed")
+	script := filepath.Join(dir, "hook.sh")
+	assert.NoError(t, ioutil.WriteFile(script, []byte("#!/bin/sh\necho $1 > "+marker+"\n"), 0700))
+
+	store := &persist.Filestore{Path: dir}
+	h := newCountingHost(dir, "m-1", &createCounter{}, false)
+	h.HostOptions.HookOptions = &hook.Options{Scripts: []string{script}}
+	assert.NoError(t, store.Save(h))
+
+	assert.NoError(t, Remove(store, h, false))
+
+	exists, err := store.Exists("m-1")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	event, err := ioutil.ReadFile(marker)
+	assert.NoError(t, err)
+	assert.Equal(t, "post-remove\n", string(event))
+}
@@ -0,0 +1,130 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// JSONLogger writes a JSON object per record, one per line, for the tools
+// wrapping machine to read.
+type JSONLogger struct {
+	fields Fields
+	Writer io.Writer
+	mu     *sync.Mutex
+}
+
+// NewJSONLogger returns a logger writing its records to w.
+func NewJSONLogger(w io.Writer) JSONLogger {
+	return JSONLogger{
+		Writer: w,
+		mu:     &sync.Mutex{},
+	}
+}
+
+func (t JSONLogger) write(level, msg string, fields Fields) {
+	record := map[string]interface{}{}
+	for k, v := range t.fields {
+		record[k] = v
+	}
+	for k, v := range fields {
+		record[k] = v
+	}
+
+	record["time"] = time.Now().Format(time.RFC3339)
+	record["level"] = level
+	if msg != "" {
+		record["msg"] = msg
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		data, _ = json.Marshal(map[string]string{"level": "error", "msg": err.Error()})
+	}
+
+	defer t.mu.Unlock()
+	t.mu.Lock()
+	t.Writer.Write(append(data, '\n'))
+}
+
+func (t JSONLogger) Debug(args ...interface{}) {
+	if IsDebug {
+		t.write("debug", fmt.Sprint(args...), nil)
+	}
+}
+
+func (t JSONLogger) Debugf(fmtString string, args ...interface{}) {
+	if IsDebug {
+		t.write("debug", fmt.Sprintf(fmtString, args...), nil)
+	}
+}
+
+func (t JSONLogger) Error(args ...interface{}) {
+	t.write("error", fmt.Sprint(args...), nil)
+}
+
+func (t JSONLogger) Errorf(fmtString string, args ...interface{}) {
+	t.write("error", fmt.Sprintf(fmtString, args...), nil)
+}
+
+func (t JSONLogger) Info(args ...interface{}) {
+	t.write("info", fmt.Sprint(args...), nil)
+}
+
+func (t JSONLogger) Infof(fmtString string, args ...interface{}) {
+	t.write("info", fmt.Sprintf(fmtString, args...), nil)
+}
+
+func (t JSONLogger) Fatal(args ...interface{}) {
+	t.write("fatal", fmt.Sprint(args...), nil)
+	os.Exit(1)
+}
+
+func (t JSONLogger) Fatalf(fmtString string, args ...interface{}) {
+	t.write("fatal", fmt.Sprintf(fmtString, args...), nil)
+	os.Exit(1)
+}
+
+func (t JSONLogger) Print(args ...interface{}) {
+	t.write("info", fmt.Sprint(args...), nil)
+}
+
+func (t JSONLogger) Printf(fmtString string, args ...interface{}) {
+	t.write("info", fmt.Sprintf(fmtString, args...), nil)
+}
+
+func (t JSONLogger) Warn(args ...interface{}) {
+	t.write("warn", fmt.Sprint(args...), nil)
+}
+
+func (t JSONLogger) Warnf(fmtString string, args ...interface{}) {
+	t.write("warn", fmt.Sprintf(fmtString, args...), nil)
+}
+
+// WithFields returns a logger adding the fields to its records.
+func (t JSONLogger) WithFields(fields Fields) Logger {
+	merged := Fields{}
+	for k, v := range t.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	t.fields = merged
+
+	return t
+}
+
+// Progress writes a progress event: the step the operation on the host got
+// to and how far along it is, in percent.
+func (t JSONLogger) Progress(host, step string, percent int, msg string) {
+	t.write("info", msg, Fields{
+		"event":   "progress",
+		"host":    host,
+		"step":    step,
+		"percent": percent,
+	})
+}
@@ -0,0 +1,66 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func decodeRecord(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	record := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a JSON record, got %q: %s", buf.String(), err)
+	}
+	delete(record, "time")
+
+	return record
+}
+
+func TestJSONLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewJSONLogger(&buf)
+
+	logger.WithFields(Fields{"foo": "bar"}).Warnf("disk %d%% full", 90)
+
+	record := decodeRecord(t, &buf)
+	if record["level"] != "warn" || record["msg"] != "disk 90% full" || record["foo"] != "bar" {
+		t.Fatalf("Unexpected record %v", record)
+	}
+}
+
+func TestJSONLoggerProgress(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewJSONLogger(&buf)
+
+	logger.Progress("dev", "create", 10, "Creating machine...")
+
+	record := decodeRecord(t, &buf)
+	expected := map[string]interface{}{
+		"level":   "info",
+		"msg":     "Creating machine...",
+		"event":   "progress",
+		"host":    "dev",
+		"step":    "create",
+		"percent": float64(10),
+	}
+	for k, v := range expected {
+		if record[k] != v {
+			t.Fatalf("Expected %s to be %v, got %v", k, v, record[k])
+		}
+	}
+}
+
+func TestSetFormat(t *testing.T) {
+	defer SetFormat(FormatText)
+
+	if err := SetFormat(FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := l.(JSONLogger); !ok {
+		t.Fatalf("Expected a JSONLogger, got %T", l)
+	}
+
+	if err := SetFormat("xml"); err == nil {
+		t.Fatal("Expected an error for an unknown format")
+	}
+}
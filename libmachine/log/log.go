@@ -1,6 +1,7 @@
 package log
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"sync"
@@ -33,15 +34,27 @@ type Logger interface {
 	WithFields(Fields) Logger
 }
 
+// The formats of the logs.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
 var (
-	l = StandardLogger{
+	std = &StandardLogger{
 		mu: &sync.Mutex{},
 	}
-	IsDebug = false
+	l       Logger = std
+	IsDebug        = false
 )
 
 type Fields map[string]interface{}
 
+// progressLogger is a logger writing progress events.
+type progressLogger interface {
+	Progress(host, step string, percent int, msg string)
+}
+
 func init() {
 	// TODO: Is this really the best approach?  I worry that it will create
 	// implicit behavior which may be problmatic for users of the lib.
@@ -50,11 +63,40 @@ func init() {
 }
 
 func SetOutWriter(w io.Writer) {
-	l.OutWriter = w
+	std.OutWriter = w
 }
 
 func SetErrWriter(w io.Writer) {
-	l.ErrWriter = w
+	std.ErrWriter = w
+}
+
+// SetFormat selects the format of the logs: FormatText, or FormatJSON to
+// write them to the standard error as a JSON object per line.
+func SetFormat(format string) error {
+	switch format {
+	case "", FormatText:
+		l = std
+	case FormatJSON:
+		l = NewJSONLogger(std.ErrWriter)
+	default:
+		return fmt.Errorf("Unknown log format %q, expected %s or %s", format, FormatText, FormatJSON)
+	}
+
+	return nil
+}
+
+// Progress reports the step an operation on the host, e.g. its creation,
+// got to and how far along it is, in percent. The text logs only show the
+// message, if any.
+func Progress(host, step string, percent int, msg string) {
+	if p, ok := l.(progressLogger); ok {
+		p.Progress(host, step, percent, msg)
+		return
+	}
+
+	if msg != "" {
+		l.Info(msg)
+	}
 }
 
 func Debug(args ...interface{}) {
@@ -74,7 +116,7 @@ func Errorf(fmtString string, args ...interface{}) {
 }
 
 func Errorln(args ...interface{}) {
-	l.Errorln(args...)
+	l.Error(args...)
 }
 
 func Info(args ...interface{}) {
@@ -86,7 +128,7 @@ func Infof(fmtString string, args ...interface{}) {
 }
 
 func Infoln(args ...interface{}) {
-	l.Infoln(args...)
+	l.Info(args...)
 }
 
 func Fatal(args ...interface{}) {
@@ -0,0 +1,205 @@
+package mcndockerclient
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine/auth"
+)
+
+// DefaultPool is the pool used by the docker-machine commands.
+var DefaultPool = NewPool(PoolOptions{})
+
+// PoolOptions configures the connections kept by a Pool.
+type PoolOptions struct {
+	// MaxIdleConnsPerHost is the number of idle connections kept for each
+	// machine. Zero uses net/http's default.
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout closes idle connections after this duration. Zero keeps
+	// them open until the client is invalidated.
+	IdleConnTimeout time.Duration
+	// Timeout bounds each request. Zero defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+// Client talks to the Docker daemon of a machine.
+type Client struct {
+	*http.Client
+	// BaseURL is the https URL of the daemon, without a trailing slash.
+	BaseURL string
+
+	// streaming shares the connections of Client, without its timeout.
+	streaming *http.Client
+}
+
+// Get sends a GET request for path, e.g. "/version", to the daemon.
+func (c *Client) Get(path string) (*http.Response, error) {
+	return c.Client.Get(c.BaseURL + path)
+}
+
+// Stream sends a GET request for path to the daemon, like Get, but isn't
+// bounded by the timeout of the pool: the response can be read for as long as
+// the daemon streams it, e.g. "/containers/<id>/stats".
+func (c *Client) Stream(path string) (*http.Response, error) {
+	if c.streaming == nil {
+		return c.Get(path)
+	}
+
+	return c.streaming.Get(c.BaseURL + path)
+}
+
+type pooledClient struct {
+	fingerprint string
+	dockerHost  string
+	client      *Client
+	transport   *http.Transport
+}
+
+// Pool caches a Client per machine, so that calls across a fleet reuse their
+// TLS connections instead of doing a handshake each time. A cached client is
+// replaced when the certificates of the machine change.
+type Pool struct {
+	options PoolOptions
+
+	mu      sync.Mutex
+	clients map[string]*pooledClient
+}
+
+// NewPool returns an empty Pool.
+func NewPool(options PoolOptions) *Pool {
+	if options.Timeout == 0 {
+		options.Timeout = 10 * time.Second
+	}
+
+	return &Pool{
+		options: options,
+		clients: map[string]*pooledClient{},
+	}
+}
+
+// Get returns the client of the machine named machineName, whose daemon
+// listens on dockerHost, e.g. tcp://192.168.99.100:2376.
+func (p *Pool) Get(machineName, dockerHost string, authOptions *auth.Options) (*Client, error) {
+	fingerprint, err := certFingerprint(authOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if cached, ok := p.clients[machineName]; ok {
+		if cached.fingerprint == fingerprint && cached.dockerHost == dockerHost {
+			return cached.client, nil
+		}
+
+		cached.transport.CloseIdleConnections()
+		delete(p.clients, machineName)
+	}
+
+	pooled, err := p.newClient(dockerHost, authOptions)
+	if err != nil {
+		return nil, err
+	}
+	pooled.fingerprint = fingerprint
+
+	p.clients[machineName] = pooled
+
+	return pooled.client, nil
+}
+
+// Invalidate drops the client of the machine named machineName, e.g. when it
+// is removed.
+func (p *Pool) Invalidate(machineName string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if cached, ok := p.clients[machineName]; ok {
+		cached.transport.CloseIdleConnections()
+		delete(p.clients, machineName)
+	}
+}
+
+// Close drops every client and closes their idle connections.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for machineName, cached := range p.clients {
+		cached.transport.CloseIdleConnections()
+		delete(p.clients, machineName)
+	}
+}
+
+func (p *Pool) newClient(dockerHost string, authOptions *auth.Options) (*pooledClient, error) {
+	u, err := url.Parse(dockerHost)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid Docker host %q", dockerHost)
+	}
+
+	caCert, err := ioutil.ReadFile(authOptions.CaCertPath)
+	if err != nil {
+		return nil, err
+	}
+
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(caCert) {
+		return nil, errors.New("unable to read the CA certificate")
+	}
+
+	keyPair, err := tls.LoadX509KeyPair(authOptions.ClientCertPath, authOptions.ClientKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{
+			RootCAs:      certPool,
+			Certificates: []tls.Certificate{keyPair},
+		},
+		MaxIdleConnsPerHost: p.options.MaxIdleConnsPerHost,
+		IdleConnTimeout:     p.options.IdleConnTimeout,
+	}
+
+	return &pooledClient{
+		dockerHost: dockerHost,
+		transport:  transport,
+		client: &Client{
+			Client: &http.Client{
+				Timeout:   p.options.Timeout,
+				Transport: transport,
+			},
+			BaseURL:   "https://" + u.Host,
+			streaming: &http.Client{Transport: transport},
+		},
+	}, nil
+}
+
+// certFingerprint hashes the CA and client certificates of a machine, so that
+// regenerated certificates are detected.
+func certFingerprint(authOptions *auth.Options) (string, error) {
+	h := sha256.New()
+	for _, path := range []string{authOptions.CaCertPath, authOptions.ClientCertPath, authOptions.ClientKeyPath} {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+
+		h.Write(content)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
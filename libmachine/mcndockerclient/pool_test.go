@@ -0,0 +1,123 @@
+package mcndockerclient
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/cert"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestAuthOptions(t *testing.T) (*auth.Options, string) {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authOptions := &auth.Options{
+		CaCertPath:       filepath.Join(dir, "ca.pem"),
+		CaPrivateKeyPath: filepath.Join(dir, "ca-key.pem"),
+		ClientCertPath:   filepath.Join(dir, "cert.pem"),
+		ClientKeyPath:    filepath.Join(dir, "key.pem"),
+	}
+
+	if err := cert.GenerateCACertificate(authOptions.CaCertPath, authOptions.CaPrivateKeyPath, "test", 2048); err != nil {
+		t.Fatal(err)
+	}
+
+	generateClientCert(t, authOptions)
+
+	return authOptions, dir
+}
+
+func generateClientCert(t *testing.T, authOptions *auth.Options) {
+	if err := cert.GenerateCert([]string{""}, authOptions.ClientCertPath, authOptions.ClientKeyPath, authOptions.CaCertPath, authOptions.CaPrivateKeyPath, "test", 2048); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPoolReusesClient(t *testing.T) {
+	authOptions, dir := newTestAuthOptions(t)
+	defer os.RemoveAll(dir)
+
+	pool := NewPool(PoolOptions{})
+
+	client, err := pool.Get("dev", "tcp://192.168.99.100:2376", authOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://192.168.99.100:2376", client.BaseURL)
+
+	again, err := pool.Get("dev", "tcp://192.168.99.100:2376", authOptions)
+	assert.NoError(t, err)
+	assert.True(t, client == again)
+}
+
+func TestPoolReplacesClientWhenCertsChange(t *testing.T) {
+	authOptions, dir := newTestAuthOptions(t)
+	defer os.RemoveAll(dir)
+
+	pool := NewPool(PoolOptions{})
+
+	client, err := pool.Get("dev", "tcp://192.168.99.100:2376", authOptions)
+	assert.NoError(t, err)
+
+	generateClientCert(t, authOptions)
+
+	regenerated, err := pool.Get("dev", "tcp://192.168.99.100:2376", authOptions)
+	assert.NoError(t, err)
+	assert.False(t, client == regenerated)
+}
+
+func TestPoolReplacesClientWhenHostChanges(t *testing.T) {
+	authOptions, dir := newTestAuthOptions(t)
+	defer os.RemoveAll(dir)
+
+	pool := NewPool(PoolOptions{})
+
+	client, err := pool.Get("dev", "tcp://192.168.99.100:2376", authOptions)
+	assert.NoError(t, err)
+
+	moved, err := pool.Get("dev", "tcp://192.168.99.101:2376", authOptions)
+	assert.NoError(t, err)
+	assert.False(t, client == moved)
+	assert.Equal(t, "https://192.168.99.101:2376", moved.BaseURL)
+}
+
+func TestPoolInvalidate(t *testing.T) {
+	authOptions, dir := newTestAuthOptions(t)
+	defer os.RemoveAll(dir)
+
+	pool := NewPool(PoolOptions{})
+
+	client, err := pool.Get("dev", "tcp://192.168.99.100:2376", authOptions)
+	assert.NoError(t, err)
+
+	pool.Invalidate("dev")
+
+	fresh, err := pool.Get("dev", "tcp://192.168.99.100:2376", authOptions)
+	assert.NoError(t, err)
+	assert.False(t, client == fresh)
+}
+
+func TestPoolGetFailsWithoutCerts(t *testing.T) {
+	pool := NewPool(PoolOptions{})
+
+	_, err := pool.Get("dev", "tcp://192.168.99.100:2376", &auth.Options{CaCertPath: "/does/not/exist"})
+	assert.Error(t, err)
+}
+
+func TestClientStreamHasNoTimeout(t *testing.T) {
+	authOptions, dir := newTestAuthOptions(t)
+	defer os.RemoveAll(dir)
+
+	pool := NewPool(PoolOptions{Timeout: time.Second})
+
+	client, err := pool.Get("dev", "tcp://192.168.99.100:2376", authOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, client.Timeout)
+	assert.Equal(t, time.Duration(0), client.streaming.Timeout)
+	assert.True(t, client.Transport == client.streaming.Transport)
+}
@@ -9,6 +9,17 @@ var (
 	ErrInvalidHostname = errors.New("Invalid hostname specified. Allowed hostname chars are: 0-9a-zA-Z . -")
 )
 
+// machineNotExistMsg is the error message the local VM drivers return when
+// the VM backing a host is gone. The error crosses the plugin RPC boundary
+// as a plain string, so it has to be matched by message.
+const machineNotExistMsg = "machine does not exist"
+
+// IsMachineNotExist tells whether err is the error of a driver whose machine
+// is gone, as opposed to failing to get its state.
+func IsMachineNotExist(err error) bool {
+	return err != nil && err.Error() == machineNotExistMsg
+}
+
 type ErrHostDoesNotExist struct {
 	Name string
 }
@@ -17,6 +28,16 @@ func (e ErrHostDoesNotExist) Error() string {
 	return fmt.Sprintf("Host does not exist: %q", e.Name)
 }
 
+// ErrHostConflict is returned when saving a host whose record was saved by
+// another command since it was loaded.
+type ErrHostConflict struct {
+	Name string
+}
+
+func (e ErrHostConflict) Error() string {
+	return fmt.Sprintf("Host %q was changed by another command since it was loaded, try again", e.Name)
+}
+
 type ErrHostAlreadyExists struct {
 	Name string
 }
@@ -0,0 +1,179 @@
+package mcnutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	defaultBoot2DockerReleasesURL = "https://api.github.com/repos/boot2docker/boot2docker/releases"
+	boot2DockerReleasesCacheFile  = "boot2docker-releases.json"
+)
+
+var (
+	reGithubReleasesURL = regexp.MustCompile("(https?)://([^/]+)(/api/v3)?/repos/([^/]+)/([^/]+)/releases")
+	reGithubNextLink    = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+	// Boot2DockerReleasesCacheTTL is how long a listing of the boot2docker
+	// releases is reused before the release source is queried again.
+	Boot2DockerReleasesCacheTTL = 10 * time.Minute
+)
+
+// Boot2DockerRelease is a published release of boot2docker.
+type Boot2DockerRelease struct {
+	Tag        string
+	PreRelease bool
+	ISOURL     string
+}
+
+type boot2DockerReleasesCache struct {
+	APIURL   string
+	Fetched  time.Time
+	Releases []Boot2DockerRelease
+}
+
+// ListBoot2DockerReleases lists the releases published at apiURL, a GitHub
+// (Enterprise) releases API URL, newest first. The listing is cached for
+// Boot2DockerReleasesCacheTTL unless refresh is true.
+func (b *B2dUtils) ListBoot2DockerReleases(apiURL string, refresh bool) ([]Boot2DockerRelease, error) {
+	if apiURL == "" {
+		apiURL = defaultBoot2DockerReleasesURL
+	}
+
+	if !refresh {
+		if releases, ok := b.cachedBoot2DockerReleases(apiURL); ok {
+			log.Debugf("Using the boot2docker releases cached in %s", b.imgCachePath)
+			return releases, nil
+		}
+	}
+
+	releases, err := b.fetchBoot2DockerReleases(apiURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := b.cacheBoot2DockerReleases(apiURL, releases); err != nil {
+		log.Debugf("Unable to cache the boot2docker releases: %s", err)
+	}
+
+	return releases, nil
+}
+
+func (b *B2dUtils) fetchBoot2DockerReleases(apiURL string) ([]Boot2DockerRelease, error) {
+	matches := reGithubReleasesURL.FindStringSubmatch(apiURL)
+	if len(matches) != 6 {
+		return nil, fmt.Errorf("%s is not a GitHub releases API URL", apiURL)
+	}
+
+	scheme, host, org, repo := matches[1], matches[2], matches[4], matches[5]
+	if host == "api.github.com" {
+		host = "github.com"
+	}
+
+	listed := []githubRelease{}
+	for pageURL := apiURL; pageURL != ""; {
+		page, next, err := b.fetchReleasesPage(pageURL)
+		if err != nil {
+			return nil, err
+		}
+
+		listed = append(listed, page...)
+		pageURL = next
+	}
+
+	releases := []Boot2DockerRelease{}
+	for _, release := range listed {
+		releases = append(releases, Boot2DockerRelease{
+			Tag:        release.TagName,
+			PreRelease: release.PreRelease,
+			ISOURL:     fmt.Sprintf("%s://%s/%s/%s/releases/download/%s/boot2docker.iso", scheme, host, org, repo, release.TagName),
+		})
+	}
+
+	return releases, nil
+}
+
+type githubRelease struct {
+	TagName    string `json:"tag_name"`
+	PreRelease bool   `json:"prerelease"`
+}
+
+// fetchReleasesPage gets a page of the releases listed by the GitHub API,
+// along with the URL of the next page, if any.
+func (b *B2dUtils) fetchReleasesPage(pageURL string) ([]githubRelease, string, error) {
+	req, err := b.getReleasesRequest(pageURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rsp, err := getClient().Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("Unable to reach the boot2docker release source %s: %s", pageURL, err)
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("Unexpected response from the boot2docker release source %s: %s\nYou may be getting rate limited by Github.", pageURL, rsp.Status)
+	}
+
+	var page []githubRelease
+	if err := json.NewDecoder(rsp.Body).Decode(&page); err != nil {
+		return nil, "", fmt.Errorf("Error demarshaling the Github API response: %s", err)
+	}
+
+	return page, nextPageURL(rsp.Header), nil
+}
+
+// nextPageURL returns the URL of the next page of a paginated GitHub API
+// response, found in its Link header, or "" on the last page.
+func nextPageURL(header http.Header) string {
+	if matches := reGithubNextLink.FindStringSubmatch(strings.Join(header["Link"], ",")); matches != nil {
+		return matches[1]
+	}
+
+	return ""
+}
+
+func (b *B2dUtils) cachedBoot2DockerReleases(apiURL string) ([]Boot2DockerRelease, bool) {
+	data, err := ioutil.ReadFile(filepath.Join(b.imgCachePath, boot2DockerReleasesCacheFile))
+	if err != nil {
+		return nil, false
+	}
+
+	var cache boot2DockerReleasesCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, false
+	}
+
+	if cache.APIURL != apiURL || time.Since(cache.Fetched) > Boot2DockerReleasesCacheTTL {
+		return nil, false
+	}
+
+	return cache.Releases, true
+}
+
+func (b *B2dUtils) cacheBoot2DockerReleases(apiURL string, releases []Boot2DockerRelease) error {
+	data, err := json.Marshal(boot2DockerReleasesCache{
+		APIURL:   apiURL,
+		Fetched:  time.Now(),
+		Releases: releases,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(b.imgCachePath, 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(b.imgCachePath, boot2DockerReleasesCacheFile), data, 0600)
+}
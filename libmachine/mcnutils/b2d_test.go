@@ -5,8 +5,10 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"bytes"
 
@@ -110,3 +112,117 @@ func TestReaderWithProgress(t *testing.T) {
 	readerWithProgress.Close()
 	assert.Equal(t, "0%....10%....20%....30%....40%....50%....60%....70%....80%....90%....100%\n", output.String())
 }
+
+func TestListBoot2DockerReleases(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`[{"tag_name": "v1.10.0-rc1", "prerelease": true}, {"tag_name": "v1.9.1"}]`))
+	}))
+	defer ts.Close()
+
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	b := NewB2dUtils(tmpDir)
+	releases, err := b.ListBoot2DockerReleases(ts.URL+"/repos/org/repo/releases", false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Boot2DockerRelease{
+		{Tag: "v1.10.0-rc1", PreRelease: true, ISOURL: ts.URL + "/org/repo/releases/download/v1.10.0-rc1/boot2docker.iso"},
+		{Tag: "v1.9.1", ISOURL: ts.URL + "/org/repo/releases/download/v1.9.1/boot2docker.iso"},
+	}, releases)
+
+	cached, err := b.ListBoot2DockerReleases(ts.URL+"/repos/org/repo/releases", false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, releases, cached)
+	assert.Equal(t, 1, requests)
+
+	_, err = b.ListBoot2DockerReleases(ts.URL+"/repos/org/repo/releases", true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, requests)
+}
+
+func TestListBoot2DockerReleasesFollowsPages(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/org/repo/releases?page=1>; rel="prev", <%s/repos/org/repo/releases?page=1>; rel="first"`, ts.URL, ts.URL))
+			w.Write([]byte(`[{"tag_name": "v1.8.0"}]`))
+			return
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s/repos/org/repo/releases?page=2>; rel="next", <%s/repos/org/repo/releases?page=2>; rel="last"`, ts.URL, ts.URL))
+		w.Write([]byte(`[{"tag_name": "v1.9.1"}]`))
+	}))
+	defer ts.Close()
+
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	releases, err := NewB2dUtils(tmpDir).ListBoot2DockerReleases(ts.URL+"/repos/org/repo/releases", false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Boot2DockerRelease{
+		{Tag: "v1.9.1", ISOURL: ts.URL + "/org/repo/releases/download/v1.9.1/boot2docker.iso"},
+		{Tag: "v1.8.0", ISOURL: ts.URL + "/org/repo/releases/download/v1.8.0/boot2docker.iso"},
+	}, releases)
+}
+
+func TestListBoot2DockerReleasesCacheExpires(t *testing.T) {
+	defer func(ttl time.Duration) {
+		Boot2DockerReleasesCacheTTL = ttl
+	}(Boot2DockerReleasesCacheTTL)
+	Boot2DockerReleasesCacheTTL = 0
+
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`[{"tag_name": "v1.9.1"}]`))
+	}))
+	defer ts.Close()
+
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	b := NewB2dUtils(tmpDir)
+	b.ListBoot2DockerReleases(ts.URL+"/repos/org/repo/releases", false)
+	b.ListBoot2DockerReleases(ts.URL+"/repos/org/repo/releases", false)
+
+	assert.Equal(t, 2, requests)
+}
+
+func TestListBoot2DockerReleasesUnreachable(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	apiURL := ts.URL + "/repos/org/repo/releases"
+	ts.Close()
+
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	_, err = NewB2dUtils(tmpDir).ListBoot2DockerReleases(apiURL, false)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Unable to reach the boot2docker release source "+apiURL)
+}
+
+func TestListBoot2DockerReleasesRateLimited(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	_, err = NewB2dUtils(tmpDir).ListBoot2DockerReleases(ts.URL+"/repos/org/repo/releases", false)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "403 Forbidden")
+}
@@ -0,0 +1,75 @@
+package mcnutils
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+)
+
+// b2dFormatMagic is the first file of the disk of a boot2docker machine,
+// which tells its automount script to format the disk.
+// See https://github.com/boot2docker/boot2docker/blob/master/rootfs/rootfs/etc/rc.d/automount
+const b2dFormatMagic = "boot2docker, please format-me"
+
+// MakeB2dDiskImage creates the sparse raw disk of a boot2docker machine at
+// path, of size MB. It starts with the tar which tells boot2docker to format
+// the disk and to authorize the public SSH key at publicSSHKeyPath.
+func MakeB2dDiskImage(path, publicSSHKeyPath string, size int) error {
+	pubKey, err := ioutil.ReadFile(publicSSHKeyPath)
+	if err != nil {
+		return err
+	}
+
+	tarBuf, err := b2dDiskTar(pubKey)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(tarBuf.Bytes()); err != nil {
+		return err
+	}
+
+	return file.Truncate(int64(size) * 1024 * 1024)
+}
+
+// b2dDiskTar returns the tar starting the disk of a boot2docker machine,
+// with the public SSH key as authorized key.
+func b2dDiskTar(pubKey []byte) (*bytes.Buffer, error) {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+
+	// The magic string first so the automount script knows to format the
+	// disk.
+	if err := writeTarFile(tw, &tar.Header{Name: b2dFormatMagic, Size: int64(len(b2dFormatMagic))}, []byte(b2dFormatMagic)); err != nil {
+		return nil, err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: ".ssh", Typeflag: tar.TypeDir, Mode: 0700}); err != nil {
+		return nil, err
+	}
+	for _, name := range []string{".ssh/authorized_keys", ".ssh/authorized_keys2"} {
+		if err := writeTarFile(tw, &tar.Header{Name: name, Size: int64(len(pubKey)), Mode: 0644}, pubKey); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+func writeTarFile(tw *tar.Writer, header *tar.Header, content []byte) error {
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err := tw.Write(content)
+	return err
+}
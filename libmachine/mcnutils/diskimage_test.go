@@ -0,0 +1,52 @@
+package mcnutils
+
+import (
+	"archive/tar"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeB2dDiskImage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	keyPath := filepath.Join(dir, "id_rsa.pub")
+	diskPath := filepath.Join(dir, "disk.raw")
+	assert.NoError(t, ioutil.WriteFile(keyPath, []byte("ssh-rsa AAAA"), 0600))
+
+	assert.NoError(t, MakeB2dDiskImage(diskPath, keyPath, 1))
+
+	info, err := os.Stat(diskPath)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1024*1024), info.Size())
+
+	file, err := os.Open(diskPath)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	names := []string{}
+	tr := tar.NewReader(file)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		names = append(names, header.Name)
+
+		if header.Name == ".ssh/authorized_keys" {
+			content, err := ioutil.ReadAll(tr)
+			assert.NoError(t, err)
+			assert.Equal(t, "ssh-rsa AAAA", string(content))
+		}
+	}
+
+	assert.Equal(t, []string{b2dFormatMagic, ".ssh", ".ssh/authorized_keys", ".ssh/authorized_keys2"}, names)
+	assert.Error(t, MakeB2dDiskImage(diskPath, keyPath, 1))
+}
@@ -0,0 +1,44 @@
+package mcnutils
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	lockRetryInterval = 50 * time.Millisecond
+	lockTimeout       = 30 * time.Second
+)
+
+// LockFile takes an exclusive lock on path by creating it, waiting for the
+// other commands holding it to remove it. A lock older than lockTimeout was
+// left by a command which didn't exit cleanly, and is taken over.
+func LockFile(path string) (func(), error) {
+	deadline := time.Now().Add(lockTimeout)
+
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			f.Close()
+			return func() {
+				os.Remove(path)
+			}, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > lockTimeout {
+			os.Remove(path)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("Timed out waiting for the lock %s", path)
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"runtime"
 	"strconv"
@@ -134,3 +135,25 @@ func GenerateRandomID() string {
 		return value
 	}
 }
+
+// HostIPFor returns the address of the host on the network of the machine
+// at machineIP, which the machine reaches the host at.
+func HostIPFor(machineIP string) (string, error) {
+	ip := net.ParseIP(machineIP)
+	if ip == nil {
+		return "", fmt.Errorf("invalid IP address %q", machineIP)
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.Contains(ip) && !ipNet.IP.Equal(ip) {
+			return ipNet.IP.String(), nil
+		}
+	}
+
+	return "", fmt.Errorf("the host has no address on the network of %s", machineIP)
+}
@@ -0,0 +1,308 @@
+package nfs
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+)
+
+// bootlocalPath is the script boot2docker runs at boot, which mounts the
+// shares again.
+const bootlocalPath = "/var/lib/boot2docker/bootlocal.sh"
+
+var (
+	// exportsPath is the file the NFS server of the host reads its exports
+	// from.
+	exportsPath = "/etc/exports"
+
+	// exportsLockPath is locked while the exports are rewritten, so that
+	// the commands creating or removing machines at the same time don't
+	// lose each other's changes.
+	exportsLockPath = filepath.Join(os.TempDir(), fmt.Sprintf("docker-machine-exports-%d.lock", os.Getuid()))
+
+	// runAsRoot runs a command on the host with sudo, which prompts for a
+	// password if needed. Tests replace it.
+	runAsRoot = sudo
+)
+
+// Supported returns an error unless the host can export folders over NFS.
+func Supported(goos string) error {
+	switch goos {
+	case "darwin", "linux":
+		return nil
+	}
+
+	return fmt.Errorf("NFS shares aren't supported on %s hosts", goos)
+}
+
+func sudo(stdin io.Reader, name string, args ...string) error {
+	cmd := exec.Command("sudo", append([]string{name}, args...)...)
+	cmd.Stdin = stdin
+	cmd.Stderr = os.Stderr
+
+	if output, err := cmd.Output(); err != nil {
+		return fmt.Errorf("sudo %s %s failed: %s %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+func beginMarker(machine string) string {
+	return "# docker-machine " + machine + " begin"
+}
+
+func endMarker(machine string) string {
+	return "# docker-machine " + machine + " end"
+}
+
+// quoteExportPath quotes the path of a folder for the exports(5) of goos, in
+// double quotes. The characters which can't appear between them, the control
+// characters, the double quotes and the backslashes, are written as \ooo
+// octal escapes on Linux. The exports of macOS have no such escapes, so the
+// paths with these characters are refused.
+func quoteExportPath(goos, path string) (string, error) {
+	quoted := []byte{'"'}
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c >= 0x20 && c != 0x7f && c != '"' && c != '\\' {
+			quoted = append(quoted, c)
+			continue
+		}
+
+		if goos == "darwin" {
+			return "", fmt.Errorf("The folder %q can't be exported over NFS, the exports of macOS can't quote its characters", path)
+		}
+		quoted = append(quoted, fmt.Sprintf("\\%03o", c)...)
+	}
+
+	return string(append(quoted, '"')), nil
+}
+
+// exportLine returns the line of /etc/exports sharing the folder with the
+// machine at clientIP. The files are owned by the user of the host, whatever
+// the user the machine writes them as.
+func exportLine(goos string, folder drivers.SharedFolder, clientIP string, uid, gid int) (string, error) {
+	hostPath, err := quoteExportPath(goos, folder.HostPath)
+	if err != nil {
+		return "", err
+	}
+
+	if goos == "darwin" {
+		line := fmt.Sprintf("%s %s -alldirs -mapall=%d:%d", hostPath, clientIP, uid, gid)
+		if folder.ReadOnly {
+			line += " -ro"
+		}
+		return line, nil
+	}
+
+	access := "rw"
+	if folder.ReadOnly {
+		access = "ro"
+	}
+
+	return fmt.Sprintf("%s %s(%s,no_subtree_check,insecure,all_squash,anonuid=%d,anongid=%d)", hostPath, clientIP, access, uid, gid), nil
+}
+
+// isMarker tells whether line begins or ends the block of a machine.
+func isMarker(line string) bool {
+	return strings.HasPrefix(line, "# docker-machine ") && (strings.HasSuffix(line, " begin") || strings.HasSuffix(line, " end"))
+}
+
+// exportClient returns the client of an export line written by exportLine,
+// the IP address following the quoted folder.
+func exportClient(line string) string {
+	if !strings.HasPrefix(line, `"`) {
+		return ""
+	}
+
+	end := strings.Index(line[1:], `"`)
+	if end < 0 {
+		return ""
+	}
+
+	client := strings.TrimSpace(line[end+2:])
+	if i := strings.IndexAny(client, " ("); i >= 0 {
+		client = client[:i]
+	}
+
+	return client
+}
+
+// rewriteExports replaces the block of lines of the machine in the content
+// of /etc/exports with lines, in place, adding it at the end if there's none
+// and removing it if there are no lines. The lines of the blocks of the
+// other machines exporting to clientIP are removed: they were left behind by
+// a machine which had the address before.
+func rewriteExports(content, machine string, lines []string, clientIP string) string {
+	block := append(append([]string{beginMarker(machine)}, lines...), endMarker(machine))
+	if len(lines) == 0 {
+		block = nil
+	}
+
+	existing := []string{}
+	if trimmed := strings.TrimRight(content, "\n"); trimmed != "" {
+		existing = strings.Split(trimmed, "\n")
+	}
+
+	kept := []string{}
+	inBlock, inOtherBlock, written := false, false, false
+
+	for _, line := range existing {
+		switch {
+		case line == beginMarker(machine):
+			inBlock = true
+			if !written {
+				kept = append(kept, block...)
+				written = true
+			}
+		case line == endMarker(machine):
+			inBlock = false
+		case inBlock:
+		case isMarker(line):
+			inOtherBlock = strings.HasSuffix(line, " begin")
+			kept = append(kept, line)
+		case inOtherBlock && clientIP != "" && exportClient(line) == clientIP:
+			log.Infof("Removing the stale NFS export %s", line)
+		default:
+			kept = append(kept, line)
+		}
+	}
+
+	if !written {
+		kept = append(kept, block...)
+	}
+
+	if len(kept) == 0 {
+		return ""
+	}
+
+	return strings.Join(kept, "\n") + "\n"
+}
+
+// writeExports replaces the exports of the machine, exporting to clientIP,
+// and has the NFS server of the host reload them.
+func writeExports(machine string, lines []string, clientIP string) error {
+	unlock, err := mcnutils.LockFile(exportsLockPath)
+	if err != nil {
+		return fmt.Errorf("Error locking %s: %s", exportsPath, err)
+	}
+	defer unlock()
+
+	content, err := ioutil.ReadFile(exportsPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	updated := rewriteExports(string(content), machine, lines, clientIP)
+	if updated == string(content) {
+		return nil
+	}
+
+	if err := runAsRoot(strings.NewReader(updated), "tee", exportsPath); err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "darwin" {
+		return runAsRoot(nil, "nfsd", "update")
+	}
+
+	return runAsRoot(nil, "exportfs", "-ra")
+}
+
+// UpdateExports exports the folders to the machine at clientIP, replacing
+// its previous exports.
+func UpdateExports(machine, clientIP string, folders []drivers.SharedFolder) error {
+	if err := Supported(runtime.GOOS); err != nil {
+		return err
+	}
+
+	lines := []string{}
+	for _, folder := range folders {
+		line, err := exportLine(runtime.GOOS, folder, clientIP, os.Getuid(), os.Getgid())
+		if err != nil {
+			return err
+		}
+		lines = append(lines, line)
+	}
+
+	log.Infof("Exporting %d folder(s) of the host to %s over NFS, this may ask for your password...", len(folders), machine)
+	return writeExports(machine, lines, clientIP)
+}
+
+// RemoveExports removes the exports of the machine, leaving the folders as
+// they are.
+func RemoveExports(machine string) error {
+	if err := Supported(runtime.GOOS); err != nil {
+		return nil
+	}
+
+	return writeExports(machine, nil, "")
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// mountOptions returns the NFS mount options of the folder. Attributes are
+// cached for a second only when it must stay consistent with the host.
+func mountOptions(folder drivers.SharedFolder) string {
+	options := "vers=3,tcp,nolock,noacl"
+	switch folder.Consistency {
+	case drivers.ConsistencyCached:
+	case drivers.ConsistencyDelegated:
+		options += ",noatime"
+	default:
+		options += ",actimeo=1"
+	}
+
+	if folder.ReadOnly {
+		options += ",ro"
+	}
+
+	return options
+}
+
+// MountCommand returns the command mounting the folder, exported by the
+// host at hostIP, in the machine, over whatever the driver mounted there.
+func MountCommand(hostIP string, folder drivers.SharedFolder) string {
+	return fmt.Sprintf("sudo mkdir -p %[1]s && (sudo umount %[1]s 2>/dev/null; sudo %[2]s)", shellQuote(folder.GuestPath), mountCommand(hostIP, folder))
+}
+
+func mountCommand(hostIP string, folder drivers.SharedFolder) string {
+	return fmt.Sprintf("mount -t nfs -o %s %s %s", mountOptions(folder), shellQuote(hostIP+":"+folder.HostPath), shellQuote(folder.GuestPath))
+}
+
+// PersistCommand returns the command mounting the folder again when the
+// machine boots: from bootlocal.sh on boot2docker, whose root file system
+// doesn't persist, and from /etc/fstab elsewhere. The previous line mounting
+// the folder, e.g. from another address of the host, is replaced.
+func PersistCommand(hostIP string, folder drivers.SharedFolder) string {
+	bootPrefix := "umount " + shellQuote(folder.GuestPath) + " 2>/dev/null; mount -t nfs "
+	bootLine := "umount " + shellQuote(folder.GuestPath) + " 2>/dev/null; " + mountCommand(hostIP, folder)
+	fstabKey := fmt.Sprintf(" %s nfs ", fstabEscape(folder.GuestPath))
+	fstabLine := fmt.Sprintf("%s:%s %s nfs %s,_netdev 0 0", hostIP, fstabEscape(folder.HostPath), fstabEscape(folder.GuestPath), mountOptions(folder))
+
+	return fmt.Sprintf("if [ -d /var/lib/boot2docker ]; then ([ -f %[1]s ] || echo '#!/bin/sh' | sudo tee %[1]s >/dev/null) && %[2]s && sudo chmod +x %[1]s; else %[3]s; fi",
+		bootlocalPath, replaceLineCommand(bootlocalPath, bootPrefix, bootLine), replaceLineCommand("/etc/fstab", fstabKey, fstabLine))
+}
+
+// replaceLineCommand returns the command replacing the lines of file which
+// contain key by line, added at the end of the file.
+func replaceLineCommand(file, key, line string) string {
+	script := `grep -vF -- "$1" "$2" > "$2.tmp"; echo "$3" >> "$2.tmp" && cat "$2.tmp" > "$2" && rm -f "$2.tmp"`
+	return fmt.Sprintf("sudo sh -c %s sh %s %s %s", shellQuote(script), shellQuote(key), shellQuote(file), shellQuote(line))
+}
+
+// fstabEscape escapes the spaces of a path of /etc/fstab.
+func fstabEscape(path string) string {
+	return strings.Replace(path, " ", `\040`, -1)
+}
@@ -0,0 +1,165 @@
+package nfs
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSupported(t *testing.T) {
+	assert.NoError(t, Supported("darwin"))
+	assert.NoError(t, Supported("linux"))
+	assert.Error(t, Supported("windows"))
+}
+
+func TestExportLine(t *testing.T) {
+	folder := drivers.SharedFolder{HostPath: "/Users/me/src", GuestPath: "/src"}
+	readOnly := drivers.SharedFolder{HostPath: "/Users/me/src", GuestPath: "/src", ReadOnly: true}
+
+	for _, test := range []struct {
+		goos   string
+		folder drivers.SharedFolder
+		line   string
+	}{
+		{"darwin", folder, `"/Users/me/src" 192.168.99.100 -alldirs -mapall=501:20`},
+		{"darwin", readOnly, `"/Users/me/src" 192.168.99.100 -alldirs -mapall=501:20 -ro`},
+		{"linux", folder, `"/Users/me/src" 192.168.99.100(rw,no_subtree_check,insecure,all_squash,anonuid=501,anongid=20)`},
+		{"linux", readOnly, `"/Users/me/src" 192.168.99.100(ro,no_subtree_check,insecure,all_squash,anonuid=501,anongid=20)`},
+	} {
+		line, err := exportLine(test.goos, test.folder, "192.168.99.100", 501, 20)
+		assert.NoError(t, err)
+		assert.Equal(t, test.line, line)
+	}
+}
+
+func TestQuoteExportPath(t *testing.T) {
+	for _, goos := range []string{"darwin", "linux"} {
+		quoted, err := quoteExportPath(goos, "/home/me/my src/café")
+		assert.NoError(t, err)
+		assert.Equal(t, `"/home/me/my src/café"`, quoted)
+	}
+
+	quoted, err := quoteExportPath("linux", "/home/me/a\"b\\c\td\ne")
+	assert.NoError(t, err)
+	assert.Equal(t, `"/home/me/a\042b\134c\011d\012e"`, quoted)
+
+	for _, path := range []string{"/home/me/a\"b", "/home/me/a\\b", "/home/me/a\nb"} {
+		_, err := quoteExportPath("darwin", path)
+		assert.Error(t, err, path)
+	}
+}
+
+func TestRewriteExportsAddsBlock(t *testing.T) {
+	content := "/srv/other 10.0.0.1\n"
+
+	assert.Equal(t, "/srv/other 10.0.0.1\n# docker-machine dev begin\nline1\nline2\n# docker-machine dev end\n", rewriteExports(content, "dev", []string{"line1", "line2"}, ""))
+	assert.Equal(t, "# docker-machine dev begin\nline1\n# docker-machine dev end\n", rewriteExports("", "dev", []string{"line1"}, ""))
+}
+
+func TestRewriteExportsReplacesBlock(t *testing.T) {
+	content := "# docker-machine dev begin\nold\n# docker-machine dev end\n# docker-machine prod begin\nprod\n# docker-machine prod end\n"
+
+	assert.Equal(t, "# docker-machine dev begin\nnew\n# docker-machine dev end\n# docker-machine prod begin\nprod\n# docker-machine prod end\n", rewriteExports(content, "dev", []string{"new"}, ""))
+	assert.Equal(t, "# docker-machine prod begin\nprod\n# docker-machine prod end\n", rewriteExports(content, "dev", nil, ""))
+	assert.Equal(t, "", rewriteExports("# docker-machine dev begin\nold\n# docker-machine dev end\n", "dev", nil, ""))
+}
+
+func TestRewriteExportsRemovesStaleExports(t *testing.T) {
+	content := "\"/srv\" 192.168.99.100\n# docker-machine old begin\n\"/Users/me/src\" 192.168.99.100 -alldirs -mapall=501:20\n\"/Users/me/my docs\" 192.168.99.100(rw,no_subtree_check)\n\"/Users/me/tmp\" 192.168.99.101 -alldirs\n# docker-machine old end\n"
+
+	assert.Equal(t, "\"/srv\" 192.168.99.100\n# docker-machine old begin\n\"/Users/me/tmp\" 192.168.99.101 -alldirs\n# docker-machine old end\n# docker-machine dev begin\nnew\n# docker-machine dev end\n", rewriteExports(content, "dev", []string{"new"}, "192.168.99.100"))
+}
+
+func TestExportClient(t *testing.T) {
+	assert.Equal(t, "192.168.99.100", exportClient(`"/Users/me/my src" 192.168.99.100 -alldirs -mapall=501:20`))
+	assert.Equal(t, "192.168.99.100", exportClient(`"/Users/me/src" 192.168.99.100(rw,no_subtree_check)`))
+	assert.Equal(t, "", exportClient(`/srv 10.0.0.1`))
+	assert.Equal(t, "192.168.99.100", exportClient(`"/home/me/a\042b" 192.168.99.100(rw,no_subtree_check)`))
+	assert.Equal(t, "", exportClient(`"/srv 10.0.0.1`))
+}
+
+func TestWriteExports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nfs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	defer func(path, lockPath string, run func(io.Reader, string, ...string) error) {
+		exportsPath, exportsLockPath, runAsRoot = path, lockPath, run
+	}(exportsPath, exportsLockPath, runAsRoot)
+
+	exportsPath = filepath.Join(dir, "exports")
+	exportsLockPath = filepath.Join(dir, "exports.lock")
+	calls := []string{}
+	runAsRoot = func(stdin io.Reader, name string, args ...string) error {
+		calls = append(calls, name)
+		if name == "tee" {
+			content, _ := ioutil.ReadAll(stdin)
+			return ioutil.WriteFile(args[0], content, 0644)
+		}
+		return nil
+	}
+
+	assert.NoError(t, writeExports("dev", []string{"line"}, "192.168.99.100"))
+	assert.Len(t, calls, 2)
+	assert.Equal(t, "tee", calls[0])
+
+	content, _ := ioutil.ReadFile(exportsPath)
+	assert.Equal(t, "# docker-machine dev begin\nline\n# docker-machine dev end\n", string(content))
+
+	// Nothing is rewritten nor reloaded when the exports don't change.
+	assert.NoError(t, writeExports("dev", []string{"line"}, "192.168.99.100"))
+	assert.Len(t, calls, 2)
+}
+
+func TestMountOptions(t *testing.T) {
+	assert.Equal(t, "vers=3,tcp,nolock,noacl,actimeo=1", mountOptions(drivers.SharedFolder{Consistency: drivers.ConsistencyConsistent}))
+	assert.Equal(t, "vers=3,tcp,nolock,noacl", mountOptions(drivers.SharedFolder{Consistency: drivers.ConsistencyCached}))
+	assert.Equal(t, "vers=3,tcp,nolock,noacl,noatime,ro", mountOptions(drivers.SharedFolder{Consistency: drivers.ConsistencyDelegated, ReadOnly: true}))
+}
+
+func TestMountCommand(t *testing.T) {
+	folder := drivers.SharedFolder{HostPath: "/Users/me/src", GuestPath: "/src", Consistency: drivers.ConsistencyCached}
+
+	assert.Equal(t, "sudo mkdir -p '/src' && (sudo umount '/src' 2>/dev/null; sudo mount -t nfs -o vers=3,tcp,nolock,noacl '192.168.99.1:/Users/me/src' '/src')", MountCommand("192.168.99.1", folder))
+}
+
+func TestPersistCommand(t *testing.T) {
+	folder := drivers.SharedFolder{HostPath: "/Users/me/my src", GuestPath: "/src", Consistency: drivers.ConsistencyCached}
+
+	command := PersistCommand("192.168.99.1", folder)
+
+	assert.Contains(t, command, `'/var/lib/boot2docker/bootlocal.sh'`)
+	assert.Contains(t, command, `'umount '\''/src'\'' 2>/dev/null; mount -t nfs '`)
+	assert.Contains(t, command, `' /src nfs ' '/etc/fstab' '192.168.99.1:/Users/me/my\040src /src nfs vers=3,tcp,nolock,noacl,_netdev 0 0'`)
+}
+
+func TestReplaceLineCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the machines run the command with a POSIX shell")
+	}
+
+	dir, err := ioutil.TempDir("", "nfs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fstab := filepath.Join(dir, "fstab")
+	assert.NoError(t, ioutil.WriteFile(fstab, []byte("/dev/sda1 / ext4 defaults 0 1\n192.168.99.1:/old /src nfs vers=3 0 0\n"), 0644))
+
+	// The command runs without sudo here.
+	command := strings.TrimPrefix(replaceLineCommand(fstab, " /src nfs ", "192.168.56.1:/new /src nfs vers=3 0 0"), "sudo ")
+	for i := 0; i < 2; i++ {
+		output, err := exec.Command("sh", "-c", command).CombinedOutput()
+		assert.NoError(t, err, string(output))
+	}
+
+	content, _ := ioutil.ReadFile(fstab)
+	assert.Equal(t, "/dev/sda1 / ext4 defaults 0 1\n192.168.56.1:/new /src nfs vers=3 0 0\n", string(content))
+}
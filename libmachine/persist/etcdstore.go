@@ -0,0 +1,428 @@
+package persist
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnerror"
+)
+
+const (
+	// etcdErrorKeyNotFound is the error code etcd answers about a missing key.
+	etcdErrorKeyNotFound = 100
+
+	// etcdErrorTestFailed and etcdErrorNodeExist are the error codes etcd
+	// answers when the key compared with prevIndex or prevExist changed.
+	etcdErrorTestFailed = 101
+	etcdErrorNodeExist  = 105
+)
+
+// Etcdstore keeps the host records in etcd, through its v2 keys API, so that
+// several workstations or CI runners share them. Each record is the value of
+// the key <Prefix>/machines/<name>.
+//
+// The certificates and the SSH keys of the machines are shared too, in the
+// key <Prefix>/files/<name>, and written to the local store of the
+// workstations loading the machines. The disks of the machines stay in the
+// local store.
+type Etcdstore struct {
+	// Endpoint is the URL of an etcd member, e.g. http://127.0.0.1:2379.
+	Endpoint string
+	Prefix   string
+	Local    Filestore
+	Client   *http.Client
+}
+
+type etcdNode struct {
+	Key           string     `json:"key"`
+	Value         string     `json:"value"`
+	Dir           bool       `json:"dir"`
+	Nodes         []etcdNode `json:"nodes"`
+	ModifiedIndex uint64     `json:"modifiedIndex"`
+}
+
+type etcdResponse struct {
+	ErrorCode int      `json:"errorCode"`
+	Message   string   `json:"message"`
+	Node      etcdNode `json:"node"`
+}
+
+// etcdKeyNotFound is returned when the key asked for doesn't exist.
+type etcdKeyNotFound struct {
+	Key string
+}
+
+func (e etcdKeyNotFound) Error() string {
+	return fmt.Sprintf("etcd key %s not found", e.Key)
+}
+
+// etcdCompareFailed is returned when the key was changed since it was read.
+type etcdCompareFailed struct {
+	Key string
+}
+
+func (e etcdCompareFailed) Error() string {
+	return fmt.Sprintf("etcd key %s was changed", e.Key)
+}
+
+func (s Etcdstore) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+
+	return &http.Client{Timeout: 30 * time.Second}
+}
+
+func (s Etcdstore) machinesKey() string {
+	return path.Join("/", s.Prefix, "machines")
+}
+
+func (s Etcdstore) hostKey(name string) string {
+	return path.Join(s.machinesKey(), name)
+}
+
+func (s Etcdstore) filesKey(name string) string {
+	return path.Join("/", s.Prefix, "files", name)
+}
+
+func (s Etcdstore) hostDir(name string) string {
+	return filepath.Join(s.Local.getMachinesDir(), name)
+}
+
+// sharedFiles are the files of the directory of a machine shared with the
+// other workstations, relative to it.
+var sharedFiles = []string{
+	"ca.pem",
+	"cert.pem",
+	"key.pem",
+	"server.pem",
+	"server-key.pem",
+	"id_rsa",
+	"id_rsa.pub",
+	"certs/ca.pem",
+	"certs/ca-key.pem",
+	"certs/cert.pem",
+	"certs/key.pem",
+}
+
+// saveFiles shares the certificates and the keys of the machine found in
+// its local directory.
+func (s Etcdstore) saveFiles(name string) error {
+	files := map[string][]byte{}
+	for _, file := range sharedFiles {
+		data, err := ioutil.ReadFile(filepath.Join(s.hostDir(name), filepath.FromSlash(file)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		files[file] = data
+	}
+
+	if len(files) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(files)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.do("PUT", s.filesKey(name), url.Values{"value": {string(data)}})
+	return err
+}
+
+// restoreFiles writes the shared certificates and keys of the machine
+// missing from its local directory.
+func (s Etcdstore) restoreFiles(name string) error {
+	answer, err := s.do("GET", s.filesKey(name), nil)
+	if _, missing := err.(etcdKeyNotFound); missing {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	files := map[string][]byte{}
+	if err := json.Unmarshal([]byte(answer.Node.Value), &files); err != nil {
+		return fmt.Errorf("Error reading the files of %s: %s", name, err)
+	}
+
+	for _, file := range sharedFiles {
+		data, ok := files[file]
+		if !ok {
+			continue
+		}
+
+		p := filepath.Join(s.hostDir(name), filepath.FromSlash(file))
+		if _, err := os.Stat(p); err == nil {
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			return err
+		}
+
+		if err := ioutil.WriteFile(p, data, 0600); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// relocate rewrites the paths of a machine saved by another workstation to
+// those of the local store. Unless the machine has a CA of its own, the
+// copies of the certificates in its directory are used, those of the local
+// store being of another CA.
+func (s Etcdstore) relocate(h *host.Host) error {
+	authOptions := h.HostOptions.AuthOptions
+	if authOptions == nil || authOptions.StorePath == "" {
+		return nil
+	}
+
+	sep := "/"
+	if strings.Contains(authOptions.StorePath, `\`) && !strings.Contains(authOptions.StorePath, "/") {
+		sep = `\`
+	}
+
+	suffix := sep + "machines" + sep + h.Name
+	if !strings.HasSuffix(authOptions.StorePath, suffix) {
+		return nil
+	}
+
+	from := strings.TrimSuffix(authOptions.StorePath, suffix)
+	if from == s.Local.Path {
+		return nil
+	}
+
+	ownCA := strings.HasPrefix(authOptions.CaCertPath, authOptions.StorePath+sep)
+	if err := RelocateHost(h, from, sep, s.Local.Path); err != nil {
+		return err
+	}
+
+	if !ownCA {
+		dir := s.hostDir(h.Name)
+		authOptions.CertDir = dir
+		authOptions.CaCertPath = filepath.Join(dir, "ca.pem")
+		authOptions.CaPrivateKeyPath = filepath.Join(dir, "ca-key.pem")
+		authOptions.ClientCertPath = filepath.Join(dir, "cert.pem")
+		authOptions.ClientKeyPath = filepath.Join(dir, "key.pem")
+	}
+
+	return nil
+}
+
+// do sends a request about key to the keys API and decodes the answer.
+func (s Etcdstore) do(method, key string, form url.Values) (*etcdResponse, error) {
+	u := strings.TrimSuffix(s.Endpoint, "/") + "/v2/keys" + key
+
+	encoded := ""
+	if form != nil {
+		encoded = form.Encode()
+	}
+
+	req, err := http.NewRequest(method, u, strings.NewReader(encoded))
+	if err != nil {
+		return nil, err
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error reaching etcd at %s: %s", s.Endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	answer := &etcdResponse{}
+	if err := json.Unmarshal(data, answer); err != nil {
+		return nil, fmt.Errorf("Unexpected answer from etcd (%s): %s", resp.Status, err)
+	}
+
+	if answer.ErrorCode == etcdErrorKeyNotFound {
+		return nil, etcdKeyNotFound{Key: key}
+	}
+	if answer.ErrorCode == etcdErrorTestFailed || answer.ErrorCode == etcdErrorNodeExist || resp.StatusCode == http.StatusPreconditionFailed {
+		return nil, etcdCompareFailed{Key: key}
+	}
+	if answer.ErrorCode != 0 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("Error from etcd about %s (%s): %s", key, resp.Status, answer.Message)
+	}
+
+	return answer, nil
+}
+
+// Save saves the host, unless its record was saved by another command, of
+// this workstation or another one, since it was loaded. The record is only
+// replaced if it's still the one whose revision was compared, through the
+// prevIndex or prevExist conditions of etcd.
+func (s Etcdstore) Save(host *host.Host) error {
+	condition := url.Values{}
+
+	answer, err := s.do("GET", s.hostKey(host.Name), nil)
+	switch err.(type) {
+	case nil:
+		var record struct {
+			Revision int
+		}
+		if err := json.Unmarshal([]byte(answer.Node.Value), &record); err != nil {
+			return err
+		}
+		if record.Revision != host.Revision {
+			return mcnerror.ErrHostConflict{
+				Name: host.Name,
+			}
+		}
+		condition.Set("prevIndex", strconv.FormatUint(answer.Node.ModifiedIndex, 10))
+	case etcdKeyNotFound:
+		if host.Revision != 0 {
+			return mcnerror.ErrHostConflict{
+				Name: host.Name,
+			}
+		}
+		condition.Set("prevExist", "false")
+	default:
+		return err
+	}
+
+	host.Revision++
+	data, err := marshalHost(host)
+	if err != nil {
+		host.Revision--
+		return err
+	}
+
+	condition.Set("value", string(data))
+	if _, err := s.do("PUT", s.hostKey(host.Name), condition); err != nil {
+		host.Revision--
+		if _, changed := err.(etcdCompareFailed); changed {
+			return mcnerror.ErrHostConflict{
+				Name: host.Name,
+			}
+		}
+		return err
+	}
+
+	return s.saveFiles(host.Name)
+}
+
+// Lock takes the lock name of the local store: it only locks out the
+// commands of this workstation, not those of the others sharing etcd.
+func (s Etcdstore) Lock(name string) (func(), error) {
+	return s.Local.Lock(name)
+}
+
+// Remove removes the record of the machine, its shared files and its local
+// directory.
+func (s Etcdstore) Remove(name string) error {
+	for _, key := range []string{s.hostKey(name), s.filesKey(name)} {
+		if _, err := s.do("DELETE", key, nil); err != nil {
+			if _, missing := err.(etcdKeyNotFound); !missing {
+				return err
+			}
+		}
+	}
+
+	return s.Local.Remove(name)
+}
+
+func (s Etcdstore) List() ([]*host.Host, error) {
+	answer, err := s.do("GET", s.machinesKey(), nil)
+	if _, missing := err.(etcdKeyNotFound); missing {
+		return []*host.Host{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := []*host.Host{}
+
+	for _, node := range answer.Node.Nodes {
+		if node.Dir {
+			continue
+		}
+
+		name := path.Base(node.Key)
+		h, err := s.load(name, []byte(node.Value))
+		if err != nil {
+			log.Errorf("error loading host %q: %s", name, err)
+			continue
+		}
+		hosts = append(hosts, h)
+	}
+
+	return hosts, nil
+}
+
+func (s Etcdstore) Exists(name string) (bool, error) {
+	_, err := s.do("GET", s.hostKey(name), nil)
+	if _, missing := err.(etcdKeyNotFound); missing {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (s Etcdstore) Load(name string) (*host.Host, error) {
+	answer, err := s.do("GET", s.hostKey(name), nil)
+	if _, missing := err.(etcdKeyNotFound); missing {
+		return nil, mcnerror.ErrHostDoesNotExist{
+			Name: name,
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.load(name, []byte(answer.Node.Value))
+}
+
+// load reads the record of a host, saving it back if it had to be migrated,
+// and writes its shared files to the local store.
+func (s Etcdstore) load(name string, data []byte) (*host.Host, error) {
+	h, migrationPerformed, err := unmarshalHost(name, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.restoreFiles(name); err != nil {
+		return nil, fmt.Errorf("Error writing the certificates and keys of %s: %s", name, err)
+	}
+
+	if err := s.relocate(h); err != nil {
+		return nil, err
+	}
+
+	if migrationPerformed {
+		if err := s.Save(h); err != nil {
+			return nil, fmt.Errorf("Error saving config after migration was performed: %s", err)
+		}
+	}
+
+	return h, nil
+}
+
+// NewHost returns a host whose certificates are in the local store.
+func (s Etcdstore) NewHost(driver drivers.Driver) (*host.Host, error) {
+	return s.Local.NewHost(driver)
+}
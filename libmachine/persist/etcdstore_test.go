@@ -0,0 +1,372 @@
+package persist
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	_ "github.com/docker/machine/drivers/none"
+	"github.com/docker/machine/libmachine/cert"
+	"github.com/docker/machine/libmachine/hosttest"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeEtcd implements the part of the etcd v2 keys API used by the store.
+type fakeEtcd struct {
+	sync.Mutex
+	keys    map[string]string
+	indexes map[string]uint64
+	index   uint64
+}
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/v2/keys")
+	notFound := func() {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(etcdResponse{ErrorCode: etcdErrorKeyNotFound, Message: "Key not found"})
+	}
+
+	compareFailed := func(code int) {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		json.NewEncoder(w).Encode(etcdResponse{ErrorCode: code, Message: "Compare failed"})
+	}
+
+	switch r.Method {
+	case "PUT":
+		_, present := f.keys[key]
+		if r.FormValue("prevExist") == "false" && present {
+			compareFailed(etcdErrorNodeExist)
+			return
+		}
+		if prevIndex := r.FormValue("prevIndex"); prevIndex != "" && (!present || prevIndex != strconv.FormatUint(f.indexes[key], 10)) {
+			compareFailed(etcdErrorTestFailed)
+			return
+		}
+		f.index++
+		f.keys[key] = r.FormValue("value")
+		f.indexes[key] = f.index
+		json.NewEncoder(w).Encode(etcdResponse{Node: etcdNode{Key: key, Value: f.keys[key], ModifiedIndex: f.index}})
+	case "DELETE":
+		if _, present := f.keys[key]; !present {
+			notFound()
+			return
+		}
+		delete(f.keys, key)
+		json.NewEncoder(w).Encode(etcdResponse{Node: etcdNode{Key: key}})
+	case "GET":
+		if value, present := f.keys[key]; present {
+			json.NewEncoder(w).Encode(etcdResponse{Node: etcdNode{Key: key, Value: value, ModifiedIndex: f.indexes[key]}})
+			return
+		}
+
+		dir := etcdNode{Key: key, Dir: true}
+		children := []string{}
+		for k := range f.keys {
+			if strings.HasPrefix(k, key+"/") && !strings.Contains(strings.TrimPrefix(k, key+"/"), "/") {
+				children = append(children, k)
+			}
+		}
+		if len(children) == 0 {
+			notFound()
+			return
+		}
+		sort.Strings(children)
+		for _, k := range children {
+			dir.Nodes = append(dir.Nodes, etcdNode{Key: k, Value: f.keys[k]})
+		}
+		json.NewEncoder(w).Encode(etcdResponse{Node: dir})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestEtcdstore(t *testing.T) (*Etcdstore, *fakeEtcd, func()) {
+	etcd := &fakeEtcd{keys: map[string]string{}, indexes: map[string]uint64{}}
+	server := httptest.NewServer(etcd)
+
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := &Etcdstore{
+		Endpoint: server.URL,
+		Prefix:   "team",
+		Local:    Filestore{Path: tmpDir},
+	}
+
+	return store, etcd, func() {
+		server.Close()
+		os.RemoveAll(tmpDir)
+	}
+}
+
+func TestEtcdstoreSaveLoad(t *testing.T) {
+	store, etcd, closeServer := newTestEtcdstore(t)
+	defer closeServer()
+
+	h, err := hosttest.GetDefaultTestHost()
+	assert.NoError(t, err)
+
+	assert.NoError(t, store.Save(h))
+	_, present := etcd.keys["/team/machines/"+h.Name]
+	assert.True(t, present)
+
+	exists, err := store.Exists(h.Name)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	loaded, err := store.Load(h.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, h.Name, loaded.Name)
+	assert.Equal(t, h.DriverName, loaded.DriverName)
+}
+
+func TestEtcdstoreSaveConflict(t *testing.T) {
+	store, _, closeServer := newTestEtcdstore(t)
+	defer closeServer()
+
+	h, err := hosttest.GetDefaultTestHost()
+	assert.NoError(t, err)
+	assert.NoError(t, store.Save(h))
+	assert.Equal(t, 1, h.Revision)
+
+	first, err := store.Load(h.Name)
+	assert.NoError(t, err)
+	second, err := store.Load(h.Name)
+	assert.NoError(t, err)
+
+	revision := second.Revision
+	assert.NoError(t, store.Save(first))
+	assert.Equal(t, mcnerror.ErrHostConflict{Name: h.Name}, store.Save(second))
+	assert.Equal(t, revision, second.Revision)
+
+	// A host created by two commands at once.
+	created, err := hosttest.GetDefaultTestHost()
+	assert.NoError(t, err)
+	assert.Equal(t, mcnerror.ErrHostConflict{Name: h.Name}, store.Save(created))
+}
+
+func TestEtcdstoreSaveConflictWhileSaving(t *testing.T) {
+	etcd := &fakeEtcd{keys: map[string]string{}, indexes: map[string]uint64{}}
+	key := "/v2/keys/team/machines/" + hosttest.DefaultHostName
+
+	// Another workstation saves the host between the check of its revision
+	// and its save.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etcd.ServeHTTP(w, r)
+		if r.Method == "GET" && r.URL.Path == key {
+			etcd.Lock()
+			etcd.index++
+			etcd.indexes[strings.TrimPrefix(key, "/v2/keys")] = etcd.index
+			etcd.Unlock()
+		}
+	}))
+	defer server.Close()
+
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	store := &Etcdstore{Endpoint: server.URL, Prefix: "team", Local: Filestore{Path: tmpDir}}
+
+	h, err := hosttest.GetDefaultTestHost()
+	assert.NoError(t, err)
+	assert.NoError(t, store.Save(h))
+
+	assert.Equal(t, mcnerror.ErrHostConflict{Name: h.Name}, store.Save(h))
+	assert.Equal(t, 1, h.Revision)
+}
+
+func TestEtcdstoreList(t *testing.T) {
+	store, _, closeServer := newTestEtcdstore(t)
+	defer closeServer()
+
+	hosts, err := store.List()
+	assert.NoError(t, err)
+	assert.Empty(t, hosts)
+
+	h, err := hosttest.GetDefaultTestHost()
+	assert.NoError(t, err)
+	assert.NoError(t, store.Save(h))
+
+	hosts, err = store.List()
+	assert.NoError(t, err)
+	assert.Len(t, hosts, 1)
+	assert.Equal(t, h.Name, hosts[0].Name)
+}
+
+func TestEtcdstoreRemove(t *testing.T) {
+	store, etcd, closeServer := newTestEtcdstore(t)
+	defer closeServer()
+
+	h, err := hosttest.GetDefaultTestHost()
+	assert.NoError(t, err)
+	assert.NoError(t, store.Save(h))
+
+	dir := filepath.Join(store.Local.Path, "machines", h.Name)
+	assert.NoError(t, os.MkdirAll(dir, 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "id_rsa"), []byte("key"), 0600))
+	assert.NoError(t, store.Save(h))
+	_, present := etcd.keys["/team/files/"+h.Name]
+	assert.True(t, present)
+
+	assert.NoError(t, store.Remove(h.Name))
+	assert.Empty(t, etcd.keys)
+
+	_, err = os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+
+	exists, err := store.Exists(h.Name)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	assert.NoError(t, store.Remove(h.Name))
+}
+
+func TestEtcdstoreSharesFiles(t *testing.T) {
+	store, _, closeServer := newTestEtcdstore(t)
+	defer closeServer()
+
+	h, err := hosttest.GetDefaultTestHost()
+	assert.NoError(t, err)
+
+	// The machine is created on another workstation.
+	otherPath := "/home/other/.docker/machine"
+	otherDir := filepath.Join(store.Local.Path, "other")
+	other := &Etcdstore{Endpoint: store.Endpoint, Prefix: store.Prefix, Local: Filestore{Path: otherDir}}
+	hostDir := filepath.Join(otherDir, "machines", h.Name)
+	assert.NoError(t, os.MkdirAll(hostDir, 0700))
+	for _, file := range []string{"ca.pem", "cert.pem", "key.pem", "id_rsa"} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(hostDir, file), []byte(file), 0600))
+	}
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(hostDir, "disk.vmdk"), []byte("disk"), 0600))
+
+	h.HostOptions.AuthOptions.StorePath = otherPath + "/machines/" + h.Name
+	h.HostOptions.AuthOptions.CaCertPath = otherPath + "/certs/ca.pem"
+	h.HostOptions.AuthOptions.ServerCertPath = otherPath + "/machines/" + h.Name + "/server.pem"
+	h.RawDriver = []byte(`{"SSHKeyPath":"` + otherPath + `/machines/` + h.Name + `/id_rsa"}`)
+	assert.NoError(t, other.Save(h))
+
+	loaded, err := store.Load(h.Name)
+	assert.NoError(t, err)
+
+	localDir := filepath.Join(store.Local.Path, "machines", h.Name)
+	authOptions := loaded.HostOptions.AuthOptions
+	assert.Equal(t, localDir, authOptions.StorePath)
+	assert.Equal(t, filepath.Join(localDir, "server.pem"), authOptions.ServerCertPath)
+	assert.Equal(t, filepath.Join(localDir, "ca.pem"), authOptions.CaCertPath)
+	assert.Equal(t, filepath.Join(localDir, "key.pem"), authOptions.ClientKeyPath)
+	assert.Equal(t, `{"SSHKeyPath":"`+filepath.Join(localDir, "id_rsa")+`"}`, string(loaded.RawDriver))
+
+	data, err := ioutil.ReadFile(filepath.Join(localDir, "id_rsa"))
+	assert.NoError(t, err)
+	assert.Equal(t, "id_rsa", string(data))
+
+	fi, err := os.Stat(filepath.Join(localDir, "key.pem"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	_, err = os.Stat(filepath.Join(localDir, "disk.vmdk"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestEtcdstoreLoadMissingHost(t *testing.T) {
+	store, _, closeServer := newTestEtcdstore(t)
+	defer closeServer()
+
+	_, err := store.Load("missing")
+	assert.Equal(t, mcnerror.ErrHostDoesNotExist{Name: "missing"}, err)
+}
+
+func TestEtcdstoreReportsErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(etcdResponse{ErrorCode: 300, Message: "Raft Internal Error"})
+	}))
+	defer server.Close()
+
+	store := &Etcdstore{Endpoint: server.URL, Prefix: "team"}
+
+	_, err := store.Exists("dev")
+	assert.EqualError(t, err, "Error from etcd about /team/machines/dev (500 Internal Server Error): Raft Internal Error")
+}
+
+// newTestEtcdCert writes a CA and a client certificate signed by it to dir.
+func newTestEtcdCert(t *testing.T, dir string) (caCert, clientCert, clientKey string) {
+	generator := cert.NewX509CertGenerator()
+	caCert, caKey := filepath.Join(dir, "etcd-ca.pem"), filepath.Join(dir, "etcd-ca-key.pem")
+	clientCert, clientKey = filepath.Join(dir, "etcd-client.pem"), filepath.Join(dir, "etcd-client-key.pem")
+	if err := generator.GenerateCACertificate(caCert, caKey, "test", 2048); err != nil {
+		t.Fatal(err)
+	}
+	if err := generator.GenerateCert([]string{""}, clientCert, clientKey, caCert, caKey, "test", 2048); err != nil {
+		t.Fatal(err)
+	}
+
+	return caCert, clientCert, clientKey
+}
+
+func TestNewStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	caCert, clientCert, clientKey := newTestEtcdCert(t, dir)
+
+	store, err := NewStore(StoreOptions{Path: "/tmp/machine"})
+	assert.NoError(t, err)
+	assert.Equal(t, &Filestore{Path: "/tmp/machine"}, store)
+
+	store, err = NewStore(StoreOptions{Driver: EtcdDriver, URL: "https://etcd.example.com:2379/team/", EtcdCACert: caCert, EtcdCert: clientCert, EtcdKey: clientKey, Path: "/tmp/machine"})
+	assert.NoError(t, err)
+	etcdstore := store.(*Etcdstore)
+	assert.Equal(t, "https://etcd.example.com:2379", etcdstore.Endpoint)
+	assert.Equal(t, "team", etcdstore.Prefix)
+	assert.Equal(t, Filestore{Path: "/tmp/machine"}, etcdstore.Local)
+	config := etcdstore.Client.Transport.(*http.Transport).TLSClientConfig
+	assert.Len(t, config.Certificates, 1)
+	assert.NotNil(t, config.RootCAs)
+
+	store, err = NewStore(StoreOptions{Driver: EtcdDriver, URL: "http://127.0.0.1:2379"})
+	assert.NoError(t, err)
+	assert.Equal(t, &Etcdstore{Endpoint: "http://127.0.0.1:2379", Prefix: defaultEtcdPrefix}, store)
+
+	store, err = NewStore(StoreOptions{Driver: EtcdDriver, URL: "https://etcd.example.com", EtcdCert: clientCert, EtcdKey: clientKey})
+	assert.NoError(t, err)
+	assert.Equal(t, defaultEtcdPrefix, store.(*Etcdstore).Prefix)
+	assert.Nil(t, store.(*Etcdstore).Client.Transport.(*http.Transport).TLSClientConfig.RootCAs)
+}
+
+func TestNewStoreErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	caCert, clientCert, clientKey := newTestEtcdCert(t, dir)
+
+	for _, options := range []StoreOptions{
+		{Driver: "s3"},
+		{Driver: EtcdDriver},
+		{Driver: EtcdDriver, URL: "etcd.example.com:2379"},
+		{Driver: EtcdDriver, URL: "ftp://etcd.example.com"},
+		{Driver: EtcdDriver, URL: "http://etcd.example.com:2379", EtcdCert: clientCert, EtcdKey: clientKey},
+		{Driver: EtcdDriver, URL: "https://etcd.example.com:2379"},
+		{Driver: EtcdDriver, URL: "https://etcd.example.com:2379", EtcdCert: clientCert},
+		{Driver: EtcdDriver, URL: "https://etcd.example.com:2379", EtcdCert: clientCert, EtcdKey: caCert},
+		{Driver: EtcdDriver, URL: "https://etcd.example.com:2379", EtcdCACert: clientKey, EtcdCert: clientCert, EtcdKey: clientKey},
+	} {
+		_, err := NewStore(options)
+		assert.Error(t, err, options.Driver+" "+options.URL)
+	}
+}
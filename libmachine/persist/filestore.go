@@ -10,15 +10,19 @@ import (
 
 	"github.com/docker/machine/libmachine/auth"
 	"github.com/docker/machine/libmachine/drivers"
-	"github.com/docker/machine/libmachine/drivers/rpc"
 	"github.com/docker/machine/libmachine/engine"
 	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/mcnutils"
 	"github.com/docker/machine/libmachine/swarm"
 	"github.com/docker/machine/libmachine/version"
 )
 
+// lockFileName is the file of the directory of a host locked while it's
+// saved.
+const lockFileName = ".lock"
+
 type Filestore struct {
 	Path             string
 	CaCertPath       string
@@ -29,35 +33,58 @@ func (s Filestore) getMachinesDir() string {
 	return filepath.Join(s.Path, "machines")
 }
 
+// saveToFile writes the file through a temporary file renamed over it, for
+// the commands reading it at the same time never to see it half written.
 func (s Filestore) saveToFile(data []byte, file string) error {
-	return ioutil.WriteFile(file, data, 0600)
-}
+	tmp, err := ioutil.TempFile(filepath.Dir(file), "."+filepath.Base(file))
+	if err != nil {
+		return err
+	}
 
-func (s Filestore) Save(host *host.Host) error {
-	if serialDriver, ok := host.Driver.(*drivers.SerialDriver); ok {
-		// Unwrap Driver
-		host.Driver = serialDriver.Driver
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
 
-		// Re-wrap Driver when done
-		defer func() {
-			host.Driver = serialDriver
-		}()
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
 	}
 
-	// TODO: Does this belong here?
-	if rpcClientDriver, ok := host.Driver.(*rpcdriver.RPCClientDriver); ok {
-		data, err := rpcClientDriver.GetConfigRaw()
-		if err != nil {
-			return fmt.Errorf("Error getting raw config for driver: %s", err)
-		}
-		host.RawDriver = data
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		os.Remove(tmp.Name())
+		return err
 	}
 
-	data, err := json.MarshalIndent(host, "", "    ")
+	return nil
+}
+
+// savedRevision returns the revision of the record of the host, 0 if it
+// has none.
+func (s Filestore) savedRevision(name string) (int, error) {
+	data, err := ioutil.ReadFile(filepath.Join(s.getMachinesDir(), name, "config.json"))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
 	if err != nil {
-		return err
+		return 0, err
+	}
+
+	var record struct {
+		Revision int
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return 0, err
 	}
 
+	return record.Revision, nil
+}
+
+// Save saves the host, unless its record was saved by another command since
+// it was loaded. The commands saving the host at the same time take turns
+// through a lock.
+func (s Filestore) Save(host *host.Host) error {
 	hostPath := filepath.Join(s.getMachinesDir(), host.Name)
 
 	// Ensure that the directory we want to save to exists.
@@ -65,7 +92,49 @@ func (s Filestore) Save(host *host.Host) error {
 		return err
 	}
 
-	return s.saveToFile(data, filepath.Join(hostPath, "config.json"))
+	unlock, err := mcnutils.LockFile(filepath.Join(hostPath, lockFileName))
+	if err != nil {
+		return fmt.Errorf("Error locking the record of %s: %s", host.Name, err)
+	}
+	defer unlock()
+
+	revision, err := s.savedRevision(host.Name)
+	if err != nil {
+		return err
+	}
+
+	if revision != host.Revision {
+		return mcnerror.ErrHostConflict{
+			Name: host.Name,
+		}
+	}
+
+	host.Revision++
+	data, err := marshalHost(host)
+	if err == nil {
+		err = s.saveToFile(data, filepath.Join(hostPath, "config.json"))
+	}
+	if err != nil {
+		host.Revision--
+		return err
+	}
+
+	return nil
+}
+
+// Lock takes the lock name of the store, shared with the other commands
+// using the storage path.
+func (s Filestore) Lock(name string) (func(), error) {
+	if err := os.MkdirAll(s.Path, 0700); err != nil {
+		return nil, err
+	}
+
+	unlock, err := mcnutils.LockFile(filepath.Join(s.Path, "."+name+".lock"))
+	if err != nil {
+		return nil, fmt.Errorf("Error taking the %s lock: %s", name, err)
+	}
+
+	return unlock, nil
 }
 
 func (s Filestore) Remove(name string) error {
@@ -113,19 +182,13 @@ func (s Filestore) loadConfig(h *host.Host) error {
 		return err
 	}
 
-	// Remember the machine name so we don't have to pass it through each
-	// struct in the migration.
-	name := h.Name
-
-	migratedHost, migrationPerformed, err := host.MigrateHost(h, data)
+	migratedHost, migrationPerformed, err := unmarshalHost(h.Name, data)
 	if err != nil {
-		return fmt.Errorf("Error getting migrated host: %s", err)
+		return err
 	}
 
 	*h = *migratedHost
 
-	h.Name = name
-
 	// If we end up performing a migration, we should save afterwards so we don't have to do it again on subsequent invocations.
 	if migrationPerformed {
 		if err := s.saveToFile(data, filepath.Join(s.getMachinesDir(), h.Name, "config.json.bak")); err != nil {
@@ -133,7 +196,10 @@ func (s Filestore) loadConfig(h *host.Host) error {
 		}
 
 		if err := s.Save(h); err != nil {
-			return fmt.Errorf("Error saving config after migration was performed: %s", err)
+			// Another command saved the host since, migrated already.
+			if _, ok := err.(mcnerror.ErrHostConflict); !ok {
+				return fmt.Errorf("Error saving config after migration was performed: %s", err)
+			}
 		}
 	}
 
@@ -181,7 +247,7 @@ func (s Filestore) NewHost(driver drivers.Driver) (*host.Host, error) {
 		},
 		SwarmOptions: &swarm.Options{
 			Host:     "tcp://0.0.0.0:3376",
-			Image:    "swarm:latest",
+			Image:    swarm.DockerImage,
 			Strategy: "spread",
 		},
 	}
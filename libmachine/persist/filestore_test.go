@@ -1,16 +1,23 @@
 package persist
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/docker/machine/commands/mcndirs"
 	_ "github.com/docker/machine/drivers/none"
+	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/hosttest"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/secrets"
 )
 
 func cleanup() {
@@ -183,3 +190,214 @@ func TestStoreLoad(t *testing.T) {
 		t.Fatalf("GetURL is not %q, got %q", expectedURL, actualURL)
 	}
 }
+
+// getTestHost returns a test host with its raw driver configuration, which
+// isn't migrated, and so saved, again when loaded.
+func getTestHost(t *testing.T) *host.Host {
+	h, err := hosttest.GetDefaultTestHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.RawDriver, err = json.Marshal(h.Driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return h
+}
+
+func TestStoreSaveRevision(t *testing.T) {
+	defer cleanup()
+
+	store := getTestStore()
+
+	h := getTestHost(t)
+
+	for i := 1; i <= 2; i++ {
+		if err := store.Save(h); err != nil {
+			t.Fatal(err)
+		}
+
+		if h.Revision != i {
+			t.Fatalf("Revision is not %d after saving, got %d", i, h.Revision)
+		}
+	}
+
+	loaded, err := store.Load(h.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Revision != 2 {
+		t.Fatalf("Revision is not 2 when loaded, got %d", loaded.Revision)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(store.getMachinesDir(), h.Name, ".config.json*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(matches) != 0 {
+		t.Fatalf("Temporary files were left: %v", matches)
+	}
+}
+
+func TestStoreSaveConflict(t *testing.T) {
+	defer cleanup()
+
+	store := getTestStore()
+
+	h := getTestHost(t)
+
+	if err := store.Save(h); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := store.Load(h.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := store.Load(h.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Save(first); err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Save(second)
+	if _, ok := err.(mcnerror.ErrHostConflict); !ok {
+		t.Fatalf("Saving a host changed since it was loaded should fail with a conflict, got %v", err)
+	}
+
+	if second.Revision != 1 {
+		t.Fatalf("Revision should be left at 1 after the conflict, got %d", second.Revision)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	defer cleanup()
+
+	store := getTestStore()
+
+	h := getTestHost(t)
+
+	if err := store.Save(h); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			if err := Update(store, h.Name, func(h *host.Host) error {
+				h.HostOptions.EngineOptions.Labels = append(h.HostOptions.EngineOptions.Labels, fmt.Sprintf("l%d=x", i))
+				return nil
+			}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	loaded, err := store.Load(h.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(loaded.HostOptions.EngineOptions.Labels) != 3 {
+		t.Fatalf("Every update should be saved, got the labels %v", loaded.HostOptions.EngineOptions.Labels)
+	}
+}
+
+func TestLock(t *testing.T) {
+	defer cleanup()
+
+	store := getTestStore()
+
+	// The counter is only updated right when the commands take turns.
+	counter := 0
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			unlock, err := Lock(store, "test")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer unlock()
+
+			value := counter
+			runtime.Gosched()
+			counter = value + 1
+		}()
+	}
+	wg.Wait()
+
+	if counter != 5 {
+		t.Fatalf("The lock should be held by one command at a time, got %d", counter)
+	}
+}
+
+func TestStoreSaveEncryptsSecrets(t *testing.T) {
+	defer cleanup()
+
+	store := getTestStore()
+
+	key, err := secrets.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.Setenv(secrets.KeyEnvVar, os.Getenv(secrets.KeyEnvVar))
+	os.Setenv(secrets.KeyEnvVar, key)
+
+	h := getTestHost(t)
+	h.RawDriver = []byte(`{"MachineName":"test-host","AccessToken":"tok-123"}`)
+
+	if err := store.Save(h); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(h.RawDriver) != `{"MachineName":"test-host","AccessToken":"tok-123"}` {
+		t.Fatalf("The secrets of the saved host should be left in clear, got %s", h.RawDriver)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(store.getMachinesDir(), h.Name, "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var record struct {
+		RawDriver []byte
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(record.RawDriver), "tok-123") {
+		t.Fatalf("The secrets should be encrypted in the record, got %s", record.RawDriver)
+	}
+
+	loaded, err := store.Load(h.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(loaded.RawDriver), `"AccessToken":"tok-123"`) {
+		t.Fatalf("The secrets should be decrypted when loaded, got %s", loaded.RawDriver)
+	}
+
+	os.Setenv(secrets.KeyEnvVar, "")
+	if _, err := store.Load(h.Name); err == nil {
+		t.Fatal("Loading a host with encrypted secrets without the key should fail")
+	}
+}
@@ -0,0 +1,112 @@
+package persist
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/machine/drivers/none"
+	"github.com/docker/machine/libmachine/host"
+)
+
+// RelocatePath rewrites the path under the storage path from, whose
+// separator is sep, to the same path under the storage path to.
+func RelocatePath(p, from, sep, to string) string {
+	if from == "" || sep == "" {
+		return p
+	}
+
+	if p == from {
+		return to
+	}
+
+	if !strings.HasPrefix(p, from+sep) {
+		return p
+	}
+
+	parts := strings.Split(p[len(from)+len(sep):], sep)
+
+	return filepath.Join(append([]string{to}, parts...)...)
+}
+
+// RelocateJSONPaths rewrites the strings of the JSON document, wherever they
+// are in it.
+func RelocateJSONPaths(data []byte, rewrite func(string) string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	var walk func(v interface{}) interface{}
+	walk = func(v interface{}) interface{} {
+		switch v := v.(type) {
+		case string:
+			return rewrite(v)
+		case map[string]interface{}:
+			for k, e := range v {
+				v[k] = walk(e)
+			}
+		case []interface{}:
+			for i, e := range v {
+				v[i] = walk(e)
+			}
+		}
+
+		return v
+	}
+
+	return json.Marshal(walk(doc))
+}
+
+// RelocateHost rewrites the paths of the machine, of its options and of the
+// configuration of its driver, from the storage path from, whose separator
+// is sep, to the storage path to.
+func RelocateHost(h *host.Host, from, sep, to string) error {
+	rewrite := func(p string) string {
+		return RelocatePath(p, from, sep, to)
+	}
+
+	authOptions := h.HostOptions.AuthOptions
+	for _, p := range []*string{
+		&authOptions.CertDir,
+		&authOptions.CaCertPath,
+		&authOptions.CaPrivateKeyPath,
+		&authOptions.ClientCertPath,
+		&authOptions.ClientKeyPath,
+		&authOptions.ServerCertPath,
+		&authOptions.ServerKeyPath,
+		&authOptions.StorePath,
+	} {
+		*p = rewrite(*p)
+	}
+
+	if h.RawDriver != nil {
+		data, err := RelocateJSONPaths(h.RawDriver, rewrite)
+		if err != nil {
+			return fmt.Errorf("Error reading the configuration of the driver: %s", err)
+		}
+		h.RawDriver = data
+	}
+
+	if d, ok := h.Driver.(*none.Driver); ok {
+		data, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+
+		if data, err = RelocateJSONPaths(data, rewrite); err != nil {
+			return err
+		}
+
+		if err := json.Unmarshal(data, d); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,24 @@
+package persist
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelocatePath(t *testing.T) {
+	assert.Equal(t, "/home/b/.docker/machine/machines/dev/id_rsa", RelocatePath("/Users/a/.docker/machine/machines/dev/id_rsa", "/Users/a/.docker/machine", "/", "/home/b/.docker/machine"))
+	assert.Equal(t, "/home/b/.docker/machine", RelocatePath("/Users/a/.docker/machine", "/Users/a/.docker/machine", "/", "/home/b/.docker/machine"))
+	assert.Equal(t, "/home/b/.docker/machine/certs/ca.pem", RelocatePath(`C:\Users\a\.docker\machine\certs\ca.pem`, `C:\Users\a\.docker\machine`, `\`, "/home/b/.docker/machine"))
+	assert.Equal(t, "/Users/a/.docker/machine2/ca.pem", RelocatePath("/Users/a/.docker/machine2/ca.pem", "/Users/a/.docker/machine", "/", "/home/b/.docker/machine"))
+	assert.Equal(t, "/home/a/.ssh/id_rsa", RelocatePath("/home/a/.ssh/id_rsa", "/Users/a/.docker/machine", "/", "/home/b/.docker/machine"))
+}
+
+func TestRelocateJSONPaths(t *testing.T) {
+	data, err := RelocateJSONPaths([]byte(`{"SSHKeyPath":"/a/machines/dev/id_rsa","SSHPort":22,"Tags":["/a/x","y"]}`), func(p string) string {
+		return RelocatePath(p, "/a", "/", "/b")
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"SSHKeyPath":"/b/machines/dev/id_rsa","SSHPort":22,"Tags":["/b/x","y"]}`, string(data))
+}
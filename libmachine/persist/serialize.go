@@ -0,0 +1,195 @@
+package persist
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/drivers/rpc"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/secrets"
+)
+
+// The record of a host, whatever the store, is its JSON configuration. Its
+// ConfigVersion field versions the format: a record written by an older
+// version is migrated when loaded, and one written by a newer version is
+// refused rather than misread.
+
+// marshalHost returns the record of h. The configuration of a plugin driver
+// is read from the plugin first.
+func marshalHost(h *host.Host) ([]byte, error) {
+	if serialDriver, ok := h.Driver.(*drivers.SerialDriver); ok {
+		// Unwrap Driver
+		h.Driver = serialDriver.Driver
+
+		// Re-wrap Driver when done
+		defer func() {
+			h.Driver = serialDriver
+		}()
+	}
+
+	// TODO: Does this belong here?
+	if rpcClientDriver, ok := h.Driver.(*rpcdriver.RPCClientDriver); ok {
+		data, err := rpcClientDriver.GetConfigRaw()
+		if err != nil {
+			return nil, fmt.Errorf("Error getting raw config for driver: %s", err)
+		}
+		h.RawDriver = data
+	}
+
+	key, err := secrets.LookupKey()
+	if secrets.IsKeychainError(err) {
+		// The keychain may hold a key or not, the record of a machine whose
+		// secrets were encrypted can't have been loaded without it.
+		log.Warnf("The secrets of %s are saved in clear: %s", h.Name, err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if key != nil {
+		return marshalSealedHost(h, key)
+	}
+
+	return json.MarshalIndent(h, "", "    ")
+}
+
+// DriverConfig returns the JSON configuration of the driver, as saved in the
+// record of its host. The configuration of a plugin driver is read from the
+// plugin.
+func DriverConfig(d drivers.Driver) ([]byte, error) {
+	if serialDriver, ok := d.(*drivers.SerialDriver); ok {
+		d = serialDriver.Driver
+	}
+
+	if rpcClientDriver, ok := d.(*rpcdriver.RPCClientDriver); ok {
+		data, err := rpcClientDriver.GetConfigRaw()
+		if err != nil {
+			return nil, fmt.Errorf("Error getting raw config for driver: %s", err)
+		}
+		return data, nil
+	}
+
+	return json.Marshal(d)
+}
+
+// sealedDriver is marshalled as the configuration of the driver with its
+// secrets encrypted.
+type sealedDriver struct {
+	drivers.Driver
+	data []byte
+}
+
+func (d sealedDriver) MarshalJSON() ([]byte, error) {
+	return d.data, nil
+}
+
+// marshalSealedHost returns the record of h with the secrets of the
+// configuration of its driver encrypted with key. h keeps them in clear.
+func marshalSealedHost(h *host.Host, key []byte) ([]byte, error) {
+	sealed := *h
+
+	if h.RawDriver != nil {
+		data, err := secrets.SealFields(key, h.RawDriver)
+		if err != nil {
+			return nil, fmt.Errorf("Error encrypting the secrets of the driver: %s", err)
+		}
+		sealed.RawDriver = data
+	}
+
+	if h.Driver != nil {
+		data, err := json.Marshal(h.Driver)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err = secrets.SealFields(key, data)
+		if err != nil {
+			return nil, fmt.Errorf("Error encrypting the secrets of the driver: %s", err)
+		}
+		sealed.Driver = sealedDriver{h.Driver, data}
+	}
+
+	return json.MarshalIndent(&sealed, "", "    ")
+}
+
+// openHost decrypts the secrets of the configuration of the driver in the
+// record, encrypted when it was saved with a secrets key.
+func openHost(data []byte) ([]byte, error) {
+	var record map[string]json.RawMessage
+	if err := json.Unmarshal(data, &record); err != nil {
+		// Left for the migration to report.
+		return data, nil
+	}
+
+	// A record without encrypted secrets is loaded when the keychain can't
+	// be read, the others fail with why.
+	key, keyErr := secrets.LookupKey()
+	if keyErr != nil && !secrets.IsKeychainError(keyErr) {
+		return nil, keyErr
+	}
+
+	if driver, ok := record["Driver"]; ok {
+		opened, err := secrets.OpenFields(key, driver)
+		if err == secrets.ErrNoKey && keyErr != nil {
+			return nil, keyErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		record["Driver"] = opened
+	}
+
+	if rawDriver, ok := record["RawDriver"]; ok {
+		var raw []byte
+		if err := json.Unmarshal(rawDriver, &raw); err == nil && raw != nil {
+			opened, err := secrets.OpenFields(key, raw)
+			if err == secrets.ErrNoKey && keyErr != nil {
+				return nil, keyErr
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			if record["RawDriver"], err = json.Marshal(opened); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return json.Marshal(record)
+}
+
+// unmarshalHost reads the record of the host name, migrating it to the
+// current version if needed. It returns whether a migration was performed,
+// in which case the store should save the host again.
+func unmarshalHost(name string, data []byte) (*host.Host, bool, error) {
+	data, err := openHost(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("Error decrypting the secrets of %s: %s", name, err)
+	}
+
+	h := &host.Host{
+		Name: name,
+	}
+
+	migratedHost, migrationPerformed, err := host.MigrateHost(h, data)
+	if err != nil {
+		return nil, false, fmt.Errorf("Error getting migrated host: %s", err)
+	}
+
+	// Remember the machine name so we don't have to pass it through each
+	// struct in the migration.
+	migratedHost.Name = name
+
+	// Keep the revision of the record through the migrations, for the store
+	// to tell whether it was saved since.
+	var record struct {
+		Revision int
+	}
+	if err := json.Unmarshal(data, &record); err == nil {
+		migratedHost.Revision = record.Revision
+	}
+
+	return migratedHost, migrationPerformed, nil
+}
@@ -1,8 +1,20 @@
 package persist
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/mcnerror"
 )
 
 type Store interface {
@@ -24,3 +36,194 @@ type Store interface {
 	// Save persists a machine in the store
 	Save(host *host.Host) error
 }
+
+// Locker is implemented by the stores which lock out the other commands
+// using them, e.g. while allocating something among the hosts.
+type Locker interface {
+	// Lock takes the lock name, waiting for the command holding it. The
+	// lock is released by the returned function.
+	Lock(name string) (func(), error)
+}
+
+var (
+	processLocksMutex sync.Mutex
+	processLocks      = map[string]*sync.Mutex{}
+)
+
+// Lock takes the lock name of the store, waiting for the command holding
+// it. The stores which can't be locked are only locked within the command.
+func Lock(store Store, name string) (func(), error) {
+	if locker, ok := store.(Locker); ok {
+		return locker.Lock(name)
+	}
+
+	processLocksMutex.Lock()
+	mutex, ok := processLocks[name]
+	if !ok {
+		mutex = &sync.Mutex{}
+		processLocks[name] = mutex
+	}
+	processLocksMutex.Unlock()
+
+	mutex.Lock()
+	return mutex.Unlock, nil
+}
+
+// updateAttempts is how many times Update loads and edits a host saved by
+// other commands in the meantime before giving up.
+const updateAttempts = 5
+
+// Update loads the host name, edits it with update and saves it. The host is
+// loaded and edited again when another command saved it in the meantime.
+func Update(store Store, name string, update func(h *host.Host) error) error {
+	var err error
+	for i := 0; i < updateAttempts; i++ {
+		var h *host.Host
+		h, err = store.Load(name)
+		if err != nil {
+			return err
+		}
+
+		if err = update(h); err != nil {
+			return err
+		}
+
+		err = store.Save(h)
+		if _, ok := err.(mcnerror.ErrHostConflict); !ok {
+			return err
+		}
+	}
+
+	return err
+}
+
+const (
+	// FilestoreDriver keeps the hosts in the local storage path.
+	FilestoreDriver = "filestore"
+
+	// EtcdDriver keeps the hosts in etcd.
+	EtcdDriver = "etcd"
+
+	defaultEtcdPrefix = "docker-machine"
+)
+
+// StoreOptions selects and configures a store.
+type StoreOptions struct {
+	// Driver is the kind of store, FilestoreDriver when empty.
+	Driver string
+
+	// URL locates a remote store, e.g. http://127.0.0.1:2379/team for etcd,
+	// where the path is the prefix of the keys.
+	URL string
+
+	// EtcdCACert is the CA verifying the certificate of etcd, those of the
+	// system when empty. EtcdCert and EtcdKey are the client certificate
+	// and key with which etcd authenticates the workstation, required
+	// unless etcd is on the local host.
+	EtcdCACert string
+	EtcdCert   string
+	EtcdKey    string
+
+	Path             string
+	CaCertPath       string
+	CaPrivateKeyPath string
+}
+
+// NewStore returns the store described by the options.
+func NewStore(options StoreOptions) (Store, error) {
+	local := Filestore{
+		Path:             options.Path,
+		CaCertPath:       options.CaCertPath,
+		CaPrivateKeyPath: options.CaPrivateKeyPath,
+	}
+
+	switch options.Driver {
+	case "", FilestoreDriver:
+		return &local, nil
+	case EtcdDriver:
+		if options.URL == "" {
+			return nil, fmt.Errorf("The %s storage driver needs a storage URL", EtcdDriver)
+		}
+
+		u, err := url.Parse(options.URL)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid storage URL %q: %s", options.URL, err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("Invalid storage URL %q: an http or https URL is expected", options.URL)
+		}
+		// The keys of the machines are kept in etcd too.
+		if !isLoopback(u.Hostname()) {
+			if u.Scheme == "http" {
+				return nil, fmt.Errorf("Invalid storage URL %q: etcd must be reached with https, the store holds the keys of the machines", options.URL)
+			}
+			if options.EtcdCert == "" || options.EtcdKey == "" {
+				return nil, fmt.Errorf("The %s storage driver needs a client certificate and key for etcd to authenticate the workstation, the store holds the keys of the machines", EtcdDriver)
+			}
+		}
+
+		client, err := etcdClient(options)
+		if err != nil {
+			return nil, err
+		}
+
+		prefix := strings.Trim(u.Path, "/")
+		if prefix == "" {
+			prefix = defaultEtcdPrefix
+		}
+
+		return &Etcdstore{
+			Endpoint: u.Scheme + "://" + u.Host,
+			Prefix:   prefix,
+			Local:    local,
+			Client:   client,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("Unknown storage driver %q, expected %s or %s", options.Driver, FilestoreDriver, EtcdDriver)
+}
+
+// etcdClient returns the client reaching etcd with the CA and the client
+// certificate of the options, nil for the default one when there are none.
+func etcdClient(options StoreOptions) (*http.Client, error) {
+	if options.EtcdCACert == "" && options.EtcdCert == "" && options.EtcdKey == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{}
+
+	if options.EtcdCACert != "" {
+		caCert, err := ioutil.ReadFile(options.EtcdCACert)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading the CA of etcd: %s", err)
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("Error reading the CA of etcd: no certificate found in %s", options.EtcdCACert)
+		}
+	}
+
+	if options.EtcdCert != "" || options.EtcdKey != "" {
+		keyPair, err := tls.LoadX509KeyPair(options.EtcdCert, options.EtcdKey)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading the client certificate for etcd: %s", err)
+		}
+		config.Certificates = []tls.Certificate{keyPair}
+	}
+
+	return &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: config},
+	}, nil
+}
+
+// isLoopback returns whether host is the local host.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -60,7 +60,7 @@ func (provisioner *ArchProvisioner) Package(name string, action pkgaction.Packag
 
 	pacmanOpts = pacmanOpts + " --noconfirm --noprogressbar"
 
-	command := fmt.Sprintf("sudo -E pacman %s %s", pacmanOpts, name)
+	command := fmt.Sprintf("%ssudo -E pacman %s %s", proxyEnvPrefix(provisioner.EngineOptions), pacmanOpts, name)
 
 	log.Debugf("package: action=%s name=%s", action.String(), name)
 
@@ -85,7 +85,7 @@ func (provisioner *ArchProvisioner) Provision(swarmOptions swarm.Options, authOp
 	provisioner.SwarmOptions = swarmOptions
 	provisioner.AuthOptions = authOptions
 	provisioner.EngineOptions = engineOptions
-	swarmOptions.Env = engineOptions.Env
+	swarmOptions.Env = engineOptions.Environment()
 
 	if provisioner.EngineOptions.StorageDriver == "" {
 		provisioner.EngineOptions.StorageDriver = "overlay"
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"path"
+	"strings"
 	"text/template"
 	"time"
 
@@ -21,6 +22,8 @@ import (
 	"github.com/docker/machine/libmachine/swarm"
 )
 
+const b2dBootsyncPath = "/var/lib/boot2docker/bootsync.sh"
+
 func init() {
 	Register("boot2docker", &RegisteredProvisioner{
 		New: NewBoot2DockerProvisioner,
@@ -72,6 +75,12 @@ func (provisioner *Boot2DockerProvisioner) upgradeIso() error {
 		}
 	}
 
+	return provisioner.replaceIso(b2dutils, d.Boot2DockerURL)
+}
+
+// replaceIso stops the machine, replaces its ISO with the one downloaded
+// from isoURL, or the cached default one if empty, and starts it again.
+func (provisioner *Boot2DockerProvisioner) replaceIso(b2dutils *mcnutils.B2dUtils, isoURL string) error {
 	log.Info("Stopping machine to do the upgrade...")
 
 	if err := provisioner.Driver.Stop(); err != nil {
@@ -88,7 +97,7 @@ func (provisioner *Boot2DockerProvisioner) upgradeIso() error {
 
 	// Either download the latest version of the b2d url that was explicitly
 	// specified when creating the VM or copy the (updated) default ISO
-	if err := b2dutils.CopyIsoToMachineDir(d.Boot2DockerURL, machineName); err != nil {
+	if err := b2dutils.CopyIsoToMachineDir(isoURL, machineName); err != nil {
 		return err
 	}
 
@@ -101,6 +110,43 @@ func (provisioner *Boot2DockerProvisioner) upgradeIso() error {
 	return mcnutils.WaitFor(drivers.MachineInState(provisioner.Driver, state.Running))
 }
 
+// AvailableEngineVersions lists the engine versions of the boot2docker
+// releases, which are tagged with the version of the engine they ship.
+func (provisioner *Boot2DockerProvisioner) AvailableEngineVersions() ([]string, error) {
+	releases, err := mcnutils.NewB2dUtils(mcndirs.GetBaseDir()).ListBoot2DockerReleases("", false)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []string{}
+	for _, release := range releases {
+		if !release.PreRelease {
+			versions = append(versions, strings.TrimPrefix(release.Tag, "v"))
+		}
+	}
+
+	return versions, nil
+}
+
+// InstallEngineVersion replaces the ISO of the machine with the one of the
+// boot2docker release shipping the engine version.
+func (provisioner *Boot2DockerProvisioner) InstallEngineVersion(version string) error {
+	b2dutils := mcnutils.NewB2dUtils(mcndirs.GetBaseDir())
+
+	releases, err := b2dutils.ListBoot2DockerReleases("", false)
+	if err != nil {
+		return err
+	}
+
+	for _, release := range releases {
+		if strings.TrimPrefix(release.Tag, "v") == version {
+			return provisioner.replaceIso(b2dutils, release.ISOURL)
+		}
+	}
+
+	return fmt.Errorf("no boot2docker release ships engine version %s", version)
+}
+
 func (provisioner *Boot2DockerProvisioner) Package(name string, action pkgaction.PackageAction) error {
 	if name == "docker" && action == pkgaction.Upgrade {
 		if err := provisioner.upgradeIso(); err != nil {
@@ -134,6 +180,10 @@ func (provisioner *Boot2DockerProvisioner) GetAuthOptions() auth.Options {
 	return provisioner.AuthOptions
 }
 
+func (provisioner *Boot2DockerProvisioner) GetEngineOptions() engine.Options {
+	return provisioner.EngineOptions
+}
+
 func (provisioner *Boot2DockerProvisioner) GenerateDockerOptions(dockerPort int) (*DockerOptions, error) {
 	var (
 		engineCfg bytes.Buffer
@@ -157,7 +207,7 @@ DOCKER_TLS=auto
 SERVERKEY={{.AuthOptions.ServerKeyRemotePath}}
 SERVERCERT={{.AuthOptions.ServerCertRemotePath}}
 
-{{range .EngineOptions.Env}}export \"{{ printf "%q" . }}\"
+{{range .EngineOptions.Environment}}export \"{{ printf "%q" . }}\"
 {{end}}
 `
 	t, err := template.New("engineConfig").Parse(engineConfigTmpl)
@@ -235,7 +285,7 @@ func (provisioner *Boot2DockerProvisioner) Provision(swarmOptions swarm.Options,
 	provisioner.SwarmOptions = swarmOptions
 	provisioner.AuthOptions = authOptions
 	provisioner.EngineOptions = engineOptions
-	swarmOptions.Env = engineOptions.Env
+	swarmOptions.Env = engineOptions.Environment()
 
 	if provisioner.EngineOptions.StorageDriver == "" {
 		provisioner.EngineOptions.StorageDriver = "aufs"
@@ -275,3 +325,13 @@ func (provisioner *Boot2DockerProvisioner) SSHCommand(args string) (string, erro
 func (provisioner *Boot2DockerProvisioner) GetDriver() drivers.Driver {
 	return provisioner.Driver
 }
+
+// b2dRunAtBootCommands returns the commands which make boot2docker run cmd as
+// root at every boot, from the bootsync script of its persistent storage.
+// Running them again doesn't add cmd twice.
+func b2dRunAtBootCommands(cmd string) []string {
+	return []string{
+		fmt.Sprintf("sudo touch %s && sudo chmod +x %s", b2dBootsyncPath, b2dBootsyncPath),
+		fmt.Sprintf("grep -qxF '%s' %s || echo '%s' | sudo tee -a %s", cmd, b2dBootsyncPath, cmd, b2dBootsyncPath),
+	}
+}
@@ -0,0 +1,271 @@
+package provision
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// remoteCert is a certificate or key to install on the remote machine.
+type remoteCert struct {
+	Path    string
+	Content []byte
+}
+
+func stagedPath(path string) string {
+	return path + ".new"
+}
+
+func backupPath(path string) string {
+	return path + ".bak"
+}
+
+// certSwap installs a new set of TLS certificates without losing the old ones
+// until the new ones are known to work. Both the remote certificates and the
+// local server certificate are first written next to their final location,
+// and only moved into place once the daemon came up with them. The remote
+// configuration files of the daemon rewritten meanwhile are backed up too.
+type certSwap struct {
+	SSHCommander
+
+	// Remote is the list of certificates to install on the remote machine.
+	Remote []remoteCert
+	// Configs are the remote configuration files rewritten with the
+	// certificates, restored if the swap fails.
+	Configs []string
+	// Local is the list of local files which were written to their staged
+	// path and must be moved into place once the swap succeeds.
+	Local []string
+}
+
+func (s *certSwap) remotePaths() []string {
+	paths := append([]string{}, s.Configs...)
+	for _, c := range s.Remote {
+		paths = append(paths, c.Path)
+	}
+
+	return paths
+}
+
+// stage uploads the new remote certificates next to the current ones and
+// backs up the configuration files. Stale backups left by an earlier swap
+// are removed first, so that a rollback can't restore them.
+func (s *certSwap) stage() error {
+	if _, err := s.SSHCommand(s.cleanupCommand()); err != nil {
+		return err
+	}
+
+	if len(s.Configs) > 0 {
+		cmds := []string{}
+		for _, path := range s.Configs {
+			cmds = append(cmds, fmt.Sprintf("if [ -f %s ]; then sudo cp -f %s %s; fi", path, path, backupPath(path)))
+		}
+		if _, err := s.SSHCommand(strings.Join(cmds, " && ")); err != nil {
+			return err
+		}
+	}
+
+	// printf will choke if we don't pass a format string because of the
+	// dashes, so that's the reason for the '%%s'
+	certTransferCmdFmt := "printf '%%s' '%s' | sudo tee %s"
+
+	for _, c := range s.Remote {
+		if _, err := s.SSHCommand(fmt.Sprintf(certTransferCmdFmt, string(c.Content), stagedPath(c.Path))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// swapCommand backs up the current remote certificates and moves the staged
+// ones into place, in a single command so that a dropped connection can't
+// leave only some of them replaced.
+func (s *certSwap) swapCommand() string {
+	cmds := []string{}
+	for _, c := range s.Remote {
+		cmds = append(cmds, fmt.Sprintf("if [ -f %s ]; then sudo cp -f %s %s; fi", c.Path, c.Path, backupPath(c.Path)))
+	}
+	for _, c := range s.Remote {
+		cmds = append(cmds, fmt.Sprintf("sudo mv -f %s %s", stagedPath(c.Path), c.Path))
+	}
+
+	return strings.Join(cmds, " && ")
+}
+
+// rollbackCommand puts the backed up remote certificates and configuration
+// files back in place.
+func (s *certSwap) rollbackCommand() string {
+	cmds := []string{}
+	for _, path := range s.remotePaths() {
+		cmds = append(cmds, fmt.Sprintf("if [ -f %s ]; then sudo mv -f %s %s; fi", backupPath(path), backupPath(path), path))
+	}
+	for _, c := range s.Remote {
+		cmds = append(cmds, fmt.Sprintf("sudo rm -f %s", stagedPath(c.Path)))
+	}
+
+	return strings.Join(cmds, "; ")
+}
+
+// cleanupCommand removes the backups of the replaced remote certificates and
+// configuration files.
+func (s *certSwap) cleanupCommand() string {
+	paths := []string{}
+	for _, path := range s.remotePaths() {
+		paths = append(paths, backupPath(path))
+	}
+
+	return "sudo rm -f " + strings.Join(paths, " ")
+}
+
+// Run stages the certificates and calls configure, which is expected to stop
+// the daemon and rewrite its configuration, then swaps the certificates and
+// calls activate, which is expected to start the daemon and check it
+// accepts them. If any step fails, the old remote certificates and
+// configuration files are restored, restore is called to bring the daemon
+// back up with them and the local files are restored.
+func (s *certSwap) Run(configure, activate, restore func() error) error {
+	err := s.stage()
+	if err == nil {
+		err = configure()
+	}
+	if err == nil {
+		_, err = s.SSHCommand(s.swapCommand())
+	}
+	if err == nil {
+		err = activate()
+	}
+	if err == nil {
+		err = s.commitLocal()
+	}
+
+	if err != nil {
+		log.Warnf("Installing the new certificates failed, rolling back to the previous ones: %s", err)
+		s.rollback(restore)
+		return err
+	}
+
+	if _, err := s.SSHCommand(s.cleanupCommand()); err != nil {
+		log.Debugf("Unable to remove the backups of the previous certificates: %s", err)
+	}
+	s.discardLocalBackups()
+
+	return nil
+}
+
+// commitLocal moves the staged local files into place, backing up the
+// current ones. If one can't be, those already moved are put back.
+func (s *certSwap) commitLocal() error {
+	for i, path := range s.Local {
+		err := os.Rename(path, backupPath(path))
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		if err == nil {
+			err = os.Rename(stagedPath(path), path)
+		}
+		if err != nil {
+			s.restoreLocal(s.Local[:i+1])
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restoreLocal puts the backups of the local files back in place.
+func (s *certSwap) restoreLocal(paths []string) {
+	for _, path := range paths {
+		if _, err := os.Stat(backupPath(path)); err != nil {
+			continue
+		}
+		if err := os.Rename(backupPath(path), path); err != nil {
+			log.Errorf("Unable to restore %s: %s", path, err)
+		}
+	}
+}
+
+func (s *certSwap) rollback(restore func() error) {
+	if _, err := s.SSHCommand(s.rollbackCommand()); err != nil {
+		log.Errorf("Unable to restore the previous certificates on the remote machine: %s", err)
+	}
+
+	if err := restore(); err != nil {
+		log.Errorf("Unable to restart the daemon with the previous certificates: %s", err)
+	}
+
+	s.discardLocal()
+}
+
+// discardLocal removes the staged local files which weren't moved into place.
+func (s *certSwap) discardLocal() {
+	for _, path := range s.Local {
+		os.Remove(stagedPath(path))
+	}
+}
+
+// discardLocalBackups removes the backups of the replaced local files.
+func (s *certSwap) discardLocalBackups() {
+	for _, path := range s.Local {
+		os.Remove(backupPath(path))
+	}
+}
+
+// daemonTLSCheck returns the check of the daemon at addr once it's restarted
+// with the new certificates, or nil if the machine of d is reached through a
+// bastion: its daemon isn't reached directly then, only its listening is
+// checked.
+func daemonTLSCheck(d drivers.Driver, addr string, caCert []byte, clientCertPath, clientKeyPath string) func() error {
+	if jump := drivers.GetSSHProxyJump(d); jump != "" {
+		log.Debugf("Not checking the TLS of the daemon at %s, the machine is reached through %s", addr, jump)
+		return nil
+	}
+
+	return func() error {
+		return checkDaemonTLS(addr, caCert, clientCertPath, clientKeyPath)
+	}
+}
+
+// checkDaemonTLS checks the daemon at addr is reached with TLS, presenting a
+// certificate of the CA caCert and accepting the client certificate.
+func checkDaemonTLS(addr string, caCert []byte, clientCertPath, clientKeyPath string) error {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return errors.New("Error reading the CA certificate")
+	}
+
+	keyPair, err := tls.LoadX509KeyPair(clientCertPath, clientKeyPath)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				RootCAs:      pool,
+				Certificates: []tls.Certificate{keyPair},
+			},
+		},
+	}
+
+	resp, err := client.Get("https://" + addr + "/_ping")
+	if err != nil {
+		return fmt.Errorf("Error reaching the daemon with the new certificates: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Error reaching the daemon with the new certificates: %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,219 @@
+package provision
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/cert"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSSHCommander struct {
+	commands []string
+	failOn   string
+	stdOut   map[string]string
+}
+
+func (f *fakeSSHCommander) SSHCommand(args string) (string, error) {
+	f.commands = append(f.commands, args)
+	if f.failOn != "" && strings.Contains(args, f.failOn) {
+		return "", errors.New("connection lost")
+	}
+
+	return f.stdOut[args], nil
+}
+
+func newTestCertSwap(t *testing.T, ssh SSHCommander) (*certSwap, string) {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := filepath.Join(dir, "server.pem")
+	ioutil.WriteFile(local, []byte("old"), 0600)
+	ioutil.WriteFile(stagedPath(local), []byte("new"), 0600)
+
+	return &certSwap{
+		SSHCommander: ssh,
+		Remote:       []remoteCert{{"/etc/docker/server.pem", []byte("new")}},
+		Local:        []string{local},
+	}, dir
+}
+
+func TestCertSwapRun(t *testing.T) {
+	ssh := &fakeSSHCommander{}
+	swap, dir := newTestCertSwap(t, ssh)
+	defer os.RemoveAll(dir)
+
+	restored := false
+	err := swap.Run(func() error { return nil }, func() error { return nil }, func() error { restored = true; return nil })
+
+	assert.NoError(t, err)
+	assert.False(t, restored)
+	assert.Equal(t, []string{
+		"sudo rm -f /etc/docker/server.pem.bak",
+		"printf '%s' 'new' | sudo tee /etc/docker/server.pem.new",
+		"if [ -f /etc/docker/server.pem ]; then sudo cp -f /etc/docker/server.pem /etc/docker/server.pem.bak; fi && sudo mv -f /etc/docker/server.pem.new /etc/docker/server.pem",
+		"sudo rm -f /etc/docker/server.pem.bak",
+	}, ssh.commands)
+
+	content, _ := ioutil.ReadFile(swap.Local[0])
+	assert.Equal(t, "new", string(content))
+	_, err = os.Stat(stagedPath(swap.Local[0]))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(backupPath(swap.Local[0]))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCertSwapRollsBackWhenDaemonFails(t *testing.T) {
+	ssh := &fakeSSHCommander{}
+	swap, dir := newTestCertSwap(t, ssh)
+	defer os.RemoveAll(dir)
+
+	restored := false
+	err := swap.Run(func() error { return nil }, func() error { return errors.New("daemon not listening") }, func() error { restored = true; return nil })
+
+	assert.EqualError(t, err, "daemon not listening")
+	assert.True(t, restored)
+	assert.Equal(t, "if [ -f /etc/docker/server.pem.bak ]; then sudo mv -f /etc/docker/server.pem.bak /etc/docker/server.pem; fi; sudo rm -f /etc/docker/server.pem.new", ssh.commands[len(ssh.commands)-1])
+
+	content, _ := ioutil.ReadFile(swap.Local[0])
+	assert.Equal(t, "old", string(content))
+	_, err = os.Stat(stagedPath(swap.Local[0]))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCertSwapRollsBackWhenUploadFails(t *testing.T) {
+	ssh := &fakeSSHCommander{failOn: "sudo tee"}
+	swap, dir := newTestCertSwap(t, ssh)
+	defer os.RemoveAll(dir)
+
+	activated, restored := false, false
+	err := swap.Run(func() error { return nil }, func() error { activated = true; return nil }, func() error { restored = true; return nil })
+
+	assert.EqualError(t, err, "connection lost")
+	assert.False(t, activated)
+	assert.True(t, restored)
+	for _, cmd := range ssh.commands {
+		assert.NotContains(t, cmd, "sudo cp -f")
+	}
+
+	content, _ := ioutil.ReadFile(swap.Local[0])
+	assert.Equal(t, "old", string(content))
+}
+
+func TestCertSwapRestoresConfigsWhenConfigureFails(t *testing.T) {
+	ssh := &fakeSSHCommander{}
+	swap, dir := newTestCertSwap(t, ssh)
+	defer os.RemoveAll(dir)
+	swap.Configs = []string{"/etc/default/docker"}
+
+	activated, restored := false, false
+	err := swap.Run(func() error { return errors.New("disk full") }, func() error { activated = true; return nil }, func() error { restored = true; return nil })
+
+	assert.EqualError(t, err, "disk full")
+	assert.False(t, activated)
+	assert.True(t, restored)
+	assert.Equal(t, []string{
+		"sudo rm -f /etc/default/docker.bak /etc/docker/server.pem.bak",
+		"if [ -f /etc/default/docker ]; then sudo cp -f /etc/default/docker /etc/default/docker.bak; fi",
+		"printf '%s' 'new' | sudo tee /etc/docker/server.pem.new",
+		"if [ -f /etc/default/docker.bak ]; then sudo mv -f /etc/default/docker.bak /etc/default/docker; fi; " +
+			"if [ -f /etc/docker/server.pem.bak ]; then sudo mv -f /etc/docker/server.pem.bak /etc/docker/server.pem; fi; " +
+			"sudo rm -f /etc/docker/server.pem.new",
+	}, ssh.commands)
+}
+
+func TestCertSwapRestoresLocalFilesWhenCommitFails(t *testing.T) {
+	ssh := &fakeSSHCommander{}
+	swap, dir := newTestCertSwap(t, ssh)
+	defer os.RemoveAll(dir)
+
+	// The key has no staged file to be moved into place.
+	key := filepath.Join(dir, "server-key.pem")
+	ioutil.WriteFile(key, []byte("old key"), 0600)
+	swap.Local = append(swap.Local, key)
+
+	err := swap.Run(func() error { return nil }, func() error { return nil }, func() error { return nil })
+
+	assert.Error(t, err)
+	content, _ := ioutil.ReadFile(swap.Local[0])
+	assert.Equal(t, "old", string(content))
+	content, _ = ioutil.ReadFile(key)
+	assert.Equal(t, "old key", string(content))
+}
+
+func TestCheckDaemonTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	generator := cert.NewX509CertGenerator()
+	caCertPath, caKeyPath := filepath.Join(dir, "ca.pem"), filepath.Join(dir, "ca-key.pem")
+	serverCertPath, serverKeyPath := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server-key.pem")
+	clientCertPath, clientKeyPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	assert.NoError(t, generator.GenerateCACertificate(caCertPath, caKeyPath, "test", 2048))
+	assert.NoError(t, generator.GenerateCert([]string{"127.0.0.1"}, serverCertPath, serverKeyPath, caCertPath, caKeyPath, "test", 2048))
+	assert.NoError(t, generator.GenerateCert([]string{""}, clientCertPath, clientKeyPath, caCertPath, caKeyPath, "test", 2048))
+
+	caCert, err := ioutil.ReadFile(caCertPath)
+	assert.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(caCert)
+	serverKeyPair, err := tls.LoadX509KeyPair(serverCertPath, serverKeyPath)
+	assert.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverKeyPair},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	server.StartTLS()
+	defer server.Close()
+	addr := strings.TrimPrefix(server.URL, "https://")
+
+	assert.NoError(t, checkDaemonTLS(addr, caCert, clientCertPath, clientKeyPath))
+
+	// A daemon still presenting the certificate of another CA.
+	otherCACertPath := filepath.Join(dir, "other-ca.pem")
+	assert.NoError(t, generator.GenerateCACertificate(otherCACertPath, filepath.Join(dir, "other-ca-key.pem"), "other", 2048))
+	otherCACert, err := ioutil.ReadFile(otherCACertPath)
+	assert.NoError(t, err)
+
+	assert.Error(t, checkDaemonTLS(addr, otherCACert, clientCertPath, clientKeyPath))
+}
+
+func TestDaemonTLSCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Nothing listens on the port of the daemon.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	direct := &fakedriver.Driver{BaseDriver: &drivers.BaseDriver{}}
+	check := daemonTLSCheck(direct, addr, nil, filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"))
+	if assert.NotNil(t, check) {
+		assert.Error(t, check())
+	}
+
+	jumped := &fakedriver.Driver{BaseDriver: &drivers.BaseDriver{SSHProxyJump: "ops@bastion.example.com"}}
+	assert.Nil(t, daemonTLSCheck(jumped, addr, nil, filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")))
+}
@@ -0,0 +1,119 @@
+package provision
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"path"
+
+	"github.com/docker/machine/libmachine/cert"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const b2dPersistentCertsDir = "/var/lib/boot2docker/certs"
+
+// caTrustStore returns the directory where the guest OS picks up additional
+// CA certificates, the extension it expects them to have and the command
+// which rebuilds the system trust bundle from it.
+func caTrustStore(p Provisioner) (dir, ext, update string, err error) {
+	switch p.(type) {
+	case *RedHatProvisioner, *CentosProvisioner, *FedoraProvisioner:
+		return "/etc/pki/ca-trust/source/anchors", "pem", "sudo update-ca-trust extract", nil
+	case *SUSEProvisioner:
+		return "/etc/pki/trust/anchors", "pem", "sudo update-ca-certificates", nil
+	case *ArchProvisioner:
+		return "/etc/ca-certificates/trust-source/anchors", "crt", "sudo trust extract-compat", nil
+	case *IgnitionProvisioner:
+		if info := p.(*IgnitionProvisioner).OsReleaseInfo; info != nil && info.ID == "fedora" {
+			return "/etc/pki/ca-trust/source/anchors", "pem", "sudo update-ca-trust extract", nil
+		}
+		return "/etc/ssl/certs", "pem", "sudo update-ca-certificates", nil
+	case *CoreOSProvisioner:
+		return "/etc/ssl/certs", "pem", "sudo update-ca-certificates", nil
+	case *DebianProvisioner, *UbuntuProvisioner, *UbuntuSystemdProvisioner:
+		return "/usr/local/share/ca-certificates", "crt", "sudo update-ca-certificates", nil
+	}
+
+	return "", "", "", fmt.Errorf("installing CA certificates is not supported by the %T provisioner", p)
+}
+
+// caCertName derives the name of a CA certificate file in the guest from its
+// content, so that provisioning the same certificate again overwrites it
+// instead of adding a duplicate.
+func caCertName(data []byte) string {
+	return fmt.Sprintf("docker-machine-%x", sha256.Sum256(data))[:31]
+}
+
+// caCertCommands returns the commands which add the PEM encoded certificates
+// in data to the trust store of the guest.
+func caCertCommands(p Provisioner, data []byte) ([]string, error) {
+	name := caCertName(data)
+
+	// printf will choke if we don't pass a format string because of the
+	// dashes, so that's the reason for the '%%s'
+	transferCmdFmt := "printf '%%s' '%s' | sudo tee %s"
+
+	if _, ok := p.(*Boot2DockerProvisioner); ok {
+		// boot2docker loads the certificates found in its persistent
+		// storage at boot. Add them to the running system as well, only
+		// once since the bundle is appended to.
+		persistentPath := path.Join(b2dPersistentCertsDir, name+".pem")
+		systemPath := path.Join("/etc/ssl/certs", name+".pem")
+
+		return []string{
+			fmt.Sprintf("sudo mkdir -p %s", b2dPersistentCertsDir),
+			fmt.Sprintf(transferCmdFmt, string(data), persistentPath),
+			fmt.Sprintf("if [ ! -f %s ]; then sudo cp %s %s && cat %s | sudo tee -a /etc/ssl/certs/ca-certificates.crt; fi", systemPath, persistentPath, systemPath, systemPath),
+		}, nil
+	}
+
+	dir, ext, update, err := caTrustStore(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{
+		fmt.Sprintf("sudo mkdir -p %s", dir),
+		fmt.Sprintf(transferCmdFmt, string(data), path.Join(dir, name+"."+ext)),
+		update,
+	}, nil
+}
+
+// caCertsCommands returns the commands installing the extra CA certificates
+// listed in the engine options, by certificate. The certificates are read,
+// and the guest checked to support them, before the engine is stopped.
+func caCertsCommands(p Provisioner) (map[string][]string, error) {
+	cmds := map[string][]string{}
+
+	for _, certPath := range p.GetEngineOptions().CACerts {
+		data, err := cert.ReadPEMCertificates(certPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if cmds[certPath], err = caCertCommands(p, data); err != nil {
+			return nil, err
+		}
+	}
+
+	return cmds, nil
+}
+
+// configureCACerts installs the extra CA certificates so that the guest, and
+// the engine in particular, trusts them.
+func configureCACerts(p Provisioner, certCmds map[string][]string) error {
+	if len(certCmds) == 0 {
+		return nil
+	}
+
+	log.Info("Installing extra CA certificates...")
+
+	for _, certPath := range p.GetEngineOptions().CACerts {
+		for _, cmd := range certCmds[certPath] {
+			if _, err := p.SSHCommand(cmd); err != nil {
+				return fmt.Errorf("error installing CA certificate %s: %s", certPath, err)
+			}
+		}
+	}
+
+	return nil
+}
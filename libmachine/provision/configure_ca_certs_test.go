@@ -0,0 +1,87 @@
+package provision
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/stretchr/testify/assert"
+)
+
+const testCACert = `-----BEGIN CERTIFICATE-----
+MIIBszCCAV2gAwIBAgIJAJ1cRBqu0kYqMA0GCSqGSIb3DQEBCwUAMBMxETAPBgNV
+-----END CERTIFICATE-----
+`
+
+func TestCACertNameIsStable(t *testing.T) {
+	name := caCertName([]byte(testCACert))
+
+	assert.Equal(t, name, caCertName([]byte(testCACert)))
+	assert.NotEqual(t, name, caCertName([]byte(testCACert+"\n")))
+	assert.True(t, strings.HasPrefix(name, "docker-machine-"))
+}
+
+func TestCACertCommandsDebian(t *testing.T) {
+	p := &DebianProvisioner{
+		NewSystemdProvisioner("debian", &fakedriver.Driver{}),
+	}
+	name := caCertName([]byte(testCACert))
+
+	cmds, err := caCertCommands(p, []byte(testCACert))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(cmds))
+	assert.Equal(t, "sudo mkdir -p /usr/local/share/ca-certificates", cmds[0])
+	assert.True(t, strings.HasSuffix(cmds[1], "| sudo tee /usr/local/share/ca-certificates/"+name+".crt"))
+	assert.Equal(t, "sudo update-ca-certificates", cmds[2])
+}
+
+func TestCACertCommandsRedHat(t *testing.T) {
+	p := &CentosProvisioner{
+		&RedHatProvisioner{
+			NewSystemdProvisioner("centos", &fakedriver.Driver{}),
+		},
+	}
+
+	cmds, err := caCertCommands(p, []byte(testCACert))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "sudo mkdir -p /etc/pki/ca-trust/source/anchors", cmds[0])
+	assert.Equal(t, "sudo update-ca-trust extract", cmds[2])
+}
+
+func TestCACertCommandsBoot2Docker(t *testing.T) {
+	p := &Boot2DockerProvisioner{
+		Driver: &fakedriver.Driver{},
+	}
+	name := caCertName([]byte(testCACert))
+
+	cmds, err := caCertCommands(p, []byte(testCACert))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(cmds))
+	assert.Equal(t, "sudo mkdir -p /var/lib/boot2docker/certs", cmds[0])
+	assert.True(t, strings.HasSuffix(cmds[1], "| sudo tee /var/lib/boot2docker/certs/"+name+".pem"))
+	assert.True(t, strings.HasPrefix(cmds[2], "if [ ! -f /etc/ssl/certs/"+name+".pem ]; then"))
+}
+
+func TestCACertCommandsUnsupported(t *testing.T) {
+	p := &RancherProvisioner{
+		GenericProvisioner{Driver: &fakedriver.Driver{}},
+	}
+
+	_, err := caCertCommands(p, []byte(testCACert))
+
+	assert.Error(t, err)
+}
+
+func TestCACertsCommandsReadsCertificates(t *testing.T) {
+	p := &DebianProvisioner{
+		NewSystemdProvisioner("debian", &fakedriver.Driver{}),
+	}
+	p.EngineOptions.CACerts = []string{"/nonexistent/ca.pem"}
+
+	_, err := caCertsCommands(p)
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,47 @@
+package provision
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+const environmentPath = "/etc/environment"
+
+// proxyCommands returns the commands which write the proxy settings in env
+// to /etc/environment, so that the sessions opened on the machine use the
+// proxy too. The settings written before are replaced, so running the
+// commands again changes nothing.
+func proxyCommands(p Provisioner, env []string) []string {
+	switch p.(type) {
+	case *Boot2DockerProvisioner, *RancherProvisioner:
+		// Their /etc is rebuilt at boot, the engine gets the settings
+		// from its own configuration.
+		return nil
+	}
+
+	return []string{
+		fmt.Sprintf("sudo touch %s && sudo sed -i '/^\\(%s\\)=/d' %s", environmentPath, strings.Join(proxyEnvNames, `\|`), environmentPath),
+		fmt.Sprintf("printf '%%s' %s | sudo tee -a %s > /dev/null", shellQuote(strings.Join(env, "\n")+"\n"), environmentPath),
+	}
+}
+
+// configureProxy writes the proxy settings of the engine options, if any, to
+// the system environment of the guest.
+func configureProxy(p Provisioner) error {
+	env := p.GetEngineOptions().ProxyEnv()
+	if len(env) == 0 {
+		return nil
+	}
+
+	log.Info("Setting proxy environment...")
+
+	for _, cmd := range proxyCommands(p, env) {
+		if _, err := p.SSHCommand(cmd); err != nil {
+			return fmt.Errorf("error setting proxy environment: %s", err)
+		}
+	}
+
+	return nil
+}
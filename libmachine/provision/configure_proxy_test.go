@@ -0,0 +1,40 @@
+package provision
+
+import (
+	"testing"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/engine"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyCommands(t *testing.T) {
+	p := &DebianProvisioner{
+		NewSystemdProvisioner("debian", &fakedriver.Driver{}),
+	}
+	env := engine.Options{HTTPProxy: "http://proxy:3128", NoProxy: "localhost"}.ProxyEnv()
+
+	cmds := proxyCommands(p, env)
+
+	assert.Equal(t, []string{
+		`sudo touch /etc/environment && sudo sed -i '/^\(HTTP_PROXY\|HTTPS_PROXY\|NO_PROXY\|http_proxy\|https_proxy\|no_proxy\)=/d' /etc/environment`,
+		"printf '%s' 'HTTP_PROXY=http://proxy:3128\nhttp_proxy=http://proxy:3128\nNO_PROXY=localhost\nno_proxy=localhost\n' | sudo tee -a /etc/environment > /dev/null",
+	}, cmds)
+}
+
+func TestProxyCommandsBoot2Docker(t *testing.T) {
+	p := &Boot2DockerProvisioner{
+		Driver: &fakedriver.Driver{},
+	}
+
+	assert.Empty(t, proxyCommands(p, []string{"HTTP_PROXY=http://proxy:3128"}))
+}
+
+func TestProxyEnvPrefix(t *testing.T) {
+	opts := engine.Options{
+		Env:        []string{"FOO=bar"},
+		HTTPSProxy: "http://proxy:3128",
+	}
+
+	assert.Equal(t, "HTTPS_PROXY='http://proxy:3128' https_proxy='http://proxy:3128' ", proxyEnvPrefix(opts))
+}
@@ -69,6 +69,11 @@ func configureSwarm(p Provisioner, swarmOptions swarm.Options, authOptions auth.
 
 	dockerDir := p.GetDockerOptionsDir()
 
+	// Hosts created before the image was configurable have none set.
+	if swarmOptions.Image == "" {
+		swarmOptions.Image = swarm.DockerImage
+	}
+
 	swarmCmdContext := SwarmCommandContext{
 		ContainerName: "",
 		Env:           swarmOptions.Env,
@@ -99,7 +104,7 @@ manage \
 --tlscert={{.AuthOptions.ServerCertRemotePath}} \
 --tlskey={{.AuthOptions.ServerKeyRemotePath}} \
 -H {{.SwarmOptions.Host}} \
---strategy {{.SwarmOptions.Strategy}} {{range .SwarmOptions.ArbitraryFlags}} --{{.}}{{end}} {{.SwarmOptions.Discovery}}
+--strategy {{.SwarmOptions.Strategy}} {{range .SwarmOptions.ArbitraryFlags}} --{{.}}{{end}}{{range .SwarmOptions.DiscoveryOpts}} --discovery-opt {{.}}{{end}} {{.SwarmOptions.Discovery}}
 `
 
 	swarmWorkerCmdTemplate := `sudo docker run -d \
@@ -107,7 +112,7 @@ manage \
 {{range .Env}} -e {{.}}{{end}} \
 --name swarm-agent \
 {{.SwarmImage}} \
-join --advertise {{.IP}}:{{.DockerPort}} {{.SwarmOptions.Discovery}}
+join --advertise {{.IP}}:{{.DockerPort}}{{range .SwarmOptions.DiscoveryOpts}} --discovery-opt {{.}}{{end}} {{.SwarmOptions.Discovery}}
 `
 
 	if swarmOptions.Master {
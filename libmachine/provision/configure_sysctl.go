@@ -0,0 +1,75 @@
+package provision
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	sysctlConfPath    = "/etc/sysctl.d/99-docker-machine.conf"
+	b2dSysctlConfPath = "/var/lib/boot2docker/sysctl.conf"
+)
+
+// reSysctl matches a key=value kernel parameter setting which is safe to
+// quote in a shell command.
+var reSysctl = regexp.MustCompile(`^[A-Za-z0-9_.-]+=[A-Za-z0-9_. -]+$`)
+
+// sysctlCommands returns the commands which apply the kernel parameters in
+// sysctls and persist them across reboots. The settings are written to a file
+// of their own, so running the commands again changes nothing.
+func sysctlCommands(p Provisioner, sysctls []string) ([]string, error) {
+	for _, sysctl := range sysctls {
+		if !reSysctl.MatchString(sysctl) {
+			return nil, fmt.Errorf("invalid kernel parameter setting %q", sysctl)
+		}
+	}
+
+	confPath := sysctlConfPath
+	switch p.(type) {
+	case *Boot2DockerProvisioner:
+		confPath = b2dSysctlConfPath
+	case *RancherProvisioner:
+		return nil, errors.New("setting kernel parameters is not supported on RancherOS")
+	}
+
+	applyCmd := fmt.Sprintf("sysctl -p %s", confPath)
+	cmds := []string{
+		fmt.Sprintf("printf '%%s' '%s' | sudo tee %s > /dev/null", strings.Join(sysctls, "\n")+"\n", confPath),
+		fmt.Sprintf("sudo %s", applyCmd),
+	}
+
+	// boot2docker doesn't keep /etc, so load the settings at boot instead.
+	if _, ok := p.(*Boot2DockerProvisioner); ok {
+		cmds = append(cmds, b2dRunAtBootCommands(applyCmd)...)
+	}
+
+	return cmds, nil
+}
+
+// configureSysctls applies the kernel parameters configured in the engine
+// options, if any.
+func configureSysctls(p Provisioner) error {
+	sysctls := p.GetEngineOptions().Sysctls
+	if len(sysctls) == 0 {
+		return nil
+	}
+
+	log.Info("Setting kernel parameters...")
+
+	cmds, err := sysctlCommands(p, sysctls)
+	if err != nil {
+		return err
+	}
+
+	for _, cmd := range cmds {
+		if _, err := p.SSHCommand(cmd); err != nil {
+			return fmt.Errorf("error setting kernel parameters: %s", err)
+		}
+	}
+
+	return nil
+}
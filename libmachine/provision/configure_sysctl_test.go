@@ -0,0 +1,50 @@
+package provision
+
+import (
+	"testing"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/engine"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSysctlCommandsSystemd(t *testing.T) {
+	p := &DebianProvisioner{
+		NewSystemdProvisioner("debian", &fakedriver.Driver{}),
+	}
+
+	cmds, err := sysctlCommands(p, engine.InotifySysctls)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"printf '%s' 'fs.inotify.max_user_watches=524288\nfs.inotify.max_user_instances=512\n' | sudo tee /etc/sysctl.d/99-docker-machine.conf > /dev/null",
+		"sudo sysctl -p /etc/sysctl.d/99-docker-machine.conf",
+	}, cmds)
+}
+
+func TestSysctlCommandsBoot2Docker(t *testing.T) {
+	p := &Boot2DockerProvisioner{
+		Driver: &fakedriver.Driver{},
+	}
+
+	cmds, err := sysctlCommands(p, engine.InotifySysctls)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"printf '%s' 'fs.inotify.max_user_watches=524288\nfs.inotify.max_user_instances=512\n' | sudo tee /var/lib/boot2docker/sysctl.conf > /dev/null",
+		"sudo sysctl -p /var/lib/boot2docker/sysctl.conf",
+		"sudo touch /var/lib/boot2docker/bootsync.sh && sudo chmod +x /var/lib/boot2docker/bootsync.sh",
+		"grep -qxF 'sysctl -p /var/lib/boot2docker/sysctl.conf' /var/lib/boot2docker/bootsync.sh || echo 'sysctl -p /var/lib/boot2docker/sysctl.conf' | sudo tee -a /var/lib/boot2docker/bootsync.sh",
+	}, cmds)
+}
+
+func TestSysctlCommandsRejectsInvalidSettings(t *testing.T) {
+	p := &DebianProvisioner{
+		NewSystemdProvisioner("debian", &fakedriver.Driver{}),
+	}
+
+	for _, sysctl := range []string{"fs.inotify.max_user_watches", "vm.swappiness=1'; reboot; '"} {
+		_, err := sysctlCommands(p, []string{sysctl})
+		assert.Error(t, err, sysctl)
+	}
+}
@@ -0,0 +1,108 @@
+package provision
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+const b2dZoneinfoPath = "/var/lib/boot2docker/localtime"
+
+// reTimezone matches the names of the tz database, which end up in shell
+// commands run on the guest.
+var reTimezone = regexp.MustCompile(`^[A-Za-z0-9_+-]+(/[A-Za-z0-9_+-]+)*$`)
+
+// zoneinfoDirs lists where the tz database is usually installed.
+var zoneinfoDirs = []string{
+	"/usr/share/zoneinfo",
+	"/usr/share/lib/zoneinfo",
+	"/usr/lib/locale/TZ",
+}
+
+// readZoneinfo returns the compiled tz database file of zone from the host.
+// It is a variable so that tests don't depend on the tz database of the host.
+var readZoneinfo = func(zone string) ([]byte, error) {
+	dirs := zoneinfoDirs
+	if dir := os.Getenv("ZONEINFO"); dir != "" {
+		dirs = append([]string{dir}, dirs...)
+	}
+
+	for _, dir := range dirs {
+		if data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(zone))); err == nil {
+			return data, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unable to find the time zone data of %s on this host", zone)
+}
+
+// timezoneCommands returns the commands which set the time zone of the guest
+// to zone. Running them again with the same zone changes nothing.
+func timezoneCommands(p Provisioner, zone string) ([]string, error) {
+	if !reTimezone.MatchString(zone) {
+		return nil, fmt.Errorf("invalid time zone %q", zone)
+	}
+
+	zoneinfoPath := "/usr/share/zoneinfo/" + zone
+
+	switch p.(type) {
+	case *Boot2DockerProvisioner:
+		// boot2docker ships without the tz database, so upload the zone
+		// from the host and restore it from the persistent storage at boot.
+		data, err := readZoneinfo(zone)
+		if err != nil {
+			return nil, err
+		}
+
+		restoreCmd := fmt.Sprintf("cp %s /etc/localtime", b2dZoneinfoPath)
+
+		return append([]string{
+			fmt.Sprintf("printf '%%s' '%s' | base64 -d | sudo tee %s > /dev/null", base64.StdEncoding.EncodeToString(data), b2dZoneinfoPath),
+			fmt.Sprintf("sudo %s", restoreCmd),
+		}, b2dRunAtBootCommands(restoreCmd)...), nil
+	case *UbuntuProvisioner:
+		return []string{
+			fmt.Sprintf("test -f %s", zoneinfoPath),
+			fmt.Sprintf("echo '%s' | sudo tee /etc/timezone", zone),
+			fmt.Sprintf("sudo ln -sf %s /etc/localtime", zoneinfoPath),
+		}, nil
+	case *RancherProvisioner:
+		return nil, errors.New("setting the time zone is not supported on RancherOS")
+	}
+
+	// Every other supported distribution uses systemd.
+	return []string{
+		fmt.Sprintf("test -f %s", zoneinfoPath),
+		fmt.Sprintf("sudo timedatectl set-timezone %s", zone),
+	}, nil
+}
+
+// configureTimezone sets the time zone of the guest if one is configured in
+// the engine options. Only the zone is changed, never the clock itself.
+func configureTimezone(p Provisioner) error {
+	zone := p.GetEngineOptions().Timezone
+	if zone == "" {
+		return nil
+	}
+
+	log.Infof("Setting the time zone to %s...", zone)
+
+	cmds, err := timezoneCommands(p, zone)
+	if err != nil {
+		return err
+	}
+
+	for _, cmd := range cmds {
+		if _, err := p.SSHCommand(cmd); err != nil {
+			return fmt.Errorf("error setting the time zone to %s: %s", zone, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package provision
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimezoneCommandsSystemd(t *testing.T) {
+	p := &DebianProvisioner{
+		NewSystemdProvisioner("debian", &fakedriver.Driver{}),
+	}
+
+	cmds, err := timezoneCommands(p, "America/New_York")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"test -f /usr/share/zoneinfo/America/New_York",
+		"sudo timedatectl set-timezone America/New_York",
+	}, cmds)
+}
+
+func TestTimezoneCommandsUbuntuUpstart(t *testing.T) {
+	p := &UbuntuProvisioner{
+		GenericProvisioner{Driver: &fakedriver.Driver{}},
+	}
+
+	cmds, err := timezoneCommands(p, "Europe/Paris")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"test -f /usr/share/zoneinfo/Europe/Paris",
+		"echo 'Europe/Paris' | sudo tee /etc/timezone",
+		"sudo ln -sf /usr/share/zoneinfo/Europe/Paris /etc/localtime",
+	}, cmds)
+}
+
+func TestTimezoneCommandsBoot2Docker(t *testing.T) {
+	defer func(read func(string) ([]byte, error)) {
+		readZoneinfo = read
+	}(readZoneinfo)
+	readZoneinfo = func(zone string) ([]byte, error) {
+		return []byte("TZif2"), nil
+	}
+
+	p := &Boot2DockerProvisioner{
+		Driver: &fakedriver.Driver{},
+	}
+
+	cmds, err := timezoneCommands(p, "Europe/Paris")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(cmds))
+	assert.Equal(t, "printf '%s' 'VFppZjI=' | base64 -d | sudo tee /var/lib/boot2docker/localtime > /dev/null", cmds[0])
+	assert.Equal(t, "sudo cp /var/lib/boot2docker/localtime /etc/localtime", cmds[1])
+	assert.True(t, strings.HasPrefix(cmds[3], "grep -qxF 'cp /var/lib/boot2docker/localtime /etc/localtime' /var/lib/boot2docker/bootsync.sh ||"))
+}
+
+func TestTimezoneCommandsRejectsShellCharacters(t *testing.T) {
+	p := &DebianProvisioner{
+		NewSystemdProvisioner("debian", &fakedriver.Driver{}),
+	}
+
+	_, err := timezoneCommands(p, "UTC; reboot")
+
+	assert.Error(t, err)
+}
@@ -67,6 +67,7 @@ Requires=docker.socket early-docker.target
 
 [Service]
 Environment=TMPDIR=/var/tmp
+Environment={{range .EngineOptions.Environment}}{{ printf "%q" . }} {{end}}
 EnvironmentFile=-/run/flannel_docker_opts.env
 MountFlags=slave
 LimitNOFILE=1048576
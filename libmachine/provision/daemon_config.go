@@ -0,0 +1,83 @@
+package provision
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+const daemonJSONPath = "/etc/docker/daemon.json"
+
+// DaemonConfigFile is a file configuring the Docker daemon of a machine.
+type DaemonConfigFile struct {
+	Path    string
+	Content string
+	Found   bool
+}
+
+// DaemonConfigPaths returns where the configuration files of the Docker
+// daemon are expected to be on the guest, for the distribution p provisions:
+// daemon.json and the file machine writes the engine options to. On systemd
+// distributions, the drop-in directory of the docker unit is returned too.
+func DaemonConfigPaths(p Provisioner) (files []string, dropInDir string, err error) {
+	// The port doesn't matter, only the path of the options is used.
+	opts, err := p.GenerateDockerOptions(2376)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if strings.HasSuffix(opts.EngineOptionsPath, ".service") {
+		dropInDir = opts.EngineOptionsPath + ".d"
+	}
+
+	return []string{daemonJSONPath, opts.EngineOptionsPath}, dropInDir, nil
+}
+
+// FetchDaemonConfig reads the configuration files of the Docker daemon from
+// the guest. Expected files which don't exist are returned with Found set to
+// false, while drop-ins are only returned if there are some.
+func FetchDaemonConfig(p Provisioner) ([]DaemonConfigFile, error) {
+	paths, dropInDir, err := DaemonConfigPaths(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if dropInDir != "" {
+		// find fails if the directory doesn't exist, which only means that
+		// there are no drop-ins.
+		if out, err := p.SSHCommand(fmt.Sprintf("sudo find %s -maxdepth 1 -name '*.conf' -type f", dropInDir)); err == nil {
+			for _, dropIn := range strings.Fields(out) {
+				paths = append(paths, path.Clean(dropIn))
+			}
+		}
+	}
+
+	files := []DaemonConfigFile{}
+	for _, filePath := range paths {
+		file, err := fetchDaemonConfigFile(p, filePath)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+func fetchDaemonConfigFile(p Provisioner, filePath string) (DaemonConfigFile, error) {
+	file := DaemonConfigFile{Path: filePath}
+
+	if _, err := p.SSHCommand(fmt.Sprintf("sudo test -f %s", filePath)); err != nil {
+		return file, nil
+	}
+
+	content, err := p.SSHCommand(fmt.Sprintf("sudo cat %s", filePath))
+	if err != nil {
+		return file, fmt.Errorf("error reading %s: %s", filePath, err)
+	}
+
+	file.Content = content
+	file.Found = true
+
+	return file, nil
+}
@@ -0,0 +1,56 @@
+package provision
+
+import (
+	"testing"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDaemonConfigPathsBoot2Docker(t *testing.T) {
+	p := &Boot2DockerProvisioner{
+		Driver: &fakedriver.Driver{},
+	}
+
+	paths, dropInDir, err := DaemonConfigPaths(p)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/etc/docker/daemon.json", "/var/lib/boot2docker/profile"}, paths)
+	assert.Empty(t, dropInDir)
+}
+
+func TestDaemonConfigPathsSystemd(t *testing.T) {
+	p := &DebianProvisioner{
+		NewSystemdProvisioner("debian", &fakedriver.Driver{}),
+	}
+
+	paths, dropInDir, err := DaemonConfigPaths(p)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/etc/docker/daemon.json", "/etc/systemd/system/docker.service"}, paths)
+	assert.Equal(t, "/etc/systemd/system/docker.service.d", dropInDir)
+}
+
+func TestFetchDaemonConfig(t *testing.T) {
+	ssh := &fakeSSHCommander{
+		failOn: "test -f /etc/docker/daemon.json",
+		stdOut: map[string]string{
+			"sudo find /etc/systemd/system/docker.service.d -maxdepth 1 -name '*.conf' -type f": "/etc/systemd/system/docker.service.d/http-proxy.conf\n",
+			"sudo cat /etc/systemd/system/docker.service":                                       "[Service]\n",
+			"sudo cat /etc/systemd/system/docker.service.d/http-proxy.conf":                     "[Service]\nEnvironment=HTTP_PROXY=http://proxy:3128\n",
+		},
+	}
+	p := &DebianProvisioner{
+		NewSystemdProvisioner("debian", &fakedriver.Driver{}),
+	}
+	p.SSHCommander = ssh
+
+	files, err := FetchDaemonConfig(p)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []DaemonConfigFile{
+		{Path: "/etc/docker/daemon.json"},
+		{Path: "/etc/systemd/system/docker.service", Content: "[Service]\n", Found: true},
+		{Path: "/etc/systemd/system/docker.service.d/http-proxy.conf", Content: "[Service]\nEnvironment=HTTP_PROXY=http://proxy:3128\n", Found: true},
+	}, files)
+}
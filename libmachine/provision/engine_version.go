@@ -0,0 +1,250 @@
+package provision
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/docker/machine/libmachine/engine"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/provision/serviceaction"
+)
+
+// enginePackage is the package of the engine whose versions are installed by
+// the package managers.
+const enginePackage = "docker-ce"
+
+// EngineVersioner is implemented by the provisioners which can install a
+// given version of the engine.
+type EngineVersioner interface {
+	// AvailableEngineVersions lists the versions of the engine which can be
+	// installed, such as 20.10.24.
+	AvailableEngineVersions() ([]string, error)
+
+	// InstallEngineVersion installs the version of the engine, whether it's
+	// older or newer than the installed one, and pins it so that it isn't
+	// changed by the package manager.
+	InstallEngineVersion(version string) error
+}
+
+// AsEngineVersioner returns the provisioner as an EngineVersioner, or an
+// error if it can't install a given version of the engine.
+func AsEngineVersioner(p Provisioner) (EngineVersioner, error) {
+	versioner, ok := p.(EngineVersioner)
+	if !ok {
+		return nil, errors.New("installing a given engine version is not supported on this distribution")
+	}
+
+	return versioner, nil
+}
+
+// enginePackageVersion is a version of the engine package.
+type enginePackageVersion struct {
+	// Engine is the version of the engine, such as 20.10.24.
+	Engine string
+	// Package is the version of the package, such as
+	// 5:20.10.24~3-0~ubuntu-focal.
+	Package string
+}
+
+// engineVersionOfPackage returns the engine version of a package version:
+// what comes before the distribution revision, without the epoch.
+func engineVersionOfPackage(pkgVersion string) string {
+	if i := strings.Index(pkgVersion, ":"); i != -1 {
+		pkgVersion = pkgVersion[i+1:]
+	}
+
+	if i := strings.IndexAny(pkgVersion, "~-"); i != -1 {
+		pkgVersion = pkgVersion[:i]
+	}
+
+	return pkgVersion
+}
+
+// parsePackageVersions parses the versions of the engine package listed by a
+// package manager, one per line with the version in the given field. The
+// lines which don't list the package are ignored.
+func parsePackageVersions(out string, field int) []enginePackageVersion {
+	versions := []enginePackageVersion{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(strings.Replace(line, "|", " ", -1))
+		if len(fields) <= field || (fields[0] != enginePackage && !strings.HasPrefix(fields[0], enginePackage+".")) {
+			continue
+		}
+
+		versions = append(versions, enginePackageVersion{
+			Engine:  engineVersionOfPackage(fields[field]),
+			Package: fields[field],
+		})
+	}
+
+	return versions
+}
+
+func engineVersions(versions []enginePackageVersion) []string {
+	engineVersions := []string{}
+	for _, v := range versions {
+		engineVersions = append(engineVersions, v.Engine)
+	}
+
+	return engineVersions
+}
+
+// findPackageVersion returns the package version of the engine version.
+func findPackageVersion(versions []enginePackageVersion, version string) (string, error) {
+	for _, v := range versions {
+		if v.Engine == version {
+			return v.Package, nil
+		}
+	}
+
+	return "", fmt.Errorf("engine version %s is not available, available versions are %s", version, strings.Join(engineVersions(versions), ", "))
+}
+
+// aptPackageVersions lists the versions of the engine package in the apt
+// repositories of the machine.
+func aptPackageVersions(p Provisioner) ([]enginePackageVersion, error) {
+	env := proxyEnvPrefix(p.GetEngineOptions())
+	if _, err := p.SSHCommand(env + "sudo -E apt-get update"); err != nil {
+		return nil, err
+	}
+
+	out, err := p.SSHCommand("apt-cache madison " + enginePackage)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePackageVersions(out, 1), nil
+}
+
+func aptAvailableEngineVersions(p Provisioner) ([]string, error) {
+	versions, err := aptPackageVersions(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return engineVersions(versions), nil
+}
+
+// aptInstallEngineVersion installs the package version of the engine version
+// and holds it.
+func aptInstallEngineVersion(p Provisioner, version string) error {
+	versions, err := aptPackageVersions(p)
+	if err != nil {
+		return err
+	}
+
+	pkgVersion, err := findPackageVersion(versions, version)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Installing %s %s...", enginePackage, pkgVersion)
+
+	env := proxyEnvPrefix(p.GetEngineOptions())
+	commands := []string{
+		fmt.Sprintf("sudo apt-mark unhold %s", enginePackage),
+		fmt.Sprintf("%sDEBIAN_FRONTEND=noninteractive sudo -E apt-get install -y --allow-downgrades %s=%s", env, enginePackage, pkgVersion),
+		fmt.Sprintf("sudo apt-mark hold %s", enginePackage),
+	}
+
+	for _, command := range commands {
+		if _, err := p.SSHCommand(command); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// yumPackageVersions lists the versions of the engine package in the yum
+// repositories of the machine.
+func yumPackageVersions(p Provisioner) ([]enginePackageVersion, error) {
+	out, err := p.SSHCommand(fmt.Sprintf("%ssudo -E yum list -q --showduplicates %s", proxyEnvPrefix(p.GetEngineOptions()), enginePackage))
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePackageVersions(out, 1), nil
+}
+
+func yumAvailableEngineVersions(p Provisioner) ([]string, error) {
+	versions, err := yumPackageVersions(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return engineVersions(versions), nil
+}
+
+// yumInstallEngineVersion installs the package version of the engine version
+// and locks it.
+func yumInstallEngineVersion(p Provisioner, version string) error {
+	versions, err := yumPackageVersions(p)
+	if err != nil {
+		return err
+	}
+
+	pkgVersion, err := findPackageVersion(versions, version)
+	if err != nil {
+		return err
+	}
+
+	// yum takes the version without the epoch.
+	if i := strings.Index(pkgVersion, ":"); i != -1 {
+		pkgVersion = pkgVersion[i+1:]
+	}
+	pkg := fmt.Sprintf("%s-%s", enginePackage, pkgVersion)
+
+	log.Infof("Installing %s...", pkg)
+
+	env := proxyEnvPrefix(p.GetEngineOptions())
+	commands := []string{
+		fmt.Sprintf("%ssudo -E yum install -y yum-plugin-versionlock", env),
+		fmt.Sprintf("sudo yum versionlock delete %s || true", enginePackage),
+		fmt.Sprintf("%[1]ssudo -E yum install -y %[2]s || %[1]ssudo -E yum downgrade -y %[2]s", env, pkg),
+		fmt.Sprintf("sudo yum versionlock add %s", enginePackage),
+	}
+
+	for _, command := range commands {
+		if _, err := p.SSHCommand(command); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// InstallStaticEngine replaces the engine installed with the static install
+// method by the static binaries of the InstallVersion of engineOptions, and
+// restarts it. The binaries are downloaded and checked before the engine is
+// stopped, so that a failed download leaves it running.
+func InstallStaticEngine(p Provisioner, engineOptions engine.Options) error {
+	if engineOptions.InstallVersion == "" {
+		return fmt.Errorf("the %s install method needs an engine version", engine.InstallMethodStatic)
+	}
+
+	log.Infof("Downloading the static binaries of engine version %s...", engineOptions.InstallVersion)
+
+	output, err := p.SSHCommand(staticDockerDownloadCommand(engineOptions) + ` && echo "$dir" || { status=$?; rm -rf $dir; exit $status; }`)
+	if err != nil {
+		return fmt.Errorf("error downloading docker: %s\n", output)
+	}
+	dir := strings.TrimSpace(output)
+
+	if err := p.Service("docker", serviceaction.Stop); err != nil {
+		p.SSHCommand(fmt.Sprintf("rm -rf %s", shellQuote(dir)))
+		return err
+	}
+
+	log.Infof("Installing the static binaries of engine version %s...", engineOptions.InstallVersion)
+
+	if output, err := p.SSHCommand(fmt.Sprintf("dir=%s; %s; status=$?; rm -rf $dir; exit $status", shellQuote(dir), staticDockerExtractCommand)); err != nil {
+		if err := p.Service("docker", serviceaction.Start); err != nil {
+			log.Warnf("Error starting the engine again: %s", err)
+		}
+		return fmt.Errorf("error installing docker: %s\n", output)
+	}
+
+	return p.Service("docker", serviceaction.Start)
+}
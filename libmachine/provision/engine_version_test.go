@@ -0,0 +1,86 @@
+package provision
+
+import (
+	"testing"
+
+	"github.com/docker/machine/libmachine/engine"
+	"github.com/docker/machine/libmachine/provision/serviceaction"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePackageVersionsOfApt(t *testing.T) {
+	out := ` docker-ce | 5:20.10.24~3-0~ubuntu-focal | https://download.docker.com/linux/ubuntu focal/stable amd64 Packages
+ docker-ce | 5:19.03.15~3-0~ubuntu-focal | https://download.docker.com/linux/ubuntu focal/stable amd64 Packages
+ docker-ce-cli | 5:20.10.24~3-0~ubuntu-focal | https://download.docker.com/linux/ubuntu focal/stable amd64 Packages
+`
+
+	versions := parsePackageVersions(out, 1)
+
+	assert.Equal(t, []enginePackageVersion{
+		{Engine: "20.10.24", Package: "5:20.10.24~3-0~ubuntu-focal"},
+		{Engine: "19.03.15", Package: "5:19.03.15~3-0~ubuntu-focal"},
+	}, versions)
+}
+
+func TestParsePackageVersionsOfYum(t *testing.T) {
+	out := `Available Packages
+docker-ce.x86_64               3:20.10.24-3.el8               docker-ce-stable
+docker-ce.x86_64               3:19.03.15-3.el8               docker-ce-stable
+`
+
+	versions := parsePackageVersions(out, 1)
+
+	assert.Equal(t, []string{"20.10.24", "19.03.15"}, engineVersions(versions))
+}
+
+func TestFindPackageVersion(t *testing.T) {
+	versions := []enginePackageVersion{
+		{Engine: "20.10.24", Package: "5:20.10.24~3-0~ubuntu-focal"},
+	}
+
+	pkgVersion, err := findPackageVersion(versions, "20.10.24")
+	assert.NoError(t, err)
+	assert.Equal(t, "5:20.10.24~3-0~ubuntu-focal", pkgVersion)
+
+	_, err = findPackageVersion(versions, "19.03.15")
+	assert.EqualError(t, err, "engine version 19.03.15 is not available, available versions are 20.10.24")
+}
+
+// fakeEngineProvisioner records the SSH commands and the service actions.
+type fakeEngineProvisioner struct {
+	Provisioner
+	fakeSSHCommander
+}
+
+func (p *fakeEngineProvisioner) SSHCommand(args string) (string, error) {
+	return p.fakeSSHCommander.SSHCommand(args)
+}
+
+func (p *fakeEngineProvisioner) Service(name string, action serviceaction.ServiceAction) error {
+	p.commands = append(p.commands, "service "+name+" "+action.String())
+	return nil
+}
+
+func TestInstallStaticEngine(t *testing.T) {
+	p := &fakeEngineProvisioner{}
+	p.stdOut = map[string]string{}
+	engineOptions := engine.Options{InstallVersion: "17.03.2-ce"}
+	download := staticDockerDownloadCommand(engineOptions) + ` && echo "$dir" || { status=$?; rm -rf $dir; exit $status; }`
+	p.stdOut[download] = "/tmp/tmp.123\n"
+
+	assert.NoError(t, InstallStaticEngine(p, engineOptions))
+	assert.Equal(t, []string{
+		download,
+		"service docker stop",
+		"dir='/tmp/tmp.123'; sudo tar -xzf $dir/docker.tgz -C /usr/bin --strip-components=1; status=$?; rm -rf $dir; exit $status",
+		"service docker start",
+	}, p.commands)
+}
+
+func TestInstallStaticEngineKeepsEngineRunningWhenDownloadFails(t *testing.T) {
+	p := &fakeEngineProvisioner{}
+	p.failOn = "curl"
+
+	assert.Error(t, InstallStaticEngine(p, engine.Options{InstallVersion: "17.03.2-ce"}))
+	assert.Len(t, p.commands, 1)
+}
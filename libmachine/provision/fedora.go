@@ -19,3 +19,9 @@ func NewFedoraProvisioner(d drivers.Driver) Provisioner {
 type FedoraProvisioner struct {
 	*RedHatProvisioner
 }
+
+// CompatibleWithHost leaves Fedora CoreOS, which has no package manager to
+// install the engine with, to the IgnitionProvisioner.
+func (provisioner *FedoraProvisioner) CompatibleWithHost() bool {
+	return provisioner.OsReleaseInfo.ID == provisioner.OsReleaseID && !isIgnitionHost(provisioner.OsReleaseInfo)
+}
@@ -0,0 +1,164 @@
+package provision
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"text/template"
+
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/engine"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/provision/pkgaction"
+	"github.com/docker/machine/libmachine/provision/serviceaction"
+	"github.com/docker/machine/libmachine/swarm"
+)
+
+// ignitionDockerDropIn is the systemd drop-in overriding how the engine
+// shipped with the image is started. /usr is read-only on these distros, so
+// the unit itself is left alone.
+const ignitionDockerDropIn = "/etc/systemd/system/docker.service.d/10-machine.conf"
+
+func init() {
+	Register("Ignition", &RegisteredProvisioner{
+		New: NewIgnitionProvisioner,
+	})
+}
+
+func NewIgnitionProvisioner(d drivers.Driver) Provisioner {
+	p := &IgnitionProvisioner{
+		NewSystemdProvisioner("", d),
+	}
+	p.DaemonOptionsFile = ignitionDockerDropIn
+	p.Packages = nil
+	return p
+}
+
+// IgnitionProvisioner provisions the container-optimized distros which are
+// configured by Ignition at first boot instead of cloud-config, such as
+// Fedora CoreOS and Flatcar. They ship the engine in their read-only image
+// and have no package manager, so only the files under /etc are changed.
+type IgnitionProvisioner struct {
+	SystemdProvisioner
+}
+
+// isIgnitionHost tells whether the OS described by info is configured by
+// Ignition.
+func isIgnitionHost(info *OsRelease) bool {
+	if info == nil {
+		return false
+	}
+
+	switch info.ID {
+	case "flatcar":
+		return true
+	case "fedora":
+		return info.VariantID == "coreos"
+	}
+
+	return false
+}
+
+func (provisioner *IgnitionProvisioner) CompatibleWithHost() bool {
+	return isIgnitionHost(provisioner.OsReleaseInfo)
+}
+
+func (provisioner *IgnitionProvisioner) SetHostname(hostname string) error {
+	log.Debugf("SetHostname: %s", hostname)
+
+	if _, err := provisioner.SSHCommand(fmt.Sprintf("sudo hostnamectl set-hostname %s", hostname)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (provisioner *IgnitionProvisioner) Package(name string, action pkgaction.PackageAction) error {
+	return nil
+}
+
+func (provisioner *IgnitionProvisioner) GenerateDockerOptions(dockerPort int) (*DockerOptions, error) {
+	var (
+		engineCfg bytes.Buffer
+	)
+
+	driverNameLabel := fmt.Sprintf("provider=%s", provisioner.Driver.DriverName())
+	provisioner.EngineOptions.Labels = append(provisioner.EngineOptions.Labels, driverNameLabel)
+
+	// The engine is socket activated, so the local socket is inherited
+	// from docker.socket rather than bound by the daemon.
+	engineConfigTmpl := `[Service]
+ExecStart=
+ExecStart=/usr/bin/dockerd --host=fd:// --host=tcp://0.0.0.0:{{.DockerPort}} --tlsverify --tlscacert {{.AuthOptions.CaCertRemotePath}} --tlscert {{.AuthOptions.ServerCertRemotePath}} --tlskey {{.AuthOptions.ServerKeyRemotePath}}{{ if .EngineOptions.StorageDriver }} --storage-driver {{.EngineOptions.StorageDriver}}{{ end }}{{ range .EngineOptions.Labels }} --label {{.}}{{ end }}{{ range .EngineOptions.InsecureRegistry }} --insecure-registry {{.}}{{ end }}{{ range .EngineOptions.RegistryMirror }} --registry-mirror {{.}}{{ end }}{{ range .EngineOptions.ArbitraryFlags }} --{{.}}{{ end }}
+MountFlags=slave
+LimitNOFILE=1048576
+LimitNPROC=1048576
+LimitCORE=infinity
+Environment={{range .EngineOptions.Environment}}{{ printf "%q" . }} {{end}}
+`
+
+	t, err := template.New("engineConfig").Parse(engineConfigTmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	engineConfigContext := EngineConfigContext{
+		DockerPort:    dockerPort,
+		AuthOptions:   provisioner.AuthOptions,
+		EngineOptions: provisioner.EngineOptions,
+	}
+
+	t.Execute(&engineCfg, engineConfigContext)
+
+	return &DockerOptions{
+		EngineOptions:     engineCfg.String(),
+		EngineOptionsPath: provisioner.DaemonOptionsFile,
+	}, nil
+}
+
+func (provisioner *IgnitionProvisioner) Provision(swarmOptions swarm.Options, authOptions auth.Options, engineOptions engine.Options) error {
+	provisioner.SwarmOptions = swarmOptions
+	provisioner.AuthOptions = authOptions
+	provisioner.EngineOptions = engineOptions
+	swarmOptions.Env = engineOptions.Environment()
+
+	if err := provisioner.SetHostname(provisioner.Driver.GetMachineName()); err != nil {
+		return err
+	}
+
+	if _, err := provisioner.SSHCommand(fmt.Sprintf("sudo mkdir -p %s", path.Dir(provisioner.DaemonOptionsFile))); err != nil {
+		return err
+	}
+
+	if err := makeDockerOptionsDir(provisioner); err != nil {
+		return err
+	}
+
+	// The engine is not enabled by default, make sure it comes back
+	// after a reboot. It is started once with its stock settings so that
+	// reconfiguring it below finds the bridge it sets up.
+	if err := provisioner.Service("docker.socket", serviceaction.Enable); err != nil {
+		return err
+	}
+
+	if err := provisioner.Service("docker", serviceaction.Enable); err != nil {
+		return err
+	}
+
+	if err := provisioner.Service("docker", serviceaction.Start); err != nil {
+		return err
+	}
+
+	provisioner.AuthOptions = setRemoteAuthOptions(provisioner)
+
+	if err := ConfigureAuth(provisioner); err != nil {
+		return err
+	}
+
+	if err := configureSwarm(provisioner, swarmOptions, provisioner.AuthOptions); err != nil {
+		return err
+	}
+
+	return nil
+}
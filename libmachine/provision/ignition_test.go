@@ -0,0 +1,68 @@
+package provision
+
+import (
+	"testing"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIgnitionCompatibleWithHost(t *testing.T) {
+	cases := []struct {
+		info     *OsRelease
+		ignition bool
+	}{
+		{&OsRelease{ID: "fedora", VariantID: "coreos"}, true},
+		{&OsRelease{ID: "flatcar", IDLike: "coreos"}, true},
+		{&OsRelease{ID: "fedora", VariantID: "server"}, false},
+		{&OsRelease{ID: "fedora"}, false},
+		{&OsRelease{ID: "coreos"}, false},
+	}
+
+	for _, c := range cases {
+		ignition := NewIgnitionProvisioner(&fakedriver.Driver{})
+		ignition.SetOsReleaseInfo(c.info)
+		fedora := NewFedoraProvisioner(&fakedriver.Driver{})
+		fedora.SetOsReleaseInfo(c.info)
+
+		assert.Equal(t, c.ignition, ignition.CompatibleWithHost(), "%+v", c.info)
+		assert.Equal(t, c.info.ID == "fedora" && !c.ignition, fedora.CompatibleWithHost(), "%+v", c.info)
+	}
+}
+
+func TestIgnitionGenerateDockerOptions(t *testing.T) {
+	p := NewIgnitionProvisioner(&fakedriver.Driver{}).(*IgnitionProvisioner)
+	p.AuthOptions = auth.Options{
+		CaCertRemotePath:     "/etc/docker/ca.pem",
+		ServerCertRemotePath: "/etc/docker/server.pem",
+		ServerKeyRemotePath:  "/etc/docker/server-key.pem",
+	}
+
+	opts, err := p.GenerateDockerOptions(2376)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/etc/systemd/system/docker.service.d/10-machine.conf", opts.EngineOptionsPath)
+	assert.Contains(t, opts.EngineOptions, "ExecStart=\nExecStart=/usr/bin/dockerd --host=fd:// --host=tcp://0.0.0.0:2376 --tlsverify")
+	assert.Contains(t, opts.EngineOptions, "--label provider=Driver")
+	assert.NotContains(t, opts.EngineOptions, "--storage-driver")
+}
+
+func TestCACertCommandsIgnition(t *testing.T) {
+	fcos := NewIgnitionProvisioner(&fakedriver.Driver{})
+	fcos.SetOsReleaseInfo(&OsRelease{ID: "fedora", VariantID: "coreos"})
+	flatcar := NewIgnitionProvisioner(&fakedriver.Driver{})
+	flatcar.SetOsReleaseInfo(&OsRelease{ID: "flatcar"})
+
+	cmds, err := caCertCommands(fcos, []byte(testCACert))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "sudo mkdir -p /etc/pki/ca-trust/source/anchors", cmds[0])
+	assert.Equal(t, "sudo update-ca-trust extract", cmds[2])
+
+	cmds, err = caCertCommands(flatcar, []byte(testCACert))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "sudo mkdir -p /etc/ssl/certs", cmds[0])
+	assert.Equal(t, "sudo update-ca-certificates", cmds[2])
+}
@@ -24,6 +24,7 @@ type OsRelease struct {
 	IDLike       string `osr:"ID_LIKE"`
 	PrettyName   string `osr:"PRETTY_NAME"`
 	VersionID    string `osr:"VERSION_ID"`
+	VariantID    string `osr:"VARIANT_ID"`
 	HomeURL      string `osr:"HOME_URL"`
 	SupportURL   string `osr:"SUPPORT_URL"`
 	BugReportURL string `osr:"BUG_REPORT_URL"`
@@ -32,6 +32,9 @@ type Provisioner interface {
 	// Return the auth options used to configure remote connection for the daemon.
 	GetAuthOptions() auth.Options
 
+	// Return the engine options used to configure the daemon.
+	GetEngineOptions() engine.Options
+
 	// Run a package action e.g. install
 	Package(name string, action pkgaction.PackageAction) error
 
@@ -27,12 +27,12 @@ enabled=1
 gpgkey=https://yum.dockerproject.org/gpg
 `
 	engineConfigTemplate = `[Service]
-ExecStart=/usr/bin/docker -d -H tcp://0.0.0.0:{{.DockerPort}} -H unix:///var/run/docker.sock --storage-driver {{.EngineOptions.StorageDriver}} --tlsverify --tlscacert {{.AuthOptions.CaCertRemotePath}} --tlscert {{.AuthOptions.ServerCertRemotePath}} --tlskey {{.AuthOptions.ServerKeyRemotePath}} {{ range .EngineOptions.Labels }}--label {{.}} {{ end }}{{ range .EngineOptions.InsecureRegistry }}--insecure-registry {{.}} {{ end }}{{ range .EngineOptions.RegistryMirror }}--registry-mirror {{.}} {{ end }}{{ range .EngineOptions.ArbitraryFlags }}--{{.}} {{ end }}
+ExecStart={{ if eq .EngineOptions.InstallMethod "static" }}/usr/bin/dockerd{{ else }}/usr/bin/docker -d{{ end }} -H tcp://0.0.0.0:{{.DockerPort}} -H unix:///var/run/docker.sock --storage-driver {{.EngineOptions.StorageDriver}} --tlsverify --tlscacert {{.AuthOptions.CaCertRemotePath}} --tlscert {{.AuthOptions.ServerCertRemotePath}} --tlskey {{.AuthOptions.ServerKeyRemotePath}} {{ range .EngineOptions.Labels }}--label {{.}} {{ end }}{{ range .EngineOptions.InsecureRegistry }}--insecure-registry {{.}} {{ end }}{{ range .EngineOptions.RegistryMirror }}--registry-mirror {{.}} {{ end }}{{ range .EngineOptions.ArbitraryFlags }}--{{.}} {{ end }}
 MountFlags=slave
 LimitNOFILE=1048576
 LimitNPROC=1048576
 LimitCORE=infinity
-Environment={{range .EngineOptions.Env}}{{ printf "%q" . }} {{end}}
+Environment={{range .EngineOptions.Environment}}{{ printf "%q" . }} {{end}}
 `
 )
 
@@ -84,6 +84,14 @@ func (provisioner *RedHatProvisioner) SetHostname(hostname string) error {
 	return nil
 }
 
+func (provisioner *RedHatProvisioner) AvailableEngineVersions() ([]string, error) {
+	return yumAvailableEngineVersions(provisioner)
+}
+
+func (provisioner *RedHatProvisioner) InstallEngineVersion(version string) error {
+	return yumInstallEngineVersion(provisioner, version)
+}
+
 func (provisioner *RedHatProvisioner) Package(name string, action pkgaction.PackageAction) error {
 	var packageAction string
 
@@ -96,7 +104,7 @@ func (provisioner *RedHatProvisioner) Package(name string, action pkgaction.Pack
 		packageAction = "upgrade"
 	}
 
-	command := fmt.Sprintf("sudo -E yum %s -y %s", packageAction, name)
+	command := fmt.Sprintf("%ssudo -E yum %s -y %s", proxyEnvPrefix(provisioner.EngineOptions), packageAction, name)
 
 	if _, err := provisioner.SSHCommand(command); err != nil {
 		return err
@@ -106,8 +114,17 @@ func (provisioner *RedHatProvisioner) Package(name string, action pkgaction.Pack
 }
 
 func installDocker(provisioner *RedHatProvisioner) error {
-	if err := provisioner.installOfficialDocker(); err != nil {
-		return err
+	// The package method installs the package of the yum repository rather
+	// than running the install script.
+	switch provisioner.EngineOptions.InstallMethod {
+	case "", engine.InstallMethodPackage:
+		if err := provisioner.installOfficialDocker(); err != nil {
+			return err
+		}
+	default:
+		if err := installDockerGeneric(provisioner, provisioner.EngineOptions); err != nil {
+			return err
+		}
 	}
 
 	if err := provisioner.Service("docker", serviceaction.Restart); err != nil {
@@ -128,7 +145,7 @@ func (provisioner *RedHatProvisioner) installOfficialDocker() error {
 		return err
 	}
 
-	if _, err := provisioner.SSHCommand("sudo yum install -y docker-engine"); err != nil {
+	if _, err := provisioner.SSHCommand(proxyEnvPrefix(provisioner.EngineOptions) + "sudo -E yum install -y docker-engine"); err != nil {
 		return err
 	}
 
@@ -149,7 +166,7 @@ func (provisioner *RedHatProvisioner) Provision(swarmOptions swarm.Options, auth
 	provisioner.SwarmOptions = swarmOptions
 	provisioner.AuthOptions = authOptions
 	provisioner.EngineOptions = engineOptions
-	swarmOptions.Env = engineOptions.Env
+	swarmOptions.Env = engineOptions.Environment()
 
 	// set default storage driver for redhat
 	if provisioner.EngineOptions.StorageDriver == "" {
@@ -168,7 +185,7 @@ func (provisioner *RedHatProvisioner) Provision(swarmOptions swarm.Options, auth
 	}
 
 	// update OS -- this is needed for libdevicemapper and the docker install
-	if _, err := provisioner.SSHCommand("sudo yum -y update"); err != nil {
+	if _, err := provisioner.SSHCommand(proxyEnvPrefix(provisioner.EngineOptions) + "sudo -E yum -y update"); err != nil {
 		return err
 	}
 
@@ -113,7 +113,7 @@ func (provisioner *SUSEProvisioner) Package(name string, action pkgaction.Packag
 		packageAction = "upgrade"
 	}
 
-	command := fmt.Sprintf("sudo -E zypper -n %s %s", packageAction, name)
+	command := fmt.Sprintf("%ssudo -E zypper -n %s %s", proxyEnvPrefix(provisioner.EngineOptions), packageAction, name)
 
 	if _, err := provisioner.SSHCommand(command); err != nil {
 		return err
@@ -136,7 +136,7 @@ func (provisioner *SUSEProvisioner) Provision(swarmOptions swarm.Options, authOp
 	provisioner.SwarmOptions = swarmOptions
 	provisioner.AuthOptions = authOptions
 	provisioner.EngineOptions = engineOptions
-	swarmOptions.Env = engineOptions.Env
+	swarmOptions.Env = engineOptions.Environment()
 
 	if err := provisioner.SetHostname(provisioner.Driver.GetMachineName()); err != nil {
 		return err
@@ -149,14 +149,14 @@ func (provisioner *SUSEProvisioner) Provision(swarmOptions swarm.Options, authOp
 	}
 
 	// update OS -- this is needed for libdevicemapper and the docker install
-	if _, err := provisioner.SSHCommand("sudo zypper ref"); err != nil {
+	if _, err := provisioner.SSHCommand(proxyEnvPrefix(provisioner.EngineOptions) + "sudo -E zypper ref"); err != nil {
 		return err
 	}
-	if _, err := provisioner.SSHCommand("sudo zypper -n update"); err != nil {
+	if _, err := provisioner.SSHCommand(proxyEnvPrefix(provisioner.EngineOptions) + "sudo -E zypper -n update"); err != nil {
 		return err
 	}
 
-	if err := installDockerGeneric(provisioner, engineOptions.InstallURL); err != nil {
+	if err := installDockerGeneric(provisioner, engineOptions); err != nil {
 		return err
 	}
 
@@ -210,7 +210,8 @@ func (provisioner *SUSEProvisioner) GenerateDockerOptions(dockerPort int) (*Dock
 
 	engineConfigTmpl := `# File automatically generated by docker-machine
 DOCKER_OPTS=' -H tcp://0.0.0.0:{{.DockerPort}} {{ if .EngineOptions.StorageDriver }} --storage-driver {{.EngineOptions.StorageDriver}} {{ end }} --tlsverify --tlscacert {{.AuthOptions.CaCertRemotePath}} --tlscert {{.AuthOptions.ServerCertRemotePath}} --tlskey {{.AuthOptions.ServerKeyRemotePath}} {{ range .EngineOptions.Labels }}--label {{.}} {{ end }}{{ range .EngineOptions.InsecureRegistry }}--insecure-registry {{.}} {{ end }}{{ range .EngineOptions.RegistryMirror }}--registry-mirror {{.}} {{ end }}{{ range .EngineOptions.ArbitraryFlags }}--{{.}} {{ end }}'
-`
+{{range .EngineOptions.Environment}}{{.}}
+{{end}}`
 	t, err := template.New("engineConfig").Parse(engineConfigTmpl)
 	if err != nil {
 		return nil, err
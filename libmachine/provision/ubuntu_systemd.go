@@ -45,6 +45,14 @@ func (provisioner *UbuntuSystemdProvisioner) CompatibleWithHost() bool {
 
 }
 
+func (provisioner *UbuntuSystemdProvisioner) AvailableEngineVersions() ([]string, error) {
+	return aptAvailableEngineVersions(provisioner)
+}
+
+func (provisioner *UbuntuSystemdProvisioner) InstallEngineVersion(version string) error {
+	return aptInstallEngineVersion(provisioner, version)
+}
+
 func (provisioner *UbuntuSystemdProvisioner) Package(name string, action pkgaction.PackageAction) error {
 	var packageAction string
 
@@ -64,7 +72,7 @@ func (provisioner *UbuntuSystemdProvisioner) Package(name string, action pkgacti
 	}
 
 	if updateMetadata {
-		if _, err := provisioner.SSHCommand("sudo apt-get update"); err != nil {
+		if _, err := provisioner.SSHCommand(proxyEnvPrefix(provisioner.EngineOptions) + "sudo -E apt-get update"); err != nil {
 			return err
 		}
 	}
@@ -84,14 +92,14 @@ func (provisioner *UbuntuSystemdProvisioner) Package(name string, action pkgacti
 		}
 
 		for _, cmd := range commands {
-			command := fmt.Sprintf("sudo DEBIAN_FRONTEND=noninteractive %s", cmd)
+			command := fmt.Sprintf("%ssudo -E DEBIAN_FRONTEND=noninteractive %s", proxyEnvPrefix(provisioner.EngineOptions), cmd)
 			if _, err := provisioner.SSHCommand(command); err != nil {
 				return err
 			}
 		}
 	}
 
-	command := fmt.Sprintf("DEBIAN_FRONTEND=noninteractive sudo -E apt-get %s -y  %s", packageAction, name)
+	command := fmt.Sprintf("%sDEBIAN_FRONTEND=noninteractive sudo -E apt-get %s -y  %s", proxyEnvPrefix(provisioner.EngineOptions), packageAction, name)
 
 	log.Debugf("package: action=%s name=%s", action.String(), name)
 
@@ -116,7 +124,7 @@ func (provisioner *UbuntuSystemdProvisioner) Provision(swarmOptions swarm.Option
 	provisioner.SwarmOptions = swarmOptions
 	provisioner.AuthOptions = authOptions
 	provisioner.EngineOptions = engineOptions
-	swarmOptions.Env = engineOptions.Env
+	swarmOptions.Env = engineOptions.Environment()
 
 	if provisioner.EngineOptions.StorageDriver == "" {
 		provisioner.EngineOptions.StorageDriver = "aufs"
@@ -135,7 +143,7 @@ func (provisioner *UbuntuSystemdProvisioner) Provision(swarmOptions swarm.Option
 	}
 
 	log.Debug("installing docker")
-	if err := installDockerGeneric(provisioner, engineOptions.InstallURL); err != nil {
+	if err := installDockerGeneric(provisioner, engineOptions); err != nil {
 		return err
 	}
 
@@ -64,6 +64,14 @@ func (provisioner *UbuntuProvisioner) Service(name string, action serviceaction.
 	return nil
 }
 
+func (provisioner *UbuntuProvisioner) AvailableEngineVersions() ([]string, error) {
+	return aptAvailableEngineVersions(provisioner)
+}
+
+func (provisioner *UbuntuProvisioner) InstallEngineVersion(version string) error {
+	return aptInstallEngineVersion(provisioner, version)
+}
+
 func (provisioner *UbuntuProvisioner) Package(name string, action pkgaction.PackageAction) error {
 	var packageAction string
 
@@ -83,7 +91,7 @@ func (provisioner *UbuntuProvisioner) Package(name string, action pkgaction.Pack
 	}
 
 	if updateMetadata {
-		if _, err := provisioner.SSHCommand("sudo apt-get update"); err != nil {
+		if _, err := provisioner.SSHCommand(proxyEnvPrefix(provisioner.EngineOptions) + "sudo -E apt-get update"); err != nil {
 			return err
 		}
 	}
@@ -103,14 +111,14 @@ func (provisioner *UbuntuProvisioner) Package(name string, action pkgaction.Pack
 		}
 
 		for _, cmd := range commands {
-			command := fmt.Sprintf("sudo DEBIAN_FRONTEND=noninteractive %s", cmd)
+			command := fmt.Sprintf("%ssudo -E DEBIAN_FRONTEND=noninteractive %s", proxyEnvPrefix(provisioner.EngineOptions), cmd)
 			if _, err := provisioner.SSHCommand(command); err != nil {
 				return err
 			}
 		}
 	}
 
-	command := fmt.Sprintf("DEBIAN_FRONTEND=noninteractive sudo -E apt-get %s -y  %s", packageAction, name)
+	command := fmt.Sprintf("%sDEBIAN_FRONTEND=noninteractive sudo -E apt-get %s -y  %s", proxyEnvPrefix(provisioner.EngineOptions), packageAction, name)
 
 	log.Debugf("package: action=%s name=%s", action.String(), name)
 
@@ -135,7 +143,7 @@ func (provisioner *UbuntuProvisioner) Provision(swarmOptions swarm.Options, auth
 	provisioner.SwarmOptions = swarmOptions
 	provisioner.AuthOptions = authOptions
 	provisioner.EngineOptions = engineOptions
-	swarmOptions.Env = engineOptions.Env
+	swarmOptions.Env = engineOptions.Environment()
 
 	if provisioner.EngineOptions.StorageDriver == "" {
 		provisioner.EngineOptions.StorageDriver = "aufs"
@@ -151,7 +159,7 @@ func (provisioner *UbuntuProvisioner) Provision(swarmOptions swarm.Options, auth
 		}
 	}
 
-	if err := installDockerGeneric(provisioner, engineOptions.InstallURL); err != nil {
+	if err := installDockerGeneric(provisioner, engineOptions); err != nil {
 		return err
 	}
 
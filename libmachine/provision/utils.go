@@ -3,6 +3,7 @@ package provision
 import (
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/url"
 	"path"
 	"path/filepath"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/docker/machine/libmachine/auth"
 	"github.com/docker/machine/libmachine/cert"
+	"github.com/docker/machine/libmachine/engine"
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/mcnutils"
 	"github.com/docker/machine/libmachine/provision/serviceaction"
@@ -23,10 +25,111 @@ type DockerOptions struct {
 	EngineOptionsPath string
 }
 
-func installDockerGeneric(p Provisioner, baseURL string) error {
+// proxyEnvNames are the engine environment variables which are passed to
+// the install script and the package managers, so that they work from behind
+// a proxy.
+var proxyEnvNames = []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy"}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// proxyEnvPrefix returns the variable assignments which pass the proxy
+// settings of engineOptions to a shell command, e.g. a package manager run
+// with sudo -E.
+func proxyEnvPrefix(engineOptions engine.Options) string {
+	env := ""
+	for _, kv := range engineOptions.Environment() {
+		parts := strings.SplitN(kv, "=", 2)
+		for _, name := range proxyEnvNames {
+			if len(parts) == 2 && parts[0] == name {
+				env += fmt.Sprintf("%s=%s ", name, shellQuote(parts[1]))
+			}
+		}
+	}
+
+	return env
+}
+
+// installDockerCommand returns the command installing docker with the install
+// script at the InstallURL of engineOptions, unless docker is already there.
+// If InstallURLSHA256 is set, the script is only run if it has that checksum.
+func installDockerCommand(engineOptions engine.Options) string {
+	env := proxyEnvPrefix(engineOptions)
+	installURL := shellQuote(engineOptions.InstallURL)
+	if engineOptions.InstallURLSHA256 == "" {
+		return fmt.Sprintf("if ! type docker; then %scurl -fsSL %s | %ssh -; fi", env, installURL, env)
+	}
+
+	return fmt.Sprintf("if ! type docker; then script=$(mktemp) && %scurl -fsSL -o $script %s && echo '%s  '$script | sha256sum -c - && %ssh $script; status=$?; rm -f $script; exit $status; fi",
+		env, installURL, strings.ToLower(engineOptions.InstallURLSHA256), env)
+}
+
+// staticDockerUnit is the systemd unit running the daemon installed from the
+// static binaries, until it's configured.
+const staticDockerUnit = `[Service]
+ExecStart=/usr/bin/dockerd -H unix:///var/run/docker.sock
+
+[Install]
+WantedBy=multi-user.target
+`
+
+// staticDockerDownloadCommand returns the command downloading the static
+// binaries of the InstallVersion of engineOptions for the architecture of the
+// machine to the temporary directory $dir, and checking the archive holds
+// the daemon.
+func staticDockerDownloadCommand(engineOptions engine.Options) string {
+	archiveURL := fmt.Sprintf("%s/'$(uname -m)'/docker-%s.tgz", engine.StaticInstallURL, engineOptions.InstallVersion)
+
+	return fmt.Sprintf("dir=$(mktemp -d) && %scurl -fsSL -o $dir/docker.tgz '%s' && tar -tzf $dir/docker.tgz docker/dockerd >/dev/null",
+		proxyEnvPrefix(engineOptions), archiveURL)
+}
+
+// staticDockerExtractCommand extracts the static binaries downloaded to $dir
+// to /usr/bin.
+const staticDockerExtractCommand = "sudo tar -xzf $dir/docker.tgz -C /usr/bin --strip-components=1"
+
+// installStaticDockerCommand returns the command installing the static
+// binaries of the InstallVersion of engineOptions and starting the daemon with
+// systemd, unless docker is already there.
+func installStaticDockerCommand(engineOptions engine.Options) string {
+	return fmt.Sprintf("if ! type docker; then %s && %s && printf %%s %s | sudo tee /etc/systemd/system/docker.service && sudo systemctl daemon-reload && sudo systemctl start docker; status=$?; rm -rf $dir; exit $status; fi",
+		staticDockerDownloadCommand(engineOptions), staticDockerExtractCommand, shellQuote(staticDockerUnit))
+}
+
+// installEngineCommand returns the command installing docker with the
+// InstallMethod of engineOptions, or "" if it isn't installed.
+func installEngineCommand(engineOptions engine.Options) (string, error) {
+	switch engineOptions.InstallMethod {
+	case "", engine.InstallMethodPackage:
+		return installDockerCommand(engineOptions), nil
+	case engine.InstallMethodStatic:
+		if engineOptions.InstallVersion == "" {
+			return "", fmt.Errorf("the %s install method needs an engine version", engine.InstallMethodStatic)
+		}
+		return installStaticDockerCommand(engineOptions), nil
+	case engine.InstallMethodNone:
+		return "", nil
+	}
+
+	return "", fmt.Errorf("unknown engine install method %q", engineOptions.InstallMethod)
+}
+
+func installDockerGeneric(p Provisioner, engineOptions engine.Options) error {
+	command, err := installEngineCommand(engineOptions)
+	if err != nil {
+		return err
+	}
+
+	if command == "" {
+		log.Debug("skipping the installation of docker")
+		return nil
+	}
+
 	// install docker - until cloudinit we use ubuntu everywhere so we
 	// just install it using the docker repos
-	if output, err := p.SSHCommand(fmt.Sprintf("if ! type docker; then curl -sSL %s | sh -; fi", baseURL)); err != nil {
+	if output, err := p.SSHCommand(command); err != nil {
 		return fmt.Errorf("error installing docker: %s\n", output)
 	}
 
@@ -55,6 +158,17 @@ func setRemoteAuthOptions(p Provisioner) auth.Options {
 	return authOptions
 }
 
+// serverCertOptions returns the options of the server certificate of the
+// machine, signed for org.
+func serverCertOptions(authOptions auth.Options, org string) cert.Options {
+	return cert.Options{
+		Org:      org,
+		KeyType:  authOptions.ServerKeyType,
+		Bits:     authOptions.ServerKeyBits,
+		Validity: authOptions.ServerCertValidity,
+	}
+}
+
 func ConfigureAuth(p Provisioner) error {
 	var (
 		err error
@@ -64,7 +178,6 @@ func ConfigureAuth(p Provisioner) error {
 	machineName := driver.GetMachineName()
 	authOptions := p.GetAuthOptions()
 	org := mcnutils.GetUsername() + "." + machineName
-	bits := 2048
 
 	ip, err := driver.GetIP()
 	if err != nil {
@@ -95,62 +208,46 @@ func ConfigureAuth(p Provisioner) error {
 		hosts,
 	)
 
-	// TODO: Switch to passing just authOptions to this func
-	// instead of all these individual fields
-	err = cert.GenerateCert(
+	// The server cert is generated next to the current one, which is kept
+	// until the daemon is known to accept the new one.
+	swap := &certSwap{
+		SSHCommander: p,
+		Local:        []string{authOptions.ServerCertPath, authOptions.ServerKeyPath},
+	}
+	defer swap.discardLocal()
+
+	err = cert.GenerateServerCert(
+		&authOptions,
 		hosts,
-		authOptions.ServerCertPath,
-		authOptions.ServerKeyPath,
-		authOptions.CaCertPath,
-		authOptions.CaPrivateKeyPath,
-		org,
-		bits,
+		stagedPath(authOptions.ServerCertPath),
+		stagedPath(authOptions.ServerKeyPath),
+		serverCertOptions(authOptions, org),
 	)
 
 	if err != nil {
 		return fmt.Errorf("error generating server cert: %s", err)
 	}
 
-	if err := p.Service("docker", serviceaction.Stop); err != nil {
-		return err
-	}
-
-	if _, err := p.SSHCommand("sudo ip link delete docker0"); err != nil {
-		return err
-	}
-
 	// upload certs and configure TLS auth
 	caCert, err := ioutil.ReadFile(authOptions.CaCertPath)
 	if err != nil {
 		return err
 	}
 
-	serverCert, err := ioutil.ReadFile(authOptions.ServerCertPath)
+	serverCert, err := ioutil.ReadFile(stagedPath(authOptions.ServerCertPath))
 	if err != nil {
 		return err
 	}
-	serverKey, err := ioutil.ReadFile(authOptions.ServerKeyPath)
+	serverKey, err := ioutil.ReadFile(stagedPath(authOptions.ServerKeyPath))
 	if err != nil {
 		return err
 	}
 
-	log.Info("Copying certs to the remote machine...")
-
-	// printf will choke if we don't pass a format string because of the
-	// dashes, so that's the reason for the '%%s'
-	certTransferCmdFmt := "printf '%%s' '%s' | sudo tee %s"
-
 	// These ones are for Jessie and Mike <3 <3 <3
-	if _, err := p.SSHCommand(fmt.Sprintf(certTransferCmdFmt, string(caCert), authOptions.CaCertRemotePath)); err != nil {
-		return err
-	}
-
-	if _, err := p.SSHCommand(fmt.Sprintf(certTransferCmdFmt, string(serverCert), authOptions.ServerCertRemotePath)); err != nil {
-		return err
-	}
-
-	if _, err := p.SSHCommand(fmt.Sprintf(certTransferCmdFmt, string(serverKey), authOptions.ServerKeyRemotePath)); err != nil {
-		return err
+	swap.Remote = []remoteCert{
+		{authOptions.CaCertRemotePath, caCert},
+		{authOptions.ServerCertRemotePath, serverCert},
+		{authOptions.ServerKeyRemotePath, serverKey},
 	}
 
 	dockerURL, err := driver.GetURL()
@@ -176,17 +273,81 @@ func ConfigureAuth(p Provisioner) error {
 		return err
 	}
 
-	log.Info("Setting Docker configuration on the remote daemon...")
-
-	if _, err = p.SSHCommand(fmt.Sprintf("printf %%s \"%s\" | sudo tee %s", dkrcfg.EngineOptions, dkrcfg.EngineOptionsPath)); err != nil {
+	caCertCmds, err := caCertsCommands(p)
+	if err != nil {
 		return err
 	}
 
-	if err := p.Service("docker", serviceaction.Start); err != nil {
-		return err
+	swap.Configs = []string{dkrcfg.EngineOptionsPath, environmentPath}
+
+	configure := func() error {
+		if err := p.Service("docker", serviceaction.Stop); err != nil {
+			return err
+		}
+
+		if _, err := p.SSHCommand("sudo ip link delete docker0"); err != nil {
+			return err
+		}
+
+		log.Info("Setting Docker configuration on the remote daemon...")
+
+		if _, err := p.SSHCommand(fmt.Sprintf("printf %%s \"%s\" | sudo tee %s", dkrcfg.EngineOptions, dkrcfg.EngineOptionsPath)); err != nil {
+			return err
+		}
+
+		if err := configureCACerts(p, caCertCmds); err != nil {
+			return err
+		}
+
+		if err := configureTimezone(p); err != nil {
+			return err
+		}
+
+		if err := configureSysctls(p); err != nil {
+			return err
+		}
+
+		if err := configureProxy(p); err != nil {
+			return err
+		}
+
+		log.Info("Copying certs to the remote machine...")
+
+		return nil
+	}
+
+	startDocker := func() error {
+		if err := p.Service("docker", serviceaction.Start); err != nil {
+			return err
+		}
+
+		return waitForDocker(p, dockerPort)
+	}
+
+	// The daemon listening isn't enough: the client must trust its new
+	// certificate and be accepted by it.
+	dockerAddr := net.JoinHostPort(u.Hostname(), strconv.Itoa(dockerPort))
+	checkTLS := daemonTLSCheck(driver, dockerAddr, caCert, authOptions.ClientCertPath, authOptions.ClientKeyPath)
+	activate := func() error {
+		if err := startDocker(); err != nil {
+			return err
+		}
+		if checkTLS == nil {
+			return nil
+		}
+
+		var tlsErr error
+		if err := mcnutils.WaitForSpecific(func() bool {
+			tlsErr = checkTLS()
+			return tlsErr == nil
+		}, 3, 2*time.Second); err != nil {
+			return tlsErr
+		}
+
+		return nil
 	}
 
-	return waitForDocker(p, dockerPort)
+	return swap.Run(configure, activate, startDocker)
 }
 
 func matchNetstatOut(reDaemonListening, netstatOut string) bool {
@@ -8,6 +8,8 @@ import (
 
 	"github.com/docker/machine/drivers/fakedriver"
 	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/engine"
+	"github.com/stretchr/testify/assert"
 )
 
 var (
@@ -156,3 +158,49 @@ func TestMachineCustomPortBoot2Docker(t *testing.T) {
 		t.Errorf("expected url %s; received %s", bindURL, url)
 	}
 }
+
+func TestInstallDockerCommand(t *testing.T) {
+	cmd := installDockerCommand(engine.Options{InstallURL: "https://get.docker.com"})
+
+	assert.Equal(t, "if ! type docker; then curl -fsSL 'https://get.docker.com' | sh -; fi", cmd)
+}
+
+func TestInstallDockerCommandUsesProxy(t *testing.T) {
+	cmd := installDockerCommand(engine.Options{
+		InstallURL: "https://get.docker.com",
+		Env:        []string{"HTTPS_PROXY=http://proxy:3128", "FOO=bar", "no_proxy=localhost"},
+	})
+
+	assert.Equal(t, "if ! type docker; then HTTPS_PROXY='http://proxy:3128' no_proxy='localhost' curl -fsSL 'https://get.docker.com' | HTTPS_PROXY='http://proxy:3128' no_proxy='localhost' sh -; fi", cmd)
+}
+
+func TestInstallDockerCommandVerifiesSHA256(t *testing.T) {
+	sum := strings.Repeat("AB", 32)
+	cmd := installDockerCommand(engine.Options{InstallURL: "https://get.docker.com", InstallURLSHA256: sum})
+
+	assert.Contains(t, cmd, "curl -fsSL -o $script 'https://get.docker.com'")
+	assert.Contains(t, cmd, "echo '"+strings.ToLower(sum)+"  '$script | sha256sum -c - && sh $script")
+	assert.NotContains(t, cmd, "| sh -")
+}
+
+func TestInstallEngineCommand(t *testing.T) {
+	cmd, err := installEngineCommand(engine.Options{InstallURL: "https://get.docker.com", InstallMethod: engine.InstallMethodPackage})
+	assert.NoError(t, err)
+	assert.Contains(t, cmd, "curl -fsSL 'https://get.docker.com' | sh -")
+
+	cmd, err = installEngineCommand(engine.Options{InstallMethod: engine.InstallMethodStatic, InstallVersion: "17.03.2-ce"})
+	assert.NoError(t, err)
+	assert.Contains(t, cmd, "curl -fsSL -o $dir/docker.tgz 'https://download.docker.com/linux/static/stable/'$(uname -m)'/docker-17.03.2-ce.tgz'")
+	assert.Contains(t, cmd, "sudo tar -xzf $dir/docker.tgz -C /usr/bin --strip-components=1")
+	assert.Contains(t, cmd, "ExecStart=/usr/bin/dockerd")
+
+	cmd, err = installEngineCommand(engine.Options{InstallMethod: engine.InstallMethodNone})
+	assert.NoError(t, err)
+	assert.Equal(t, "", cmd)
+
+	_, err = installEngineCommand(engine.Options{InstallMethod: engine.InstallMethodStatic})
+	assert.Error(t, err)
+
+	_, err = installEngineCommand(engine.Options{InstallMethod: "rpm"})
+	assert.Error(t, err)
+}
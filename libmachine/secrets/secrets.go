@@ -0,0 +1,316 @@
+// Package secrets encrypts the sensitive fields of the configuration of the
+// drivers, such as the access keys and the tokens of the cloud providers, for
+// them not to be kept in clear in the store.
+package secrets
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/docker/machine/libmachine/keychain"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// KeyEnvVar is the environment variable giving the key, base64 encoded.
+	// It takes precedence over the key of the keychain.
+	KeyEnvVar = "MACHINE_SECRETS_KEY"
+
+	// keySize is the size of the AES-256 keys.
+	keySize = 32
+
+	// keychainService and keychainAccount identify the key in the keychain.
+	keychainService = "docker-machine"
+	keychainAccount = "secrets-key"
+
+	// sealedPrefix starts the encrypted values, followed by the nonce and
+	// the ciphertext, base64 encoded.
+	sealedPrefix = "machine-secret:v1:"
+)
+
+var (
+	// ErrNoKey is returned when decrypting a configuration without a key.
+	ErrNoKey = fmt.Errorf("Error: the configuration has encrypted secrets but there's no key to decrypt them, set %s or store the key in the keychain", KeyEnvVar)
+
+	errInvalidSealed = errors.New("Error: invalid encrypted secret")
+
+	// sensitiveSuffixes end the names of the fields holding secrets.
+	sensitiveSuffixes = []string{"Password", "Secret", "SecretKey", "Token"}
+
+	// sensitiveNames are the other fields holding secrets.
+	sensitiveNames = map[string]bool{
+		"AccessKey": true,
+		"APIKey":    true,
+		"ApiKey":    true,
+	}
+
+	// keychainGet reads the key from the keychain.
+	keychainGet = keychain.Get
+
+	keychainKey struct {
+		sync.Once
+		key []byte
+		err error
+	}
+)
+
+// GenerateKey returns a new key, base64 encoded.
+func GenerateKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ParseKey decodes a base64 encoded key.
+func ParseKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("Error: the secrets key must be %d bytes, base64 encoded", keySize)
+	}
+
+	return key, nil
+}
+
+// KeychainError is returned by LookupKey when the keychain can't be read,
+// e.g. when it's locked, so that whether it holds a key isn't known.
+type KeychainError struct {
+	Err error
+}
+
+func (e *KeychainError) Error() string {
+	return fmt.Sprintf("Error reading the secrets key from the keychain: %s", e.Err)
+}
+
+// IsKeychainError tells whether LookupKey failed because the keychain can't
+// be read.
+func IsKeychainError(err error) bool {
+	_, ok := err.(*KeychainError)
+	return ok
+}
+
+// LookupKey returns the key given by KeyEnvVar, else the one stored in the
+// keychain, or nil if there's none. It returns a KeychainError when the
+// keychain can't be read.
+func LookupKey() ([]byte, error) {
+	if s := os.Getenv(KeyEnvVar); s != "" {
+		return ParseKey(s)
+	}
+
+	keychainKey.Do(func() {
+		s, err := keychainGet(keychainService, keychainAccount)
+		switch {
+		case err == keychain.ErrNotFound || keychain.IsUnavailable(err):
+			log.Debugf("No secrets key in the keychain: %s", err)
+		case err != nil:
+			keychainKey.err = &KeychainError{err}
+		default:
+			keychainKey.key, keychainKey.err = ParseKey(s)
+		}
+	})
+
+	return keychainKey.key, keychainKey.err
+}
+
+// StoreKeychainKey stores the key in the keychain, replacing the one stored
+// before.
+func StoreKeychainKey(key string) error {
+	return keychain.Set(keychainService, keychainAccount, "Docker Machine secrets key", key)
+}
+
+// IsSensitive tells whether the field of the configuration of a driver holds
+// a secret.
+func IsSensitive(field string) bool {
+	if sensitiveNames[field] {
+		return true
+	}
+
+	for _, suffix := range sensitiveSuffixes {
+		if strings.HasSuffix(field, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsEncrypted tells whether the value was encrypted by Encrypt.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// Encrypt encrypts the value with AES-256-GCM.
+func Encrypt(key []byte, value string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(value), nil)
+
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value encrypted by Encrypt.
+func Decrypt(key []byte, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errInvalidSealed
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, sealedPrefix))
+	if err != nil {
+		return "", errInvalidSealed
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return "", errInvalidSealed
+	}
+
+	opened, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("Error: the secrets key can't decrypt the configuration, it was encrypted with another key")
+	}
+
+	return string(opened), nil
+}
+
+func decodeObject(config []byte) (map[string]interface{}, error) {
+	var object map[string]interface{}
+
+	d := json.NewDecoder(bytes.NewReader(config))
+	d.UseNumber()
+	if err := d.Decode(&object); err != nil {
+		return nil, err
+	}
+
+	return object, nil
+}
+
+// sealObject encrypts the sensitive fields of the object and of the objects
+// it holds. It returns whether any was encrypted.
+func sealObject(key []byte, object map[string]interface{}) (bool, error) {
+	sealed := false
+
+	for name, value := range object {
+		switch v := value.(type) {
+		case string:
+			if v == "" || IsEncrypted(v) || !IsSensitive(name) {
+				continue
+			}
+
+			encrypted, err := Encrypt(key, v)
+			if err != nil {
+				return false, err
+			}
+			object[name] = encrypted
+			sealed = true
+		case map[string]interface{}:
+			s, err := sealObject(key, v)
+			if err != nil {
+				return false, err
+			}
+			sealed = sealed || s
+		}
+	}
+
+	return sealed, nil
+}
+
+// openObject decrypts the encrypted fields of the object and of the objects
+// it holds. It returns whether any was decrypted.
+func openObject(key []byte, object map[string]interface{}) (bool, error) {
+	opened := false
+
+	for name, value := range object {
+		switch v := value.(type) {
+		case string:
+			if !IsEncrypted(v) {
+				continue
+			}
+
+			if key == nil {
+				return false, ErrNoKey
+			}
+
+			decrypted, err := Decrypt(key, v)
+			if err != nil {
+				return false, err
+			}
+			object[name] = decrypted
+			opened = true
+		case map[string]interface{}:
+			o, err := openObject(key, v)
+			if err != nil {
+				return false, err
+			}
+			opened = opened || o
+		}
+	}
+
+	return opened, nil
+}
+
+// SealFields encrypts the sensitive fields of the JSON configuration of a
+// driver. The configuration is returned as is when it has none, or when it
+// isn't a JSON object.
+func SealFields(key []byte, config []byte) ([]byte, error) {
+	object, err := decodeObject(config)
+	if err != nil {
+		return config, nil
+	}
+
+	sealed, err := sealObject(key, object)
+	if err != nil || !sealed {
+		return config, err
+	}
+
+	return json.Marshal(object)
+}
+
+// OpenFields decrypts the encrypted fields of the JSON configuration of a
+// driver, with key, which may be nil if it has none. The configuration is
+// returned as is when it has none, or when it isn't a JSON object.
+func OpenFields(key []byte, config []byte) ([]byte, error) {
+	object, err := decodeObject(config)
+	if err != nil {
+		return config, nil
+	}
+
+	opened, err := openObject(key, object)
+	if err != nil || !opened {
+		return config, err
+	}
+
+	return json.Marshal(object)
+}
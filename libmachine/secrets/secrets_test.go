@@ -0,0 +1,153 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/docker/machine/libmachine/keychain"
+	"github.com/stretchr/testify/assert"
+)
+
+func testKey(t *testing.T) []byte {
+	s, err := GenerateKey()
+	assert.NoError(t, err)
+
+	key, err := ParseKey(s)
+	assert.NoError(t, err)
+
+	return key
+}
+
+func TestParseKey(t *testing.T) {
+	_, err := ParseKey("dG9vIHNob3J0")
+	assert.Error(t, err)
+
+	_, err = ParseKey("not base64!")
+	assert.Error(t, err)
+}
+
+func TestLookupKeyFromEnv(t *testing.T) {
+	s, err := GenerateKey()
+	assert.NoError(t, err)
+
+	defer os.Setenv(KeyEnvVar, os.Getenv(KeyEnvVar))
+	os.Setenv(KeyEnvVar, s)
+
+	key, err := LookupKey()
+	assert.NoError(t, err)
+	assert.Len(t, key, keySize)
+
+	os.Setenv(KeyEnvVar, "invalid")
+	_, err = LookupKey()
+	assert.Error(t, err)
+}
+
+func TestLookupKeyFromLockedKeychain(t *testing.T) {
+	defer os.Setenv(KeyEnvVar, os.Getenv(KeyEnvVar))
+	os.Unsetenv(KeyEnvVar)
+
+	defer func(get func(service, account string) (string, error)) {
+		keychainGet = get
+		keychainKey.Once = sync.Once{}
+		keychainKey.key, keychainKey.err = nil, nil
+	}(keychainGet)
+
+	keychainKey.Once = sync.Once{}
+	keychainGet = func(service, account string) (string, error) {
+		return "", errors.New("the keyring is locked")
+	}
+
+	key, err := LookupKey()
+
+	assert.Nil(t, key)
+	assert.True(t, IsKeychainError(err))
+	assert.EqualError(t, err, "Error reading the secrets key from the keychain: the keyring is locked")
+
+	keychainKey.Once = sync.Once{}
+	keychainKey.err = nil
+	keychainGet = func(service, account string) (string, error) {
+		return "", keychain.ErrNotFound
+	}
+
+	key, err = LookupKey()
+
+	assert.Nil(t, key)
+	assert.NoError(t, err)
+}
+
+func TestIsSensitive(t *testing.T) {
+	for _, field := range []string{"AccessKey", "SecretKey", "SessionToken", "AccessToken", "APIToken", "APIKey", "ApiKey", "APISecretKey", "Password", "SSHPassword", "ClientSecret"} {
+		assert.True(t, IsSensitive(field), field)
+	}
+
+	for _, field := range []string{"SSHKeyPath", "SSHKey", "PublicKey", "MachineName", "Region", "TokenURL"} {
+		assert.False(t, IsSensitive(field), field)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := testKey(t)
+
+	encrypted, err := Encrypt(key, "s3cr3t")
+	assert.NoError(t, err)
+	assert.True(t, IsEncrypted(encrypted))
+	assert.NotContains(t, encrypted, "s3cr3t")
+
+	decrypted, err := Decrypt(key, encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cr3t", decrypted)
+
+	_, err = Decrypt(testKey(t), encrypted)
+	assert.Error(t, err)
+
+	_, err = Decrypt(key, sealedPrefix+"AAAA")
+	assert.Error(t, err)
+
+	_, err = Decrypt(key, "s3cr3t")
+	assert.Error(t, err)
+}
+
+func TestSealOpenFields(t *testing.T) {
+	key := testKey(t)
+	config := []byte(`{"AccessKey":"AKIA","SecretKey":"s3cr3t","Region":"us-east-1","SSHPort":22,"Password":"","Nested":{"SessionToken":"tok"}}`)
+
+	sealed, err := SealFields(key, config)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(sealed), "AKIA")
+	assert.NotContains(t, string(sealed), "s3cr3t")
+	assert.NotContains(t, string(sealed), "tok\"")
+	assert.Contains(t, string(sealed), `"Region":"us-east-1"`)
+
+	// Sealing again leaves the encrypted fields alone.
+	resealed, err := SealFields(key, sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, sealed, resealed)
+
+	_, err = OpenFields(nil, sealed)
+	assert.Equal(t, ErrNoKey, err)
+
+	opened, err := OpenFields(key, sealed)
+	assert.NoError(t, err)
+
+	var expected, actual map[string]interface{}
+	assert.NoError(t, json.Unmarshal(config, &expected))
+	assert.NoError(t, json.Unmarshal(opened, &actual))
+	assert.Equal(t, expected, actual)
+}
+
+func TestSealOpenFieldsUnchanged(t *testing.T) {
+	key := testKey(t)
+
+	for _, config := range []string{`{"Region":"us-east-1"}`, `null`, `not json`} {
+		sealed, err := SealFields(key, []byte(config))
+		assert.NoError(t, err)
+		assert.Equal(t, config, string(sealed))
+
+		opened, err := OpenFields(nil, []byte(config))
+		assert.NoError(t, err)
+		assert.Equal(t, config, string(opened))
+	}
+}
@@ -0,0 +1,108 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/docker/machine/libmachine/log"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+var (
+	errNoAgent = errors.New("Error: SSH agent forwarding needs an SSH agent, SSH_AUTH_SOCK is not set")
+
+	// sshAgent is the connection to the SSH agent, opened the first time
+	// its keys are needed and kept for them to sign.
+	sshAgent struct {
+		sync.Mutex
+		agent agent.Agent
+	}
+)
+
+// AgentSocket returns the socket of the SSH agent given by SSH_AUTH_SOCK, or
+// "" if there's none.
+func AgentSocket() string {
+	return os.Getenv("SSH_AUTH_SOCK")
+}
+
+func agentClient() (agent.Agent, error) {
+	sshAgent.Lock()
+	defer sshAgent.Unlock()
+
+	if sshAgent.agent != nil {
+		return sshAgent.agent, nil
+	}
+
+	conn, err := net.Dial("unix", AgentSocket())
+	if err != nil {
+		return nil, fmt.Errorf("Error connecting to the SSH agent: %s", err)
+	}
+
+	sshAgent.agent = agent.NewClient(conn)
+
+	return sshAgent.agent, nil
+}
+
+// agentSigners returns the keys held by the SSH agent, such as the keys of
+// hardware tokens which can't be read from a file, or none if there's no
+// agent.
+func agentSigners() ([]ssh.Signer, error) {
+	if AgentSocket() == "" {
+		return nil, nil
+	}
+
+	client, err := agentClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Signers()
+}
+
+// existingKeys returns the private key files which exist. The missing ones
+// are left out when the SSH agent may hold the keys instead.
+func existingKeys(keys []string) []string {
+	existing := []string{}
+	for _, k := range keys {
+		if _, err := os.Stat(k); os.IsNotExist(err) && AgentSocket() != "" {
+			log.Debugf("%s doesn't exist, using the keys of the SSH agent", k)
+			continue
+		}
+
+		existing = append(existing, k)
+	}
+
+	return existing
+}
+
+// ForwardAgent returns the client forwarding the SSH agent to the host in its
+// shells, like ssh -A.
+func ForwardAgent(client Client) (Client, error) {
+	if AgentSocket() == "" {
+		return nil, errNoAgent
+	}
+
+	switch c := client.(type) {
+	case ExternalClient:
+		c.BaseArgs = append([]string{"-A"}, c.BaseArgs...)
+		return c, nil
+	case NativeClient:
+		c.ForwardAgent = true
+		return c, nil
+	}
+
+	return nil, errors.New("Error: SSH agent forwarding is not supported by this SSH client")
+}
+
+// forwardAgent forwards the SSH agent to the host of conn in the session.
+func forwardAgent(conn *ssh.Client, session *ssh.Session) error {
+	if err := agent.ForwardToRemote(conn, AgentSocket()); err != nil {
+		return err
+	}
+
+	return agent.RequestAgentForwarding(session)
+}
@@ -0,0 +1,95 @@
+package ssh
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// serveTestAgent serves a keyring holding a key on a socket given by
+// SSH_AUTH_SOCK, until the returned function is called.
+func serveTestAgent(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+
+	keyring := agent.NewKeyring()
+	assert.NoError(t, keyring.Add(agent.AddedKey{PrivateKey: key}))
+
+	socket := filepath.Join(dir, "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	assert.NoError(t, err)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+
+	previous := os.Getenv("SSH_AUTH_SOCK")
+	os.Setenv("SSH_AUTH_SOCK", socket)
+
+	return func() {
+		os.Setenv("SSH_AUTH_SOCK", previous)
+		listener.Close()
+		os.RemoveAll(dir)
+
+		sshAgent.Lock()
+		sshAgent.agent = nil
+		sshAgent.Unlock()
+	}
+}
+
+func TestAgentSigners(t *testing.T) {
+	defer serveTestAgent(t)()
+
+	signers, err := agentSigners()
+	assert.NoError(t, err)
+	assert.Len(t, signers, 1)
+}
+
+func TestExistingKeys(t *testing.T) {
+	defer os.Setenv("SSH_AUTH_SOCK", os.Getenv("SSH_AUTH_SOCK"))
+
+	os.Unsetenv("SSH_AUTH_SOCK")
+	assert.Equal(t, []string{"/missing/id_rsa"}, existingKeys([]string{"/missing/id_rsa"}))
+
+	os.Setenv("SSH_AUTH_SOCK", "/tmp/agent.sock")
+	assert.Empty(t, existingKeys([]string{"/missing/id_rsa"}))
+}
+
+func TestNewExternalClientWithAgent(t *testing.T) {
+	defer os.Setenv("SSH_AUTH_SOCK", os.Getenv("SSH_AUTH_SOCK"))
+	os.Setenv("SSH_AUTH_SOCK", "/tmp/agent.sock")
+
+	client, err := NewExternalClient("/usr/bin/ssh", "docker", "localhost", 2022, &Auth{Keys: []string{"/missing/id_rsa"}})
+	assert.NoError(t, err)
+
+	assert.NotContains(t, client.BaseArgs, "IdentitiesOnly=yes")
+	assert.NotContains(t, client.BaseArgs, "-i")
+
+	forwarding, err := ForwardAgent(client)
+	assert.NoError(t, err)
+	assert.Equal(t, "-A", forwarding.(ExternalClient).BaseArgs[0])
+}
+
+func TestForwardAgentWithoutAgent(t *testing.T) {
+	defer os.Setenv("SSH_AUTH_SOCK", os.Getenv("SSH_AUTH_SOCK"))
+	os.Unsetenv("SSH_AUTH_SOCK")
+
+	_, err := ForwardAgent(NativeClient{})
+	assert.Equal(t, errNoAgent, err)
+}
@@ -2,10 +2,14 @@ package ssh
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/docker/docker/pkg/term"
 	"github.com/docker/machine/libmachine/log"
@@ -19,6 +23,13 @@ type Client interface {
 	Shell(args ...string) error
 }
 
+// InputClient is a client giving input to the commands it runs, e.g. for
+// secrets to stay out of their command lines, which are logged.
+type InputClient interface {
+	Client
+	OutputWithInput(command string, input io.Reader) (string, error)
+}
+
 type ExternalClient struct {
 	BaseArgs   []string
 	BinaryPath string
@@ -28,11 +39,18 @@ type NativeClient struct {
 	Config   ssh.ClientConfig
 	Hostname string
 	Port     int
+	// ForwardAgent forwards the SSH agent to the host in the shells.
+	ForwardAgent bool
+	// ProxyJump is the bastion through which the host is reached, if any.
+	ProxyJump *ProxyJump
 }
 
 type Auth struct {
 	Passwords []string
 	Keys      []string
+	// ProxyJump is the bastion through which the host is reached,
+	// [user@]host[:port], or empty to reach it directly.
+	ProxyJump string
 }
 
 type ClientType string
@@ -49,18 +67,37 @@ const (
 var (
 	baseSSHArgs = []string{
 		"-o", "PasswordAuthentication=no",
-		"-o", "IdentitiesOnly=yes",
 		"-o", "StrictHostKeyChecking=no",
 		"-o", "UserKnownHostsFile=/dev/null",
 		"-o", "LogLevel=quiet", // suppress "Warning: Permanently added '[localhost]:2022' (ECDSA) to the list of known hosts."
 		"-o", "ConnectionAttempts=3", // retry 3 times if SSH connection fails
 		"-o", "ConnectTimeout=10", // timeout after 10 seconds
-		"-o", "ControlMaster=no", // disable ssh multiplexing
-		"-o", "ControlPath=no",
 	}
 	defaultClientType = External
+
+	// controlPersist is how long the master connection of the external
+	// client outlives its last command.
+	controlPersist = "60s"
+
+	// controlDir is the directory of the sockets of the connections shared
+	// by the external client, which doesn't share them if empty.
+	controlDir string
+
+	// nativeConnections are the connections of the native client, by
+	// user@host:port, kept open so that the commands run on a host share a
+	// single handshake.
+	nativeConnections = struct {
+		sync.Mutex
+		clients map[string]*ssh.Client
+	}{clients: map[string]*ssh.Client{}}
 )
 
+// SetControlDir sets the directory of the sockets of the connections shared
+// by the external client.
+func SetControlDir(dir string) {
+	controlDir = dir
+}
+
 func SetDefaultClient(clientType ClientType) {
 	// Allow over-riding of default client type, so that even if ssh binary
 	// is found in PATH we can still use the Go native implementation if
@@ -95,19 +132,28 @@ func NewNativeClient(user, host string, port int, auth *Auth) (Client, error) {
 		return nil, fmt.Errorf("Error getting config for native Go SSH: %s", err)
 	}
 
-	return NativeClient{
+	client := NativeClient{
 		Config:   config,
 		Hostname: host,
 		Port:     port,
-	}, nil
+	}
+
+	if auth.ProxyJump != "" {
+		if client.ProxyJump, err = ParseProxyJump(auth.ProxyJump); err != nil {
+			return nil, err
+		}
+	}
+
+	return client, nil
 }
 
 func NewNativeConfig(user string, auth *Auth) (ssh.ClientConfig, error) {
 	var (
 		authMethods []ssh.AuthMethod
+		signers     []ssh.Signer
 	)
 
-	for _, k := range auth.Keys {
+	for _, k := range existingKeys(auth.Keys) {
 		key, err := ioutil.ReadFile(k)
 		if err != nil {
 			return ssh.ClientConfig{}, err
@@ -118,9 +164,20 @@ func NewNativeConfig(user string, auth *Auth) (ssh.ClientConfig, error) {
 			return ssh.ClientConfig{}, err
 		}
 
-		authMethods = append(authMethods, ssh.PublicKeys(privateKey))
+		signers = append(signers, privateKey)
 	}
 
+	// Each method is only tried once, so the keys of the files and of the
+	// SSH agent are offered by the same one.
+	authMethods = append(authMethods, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+		agentSigners, err := agentSigners()
+		if err != nil {
+			log.Debug(err)
+		}
+
+		return append(signers, agentSigners...), nil
+	}))
+
 	for _, p := range auth.Passwords {
 		authMethods = append(authMethods, ssh.Password(p))
 	}
@@ -131,22 +188,114 @@ func NewNativeConfig(user string, auth *Auth) (ssh.ClientConfig, error) {
 	}, nil
 }
 
-func (client NativeClient) dialSuccess() bool {
-	if _, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", client.Hostname, client.Port), &client.Config); err != nil {
-		log.Debugf("Error dialing TCP: %s", err)
-		return false
+func (client NativeClient) address() string {
+	return fmt.Sprintf("%s:%d", client.Hostname, client.Port)
+}
+
+func (client NativeClient) connectionKey() string {
+	return fmt.Sprintf("%s@%s", client.Config.User, client.address())
+}
+
+// dialHost connects to the host, through the bastion if there's one.
+func (client NativeClient) dialHost() (*ssh.Client, error) {
+	if client.ProxyJump != nil {
+		return client.ProxyJump.dial(client.address(), &client.Config)
+	}
+
+	return ssh.Dial("tcp", client.address(), &client.Config)
+}
+
+// dial connects to the host, waiting for it to accept SSH connections.
+func (client NativeClient) dial() (*ssh.Client, error) {
+	var conn *ssh.Client
+
+	err := mcnutils.WaitFor(func() bool {
+		var err error
+		if conn, err = client.dialHost(); err != nil {
+			log.Debugf("Error dialing TCP: %s", err)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// connection returns the open connection to the host, dialing it if there's
+// none yet.
+func (client NativeClient) connection() (*ssh.Client, error) {
+	key := client.connectionKey()
+
+	nativeConnections.Lock()
+	conn, present := nativeConnections.clients[key]
+	nativeConnections.Unlock()
+	if present {
+		return conn, nil
+	}
+
+	// Dial without holding the lock, which would hold up the commands run
+	// on the other hosts while this one boots.
+	conn, err := client.dial()
+	if err != nil {
+		return nil, err
+	}
+
+	nativeConnections.Lock()
+	defer nativeConnections.Unlock()
+
+	if other, present := nativeConnections.clients[key]; present {
+		conn.Close()
+		return other, nil
+	}
+	nativeConnections.clients[key] = conn
+
+	return conn, nil
+}
+
+// forget closes conn and drops it from the open connections.
+func (client NativeClient) forget(conn *ssh.Client) {
+	key := client.connectionKey()
+
+	nativeConnections.Lock()
+	defer nativeConnections.Unlock()
+
+	if nativeConnections.clients[key] == conn {
+		delete(nativeConnections.clients, key)
+	}
+	conn.Close()
+}
+
+// CloseConnections closes the connections kept open by the native client.
+func CloseConnections() {
+	nativeConnections.Lock()
+	defer nativeConnections.Unlock()
+
+	for key, conn := range nativeConnections.clients {
+		conn.Close()
+		delete(nativeConnections.clients, key)
 	}
-	return true
 }
 
 func (client NativeClient) session(command string) (*ssh.Session, error) {
-	if err := mcnutils.WaitFor(client.dialSuccess); err != nil {
+	conn, err := client.connection()
+	if err != nil {
 		return nil, fmt.Errorf("Error attempting SSH client dial: %s", err)
 	}
 
-	conn, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", client.Hostname, client.Port), &client.Config)
-	if err != nil {
-		return nil, fmt.Errorf("Mysterious error dialing TCP for SSH (we already succeeded at least once) : %s", err)
+	session, err := conn.NewSession()
+	if err == nil {
+		return session, nil
+	}
+
+	// The host may have dropped the connection, e.g. when it rebooted.
+	log.Debugf("Error opening an SSH session on %s, reconnecting: %s", client.address(), err)
+	client.forget(conn)
+
+	if conn, err = client.connection(); err != nil {
+		return nil, fmt.Errorf("Error attempting SSH client dial: %s", err)
 	}
 
 	return conn.NewSession()
@@ -164,6 +313,20 @@ func (client NativeClient) Output(command string) (string, error) {
 	return string(output), err
 }
 
+// OutputWithInput runs the command with input as its standard input.
+func (client NativeClient) OutputWithInput(command string, input io.Reader) (string, error) {
+	session, err := client.session(command)
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+
+	session.Stdin = input
+	output, err := session.CombinedOutput(command)
+
+	return string(output), err
+}
+
 func (client NativeClient) OutputWithPty(command string) (string, error) {
 	session, err := client.session(command)
 	if err != nil {
@@ -199,7 +362,7 @@ func (client NativeClient) Shell(args ...string) error {
 	var (
 		termWidth, termHeight int
 	)
-	conn, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", client.Hostname, client.Port), &client.Config)
+	conn, err := client.dialHost()
 	if err != nil {
 		return err
 	}
@@ -211,6 +374,12 @@ func (client NativeClient) Shell(args ...string) error {
 
 	defer session.Close()
 
+	if client.ForwardAgent {
+		if err := forwardAgent(conn, session); err != nil {
+			return fmt.Errorf("Error forwarding the SSH agent: %s", err)
+		}
+	}
+
 	session.Stdout = os.Stdout
 	session.Stderr = os.Stderr
 	session.Stdin = os.Stdin
@@ -260,10 +429,27 @@ func NewExternalClient(sshBinaryPath, user, host string, port int, auth *Auth) (
 		BinaryPath: sshBinaryPath,
 	}
 
-	args := append(baseSSHArgs, fmt.Sprintf("%s@%s", user, host))
+	keys := existingKeys(auth.Keys)
+
+	args := append([]string{}, baseSSHArgs...)
+	if len(keys) > 0 {
+		// Only offer the keys of the machine, not the ones of the agent.
+		args = append(args, "-o", "IdentitiesOnly=yes")
+	}
+	args = append(args, controlArgs(user, host, port)...)
+
+	if auth.ProxyJump != "" {
+		jump, err := ParseProxyJump(auth.ProxyJump)
+		if err != nil {
+			return client, err
+		}
+		args = append(args, "-o", "ProxyCommand="+jump.proxyCommand(sshBinaryPath, user))
+	}
+
+	args = append(args, fmt.Sprintf("%s@%s", user, host))
 
 	// Specify which private keys to use to authorize the SSH request.
-	for _, privateKeyPath := range auth.Keys {
+	for _, privateKeyPath := range keys {
 		args = append(args, "-i", privateKeyPath)
 	}
 
@@ -275,6 +461,60 @@ func NewExternalClient(sshBinaryPath, user, host string, port int, auth *Auth) (
 	return client, nil
 }
 
+// controlArgs returns the options making the external client share one
+// connection per host between its commands. Multiplexing is disabled where
+// it isn't supported, without a directory for the sockets, or when their
+// path would be too long for a socket.
+func controlArgs(user, host string, port int) []string {
+	disabled := []string{
+		"-o", "ControlMaster=no",
+		"-o", "ControlPath=no",
+	}
+
+	if runtime.GOOS == "windows" || controlDir == "" {
+		return disabled
+	}
+
+	if err := checkControlDir(controlDir); err != nil {
+		log.Debugf("Not sharing SSH connections: %s", err)
+		return disabled
+	}
+
+	// ssh adds a suffix of 17 characters to the path while creating the
+	// socket, whose path can be 104 bytes long on some systems.
+	path := filepath.Join(controlDir, "%r@%h:%p")
+	if len(filepath.Join(controlDir, fmt.Sprintf("%s@%s:%d", user, host, port)))+17 >= 104 {
+		log.Debugf("Not sharing SSH connections: the path of the sockets in %s would be too long", controlDir)
+		return disabled
+	}
+
+	return []string{
+		"-o", "ControlMaster=auto",
+		"-o", "ControlPath=" + path,
+		"-o", "ControlPersist=" + controlPersist,
+	}
+}
+
+// checkControlDir creates the directory of the sockets, or checks that only
+// the user can use the existing one, for no one else to get the
+// connections.
+func checkControlDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+
+	if !fi.IsDir() || fi.Mode().Perm() != 0700 || !ownedByUser(fi) {
+		return fmt.Errorf("%s must be a directory only the user can use", dir)
+	}
+
+	return nil
+}
+
 func getSSHCmd(binaryPath string, args ...string) *exec.Cmd {
 	return exec.Command(binaryPath, args...)
 }
@@ -286,6 +526,15 @@ func (client ExternalClient) Output(command string) (string, error) {
 	return string(output), err
 }
 
+// OutputWithInput runs the command with input as its standard input.
+func (client ExternalClient) OutputWithInput(command string, input io.Reader) (string, error) {
+	args := append(client.BaseArgs, command)
+	cmd := getSSHCmd(client.BinaryPath, args...)
+	cmd.Stdin = input
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
+
 func (client ExternalClient) Shell(args ...string) error {
 	args = append(client.BaseArgs, args...)
 	cmd := getSSHCmd(client.BinaryPath, args...)
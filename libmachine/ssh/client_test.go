@@ -1,9 +1,18 @@
 package ssh
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
 )
 
 func TestGetSSHCmdArgs(t *testing.T) {
@@ -43,3 +52,155 @@ func TestGetSSHCmdArgs(t *testing.T) {
 		assert.Equal(t, cmd.Args, c.expectedArgs)
 	}
 }
+
+// testSSHServer answers every command with its own text, and counts the
+// connections it accepted.
+type testSSHServer struct {
+	listener    net.Listener
+	config      *ssh.ServerConfig
+	connections int32
+}
+
+func newTestSSHServer(t *testing.T) *testSSHServer {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+
+	signer, err := ssh.NewSignerFromKey(key)
+	assert.NoError(t, err)
+
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	server := &testSSHServer{listener: listener, config: config}
+	go server.serve()
+
+	return server
+}
+
+func (s *testSSHServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *testSSHServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		atomic.AddInt32(&s.connections, 1)
+
+		go func() {
+			_, channels, requests, err := ssh.NewServerConn(conn, s.config)
+			if err != nil {
+				return
+			}
+			go ssh.DiscardRequests(requests)
+
+			for newChannel := range channels {
+				channel, requests, err := newChannel.Accept()
+				if err != nil {
+					continue
+				}
+
+				go func() {
+					for req := range requests {
+						if req.Type != "exec" {
+							req.Reply(false, nil)
+							continue
+						}
+						req.Reply(true, nil)
+
+						// The payload is the length-prefixed command.
+						channel.Write(req.Payload[4:])
+						channel.SendRequest("exit-status", false, []byte{0, 0, 0, 0})
+						channel.Close()
+					}
+				}()
+			}
+		}()
+	}
+}
+
+func TestNativeClientReusesConnection(t *testing.T) {
+	server := newTestSSHServer(t)
+	defer server.listener.Close()
+	defer CloseConnections()
+
+	client, err := NewNativeClient("docker", "127.0.0.1", server.port(), &Auth{})
+	assert.NoError(t, err)
+
+	for _, command := range []string{"hostname", "uname -r", "cat /etc/os-release"} {
+		output, err := client.Output(command)
+		assert.NoError(t, err)
+		assert.Equal(t, command, output)
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&server.connections))
+}
+
+func TestNativeClientReconnectsAfterClose(t *testing.T) {
+	server := newTestSSHServer(t)
+	defer server.listener.Close()
+	defer CloseConnections()
+
+	client, err := NewNativeClient("docker", "127.0.0.1", server.port(), &Auth{})
+	assert.NoError(t, err)
+
+	_, err = client.Output("hostname")
+	assert.NoError(t, err)
+
+	CloseConnections()
+
+	output, err := client.Output("hostname")
+	assert.NoError(t, err)
+	assert.Equal(t, "hostname", output)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&server.connections))
+}
+
+func TestNewExternalClientSharesConnections(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ssh multiplexing isn't supported on windows")
+	}
+
+	tmpDir, err := ioutil.TempDir("", "ssh")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	SetControlDir(filepath.Join(tmpDir, "ssh"))
+	defer SetControlDir("")
+
+	client, err := NewExternalClient("/usr/bin/ssh", "docker", "localhost", 2022, &Auth{Keys: []string{"/tmp/id_rsa"}})
+	assert.NoError(t, err)
+
+	assert.Contains(t, client.BaseArgs, "ControlMaster=auto")
+	assert.Contains(t, client.BaseArgs, "ControlPath="+filepath.Join(tmpDir, "ssh", "%r@%h:%p"))
+	assert.Contains(t, client.BaseArgs, "ControlPersist="+controlPersist)
+	assert.Equal(t, []string{"docker@localhost", "-i", "/tmp/id_rsa", "-p", "2022"}, client.BaseArgs[len(client.BaseArgs)-5:])
+
+	fi, err := os.Stat(filepath.Join(tmpDir, "ssh"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), fi.Mode().Perm())
+}
+
+func TestNewExternalClientDoesntShareConnectionsInOpenDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ssh multiplexing isn't supported on windows")
+	}
+
+	tmpDir, err := ioutil.TempDir("", "ssh")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	assert.NoError(t, os.Chmod(tmpDir, 0777))
+	SetControlDir(tmpDir)
+	defer SetControlDir("")
+
+	client, err := NewExternalClient("/usr/bin/ssh", "docker", "localhost", 2022, &Auth{})
+	assert.NoError(t, err)
+
+	assert.Contains(t, client.BaseArgs, "ControlMaster=no")
+	assert.Contains(t, client.BaseArgs, "ControlPath=no")
+}
@@ -0,0 +1,9 @@
+package ssh
+
+import "os"
+
+// ownedByUser returns whether the file is owned by the user running machine,
+// which isn't checked on Windows where the connections aren't shared.
+func ownedByUser(fi os.FileInfo) bool {
+	return true
+}
@@ -0,0 +1,109 @@
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ProxyJump is a bastion through which a host is reached, like the
+// ProxyJump of OpenSSH.
+type ProxyJump struct {
+	// User is the user on the bastion, the one on the host if empty.
+	User string
+	Host string
+	Port int
+}
+
+// ParseProxyJump parses a bastion given as [user@]host[:port].
+func ParseProxyJump(s string) (*ProxyJump, error) {
+	jump := &ProxyJump{Host: s, Port: 22}
+
+	if i := strings.LastIndex(jump.Host, "@"); i != -1 {
+		jump.User, jump.Host = jump.Host[:i], jump.Host[i+1:]
+	}
+
+	if host, port, err := net.SplitHostPort(jump.Host); err == nil {
+		p, err := strconv.Atoi(port)
+		if err != nil || p <= 0 || p > 65535 {
+			return nil, fmt.Errorf("Invalid port %q of the SSH bastion %q", port, s)
+		}
+		jump.Host, jump.Port = host, p
+	}
+
+	if jump.Host == "" || strings.ContainsAny(jump.Host, "@ ") || (strings.Contains(jump.Host, ":") && net.ParseIP(jump.Host) == nil) {
+		return nil, fmt.Errorf("The SSH bastion must be given as [user@]host[:port], not %q", s)
+	}
+
+	return jump, nil
+}
+
+func (jump *ProxyJump) address() string {
+	return net.JoinHostPort(jump.Host, strconv.Itoa(jump.Port))
+}
+
+func (jump *ProxyJump) user(hostUser string) string {
+	if jump.User == "" {
+		return hostUser
+	}
+
+	return jump.User
+}
+
+// proxyCommand returns the ProxyCommand making the external client connect
+// through the bastion. The keys of the machine aren't offered to the
+// bastion, which is reached with the keys and configuration of the user.
+func (jump *ProxyJump) proxyCommand(binaryPath, hostUser string) string {
+	args := []string{binaryPath}
+	args = append(args, baseSSHArgs...)
+	args = append(args, "-p", strconv.Itoa(jump.Port), fmt.Sprintf("%s@%s", jump.user(hostUser), jump.Host))
+
+	// The command is run by the shell once ssh expanded its % tokens.
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(strings.Replace(arg, "%", "%%", -1))
+	}
+
+	return strings.Join(append(quoted, "-W", "%h:%p"), " ")
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// dial connects to addr through the bastion, authenticating on both with
+// config.
+func (jump *ProxyJump) dial(addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	bastionConfig := *config
+	bastionConfig.User = jump.user(config.User)
+
+	bastion, err := ssh.Dial("tcp", jump.address(), &bastionConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Error connecting to the SSH bastion %s: %s", jump.address(), err)
+	}
+
+	conn, err := bastion.Dial("tcp", addr)
+	if err != nil {
+		bastion.Close()
+		return nil, fmt.Errorf("Error connecting to %s through the SSH bastion %s: %s", addr, jump.address(), err)
+	}
+
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		bastion.Close()
+		return nil, err
+	}
+
+	client := ssh.NewClient(c, chans, reqs)
+	go func() {
+		client.Wait()
+		bastion.Close()
+	}()
+
+	return client, nil
+}
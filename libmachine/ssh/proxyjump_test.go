@@ -0,0 +1,62 @@
+package ssh
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProxyJump(t *testing.T) {
+	cases := []struct {
+		jump     string
+		expected ProxyJump
+	}{
+		{"bastion.example.com", ProxyJump{Host: "bastion.example.com", Port: 22}},
+		{"ec2-user@bastion.example.com", ProxyJump{User: "ec2-user", Host: "bastion.example.com", Port: 22}},
+		{"ec2-user@10.0.0.1:2222", ProxyJump{User: "ec2-user", Host: "10.0.0.1", Port: 2222}},
+		{"[fd00::1]:2222", ProxyJump{Host: "fd00::1", Port: 2222}},
+	}
+
+	for _, c := range cases {
+		jump, err := ParseProxyJump(c.jump)
+		assert.NoError(t, err)
+		assert.Equal(t, c.expected, *jump)
+	}
+
+	for _, s := range []string{"", "user@", "bastion:ssh", "bastion:70000", "bastion example.com"} {
+		_, err := ParseProxyJump(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestNewExternalClientWithProxyJump(t *testing.T) {
+	client, err := NewExternalClient("/usr/bin/ssh", "docker", "10.0.0.12", 22, &Auth{
+		Keys:      []string{"/tmp/id_rsa"},
+		ProxyJump: "ec2-user@bastion.example.com:2222",
+	})
+	assert.NoError(t, err)
+
+	assert.Contains(t, client.BaseArgs, "ProxyCommand='/usr/bin/ssh' "+
+		"'-o' 'PasswordAuthentication=no' '-o' 'StrictHostKeyChecking=no' '-o' 'UserKnownHostsFile=/dev/null' "+
+		"'-o' 'LogLevel=quiet' '-o' 'ConnectionAttempts=3' '-o' 'ConnectTimeout=10' "+
+		"'-p' '2222' 'ec2-user@bastion.example.com' -W %h:%p")
+
+	client, err = NewExternalClient("/opt/open ssh/ssh", "docker", "10.0.0.12", 22, &Auth{ProxyJump: "bastion.example.com"})
+	assert.NoError(t, err)
+	assert.Contains(t, client.BaseArgs, "ProxyCommand='/opt/open ssh/ssh' '-o' 'PasswordAuthentication=no' "+
+		"'-o' 'StrictHostKeyChecking=no' '-o' 'UserKnownHostsFile=/dev/null' "+
+		"'-o' 'LogLevel=quiet' '-o' 'ConnectionAttempts=3' '-o' 'ConnectTimeout=10' "+
+		"'-p' '22' 'docker@bastion.example.com' -W %h:%p")
+
+	_, err = NewExternalClient("/usr/bin/ssh", "docker", "10.0.0.12", 22, &Auth{ProxyJump: "bastion:ssh"})
+	assert.Error(t, err)
+}
+
+func TestNewNativeClientWithProxyJump(t *testing.T) {
+	client, err := NewNativeClient("docker", "10.0.0.12", 22, &Auth{ProxyJump: "bastion.example.com"})
+	assert.NoError(t, err)
+
+	jump := client.(NativeClient).ProxyJump
+	assert.Equal(t, "bastion.example.com:22", jump.address())
+	assert.Equal(t, "docker", jump.user("docker"))
+}
@@ -2,12 +2,20 @@ package swarm
 
 const (
 	DiscoveryServiceEndpoint = "https://discovery-stage.hub.docker.com/v1"
+	DockerImage              = "swarm:latest"
+)
+
+var (
+	// DiscoveryBackends are the URL schemes of the discovery backends
+	// supported by Swarm.
+	DiscoveryBackends = []string{"token", "consul", "etcd", "zk", "file", "nodes"}
 )
 
 type Options struct {
 	IsSwarm        bool
 	Address        string
 	Discovery      string
+	DiscoveryOpts  []string
 	Master         bool
 	Host           string
 	Image          string
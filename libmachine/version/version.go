@@ -4,6 +4,10 @@ var (
 	// APIVersion dictates which version of the libmachine API this is.
 	APIVersion = 1
 
+	// MinAPIVersion is the oldest version of the libmachine API the driver
+	// plugins may speak.
+	MinAPIVersion = 1
+
 	// ConfigVersion dictates which version of the config.json format is
 	// used. It needs to be bumped if there is a breaking change, and
 	// therefore migration, introduced to the config file format.
@@ -0,0 +1,249 @@
+package libmachine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/persist"
+	"github.com/docker/machine/libmachine/provision"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/docker/machine/libmachine/wireguard"
+)
+
+// wireGuardLock is the lock of the store taken while allocating the
+// addresses of the overlay and configuring its machines.
+const wireGuardLock = "wireguard"
+
+// ErrWireGuardSharedStore is returned when creating a machine of the WireGuard
+// overlay in a store shared with other workstations.
+var ErrWireGuardSharedStore = errors.New("WireGuard can't be used with the etcd store: the addresses of the overlay are allocated under a lock of this workstation only, the other ones could give the same")
+
+// driverLoader gives the hosts loaded from the store a driver reaching their
+// machine.
+var driverLoader func(h *host.Host) error
+
+// SetDriverLoader sets how the hosts loaded from the store get a driver
+// reaching their machine, e.g. through the plugin of their driver. Without
+// one, provisioning a machine of the WireGuard overlay doesn't add it to the
+// peers of the other machines.
+func SetDriverLoader(loader func(h *host.Host) error) {
+	driverLoader = loader
+}
+
+// saveNewHost saves the host about to be created. A machine of the WireGuard
+// overlay first gets the first free address of its subnet, the other
+// commands being locked out until it's saved with it.
+func saveNewHost(store persist.Store, h *host.Host) error {
+	if !h.UsesWireGuard() || h.HostOptions.WireGuardOptions.Address != "" {
+		return store.Save(h)
+	}
+
+	if _, shared := store.(*persist.Etcdstore); shared {
+		return ErrWireGuardSharedStore
+	}
+
+	unlock, err := persist.Lock(store, wireGuardLock)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	hosts, err := store.List()
+	if err != nil {
+		return fmt.Errorf("Error listing the hosts of the store: %s", err)
+	}
+
+	opts := h.HostOptions.WireGuardOptions
+	used := []string{}
+	for _, other := range wireGuardMembers(hosts, opts.Subnet) {
+		if other.Name != h.Name {
+			used = append(used, other.HostOptions.WireGuardOptions.Address)
+		}
+	}
+
+	address, err := wireguard.AllocateAddress(opts.Subnet, used)
+	if err != nil {
+		return err
+	}
+
+	opts.Address = address
+	if err := store.Save(h); err != nil {
+		opts.Address = ""
+		return err
+	}
+
+	return nil
+}
+
+// wireGuardMembers returns the machines of the overlay subnet which got an
+// address.
+func wireGuardMembers(hosts []*host.Host, subnet string) []*host.Host {
+	members := []*host.Host{}
+	for _, h := range hosts {
+		if h.UsesWireGuard() && h.HostOptions.WireGuardOptions.Subnet == subnet && h.HostOptions.WireGuardOptions.Address != "" {
+			members = append(members, h)
+		}
+	}
+
+	return members
+}
+
+// wireGuardPeersOf returns the peers of the machine, the other machines of
+// its overlay subnet which were provisioned.
+func wireGuardPeersOf(h *host.Host, members []*host.Host) []wireguard.Peer {
+	peers := []wireguard.Peer{}
+	for _, other := range members {
+		opts := other.HostOptions.WireGuardOptions
+		if other.Name == h.Name || opts.Endpoint == "" {
+			continue
+		}
+
+		peers = append(peers, opts.Peer(other.Name))
+	}
+
+	return peers
+}
+
+// joinWireGuardMesh installs WireGuard on the machine being provisioned,
+// records where the other machines reach it and brings its overlay up with
+// them as peers, then adds it to their peers.
+func joinWireGuardMesh(store persist.Store, h *host.Host, provisioner provision.Provisioner) error {
+	if !h.UsesWireGuard() {
+		return nil
+	}
+
+	if err := h.InstallWireGuard(provisioner); err != nil {
+		return err
+	}
+
+	unlock, err := persist.Lock(store, wireGuardLock)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := h.SetWireGuardEndpoint(); err != nil {
+		return err
+	}
+
+	if err := store.Save(h); err != nil {
+		return fmt.Errorf("Error saving the WireGuard endpoint of %s: %s", h.Name, err)
+	}
+
+	hosts, err := store.List()
+	if err != nil {
+		return fmt.Errorf("Error listing the hosts of the store: %s", err)
+	}
+
+	if err := h.ApplyWireGuard(wireGuardPeersOf(h, wireGuardMembers(hosts, h.HostOptions.WireGuardOptions.Subnet))); err != nil {
+		return err
+	}
+
+	if err := updateWireGuardMesh(store, h.HostOptions.WireGuardOptions.Subnet, h.Name); err != nil {
+		log.Warnf("Error adding %s to the WireGuard peers of the other machines, run 'docker-machine wireguard sync' to retry: %s", h.Name, err)
+	}
+
+	return nil
+}
+
+// SyncWireGuardMesh configures the overlay of every running machine of the
+// store with all the others as peers, so that the machines whose IP address
+// changed or which were removed since they were provisioned are updated or
+// dropped.
+func SyncWireGuardMesh(store persist.Store) error {
+	unlock, err := persist.Lock(store, wireGuardLock)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	hosts, err := store.List()
+	if err != nil {
+		return fmt.Errorf("Error listing the hosts of the store: %s", err)
+	}
+
+	subnets := map[string]bool{}
+	errs := []string{}
+	for _, h := range hosts {
+		if !h.UsesWireGuard() || subnets[h.HostOptions.WireGuardOptions.Subnet] {
+			continue
+		}
+
+		subnet := h.HostOptions.WireGuardOptions.Subnet
+		subnets[subnet] = true
+		if err := updateWireGuardMesh(store, subnet, ""); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+
+	return nil
+}
+
+// updateWireGuardMesh configures the overlay of the running machines of the
+// subnet, but skipped, with the other machines of the subnet as peers. Their
+// endpoint is recorded first, in case their IP address changed. The caller
+// holds the lock of the overlay.
+func updateWireGuardMesh(store persist.Store, subnet, skipped string) error {
+	if driverLoader == nil {
+		return errors.New("the machines of the store can't be reached")
+	}
+
+	hosts, err := store.List()
+	if err != nil {
+		return fmt.Errorf("Error listing the hosts of the store: %s", err)
+	}
+
+	members := wireGuardMembers(hosts, subnet)
+	running := []*host.Host{}
+	for _, h := range members {
+		// The machines without an endpoint weren't provisioned yet, they get
+		// their peers once they are.
+		if h.Name == skipped || h.HostOptions.WireGuardOptions.Endpoint == "" {
+			continue
+		}
+
+		if err := driverLoader(h); err != nil {
+			log.Warnf("Error loading the driver of %s: %s", h.Name, err)
+			continue
+		}
+
+		if currentState, err := h.Driver.GetState(); err != nil || currentState != state.Running {
+			log.Infof("%s isn't running, run 'docker-machine wireguard sync' once it is to configure its WireGuard overlay.", h.Name)
+			continue
+		}
+
+		changed, err := h.SetWireGuardEndpoint()
+		if err != nil {
+			log.Warnf("Leaving %s out of the WireGuard overlay: %s", h.Name, err)
+			continue
+		}
+
+		if changed {
+			if err := store.Save(h); err != nil {
+				log.Warnf("Error saving the WireGuard endpoint of %s: %s", h.Name, err)
+			}
+		}
+
+		running = append(running, h)
+	}
+
+	errs := []string{}
+	for _, h := range running {
+		if err := h.ApplyWireGuard(wireGuardPeersOf(h, members)); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package wireguard
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+const (
+	// InterfaceName is the WireGuard interface of the overlay on the
+	// machines.
+	InterfaceName = "wg-machine"
+
+	// DefaultSubnet is the overlay network the machines get their addresses
+	// in by default.
+	DefaultSubnet = "10.200.0.0/24"
+
+	// DefaultListenPort is the UDP port the machines listen on by default.
+	DefaultListenPort = 51820
+
+	configPath = "/etc/wireguard/" + InterfaceName + ".conf"
+
+	// persistentKeepalive keeps the tunnels through NATs open, in seconds.
+	persistentKeepalive = 25
+)
+
+// CheckCommand succeeds when the WireGuard tools are on the machine.
+const CheckCommand = "command -v wg-quick"
+
+// PackageName is the package of the WireGuard tools, installed with the
+// package manager of the machine.
+const PackageName = "wireguard-tools"
+
+// ApplyCommand writes the configuration given on its standard input on the
+// machine, keeping the private key it holds out of the command line, and
+// brings the overlay up, or updates its peers without dropping the tunnels
+// if it's already up. The overlay is brought up at boot where systemd runs.
+const ApplyCommand = `sudo sh -c 'umask 077 && mkdir -p /etc/wireguard && cat > ` + configPath + `.tmp && mv ` + configPath + `.tmp ` + configPath + `' && ` +
+	`if ip link show ` + InterfaceName + ` >/dev/null 2>&1; then sudo bash -c 'wg syncconf ` + InterfaceName + ` <(wg-quick strip ` + InterfaceName + `)'; else sudo wg-quick up ` + InterfaceName + `; fi && ` +
+	`if command -v systemctl >/dev/null; then sudo systemctl enable wg-quick@` + InterfaceName + ` >/dev/null 2>&1 || true; fi`
+
+// Options configure the WireGuard overlay of a machine.
+type Options struct {
+	// Subnet is the overlay network, such as 10.200.0.0/24.
+	Subnet string
+	// Address is the address of the machine in Subnet, allocated when it's
+	// created.
+	Address string
+	// ListenPort is the UDP port the machine listens on.
+	ListenPort int
+	// Endpoint is where the other machines reach the machine, ip:port,
+	// recorded when it's provisioned.
+	Endpoint string
+	// PrivateKey and PublicKey are the base64 encoded keys of the machine.
+	PrivateKey string
+	PublicKey  string
+}
+
+// Peer is another machine of the overlay.
+type Peer struct {
+	Name      string
+	PublicKey string
+	// Endpoint is where the peer is reached, ip:port.
+	Endpoint string
+	// Address is the address of the peer in the overlay.
+	Address string
+}
+
+// Peer returns the machine name as a peer of the other machines.
+func (o Options) Peer(name string) Peer {
+	return Peer{
+		Name:      name,
+		PublicKey: o.PublicKey,
+		Endpoint:  o.Endpoint,
+		Address:   o.Address,
+	}
+}
+
+// GenerateKeyPair generates a private key and its public key, base64
+// encoded like wg genkey and wg pubkey do.
+func GenerateKeyPair() (string, string, error) {
+	var privateKey [32]byte
+	if _, err := rand.Read(privateKey[:]); err != nil {
+		return "", "", err
+	}
+
+	// Clamp the key as curve25519 expects.
+	privateKey[0] &= 248
+	privateKey[31] &= 127
+	privateKey[31] |= 64
+
+	var publicKey [32]byte
+	curve25519.ScalarBaseMult(&publicKey, &privateKey)
+
+	return base64.StdEncoding.EncodeToString(privateKey[:]), base64.StdEncoding.EncodeToString(publicKey[:]), nil
+}
+
+// NewOptions returns the options of a machine joining the overlay network
+// subnet, with a new key pair. Its address is allocated later.
+func NewOptions(subnet string, listenPort int) (*Options, error) {
+	if err := ValidateSubnet(subnet); err != nil {
+		return nil, err
+	}
+
+	if listenPort <= 0 || listenPort > 65535 {
+		return nil, fmt.Errorf("invalid WireGuard port %d", listenPort)
+	}
+
+	privateKey, publicKey, err := GenerateKeyPair()
+	if err != nil {
+		return nil, fmt.Errorf("error generating the WireGuard keys: %s", err)
+	}
+
+	return &Options{
+		Subnet:     subnet,
+		ListenPort: listenPort,
+		PrivateKey: privateKey,
+		PublicKey:  publicKey,
+	}, nil
+}
+
+func parseSubnet(subnet string) (*net.IPNet, error) {
+	_, network, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WireGuard subnet %q: %s", subnet, err)
+	}
+
+	if network.IP.To4() == nil {
+		return nil, fmt.Errorf("the WireGuard subnet %q is not an IPv4 network", subnet)
+	}
+
+	return network, nil
+}
+
+// ValidateSubnet checks the subnet is an IPv4 network with room for at least
+// two machines.
+func ValidateSubnet(subnet string) error {
+	network, err := parseSubnet(subnet)
+	if err != nil {
+		return err
+	}
+
+	if ones, _ := network.Mask.Size(); ones > 30 {
+		return fmt.Errorf("the WireGuard subnet %q is too small", subnet)
+	}
+
+	return nil
+}
+
+// AllocateAddress returns the first address of the hosts of subnet which
+// isn't used.
+func AllocateAddress(subnet string, used []string) (string, error) {
+	network, err := parseSubnet(subnet)
+	if err != nil {
+		return "", err
+	}
+
+	usedAddresses := map[string]bool{}
+	for _, address := range used {
+		usedAddresses[address] = true
+	}
+
+	ones, bits := network.Mask.Size()
+	first := binary.BigEndian.Uint32(network.IP.To4())
+	size := uint32(1) << uint(bits-ones)
+
+	// Skip the network and the broadcast addresses.
+	for i := uint32(1); i < size-1; i++ {
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, first+i)
+
+		if !usedAddresses[ip.String()] {
+			return ip.String(), nil
+		}
+	}
+
+	return "", errors.New("no address left in the WireGuard subnet " + subnet)
+}
+
+// Config returns the wg-quick configuration of the machine with the peers.
+func Config(opts Options, peers []Peer) (string, error) {
+	network, err := parseSubnet(opts.Subnet)
+	if err != nil {
+		return "", err
+	}
+	ones, _ := network.Mask.Size()
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "[Interface]\nPrivateKey = %s\nAddress = %s/%d\nListenPort = %d\n", opts.PrivateKey, opts.Address, ones, opts.ListenPort)
+
+	for _, peer := range peers {
+		fmt.Fprintf(&buf, "\n# %s\n[Peer]\nPublicKey = %s\nEndpoint = %s\nAllowedIPs = %s/32\nPersistentKeepalive = %d\n", peer.Name, peer.PublicKey, peer.Endpoint, peer.Address, persistentKeepalive)
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,106 @@
+package wireguard
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/curve25519"
+)
+
+func TestGenerateKeyPair(t *testing.T) {
+	privateKey, publicKey, err := GenerateKeyPair()
+	assert.NoError(t, err)
+
+	private, err := base64.StdEncoding.DecodeString(privateKey)
+	assert.NoError(t, err)
+	assert.Len(t, private, 32)
+
+	var in, out [32]byte
+	copy(in[:], private)
+	curve25519.ScalarBaseMult(&out, &in)
+
+	assert.Equal(t, base64.StdEncoding.EncodeToString(out[:]), publicKey)
+}
+
+func TestNewOptions(t *testing.T) {
+	opts, err := NewOptions(DefaultSubnet, DefaultListenPort)
+
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultSubnet, opts.Subnet)
+	assert.Equal(t, DefaultListenPort, opts.ListenPort)
+	assert.Empty(t, opts.Address)
+	assert.NotEmpty(t, opts.PrivateKey)
+	assert.NotEmpty(t, opts.PublicKey)
+}
+
+func TestNewOptionsGivenInvalidPort(t *testing.T) {
+	_, err := NewOptions(DefaultSubnet, 70000)
+
+	assert.EqualError(t, err, "invalid WireGuard port 70000")
+}
+
+func TestValidateSubnet(t *testing.T) {
+	assert.NoError(t, ValidateSubnet("10.200.0.0/24"))
+	assert.NoError(t, ValidateSubnet("172.16.0.0/30"))
+
+	assert.Error(t, ValidateSubnet("10.200.0.0"))
+	assert.Error(t, ValidateSubnet("fd00::/64"))
+	assert.EqualError(t, ValidateSubnet("10.200.0.0/31"), `the WireGuard subnet "10.200.0.0/31" is too small`)
+}
+
+func TestAllocateAddress(t *testing.T) {
+	address, err := AllocateAddress("10.200.0.0/24", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.200.0.1", address)
+
+	address, err = AllocateAddress("10.200.0.0/24", []string{"10.200.0.1", "10.200.0.3"})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.200.0.2", address)
+}
+
+func TestAllocateAddressGivenFullSubnet(t *testing.T) {
+	_, err := AllocateAddress("10.200.0.0/30", []string{"10.200.0.1", "10.200.0.2"})
+
+	assert.EqualError(t, err, "no address left in the WireGuard subnet 10.200.0.0/30")
+}
+
+func TestConfig(t *testing.T) {
+	opts := Options{
+		Subnet:     "10.200.0.0/24",
+		Address:    "10.200.0.1",
+		ListenPort: 51820,
+		PrivateKey: "private",
+		PublicKey:  "public",
+	}
+	peers := []Peer{{
+		Name:      "other",
+		PublicKey: "otherpublic",
+		Endpoint:  "1.2.3.4:51820",
+		Address:   "10.200.0.2",
+	}}
+
+	config, err := Config(opts, peers)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `[Interface]
+PrivateKey = private
+Address = 10.200.0.1/24
+ListenPort = 51820
+
+# other
+[Peer]
+PublicKey = otherpublic
+Endpoint = 1.2.3.4:51820
+AllowedIPs = 10.200.0.2/32
+PersistentKeepalive = 25
+`, config)
+}
+
+func TestApplyCommand(t *testing.T) {
+	assert.True(t, strings.Contains(ApplyCommand, "umask 077"))
+	assert.True(t, strings.Contains(ApplyCommand, "cat > /etc/wireguard/wg-machine.conf.tmp"))
+	assert.True(t, strings.Contains(ApplyCommand, "sudo wg-quick up wg-machine"))
+	assert.True(t, strings.Contains(ApplyCommand, "wg syncconf wg-machine"))
+}
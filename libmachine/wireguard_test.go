@@ -0,0 +1,82 @@
+package libmachine
+
+import (
+	"io/ioutil"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/persist"
+	"github.com/docker/machine/libmachine/wireguard"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAllAllocatesWireGuardAddresses(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store := &persist.Filestore{Path: dir}
+	counter := &createCounter{release: make(chan struct{}, 4)}
+
+	hosts := []*host.Host{}
+	for _, name := range []string{"m-1", "m-2", "m-3", "m-4"} {
+		h := newCountingHost(dir, name, counter, false)
+		h.RawDriver = []byte("{}")
+		h.HostOptions.WireGuardOptions = &wireguard.Options{Subnet: "10.200.0.0/24", ListenPort: wireguard.DefaultListenPort}
+		hosts = append(hosts, h)
+		counter.release <- struct{}{}
+	}
+
+	assert.Empty(t, CreateAll(store, hosts, 4))
+
+	addresses := []string{}
+	for _, h := range hosts {
+		addresses = append(addresses, h.HostOptions.WireGuardOptions.Address)
+	}
+	sort.Strings(addresses)
+
+	assert.Equal(t, []string{"10.200.0.1", "10.200.0.2", "10.200.0.3", "10.200.0.4"}, addresses)
+}
+
+func TestSaveNewHostRefusesWireGuardWithEtcd(t *testing.T) {
+	store := &persist.Etcdstore{Endpoint: "http://127.0.0.1:0", Prefix: "team"}
+	h := wireGuardHost("m-1", "10.200.0.0/24", "", "")
+
+	assert.Equal(t, ErrWireGuardSharedStore, saveNewHost(store, h))
+	assert.Empty(t, h.HostOptions.WireGuardOptions.Address)
+}
+
+func wireGuardHost(name, subnet, address, endpoint string) *host.Host {
+	return &host.Host{
+		Name: name,
+		HostOptions: &host.Options{
+			WireGuardOptions: &wireguard.Options{
+				Subnet:     subnet,
+				Address:    address,
+				ListenPort: wireguard.DefaultListenPort,
+				Endpoint:   endpoint,
+				PublicKey:  name + "key",
+			},
+		},
+	}
+}
+
+func TestWireGuardPeersOf(t *testing.T) {
+	first := wireGuardHost("first", "10.200.0.0/24", "10.200.0.1", "1.2.3.4:51820")
+	second := wireGuardHost("second", "10.200.0.0/24", "10.200.0.2", "1.2.3.5:51820")
+	unprovisioned := wireGuardHost("unprovisioned", "10.200.0.0/24", "10.200.0.3", "")
+	other := wireGuardHost("other", "10.201.0.0/24", "10.201.0.1", "1.2.3.6:51820")
+	plain := &host.Host{Name: "plain", HostOptions: &host.Options{}}
+
+	members := wireGuardMembers([]*host.Host{first, second, unprovisioned, other, plain}, "10.200.0.0/24")
+
+	assert.Equal(t, []*host.Host{first, second, unprovisioned}, members)
+	assert.Equal(t, []wireguard.Peer{{
+		Name:      "second",
+		PublicKey: "secondkey",
+		Endpoint:  "1.2.3.5:51820",
+		Address:   "10.200.0.2",
+	}}, wireGuardPeersOf(first, members))
+}